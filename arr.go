@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// arrClient is a libraryManager backed by the v3 REST API shared by Radarr
+// and Sonarr: media they monitor are never deleted, and media whose files
+// were deleted are rescanned afterwards.
+type arrClient struct {
+	name     string // shown in messages and logs
+	baseURL  string
+	apiKey   string
+	resource string // API resource listing the media, e.g. "movie"
+	rescan   string // command rescanning one item, e.g. "RescanMovie"
+	idParam  string // id parameter of that command, e.g. "movieId"
+	// missingOnly keeps only monitored media with files still to download,
+	// as Sonarr reports them; Radarr movies are kept whenever monitored
+	missingOnly bool
+	paths       pathMap
+	client      *http.Client
+
+	items []arrItem
+}
+
+// arrItem is the part of a movie (or series) the cleanup needs.
+type arrItem struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	Year      int    `json:"year"`
+	Path      string `json:"path"`
+	Monitored bool   `json:"monitored"`
+	// Statistics is only read from Sonarr series
+	Statistics struct {
+		EpisodeFileCount int `json:"episodeFileCount"`
+		EpisodeCount     int `json:"episodeCount"` // monitored episodes
+	} `json:"statistics"`
+}
+
+// newRadarr returns the client of the Radarr instance at baseURL. paths maps
+// the folders Radarr reports to the scanned ones.
+func newRadarr(baseURL, apiKey string, paths pathMap) *arrClient {
+	return &arrClient{
+		name:     "Radarr",
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		resource: "movie",
+		rescan:   "RescanMovie",
+		idParam:  "movieId",
+		paths:    paths,
+		client:   &http.Client{Timeout: time.Minute},
+	}
+}
+
+// newSonarr returns the client of the Sonarr instance at baseURL. Only
+// monitored series with episodes not downloaded yet are kept: once a series
+// is complete, a season or episode folder without video is orphaned.
+func newSonarr(baseURL, apiKey string, paths pathMap) *arrClient {
+	return &arrClient{
+		name:        "Sonarr",
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		apiKey:      apiKey,
+		resource:    "series",
+		rescan:      "RescanSeries",
+		idParam:     "seriesId",
+		missingOnly: true,
+		paths:       paths,
+		client:      &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (c *arrClient) Name() string { return c.name }
+
+// Load fetches every movie or series with its folder, translated to a local
+// path.
+func (c *arrClient) Load(ctx context.Context) error {
+	var items []arrItem
+	if err := c.call(ctx, http.MethodGet, "/api/v3/"+c.resource, nil, &items); err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].Path = c.paths.Local(items[i].Path)
+	}
+	c.items = items
+	return nil
+}
+
+// Wanted reports the monitored movie or series whose folder holds path, or
+// is path.
+func (c *arrClient) Wanted(path string) (string, bool) {
+	for _, item := range c.items {
+		if !item.Monitored || item.Path == "" || !cleanup.IsWithin(item.Path, path) {
+			continue
+		}
+		if !c.missingOnly || item.Statistics.EpisodeFileCount < item.Statistics.EpisodeCount {
+			return item.label(), true
+		}
+	}
+	return "", false
+}
+
+// Rescan sends one rescan command per known movie or series inside or
+// around the deleted paths. Folders the application does not know need
+// none.
+func (c *arrClient) Rescan(ctx context.Context, deleted []string) error {
+	seen := map[int]bool{}
+	for _, item := range c.items {
+		for _, path := range deleted {
+			if item.Path != "" && !seen[item.ID] && (cleanup.IsWithin(item.Path, path) || cleanup.IsWithin(path, item.Path)) {
+				seen[item.ID] = true
+				command := map[string]any{"name": c.rescan, c.idParam: item.ID}
+				if err := c.call(ctx, http.MethodPost, "/api/v3/command", command, nil); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// call sends an API request authenticated with the API key; see callJSON.
+func (c *arrClient) call(ctx context.Context, method, path string, body, out any) error {
+	return callJSON(ctx, c.client, method, c.baseURL+path, http.Header{"X-Api-Key": {c.apiKey}}, body, out)
+}
+
+func (item arrItem) label() string {
+	if item.Year == 0 {
+		return item.Title
+	}
+	return fmt.Sprintf("%s (%d)", item.Title, item.Year)
+}
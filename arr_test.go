@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakeArr serves items as the given API resource ("movie" or "series") and
+// records the commands it receives.
+func fakeArr(t *testing.T, resource string, items []arrItem) (*httptest.Server, *[]map[string]any) {
+	var mu sync.Mutex
+	var commands []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/"+resource:
+			json.NewEncoder(w).Encode(items)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/command":
+			var command map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&command); err != nil {
+				t.Errorf("Invalid command: %v", err)
+			}
+			mu.Lock()
+			commands = append(commands, command)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &commands
+}
+
+// ============================================================================
+// Tests for arrClient
+// ============================================================================
+
+func TestRadarr_WantedOnlyMonitoredMovies(t *testing.T) {
+	library := t.TempDir()
+	server, _ := fakeArr(t, "movie", []arrItem{
+		{ID: 1, Title: "Upcoming", Year: 2025, Path: "/movies/Studio/Upcoming (2025)", Monitored: true},
+		{ID: 2, Title: "Dropped", Year: 2001, Path: "/movies/Studio/Dropped (2001)"},
+	})
+	radarr := newRadarr(server.URL+"/", "secret", pathMap{{Remote: "/movies", Local: library}})
+	if err := radarr.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	media, ok := radarr.Wanted(filepath.Join(library, "Studio", "Upcoming (2025)", "poster.jpg"))
+	if !ok || media != "Upcoming (2025)" {
+		t.Errorf("Expected the monitored movie to be wanted, got %q, %v", media, ok)
+	}
+	if _, ok := radarr.Wanted(filepath.Join(library, "Studio", "Dropped (2001)")); ok {
+		t.Error("Expected an unmonitored movie not to be wanted")
+	}
+	if _, ok := radarr.Wanted(filepath.Join(library, "Studio")); ok {
+		t.Error("Expected the studio folder not to be wanted")
+	}
+}
+
+func TestRadarr_RescansDeletedMovies(t *testing.T) {
+	library := t.TempDir()
+	server, commands := fakeArr(t, "movie", []arrItem{
+		{ID: 1, Title: "Upcoming", Path: "/movies/Studio/Upcoming (2025)", Monitored: true},
+		{ID: 2, Title: "Dropped", Path: "/movies/Studio/Dropped (2001)"},
+	})
+	radarr := newRadarr(server.URL, "secret", pathMap{{Remote: "/movies", Local: library}})
+	if err := radarr.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	deleted := []string{
+		filepath.Join(library, "Studio", "Dropped (2001)"),
+		filepath.Join(library, "Studio", "Dropped (2001)", "extrafanart"),
+		filepath.Join(library, "Studio", "Unknown (1999)"),
+	}
+	if err := radarr.Rescan(context.Background(), deleted); err != nil {
+		t.Fatalf("Rescan failed: %v", err)
+	}
+	if len(*commands) != 1 {
+		t.Fatalf("Expected 1 command, got %d: %v", len(*commands), *commands)
+	}
+	command := (*commands)[0]
+	if command["name"] != "RescanMovie" || command["movieId"] != float64(2) {
+		t.Errorf("Expected RescanMovie of movie 2, got %v", command)
+	}
+}
+
+func TestRadarr_LoadFailsWithWrongKey(t *testing.T) {
+	server, _ := fakeArr(t, "movie", nil)
+	radarr := newRadarr(server.URL, "wrong", nil)
+	if err := radarr.Load(context.Background()); err == nil {
+		t.Error("Expected an error for a rejected API key")
+	}
+}
+
+func TestSonarr_WantedOnlyIncompleteMonitoredSeries(t *testing.T) {
+	library := t.TempDir()
+	airing := arrItem{ID: 1, Title: "Airing", Path: "/tv/Airing", Monitored: true}
+	airing.Statistics.EpisodeCount, airing.Statistics.EpisodeFileCount = 10, 8
+	complete := arrItem{ID: 2, Title: "Complete", Path: "/tv/Complete", Monitored: true}
+	complete.Statistics.EpisodeCount, complete.Statistics.EpisodeFileCount = 10, 10
+	server, commands := fakeArr(t, "series", []arrItem{airing, complete})
+	sonarr := newSonarr(server.URL, "secret", pathMap{{Remote: "/tv", Local: library}})
+	if err := sonarr.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if media, ok := sonarr.Wanted(filepath.Join(library, "Airing", "Season 02")); !ok || media != "Airing" {
+		t.Errorf("Expected a season of a series missing episodes to be wanted, got %q, %v", media, ok)
+	}
+	if _, ok := sonarr.Wanted(filepath.Join(library, "Complete", "Season 01")); ok {
+		t.Error("Expected a season of a complete series not to be wanted")
+	}
+
+	if err := sonarr.Rescan(context.Background(), []string{filepath.Join(library, "Complete", "Season 01")}); err != nil {
+		t.Fatalf("Rescan failed: %v", err)
+	}
+	if len(*commands) != 1 || (*commands)[0]["name"] != "RescanSeries" || (*commands)[0]["seriesId"] != float64(2) {
+		t.Errorf("Expected RescanSeries of series 2, got %v", *commands)
+	}
+}
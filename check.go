@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// titleVerdict sums up what a check of titlePath found: whether the folder
+// would be deleted and why, or kept.
+func titleVerdict(result *cleanup.CleanupResult, titlePath string) string {
+	titlePath = filepath.Clean(titlePath)
+	others := 0
+	for _, f := range result.Findings {
+		if filepath.Clean(f.Path) != titlePath {
+			if cleanup.IsWithin(titlePath, f.Path) {
+				others++
+			}
+			continue
+		}
+		switch f.Category {
+		case cleanup.CategoryOrphanedFolder:
+			return "delete, metadata without a video file"
+		case cleanup.CategoryEmptyFolder:
+			return "delete, empty folder"
+		case cleanup.CategoryMisfiledVideo:
+			return "keep, the main video is in an extras folder"
+		case cleanup.CategoryDownloadInProgress:
+			return "keep, a download is still in progress"
+		default:
+			others++
+		}
+	}
+	if others > 0 {
+		return "keep, see the findings above"
+	}
+	return "keep, nothing to clean up"
+}
+
+// printVerdicts writes the verdict of every checked title to w.
+func printVerdicts(w io.Writer, result *cleanup.CleanupResult, titlePaths []string, lang *Language) {
+	for _, titlePath := range titlePaths {
+		lang.Fprintf(w, "\n🔎 %s: %s\n", titlePath, lang.T(titleVerdict(result, titlePath)))
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for title verdicts
+// ============================================================================
+
+func TestTitleVerdict(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Orphan"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/Empty"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryMisfiledVideo, Path: "/lib/S/Misfiled", Message: "Only video is in an extras subfolder (extras/movie.mkv)"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryDownloadInProgress, Path: "/lib/S/Downloading", Message: "Partial download (movie.mkv.part)"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/S/Warned/random", Message: "Unexpected subdirectory in title folder"})
+
+	tests := map[string]string{
+		"/lib/S/Orphan":      "delete, metadata without a video file",
+		"/lib/S/Empty/":      "delete, empty folder",
+		"/lib/S/Misfiled":    "keep, the main video is in an extras folder",
+		"/lib/S/Downloading": "keep, a download is still in progress",
+		"/lib/S/Warned":      "keep, see the findings above",
+		"/lib/S/Fine":        "keep, nothing to clean up",
+		"/lib/S/Warn":        "keep, nothing to clean up", // prefix of another title only
+	}
+	for titlePath, expected := range tests {
+		if got := titleVerdict(result, titlePath); got != expected {
+			t.Errorf("titleVerdict(%s): expected %q, got %q", titlePath, expected, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package cleanup
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// holdRecent turns the deletable finding f into a structure warning when
+// anything in it was modified after cutoff: a folder still being imported
+// may hold its metadata before its video has been copied in.
+func (r *scanRun) holdRecent(f Finding, cutoff time.Time) Finding {
+	var what string
+	switch f.Category {
+	case CategoryOrphanedFolder:
+		what = "Orphaned folder"
+	case CategoryOrphanedFile:
+		what = "Orphaned file"
+	case CategoryEmptyFolder:
+		what = "Empty folder"
+	default:
+		return f
+	}
+	if f.Modified == nil || !f.Modified.After(cutoff) {
+		return f
+	}
+	return Finding{Category: CategoryStructureWarning, Path: f.Path, Severity: SeverityInfo,
+		Message: fmt.Sprintf("%s modified within the last %s, left for now", what, FormatAge(r.minAge))}
+}
+
+// stampModified sets the Modified time of a deletable finding f that has
+// none yet; findings replayed from a state file or checkpoint keep theirs.
+func (r *scanRun) stampModified(f Finding) Finding {
+	switch f.Category {
+	case CategoryOrphanedFolder, CategoryOrphanedFile, CategoryEmptyFolder:
+	default:
+		return f
+	}
+	if f.Modified != nil {
+		return f
+	}
+	if newest, ok := newestModTime(r.fsys, f.Path); ok {
+		f.Modified = &newest
+	}
+	return f
+}
+
+// newestModTime returns the latest modification time of path and, for a
+// folder, of everything below it. Entries that cannot be read are skipped;
+// ok is false only if path itself cannot be.
+func newestModTime(fsys FS, path string) (newest time.Time, ok bool) {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	newest = info.ModTime()
+	if !info.IsDir() {
+		return newest, true
+	}
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return newest, true
+	}
+	for _, entry := range entries {
+		var t time.Time
+		if entry.IsDir() {
+			t, _ = newestModTime(fsys, filepath.Join(path, entry.Name()))
+		} else if info, err := entry.Info(); err == nil {
+			t = info.ModTime()
+		}
+		if t.After(newest) {
+			newest = t
+		}
+	}
+	return newest, true
+}
+
+// ParseAge parses an age such as "7d", "2w" or any time.ParseDuration
+// value ("36h"). Days are 24 hours and weeks 7 days.
+func ParseAge(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if n := len(s); n > 1 {
+		if unit, ok := units[s[n-1]]; ok {
+			if count, err := strconv.Atoi(s[:n-1]); err == nil && count >= 0 {
+				return time.Duration(count) * unit, nil
+			}
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// FormatAge formats d in whole days when it is a number of days, as
+// ParseAge accepts it, and as time.Duration does otherwise.
+func FormatAge(d time.Duration) string {
+	if day := 24 * time.Hour; d > 0 && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
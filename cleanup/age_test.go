@@ -0,0 +1,137 @@
+package cleanup
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for WithMinAge
+// ============================================================================
+
+func TestScan_MinAgeHoldsRecentOrphans(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Old Orphan (2001)").Metadata("movie.nfo").
+		Studio("Studio").
+		Title("Importing (2024)").Metadata("movie.nfo", "poster.jpg").
+		MapFS()
+	importing := filepath.Join("Studio", "Importing (2024)")
+	fsys[filepath.ToSlash(filepath.Join(importing, "poster.jpg"))].ModTime = time.Now().Add(-time.Hour)
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithMinAge(7*24*time.Hour)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := filepath.Join("Studio", "Old Orphan (2001)")
+	if len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] != expected {
+		t.Errorf("Expected only %s to be orphaned, got %v", expected, result.OrphanedFolders)
+	}
+	held := false
+	for _, f := range result.Findings {
+		if f.Path == importing {
+			held = f.Category == CategoryStructureWarning
+		}
+	}
+	if !held {
+		t.Errorf("Expected %s to be reported as a structure warning, got %v", importing, result.Findings)
+	}
+}
+
+func TestScan_MinAgeHeldFindingsFollowCategories(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Old Orphan (2001)").Metadata("movie.nfo").
+		Title("Importing (2024)").Metadata("movie.nfo").
+		MapFS()
+	fsys["Studio/Importing (2024)/movie.nfo"].ModTime = time.Now()
+
+	result := &CleanupResult{}
+	scanner := NewScanner(WithFS(IOFS(fsys)), WithMinAge(7*24*time.Hour), WithCategories(CategoryOrphanedFolder))
+	if err := scanner.Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	expected := filepath.Join("Studio", "Old Orphan (2001)")
+	if len(result.Findings) != 1 || result.Findings[0].Path != expected {
+		t.Errorf("Expected only %s, without a structure warning for the held orphan, got %v", expected, result.Findings)
+	}
+}
+
+func TestScan_NoMinAgeReportsRecentOrphans(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Importing (2024)").Metadata("movie.nfo").
+		MapFS()
+	fsys["Studio/Importing (2024)/movie.nfo"].ModTime = time.Now()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %v", result.OrphanedFolders)
+	}
+}
+
+func TestScan_RecordsModifiedTime(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Orphan (2001)").Metadata("movie.nfo", "poster.jpg").
+		Title("Movie (2002)").Video("Movie (2002).mkv").
+		MapFS()
+	newest := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fsys["Studio/Orphan (2001)/movie.nfo"].ModTime = newest.Add(-48 * time.Hour)
+	fsys["Studio/Orphan (2001)/poster.jpg"].ModTime = newest
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.Findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %v", result.Findings)
+	}
+	if modified := result.Findings[0].Modified; modified == nil || !modified.Equal(newest) {
+		t.Errorf("Expected the newest file time %v, got %v", newest, modified)
+	}
+}
+
+// ============================================================================
+// Tests for ParseAge and FormatAge
+// ============================================================================
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+		ok    bool
+	}{
+		{"7d", 7 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"36h", 36 * time.Hour, true},
+		{"0", 0, true},
+		{"d", 0, false},
+		{"-1d", 0, false},
+		{"1.5d", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseAge(tt.input)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseAge(%q): expected %v (ok %v), got %v (%v)", tt.input, tt.want, tt.ok, got, err)
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	if got := FormatAge(7 * 24 * time.Hour); got != "7d" {
+		t.Errorf("Expected 7d, got %s", got)
+	}
+	if got := FormatAge(36 * time.Hour); got != "36h0m0s" {
+		t.Errorf("Expected 36h0m0s, got %s", got)
+	}
+}
@@ -0,0 +1,215 @@
+package cleanup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Backup is a tar archive the items a Deleter given it with WithBackup
+// disposes of are written to first, so a run that deleted the wrong things
+// can be recovered from a single file. Items are stored under their absolute
+// path, without the leading separator or volume name, as tar does. Use
+// CreateBackup; it is safe for concurrent use.
+type Backup struct {
+	Path string
+
+	mu     sync.Mutex
+	file   *os.File
+	tw     *tar.Writer
+	closer func() error // flushes the compression
+	items  int
+	err    error // the first write error; the archive is unusable after it
+}
+
+// CreateBackup creates the archive at path, compressed after its extension:
+// .tar.gz or .tgz with gzip, .tar.zst or .tzst by piping it through the zstd
+// command, which must be in PATH, and .tar uncompressed. An existing file is
+// never overwritten.
+func CreateBackup(path string) (*Backup, error) {
+	name := strings.ToLower(path)
+	var zstd string
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"), strings.HasSuffix(name, ".tar"):
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		bin, err := exec.LookPath("zstd")
+		if err != nil {
+			return nil, fmt.Errorf("backup %s: zstd not found in PATH, install it or use a .tar.gz archive", path)
+		}
+		zstd = bin
+	default:
+		return nil, fmt.Errorf("backup %s: unknown archive type (expected .tar, .tar.gz, .tgz, .tar.zst or .tzst)", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	b := &Backup{Path: path, file: file}
+
+	switch {
+	case zstd != "":
+		cmd := exec.Command(zstd, "-q", "-c")
+		cmd.Stdout = file
+		stdin, err := cmd.StdinPipe()
+		if err == nil {
+			err = cmd.Start()
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(path)
+			return nil, fmt.Errorf("backup: zstd: %w", err)
+		}
+		b.tw = tar.NewWriter(stdin)
+		b.closer = func() error {
+			stdin.Close()
+			if err := cmd.Wait(); err != nil {
+				return fmt.Errorf("zstd: %w", err)
+			}
+			return nil
+		}
+	case strings.HasSuffix(name, ".tar"):
+		b.tw = tar.NewWriter(file)
+		b.closer = func() error { return nil }
+	default:
+		gz := gzip.NewWriter(file)
+		b.tw = tar.NewWriter(gz)
+		b.closer = gz.Close
+	}
+	return b, nil
+}
+
+// WithBackup writes every item the Deleter disposes of to b first. An item
+// that cannot be written to the archive is left in place and reported as a
+// failure, as is everything after the archive became unusable.
+func WithBackup(b *Backup) DeleterOption {
+	return func(d *Deleter) {
+		d.backup = b
+	}
+}
+
+// Items returns the number of items written to the archive so far.
+func (b *Backup) Items() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.items
+}
+
+// Close completes the archive and flushes it to disk. An archive nothing
+// was written to is removed.
+func (b *Backup) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	err := b.tw.Close()
+	if closeErr := b.closer(); err == nil {
+		err = closeErr
+	}
+	if syncErr := b.file.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := b.file.Close(); err == nil {
+		err = closeErr
+	}
+	if b.items == 0 && b.err == nil {
+		os.Remove(b.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("backup %s: %w", b.Path, err)
+	}
+	return nil
+}
+
+// add writes the file or folder at f.Path, with everything below it, to the
+// archive.
+func (b *Backup) add(f Finding) error {
+	root, err := filepath.Abs(f.Path)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return fmt.Errorf("backup %s unusable: %w", b.Path, b.err)
+	}
+	err = walkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return b.write(path, info)
+	})
+	var broken *archiveError
+	if errors.As(err, &broken) {
+		b.err = broken.err
+	}
+	if err != nil {
+		return err
+	}
+	b.items++
+	return nil
+}
+
+// write adds the entry at path to the archive.
+func (b *Backup) write(path string, info fs.FileInfo) error {
+	var link string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(longPath(path))
+		if err != nil {
+			return err
+		}
+		link = target
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimPrefix(path, filepath.VolumeName(path))
+	header.Name = strings.TrimLeft(filepath.ToSlash(name), "/")
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if !info.Mode().IsRegular() {
+		if err := b.tw.WriteHeader(header); err != nil {
+			return &archiveError{err}
+		}
+		return nil
+	}
+	// Opened before the header is written, so an unreadable file leaves
+	// the archive intact
+	file, err := os.Open(longPath(path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := b.tw.WriteHeader(header); err != nil {
+		return &archiveError{err}
+	}
+	// A file that shrank since leaves its entry incomplete too
+	if _, err := io.CopyN(b.tw, file, header.Size); err != nil {
+		return &archiveError{fmt.Errorf("%s: %w", path, err)}
+	}
+	return nil
+}
+
+// archiveError is a failed write to the archive, after which it ends in the
+// middle of an entry and cannot take more. Items that cannot be read leave
+// it intact.
+type archiveError struct {
+	err error
+}
+
+func (e *archiveError) Error() string { return e.err.Error() }
+func (e *archiveError) Unwrap() error { return e.err }
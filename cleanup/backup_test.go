@@ -0,0 +1,124 @@
+package cleanup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// archiveNames returns the sorted names of the entries of the gzipped tar
+// archive at path.
+func archiveNames(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected the archive at %s, got %v", path, err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ============================================================================
+// Tests for Backup
+// ============================================================================
+
+func TestBackup_ArchivesBeforeDeleting(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	library := filepath.Join(tempDir, "Library")
+	result, paths := deletableResult(t, library)
+	archive := filepath.Join(tempDir, "backup", "run.tar.gz")
+
+	backup, err := CreateBackup(archive)
+	if err != nil {
+		t.Fatalf("CreateBackup returned error: %v", err)
+	}
+	report, err := NewDeleter(nil, WithBackup(backup), WithDeleteWorkers(4)).Delete(context.Background(), result)
+	if err != nil || len(report.Failures) > 0 {
+		t.Fatalf("Delete failed: %v %v", err, report.Failures)
+	}
+	if err := backup.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if backup.Items() != len(paths) {
+		t.Errorf("Expected %d items backed up, got %d", len(paths), backup.Items())
+	}
+
+	prefix := strings.TrimLeft(filepath.ToSlash(strings.TrimPrefix(library, filepath.VolumeName(library))), "/") + "/Studio/"
+	expected := []string{prefix + "Empty/", prefix + "Orphaned/", prefix + "Orphaned/movie.nfo", prefix + "deleted.nfo"}
+	names := archiveNames(t, archive)
+	if strings.Join(names, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected entries %v, got %v", expected, names)
+	}
+}
+
+func TestBackup_KeepsUnarchivedItems(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	result, _ := deletableResult(t, filepath.Join(tempDir, "Library"))
+	// Gone before the run: it cannot be archived, the others still are
+	missing := Finding{Category: CategoryOrphanedFile, Path: filepath.Join(tempDir, "Library", "missing.nfo"), Library: filepath.Join(tempDir, "Library")}
+
+	backup, err := CreateBackup(filepath.Join(tempDir, "run.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	if err := backup.add(missing); err == nil {
+		t.Error("Expected an error for a missing item")
+	}
+	report, err := NewDeleter(nil, WithBackup(backup)).Delete(context.Background(), result)
+	if err != nil || len(report.Deleted) != 3 {
+		t.Errorf("Expected the readable items deleted after a missing one, got %d deleted (%v %v)", len(report.Deleted), err, report.Failures)
+	}
+}
+
+func TestBackup_Create(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	existing := filepath.Join(tempDir, "existing.tar.gz")
+	createFile(t, existing)
+	if _, err := CreateBackup(existing); err == nil {
+		t.Error("Expected an existing archive to be refused")
+	}
+	if _, err := CreateBackup(filepath.Join(tempDir, "backup.zip")); err == nil {
+		t.Error("Expected an unknown archive type to be refused")
+	}
+
+	// Nothing written: no archive left behind
+	empty := filepath.Join(tempDir, "empty.tgz")
+	backup, err := CreateBackup(empty)
+	if err != nil {
+		t.Fatalf("CreateBackup returned error: %v", err)
+	}
+	if err := backup.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Errorf("Expected the empty archive removed, got %v", err)
+	}
+}
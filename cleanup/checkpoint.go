@@ -0,0 +1,326 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkpointVersion is bumped whenever the checkpoint file changes shape, so
+// checkpoints written by older versions are dropped.
+const checkpointVersion = 1
+
+// DefaultCheckpointInterval is how often a Checkpoint is saved at most while
+// a scan is in progress.
+const DefaultCheckpointInterval = time.Minute
+
+// Checkpoint records, while a scan is in progress, the top-level folders (the
+// studios in the default layout) of each library it has finished, with
+// their findings. It is saved to Path every Interval at most as folders
+// finish, so that a scan stopped by a reboot or a dropped connection can be
+// resumed: a Scanner given a Checkpoint with Resume set with WithCheckpoint
+// reports the findings recorded for those folders instead of scanning them
+// again.
+//
+// A library is forgotten once a scan of it completes. What was recorded of
+// it is dropped, rather than resumed, when the options that shape its
+// findings changed, and always with duplicate detection, which needs every
+// video. A top-level folder that could not be read in full is scanned again,
+// and so is one where an orphaned or empty item it recorded changed since.
+// A Checkpoint is safe for concurrent use by scans of different libraries.
+type Checkpoint struct {
+	Path string
+	// Resume makes scans skip the top-level folders recorded by an earlier
+	// scan. Without it, scans start over and only record their progress.
+	Resume bool
+	// Interval is how often the checkpoint is saved at most while scanning;
+	// DefaultCheckpointInterval if zero.
+	Interval time.Duration
+
+	mu        sync.Mutex
+	libraries map[string]*libraryCheckpoint // by library root
+	saved     time.Time
+	onDisk    bool  // Path holds progress that a complete scan must clear
+	err       error // the first failed save
+
+	saveMu sync.Mutex // one save at a time, so the latest one wins
+}
+
+// libraryCheckpoint is what a Checkpoint records of one library.
+type libraryCheckpoint struct {
+	Options string               `json:"options"` // hash of the scanner options and filter
+	Folders map[string][]Finding `json:"folders"` // finished top-level folders, by path
+}
+
+// checkpointFile is the JSON form of a Checkpoint.
+type checkpointFile struct {
+	Version   int                           `json:"version"`
+	Libraries map[string]*libraryCheckpoint `json:"libraries"`
+}
+
+// LoadCheckpoint reads the checkpoint saved at path. A missing file, or one
+// saved by another version, gives an empty checkpoint.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	c := &Checkpoint{Path: path, libraries: map[string]*libraryCheckpoint{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	var file checkpointFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	if file.Version == checkpointVersion && file.Libraries != nil {
+		c.libraries = file.Libraries
+	}
+	c.onDisk = len(c.libraries) > 0
+	return c, nil
+}
+
+// Save writes the checkpoint to Path, replacing the file at once so an
+// interrupted save leaves the previous checkpoint. It returns the error of
+// the first save made during a scan that failed, if any.
+func (c *Checkpoint) Save() error {
+	err := c.save()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return err
+}
+
+func (c *Checkpoint) save() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	c.mu.Lock()
+	data, err := json.Marshal(checkpointFile{Version: checkpointVersion, Libraries: c.libraries})
+	empty := len(c.libraries) == 0
+	c.saved = time.Now()
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if empty {
+		if err := os.Remove(c.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("checkpoint: %w", err)
+		}
+		c.mu.Lock()
+		c.onDisk = false
+		c.mu.Unlock()
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.Path), filepath.Base(c.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.Path); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	c.mu.Lock()
+	c.onDisk = true
+	c.mu.Unlock()
+	return nil
+}
+
+// saveDue saves the checkpoint if Interval has elapsed since the last save.
+// A failed save is kept for Save to return; the scan carries on.
+func (c *Checkpoint) saveDue() {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultCheckpointInterval
+	}
+	c.mu.Lock()
+	due := time.Since(c.saved) >= interval
+	if c.saved.IsZero() {
+		// The first save waits for a whole interval too, so short scans
+		// never write a checkpoint
+		c.saved = time.Now()
+		due = false
+	}
+	c.mu.Unlock()
+	if !due {
+		return
+	}
+	if err := c.save(); err != nil {
+		c.mu.Lock()
+		if c.err == nil {
+			c.err = err
+		}
+		c.mu.Unlock()
+	}
+}
+
+// WithCheckpoint makes Scan record in c the top-level folders it finishes,
+// and skip those c recorded earlier if c.Resume is set.
+func WithCheckpoint(c *Checkpoint) Option {
+	return func(s *Scanner) {
+		s.checkpoint = c
+	}
+}
+
+// checkpointKey hashes what the findings of a top-level folder depend on:
+// the options of optionsKey and the include and exclude patterns.
+func (s *Scanner) checkpointKey() string {
+	return s.optionsKey() + fmt.Sprintf("|%q|%q", s.filter.Include, s.filter.Exclude)
+}
+
+// checkpointScan is the part of a Checkpoint one Scan reads and updates.
+type checkpointScan struct {
+	checkpoint *Checkpoint
+	root       string
+	// resumed are the top-level folders finished by an earlier scan
+	resumed map[string][]Finding
+
+	mu      sync.Mutex
+	pending map[string][]Finding // being scanned, collecting findings
+}
+
+// begin starts a scan of the library at root with options, taking over what
+// an earlier scan with the same options finished if c.Resume is set and the
+// scan can do without reading those folders again.
+func (c *Checkpoint) begin(root, options string, resume bool) *checkpointScan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scan := &checkpointScan{checkpoint: c, root: root, pending: map[string][]Finding{}}
+	library := &libraryCheckpoint{Options: options, Folders: map[string][]Finding{}}
+	if previous := c.libraries[root]; resume && c.Resume && previous != nil && previous.Options == options {
+		scan.resumed = previous.Folders
+		for path, findings := range previous.Folders {
+			library.Folders[path] = findings
+		}
+	}
+	c.libraries[root] = library
+	return scan
+}
+
+// forget drops the top-level folder dir from what the scan resumes, so that
+// it is scanned again.
+func (t *checkpointScan) forget(dir string) {
+	delete(t.resumed, dir)
+	c := t.checkpoint
+	c.mu.Lock()
+	delete(c.libraries[t.root].Folders, dir)
+	c.mu.Unlock()
+}
+
+// stillCurrent reports whether the items that findings recorded by an earlier
+// scan would delete are as that scan saw them: still there, with nothing
+// below them modified since. A video added to an orphaned folder after the
+// checkpoint was saved makes its top-level folder be scanned again rather
+// than resumed, so the folder is not deleted on stale findings.
+func (r *scanRun) stillCurrent(findings []Finding) bool {
+	for _, f := range findings {
+		switch f.Category {
+		case CategoryOrphanedFolder, CategoryOrphanedFile, CategoryEmptyFolder:
+		default:
+			continue
+		}
+		newest, ok := newestModTime(r.fsys, f.Path)
+		if !ok || f.Modified == nil || !newest.Equal(*f.Modified) {
+			return false
+		}
+	}
+	return true
+}
+
+// start begins collecting the findings of the top-level folder dir.
+func (t *checkpointScan) start(dir string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[dir] = []Finding{}
+}
+
+// finish records the top-level folder dir as done if it was read in full,
+// and saves the checkpoint if it is due.
+func (t *checkpointScan) finish(dir string, complete bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	findings := t.pending[dir]
+	delete(t.pending, dir)
+	t.mu.Unlock()
+	if !complete {
+		return
+	}
+	c := t.checkpoint
+	c.mu.Lock()
+	c.libraries[t.root].Folders[dir] = findings
+	c.mu.Unlock()
+	c.saveDue()
+}
+
+// end forgets the library once it has been scanned completely, saving the
+// checkpoint if it had been saved with the library in it.
+func (t *checkpointScan) end(complete bool) {
+	if t == nil || !complete {
+		return
+	}
+	c := t.checkpoint
+	c.mu.Lock()
+	delete(c.libraries, t.root)
+	onDisk := c.onDisk
+	c.mu.Unlock()
+	if onDisk {
+		if err := c.save(); err != nil {
+			c.mu.Lock()
+			if c.err == nil {
+				c.err = err
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// record adds f to the top-level folder being scanned that holds it, if any.
+func (t *checkpointScan) record(f Finding) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for path, findings := range t.pending {
+		if f.Path == path || strings.HasPrefix(f.Path, path+string(filepath.Separator)) {
+			t.pending[path] = append(findings, f)
+			return
+		}
+	}
+}
+
+// failedUnder reports whether an error recorded so far may concern dir or a
+// folder below it. Errors that do not name a folder count for every one.
+func (r *scanRun) failedUnder(dir string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, err := range r.errs {
+		var unreadable *ErrUnreadableDir
+		if !errors.As(err, &unreadable) || IsWithin(dir, unreadable.Path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Tests for WithCheckpoint
+// ============================================================================
+
+// checkpointLibrary creates a library with two studios, each holding an
+// orphaned title folder.
+func checkpointLibrary(t *testing.T) string {
+	t.Helper()
+	dir := setupTestDir(t)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	createFile(t, filepath.Join(dir, "Studio A", "Orphan (2001)", "movie.nfo"))
+	createFile(t, filepath.Join(dir, "Studio B", "Orphan (2002)", "movie.nfo"))
+	return dir
+}
+
+// interruptedScan scans dir with checkpoint until the first studio is done,
+// as a scan stopped by a reboot would.
+func interruptedScan(t *testing.T, dir string, checkpoint *Checkpoint) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scanner := NewScanner(WithWorkers(1), WithCheckpoint(checkpoint), WithProgress(func(ev ProgressEvent) {
+		if _, ok := ev.(StudioScanned); ok {
+			cancel()
+		}
+	}))
+	if err := scanner.Scan(ctx, dir, func(Finding) error { return nil }); err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+}
+
+func TestCheckpoint_ResumesInterruptedScan(t *testing.T) {
+	dir := checkpointLibrary(t)
+	path := filepath.Join(dir, "checkpoint.json")
+	checkpoint, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint returned error: %v", err)
+	}
+	checkpoint.Interval = time.Nanosecond
+	interruptedScan(t, dir, checkpoint)
+	if err := checkpoint.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	checkpoint, err = LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint returned error: %v", err)
+	}
+	if n := len(checkpoint.libraries[dir].Folders); n != 1 {
+		t.Fatalf("Expected 1 finished studio recorded, got %d", n)
+	}
+	checkpoint.Resume = true
+	fsys := &countingFS{FS: osFS{}, listings: map[string]int{}}
+	var started LibraryStarted
+	result := &CleanupResult{}
+	scanner := NewScanner(WithFS(fsys), WithCheckpoint(checkpoint), WithProgress(func(ev ProgressEvent) {
+		if ev, ok := ev.(LibraryStarted); ok {
+			started = ev
+		}
+	}))
+	if err := scanner.Scan(context.Background(), dir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.OrphanedFolders) != 2 {
+		t.Errorf("Expected both orphans reported, got %v", result.OrphanedFolders)
+	}
+	if started.Resumed != 1 || started.Studios != 2 {
+		t.Errorf("Expected 1 of 2 studios resumed, got %+v", started)
+	}
+	listed := 0
+	for _, studio := range []string{"Studio A", "Studio B"} {
+		if fsys.listings[filepath.Join(dir, studio)] > 0 {
+			listed++
+		}
+	}
+	if listed != 1 {
+		t.Errorf("Expected only the unfinished studio listed, got %d", listed)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the checkpoint removed once the scan completed, got %v", err)
+	}
+}
+
+func TestCheckpoint_StartsOverWithoutResume(t *testing.T) {
+	dir := checkpointLibrary(t)
+	checkpoint := &Checkpoint{Path: filepath.Join(dir, "checkpoint.json"), Interval: time.Nanosecond, libraries: map[string]*libraryCheckpoint{}}
+	interruptedScan(t, dir, checkpoint)
+
+	for name, opts := range map[string][]Option{
+		"no --resume":     nil,
+		"options changed": {WithExtensions(".mkv", ".nfo")},
+		"duplicates":      {WithDuplicateDetection(true)},
+	} {
+		checkpoint.Resume = name != "no --resume"
+		var started LibraryStarted
+		opts = append(opts, WithCheckpoint(checkpoint), WithProgress(func(ev ProgressEvent) {
+			if ev, ok := ev.(LibraryStarted); ok {
+				started = ev
+			}
+		}))
+		if err := NewScanner(opts...).Scan(context.Background(), dir, func(Finding) error { return nil }); err != nil {
+			t.Fatalf("%s: Scan returned error: %v", name, err)
+		}
+		if started.Resumed != 0 {
+			t.Errorf("%s: Expected every studio scanned again, got %+v", name, started)
+		}
+		// The complete scan forgot the library; record it again
+		interruptedScan(t, dir, checkpoint)
+	}
+}
+
+func TestCheckpoint_RescansChangedFolders(t *testing.T) {
+	dir := checkpointLibrary(t)
+	checkpoint := &Checkpoint{Path: filepath.Join(dir, "checkpoint.json"), Interval: time.Nanosecond, libraries: map[string]*libraryCheckpoint{}}
+	interruptedScan(t, dir, checkpoint)
+	var finished string
+	for studio := range checkpoint.libraries[dir].Folders {
+		finished = studio
+	}
+	if finished == "" {
+		t.Fatal("Expected a finished studio recorded")
+	}
+	// The orphan recorded as finished got its video since
+	orphans, err := filepath.Glob(filepath.Join(finished, "Orphan *"))
+	if err != nil || len(orphans) != 1 {
+		t.Fatalf("Expected 1 orphan in %s, got %v (%v)", finished, orphans, err)
+	}
+	createFile(t, filepath.Join(orphans[0], "movie.mkv"))
+
+	checkpoint.Resume = true
+	var started LibraryStarted
+	result := &CleanupResult{}
+	scanner := NewScanner(WithCheckpoint(checkpoint), WithProgress(func(ev ProgressEvent) {
+		if ev, ok := ev.(LibraryStarted); ok {
+			started = ev
+		}
+	}))
+	if err := scanner.Scan(context.Background(), dir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if started.Resumed != 0 {
+		t.Errorf("Expected the changed studio scanned again, got %+v", started)
+	}
+	if len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] == orphans[0] {
+		t.Errorf("Expected only the other orphan reported, got %v", result.OrphanedFolders)
+	}
+}
+
+func TestCheckpoint_ShortScanWritesNothing(t *testing.T) {
+	dir := checkpointLibrary(t)
+	path := filepath.Join(dir, "checkpoint.json")
+	checkpoint, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint returned error: %v", err)
+	}
+	if err := NewScanner(WithCheckpoint(checkpoint)).Scan(context.Background(), dir, func(Finding) error { return nil }); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if err := checkpoint.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no checkpoint after a complete scan, got %v", err)
+	}
+}
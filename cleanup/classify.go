@@ -0,0 +1,163 @@
+package cleanup
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Default video extensions, used unless WithExtensions or WithClassifier is given
+var videoExtensions = map[string]bool{
+	".mkv": true,
+	".mp4": true,
+	".avi": true,
+	".m4v": true,
+	// Disc images: a full Blu-ray or DVD ISO is played like a video
+	".iso": true,
+}
+
+// Default stub extensions, used unless WithStubExtensions is given: Kodi and
+// Jellyfin play the stream a .strm file links to as the title's video
+var stubExtensions = map[string]bool{
+	".strm": true,
+}
+
+// isDiscImage reports whether name is a disc image, which is counted as a
+// video but has no container for a VideoProber to read.
+func isDiscImage(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".iso")
+}
+
+// DefaultExtensions returns the video extensions recognized unless
+// WithExtensions or WithClassifier is given, sorted.
+func DefaultExtensions() []string {
+	var exts []string
+	for ext := range videoExtensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// ParseExtensions splits a comma-separated list of video extensions such as
+// "ts, .webm,WMV" for WithExtensions. Extensions may omit the leading dot but
+// cannot contain another dot or a path separator, since only the part of a
+// file name after its last dot is compared.
+func ParseExtensions(list string) ([]string, error) {
+	var exts []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+		if ext == "" {
+			continue
+		}
+		if strings.ContainsAny(ext, `./\`) {
+			return nil, fmt.Errorf("invalid video extension %q", ext)
+		}
+		exts = append(exts, "."+ext)
+	}
+	return exts, nil
+}
+
+// Known metadata subdirectory suffixes that are expected in title folders
+var metadataSubdirSuffixes = []string{
+	".trickplay",
+}
+
+// Known metadata subdirectory names that are expected in title folders:
+// artwork and actor thumbnails of Kodi, Emby and Jellyfin
+var metadataSubdirNames = []string{
+	"extrafanart",
+	"extrathumbs",
+	"backdrops",
+	".actors",
+}
+
+// Extras subfolder names recognized by Emby and Jellyfin inside title folders
+var extrasDirNames = map[string]bool{
+	"extras":            true,
+	"trailers":          true,
+	"behind the scenes": true,
+	"deleted scenes":    true,
+	"featurettes":       true,
+	"interviews":        true,
+	"scenes":            true,
+	"shorts":            true,
+	"clips":             true,
+	"samples":           true,
+	"other":             true,
+}
+
+// isExtrasDir reports whether name is a recognized extras subfolder.
+func isExtrasDir(name string) bool {
+	return extrasDirNames[strings.ToLower(name)]
+}
+
+// Folders holding an untouched Blu-ray or DVD backup, played as a whole by
+// Kodi, Plex, Emby and Jellyfin
+var discDirNames = map[string]bool{
+	"bdmv":     true,
+	"video_ts": true,
+}
+
+// EntryKind is what a Classifier decides a directory entry is.
+type EntryKind int
+
+const (
+	// KindMetadata is any non-video file: .nfo, artwork, subtitles, etc.
+	KindMetadata EntryKind = iota
+	// KindVideo is a video file; its presence keeps a title folder alive.
+	KindVideo
+	// KindMetadataDir is a subdirectory that belongs to a video, e.g. movie.trickplay.
+	KindMetadataDir
+	// KindUnexpectedDir is any other subdirectory.
+	KindUnexpectedDir
+	// KindDiscDir is the folder structure of a disc backup, e.g. BDMV or
+	// VIDEO_TS; like a video, its presence keeps a title folder alive.
+	KindDiscDir
+)
+
+// Classifier decides what kind of entry a file or directory name is.
+type Classifier interface {
+	Classify(name string, isDir bool) EntryKind
+}
+
+// ClassifierFunc adapts a plain function to the Classifier interface.
+type ClassifierFunc func(name string, isDir bool) EntryKind
+
+// Classify calls f(name, isDir).
+func (f ClassifierFunc) Classify(name string, isDir bool) EntryKind {
+	return f(name, isDir)
+}
+
+// extensionClassifier is the default Classifier: videos and stubs are
+// recognized by extension and metadata directories by name or suffix, all
+// case-insensitively.
+type extensionClassifier struct {
+	extensions             map[string]bool
+	stubExtensions         map[string]bool
+	metadataSubdirSuffixes []string
+	metadataSubdirNames    map[string]bool
+}
+
+func (c extensionClassifier) Classify(name string, isDir bool) EntryKind {
+	if isDir {
+		lower := nameKey(name)
+		if c.metadataSubdirNames[lower] {
+			return KindMetadataDir
+		}
+		for _, suffix := range c.metadataSubdirSuffixes {
+			if strings.HasSuffix(lower, suffix) {
+				return KindMetadataDir
+			}
+		}
+		if discDirNames[lower] {
+			return KindDiscDir
+		}
+		return KindUnexpectedDir
+	}
+	if ext := strings.ToLower(filepath.Ext(name)); c.extensions[ext] || c.stubExtensions[ext] {
+		return KindVideo
+	}
+	return KindMetadata
+}
@@ -0,0 +1,1394 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// Helper function to create a test directory structure
+func setupTestDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "video-cleanup-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	return dir
+}
+
+// Helper to create a file
+func createFile(t *testing.T, path string) {
+	t.Helper()
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory %s: %v", dir, err)
+	}
+	if err := os.WriteFile(path, []byte("test content"), 0644); err != nil {
+		t.Fatalf("Failed to create file %s: %v", path, err)
+	}
+}
+
+// Helper to call scanner internals directly, collecting findings into result
+func newTestRun(s *Scanner, result *CleanupResult) *scanRun {
+	return &scanRun{Scanner: s, ctx: context.Background(), fsys: s.fsys, emit: result.add}
+}
+
+// Helper to create a directory
+func createDir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatalf("Failed to create directory %s: %v", path, err)
+	}
+}
+
+// ============================================================================
+// Tests for videoExtensions map
+// ============================================================================
+
+func TestVideoExtensions(t *testing.T) {
+	tests := []struct {
+		ext      string
+		expected bool
+	}{
+		{".mkv", true},
+		{".mp4", true},
+		{".avi", true},
+		{".m4v", true},
+		{".iso", true},
+		{".txt", false},
+		{".nfo", false},
+		{".jpg", false},
+		{".srt", false},
+		{".MKV", false}, // Case sensitive - extensions should be lowercased before lookup
+		{"mkv", false},  // Missing dot
+		{"", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.ext, func(t *testing.T) {
+			result := videoExtensions[tc.ext]
+			if result != tc.expected {
+				t.Errorf("videoExtensions[%q] = %v, want %v", tc.ext, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	exts, err := ParseExtensions(" ts, .webm,WMV,,mpg ")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := []string{".ts", ".webm", ".WMV", ".mpg"}
+	if len(exts) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, exts)
+	}
+	for i := range expected {
+		if exts[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, exts)
+			break
+		}
+	}
+
+	for _, list := range []string{"tar.gz", "a/b", `a\b`} {
+		if _, err := ParseExtensions(list); err == nil {
+			t.Errorf("Expected an error for %q", list)
+		}
+	}
+}
+
+func TestParseExtensions_AddedExtensionIsVideo(t *testing.T) {
+	exts, err := ParseExtensions("ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := cleanuptest.New().Studio("Studio").Title("Recording (2021)").Video("recording.TS").Metadata("movie.nfo").MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithExtensions(append(exts, ".mkv")...)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Expected .ts title to be kept, got orphaned %v", result.OrphanedFolders)
+	}
+}
+
+// ============================================================================
+// Tests for isDirEmpty
+// ============================================================================
+
+func TestIsDirEmpty_EmptyDirectory(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	emptyDir := filepath.Join(tempDir, "empty")
+	createDir(t, emptyDir)
+
+	isEmpty, err := isDirEmpty(osFS{}, emptyDir)
+	if err != nil {
+		t.Fatalf("isDirEmpty returned error: %v", err)
+	}
+	if !isEmpty {
+		t.Error("isDirEmpty should return true for empty directory")
+	}
+}
+
+func TestIsDirEmpty_NonEmptyDirectory(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	nonEmptyDir := filepath.Join(tempDir, "nonempty")
+	createFile(t, filepath.Join(nonEmptyDir, "file.txt"))
+
+	isEmpty, err := isDirEmpty(osFS{}, nonEmptyDir)
+	if err != nil {
+		t.Fatalf("isDirEmpty returned error: %v", err)
+	}
+	if isEmpty {
+		t.Error("isDirEmpty should return false for non-empty directory")
+	}
+}
+
+func TestIsDirEmpty_DirectoryWithSubdir(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	parentDir := filepath.Join(tempDir, "parent")
+	createDir(t, filepath.Join(parentDir, "child"))
+
+	isEmpty, err := isDirEmpty(osFS{}, parentDir)
+	if err != nil {
+		t.Fatalf("isDirEmpty returned error: %v", err)
+	}
+	if isEmpty {
+		t.Error("isDirEmpty should return false for directory with subdirectory")
+	}
+}
+
+func TestIsDirEmpty_NonExistentDirectory(t *testing.T) {
+	_, err := isDirEmpty(osFS{}, "/nonexistent/path/that/does/not/exist")
+	if err == nil {
+		t.Error("isDirEmpty should return error for non-existent directory")
+	}
+}
+
+// ============================================================================
+// Tests for checkDirectChildren
+// ============================================================================
+
+func TestCheckDirectChildren_NoFiles(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// Create directory with only subdirectories
+	createDir(t, filepath.Join(tempDir, "subdir1"))
+	createDir(t, filepath.Join(tempDir, "subdir2"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren(tempDir, "library")
+
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected 0 warnings, got %d: %v", len(result.StructureWarnings), result.StructureWarnings)
+	}
+}
+
+func TestCheckDirectChildren_WithFiles(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// Create directory with files (structure violation) - no matching video
+	createFile(t, filepath.Join(tempDir, "file1.txt"))
+	createFile(t, filepath.Join(tempDir, "file2.nfo"))
+	createDir(t, filepath.Join(tempDir, "subdir"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren(tempDir, "library")
+
+	// Files without matching video are orphaned files, not warnings
+	if len(result.OrphanedFiles) != 2 {
+		t.Errorf("Expected 2 orphaned files for files at library level, got %d", len(result.OrphanedFiles))
+	}
+}
+
+func TestCheckDirectChildren_WithVideoAndMetadata(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// Create video file and matching metadata at library level
+	createFile(t, filepath.Join(tempDir, "movie.mkv"))
+	createFile(t, filepath.Join(tempDir, "movie.nfo"))
+	createFile(t, filepath.Join(tempDir, "movie-poster.jpg"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren(tempDir, "library")
+
+	// Video and its metadata at wrong level generate warnings (not orphaned)
+	if len(result.StructureWarnings) != 3 {
+		t.Errorf("Expected 3 warnings for video+metadata at library level, got %d: %v",
+			len(result.StructureWarnings), result.StructureWarnings)
+	}
+	if len(result.OrphanedFiles) != 0 {
+		t.Errorf("Expected 0 orphaned files (video present), got %d", len(result.OrphanedFiles))
+	}
+}
+
+func TestCheckDirectChildren_OrphanedMetadata(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// Create metadata files with no matching video (orphaned)
+	createFile(t, filepath.Join(tempDir, "deleted-movie.nfo"))
+	createFile(t, filepath.Join(tempDir, "deleted-movie-poster.jpg"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren(tempDir, "library")
+
+	// Metadata without matching video are orphaned
+	if len(result.OrphanedFiles) != 2 {
+		t.Errorf("Expected 2 orphaned files, got %d", len(result.OrphanedFiles))
+	}
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected 0 warnings, got %d", len(result.StructureWarnings))
+	}
+}
+
+func TestCheckDirectChildren_MixedOrphanedAndMatching(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// Mix of: video+metadata (warnings) and orphaned metadata (orphaned files)
+	createFile(t, filepath.Join(tempDir, "existing.mkv"))
+	createFile(t, filepath.Join(tempDir, "existing.nfo"))       // matches video
+	createFile(t, filepath.Join(tempDir, "deleted.nfo"))        // orphaned
+	createFile(t, filepath.Join(tempDir, "deleted-poster.jpg")) // orphaned
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren(tempDir, "library")
+
+	// existing.mkv and existing.nfo generate warnings
+	if len(result.StructureWarnings) != 2 {
+		t.Errorf("Expected 2 warnings, got %d: %v", len(result.StructureWarnings), result.StructureWarnings)
+	}
+	// deleted.nfo and deleted-poster.jpg are orphaned
+	if len(result.OrphanedFiles) != 2 {
+		t.Errorf("Expected 2 orphaned files, got %d: %v", len(result.OrphanedFiles), result.OrphanedFiles)
+	}
+}
+
+func TestCheckDirectChildren_MultiPartVideo(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// The metadata of a multi-part video is named after the whole video
+	createFile(t, filepath.Join(tempDir, "movie-cd1.mkv"))
+	createFile(t, filepath.Join(tempDir, "movie - part2.mkv"))
+	createFile(t, filepath.Join(tempDir, "movie.nfo"))
+	createFile(t, filepath.Join(tempDir, "movie-poster.jpg"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren(tempDir, "studio")
+
+	if len(result.OrphanedFiles) != 0 {
+		t.Errorf("Expected 0 orphaned files (parts present), got %d: %v", len(result.OrphanedFiles), result.OrphanedFiles)
+	}
+	for _, f := range result.Findings {
+		if expected := filepath.Join(tempDir, "movie"); f.Target != expected {
+			t.Errorf("Expected %s to move to %s, got %q", f.Path, expected, f.Target)
+		}
+	}
+}
+
+func TestCheckDirectChildren_CaseInsensitiveMatch(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// Lowercasing alone turns the final sigma of one name into a medial one
+	createFile(t, filepath.Join(tempDir, "Οδυσσευς.mkv"))
+	createFile(t, filepath.Join(tempDir, "ΟΔΥΣΣΕΥΣ.NFO"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren(tempDir, "studio")
+
+	if len(result.OrphanedFiles) != 0 {
+		t.Errorf("Expected the NFO to match its video, got orphaned %v", result.OrphanedFiles)
+	}
+}
+
+func TestCheckDirectChildren_DecomposedNames(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// Written from macOS: decomposed é in the NFO, precomposed in the video
+	createFile(t, filepath.Join(tempDir, "Am\u00E9lie.mkv"))
+	createFile(t, filepath.Join(tempDir, "Ame\u0301lie.nfo"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren(tempDir, "studio")
+
+	if len(result.OrphanedFiles) != 0 {
+		t.Errorf("Expected the NFO to match its video, got orphaned %v", result.OrphanedFiles)
+	}
+}
+
+func TestCheckDirectChildren_NonExistentDir(t *testing.T) {
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).checkDirectChildren("/nonexistent/path", "library")
+
+	// Should not panic and should not add warnings for non-existent dir
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected 0 warnings for non-existent dir, got %d", len(result.StructureWarnings))
+	}
+}
+
+// ============================================================================
+// Tests for processTitleFolder
+// ============================================================================
+
+func TestProcessTitleFolder_WithVideoFile(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	createFile(t, filepath.Join(titleDir, "movie.mkv"))
+	createFile(t, filepath.Join(titleDir, "movie.nfo"))
+	createFile(t, filepath.Join(titleDir, "poster.jpg"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Expected no orphaned folders, got %d", len(result.OrphanedFolders))
+	}
+	if len(result.EmptyFolders) != 0 {
+		t.Errorf("Expected no empty folders, got %d", len(result.EmptyFolders))
+	}
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings, got %d", len(result.StructureWarnings))
+	}
+}
+
+func TestProcessTitleFolder_OrphanedMetadata(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	// Create metadata files but no video file
+	createFile(t, filepath.Join(titleDir, "movie.nfo"))
+	createFile(t, filepath.Join(titleDir, "poster.jpg"))
+	createFile(t, filepath.Join(titleDir, "fanart.jpg"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %d", len(result.OrphanedFolders))
+	}
+	if len(result.OrphanedFolders) > 0 && result.OrphanedFolders[0] != titleDir {
+		t.Errorf("Orphaned folder path mismatch: got %s, want %s", result.OrphanedFolders[0], titleDir)
+	}
+}
+
+func TestProcessTitleFolder_Empty(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	createDir(t, titleDir)
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.EmptyFolders) != 1 {
+		t.Errorf("Expected 1 empty folder, got %d", len(result.EmptyFolders))
+	}
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Expected no orphaned folders, got %d", len(result.OrphanedFolders))
+	}
+}
+
+func TestProcessTitleFolder_WithSubdirectory(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	createFile(t, filepath.Join(titleDir, "movie.mkv"))
+	createDir(t, filepath.Join(titleDir, "extras"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.StructureWarnings) != 1 {
+		t.Errorf("Expected 1 warning for subdirectory, got %d", len(result.StructureWarnings))
+	}
+}
+
+func TestProcessTitleFolder_WithTrickplaySubdirectory(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	createFile(t, filepath.Join(titleDir, "movie.mkv"))
+	createDir(t, filepath.Join(titleDir, "movie.trickplay"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings for .trickplay subdirectory, got %d: %v",
+			len(result.StructureWarnings), result.StructureWarnings)
+	}
+}
+
+func TestProcessTitleFolder_MixedSubdirectories(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	createFile(t, filepath.Join(titleDir, "movie.mkv"))
+	createDir(t, filepath.Join(titleDir, "movie.trickplay")) // Expected metadata subdir
+	createDir(t, filepath.Join(titleDir, "extras"))          // Unexpected subdir
+	createDir(t, filepath.Join(titleDir, "featurettes"))     // Unexpected subdir
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.StructureWarnings) != 2 {
+		t.Errorf("Expected 2 warnings for unexpected subdirectories, got %d: %v",
+			len(result.StructureWarnings), result.StructureWarnings)
+	}
+}
+
+func TestProcessTitleFolder_OnlyTrickplayNoVideo(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	// Only .trickplay folder, no video - should be orphaned
+	createDir(t, filepath.Join(titleDir, "movie.trickplay"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings for .trickplay, got %d: %v",
+			len(result.StructureWarnings), result.StructureWarnings)
+	}
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected folder with only .trickplay to be orphaned, got %d orphaned",
+			len(result.OrphanedFolders))
+	}
+}
+
+func TestProcessTitleFolder_TrickplayWithMetadataNoVideo(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	// .trickplay folder + metadata files but no video - should be orphaned
+	createDir(t, filepath.Join(titleDir, "movie.trickplay"))
+	createFile(t, filepath.Join(titleDir, "movie.nfo"))
+	createFile(t, filepath.Join(titleDir, "poster.jpg"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings, got %d: %v",
+			len(result.StructureWarnings), result.StructureWarnings)
+	}
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected folder to be orphaned, got %d orphaned",
+			len(result.OrphanedFolders))
+	}
+}
+
+func TestProcessTitleFolder_TrickplayOfMissingVideo(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	// Re-encoded under a new name: the old trickplay folder stays behind
+	createFile(t, filepath.Join(titleDir, "Movie-1080p.mkv"))
+	createFile(t, filepath.Join(titleDir, "movie.trickplay", "1.jpg"))
+	createDir(t, filepath.Join(titleDir, "movie-1080p.trickplay"))
+	createDir(t, filepath.Join(titleDir, "extrafanart"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Fatalf("Expected 1 orphaned folder, got %d: %v", len(result.OrphanedFolders), result.OrphanedFolders)
+	}
+	if expected := filepath.Join(titleDir, "movie.trickplay"); result.OrphanedFolders[0] != expected {
+		t.Errorf("Expected %s, got %s", expected, result.OrphanedFolders[0])
+	}
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", result.StructureWarnings)
+	}
+}
+
+func TestProcessTitleFolder_AllVideoFormats(t *testing.T) {
+	formats := []string{".mkv", ".mp4", ".avi", ".m4v", ".iso"}
+
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			tempDir := setupTestDir(t)
+			defer os.RemoveAll(tempDir)
+
+			titleDir := filepath.Join(tempDir, "title")
+			createFile(t, filepath.Join(titleDir, "movie"+format))
+
+			result := &CleanupResult{}
+			newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+			if len(result.OrphanedFolders) != 0 {
+				t.Errorf("Video format %s should be recognized, but folder was marked orphaned", format)
+			}
+		})
+	}
+}
+
+func TestProcessTitleFolder_CaseInsensitiveExtension(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	createFile(t, filepath.Join(titleDir, "movie.MKV")) // Uppercase extension
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.OrphanedFolders) != 0 {
+		t.Error("Uppercase video extension should be recognized")
+	}
+}
+
+func TestProcessTitleFolder_MixedCaseExtension(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	createFile(t, filepath.Join(titleDir, "movie.Mkv")) // Mixed case
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processTitleFolder(titleDir)
+
+	if len(result.OrphanedFolders) != 0 {
+		t.Error("Mixed case video extension should be recognized")
+	}
+}
+
+func TestProcessTitleFolder_DiscBackup(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	bluray := filepath.Join(tempDir, "Blu-ray (2001)")
+	createFile(t, filepath.Join(bluray, "BDMV", "STREAM", "00001.m2ts"))
+	createFile(t, filepath.Join(bluray, "movie.nfo"))
+	dvd := filepath.Join(tempDir, "DVD (2002)")
+	createFile(t, filepath.Join(dvd, "video_ts", "VTS_01_1.VOB"))
+	misfiled := filepath.Join(tempDir, "Misfiled (2003)")
+	createFile(t, filepath.Join(misfiled, "Extras", "BDMV", "index.bdmv"))
+
+	result := &CleanupResult{}
+	run := newTestRun(NewScanner(), result)
+	for _, titleDir := range []string{bluray, dvd, misfiled} {
+		run.processTitleFolder(titleDir)
+	}
+
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Expected disc backups to keep their folders, got %v", result.OrphanedFolders)
+	}
+	// Extras folders are always warned about; disc folders are not
+	if len(result.StructureWarnings) != 1 {
+		t.Errorf("Expected only the Extras folder warned about, got %v", result.StructureWarnings)
+	}
+	if misfiledVideos := result.ByCategory(CategoryMisfiledVideo); len(misfiledVideos) != 1 || misfiledVideos[0].Path != misfiled {
+		t.Errorf("Expected %s reported as misfiled, got %v", misfiled, misfiledVideos)
+	}
+}
+
+func TestScan_DownloadInProgress(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Downloading (2001)").Metadata("movie.nfo", "movie.mkv.part").
+		Title("Upgrading (2002)").Video("movie.mkv").Metadata("movie-2160p.mkv.!qB").
+		Title("Stale (2003)").Metadata("movie.nfo").
+		MapFS()
+	fsys["Studio/video.mp4.crdownload"] = &fstest.MapFile{}
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join("Studio", "Downloading (2001)"),
+		filepath.Join("Studio", "Upgrading (2002)"),
+		filepath.Join("Studio", "video.mp4.crdownload"),
+	}
+	var inProgress []string
+	for _, f := range result.ByCategory(CategoryDownloadInProgress) {
+		inProgress = append(inProgress, f.Path)
+	}
+	sort.Strings(inProgress)
+	if strings.Join(inProgress, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v in progress, got %v", expected, inProgress)
+	}
+	if stale := filepath.Join("Studio", "Stale (2003)"); len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] != stale {
+		t.Errorf("Expected only %s orphaned, got %v", stale, result.OrphanedFolders)
+	}
+	if len(result.OrphanedFiles) != 0 {
+		t.Errorf("Expected no orphaned files, got %v", result.OrphanedFiles)
+	}
+}
+
+func TestScan_IgnoresJunk(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Junk Only (2001)").Metadata(".DS_Store", "Thumbs.db", "._movie.nfo").
+		Title("Orphan (2002)").Metadata("movie.nfo", "desktop.ini").
+		Title("Movie (2003)").Video("movie.mkv").Metadata("._movie.mkv").
+		Studio("Junk Studio").
+		MapFS()
+	fsys["Junk Studio/.DS_Store"] = &fstest.MapFile{}
+	fsys["Studio/Thumbs.db"] = &fstest.MapFile{}
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := []string{"Junk Studio", filepath.Join("Studio", "Junk Only (2001)")}
+	empty := append([]string{}, result.EmptyFolders...)
+	sort.Strings(empty)
+	if strings.Join(empty, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v empty, got %v", expected, empty)
+	}
+	if orphan := filepath.Join("Studio", "Orphan (2002)"); len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] != orphan {
+		t.Errorf("Expected only %s orphaned, got %v", orphan, result.OrphanedFolders)
+	}
+	if len(result.OrphanedFiles) != 0 {
+		t.Errorf("Expected no orphaned files, got %v", result.OrphanedFiles)
+	}
+}
+
+func TestScan_JunkFiles(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Movie (2001)").Video("movie.mkv").Metadata("Thumbs.db").
+		Title("Orphan (2002)").Metadata("movie.nfo", "._movie.nfo").
+		MapFS()
+	fsys[".DS_Store"] = &fstest.MapFile{Data: []byte("ds")}
+	fsys["Studio/Movie (2001)/Extras/.DS_Store"] = &fstest.MapFile{}
+	fsys["Studio/#recycle/Thumbs.db"] = &fstest.MapFile{}
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithJunkFiles(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	var junk []string
+	for _, f := range result.ByCategory(CategoryJunkFile) {
+		junk = append(junk, f.Path)
+		if f.Path == ".DS_Store" && f.Bytes != 2 {
+			t.Errorf("Expected .DS_Store to hold 2 bytes, got %d", f.Bytes)
+		}
+	}
+	sort.Strings(junk)
+	expected := []string{
+		".DS_Store",
+		filepath.Join("Studio", "Movie (2001)", "Extras", ".DS_Store"),
+		filepath.Join("Studio", "Movie (2001)", "Thumbs.db"),
+		filepath.Join("Studio", "Orphan (2002)", "._movie.nfo"),
+	}
+	if strings.Join(junk, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected junk files %v, got %v", expected, junk)
+	}
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected the orphan to be reported as well, got %v", result.OrphanedFolders)
+	}
+
+	// Off by default
+	result = &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if junk := result.ByCategory(CategoryJunkFile); len(junk) != 0 {
+		t.Errorf("Expected no junk files without WithJunkFiles, got %v", junk)
+	}
+}
+
+func TestScan_Categories(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").Video("stray.mkv").
+		Title("Movie (2001)").Video("movie.mkv").Dir("old.trickplay", "1.jpg").
+		Title("Orphan (2002)").Metadata("movie.nfo").
+		Title("Empty (2003)").
+		Title("Misfiled (2004)").Metadata("movie.nfo").Dir("Extras", "feature.mkv").
+		MapFS()
+
+	result := &CleanupResult{}
+	scanner := NewScanner(WithFS(IOFS(fsys)), WithCategories(CategoryEmptyFolder))
+	if err := scanner.Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	expected := filepath.Join("Studio", "Empty (2003)")
+	if len(result.Findings) != 1 || result.Findings[0].Path != expected {
+		t.Errorf("Expected only the empty folder %s, got %v", expected, result.Findings)
+	}
+
+	// A misfiled video left out still keeps its title from being orphaned
+	result = &CleanupResult{}
+	scanner = NewScanner(WithFS(IOFS(fsys)), WithCategories(CategoryOrphanedFolder, CategoryEmptyFolder))
+	if err := scanner.Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	sort.Strings(result.OrphanedFolders)
+	orphans := []string{
+		filepath.Join("Studio", "Movie (2001)", "old.trickplay"),
+		filepath.Join("Studio", "Orphan (2002)"),
+	}
+	if strings.Join(result.OrphanedFolders, "|") != strings.Join(orphans, "|") {
+		t.Errorf("Expected orphaned folders %v, got %v", orphans, result.OrphanedFolders)
+	}
+	if len(result.Findings) != 3 {
+		t.Errorf("Expected 2 orphaned folders and 1 empty folder, got %v", result.Findings)
+	}
+}
+
+func TestScan_Severities(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").Video("stray.mkv").Metadata("other.nfo").
+		Title("Movie (2001)").Video("movie.mkv").Dir("Random").
+		Title("Orphan (2002)").Metadata("movie.nfo").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	expected := map[string]Severity{
+		filepath.Join("Studio", "stray.mkv"):              SeverityError,
+		filepath.Join("Studio", "other.nfo"):              SeverityInfo,
+		filepath.Join("Studio", "Movie (2001)", "Random"): SeverityWarning,
+		filepath.Join("Studio", "Orphan (2002)"):          SeverityInfo,
+	}
+	if len(result.Findings) != len(expected) {
+		t.Errorf("Expected %d findings, got %v", len(expected), result.Findings)
+	}
+	for _, f := range result.Findings {
+		if f.Severity != expected[f.Path] {
+			t.Errorf("Expected severity %q for %s, got %q", expected[f.Path], f.Path, f.Severity)
+		}
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	if !SeverityError.AtLeast(SeverityWarning) || !SeverityWarning.AtLeast(SeverityWarning) || SeverityInfo.AtLeast(SeverityWarning) {
+		t.Error("Expected severities ordered info < warning < error")
+	}
+	if s := SeverityOf(Finding{Category: CategoryCorruptVideo}); s != SeverityError {
+		t.Errorf("Expected corrupt videos to default to error, got %q", s)
+	}
+	if s := SeverityOf(Finding{Category: CategoryStructureWarning, Severity: SeverityInfo}); s != SeverityInfo {
+		t.Errorf("Expected a finding's own severity to win, got %q", s)
+	}
+	var s Severity
+	if err := s.UnmarshalText([]byte("fatal")); err == nil {
+		t.Error("Expected an unknown severity to be rejected")
+	}
+}
+
+// ============================================================================
+// Tests for processContainer
+// ============================================================================
+
+func TestProcessContainer_ValidStructure(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	studioDir := filepath.Join(tempDir, "Studio A")
+	createFile(t, filepath.Join(studioDir, "Movie 1", "movie.mkv"))
+	createFile(t, filepath.Join(studioDir, "Movie 2", "movie.mp4"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processContainer(studioDir, 0)
+
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Expected no orphaned folders, got %d", len(result.OrphanedFolders))
+	}
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings, got %d: %v", len(result.StructureWarnings), result.StructureWarnings)
+	}
+}
+
+func TestProcessContainer_WithFilesAtStudioLevel(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	studioDir := filepath.Join(tempDir, "Studio A")
+	createFile(t, filepath.Join(studioDir, "Movie 1", "movie.mkv"))
+	createFile(t, filepath.Join(studioDir, "random.txt")) // File at studio level (no matching video)
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processContainer(studioDir, 0)
+
+	// File without matching video is orphaned
+	if len(result.OrphanedFiles) != 1 {
+		t.Errorf("Expected 1 orphaned file at studio level, got %d", len(result.OrphanedFiles))
+	}
+}
+
+func TestProcessContainer_MixedContent(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	studioDir := filepath.Join(tempDir, "Studio A")
+	// Valid title with video
+	createFile(t, filepath.Join(studioDir, "Movie 1", "movie.mkv"))
+	// Orphaned title (no video)
+	createFile(t, filepath.Join(studioDir, "Movie 2", "movie.nfo"))
+	// Empty title
+	createDir(t, filepath.Join(studioDir, "Movie 3"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(), result).processContainer(studioDir, 0)
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %d", len(result.OrphanedFolders))
+	}
+	if len(result.EmptyFolders) != 1 {
+		t.Errorf("Expected 1 empty folder, got %d", len(result.EmptyFolders))
+	}
+}
+
+// ============================================================================
+// Tests for scanLibrary
+// ============================================================================
+
+func TestScanLibrary_CompleteStructure(t *testing.T) {
+	libraryDir := cleanuptest.New().
+		// Studio 1 with valid movies
+		Studio("Studio1").
+		Title("Movie1").Video("movie.mkv").Metadata("movie.nfo").
+		Title("Movie2").Video("movie.mp4").
+		// Studio 2 with orphaned folder
+		Studio("Studio2").
+		Title("Movie3").Video("movie.avi").
+		Title("OrphanedMovie").Metadata("poster.jpg").
+		TempDir(t)
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %d", len(result.OrphanedFolders))
+	}
+}
+
+func TestScanLibrary_EmptyStudios(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	createDir(t, filepath.Join(libraryDir, "EmptyStudio"))
+	createFile(t, filepath.Join(libraryDir, "Studio1", "Movie1", "movie.mkv"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.EmptyFolders) != 1 {
+		t.Errorf("Expected 1 empty folder (empty studio), got %d", len(result.EmptyFolders))
+	}
+}
+
+func TestScanLibrary_FilesAtLibraryLevel(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	createFile(t, filepath.Join(libraryDir, "readme.txt")) // No matching video
+	createDir(t, filepath.Join(libraryDir, "Studio1"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	// File without matching video is orphaned
+	if len(result.OrphanedFiles) != 1 {
+		t.Errorf("Expected 1 orphaned file at library level, got %d", len(result.OrphanedFiles))
+	}
+}
+
+func TestScanLibrary_NonExistentPath(t *testing.T) {
+	result := &CleanupResult{}
+
+	// Should not panic, and should report the unreadable root
+	err := NewScanner(WithWorkers(4)).Scan(context.Background(), "/nonexistent/path/library", result.Add)
+
+	var unreadable *ErrUnreadableDir
+	if !errors.As(err, &unreadable) {
+		t.Fatalf("Expected *ErrUnreadableDir, got %v", err)
+	}
+	if unreadable.Path != "/nonexistent/path/library" {
+		t.Errorf("Expected error for library path, got %s", unreadable.Path)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected error to wrap fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestScanLibrary_FileInsteadOfDirectory(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	filePath := filepath.Join(tempDir, "notadirectory.txt")
+	createFile(t, filePath)
+
+	result := &CleanupResult{}
+
+	// Should not panic when given a file instead of directory
+	err := NewScanner(WithWorkers(4)).Scan(context.Background(), filePath, result.Add)
+
+	if !errors.Is(err, ErrNotADirectory) {
+		t.Errorf("Expected ErrNotADirectory, got %v", err)
+	}
+}
+
+func TestScanLibrary_ConcurrencyStress(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+
+	// Create many studios and titles to stress test concurrency
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 10; j++ {
+			titleDir := filepath.Join(libraryDir,
+				"Studio"+string(rune('A'+i)),
+				"Movie"+string(rune('0'+j)))
+			if j%3 == 0 {
+				// Orphaned folder
+				createFile(t, filepath.Join(titleDir, "metadata.nfo"))
+			} else if j%3 == 1 {
+				// Valid folder with video
+				createFile(t, filepath.Join(titleDir, "video.mkv"))
+			} else {
+				// Empty folder
+				createDir(t, titleDir)
+			}
+		}
+	}
+
+	result := &CleanupResult{}
+
+	// Test with different worker counts
+	for _, workers := range []int{1, 4, 10, 20, 50} {
+		result = &CleanupResult{}
+		if err := NewScanner(WithWorkers(workers)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+			t.Fatalf("Scan returned error: %v", err)
+		}
+
+		// Should have consistent results regardless of worker count
+		expectedOrphaned := 20 * 4 // 4 orphaned per studio (j % 3 == 0 for j=0,3,6,9)
+		expectedEmpty := 20 * 3    // 3 empty per studio (j % 3 == 2 for j=2,5,8)
+
+		if len(result.OrphanedFolders) != expectedOrphaned {
+			t.Errorf("Workers=%d: Expected %d orphaned folders, got %d",
+				workers, expectedOrphaned, len(result.OrphanedFolders))
+		}
+		if len(result.EmptyFolders) != expectedEmpty {
+			t.Errorf("Workers=%d: Expected %d empty folders, got %d",
+				workers, expectedEmpty, len(result.EmptyFolders))
+		}
+	}
+}
+
+// ============================================================================
+// Tests for context cancellation
+// ============================================================================
+
+func TestScanLibrary_CancelledContext(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	createFile(t, filepath.Join(libraryDir, "Studio1", "Orphaned", "movie.nfo"))
+	createDir(t, filepath.Join(libraryDir, "Studio2", "Empty"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := &CleanupResult{}
+	err := NewScanner(WithWorkers(4)).Scan(ctx, libraryDir, result.Add)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(result.OrphanedFolders) != 0 || len(result.EmptyFolders) != 0 {
+		t.Errorf("Expected no findings after cancellation, got %d orphaned, %d empty",
+			len(result.OrphanedFolders), len(result.EmptyFolders))
+	}
+}
+
+func TestScanLibrary_ExpiredDeadline(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	createFile(t, filepath.Join(libraryDir, "Studio1", "Movie1", "movie.mkv"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	result := &CleanupResult{}
+	err := NewScanner(WithWorkers(4)).Scan(ctx, libraryDir, result.Add)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// ============================================================================
+// Integration-style tests
+// ============================================================================
+
+func TestIntegration_RealisticLibraryStructure(t *testing.T) {
+	libraryDir := cleanuptest.New().
+		// Warner Bros studio
+		Studio("Warner Bros").
+		Title("The Matrix (1999)").Video("The Matrix.mkv").Metadata("The Matrix.nfo", "poster.jpg", "fanart.jpg").
+		// Deleted movie - only metadata remains
+		Title("Deleted Movie (2020)").Metadata("Deleted Movie.nfo", "poster.jpg").
+		// Universal studio
+		Studio("Universal").
+		Title("Jurassic Park (1993)").Video("Jurassic Park.mp4").Metadata("movie.nfo").
+		// Empty folder where movie was completely removed
+		Title("Gone Movie (2021)").
+		// Empty studio
+		Studio("Empty Studio").
+		TempDir(t)
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	// Verify orphaned folders
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %d: %v", len(result.OrphanedFolders), result.OrphanedFolders)
+	}
+
+	// Verify empty folders (title folder + empty studio)
+	if len(result.EmptyFolders) != 2 {
+		t.Errorf("Expected 2 empty folders, got %d: %v", len(result.EmptyFolders), result.EmptyFolders)
+	}
+
+	// Verify no structure warnings (everything follows expected structure)
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected 0 structure warnings, got %d: %v", len(result.StructureWarnings), result.StructureWarnings)
+	}
+}
+
+func TestIntegration_MultipleLibraries(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// Create two libraries
+	library1 := filepath.Join(tempDir, "Movies")
+	library2 := filepath.Join(tempDir, "TV Shows")
+
+	createFile(t, filepath.Join(library1, "Studio1", "Movie1", "movie.mkv"))
+	createFile(t, filepath.Join(library1, "Studio1", "OrphanedMovie", "poster.jpg"))
+
+	createFile(t, filepath.Join(library2, "Network1", "Show1", "show.mp4"))
+	createDir(t, filepath.Join(library2, "Network1", "EmptyShow"))
+
+	result := &CleanupResult{}
+
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), library1, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), library2, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder across libraries, got %d", len(result.OrphanedFolders))
+	}
+	if len(result.EmptyFolders) != 1 {
+		t.Errorf("Expected 1 empty folder across libraries, got %d", len(result.EmptyFolders))
+	}
+}
+
+// ============================================================================
+// Edge case tests
+// ============================================================================
+
+func TestEdgeCase_SpecialCharactersInNames(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+
+	// Folders with special characters
+	createFile(t, filepath.Join(libraryDir, "Studio's Name", "Movie & Title (2020)", "movie.mkv"))
+	createFile(t, filepath.Join(libraryDir, "Studio [HD]", "Movie - Part 1", "orphaned.nfo"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder with special chars, got %d", len(result.OrphanedFolders))
+	}
+}
+
+func TestEdgeCase_DeepNestedSubdirectories(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	titleDir := filepath.Join(libraryDir, "Studio", "Title")
+
+	createFile(t, filepath.Join(titleDir, "movie.mkv"))
+	// Create unexpected deep nesting
+	createFile(t, filepath.Join(titleDir, "extras", "behind_scenes", "video.mp4"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	// Should warn about subdirectory in title folder
+	if len(result.StructureWarnings) != 1 {
+		t.Errorf("Expected 1 warning for nested subdirectory, got %d: %v",
+			len(result.StructureWarnings), result.StructureWarnings)
+	}
+}
+
+func TestEdgeCase_OnlyHiddenFiles(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	titleDir := filepath.Join(libraryDir, "Studio", "Title")
+
+	// Create only hidden files (Unix-style, may not be hidden on Windows)
+	createFile(t, filepath.Join(titleDir, ".DS_Store"))
+	createFile(t, filepath.Join(titleDir, ".nfo"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	// Hidden files are still files, so this should be orphaned (no video)
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder with only hidden files, got %d", len(result.OrphanedFolders))
+	}
+}
+
+func TestEdgeCase_VideoFileWithMetadata(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	titleDir := filepath.Join(libraryDir, "Studio", "Title")
+
+	// Video file with lots of metadata files
+	createFile(t, filepath.Join(titleDir, "movie.mkv"))
+	createFile(t, filepath.Join(titleDir, "movie.nfo"))
+	createFile(t, filepath.Join(titleDir, "movie-poster.jpg"))
+	createFile(t, filepath.Join(titleDir, "movie-fanart.jpg"))
+	createFile(t, filepath.Join(titleDir, "movie-banner.jpg"))
+	createFile(t, filepath.Join(titleDir, "movie.srt"))
+	createFile(t, filepath.Join(titleDir, "movie.en.srt"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 0 {
+		t.Error("Folder with video and metadata should not be orphaned")
+	}
+	if len(result.EmptyFolders) != 0 {
+		t.Error("Folder with video should not be empty")
+	}
+}
+
+func TestEdgeCase_MultipleVideoFiles(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	titleDir := filepath.Join(libraryDir, "Studio", "Title")
+
+	// Multiple video files in same folder
+	createFile(t, filepath.Join(titleDir, "movie-cd1.avi"))
+	createFile(t, filepath.Join(titleDir, "movie-cd2.avi"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 0 {
+		t.Error("Folder with multiple video files should not be orphaned")
+	}
+}
+
+func TestEdgeCase_ZeroWorkers(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	createFile(t, filepath.Join(libraryDir, "Studio", "Title", "movie.mkv"))
+
+	result := &CleanupResult{}
+
+	// Zero workers is clamped to one rather than silently skipping studios
+	if err := NewScanner(WithWorkers(0)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	emptyTitle := filepath.Join(libraryDir, "Studio", "Empty")
+	createDir(t, emptyTitle)
+	result = &CleanupResult{}
+	if err := NewScanner(WithWorkers(-1)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.EmptyFolders) != 1 {
+		t.Errorf("Expected studios to be processed with fewer than one worker, got %d empty folders", len(result.EmptyFolders))
+	}
+}
+
+// ============================================================================
+// Test CleanupResult sorting for predictability
+// ============================================================================
+
+func TestCleanupResult_Sorting(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+
+	// Create folders that would be processed in unpredictable order
+	createFile(t, filepath.Join(libraryDir, "Zebra Studio", "Movie", "orphan.nfo"))
+	createFile(t, filepath.Join(libraryDir, "Alpha Studio", "Movie", "orphan.nfo"))
+	createFile(t, filepath.Join(libraryDir, "Middle Studio", "Movie", "orphan.nfo"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithWorkers(4)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 3 {
+		t.Fatalf("Expected 3 orphaned folders, got %d", len(result.OrphanedFolders))
+	}
+
+	// Sort for predictable comparison
+	sort.Strings(result.OrphanedFolders)
+
+	if !containsSubstring(result.OrphanedFolders[0], "Alpha Studio") {
+		t.Errorf("First sorted folder should be Alpha Studio, got %s", result.OrphanedFolders[0])
+	}
+}
+
+// Helper function to check if a string contains a substring
+func containsSubstring(s, substr string) bool {
+	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsSubstringHelper(s, substr))
+}
+
+func containsSubstringHelper(s, substr string) bool {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// Benchmark tests
+// ============================================================================
+
+func BenchmarkScanLibrary_Small(b *testing.B) {
+	tempDir, err := os.MkdirTemp("", "bench-*")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			path := filepath.Join(libraryDir, "Studio"+string(rune('A'+i)), "Movie"+string(rune('0'+j)), "movie.mkv")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result := &CleanupResult{}
+		if err := NewScanner(WithWorkers(10)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScanLibrary_ConcurrencyComparison(b *testing.B) {
+	tempDir, err := os.MkdirTemp("", "bench-*")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 20; j++ {
+			path := filepath.Join(libraryDir, "Studio"+string(rune('A'+i%26))+string(rune('0'+i/26)),
+				"Movie"+string(rune('0'+j%10))+string(rune('0'+j/10)), "movie.mkv")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	workerCounts := []int{1, 4, 10, 20}
+	for _, workers := range workerCounts {
+		b.Run("workers="+string(rune('0'+workers%10)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				result := &CleanupResult{}
+				if err := NewScanner(WithWorkers(workers)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
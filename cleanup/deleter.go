@@ -0,0 +1,545 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeleteStrategy decides what "deleting" a finding means: removing it for
+// good, moving it somewhere recoverable, or renaming it in place.
+type DeleteStrategy interface {
+	// Name identifies the strategy in logs and reports.
+	Name() string
+	// Delete disposes of f.Path. Orphaned folders are disposed of with all
+	// their contents; empty folders and files individually.
+	Delete(f Finding) error
+}
+
+// MovingStrategy is implemented by strategies that move items somewhere
+// they can be brought back from, to tell where each one went.
+type MovingStrategy interface {
+	DeleteStrategy
+	// Move disposes of f like Delete and returns where it went. A zero
+	// Moved means f was removed for good.
+	Move(f Finding) (Moved, error)
+}
+
+// Moved tells where a MovingStrategy put an item.
+type Moved struct {
+	To string // where the item is now
+	// Record is what else keeps track of the move and must be updated when
+	// the item is moved back: the quarantine directory, or the .trashinfo
+	// file of the system trash.
+	Record string
+}
+
+// PermanentStrategy removes items from disk. It is the default strategy.
+type PermanentStrategy struct{}
+
+func (PermanentStrategy) Name() string { return "permanent" }
+
+func (PermanentStrategy) Delete(f Finding) error {
+	if f.Category == CategoryOrphanedFolder {
+		return os.RemoveAll(longPath(f.Path))
+	}
+	// Empty folders use Remove too: it refuses if something appeared since
+	// the scan. OS junk does not keep a folder from being empty; it goes first
+	if f.Category == CategoryEmptyFolder {
+		removeJunk(f.Path)
+	}
+	return os.Remove(longPath(f.Path))
+}
+
+// TrashDirStrategy moves items into Dir, keeping their path relative to the
+// library root below a folder named after the library, e.g.
+// Dir/Movies/Studio A/Old Movie (2019).
+type TrashDirStrategy struct {
+	Dir string
+}
+
+func (TrashDirStrategy) Name() string { return "trash" }
+
+func (s TrashDirStrategy) Delete(f Finding) error {
+	_, err := s.Move(f)
+	return err
+}
+
+func (s TrashDirStrategy) Move(f Finding) (Moved, error) {
+	rel, err := filepath.Rel(f.Library, f.Path)
+	if err != nil || f.Library == "" || !IsWithin(f.Library, f.Path) {
+		// Not under a known library: keep just the name
+		rel = filepath.Base(f.Path)
+	}
+	target, err := filepath.Abs(uniquePath(filepath.Join(s.Dir, filepath.Base(f.Library), rel)))
+	if err != nil {
+		return Moved{}, err
+	}
+	if err := os.MkdirAll(longPath(filepath.Dir(target)), 0755); err != nil {
+		return Moved{}, err
+	}
+	if err := movePath(f.Path, target); err != nil {
+		return Moved{}, err
+	}
+	return Moved{To: target}, nil
+}
+
+// CheckLibraries refuses a trash directory inside one of libraryPaths:
+// trashed items would show up in the next scan, and moving a folder into
+// itself fails.
+func (s TrashDirStrategy) CheckLibraries(libraryPaths []string) error {
+	absDir, err := filepath.Abs(s.Dir)
+	if err != nil {
+		return err
+	}
+	for _, lib := range libraryPaths {
+		absLib, err := filepath.Abs(lib)
+		if err != nil {
+			return err
+		}
+		if IsWithin(absLib, absDir) {
+			return fmt.Errorf("trash directory %s is inside library %s, choose one outside the libraries", s.Dir, lib)
+		}
+	}
+	return nil
+}
+
+// IsWithin reports whether path is dir itself or lies below it. Both must
+// be absolute, or both relative to the same directory.
+func IsWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// SystemTrashStrategy moves items to the desktop trash: the freedesktop.org
+// trash on Linux and BSD, ~/.Trash on macOS. It is not available on Windows.
+type SystemTrashStrategy struct{}
+
+func (SystemTrashStrategy) Name() string { return "system-trash" }
+
+func (s SystemTrashStrategy) Delete(f Finding) error {
+	_, err := s.Move(f)
+	return err
+}
+
+func (SystemTrashStrategy) Move(f Finding) (Moved, error) {
+	return moveToSystemTrash(f.Path)
+}
+
+// RenameStrategy renames items in place by appending Suffix (".deleted" if
+// empty), leaving them for a later manual purge.
+type RenameStrategy struct {
+	Suffix string
+}
+
+func (RenameStrategy) Name() string { return "rename" }
+
+func (s RenameStrategy) Delete(f Finding) error {
+	_, err := s.Move(f)
+	return err
+}
+
+func (s RenameStrategy) Move(f Finding) (Moved, error) {
+	suffix := s.Suffix
+	if suffix == "" {
+		suffix = ".deleted"
+	}
+	target, err := filepath.Abs(uniquePath(f.Path + suffix))
+	if err != nil {
+		return Moved{}, err
+	}
+	if err := os.Rename(longPath(f.Path), longPath(target)); err != nil {
+		return Moved{}, err
+	}
+	return Moved{To: target}, nil
+}
+
+// DeleteStrategyByName returns the built-in strategy called name. trashDir is
+// only used by the "trash" strategy.
+func DeleteStrategyByName(name, trashDir string) (DeleteStrategy, error) {
+	switch name {
+	case "", "permanent":
+		return PermanentStrategy{}, nil
+	case "trash":
+		if trashDir == "" {
+			return nil, errors.New("the trash strategy needs a trash directory")
+		}
+		return TrashDirStrategy{Dir: trashDir}, nil
+	case "system-trash":
+		return SystemTrashStrategy{}, nil
+	case "rename":
+		return RenameStrategy{}, nil
+	}
+	return nil, fmt.Errorf("unknown delete strategy %q (expected permanent, trash, system-trash or rename)", name)
+}
+
+// DeletionReport summarizes a Deleter run.
+type DeletionReport struct {
+	Strategy string
+	Deleted  []Finding
+	Skipped  []Finding // already gone when their turn came
+	Kept     []Finding // left in place, in full or in part, because they hold hardlinked files
+	Failures []error   // one *DeletionError per item that could not be deleted, or *PanicError if the strategy panicked
+}
+
+// Deleter applies a DeleteStrategy to the deletable findings of a result.
+type Deleter struct {
+	strategy          DeleteStrategy
+	workers           int
+	preserveHardlinks bool
+	progress          ProgressFunc
+	limiter           *RateLimiter
+	manifest          *Manifest
+	backup            *Backup
+}
+
+// DeleterOption configures a Deleter.
+type DeleterOption func(*Deleter)
+
+// WithDeleteWorkers sets how many items are deleted concurrently (default 1).
+func WithDeleteWorkers(n int) DeleterOption {
+	return func(d *Deleter) {
+		d.workers = n
+	}
+}
+
+// WithPreserveHardlinks leaves files that still have other hard links in
+// place, e.g. files a torrent client is seeding from another directory.
+// Orphaned folders holding such files only lose their other contents.
+func WithPreserveHardlinks(preserve bool) DeleterOption {
+	return func(d *Deleter) {
+		d.preserveHardlinks = preserve
+	}
+}
+
+// WithDeleteRateLimiter makes every item wait for limiter before it is
+// deleted.
+func WithDeleteRateLimiter(limiter *RateLimiter) DeleterOption {
+	return func(d *Deleter) {
+		d.limiter = limiter
+	}
+}
+
+// WithDeleteProgress calls fn with a DeletionDone event after every item is
+// handled.
+func WithDeleteProgress(fn ProgressFunc) DeleterOption {
+	return func(d *Deleter) {
+		d.progress = fn
+	}
+}
+
+// NewDeleter returns a Deleter using strategy (PermanentStrategy if nil).
+func NewDeleter(strategy DeleteStrategy, opts ...DeleterOption) *Deleter {
+	if strategy == nil {
+		strategy = PermanentStrategy{}
+	}
+	d := &Deleter{strategy: strategy, workers: 1}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// deletionPhases groups the deletable findings of result into batches that
+// can each be deleted concurrently, in the order the batches must run: junk
+// files first, then orphaned folders and files, then empty folders one
+// depth at a time, deepest first, so nested empties go before their parents.
+func deletionPhases(result *CleanupResult) [][]Finding {
+	var junk, orphans, empties []Finding
+	for _, f := range result.Findings {
+		switch f.Category {
+		case CategoryJunkFile:
+			junk = append(junk, f)
+		case CategoryOrphanedFolder, CategoryOrphanedFile:
+			orphans = append(orphans, f)
+		case CategoryEmptyFolder:
+			empties = append(empties, f)
+		}
+	}
+
+	depth := func(f Finding) int {
+		return strings.Count(filepath.Clean(f.Path), string(filepath.Separator))
+	}
+	sort.SliceStable(empties, func(i, j int) bool {
+		return depth(empties[i]) > depth(empties[j])
+	})
+
+	var phases [][]Finding
+	if len(junk) > 0 {
+		phases = append(phases, junk)
+	}
+	if len(orphans) > 0 {
+		phases = append(phases, orphans)
+	}
+	for start := 0; start < len(empties); {
+		end := start + 1
+		for end < len(empties) && depth(empties[end]) == depth(empties[start]) {
+			end++
+		}
+		phases = append(phases, empties[start:end])
+		start = end
+	}
+	return phases
+}
+
+// Delete disposes of every deletable finding in result. Each phase runs on
+// the worker pool; ctx is checked before every item so a timeout or
+// cancellation stops before the next item rather than mid-way. The returned
+// error is only set when ctx stopped the run; per-item failures are
+// collected in the report.
+func (d *Deleter) Delete(ctx context.Context, result *CleanupResult) (*DeletionReport, error) {
+	report := &DeletionReport{Strategy: d.strategy.Name()}
+	phases := deletionPhases(result)
+	total := 0
+	for _, phase := range phases {
+		total += len(phase)
+	}
+
+	var mu sync.Mutex
+	done := 0
+	deleteOne := func(f Finding) error {
+		if d.limiter.Wait(ctx) != nil {
+			return nil // Cancelled, the item is left alone
+		}
+		// Might have been deleted as part of a parent already
+		if d.gone(f.Path) {
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			report.Skipped = append(report.Skipped, f)
+			return nil
+		}
+
+		var kept bool
+		var err error
+		if d.preserveHardlinks {
+			kept, err = d.deleteUnlinked(f)
+		} else {
+			err = d.dispose(f)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		var failure error
+		switch {
+		case err != nil:
+			failure = &DeletionError{Path: f.Path, Cause: err}
+			report.Failures = append(report.Failures, failure)
+		case kept:
+			report.Kept = append(report.Kept, f)
+		default:
+			report.Deleted = append(report.Deleted, f)
+		}
+		if d.progress != nil {
+			d.progress(DeletionDone{Finding: f, Err: failure, Kept: kept, Done: done, Total: total})
+		}
+		return nil
+	}
+
+	for _, phase := range phases {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		// Only panicking strategies make it back here
+		if err := RunPool(ctx, d.workers, phase, deleteOne); err != nil {
+			report.Failures = append(report.Failures, SplitErrors(err)...)
+		}
+	}
+	return report, ctx.Err()
+}
+
+// dispose applies the strategy to f, after writing it to the backup and
+// before recording it in the manifest, if there are ones.
+func (d *Deleter) dispose(f Finding) error {
+	if d.backup != nil {
+		if err := d.backup.add(f); err != nil {
+			return fmt.Errorf("not backed up, left in place: %w", err)
+		}
+	}
+	if d.manifest == nil {
+		return d.strategy.Delete(f)
+	}
+	// Items of remote stores cannot be read here and go without checksum
+	bytes := f.Bytes
+	sum, n, err := checksum(f.Path)
+	if err == nil {
+		bytes = n
+	}
+	var moved Moved
+	if mover, ok := d.strategy.(MovingStrategy); ok {
+		moved, err = mover.Move(f)
+	} else {
+		err = d.strategy.Delete(f)
+	}
+	if err != nil {
+		return err
+	}
+	return d.manifest.record(f, d.strategy.Name(), moved, sum, bytes)
+}
+
+// existenceChecker is implemented by strategies whose items are not on the
+// local disk, such as S3Strategy, to tell whether one is still there.
+type existenceChecker interface {
+	Exists(path string) (bool, error)
+}
+
+// gone reports whether nothing is left at path any more.
+func (d *Deleter) gone(path string) bool {
+	if checker, ok := d.strategy.(existenceChecker); ok {
+		exists, err := checker.Exists(path)
+		return err == nil && !exists
+	}
+	_, err := os.Lstat(longPath(path))
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// deleteUnlinked disposes of f like the strategy would, except that files
+// with other hard links are left alone. kept reports whether anything was
+// left in place.
+func (d *Deleter) deleteUnlinked(f Finding) (kept bool, err error) {
+	info, err := os.Lstat(longPath(f.Path))
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() {
+		if isHardlinked(info) {
+			return true, nil
+		}
+		return false, d.dispose(f)
+	}
+	if f.Category != CategoryOrphanedFolder {
+		return false, d.dispose(f)
+	}
+
+	var files, dirs []string
+	err = walkDir(f.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if isHardlinked(info) {
+			kept = true
+		} else {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if !kept {
+		return false, d.dispose(f)
+	}
+
+	// Dispose of the rest piece by piece, then of the folders that left
+	// empty, deepest first
+	var errs []error
+	for _, path := range files {
+		piece := Finding{Category: CategoryOrphanedFile, Path: path, Library: f.Library}
+		if err := d.dispose(piece); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if empty, err := isDirEmpty(osFS{}, dirs[i]); err != nil || !empty {
+			continue
+		}
+		piece := Finding{Category: CategoryEmptyFolder, Path: dirs[i], Library: f.Library}
+		if err := d.dispose(piece); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return true, errors.Join(errs...)
+}
+
+// uniquePath returns path, or path with a timestamp appended if something
+// already exists there.
+func uniquePath(path string) string {
+	if _, err := os.Lstat(longPath(path)); errors.Is(err, fs.ErrNotExist) {
+		return path
+	}
+	return fmt.Sprintf("%s.%s", path, time.Now().Format("20060102-150405.000000000"))
+}
+
+// movePath renames src to dst, falling back to copy-and-delete when they are
+// on different filesystems.
+func movePath(src, dst string) error {
+	err := os.Rename(longPath(src), longPath(dst))
+	if err == nil {
+		return nil
+	}
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) || !isCrossDevice(linkErr.Err) {
+		return err
+	}
+	if err := copyTree(src, dst); err != nil {
+		_ = os.RemoveAll(longPath(dst))
+		return err
+	}
+	return os.RemoveAll(longPath(src))
+}
+
+// copyTree copies the file or directory src to dst, preserving modes.
+func copyTree(src, dst string) error {
+	return walkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(longPath(target), info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(longPath(path))
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, longPath(target))
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(longPath(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(longPath(dst), os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,415 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// deletableResult builds a result with one finding per deletable category
+// under libraryDir, as a scan would have produced.
+func deletableResult(t *testing.T, libraryDir string) (*CleanupResult, []string) {
+	orphanedFolder := filepath.Join(libraryDir, "Studio", "Orphaned")
+	orphanedFile := filepath.Join(libraryDir, "Studio", "deleted.nfo")
+	emptyFolder := filepath.Join(libraryDir, "Studio", "Empty")
+	createFile(t, filepath.Join(orphanedFolder, "movie.nfo"))
+	createFile(t, orphanedFile)
+	createDir(t, emptyFolder)
+
+	result := &CleanupResult{Libraries: []string{libraryDir}}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: orphanedFolder, Library: libraryDir})
+	result.add(Finding{Category: CategoryOrphanedFile, Path: orphanedFile, Library: libraryDir})
+	result.add(Finding{Category: CategoryEmptyFolder, Path: emptyFolder, Library: libraryDir})
+	return result, []string{orphanedFolder, orphanedFile, emptyFolder}
+}
+
+// ============================================================================
+// Tests for Deleter
+// ============================================================================
+
+func TestDeleter_DeletesAllCategories(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	result, paths := deletableResult(t, filepath.Join(tempDir, "Library"))
+
+	report, err := NewDeleter(nil).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Deleted) != 3 || len(report.Failures) != 0 {
+		t.Errorf("Expected 3 deleted and 0 failed, got %d deleted and %d failed: %v",
+			len(report.Deleted), len(report.Failures), report.Failures)
+	}
+	if report.Strategy != "permanent" {
+		t.Errorf("Expected permanent strategy by default, got %q", report.Strategy)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be deleted", path)
+		}
+	}
+}
+
+func TestDeleter_JunkFilesFirst(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	orphanedFolder := filepath.Join(tempDir, "Studio", "Orphaned")
+	junkInOrphan := filepath.Join(orphanedFolder, ".DS_Store")
+	junkInTitle := filepath.Join(tempDir, "Studio", "Movie", "Thumbs.db")
+	createFile(t, filepath.Join(orphanedFolder, "movie.nfo"))
+	createFile(t, junkInOrphan)
+	createFile(t, filepath.Join(tempDir, "Studio", "Movie", "movie.mkv"))
+	createFile(t, junkInTitle)
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: orphanedFolder})
+	result.add(Finding{Category: CategoryJunkFile, Path: junkInOrphan})
+	result.add(Finding{Category: CategoryJunkFile, Path: junkInTitle})
+
+	phases := deletionPhases(result)
+	if len(phases) != 2 || len(phases[0]) != 2 || phases[0][0].Category != CategoryJunkFile {
+		t.Fatalf("Expected the junk files in a first phase of their own, got %v", phases)
+	}
+
+	report, err := NewDeleter(nil).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Deleted) != 3 || len(report.Failures) != 0 {
+		t.Errorf("Expected 3 deleted and 0 failed, got %d deleted and %d failed: %v",
+			len(report.Deleted), len(report.Failures), report.Failures)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "Studio", "Movie", "movie.mkv")); err != nil {
+		t.Errorf("Expected the video next to the junk to be kept: %v", err)
+	}
+}
+
+func TestDeleter_CancelledContext(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	result, paths := deletableResult(t, filepath.Join(tempDir, "Library"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := NewDeleter(nil).Delete(ctx, result)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(report.Deleted) != 0 {
+		t.Errorf("Expected nothing deleted after cancellation, got %d", len(report.Deleted))
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s should still exist after cancellation: %v", path, err)
+		}
+	}
+}
+
+func TestDeleter_NestedEmptyFoldersDeepestFirst(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	parent := filepath.Join(tempDir, "Studio")
+	child := filepath.Join(parent, "Empty")
+	createDir(t, child)
+
+	// Scans report parents before children
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryEmptyFolder, Path: parent})
+	result.add(Finding{Category: CategoryEmptyFolder, Path: child})
+
+	report, err := NewDeleter(nil).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Deleted) != 2 || len(report.Failures) != 0 {
+		t.Errorf("Expected both empty folders deleted, got %d deleted and failures %v", len(report.Deleted), report.Failures)
+	}
+}
+
+func TestPermanentStrategy_EmptyFolderWithJunk(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	junk := filepath.Join(tempDir, "Junk (2001)")
+	createFile(t, filepath.Join(junk, ".DS_Store"))
+	createFile(t, filepath.Join(junk, "Thumbs.db"))
+	filled := filepath.Join(tempDir, "Filled (2002)")
+	createFile(t, filepath.Join(filled, "desktop.ini"))
+	createFile(t, filepath.Join(filled, "movie.mkv"))
+
+	if err := (PermanentStrategy{}).Delete(Finding{Category: CategoryEmptyFolder, Path: junk}); err != nil {
+		t.Errorf("Expected a folder holding only junk deleted, got %v", err)
+	}
+	if err := (PermanentStrategy{}).Delete(Finding{Category: CategoryEmptyFolder, Path: filled}); err == nil {
+		t.Error("Expected a folder filled since the scan to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(filled, "movie.mkv")); err != nil {
+		t.Errorf("Expected the video kept, got %v", err)
+	}
+}
+
+func TestDeleter_SkipsAlreadyDeleted(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFile, Path: filepath.Join(tempDir, "gone.nfo")})
+
+	report, err := NewDeleter(nil).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Skipped) != 1 || len(report.Failures) != 0 {
+		t.Errorf("Expected 1 skipped and no failures, got %d skipped and %v", len(report.Skipped), report.Failures)
+	}
+}
+
+func TestDeleter_IgnoresStructureWarnings(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	video := filepath.Join(tempDir, "Studio", "movie.mkv")
+	createFile(t, video)
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryStructureWarning, Path: video, Message: "Video file at studio level"})
+
+	if _, err := NewDeleter(nil).Delete(context.Background(), result); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := os.Stat(video); err != nil {
+		t.Errorf("Structure warnings must never be deleted: %v", err)
+	}
+}
+
+func TestDeleter_FailureIsDeletionError(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// The file has to exist or it is skipped
+	file := filepath.Join(tempDir, "movie.nfo")
+	createFile(t, file)
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFile, Path: file})
+	failing := deleteStrategyFunc(func(Finding) error { return fs.ErrPermission })
+
+	var progress []DeletionDone
+	report, err := NewDeleter(failing, WithDeleteProgress(func(ev ProgressEvent) {
+		progress = append(progress, ev.(DeletionDone))
+	})).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if len(report.Failures) != 1 {
+		t.Fatalf("Expected 1 failure, got %d", len(report.Failures))
+	}
+	var deletionErr *DeletionError
+	if !errors.As(report.Failures[0], &deletionErr) || !errors.Is(deletionErr, fs.ErrPermission) {
+		t.Errorf("Expected DeletionError wrapping ErrPermission, got %v", report.Failures[0])
+	}
+	if len(progress) != 1 || progress[0].Err == nil || progress[0].Done != 1 || progress[0].Total != 1 {
+		t.Errorf("Expected one failed progress event, got %+v", progress)
+	}
+}
+
+type deleteStrategyFunc func(Finding) error
+
+func (deleteStrategyFunc) Name() string { return "func" }
+
+func (fn deleteStrategyFunc) Delete(f Finding) error { return fn(f) }
+
+// ============================================================================
+// Tests for delete strategies
+// ============================================================================
+
+func TestTrashDirStrategy_PreservesRelativePaths(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Movies")
+	trashDir := filepath.Join(tempDir, "Trash")
+	result, _ := deletableResult(t, libraryDir)
+
+	report, err := NewDeleter(TrashDirStrategy{Dir: trashDir}).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Failures) != 0 {
+		t.Fatalf("Expected no failures, got %v", report.Failures)
+	}
+
+	for _, rel := range []string{
+		filepath.Join("Movies", "Studio", "Orphaned", "movie.nfo"),
+		filepath.Join("Movies", "Studio", "deleted.nfo"),
+		filepath.Join("Movies", "Studio", "Empty"),
+	} {
+		if _, err := os.Stat(filepath.Join(trashDir, rel)); err != nil {
+			t.Errorf("Expected %s in trash: %v", rel, err)
+		}
+	}
+}
+
+func TestTrashDirStrategy_NameCollision(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Movies")
+	trashDir := filepath.Join(tempDir, "Trash")
+	file := filepath.Join(libraryDir, "Studio", "deleted.nfo")
+	createFile(t, filepath.Join(trashDir, "Movies", "Studio", "deleted.nfo"))
+	createFile(t, file)
+
+	strategy := TrashDirStrategy{Dir: trashDir}
+	if err := strategy.Delete(Finding{Category: CategoryOrphanedFile, Path: file, Library: libraryDir}); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(trashDir, "Movies", "Studio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected both copies kept in trash, got %d entries", len(entries))
+	}
+}
+
+func TestTrashDirStrategy_CheckLibraries(t *testing.T) {
+	libraries := []string{filepath.Join("media", "Movies"), filepath.Join("media", "TV")}
+	tests := []struct {
+		dir     string
+		allowed bool
+	}{
+		{filepath.Join("media", "Trash"), true},
+		{filepath.Join("media", "Movies-trash"), true},
+		{filepath.Join("media", "Movies", ".trash"), false},
+		{filepath.Join("media", "TV"), false},
+		{"media", true},
+	}
+	for _, tt := range tests {
+		err := TrashDirStrategy{Dir: tt.dir}.CheckLibraries(libraries)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckLibraries(%q): expected allowed=%v, got %v", tt.dir, tt.allowed, err)
+		}
+	}
+}
+
+func TestRenameStrategy(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	folder := filepath.Join(tempDir, "Studio", "Orphaned")
+	createFile(t, filepath.Join(folder, "movie.nfo"))
+
+	if err := (RenameStrategy{}).Delete(Finding{Category: CategoryOrphanedFolder, Path: folder}); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := os.Stat(folder); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be renamed away", folder)
+	}
+	if _, err := os.Stat(filepath.Join(folder+".deleted", "movie.nfo")); err != nil {
+		t.Errorf("Expected renamed folder with its contents: %v", err)
+	}
+}
+
+func TestSystemTrashStrategy_Freedesktop(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("freedesktop trash only")
+	}
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tempDir, "data"))
+
+	file := filepath.Join(tempDir, "Movies", "Studio", "deleted.nfo")
+	createFile(t, file)
+
+	if err := (SystemTrashStrategy{}).Delete(Finding{Category: CategoryOrphanedFile, Path: file}); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	trash := filepath.Join(tempDir, "data", "Trash")
+	if _, err := os.Stat(filepath.Join(trash, "files", "deleted.nfo")); err != nil {
+		t.Errorf("Expected file in trash: %v", err)
+	}
+	info, err := os.ReadFile(filepath.Join(trash, "info", "deleted.nfo.trashinfo"))
+	if err != nil {
+		t.Fatalf("Expected trashinfo file: %v", err)
+	}
+	if !strings.Contains(string(info), "Path="+filepath.ToSlash(file)) {
+		t.Errorf("Expected trashinfo to record the original path, got:\n%s", info)
+	}
+}
+
+func TestDeleteStrategyByName(t *testing.T) {
+	for _, name := range []string{"", "permanent", "system-trash", "rename"} {
+		if _, err := DeleteStrategyByName(name, ""); err != nil {
+			t.Errorf("Expected strategy %q to be known, got %v", name, err)
+		}
+	}
+	if _, err := DeleteStrategyByName("trash", ""); err == nil {
+		t.Error("Expected trash strategy without a directory to fail")
+	}
+	if s, err := DeleteStrategyByName("trash", "/tmp/trash"); err != nil || s.Name() != "trash" {
+		t.Errorf("Expected trash strategy, got %v, %v", s, err)
+	}
+	if _, err := DeleteStrategyByName("shred", ""); err == nil {
+		t.Error("Expected unknown strategy to fail")
+	}
+}
+
+func TestDeleter_ConcurrentWorkers(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	result := &CleanupResult{}
+	for _, studio := range []string{"A", "B", "C", "D"} {
+		studioDir := filepath.Join(tempDir, studio)
+		createFile(t, filepath.Join(studioDir, "Orphaned", "movie.nfo"))
+		createDir(t, filepath.Join(studioDir, "Empty"))
+		result.add(Finding{Category: CategoryEmptyFolder, Path: studioDir})
+		result.add(Finding{Category: CategoryOrphanedFolder, Path: filepath.Join(studioDir, "Orphaned")})
+		result.add(Finding{Category: CategoryEmptyFolder, Path: filepath.Join(studioDir, "Empty")})
+	}
+
+	report, err := NewDeleter(nil, WithDeleteWorkers(4)).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	// Studios only become empty once their children are gone
+	if len(report.Deleted) != 12 || len(report.Failures) != 0 {
+		t.Errorf("Expected 12 deleted and no failures, got %d deleted and %v", len(report.Deleted), report.Failures)
+	}
+}
+
+func TestDeleter_PanickingStrategy(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	file := filepath.Join(tempDir, "movie.nfo")
+	createFile(t, file)
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFile, Path: file})
+
+	panicking := deleteStrategyFunc(func(Finding) error { panic("boom") })
+	report, err := NewDeleter(panicking).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	var panicErr *PanicError
+	if len(report.Failures) != 1 || !errors.As(report.Failures[0], &panicErr) {
+		t.Errorf("Expected one PanicError failure, got %v", report.Failures)
+	}
+}
@@ -0,0 +1,19 @@
+package cleanup
+
+import "strings"
+
+// Suffixes of the files download clients write to until a download
+// completes: Firefox and qBittorrent (.part), qBittorrent's incomplete
+// marker (.!qB), Chrome (.crdownload) and temporary files in general (.tmp)
+var partialDownloadSuffixes = []string{".part", ".!qb", ".crdownload", ".tmp"}
+
+// isPartialDownload reports whether name is a download still in progress.
+func isPartialDownload(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range partialDownloadSuffixes {
+		if strings.HasSuffix(lower, suffix) && len(lower) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package cleanup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// videoFile is a video seen during a scan, kept for duplicate detection.
+type videoFile struct {
+	path  string
+	size  int64
+	id    fileID
+	hasID bool
+	info  fs.FileInfo // for os.SameFile when the filesystem has no file ids
+}
+
+// recordVideo remembers the video entry in dirPath when duplicate detection
+// is enabled.
+func (r *scanRun) recordVideo(dirPath string, entry fs.DirEntry) {
+	if !r.detectDuplicates {
+		return
+	}
+	path := filepath.Join(dirPath, entry.Name())
+	var info fs.FileInfo
+	var err error
+	if entry.Type()&fs.ModeSymlink != 0 {
+		info, err = r.fsys.Stat(path) // Only followed symlinks count as videos
+	} else {
+		info, err = entry.Info()
+	}
+	if err != nil || info.Size() == 0 {
+		return
+	}
+
+	v := videoFile{path: path, size: info.Size(), info: info}
+	v.id, v.hasID = fileIDOf(info)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.videos = append(r.videos, v)
+}
+
+// reportDuplicates emits a CategoryDuplicateVideo finding for every video
+// whose exact size is shared with a video elsewhere in the library. Paths
+// to the same device and inode are hard links to a single copy, not
+// duplicates, and are reported once at most. So are paths to the same
+// directory entry, such as a followed symlink and its target, or two
+// spellings of one name on a case-insensitive filesystem, where the file
+// ids are unknown (Windows) but os.SameFile can tell. With
+// WithDuplicateHashing, only copies with identical contents are reported.
+func (r *scanRun) reportDuplicates() {
+	if !r.detectDuplicates {
+		return
+	}
+	bySize := map[int64][]videoFile{}
+	var sizes []int64
+	for _, v := range r.videos {
+		if bySize[v.size] == nil {
+			sizes = append(sizes, v.size)
+		}
+		bySize[v.size] = append(bySize[v.size], v)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+
+	for _, size := range sizes {
+		videos := bySize[size]
+		// One representative path per physical copy
+		sort.Slice(videos, func(i, j int) bool { return videos[i].path < videos[j].path })
+		seen := map[fileID]bool{}
+		var copies []videoFile
+		for _, v := range videos {
+			if v.hasID {
+				if seen[v.id] {
+					continue
+				}
+				seen[v.id] = true
+			} else if sameFileAsAny(v, copies) {
+				continue
+			}
+			copies = append(copies, v)
+		}
+		if len(copies) < 2 {
+			continue
+		}
+		if r.hashDuplicates {
+			for _, identical := range r.identicalCopies(copies) {
+				r.reportIdentical(identical)
+			}
+			continue
+		}
+
+		for i, v := range copies {
+			others := make([]string, 0, len(copies)-1)
+			for j, other := range copies {
+				if j != i {
+					others = append(others, other.path)
+				}
+			}
+			r.emit(Finding{Category: CategoryDuplicateVideo, Path: v.path,
+				Message: fmt.Sprintf("Same size (%s) as %s", FormatBytes(size), strings.Join(others, ", "))})
+		}
+	}
+}
+
+// sameFileAsAny reports whether v, which has no file id, is the same file
+// as one of copies. Only files read from the local filesystem can be told
+// apart this way; os.SameFile is false for any other FileInfo.
+func sameFileAsAny(v videoFile, copies []videoFile) bool {
+	for _, c := range copies {
+		if !c.hasID && os.SameFile(v.info, c.info) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashSampleSize is how much of the start and of the end of a video is
+// hashed to tell most same-size videos apart before reading them in full.
+const hashSampleSize = 64 << 10
+
+// identicalCopies splits copies, videos of one size, into the groups of two
+// or more whose contents are byte-identical. Candidates are first told
+// apart by a hash of their start and end, then hashed in full.
+func (r *scanRun) identicalCopies(copies []videoFile) [][]videoFile {
+	groups := [][]videoFile{copies}
+	for _, sample := range []bool{true, false} {
+		var next [][]videoFile
+		for _, group := range groups {
+			byHash := map[string][]videoFile{}
+			var sums []string
+			for _, v := range group {
+				if r.ctx.Err() != nil {
+					return nil
+				}
+				sum, err := r.hashVideo(v, sample)
+				if err != nil {
+					r.fail(&ErrUnreadableFile{Path: v.path, Err: err})
+					continue
+				}
+				if byHash[sum] == nil {
+					sums = append(sums, sum)
+				}
+				byHash[sum] = append(byHash[sum], v)
+			}
+			for _, sum := range sums {
+				if len(byHash[sum]) > 1 {
+					next = append(next, byHash[sum])
+				}
+			}
+		}
+		groups = next
+	}
+	return groups
+}
+
+// hashVideo returns the SHA-256 of the contents of v, or with sample of its
+// first and last hashSampleSize bytes only.
+func (r *scanRun) hashVideo(v videoFile, sample bool) (string, error) {
+	opener, ok := r.fsys.(fileOpener)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	file, err := opener.Open(v.path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if !sample || v.size <= 2*hashSampleSize {
+		_, err = io.Copy(h, file)
+	} else if at, ok := file.(io.ReaderAt); ok {
+		_, err = io.Copy(h, io.NewSectionReader(at, 0, hashSampleSize))
+		if err == nil {
+			_, err = io.Copy(h, io.NewSectionReader(at, v.size-hashSampleSize, hashSampleSize))
+		}
+	} else {
+		_, err = io.CopyN(h, file, hashSampleSize)
+	}
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// reportIdentical emits a CategoryDuplicateVideo finding for each of the
+// identical copies, sorted by path. Every copy but the first carries its
+// usage, the space the redundant copies waste.
+func (r *scanRun) reportIdentical(copies []videoFile) {
+	for i, v := range copies {
+		others := make([]string, 0, len(copies)-1)
+		for j, other := range copies {
+			if j != i {
+				others = append(others, other.path)
+			}
+		}
+		f := Finding{Category: CategoryDuplicateVideo, Path: v.path,
+			Message: fmt.Sprintf("Identical to %s", strings.Join(others, ", "))}
+		if i > 0 {
+			f.Usage = fileUsage(v.path, v.info)
+		}
+		r.emit(f)
+	}
+}
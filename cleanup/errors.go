@@ -0,0 +1,231 @@
+package cleanup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrNotADirectory is returned by Scan when the library root exists but is
+// not a directory.
+var ErrNotADirectory = errors.New("not a directory")
+
+// ErrFindingLimit is returned by the callback built by LimitFindings, and so
+// by Scan, once the maximum number of findings has been recorded.
+var ErrFindingLimit = errors.New("finding limit reached")
+
+// ErrPlanSignature is returned by Plan.Verify when the plan was edited after
+// it was signed, or signed with another key.
+var ErrPlanSignature = errors.New("plan signature does not match, the plan was edited or signed with another key")
+
+// ErrUnreadableDir reports a directory that could not be listed. Scan returns
+// it directly when the library root is unreadable, and joins one per folder
+// that could not be read below the root.
+type ErrUnreadableDir struct {
+	Path string
+	Err  error
+}
+
+func (e *ErrUnreadableDir) Error() string {
+	return fmt.Sprintf("cannot read directory %s: %v", e.Path, e.Err)
+}
+
+func (e *ErrUnreadableDir) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnreadableFile reports a file whose contents could not be read, such as
+// a video to hash for WithDuplicateHashing. Scan joins one per file.
+type ErrUnreadableFile struct {
+	Path string
+	Err  error
+}
+
+func (e *ErrUnreadableFile) Error() string {
+	return fmt.Sprintf("cannot read file %s: %v", e.Path, e.Err)
+}
+
+func (e *ErrUnreadableFile) Unwrap() error {
+	return e.Err
+}
+
+// DeletionError reports an item that could not be deleted.
+type DeletionError struct {
+	Path  string
+	Cause error
+}
+
+func (e *DeletionError) Error() string {
+	return fmt.Sprintf("failed to delete %s: %v", e.Path, e.Cause)
+}
+
+func (e *DeletionError) Unwrap() error {
+	return e.Cause
+}
+
+// RestoreError reports a quarantined item that could not be put back.
+type RestoreError struct {
+	Path  string
+	Cause error
+}
+
+func (e *RestoreError) Error() string {
+	return fmt.Sprintf("failed to restore %s: %v", e.Path, e.Cause)
+}
+
+func (e *RestoreError) Unwrap() error {
+	return e.Cause
+}
+
+// PermissionFixError reports an item whose ownership or mode could not be
+// fixed.
+type PermissionFixError struct {
+	Path  string
+	Cause error
+}
+
+func (e *PermissionFixError) Error() string {
+	return fmt.Sprintf("failed to fix permissions of %s: %v", e.Path, e.Cause)
+}
+
+func (e *PermissionFixError) Unwrap() error {
+	return e.Cause
+}
+
+// MoveError reports a misplaced item that could not be moved into its
+// target folder.
+type MoveError struct {
+	Path   string
+	Target string
+	Cause  error
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("failed to move %s to %s: %v", e.Path, e.Target, e.Cause)
+}
+
+func (e *MoveError) Unwrap() error {
+	return e.Cause
+}
+
+// S3Error is an error answered by an S3-compatible store. A 404 status,
+// such as NoSuchKey, matches fs.ErrNotExist.
+type S3Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *S3Error) Error() string {
+	switch {
+	case e.Code != "" && e.Message != "":
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	case e.Code != "":
+		return e.Code
+	}
+	return fmt.Sprintf("HTTP %d", e.Status)
+}
+
+func (e *S3Error) Is(target error) bool {
+	return target == fs.ErrNotExist && e.Status == http.StatusNotFound
+}
+
+// WebDAVError is an error status answered by a WebDAV server. A 404
+// status matches fs.ErrNotExist.
+type WebDAVError struct {
+	Method string
+	Status int
+}
+
+func (e *WebDAVError) Error() string {
+	return fmt.Sprintf("%s: HTTP %d %s", e.Method, e.Status, http.StatusText(e.Status))
+}
+
+func (e *WebDAVError) Is(target error) bool {
+	return target == fs.ErrNotExist && e.Status == http.StatusNotFound
+}
+
+// PlanChangedError is returned by Plan.Verify when items of the plan changed
+// on disk, or disappeared, since the plan was made.
+type PlanChangedError struct {
+	Paths []string
+}
+
+func (e *PlanChangedError) Error() string {
+	return fmt.Sprintf("%d planned items changed since the plan was made: %s", len(e.Paths), strings.Join(e.Paths, ", "))
+}
+
+// PanicError reports a panic recovered in a worker goroutine. The rest of
+// the run carries on without the item that panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// SplitErrors flattens an error produced by errors.Join, such as the
+// unreadable folders returned by Scanner.Scan, into its parts.
+func SplitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// LockedError is returned by LockLibrary when another run holds the lock of
+// the library. PID and Host are zero when the lock file cannot be read.
+type LockedError struct {
+	Library string
+	Path    string // the lock file
+	PID     int
+	Host    string
+	Since   time.Time
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("%s is locked by another run (lock file %s)", e.Library, e.Path)
+	}
+	return fmt.Sprintf("%s is locked by process %d on %s since %s (lock file %s)",
+		e.Library, e.PID, e.Host, e.Since.Local().Format(time.DateTime), e.Path)
+}
+
+// ThresholdError is returned by DeletionThreshold.Check when a run would
+// delete more than the threshold allows. Titles is -1 when the number of
+// title folders scanned is not known.
+type ThresholdError struct {
+	Deletions int
+	Titles    int
+	Threshold DeletionThreshold
+}
+
+func (e *ThresholdError) Error() string {
+	if e.Threshold.MaxCount > 0 && e.Deletions > e.Threshold.MaxCount {
+		return fmt.Sprintf("%d items to delete, over the maximum of %d", e.Deletions, e.Threshold.MaxCount)
+	}
+	return fmt.Sprintf("%d items to delete for %d title folders scanned, over the maximum of %g%%",
+		e.Deletions, e.Titles, e.Threshold.MaxPercent)
+}
+
+// ProtectedError is returned by ProtectedPaths.Check when actions would
+// touch protected paths.
+type ProtectedError struct {
+	Findings []ProtectedFinding
+}
+
+func (e *ProtectedError) Error() string {
+	first := e.Findings[0]
+	if len(e.Findings) == 1 {
+		return fmt.Sprintf("%s is protected by %q", first.Finding.Path, first.Pattern)
+	}
+	return fmt.Sprintf("%s is protected by %q, and %d more items", first.Finding.Path, first.Pattern, len(e.Findings)-1)
+}
@@ -0,0 +1,124 @@
+package cleanup
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PathFilter selects the folders a scan visits with glob patterns matched
+// against their path relative to the library root, using forward slashes:
+// "Studio A" for a studio, "Studio A/Old Movie (2019)" for a title. Each
+// "/"-separated element follows path.Match, and an element "**" matches any
+// number of folders, so "**/Staging/**" matches a Staging folder at any
+// level and everything below it.
+type PathFilter struct {
+	// Include, when not empty, limits the scan to folders matching one of
+	// the patterns, with everything below them.
+	Include []string
+	// Exclude skips folders matching one of the patterns, with everything
+	// below them, even if they are included.
+	Exclude []string
+}
+
+// Validate reports the first malformed pattern.
+func (f PathFilter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		for _, elem := range strings.Split(pattern, "/") {
+			if _, err := path.Match(elem, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// allows reports whether the folder at rel, relative to the library root,
+// should be visited. A container (leaf false) is also visited when only
+// folders below it can be included.
+func (f PathFilter) allows(rel string, leaf bool) bool {
+	if len(f.Include) == 0 && len(f.Exclude) == 0 {
+		return true
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	for _, pattern := range f.Exclude {
+		if matchElems(strings.Split(pattern, "/"), elems) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		patternElems := strings.Split(pattern, "/")
+		// Included along with a parent, or on its own
+		for n := 1; n <= len(elems); n++ {
+			if matchElems(patternElems, elems[:n]) {
+				return true
+			}
+		}
+		if !leaf && matchesBelow(patternElems, elems) {
+			return true
+		}
+	}
+	return false
+}
+
+// selects reports whether the folder at rel, relative to the library root,
+// is itself part of what Include selects, rather than only visited for the
+// folders below it. Files directly in a folder that is not are left alone.
+func (f PathFilter) selects(rel string) bool {
+	if len(f.Include) == 0 {
+		return true
+	}
+	if rel == "." {
+		return false
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	for _, pattern := range f.Include {
+		patternElems := strings.Split(pattern, "/")
+		for n := 1; n <= len(elems); n++ {
+			if matchElems(patternElems, elems[:n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchElems reports whether the pattern elements match the path elements.
+func matchElems(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchElems(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+// matchesBelow reports whether the pattern could match a path below elems.
+func matchesBelow(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if len(elems) == 0 || pattern[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package cleanup
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for PathFilter
+// ============================================================================
+
+func TestPathFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter PathFilter
+		rel    string
+		leaf   bool
+		want   bool
+	}{
+		{"no patterns", PathFilter{}, "Studio A", false, true},
+		{"excluded studio", PathFilter{Exclude: []string{"Studio A"}}, "Studio A", false, false},
+		{"excluded at any level", PathFilter{Exclude: []string{"**/Staging/**"}}, "Studio A/Staging", true, false},
+		{"excluded at top level", PathFilter{Exclude: []string{"**/Staging/**"}}, "Staging", false, false},
+		{"not excluded", PathFilter{Exclude: []string{"**/Staging/**"}}, "Studio A/Staging Area", true, true},
+		{"wildcard element", PathFilter{Exclude: []string{"*/Sample*"}}, "Studio A/Sample Movie", true, false},
+		{"included studio", PathFilter{Include: []string{"Studio A"}}, "Studio A", false, true},
+		{"title of included studio", PathFilter{Include: []string{"Studio A"}}, "Studio A/Old Movie (2019)", true, true},
+		{"other studio", PathFilter{Include: []string{"Studio A"}}, "Studio B", false, false},
+		{"studio holding included titles", PathFilter{Include: []string{"*/Old*"}}, "Studio B", false, true},
+		{"title not included", PathFilter{Include: []string{"*/Old*"}}, "Studio B/New Movie (2024)", true, false},
+		{"exclude wins", PathFilter{Include: []string{"Studio A"}, Exclude: []string{"Studio A/Old*"}}, "Studio A/Old Movie (2019)", true, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.allows(filepath.FromSlash(tt.rel), tt.leaf); got != tt.want {
+			t.Errorf("%s: expected allows(%q) = %v, got %v", tt.name, tt.rel, tt.want, got)
+		}
+	}
+}
+
+func TestPathFilter_Validate(t *testing.T) {
+	if err := (PathFilter{Include: []string{"Studio [AB]/**"}}).Validate(); err != nil {
+		t.Errorf("Expected a valid pattern, got %v", err)
+	}
+	if err := (PathFilter{Exclude: []string{"Studio [A"}}).Validate(); err == nil {
+		t.Error("Expected an error for an unterminated character class")
+	}
+}
+
+func TestScan_PathFilter(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio A").
+		Title("Orphan A (2001)").Metadata("movie.nfo").
+		Studio("Studio A").
+		Title("Staging").Metadata("movie.nfo").
+		Studio("Studio B").
+		Title("Orphan B (2002)").Metadata("movie.nfo").
+		MapFS()
+
+	filter := PathFilter{Include: []string{"Studio A"}, Exclude: []string{"**/Staging/**"}}
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithPathFilter(filter)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := filepath.Join("Studio A", "Orphan A (2001)")
+	if len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] != expected {
+		t.Errorf("Expected only %s, got %v", expected, result.OrphanedFolders)
+	}
+}
+
+func TestScan_IncludeLeavesFilesAboveAlone(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("S3 Studio").
+		Title("Orphan A (2001)").Metadata("movie.nfo").
+		Studio("Studio B").
+		Title("Orphan B (2002)").Metadata("movie.nfo").
+		Up().Metadata("stray.nfo").
+		Root().Metadata("library.nfo").
+		MapFS()
+
+	for name, include := range map[string]string{"studio": "S3*", "title": "*/Orphan A*"} {
+		result := &CleanupResult{}
+		if err := NewScanner(WithFS(IOFS(fsys)), WithPathFilter(PathFilter{Include: []string{include}})).Scan(context.Background(), ".", result.Add); err != nil {
+			t.Fatalf("%s: Scan returned error: %v", name, err)
+		}
+		expected := filepath.Join("S3 Studio", "Orphan A (2001)")
+		if len(result.Findings) != 1 || result.Findings[0].Path != expected {
+			t.Errorf("%s: expected only %s, got %v", name, expected, result.Findings)
+		}
+	}
+}
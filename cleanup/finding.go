@@ -0,0 +1,396 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON serialization of CleanupResult and
+// Finding, shared by every machine-readable output. Adding fields keeps the
+// version; renaming or removing one bumps it.
+const SchemaVersion = 1
+
+// Category classifies a Finding. Its string value is part of the JSON schema
+// and never changes once released.
+type Category string
+
+const (
+	// CategoryOrphanedFolder is a title folder with metadata but no video.
+	CategoryOrphanedFolder Category = "orphaned_folder"
+	// CategoryOrphanedFile is a metadata file at the wrong level with no video.
+	CategoryOrphanedFile Category = "orphaned_file"
+	// CategoryEmptyFolder is a folder holding nothing, or nothing but OS junk.
+	CategoryEmptyFolder Category = "empty_folder"
+	// CategoryJunkFile is a file the operating system left behind, such as
+	// .DS_Store or Thumbs.db, anywhere in the library. Only reported when
+	// WithJunkFiles is given; deleted on its own, whatever its folder is
+	// found to be.
+	CategoryJunkFile Category = "junk_file"
+	// CategoryStructureWarning is a file or folder not matching the expected
+	// structure. Warnings are reported but never deleted.
+	CategoryStructureWarning Category = "structure_warning"
+	// CategoryPermissionMismatch is a file or folder whose owner, group or
+	// mode differs from the configured PermissionPolicy. Only reported when
+	// the audit is enabled; never deleted.
+	CategoryPermissionMismatch Category = "permission_mismatch"
+	// CategoryDuplicateVideo is a video with the same size as a video in
+	// another place in the library, or with WithDuplicateHashing the same
+	// contents. Only reported when duplicate detection is enabled; never
+	// deleted.
+	CategoryDuplicateVideo Category = "duplicate_video"
+	// CategoryMetadataMismatch is a title folder whose NFO names another
+	// title or year than the folder does, e.g. after the folder was renamed
+	// without refreshing its metadata. Only reported when the NFO check is
+	// enabled; never deleted.
+	CategoryMetadataMismatch Category = "metadata_mismatch"
+	// CategoryMissingMetadata is a title folder with a video but no NFO or
+	// poster, or an episode without its NFO, to be refreshed in the media
+	// server. Only reported when the check is enabled; never deleted.
+	CategoryMissingMetadata Category = "missing_metadata"
+	// CategoryMisfiledVideo is a title folder with no video of its own but
+	// one in an extras subfolder, likely a misplaced main feature. Reported
+	// instead of CategoryOrphanedFolder and never deleted.
+	CategoryMisfiledVideo Category = "misfiled_video"
+	// CategoryDownloadInProgress is a title folder holding partial
+	// downloads (.part, .!qB, .crdownload or .tmp files), or such a file
+	// outside any title folder. Reported instead of CategoryOrphanedFolder
+	// or CategoryOrphanedFile and never deleted, as the download may still
+	// complete.
+	CategoryDownloadInProgress Category = "download_in_progress"
+	// CategoryTruncatedVideo is a video file smaller than the minimum size
+	// (WithMinVideoSize), e.g. a 0-byte stub left by a failed download. Its
+	// title is not healthy, but the file is never deleted.
+	CategoryTruncatedVideo Category = "truncated_video"
+	// CategoryCorruptVideo is a video file a VideoProber cannot make sense
+	// of. Only reported when probing is enabled; never deleted.
+	CategoryCorruptVideo Category = "corrupt_video"
+	// CategoryBrokenSymlink is a symlinked video whose target no longer
+	// exists. It never counts as a video, so its title folder is usually
+	// orphaned as well; the link itself is only reported.
+	CategoryBrokenSymlink Category = "broken_symlink"
+	// CategoryIncompatibleName is a file or folder whose name cannot be
+	// copied as is to Windows, exFAT or SMB targets. Only reported when the
+	// name audit is enabled; never deleted.
+	CategoryIncompatibleName Category = "incompatible_name"
+	// CategoryRecycleBin is a recycle bin managed by the NAS, with the
+	// space it holds. The scan never enters recycle bins; they are only
+	// reported when WithRecycleBinUsage is given, and never deleted.
+	CategoryRecycleBin Category = "recycle_bin"
+)
+
+// Categories lists every known Category in report order.
+var Categories = []Category{
+	CategoryStructureWarning,
+	CategoryMisfiledVideo,
+	CategoryDownloadInProgress,
+	CategoryTruncatedVideo,
+	CategoryCorruptVideo,
+	CategoryBrokenSymlink,
+	CategoryOrphanedFolder,
+	CategoryOrphanedFile,
+	CategoryEmptyFolder,
+	CategoryJunkFile,
+	CategoryPermissionMismatch,
+	CategoryDuplicateVideo,
+	CategoryMetadataMismatch,
+	CategoryMissingMetadata,
+	CategoryIncompatibleName,
+	CategoryRecycleBin,
+}
+
+// Valid reports whether c is one of the known categories.
+func (c Category) Valid() bool {
+	for _, known := range Categories {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalText rejects unknown categories so reports from an incompatible
+// version fail loudly instead of being silently misread.
+func (c *Category) UnmarshalText(text []byte) error {
+	category := Category(text)
+	if !category.Valid() {
+		return fmt.Errorf("unknown finding category %q", text)
+	}
+	*c = category
+	return nil
+}
+
+// Severity ranks how much a finding needs someone to look at it, so that
+// runs can fail on findings of some severity or above. Its string value is
+// part of the JSON schema and never changes once released.
+type Severity string
+
+const (
+	// SeverityInfo is a finding the tool deals with itself or that needs no
+	// action, such as an orphaned folder or a download in progress.
+	SeverityInfo Severity = "info"
+	// SeverityWarning is a problem worth a look that does not keep a title
+	// from playing, such as stray metadata or a permission mismatch.
+	SeverityWarning Severity = "warning"
+	// SeverityError is a title the media server is likely to miss or fail
+	// to play, such as a video outside any title folder or a corrupt video.
+	SeverityError Severity = "error"
+)
+
+// Severities lists every known Severity, least severe first.
+var Severities = []Severity{SeverityInfo, SeverityWarning, SeverityError}
+
+// AtLeast reports whether s is min or more severe.
+func (s Severity) AtLeast(min Severity) bool {
+	return slices.Index(Severities, s) >= slices.Index(Severities, min)
+}
+
+// UnmarshalText rejects unknown severities, as Category.UnmarshalText does
+// unknown categories.
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity := Severity(text)
+	if !slices.Contains(Severities, severity) {
+		return fmt.Errorf("unknown finding severity %q", text)
+	}
+	*s = severity
+	return nil
+}
+
+// Severity returns the severity of the findings of category c. Structure
+// warnings range from SeverityInfo to SeverityError and carry their own.
+func (c Category) Severity() Severity {
+	switch c {
+	case CategoryMisfiledVideo, CategoryTruncatedVideo, CategoryCorruptVideo, CategoryBrokenSymlink:
+		return SeverityError
+	case CategoryStructureWarning, CategoryPermissionMismatch, CategoryDuplicateVideo,
+		CategoryMetadataMismatch, CategoryMissingMetadata, CategoryIncompatibleName:
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// SeverityOf returns the severity of f: its own, or that of its category if
+// it has none, as in reports written before severities existed.
+func SeverityOf(f Finding) Severity {
+	if f.Severity != "" {
+		return f.Severity
+	}
+	return f.Category.Severity()
+}
+
+// Finding is a single problem discovered while scanning.
+type Finding struct {
+	Category Category `json:"category"`
+	Path     string   `json:"path"`
+	// Severity is set by Scanner on every finding; see SeverityOf for those
+	// built elsewhere.
+	Severity Severity `json:"severity,omitempty"`
+	// Library is the library root the finding was discovered under.
+	Library string `json:"library,omitempty"`
+	// Message explains structure warnings; it is empty for other categories.
+	Message string `json:"message,omitempty"`
+	// Target is the folder a misplaced video or its metadata belongs in, set
+	// on the structure warnings a StructureFixer can move, or the portable
+	// path of a title folder a NameFixer can rename.
+	Target string `json:"target,omitempty"`
+	// Modified is the latest modification time of a deletable item and of
+	// anything in it, telling an item orphaned months ago from one still
+	// being imported. It is nil for other categories.
+	Modified *time.Time `json:"modified,omitempty"`
+	// Usage is the disk space held by orphaned folders and files, by recycle
+	// bins, and by the redundant copies of identical videos (every copy but
+	// the first by path).
+	Usage
+}
+
+// String returns the line printed for f in the text report.
+func (f Finding) String() string {
+	if f.Message == "" {
+		return f.Path
+	}
+	return f.Message + ": " + f.Path
+}
+
+// CleanupResult collects the findings of one or more scans. Findings is the
+// canonical record and the only one serialized; the per-category path lists
+// are kept alongside for the text report and callers that only need paths.
+type CleanupResult struct {
+	SchemaVersion int       `json:"schema_version"`
+	Libraries     []string  `json:"libraries,omitempty"` // Library roots covered, in scan order
+	Findings      []Finding `json:"findings"`
+	Errors        []error   `json:"-"` // Folders that could not be scanned
+
+	OrphanedFolders   []string `json:"-"` // Folders with metadata but no video
+	OrphanedFiles     []string `json:"-"` // Metadata files at wrong level with no video
+	EmptyFolders      []string `json:"-"` // Completely empty folders
+	StructureWarnings []string `json:"-"` // Files/folders not matching expected structure
+}
+
+// Add records f in the result. Its signature matches the Scanner.Scan
+// callback so a result can be filled with scanner.Scan(ctx, root, result.Add).
+// Add is not safe for concurrent use; Scan never calls its callback
+// concurrently.
+func (r *CleanupResult) Add(f Finding) error {
+	r.add(f)
+	return nil
+}
+
+func (r *CleanupResult) add(f Finding) {
+	r.Findings = append(r.Findings, f)
+	switch f.Category {
+	case CategoryOrphanedFolder:
+		r.OrphanedFolders = append(r.OrphanedFolders, f.Path)
+	case CategoryOrphanedFile:
+		r.OrphanedFiles = append(r.OrphanedFiles, f.Path)
+	case CategoryEmptyFolder:
+		r.EmptyFolders = append(r.EmptyFolders, f.Path)
+	case CategoryStructureWarning:
+		r.StructureWarnings = append(r.StructureWarnings, f.String())
+	}
+}
+
+// LimitFindings wraps the Scan callback fn so that it returns
+// ErrFindingLimit once max findings have been passed to fn, which stops the
+// scan. Later findings are dropped without calling fn. The same wrapper can
+// be shared by several scans, concurrent or not, to limit them in total.
+func LimitFindings(max int, fn func(Finding) error) func(Finding) error {
+	var mu sync.Mutex
+	count := 0
+	return func(f Finding) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if count >= max {
+			return ErrFindingLimit
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+		count++
+		if count >= max {
+			return ErrFindingLimit
+		}
+		return nil
+	}
+}
+
+// ByCategory returns the findings of category c, in the order they were
+// added.
+func (r *CleanupResult) ByCategory(c Category) []Finding {
+	var findings []Finding
+	for _, f := range r.Findings {
+		if f.Category == c {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Merge appends the libraries, findings and errors of other to r. Findings
+// keep their Library attribution, so results scanned separately (or
+// concurrently) can be combined without losing where each finding came from.
+func (r *CleanupResult) Merge(other *CleanupResult) {
+	for _, lib := range other.Libraries {
+		if !r.hasLibrary(lib) {
+			r.Libraries = append(r.Libraries, lib)
+		}
+	}
+	for _, f := range other.Findings {
+		r.add(f)
+	}
+	r.Errors = append(r.Errors, other.Errors...)
+}
+
+// MergeResults combines results into a new CleanupResult, in order.
+func MergeResults(results ...*CleanupResult) *CleanupResult {
+	merged := &CleanupResult{}
+	for _, result := range results {
+		merged.Merge(result)
+	}
+	return merged
+}
+
+// ForLibrary returns a new result holding only the findings attributed to
+// the library root lib. Errors are not attributed and are left out.
+func (r *CleanupResult) ForLibrary(lib string) *CleanupResult {
+	filtered := &CleanupResult{Libraries: []string{lib}}
+	for _, f := range r.Findings {
+		if f.Library == lib {
+			filtered.add(f)
+		}
+	}
+	return filtered
+}
+
+// Sorted returns a copy of r with its findings, and so the per-category
+// lists, sorted by less. Findings less does not order keep the order they
+// were found in.
+func (r *CleanupResult) Sorted(less func(a, b Finding) bool) *CleanupResult {
+	findings := append([]Finding{}, r.Findings...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		return less(findings[i], findings[j])
+	})
+	sorted := &CleanupResult{SchemaVersion: r.SchemaVersion, Libraries: r.Libraries, Errors: r.Errors}
+	for _, f := range findings {
+		sorted.add(f)
+	}
+	return sorted
+}
+
+func (r *CleanupResult) hasLibrary(lib string) bool {
+	for _, known := range r.Libraries {
+		if known == lib {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupResultJSON is the wire form of CleanupResult.
+type cleanupResultJSON struct {
+	SchemaVersion int       `json:"schema_version"`
+	Libraries     []string  `json:"libraries,omitempty"`
+	Findings      []Finding `json:"findings"`
+	Errors        []string  `json:"errors,omitempty"`
+}
+
+// MarshalJSON always writes the current SchemaVersion and renders Errors as
+// their messages.
+func (r CleanupResult) MarshalJSON() ([]byte, error) {
+	out := cleanupResultJSON{
+		SchemaVersion: SchemaVersion,
+		Libraries:     r.Libraries,
+		Findings:      r.Findings,
+	}
+	if out.Findings == nil {
+		out.Findings = []Finding{}
+	}
+	for _, err := range r.Errors {
+		out.Errors = append(out.Errors, err.Error())
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads a result written by MarshalJSON, rebuilding the
+// per-category path lists. Results from a newer schema are rejected.
+func (r *CleanupResult) UnmarshalJSON(data []byte) error {
+	var in cleanupResultJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("result schema version %d is newer than supported version %d", in.SchemaVersion, SchemaVersion)
+	}
+
+	*r = CleanupResult{SchemaVersion: in.SchemaVersion, Libraries: in.Libraries}
+	for _, f := range in.Findings {
+		r.add(f)
+	}
+	for _, msg := range in.Errors {
+		r.Errors = append(r.Errors, errors.New(msg))
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for Category
+// ============================================================================
+
+func TestCategory_Valid(t *testing.T) {
+	for _, c := range Categories {
+		if !c.Valid() {
+			t.Errorf("Category %q should be valid", c)
+		}
+	}
+	if Category("bogus").Valid() {
+		t.Error("Unknown category should not be valid")
+	}
+}
+
+func TestCategory_UnmarshalRejectsUnknown(t *testing.T) {
+	var f Finding
+	err := json.Unmarshal([]byte(`{"category":"bogus","path":"/x"}`), &f)
+	if err == nil {
+		t.Error("Expected error for unknown category")
+	}
+}
+
+// ============================================================================
+// Tests for CleanupResult JSON serialization
+// ============================================================================
+
+func TestCleanupResult_MarshalJSON(t *testing.T) {
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: "/lib/Studio/Movie"})
+	result.add(Finding{Category: CategoryStructureWarning, Path: "/lib/x.mkv", Message: "Video file at library level"})
+	result.Errors = append(result.Errors, errors.New("cannot read directory /lib/Locked"))
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+
+	expected := `{"schema_version":1,"findings":[` +
+		`{"category":"orphaned_folder","path":"/lib/Studio/Movie"},` +
+		`{"category":"structure_warning","path":"/lib/x.mkv","message":"Video file at library level"}],` +
+		`"errors":["cannot read directory /lib/Locked"]}`
+	if string(data) != expected {
+		t.Errorf("Unexpected JSON:\n got: %s\nwant: %s", data, expected)
+	}
+}
+
+func TestCleanupResult_MarshalJSONEmpty(t *testing.T) {
+	data, err := json.Marshal(&CleanupResult{})
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	if string(data) != `{"schema_version":1,"findings":[]}` {
+		t.Errorf("Unexpected JSON for empty result: %s", data)
+	}
+}
+
+func TestCleanupResult_RoundTrip(t *testing.T) {
+	original := &CleanupResult{}
+	original.add(Finding{Category: CategoryOrphanedFolder, Path: "/lib/A/Orphan"})
+	original.add(Finding{Category: CategoryOrphanedFile, Path: "/lib/A/old.nfo"})
+	original.add(Finding{Category: CategoryEmptyFolder, Path: "/lib/B"})
+	original.add(Finding{Category: CategoryStructureWarning, Path: "/lib/A/M/extras", Message: "Unexpected subdirectory in title folder"})
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+
+	var decoded CleanupResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+
+	if decoded.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", SchemaVersion, decoded.SchemaVersion)
+	}
+	if len(decoded.Findings) != 4 {
+		t.Errorf("Expected 4 findings, got %d", len(decoded.Findings))
+	}
+	if len(decoded.OrphanedFolders) != 1 || len(decoded.OrphanedFiles) != 1 ||
+		len(decoded.EmptyFolders) != 1 || len(decoded.StructureWarnings) != 1 {
+		t.Errorf("Per-category lists not rebuilt: %+v", decoded)
+	}
+}
+
+func TestCleanupResult_UnmarshalRejectsNewerSchema(t *testing.T) {
+	var result CleanupResult
+	err := json.Unmarshal([]byte(`{"schema_version":999,"findings":[]}`), &result)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected newer-schema error, got %v", err)
+	}
+}
+
+// ============================================================================
+// Tests for result merging and library attribution
+// ============================================================================
+
+func TestCleanupResult_Merge(t *testing.T) {
+	movies := &CleanupResult{Libraries: []string{"/movies"}}
+	movies.add(Finding{Category: CategoryOrphanedFolder, Path: "/movies/A/Orphan", Library: "/movies"})
+	movies.Errors = []error{errors.New("cannot read directory /movies/Locked")}
+
+	shows := &CleanupResult{Libraries: []string{"/shows"}}
+	shows.add(Finding{Category: CategoryEmptyFolder, Path: "/shows/B", Library: "/shows"})
+	shows.add(Finding{Category: CategoryOrphanedFolder, Path: "/shows/C/Orphan", Library: "/shows"})
+
+	merged := MergeResults(movies, shows)
+
+	if len(merged.Libraries) != 2 || merged.Libraries[0] != "/movies" || merged.Libraries[1] != "/shows" {
+		t.Errorf("Expected libraries in scan order, got %v", merged.Libraries)
+	}
+	if len(merged.Findings) != 3 {
+		t.Errorf("Expected 3 findings, got %d", len(merged.Findings))
+	}
+	if len(merged.OrphanedFolders) != 2 || len(merged.EmptyFolders) != 1 {
+		t.Errorf("Per-category lists not merged: %d orphaned, %d empty",
+			len(merged.OrphanedFolders), len(merged.EmptyFolders))
+	}
+	if len(merged.Errors) != 1 {
+		t.Errorf("Expected 1 error, got %d", len(merged.Errors))
+	}
+
+	// Merging the same library twice does not duplicate it
+	merged.Merge(&CleanupResult{Libraries: []string{"/movies"}})
+	if len(merged.Libraries) != 2 {
+		t.Errorf("Expected library list to stay deduplicated, got %v", merged.Libraries)
+	}
+}
+
+func TestCleanupResult_ForLibrary(t *testing.T) {
+	result := &CleanupResult{Libraries: []string{"/movies", "/shows"}}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: "/movies/A/Orphan", Library: "/movies"})
+	result.add(Finding{Category: CategoryEmptyFolder, Path: "/shows/B", Library: "/shows"})
+
+	shows := result.ForLibrary("/shows")
+	if len(shows.Findings) != 1 || shows.Findings[0].Path != "/shows/B" {
+		t.Errorf("Expected only the /shows finding, got %+v", shows.Findings)
+	}
+	if len(shows.EmptyFolders) != 1 || len(shows.OrphanedFolders) != 0 {
+		t.Errorf("Per-category lists not filtered: %+v", shows)
+	}
+}
+
+func TestCleanupResult_Sorted(t *testing.T) {
+	result := &CleanupResult{Libraries: []string{"/movies"}}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: "/movies/A", Usage: Usage{Bytes: 1}})
+	result.add(Finding{Category: CategoryEmptyFolder, Path: "/movies/B"})
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: "/movies/C", Usage: Usage{Bytes: 3}})
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: "/movies/D", Usage: Usage{Bytes: 1}})
+
+	sorted := result.Sorted(func(a, b Finding) bool { return a.Bytes > b.Bytes })
+	if got := strings.Join(sorted.OrphanedFolders, " "); got != "/movies/C /movies/A /movies/D" {
+		t.Errorf("Expected the biggest first, ties in scan order, got %s", got)
+	}
+	if len(sorted.EmptyFolders) != 1 || len(sorted.Libraries) != 1 {
+		t.Errorf("Expected every finding and library kept, got %+v", sorted)
+	}
+	if result.Findings[0].Path != "/movies/A" {
+		t.Errorf("Expected the result left as it was, got %+v", result.Findings)
+	}
+}
+
+func TestCleanupResult_LibrariesRoundTrip(t *testing.T) {
+	result := &CleanupResult{Libraries: []string{"/movies"}}
+	result.add(Finding{Category: CategoryOrphanedFile, Path: "/movies/x.nfo", Library: "/movies"})
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	var decoded CleanupResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if len(decoded.Libraries) != 1 || decoded.Findings[0].Library != "/movies" {
+		t.Errorf("Library attribution lost in round trip: %s", data)
+	}
+}
+
+// ============================================================================
+// Tests for LimitFindings
+// ============================================================================
+
+func TestLimitFindings_StopsScan(t *testing.T) {
+	b := cleanuptest.New().Studio("Studio")
+	for i := 0; i < 20; i++ {
+		b.Title(fmt.Sprintf("Orphan %02d", i)).Metadata("movie.nfo")
+	}
+	fsys := b.MapFS()
+
+	result := &CleanupResult{}
+	err := NewScanner(WithFS(IOFS(fsys)), WithWorkers(4)).Scan(context.Background(), ".", LimitFindings(5, result.Add))
+	if !errors.Is(err, ErrFindingLimit) {
+		t.Errorf("Expected ErrFindingLimit, got %v", err)
+	}
+	if len(result.Findings) != 5 {
+		t.Errorf("Expected exactly 5 findings recorded, got %d", len(result.Findings))
+	}
+}
+
+func TestLimitFindings_SharedAcrossScans(t *testing.T) {
+	fsys := cleanuptest.New().Studio("Studio").Title("A").Metadata("movie.nfo").Title("B").Metadata("movie.nfo").MapFS()
+
+	result := &CleanupResult{}
+	add := LimitFindings(3, result.Add)
+	scanner := NewScanner(WithFS(IOFS(fsys)))
+	if err := scanner.Scan(context.Background(), ".", add); err != nil {
+		t.Fatalf("Expected the first scan to stay under the limit, got %v", err)
+	}
+	if err := scanner.Scan(context.Background(), ".", add); !errors.Is(err, ErrFindingLimit) {
+		t.Errorf("Expected ErrFindingLimit in the second scan, got %v", err)
+	}
+	if len(result.Findings) != 3 {
+		t.Errorf("Expected 3 findings in total, got %d", len(result.Findings))
+	}
+}
+
+func TestLimitFindings_ConcurrentScans(t *testing.T) {
+	b := cleanuptest.New().Studio("Studio")
+	for i := 0; i < 10; i++ {
+		b.Title(fmt.Sprintf("Orphan %02d", i)).Metadata("movie.nfo")
+	}
+	fsys := b.MapFS()
+
+	var mu sync.Mutex
+	result := &CleanupResult{}
+	add := LimitFindings(7, func(f Finding) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return result.Add(f)
+	})
+	scanner := NewScanner(WithFS(IOFS(fsys)), WithWorkers(4))
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = scanner.Scan(context.Background(), ".", add)
+		}()
+	}
+	wg.Wait()
+
+	if len(result.Findings) != 7 {
+		t.Errorf("Expected exactly 7 findings across scans, got %d", len(result.Findings))
+	}
+}
@@ -0,0 +1,89 @@
+package cleanup
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FS is the read-only view of the filesystem the Scanner walks. Paths are
+// passed in the host's native form, exactly as given to the scanner.
+type FS interface {
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+}
+
+// osFS reads straight from the local filesystem and is the Scanner default.
+type osFS struct{}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(longPath(name)) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(longPath(name)) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(longPath(name)) }
+func (osFS) Readlink(name string) (string, error)       { return os.Readlink(longPath(name)) }
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(longPath(name)) }
+
+// linkReader is implemented by filesystems that can tell where a symlink
+// points, to name the target of a broken one.
+type linkReader interface {
+	Readlink(name string) (string, error)
+}
+
+// fileOpener is implemented by filesystems that can stream a file, to hash
+// videos too large to read whole.
+type fileOpener interface {
+	Open(name string) (fs.File, error)
+}
+
+// IOFS adapts an io/fs filesystem such as os.DirFS or fstest.MapFS for use
+// with WithFS. Library roots must then be given relative to fsys, e.g.
+// "Movies" rather than "/mnt/media/Movies".
+func IOFS(fsys fs.FS) FS {
+	return ioFS{fsys: fsys}
+}
+
+type ioFS struct {
+	fsys fs.FS
+}
+
+func (f ioFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.fsys, filepath.ToSlash(name))
+}
+
+func (f ioFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.fsys, filepath.ToSlash(name))
+}
+
+func (f ioFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, filepath.ToSlash(name))
+}
+
+func (f ioFS) Open(name string) (fs.File, error) {
+	return f.fsys.Open(filepath.ToSlash(name))
+}
+
+func isDirEmpty(fsys FS, dirPath string) (bool, error) {
+	entries, err := fsys.ReadDir(dirPath)
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+// walkDir is filepath.WalkDir through the extended-length form of root, so
+// folders below it with paths too long for the Windows API are walked too.
+// fn gets the paths in the form of root, as they would be without it; the
+// os calls it makes on them go through longPath.
+func walkDir(root string, fn fs.WalkDirFunc) error {
+	long := extendedPath(root)
+	if long == root {
+		return filepath.WalkDir(root, fn)
+	}
+	return filepath.WalkDir(long, func(path string, d fs.DirEntry, err error) error {
+		if path == long {
+			return fn(root, d, err)
+		}
+		return fn(filepath.Join(root, strings.TrimPrefix(path, long)), d, err)
+	})
+}
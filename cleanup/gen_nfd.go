@@ -0,0 +1,199 @@
+//go:build ignore
+
+// gen_nfd writes nfd_tables.go from UnicodeData.txt of the Unicode
+// Character Database: go generate downloads the file of -version, -ucd
+// reads a local copy instead.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	version := flag.String("version", "14.0.0", "Unicode version of the tables")
+	ucd := flag.String("ucd", "", "Read this UnicodeData.txt instead of downloading it")
+	out := flag.String("out", "nfd_tables.go", "Write the tables to this file")
+	flag.Parse()
+
+	data, err := readUCD(*ucd, *version)
+	if err != nil {
+		log.Fatal(err)
+	}
+	decompositions, classes, err := parse(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := format.Source(tables(*version, decompositions, classes))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readUCD(path, version string) ([]byte, error) {
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	url := "https://www.unicode.org/Public/" + version + "/ucd/UnicodeData.txt"
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parse reads the canonical decomposition mappings and combining classes
+// of UnicodeData.txt. Compatibility mappings (those with a <tag>) are not
+// canonical; the CJK compatibility ideographs are left out, as macOS does
+// not decompose them, and Hangul syllables have no mapping in the file.
+func parse(data []byte) (map[rune][]rune, map[rune]uint8, error) {
+	decompositions := map[rune][]rune{}
+	classes := map[rune]uint8{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ";")
+		if len(fields) < 6 {
+			continue
+		}
+		code, err := strconv.ParseUint(fields[0], 16, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("code point %q: %w", fields[0], err)
+		}
+		r := rune(code)
+		class, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			return nil, nil, fmt.Errorf("combining class of %04X: %w", r, err)
+		}
+		if class != 0 {
+			classes[r] = uint8(class)
+		}
+		mapping := fields[5]
+		if mapping == "" || strings.HasPrefix(mapping, "<") || strings.HasPrefix(fields[1], "CJK COMPATIBILITY IDEOGRAPH-") {
+			continue
+		}
+		var runes []rune
+		for _, hex := range strings.Fields(mapping) {
+			code, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil {
+				return nil, nil, fmt.Errorf("decomposition of %04X: %w", r, err)
+			}
+			runes = append(runes, rune(code))
+		}
+		decompositions[r] = runes
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Mappings are applied until nothing decomposes further, and the marks
+	// put in canonical order
+	for r := range decompositions {
+		full := decompose(r, decompositions)
+		for i := 0; i < len(full); {
+			j := i
+			for j < len(full) && classes[full[j]] != 0 {
+				j++
+			}
+			marks := full[i:j]
+			sort.SliceStable(marks, func(a, b int) bool { return classes[marks[a]] < classes[marks[b]] })
+			i = j + 1
+		}
+		decompositions[r] = full
+	}
+	return decompositions, classes, nil
+}
+
+func decompose(r rune, decompositions map[rune][]rune) []rune {
+	mapping, ok := decompositions[r]
+	if !ok {
+		return []rune{r}
+	}
+	var full []rune
+	for _, m := range mapping {
+		full = append(full, decompose(m, decompositions)...)
+	}
+	return full
+}
+
+func tables(version string, decompositions map[rune][]rune, classes map[rune]uint8) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `// Code generated by gen_nfd.go from UnicodeData.txt of the Unicode
+// Character Database %s. DO NOT EDIT.
+
+package cleanup
+
+// The canonical decompositions and combining classes nfd needs. Hangul
+// syllables decompose algorithmically and are left out, and so are the CJK
+// compatibility ideographs, which macOS does not decompose either.
+
+// decompositions maps every character with a canonical decomposition to
+// its full decomposition, in canonical order.
+var decompositions = map[rune]string{
+`, version)
+	var runes []rune
+	for r := range decompositions {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	for _, r := range runes {
+		fmt.Fprintf(&b, "0x%04X: \"%s\",\n", r, escape(decompositions[r]))
+	}
+	b.WriteString(`}
+
+// combiningClasses are the runs of characters with a non-zero canonical
+// combining class, in code point order.
+var combiningClasses = []combiningRange{
+`)
+	runes = runes[:0]
+	for r := range classes {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	for i := 0; i < len(runes); {
+		j := i
+		for j+1 < len(runes) && runes[j+1] == runes[j]+1 && classes[runes[j+1]] == classes[runes[i]] {
+			j++
+		}
+		fmt.Fprintf(&b, "{0x%04X, 0x%04X, %d},\n", runes[i], runes[j], classes[runes[i]])
+		i = j + 1
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// escape writes runes as a Go string literal body: printable ASCII as is,
+// anything else as a \u or \U escape.
+func escape(runes []rune) string {
+	var b strings.Builder
+	for _, r := range runes {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7F:
+			b.WriteRune(r)
+		case r <= 0xFFFF:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			fmt.Fprintf(&b, `\U%08X`, r)
+		}
+	}
+	return b.String()
+}
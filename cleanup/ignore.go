@@ -0,0 +1,197 @@
+package cleanup
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the file listing paths a scan leaves alone, read from
+// every folder between the library root and the paths it checks.
+const IgnoreFileName = ".cleanupignore"
+
+// ignoreRule is one pattern line of an ignore file.
+type ignoreRule struct {
+	elems   []string // "/"-separated pattern, "**" first if unanchored
+	negate  bool     // "!pattern" re-includes what an earlier rule ignored
+	dirOnly bool     // "pattern/" only matches folders
+}
+
+// ignoreFile holds the rules of the ignore file found in dir.
+type ignoreFile struct {
+	dir   string
+	rules []ignoreRule
+}
+
+// parseIgnore reads ignore file rules in gitignore syntax: one glob per
+// line, "#" comments, "!" to re-include, a trailing "/" for folders only.
+// A pattern containing a "/" other than a trailing one is relative to the
+// folder of the ignore file; any other pattern matches at any depth below
+// it. Malformed patterns are skipped.
+func parseIgnore(data []byte) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimSuffix(line, "/")
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		rule.elems = strings.Split(line, "/")
+		if !anchored {
+			rule.elems = append([]string{"**"}, rule.elems...)
+		}
+		valid := true
+		for _, elem := range rule.elems {
+			if _, err := path.Match(elem, ""); err != nil {
+				valid = false
+			}
+		}
+		if valid {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ignores reports whether the path elems, relative to the folder of the
+// ignore file, or one of the folders above them is ignored. As in git, the
+// last matching rule decides, and nothing below an ignored folder can be
+// re-included.
+func (f ignoreFile) ignores(elems []string, isDir bool) bool {
+	for n := 1; n <= len(elems); n++ {
+		dir := n < len(elems) || isDir
+		ignored := false
+		for _, rule := range f.rules {
+			if rule.dirOnly && !dir {
+				continue
+			}
+			if matchElems(rule.elems, elems[:n]) {
+				ignored = !rule.negate
+			}
+		}
+		if ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreFilesFor returns the ignore files of the library root and every
+// folder below it down to the parent of p, reading each folder's file once.
+func (r *scanRun) ignoreFilesFor(p string) []ignoreFile {
+	rel, err := filepath.Rel(r.library, filepath.Dir(p))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	dirs := []string{r.library}
+	if rel != "." {
+		dir := r.library
+		for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+			dir = filepath.Join(dir, elem)
+			dirs = append(dirs, dir)
+		}
+	}
+
+	var files []ignoreFile
+	for _, dir := range dirs {
+		r.mu.Lock()
+		rules, ok := r.ignoreRules[dir]
+		r.mu.Unlock()
+		if !ok {
+			if data, err := r.fsys.ReadFile(filepath.Join(dir, IgnoreFileName)); err == nil {
+				rules = parseIgnore(data)
+			}
+			r.mu.Lock()
+			r.ignoreRules[dir] = rules
+			r.mu.Unlock()
+		}
+		if len(rules) > 0 {
+			files = append(files, ignoreFile{dir: dir, rules: rules})
+		}
+	}
+	return files
+}
+
+// ignored reports whether p is excluded by an ignore file, or is one. isDir
+// is only consulted for rules limited to folders, and only when some ignore
+// file applies.
+func (r *scanRun) ignored(p string, isDir func() bool) bool {
+	if filepath.Base(p) == IgnoreFileName {
+		return true
+	}
+	files := r.ignoreFilesFor(p)
+	if len(files) == 0 {
+		return false
+	}
+	dir := isDir()
+	for _, f := range files {
+		rel, err := filepath.Rel(f.dir, p)
+		if err != nil {
+			continue
+		}
+		if f.ignores(strings.Split(filepath.ToSlash(rel), "/"), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// statIsDir returns an isDir callback for ignored that stats p.
+func (r *scanRun) statIsDir(p string) func() bool {
+	return func() bool {
+		info, err := r.fsys.Stat(p)
+		return err == nil && info.IsDir()
+	}
+}
+
+// withoutIgnored returns the entries of dirPath no ignore file excludes,
+// and whether any were left out.
+func (r *scanRun) withoutIgnored(dirPath string, entries []fs.DirEntry) ([]fs.DirEntry, bool) {
+	var kept []fs.DirEntry
+	for _, entry := range entries {
+		if r.ignored(filepath.Join(dirPath, entry.Name()), entry.IsDir) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept, len(kept) < len(entries)
+}
+
+// ignoredBelow reports whether an ignore file excludes anything at any
+// depth below dirPath, whose entries are given: deleting such a folder would
+// take what is ignored along. Folders that cannot be read count as holding
+// ignored paths, as nothing tells otherwise.
+func (r *scanRun) ignoredBelow(dirPath string, entries []fs.DirEntry) bool {
+	for _, entry := range entries {
+		p := filepath.Join(dirPath, entry.Name())
+		if r.ignored(p, entry.IsDir) {
+			return true
+		}
+		if !entry.IsDir() {
+			continue
+		}
+		children, err := r.fsys.ReadDir(p)
+		if err != nil {
+			r.fail(&ErrUnreadableDir{Path: p, Err: err})
+			return true
+		}
+		if r.ignoredBelow(p, children) {
+			return true
+		}
+	}
+	return false
+}
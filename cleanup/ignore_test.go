@@ -0,0 +1,113 @@
+package cleanup
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for ignore file rules
+// ============================================================================
+
+func TestIgnoreFile_Ignores(t *testing.T) {
+	f := ignoreFile{rules: parseIgnore([]byte(`# Intentional folders
+Staging/
+*.txt
+!keep.txt
+/Studio A/Extras Collection
+Studio B/**/Notes
+[broken
+`))}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"Staging", true, true},
+		{"Studio A/Staging", true, true},
+		{"Studio A/Staging/movie.nfo", false, true},
+		{"Studio A/Staging", false, false},
+		{"Studio A/readme.txt", false, true},
+		{"Studio A/keep.txt", false, false},
+		{"Studio A/Extras Collection", true, true},
+		{"Studio C/Studio A/Extras Collection", true, false},
+		{"Studio B/Movie (2001)/Notes", true, true},
+		{"Studio B/Movie (2001)/movie.nfo", false, false},
+	}
+	for _, tt := range tests {
+		if got := f.ignores(strings.Split(tt.path, "/"), tt.isDir); got != tt.expected {
+			t.Errorf("ignores(%q, dir %v): expected %v, got %v", tt.path, tt.isDir, tt.expected, got)
+		}
+	}
+}
+
+func TestParseIgnore_SkipsCommentsAndMalformedPatterns(t *testing.T) {
+	rules := parseIgnore([]byte("# comment\n\n  \n[broken\n\\#hash\r\n"))
+	if len(rules) != 1 || rules[0].elems[1] != "#hash" {
+		t.Errorf("Expected only the escaped #hash rule, got %v", rules)
+	}
+}
+
+// ============================================================================
+// Tests for .cleanupignore files in scans
+// ============================================================================
+
+func TestScan_IgnoreFiles(t *testing.T) {
+	fsys := cleanuptest.New().
+		File(IgnoreFileName, []byte("Collections/\n")).
+		Studio("Collections").
+		Title("Box Set").Metadata("poster.jpg").
+		Studio("Studio").
+		File(IgnoreFileName, []byte("notes.txt\n")).
+		Metadata("notes.txt", "stray.nfo").
+		Title("Fan Edit (2001)").
+		File(IgnoreFileName, []byte("Workbench/\n")).
+		Metadata("movie.nfo").Dir("Workbench", "draft.nfo").
+		Title("Orphan (2002)").Metadata("movie.nfo").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := map[string]Category{
+		filepath.Join("Studio", "stray.nfo"):     CategoryOrphanedFile,
+		filepath.Join("Studio", "Orphan (2002)"): CategoryOrphanedFolder,
+	}
+	if len(result.Findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %v", len(expected), result.Findings)
+	}
+	for _, f := range result.Findings {
+		if expected[f.Path] != f.Category {
+			t.Errorf("Unexpected finding %v", f)
+		}
+	}
+}
+
+func TestScan_IgnoredBelowOrphans(t *testing.T) {
+	fsys := cleanuptest.New().
+		File(IgnoreFileName, []byte("keep.txt\n")).
+		Studio("Studio").
+		Title("Movie (2001)").Metadata("movie.nfo").Dir("sub").
+		Title("Sequel (2002)").Video("sequel.mkv").Dir("old.trickplay").
+		Title("Orphan (2003)").Metadata("movie.nfo").Dir("sub", "notes.nfo").
+		MapFS()
+	fsys["Studio/Movie (2001)/sub/deeper/keep.txt"] = &fstest.MapFile{Data: []byte("keep")}
+	fsys["Studio/Sequel (2002)/old.trickplay/keep.txt"] = &fstest.MapFile{Data: []byte("keep")}
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	expected := []string{filepath.Join("Studio", "Orphan (2003)")}
+	if strings.Join(result.OrphanedFolders, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected only %v orphaned, folders holding an ignored file kept, got %v", expected, result.OrphanedFolders)
+	}
+}
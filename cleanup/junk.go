@@ -0,0 +1,64 @@
+package cleanup
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files operating systems leave behind in folders they display: Finder
+// (.DS_Store) and Windows Explorer (Thumbs.db, desktop.ini), matched
+// case-insensitively, plus the AppleDouble files ("._" prefix) macOS
+// writes on non-Apple filesystems
+var junkFileNames = map[string]bool{
+	".ds_store":   true,
+	"thumbs.db":   true,
+	"desktop.ini": true,
+}
+
+// isJunk reports whether the file name is OS junk, which neither keeps a
+// folder from being empty nor makes it orphaned.
+func isJunk(name string) bool {
+	return junkFileNames[strings.ToLower(name)] || (strings.HasPrefix(name, "._") && len(name) > 2)
+}
+
+// withoutJunk returns entries without the OS junk files.
+func withoutJunk(entries []fs.DirEntry) []fs.DirEntry {
+	var kept []fs.DirEntry
+	for _, entry := range entries {
+		if entry.IsDir() || !isJunk(entry.Name()) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// removeJunk removes the OS junk files directly in dirPath. Errors are left
+// for the removal of the folder to report.
+func removeJunk(dirPath string) {
+	entries, err := os.ReadDir(longPath(dirPath))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && isJunk(entry.Name()) {
+			os.Remove(longPath(filepath.Join(dirPath, entry.Name())))
+		}
+	}
+}
+
+// reportJunk reports the OS junk files among the entries of dirPath.
+func (r *scanRun) reportJunk(dirPath string, entries []fs.DirEntry) {
+	for _, entry := range entries {
+		if entry.IsDir() || !isJunk(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dirPath, entry.Name())
+		var usage Usage
+		if info, err := entry.Info(); err == nil {
+			usage = fileUsage(path, info)
+		}
+		r.emit(Finding{Category: CategoryJunkFile, Path: path, Usage: usage})
+	}
+}
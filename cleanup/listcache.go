@@ -0,0 +1,276 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// listingCacheVersion is bumped whenever the format of cached listings
+// changes, so caches written by older versions are dropped.
+const listingCacheVersion = 1
+
+// ListingCache remembers, between scans, the listing of every folder a
+// Scanner read along with the modification time the folder had. A Scanner
+// given one with WithListingCache reads a folder whose time did not change
+// from the cache, with one stat instead of a listing and a stat per entry.
+//
+// Unlike a ScanState, a ListingCache does not depend on the scanner options,
+// so it keeps serving when they change between dry runs. Adding, removing or
+// renaming an entry changes the time of its folder; a file rewritten in
+// place keeps the size and time it was listed with until its folder changes.
+// Folders without a modification time, such as the prefixes of an object
+// store, are never cached.
+//
+// A ListingCache is safe for concurrent use.
+type ListingCache struct {
+	mu      sync.Mutex
+	folders map[string]*cachedFolder // by folder path
+}
+
+// cachedFolder is the listing of one folder.
+type cachedFolder struct {
+	ModTime time.Time      `json:"mtime"`
+	Entries []*cachedEntry `json:"entries"`
+}
+
+// cachedEntry is one entry of a cached listing, as an fs.DirEntry and the
+// fs.FileInfo it was listed with.
+type cachedEntry struct {
+	EntryName    string      `json:"name"`
+	EntrySize    int64       `json:"size"`
+	EntryMode    fs.FileMode `json:"mode"`
+	EntryModTime time.Time   `json:"mtime"`
+	Stat         *fileStat   `json:"stat,omitempty"`
+}
+
+func (e *cachedEntry) Name() string               { return e.EntryName }
+func (e *cachedEntry) Size() int64                { return e.EntrySize }
+func (e *cachedEntry) Mode() fs.FileMode          { return e.EntryMode }
+func (e *cachedEntry) ModTime() time.Time         { return e.EntryModTime }
+func (e *cachedEntry) IsDir() bool                { return e.EntryMode.IsDir() }
+func (e *cachedEntry) Type() fs.FileMode          { return e.EntryMode.Type() }
+func (e *cachedEntry) Info() (fs.FileInfo, error) { return e, nil }
+
+// Sys returns the *fileStat the entry was listed with, so hard links and
+// owners are still recognised in cached listings.
+func (e *cachedEntry) Sys() any {
+	if e.Stat == nil {
+		return nil
+	}
+	return e.Stat
+}
+
+// fileStat is the part of what stat reports that the scanner uses beyond an
+// fs.FileInfo.
+type fileStat struct {
+	Dev   uint64 `json:"dev"`
+	Ino   uint64 `json:"ino"`
+	Nlink uint64 `json:"nlink"`
+	Uid   uint32 `json:"uid"`
+	Gid   uint32 `json:"gid"`
+}
+
+// listingCacheFile is the JSON form of a ListingCache.
+type listingCacheFile struct {
+	Version int                      `json:"version"`
+	Folders map[string]*cachedFolder `json:"folders"`
+}
+
+// NewListingCache returns a ListingCache that remembers nothing yet.
+func NewListingCache() *ListingCache {
+	return &ListingCache{folders: map[string]*cachedFolder{}}
+}
+
+// LoadListingCache reads the cache saved at path. A missing file, or one
+// saved by another version, gives an empty cache.
+func LoadListingCache(path string) (*ListingCache, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewListingCache(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing cache: %w", err)
+	}
+	var file listingCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("listing cache %s: %w", path, err)
+	}
+	cache := NewListingCache()
+	if file.Version == listingCacheVersion && file.Folders != nil {
+		cache.folders = file.Folders
+	}
+	return cache, nil
+}
+
+// Save writes the cache to path, replacing the file at once so an
+// interrupted save leaves the previous cache. Folders that were deleted, as
+// the listing of their parent now shows, are left out.
+func (c *ListingCache) Save(path string) error {
+	c.mu.Lock()
+	data, err := json.Marshal(listingCacheFile{Version: listingCacheVersion, Folders: c.live()})
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("listing cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("listing cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("listing cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("listing cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("listing cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("listing cache: %w", err)
+	}
+	return nil
+}
+
+// live returns the cached folders that are still there as far as the cache
+// knows: library roots, whose parents are not cached, and folders listed by
+// a live parent.
+func (c *ListingCache) live() map[string]*cachedFolder {
+	paths := make([]string, 0, len(c.folders))
+	for path := range c.folders {
+		paths = append(paths, path)
+	}
+	// Parents are shorter than their folders, so they are decided first
+	sort.Slice(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+	live := make(map[string]*cachedFolder, len(c.folders))
+	for _, path := range paths {
+		parent := filepath.Dir(path)
+		if _, cached := c.folders[parent]; !cached || parent == path {
+			live[path] = c.folders[path]
+			continue
+		}
+		if folder := live[parent]; folder != nil && folder.has(filepath.Base(path)) {
+			live[path] = c.folders[path]
+		}
+	}
+	return live
+}
+
+// has reports whether the folder lists a folder called name.
+func (f *cachedFolder) has(name string) bool {
+	for _, e := range f.Entries {
+		if e.EntryName == name && e.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the listing of the folder at path if it was cached with
+// modTime.
+func (c *ListingCache) lookup(path string, modTime time.Time) ([]fs.DirEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	folder := c.folders[path]
+	if folder == nil || !folder.ModTime.Equal(modTime) {
+		return nil, false
+	}
+	entries := make([]fs.DirEntry, len(folder.Entries))
+	for i, e := range folder.Entries {
+		entries[i] = e
+	}
+	return entries, true
+}
+
+// store caches the listing of the folder at path.
+func (c *ListingCache) store(path string, folder *cachedFolder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.folders[path] = folder
+}
+
+// WithListingCache makes the scanner read folders that did not change from
+// cache, and record in cache the listings of the others. It is meant for
+// local disks and mounted shares; remote filesystems list whole libraries
+// at once anyway.
+func WithListingCache(cache *ListingCache) Option {
+	return func(s *Scanner) {
+		s.listings = cache
+	}
+}
+
+// listingRace is how recently a folder may have changed for its listing not
+// to be cached: a change made in the same tick of the filesystem clock,
+// right after the listing, would not change the folder's time.
+const listingRace = 2 * time.Second
+
+// cachedFS reads folder listings from cache when the folder did not change
+// since, and passes everything else through to fsys.
+type cachedFS struct {
+	fsys  FS
+	cache *ListingCache
+}
+
+func (c cachedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	// The time is taken first, so a change made while the folder is listed
+	// shows next time
+	info, err := c.fsys.Stat(name)
+	if err != nil || info.ModTime().IsZero() {
+		return c.fsys.ReadDir(name)
+	}
+	if entries, ok := c.cache.lookup(name, info.ModTime()); ok {
+		return entries, nil
+	}
+	entries, err := c.fsys.ReadDir(name)
+	if err != nil || time.Since(info.ModTime()) < listingRace {
+		return entries, err
+	}
+	folder := &cachedFolder{ModTime: info.ModTime(), Entries: make([]*cachedEntry, 0, len(entries))}
+	for _, entry := range entries {
+		entryInfo, err := entry.Info()
+		if err != nil {
+			// Gone since the listing: the folder changed and is not cached
+			return entries, nil
+		}
+		cached := &cachedEntry{EntryName: entry.Name(), EntrySize: entryInfo.Size(),
+			EntryMode: entryInfo.Mode(), EntryModTime: entryInfo.ModTime()}
+		if st, ok := statOf(entryInfo); ok {
+			cached.Stat = st
+		}
+		folder.Entries = append(folder.Entries, cached)
+	}
+	c.cache.store(name, folder)
+	return entries, nil
+}
+
+func (c cachedFS) Stat(name string) (fs.FileInfo, error) {
+	return c.fsys.Stat(name)
+}
+
+func (c cachedFS) ReadFile(name string) ([]byte, error) {
+	return c.fsys.ReadFile(name)
+}
+
+func (c cachedFS) Open(name string) (fs.File, error) {
+	opener, ok := c.fsys.(fileOpener)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return opener.Open(name)
+}
+
+func (c cachedFS) Readlink(name string) (string, error) {
+	links, ok := c.fsys.(linkReader)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	return links.Readlink(name)
+}
@@ -0,0 +1,157 @@
+package cleanup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Tests for WithListingCache
+// ============================================================================
+
+// backdate sets the times of every folder in dir an hour back, so their
+// listings are old enough to be cached.
+func backdate(t *testing.T, dir string) {
+	t.Helper()
+	past := time.Now().Add(-time.Hour)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, past, past)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// scanWithCache scans dir with cache and returns the sorted findings and
+// the folders that were listed.
+func scanWithCache(t *testing.T, dir string, cache *ListingCache, opts ...Option) ([]string, *countingFS) {
+	t.Helper()
+	fsys := &countingFS{FS: osFS{}, listings: map[string]int{}}
+	result := &CleanupResult{}
+	opts = append([]Option{WithFS(fsys), WithListingCache(cache)}, opts...)
+	if err := NewScanner(opts...).Scan(context.Background(), dir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	var findings []string
+	for _, f := range result.Findings {
+		rel, _ := filepath.Rel(dir, f.Path)
+		findings = append(findings, string(f.Category)+" "+rel)
+	}
+	sort.Strings(findings)
+	return findings, fsys
+}
+
+func TestListingCache_ReusesUnchangedFolders(t *testing.T) {
+	dir := stateLibrary(t)
+	backdate(t, dir)
+	cache := NewListingCache()
+
+	first, _ := scanWithCache(t, dir, cache)
+	if len(first) != 2 {
+		t.Fatalf("Expected 2 findings, got %v", first)
+	}
+	second, fsys := scanWithCache(t, dir, cache)
+	if len(second) != 2 || second[0] != first[0] || second[1] != first[1] {
+		t.Errorf("Expected the same findings %v, got %v", first, second)
+	}
+	if len(fsys.listings) != 0 {
+		t.Errorf("Expected no folder listed again, got %v", fsys.listings)
+	}
+}
+
+func TestListingCache_OptionsChange(t *testing.T) {
+	dir := stateLibrary(t)
+	backdate(t, dir)
+	cache := NewListingCache()
+	scanWithCache(t, dir, cache)
+
+	// Unlike a scan state, the cache does not depend on the options
+	findings, fsys := scanWithCache(t, dir, cache, WithExtensions(".mkv", ".nfo"))
+	if len(findings) != 1 {
+		t.Errorf("Expected only the empty folder, got %v", findings)
+	}
+	if len(fsys.listings) != 0 {
+		t.Errorf("Expected no folder listed again, got %v", fsys.listings)
+	}
+}
+
+func TestListingCache_RelistsChangedFolders(t *testing.T) {
+	dir := stateLibrary(t)
+	backdate(t, dir)
+	cache := NewListingCache()
+	scanWithCache(t, dir, cache)
+
+	orphan := filepath.Join(dir, "Studio", "Orphan (2002)")
+	createFile(t, filepath.Join(orphan, "movie.mkv"))
+
+	findings, fsys := scanWithCache(t, dir, cache)
+	if len(findings) != 1 {
+		t.Errorf("Expected only the empty folder, got %v", findings)
+	}
+	if fsys.listings[orphan] != 1 {
+		t.Errorf("Expected the changed folder listed again, got %v", fsys.listings)
+	}
+	if n := fsys.listings[filepath.Join(dir, "Studio", "Movie (2001)")]; n != 0 {
+		t.Errorf("Expected the unchanged folder not to be listed again, got %d listings", n)
+	}
+}
+
+func TestListingCache_SkipsRecentFolders(t *testing.T) {
+	dir := stateLibrary(t)
+	cache := NewListingCache()
+	scanWithCache(t, dir, cache)
+
+	// Just created: a change in the same clock tick would go unnoticed
+	_, fsys := scanWithCache(t, dir, cache)
+	if fsys.listings[filepath.Join(dir, "Studio", "Orphan (2002)")] == 0 {
+		t.Error("Expected a folder changed right before the scan to be listed again")
+	}
+}
+
+func TestListingCache_SaveAndLoad(t *testing.T) {
+	dir := stateLibrary(t)
+	backdate(t, dir)
+	cache := NewListingCache()
+	scanWithCache(t, dir, cache)
+
+	// The studio folder is listed again without the deleted title folder,
+	// which is dropped along with the folders below it
+	if err := os.RemoveAll(filepath.Join(dir, "Studio", "Movie (2001)")); err != nil {
+		t.Fatal(err)
+	}
+	backdate(t, dir)
+	scanWithCache(t, dir, cache)
+
+	path := filepath.Join(setupTestDir(t), "cache", "listings.json")
+	if err := cache.Save(path); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	loaded, err := LoadListingCache(path)
+	if err != nil {
+		t.Fatalf("LoadListingCache returned error: %v", err)
+	}
+	for _, gone := range []string{"Movie (2001)", filepath.Join("Movie (2001)", "Subs")} {
+		if _, ok := loaded.folders[filepath.Join(dir, "Studio", gone)]; ok {
+			t.Errorf("Expected the deleted folder %s left out of the saved cache", gone)
+		}
+	}
+	findings, fsys := scanWithCache(t, dir, loaded)
+	if len(findings) != 2 {
+		t.Errorf("Expected 2 findings, got %v", findings)
+	}
+	if len(fsys.listings) != 0 {
+		t.Errorf("Expected the loaded cache to be used, got %v", fsys.listings)
+	}
+
+	missing, err := LoadListingCache(filepath.Join(dir, "missing.json"))
+	if err != nil || len(missing.folders) != 0 {
+		t.Errorf("Expected an empty cache for a missing file, got %v (%v)", missing.folders, err)
+	}
+}
@@ -0,0 +1,129 @@
+package cleanup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LibraryLock keeps other runs off a library while one scans it or deletes
+// from it. It is a file in a lock directory shared by the runs, holding the
+// process id and host of its owner. Use LockLibrary.
+type LibraryLock struct {
+	Path string
+}
+
+// lockOwner is the content of a lock file.
+type lockOwner struct {
+	PID   int       `json:"pid"`
+	Host  string    `json:"host"`
+	Since time.Time `json:"since"`
+}
+
+// DefaultLockDir is the lock directory of runs on this machine.
+func DefaultLockDir() string {
+	return filepath.Join(os.TempDir(), "video-folder-cleanup")
+}
+
+// LockLibrary locks library, a library root or remote library, in dir. It
+// returns a *LockedError when another run holds the lock. A lock left behind
+// by a run on this host that is no longer running is stale and taken over;
+// locks of other hosts are only ever released by their owner.
+func LockLibrary(dir, library string) (*LibraryLock, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, fmt.Errorf("lock %s: %w", library, err)
+	}
+	path := filepath.Join(dir, lockName(library))
+	host, _ := os.Hostname()
+	data, err := json.Marshal(lockOwner{PID: os.Getpid(), Host: host, Since: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	// The lock is written in full under a temporary name and linked into
+	// place, so no other run ever reads a half-written one
+	tmp, err := os.CreateTemp(dir, ".lock-*")
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", library, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", library, err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := os.Link(tmp.Name(), path)
+		if err == nil {
+			return &LibraryLock{Path: path}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("lock %s: %w", library, err)
+		}
+		owner, readErr := readLockOwner(path)
+		if errors.Is(readErr, fs.ErrNotExist) && attempt == 0 {
+			continue // released in the meantime
+		}
+		if readErr == nil && attempt == 0 && owner.Host == host && !processAlive(owner.PID) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("lock %s: removing stale lock: %w", library, err)
+			}
+			continue
+		}
+		locked := &LockedError{Library: library, Path: path}
+		if readErr == nil {
+			locked.PID, locked.Host, locked.Since = owner.PID, owner.Host, owner.Since
+		}
+		return nil, locked
+	}
+}
+
+// Unlock releases the lock.
+func (l *LibraryLock) Unlock() error {
+	if err := os.Remove(l.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unlock: %w", err)
+	}
+	return nil
+}
+
+// lockName is the lock file name of library: its base name, for whoever
+// lists the lock directory, and a hash of its absolute path, so that two
+// libraries with the same base name get their own lock.
+func lockName(library string) string {
+	key := library
+	if !strings.Contains(library, "://") {
+		if abs, err := filepath.Abs(library); err == nil {
+			key = abs
+		}
+	}
+	sum := sha256.Sum256([]byte(key))
+	base := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, filepath.Base(key))
+	return base + "-" + hex.EncodeToString(sum[:8]) + ".lock"
+}
+
+func readLockOwner(path string) (lockOwner, error) {
+	var owner lockOwner
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return owner, err
+	}
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return owner, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return owner, nil
+}
@@ -0,0 +1,104 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLock writes a lock of library in dir as owner would have.
+func writeLock(t *testing.T, dir, library string, owner lockOwner) string {
+	t.Helper()
+	data, err := json.Marshal(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, lockName(library))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// ============================================================================
+// Tests for LockLibrary
+// ============================================================================
+
+func TestLockLibrary_Exclusive(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	dir := filepath.Join(tempDir, "locks")
+
+	lock, err := LockLibrary(dir, "/media/Movies")
+	if err != nil {
+		t.Fatalf("LockLibrary returned error: %v", err)
+	}
+	_, err = LockLibrary(dir, "/media/Movies")
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected a LockedError, got %v", err)
+	}
+	if locked.PID != os.Getpid() {
+		t.Errorf("Expected the lock owned by %d, got %d", os.Getpid(), locked.PID)
+	}
+
+	other, err := LockLibrary(dir, "/media/Shows")
+	if err != nil {
+		t.Fatalf("Expected another library to lock independently, got %v", err)
+	}
+	other.Unlock()
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock returned error: %v", err)
+	}
+	lock, err = LockLibrary(dir, "/media/Movies")
+	if err != nil {
+		t.Fatalf("Expected the lock free after Unlock, got %v", err)
+	}
+	lock.Unlock()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files left in the lock directory, got %v", entries)
+	}
+}
+
+func TestLockLibrary_SameBaseName(t *testing.T) {
+	if lockName("/mnt/a/Movies") == lockName("/mnt/b/Movies") {
+		t.Error("Expected libraries with the same base name to get their own lock")
+	}
+}
+
+func TestLockLibrary_TakesOverStaleLock(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	host, _ := os.Hostname()
+
+	// No process has a negative id
+	writeLock(t, tempDir, "/media/Movies", lockOwner{PID: -1, Host: host, Since: time.Now()})
+	lock, err := LockLibrary(tempDir, "/media/Movies")
+	if err != nil {
+		t.Fatalf("Expected the stale lock taken over, got %v", err)
+	}
+	defer lock.Unlock()
+	owner, err := readLockOwner(lock.Path)
+	if err != nil || owner.PID != os.Getpid() {
+		t.Errorf("Expected the lock owned by %d, got %+v (%v)", os.Getpid(), owner, err)
+	}
+}
+
+func TestLockLibrary_KeepsLocksOfOtherHosts(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	path := writeLock(t, tempDir, "/media/Movies", lockOwner{PID: -1, Host: "other-host", Since: time.Now()})
+	_, err := LockLibrary(tempDir, "/media/Movies")
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected a LockedError, got %v", err)
+	}
+	if locked.Host != "other-host" || locked.Path != path {
+		t.Errorf("Expected the lock of other-host at %s, got %+v", path, locked)
+	}
+}
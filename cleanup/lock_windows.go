@@ -0,0 +1,24 @@
+package cleanup
+
+import "syscall"
+
+// stillActive is the exit code of a process that has not exited yet.
+const stillActive = 259
+
+// processAlive reports whether a process with the id pid is running.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// Running under another user, or gone
+		return err == syscall.ERROR_ACCESS_DENIED
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package cleanup
+
+// longPath returns p: only Windows limits the length of paths given to it.
+func longPath(p string) string {
+	return p
+}
+
+// extendedPath returns p, as longPath does.
+func extendedPath(p string) string {
+	return p
+}
@@ -0,0 +1,50 @@
+package cleanup
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxShortPath is the length from which Windows API calls need the
+// extended-length form of a path: MAX_PATH (260) less the 12 characters a
+// folder must leave for an 8.3 file name.
+const maxShortPath = 248
+
+// longPath returns p in the extended-length form (\\?\C:\... or
+// \\?\UNC\server\share\...) once its absolute path is too long for the
+// Windows API, so deep studio/title/metadata paths can be read and deleted
+// on NTFS. The os package only does this for absolute local paths on older
+// Go releases, which leaves relative library paths and SMB shares out.
+// Paths that cannot be made absolute are returned as given.
+func longPath(p string) string {
+	if strings.HasPrefix(p, `\\?\`) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil || len(abs) < maxShortPath {
+		return p
+	}
+	return extended(abs)
+}
+
+// extendedPath returns p in the extended-length form whatever its length,
+// for walks that go deeper than p itself. Paths that cannot be made
+// absolute are returned as given.
+func extendedPath(p string) string {
+	if strings.HasPrefix(p, `\\?\`) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return extended(abs)
+}
+
+// extended prefixes the absolute path abs.
+func extended(abs string) string {
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
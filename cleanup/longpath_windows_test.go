@@ -0,0 +1,65 @@
+package cleanup
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ============================================================================
+// Tests for longPath
+// ============================================================================
+
+func TestLongPath(t *testing.T) {
+	deep := strings.Repeat(`\Studio Name With A Long Title`, 10)
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"short path", `C:\Movies\Studio`, `C:\Movies\Studio`},
+		{"long local path", `C:\Movies` + deep, `\\?\C:\Movies` + deep},
+		{"long share path", `\\nas\media` + deep, `\\?\UNC\nas\media` + deep},
+		{"already extended", `\\?\C:\Movies` + deep, `\\?\C:\Movies` + deep},
+		{"cleaned before prefixing", `C:\Movies\.\Other\..` + deep, `\\?\C:\Movies` + deep},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := longPath(tt.path); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWalkDir_BelowLongPaths(t *testing.T) {
+	root := t.TempDir()
+	deep := filepath.Join(root, strings.Repeat(`Studio Name With A Long Title\`, 10))
+	if err := os.MkdirAll(longPath(deep), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(deep, "movie.nfo")
+	if err := os.WriteFile(longPath(file), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	err := walkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(path, `\\?\`) {
+			t.Errorf("Expected %s in the form of the root, got the extended form", path)
+		}
+		found = found || path == file
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkDir returned error: %v", err)
+	}
+	if !found {
+		t.Errorf("Expected %s walked", file)
+	}
+}
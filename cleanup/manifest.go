@@ -0,0 +1,316 @@
+package cleanup
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Actions recorded in a deletion manifest.
+const (
+	ManifestDeleted  = "deleted"  // removed for good
+	ManifestMoved    = "moved"    // moved by a MovingStrategy, see ManifestEntry.MovedTo
+	ManifestRestored = "restored" // moved back by Manifest.Undo
+)
+
+// ManifestEntry records one item a Deleter disposed of, or Undo brought back.
+type ManifestEntry struct {
+	Run      string    `json:"run"`
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Path     string    `json:"path"` // absolute path of the item
+	Library  string    `json:"library,omitempty"`
+	Category Category  `json:"category"`
+	Strategy string    `json:"strategy"`
+	Bytes    int64     `json:"bytes"`
+	// SHA256 is the checksum of the file, or for a folder of the names and
+	// contents of everything below it, taken right before it was disposed
+	// of. It is empty for items that could not be read locally.
+	SHA256  string `json:"sha256,omitempty"`
+	MovedTo string `json:"moved_to,omitempty"`
+	Record  string `json:"record,omitempty"` // see Moved.Record
+}
+
+// Manifest is an append-only JSON Lines file recording everything the
+// Deleters given it with WithManifest dispose of, one ManifestEntry per line,
+// as part of the run Run. Entries are written as soon as an item is gone, so
+// the file stays complete if the run is interrupted. Use OpenManifest; it is
+// safe for concurrent use.
+type Manifest struct {
+	Path string
+	Run  string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRunID returns an identifier for a run, made of its start time and a
+// random suffix.
+func NewRunID() string {
+	suffix := make([]byte, 2)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// OpenManifest opens the manifest at path for appending the entries of run,
+// creating it if needed.
+func OpenManifest(path, run string) (*Manifest, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	return &Manifest{Path: path, Run: run, file: file}, nil
+}
+
+// Close closes the manifest file.
+func (m *Manifest) Close() error {
+	return m.file.Close()
+}
+
+// append writes entry to the manifest and flushes it to disk.
+func (m *Manifest) append(entry ManifestEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return m.file.Sync()
+}
+
+// ReadManifest returns the entries of the manifest at path, oldest first. A
+// missing file has none.
+func ReadManifest(path string) ([]ManifestEntry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []ManifestEntry
+	lines := bufio.NewScanner(file)
+	lines.Buffer(nil, 1<<20)
+	for n := 1; lines.Scan(); n++ {
+		if len(lines.Bytes()) == 0 {
+			continue
+		}
+		var entry ManifestEntry
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, n, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, lines.Err()
+}
+
+// LastRun returns the last run of entries that deleted or moved anything,
+// or "" if there is none.
+func LastRun(entries []ManifestEntry) string {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Action == ManifestDeleted || entries[i].Action == ManifestMoved {
+			return entries[i].Run
+		}
+	}
+	return ""
+}
+
+// WithManifest records every item the Deleter disposes of in m, with its
+// checksum. Checksums read every file before it goes, which costs as much
+// as a copy of what is deleted.
+func WithManifest(m *Manifest) DeleterOption {
+	return func(d *Deleter) {
+		d.manifest = m
+	}
+}
+
+// record adds to the manifest that f was disposed of by strategy.
+func (m *Manifest) record(f Finding, strategy string, moved Moved, sum string, bytes int64) error {
+	path, err := filepath.Abs(f.Path)
+	if err != nil {
+		path = f.Path
+	}
+	entry := ManifestEntry{Run: m.Run, Time: time.Now().UTC(), Action: ManifestDeleted, Path: path,
+		Library: f.Library, Category: f.Category, Strategy: strategy, Bytes: bytes, SHA256: sum,
+		MovedTo: moved.To, Record: moved.Record}
+	if moved.To != "" {
+		entry.Action = ManifestMoved
+	}
+	if err := m.append(entry); err != nil {
+		return fmt.Errorf("%s but not recorded in %s: %w", entry.Action, m.Path, err)
+	}
+	return nil
+}
+
+// Undo moves the items run moved back where they were, newest first, and
+// records each in the manifest. Items already moved back are left alone.
+// Items run removed for good cannot be brought back and are returned as
+// lost. An item is not moved back over something that took its place since;
+// that is reported as a *RestoreError, as are items that cannot be moved.
+func (m *Manifest) Undo(run string) (restored, lost []ManifestEntry, err error) {
+	entries, err := ReadManifest(m.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := map[string]bool{}
+	var moved []ManifestEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Run != run {
+			continue
+		}
+		switch {
+		case entry.Action == ManifestRestored:
+			done[entry.MovedTo] = true
+		case entry.Action == ManifestDeleted:
+			lost = append(lost, entry)
+		case entry.Action == ManifestMoved && !done[entry.MovedTo]:
+			moved = append(moved, entry)
+		}
+	}
+
+	// Quarantined items go back through their quarantine directory, which
+	// drops them from its own manifest; one pass per directory
+	var errs []error
+	quarantined := map[string]map[string]ManifestEntry{}
+	for _, entry := range moved {
+		if entry.Strategy == "quarantine" && entry.Record != "" {
+			if quarantined[entry.Record] == nil {
+				quarantined[entry.Record] = map[string]ManifestEntry{}
+			}
+			quarantined[entry.Record][entry.MovedTo] = entry
+			continue
+		}
+		if err := restoreMoved(entry); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		restored = append(restored, entry)
+	}
+	for dir, items := range quarantined {
+		back, err := RestoreQuarantine(dir, func(q QuarantineEntry) bool {
+			_, ok := items[q.Quarantined]
+			return ok
+		})
+		for _, q := range back {
+			restored = append(restored, items[q.Quarantined])
+			delete(items, q.Quarantined)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, entry := range items {
+			errs = append(errs, &RestoreError{Path: entry.Path, Cause: fmt.Errorf("not in quarantine %s any more", dir)})
+		}
+	}
+
+	for _, entry := range restored {
+		entry.Time, entry.Action = time.Now().UTC(), ManifestRestored
+		if err := m.append(entry); err != nil {
+			errs = append(errs, fmt.Errorf("%s restored but not recorded in %s: %w", entry.Path, m.Path, err))
+		}
+	}
+	return restored, lost, errors.Join(errs...)
+}
+
+// restoreMoved moves the item of entry back to its path.
+func restoreMoved(entry ManifestEntry) error {
+	if _, err := os.Lstat(longPath(entry.Path)); err == nil {
+		return &RestoreError{Path: entry.Path, Cause: fs.ErrExist}
+	}
+	if err := os.MkdirAll(longPath(filepath.Dir(entry.Path)), 0755); err != nil {
+		return &RestoreError{Path: entry.Path, Cause: err}
+	}
+	if err := movePath(entry.MovedTo, entry.Path); err != nil {
+		return &RestoreError{Path: entry.Path, Cause: err}
+	}
+	if entry.Record != "" {
+		// The .trashinfo of an item no longer in the trash
+		_ = os.Remove(entry.Record)
+	}
+	return nil
+}
+
+// checksum returns the SHA-256 of the file at path, or for a folder of the
+// names, types and contents of everything below it, and the size of the
+// files read.
+func checksum(path string) (sum string, bytes int64, err error) {
+	info, err := os.Lstat(longPath(path))
+	if err != nil {
+		return "", 0, err
+	}
+	if !info.IsDir() {
+		return fileChecksum(path, info)
+	}
+	h := sha256.New()
+	err = walkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == path {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		line := "d"
+		if !d.IsDir() {
+			sum, n, err := fileChecksum(p, info)
+			if err != nil {
+				return err
+			}
+			line, bytes = "f "+sum, bytes+n
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), line)
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), bytes, nil
+}
+
+// fileChecksum returns the SHA-256 of the file at path and its size. A
+// symlink is summed by its target.
+func fileChecksum(path string, info fs.FileInfo) (string, int64, error) {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(longPath(path))
+		if err != nil {
+			return "", 0, err
+		}
+		sum := sha256.Sum256([]byte(target))
+		return hex.EncodeToString(sum[:]), 0, nil
+	}
+	file, err := os.Open(longPath(path))
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
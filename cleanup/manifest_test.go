@@ -0,0 +1,167 @@
+package cleanup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// deleteWithManifest deletes a fresh deletable result with strategy as run,
+// recording it in the manifest at manifestPath.
+func deleteWithManifest(t *testing.T, libraryDir, manifestPath, run string, strategy DeleteStrategy) []string {
+	t.Helper()
+	result, paths := deletableResult(t, libraryDir)
+	manifest, err := OpenManifest(manifestPath, run)
+	if err != nil {
+		t.Fatalf("OpenManifest returned error: %v", err)
+	}
+	defer manifest.Close()
+	report, err := NewDeleter(strategy, WithManifest(manifest), WithDeleteWorkers(4)).Delete(context.Background(), result)
+	if err != nil || len(report.Failures) > 0 {
+		t.Fatalf("Delete failed: %v %v", err, report.Failures)
+	}
+	return paths
+}
+
+// ============================================================================
+// Tests for Manifest
+// ============================================================================
+
+func TestManifest_RecordsDeletions(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "manifest.jsonl")
+
+	paths := deleteWithManifest(t, filepath.Join(tempDir, "Library"), path, "run-1", nil)
+
+	entries, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("ReadManifest returned error: %v", err)
+	}
+	if len(entries) != len(paths) {
+		t.Fatalf("Expected %d entries, got %+v", len(paths), entries)
+	}
+	content := sha256.Sum256([]byte("test content"))
+	for _, entry := range entries {
+		if entry.Run != "run-1" || entry.Action != ManifestDeleted || entry.Strategy != "permanent" || entry.MovedTo != "" {
+			t.Errorf("Expected a permanent deletion of run-1, got %+v", entry)
+		}
+		if entry.Category == CategoryOrphanedFile && (entry.SHA256 != hex.EncodeToString(content[:]) || entry.Bytes != 12) {
+			t.Errorf("Expected the checksum and size of the file, got %+v", entry)
+		}
+		if entry.Category == CategoryOrphanedFolder && (entry.SHA256 == "" || entry.Bytes != 12) {
+			t.Errorf("Expected the checksum and size of the folder, got %+v", entry)
+		}
+	}
+	if run := LastRun(entries); run != "run-1" {
+		t.Errorf("Expected run-1 as the last run, got %q", run)
+	}
+}
+
+func TestManifest_UndoTrash(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "manifest.jsonl")
+	strategy := TrashDirStrategy{Dir: filepath.Join(tempDir, "Trash")}
+
+	first := deleteWithManifest(t, filepath.Join(tempDir, "First"), path, "run-1", strategy)
+	second := deleteWithManifest(t, filepath.Join(tempDir, "Second"), path, "run-2", strategy)
+
+	manifest, err := OpenManifest(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manifest.Close()
+	restored, lost, err := manifest.Undo("run-2")
+	if err != nil {
+		t.Fatalf("Undo returned error: %v", err)
+	}
+	if len(restored) != len(second) || len(lost) != 0 {
+		t.Errorf("Expected %d restored and none lost, got %d and %d", len(second), len(restored), len(lost))
+	}
+	for _, p := range second {
+		if _, err := os.Lstat(p); err != nil {
+			t.Errorf("Expected %s to be back, got %v", p, err)
+		}
+	}
+	for _, p := range first {
+		if _, err := os.Lstat(p); err == nil {
+			t.Errorf("Expected %s of the other run to stay in the trash", p)
+		}
+	}
+
+	// Undone already: nothing left to move back
+	restored, _, err = manifest.Undo("run-2")
+	if err != nil || len(restored) != 0 {
+		t.Errorf("Expected nothing to undo twice, got %d restored (%v)", len(restored), err)
+	}
+	entries, _ := ReadManifest(path)
+	if run := LastRun(entries); run != "run-2" {
+		t.Errorf("Expected run-2 as the last run, got %q", run)
+	}
+}
+
+func TestManifest_UndoQuarantine(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "manifest.jsonl")
+	dir := filepath.Join(tempDir, "Quarantine")
+
+	paths := deleteWithManifest(t, filepath.Join(tempDir, "Library"), path, "run-1", NewQuarantine(dir))
+
+	manifest, err := OpenManifest(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manifest.Close()
+	restored, _, err := manifest.Undo("run-1")
+	if err != nil || len(restored) != len(paths) {
+		t.Fatalf("Expected %d restored, got %d (%v)", len(paths), len(restored), err)
+	}
+	if entries, _ := ReadQuarantine(dir); len(entries) != 0 {
+		t.Errorf("Expected the quarantine manifest emptied, got %v", entries)
+	}
+}
+
+func TestManifest_UndoPermanent(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "manifest.jsonl")
+
+	paths := deleteWithManifest(t, filepath.Join(tempDir, "Library"), path, "run-1", nil)
+
+	manifest, err := OpenManifest(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manifest.Close()
+	restored, lost, err := manifest.Undo("run-1")
+	if err != nil || len(restored) != 0 || len(lost) != len(paths) {
+		t.Errorf("Expected %d lost and none restored, got %d and %d (%v)", len(paths), len(lost), len(restored), err)
+	}
+}
+
+func TestManifest_UndoRefusesOccupiedPath(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "manifest.jsonl")
+
+	paths := deleteWithManifest(t, filepath.Join(tempDir, "Library"), path, "run-1", RenameStrategy{})
+	createFile(t, paths[1])
+
+	manifest, err := OpenManifest(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manifest.Close()
+	restored, _, err := manifest.Undo("run-1")
+	if len(restored) != len(paths)-1 || len(SplitErrors(err)) != 1 {
+		t.Errorf("Expected all but the replaced file restored, got %d restored (%v)", len(restored), err)
+	}
+	if _, err := os.Lstat(paths[1] + ".deleted"); err != nil {
+		t.Errorf("Expected the renamed file left in place, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package cleanup
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// posterNames are the file names, without extension, Emby, Jellyfin and
+// Kodi read the poster of a title folder from. "<video>-poster" counts too.
+var posterNames = map[string]bool{
+	"poster":  true,
+	"folder":  true,
+	"cover":   true,
+	"default": true,
+	"movie":   true,
+}
+
+// imageExtensions are the poster formats the media servers read.
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".webp": true,
+}
+
+// isPoster reports whether the file name is the poster of a title folder.
+func isPoster(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	if !imageExtensions[ext] {
+		return false
+	}
+	base := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	return posterNames[base] || strings.HasSuffix(base, "-poster")
+}
+
+// checkMissingMetadata reports the title folder titlePath, which has a
+// video, when it lacks an NFO or a poster, so it can be refreshed in the
+// media server. Season folders take their artwork from the show, so in
+// episode layouts every episode without an NFO of its own is reported
+// instead.
+func (r *scanRun) checkMissingMetadata(titlePath string, entries []fs.DirEntry, videoNames []string) {
+	if r.layout.Episodes {
+		nfos := map[string]bool{}
+		for _, entry := range entries {
+			if name := nameKey(entry.Name()); !entry.IsDir() && filepath.Ext(name) == ".nfo" {
+				nfos[strings.TrimSuffix(name, ".nfo")] = true
+			}
+		}
+		for _, video := range videoNames {
+			if !nfos[nameKey(strings.TrimSuffix(video, filepath.Ext(video)))] {
+				r.emit(Finding{Category: CategoryMissingMetadata, Path: filepath.Join(titlePath, video),
+					Message: "Missing NFO"})
+			}
+		}
+		return
+	}
+
+	hasNFO, hasPoster := false, false
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		hasNFO = hasNFO || strings.EqualFold(filepath.Ext(entry.Name()), ".nfo")
+		hasPoster = hasPoster || isPoster(entry.Name())
+	}
+	var missing []string
+	if !hasNFO {
+		missing = append(missing, "NFO")
+	}
+	if !hasPoster {
+		missing = append(missing, "poster")
+	}
+	if len(missing) > 0 {
+		r.emit(Finding{Category: CategoryMissingMetadata, Path: titlePath,
+			Message: fmt.Sprintf("Missing %s", strings.Join(missing, " and "))})
+	}
+}
@@ -0,0 +1,79 @@
+package cleanup
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for the missing metadata check
+// ============================================================================
+
+func TestIsPoster(t *testing.T) {
+	tests := map[string]bool{
+		"poster.jpg":        true,
+		"Folder.PNG":        true,
+		"movie-poster.webp": true,
+		"movie.jpg":         true,
+		"fanart.jpg":        false,
+		"poster.nfo":        false,
+		"movie-thumb.jpg":   false,
+	}
+	for name, expected := range tests {
+		if got := isPoster(name); got != expected {
+			t.Errorf("isPoster(%q): expected %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestWithMissingMetadataCheck(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Complete").Video("movie.mkv").Metadata("movie.nfo").Metadata("movie-poster.jpg").
+		Title("No Poster").Video("movie.mkv").Metadata("movie.nfo").Metadata("fanart.jpg").
+		Title("Bare").Video("movie.mkv").
+		Title("Orphan").Metadata("movie.nfo").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if missing := result.ByCategory(CategoryMissingMetadata); len(missing) != 0 {
+		t.Errorf("Expected nothing without the check, got %v", missing)
+	}
+
+	result = &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithMissingMetadataCheck(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	var got []string
+	for _, f := range result.ByCategory(CategoryMissingMetadata) {
+		got = append(got, f.String())
+	}
+	sort.Strings(got)
+	expected := []string{"Missing NFO and poster: Studio/Bare", "Missing poster: Studio/No Poster"}
+	if len(got) != 2 || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestWithMissingMetadataCheck_Episodes(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Show").Metadata("tvshow.nfo").
+		Title("Season 01").Video("Show S01E01.mkv").Metadata("Show S01E01.nfo").Video("Show S01E02.mkv").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithLayout(TVLayout), WithMissingMetadataCheck(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	missing := result.ByCategory(CategoryMissingMetadata)
+	expected := "Missing NFO: Show/Season 01/Show S01E02.mkv"
+	if len(missing) != 1 || missing[0].String() != expected {
+		t.Errorf("Expected only %q, got %v", expected, missing)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package cleanup
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDevice reports whether err means a rename crossed filesystems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,14 @@
+package cleanup
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE from winerror.h.
+const errorNotSameDevice syscall.Errno = 17
+
+// isCrossDevice reports whether err means a rename crossed volumes.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
@@ -0,0 +1,94 @@
+package cleanup
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:generate go run gen_nfd.go -version 14.0.0
+
+// combiningRange is a run of characters sharing a canonical combining class.
+type combiningRange struct {
+	lo, hi rune
+	class  uint8
+}
+
+// combiningClass returns the canonical combining class of r, 0 for the
+// starters that marks attach to.
+func combiningClass(r rune) uint8 {
+	i := sort.Search(len(combiningClasses), func(i int) bool { return combiningClasses[i].hi >= r })
+	if i < len(combiningClasses) && combiningClasses[i].lo <= r {
+		return combiningClasses[i].class
+	}
+	return 0
+}
+
+// Hangul syllables decompose into two or three jamo by arithmetic.
+const (
+	hangulBase  = 0xAC00
+	hangulCount = 11172
+	jamoLBase   = 0x1100
+	jamoVBase   = 0x1161
+	jamoTBase   = 0x11A7
+	jamoTCount  = 28
+	jamoNCount  = 588 // vowels times trailing consonants
+)
+
+// nfd returns s in Unicode Normalization Form D: every precomposed
+// character decomposed and the marks on a character in canonical order.
+// macOS writes file names decomposed, so a library filled from a Mac over
+// SMB can have "Ame\u0301lie.nfo" (e and a combining acute accent) next to
+// the "Am\u00E9lie.mkv" (precomposed é) other systems wrote; both have the
+// same NFD form.
+func nfd(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+
+	runes := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch d, ok := decompositions[r]; {
+		case ok:
+			runes = append(runes, []rune(d)...)
+		case r >= hangulBase && r < hangulBase+hangulCount:
+			i := r - hangulBase
+			runes = append(runes, jamoLBase+i/jamoNCount, jamoVBase+i%jamoNCount/jamoTCount)
+			if t := i % jamoTCount; t != 0 {
+				runes = append(runes, jamoTBase+t)
+			}
+		default:
+			runes = append(runes, r)
+		}
+	}
+
+	// Marks following the same starter are sorted by combining class, in a
+	// stable way, so that their typing order does not matter
+	for i := 0; i < len(runes); {
+		if combiningClass(runes[i]) == 0 {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && combiningClass(runes[j]) != 0 {
+			j++
+		}
+		marks := runes[i:j]
+		sort.SliceStable(marks, func(a, b int) bool { return combiningClass(marks[a]) < combiningClass(marks[b]) })
+		i = j
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range runes {
+		b.WriteRune(r)
+	}
+	return b.String()
+}
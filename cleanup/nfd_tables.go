@@ -0,0 +1,1459 @@
+// Code generated by gen_nfd.go from UnicodeData.txt of the Unicode
+// Character Database 14.0.0. DO NOT EDIT.
+
+package cleanup
+
+// The canonical decompositions and combining classes nfd needs. Hangul
+// syllables decompose algorithmically and are left out, and so are the CJK
+// compatibility ideographs, which macOS does not decompose either.
+
+// decompositions maps every character with a canonical decomposition to
+// its full decomposition, in canonical order.
+var decompositions = map[rune]string{
+	0x00C0:  "A\u0300",
+	0x00C1:  "A\u0301",
+	0x00C2:  "A\u0302",
+	0x00C3:  "A\u0303",
+	0x00C4:  "A\u0308",
+	0x00C5:  "A\u030A",
+	0x00C7:  "C\u0327",
+	0x00C8:  "E\u0300",
+	0x00C9:  "E\u0301",
+	0x00CA:  "E\u0302",
+	0x00CB:  "E\u0308",
+	0x00CC:  "I\u0300",
+	0x00CD:  "I\u0301",
+	0x00CE:  "I\u0302",
+	0x00CF:  "I\u0308",
+	0x00D1:  "N\u0303",
+	0x00D2:  "O\u0300",
+	0x00D3:  "O\u0301",
+	0x00D4:  "O\u0302",
+	0x00D5:  "O\u0303",
+	0x00D6:  "O\u0308",
+	0x00D9:  "U\u0300",
+	0x00DA:  "U\u0301",
+	0x00DB:  "U\u0302",
+	0x00DC:  "U\u0308",
+	0x00DD:  "Y\u0301",
+	0x00E0:  "a\u0300",
+	0x00E1:  "a\u0301",
+	0x00E2:  "a\u0302",
+	0x00E3:  "a\u0303",
+	0x00E4:  "a\u0308",
+	0x00E5:  "a\u030A",
+	0x00E7:  "c\u0327",
+	0x00E8:  "e\u0300",
+	0x00E9:  "e\u0301",
+	0x00EA:  "e\u0302",
+	0x00EB:  "e\u0308",
+	0x00EC:  "i\u0300",
+	0x00ED:  "i\u0301",
+	0x00EE:  "i\u0302",
+	0x00EF:  "i\u0308",
+	0x00F1:  "n\u0303",
+	0x00F2:  "o\u0300",
+	0x00F3:  "o\u0301",
+	0x00F4:  "o\u0302",
+	0x00F5:  "o\u0303",
+	0x00F6:  "o\u0308",
+	0x00F9:  "u\u0300",
+	0x00FA:  "u\u0301",
+	0x00FB:  "u\u0302",
+	0x00FC:  "u\u0308",
+	0x00FD:  "y\u0301",
+	0x00FF:  "y\u0308",
+	0x0100:  "A\u0304",
+	0x0101:  "a\u0304",
+	0x0102:  "A\u0306",
+	0x0103:  "a\u0306",
+	0x0104:  "A\u0328",
+	0x0105:  "a\u0328",
+	0x0106:  "C\u0301",
+	0x0107:  "c\u0301",
+	0x0108:  "C\u0302",
+	0x0109:  "c\u0302",
+	0x010A:  "C\u0307",
+	0x010B:  "c\u0307",
+	0x010C:  "C\u030C",
+	0x010D:  "c\u030C",
+	0x010E:  "D\u030C",
+	0x010F:  "d\u030C",
+	0x0112:  "E\u0304",
+	0x0113:  "e\u0304",
+	0x0114:  "E\u0306",
+	0x0115:  "e\u0306",
+	0x0116:  "E\u0307",
+	0x0117:  "e\u0307",
+	0x0118:  "E\u0328",
+	0x0119:  "e\u0328",
+	0x011A:  "E\u030C",
+	0x011B:  "e\u030C",
+	0x011C:  "G\u0302",
+	0x011D:  "g\u0302",
+	0x011E:  "G\u0306",
+	0x011F:  "g\u0306",
+	0x0120:  "G\u0307",
+	0x0121:  "g\u0307",
+	0x0122:  "G\u0327",
+	0x0123:  "g\u0327",
+	0x0124:  "H\u0302",
+	0x0125:  "h\u0302",
+	0x0128:  "I\u0303",
+	0x0129:  "i\u0303",
+	0x012A:  "I\u0304",
+	0x012B:  "i\u0304",
+	0x012C:  "I\u0306",
+	0x012D:  "i\u0306",
+	0x012E:  "I\u0328",
+	0x012F:  "i\u0328",
+	0x0130:  "I\u0307",
+	0x0134:  "J\u0302",
+	0x0135:  "j\u0302",
+	0x0136:  "K\u0327",
+	0x0137:  "k\u0327",
+	0x0139:  "L\u0301",
+	0x013A:  "l\u0301",
+	0x013B:  "L\u0327",
+	0x013C:  "l\u0327",
+	0x013D:  "L\u030C",
+	0x013E:  "l\u030C",
+	0x0143:  "N\u0301",
+	0x0144:  "n\u0301",
+	0x0145:  "N\u0327",
+	0x0146:  "n\u0327",
+	0x0147:  "N\u030C",
+	0x0148:  "n\u030C",
+	0x014C:  "O\u0304",
+	0x014D:  "o\u0304",
+	0x014E:  "O\u0306",
+	0x014F:  "o\u0306",
+	0x0150:  "O\u030B",
+	0x0151:  "o\u030B",
+	0x0154:  "R\u0301",
+	0x0155:  "r\u0301",
+	0x0156:  "R\u0327",
+	0x0157:  "r\u0327",
+	0x0158:  "R\u030C",
+	0x0159:  "r\u030C",
+	0x015A:  "S\u0301",
+	0x015B:  "s\u0301",
+	0x015C:  "S\u0302",
+	0x015D:  "s\u0302",
+	0x015E:  "S\u0327",
+	0x015F:  "s\u0327",
+	0x0160:  "S\u030C",
+	0x0161:  "s\u030C",
+	0x0162:  "T\u0327",
+	0x0163:  "t\u0327",
+	0x0164:  "T\u030C",
+	0x0165:  "t\u030C",
+	0x0168:  "U\u0303",
+	0x0169:  "u\u0303",
+	0x016A:  "U\u0304",
+	0x016B:  "u\u0304",
+	0x016C:  "U\u0306",
+	0x016D:  "u\u0306",
+	0x016E:  "U\u030A",
+	0x016F:  "u\u030A",
+	0x0170:  "U\u030B",
+	0x0171:  "u\u030B",
+	0x0172:  "U\u0328",
+	0x0173:  "u\u0328",
+	0x0174:  "W\u0302",
+	0x0175:  "w\u0302",
+	0x0176:  "Y\u0302",
+	0x0177:  "y\u0302",
+	0x0178:  "Y\u0308",
+	0x0179:  "Z\u0301",
+	0x017A:  "z\u0301",
+	0x017B:  "Z\u0307",
+	0x017C:  "z\u0307",
+	0x017D:  "Z\u030C",
+	0x017E:  "z\u030C",
+	0x01A0:  "O\u031B",
+	0x01A1:  "o\u031B",
+	0x01AF:  "U\u031B",
+	0x01B0:  "u\u031B",
+	0x01CD:  "A\u030C",
+	0x01CE:  "a\u030C",
+	0x01CF:  "I\u030C",
+	0x01D0:  "i\u030C",
+	0x01D1:  "O\u030C",
+	0x01D2:  "o\u030C",
+	0x01D3:  "U\u030C",
+	0x01D4:  "u\u030C",
+	0x01D5:  "U\u0308\u0304",
+	0x01D6:  "u\u0308\u0304",
+	0x01D7:  "U\u0308\u0301",
+	0x01D8:  "u\u0308\u0301",
+	0x01D9:  "U\u0308\u030C",
+	0x01DA:  "u\u0308\u030C",
+	0x01DB:  "U\u0308\u0300",
+	0x01DC:  "u\u0308\u0300",
+	0x01DE:  "A\u0308\u0304",
+	0x01DF:  "a\u0308\u0304",
+	0x01E0:  "A\u0307\u0304",
+	0x01E1:  "a\u0307\u0304",
+	0x01E2:  "\u00C6\u0304",
+	0x01E3:  "\u00E6\u0304",
+	0x01E6:  "G\u030C",
+	0x01E7:  "g\u030C",
+	0x01E8:  "K\u030C",
+	0x01E9:  "k\u030C",
+	0x01EA:  "O\u0328",
+	0x01EB:  "o\u0328",
+	0x01EC:  "O\u0328\u0304",
+	0x01ED:  "o\u0328\u0304",
+	0x01EE:  "\u01B7\u030C",
+	0x01EF:  "\u0292\u030C",
+	0x01F0:  "j\u030C",
+	0x01F4:  "G\u0301",
+	0x01F5:  "g\u0301",
+	0x01F8:  "N\u0300",
+	0x01F9:  "n\u0300",
+	0x01FA:  "A\u030A\u0301",
+	0x01FB:  "a\u030A\u0301",
+	0x01FC:  "\u00C6\u0301",
+	0x01FD:  "\u00E6\u0301",
+	0x01FE:  "\u00D8\u0301",
+	0x01FF:  "\u00F8\u0301",
+	0x0200:  "A\u030F",
+	0x0201:  "a\u030F",
+	0x0202:  "A\u0311",
+	0x0203:  "a\u0311",
+	0x0204:  "E\u030F",
+	0x0205:  "e\u030F",
+	0x0206:  "E\u0311",
+	0x0207:  "e\u0311",
+	0x0208:  "I\u030F",
+	0x0209:  "i\u030F",
+	0x020A:  "I\u0311",
+	0x020B:  "i\u0311",
+	0x020C:  "O\u030F",
+	0x020D:  "o\u030F",
+	0x020E:  "O\u0311",
+	0x020F:  "o\u0311",
+	0x0210:  "R\u030F",
+	0x0211:  "r\u030F",
+	0x0212:  "R\u0311",
+	0x0213:  "r\u0311",
+	0x0214:  "U\u030F",
+	0x0215:  "u\u030F",
+	0x0216:  "U\u0311",
+	0x0217:  "u\u0311",
+	0x0218:  "S\u0326",
+	0x0219:  "s\u0326",
+	0x021A:  "T\u0326",
+	0x021B:  "t\u0326",
+	0x021E:  "H\u030C",
+	0x021F:  "h\u030C",
+	0x0226:  "A\u0307",
+	0x0227:  "a\u0307",
+	0x0228:  "E\u0327",
+	0x0229:  "e\u0327",
+	0x022A:  "O\u0308\u0304",
+	0x022B:  "o\u0308\u0304",
+	0x022C:  "O\u0303\u0304",
+	0x022D:  "o\u0303\u0304",
+	0x022E:  "O\u0307",
+	0x022F:  "o\u0307",
+	0x0230:  "O\u0307\u0304",
+	0x0231:  "o\u0307\u0304",
+	0x0232:  "Y\u0304",
+	0x0233:  "y\u0304",
+	0x0340:  "\u0300",
+	0x0341:  "\u0301",
+	0x0343:  "\u0313",
+	0x0344:  "\u0308\u0301",
+	0x0374:  "\u02B9",
+	0x037E:  ";",
+	0x0385:  "\u00A8\u0301",
+	0x0386:  "\u0391\u0301",
+	0x0387:  "\u00B7",
+	0x0388:  "\u0395\u0301",
+	0x0389:  "\u0397\u0301",
+	0x038A:  "\u0399\u0301",
+	0x038C:  "\u039F\u0301",
+	0x038E:  "\u03A5\u0301",
+	0x038F:  "\u03A9\u0301",
+	0x0390:  "\u03B9\u0308\u0301",
+	0x03AA:  "\u0399\u0308",
+	0x03AB:  "\u03A5\u0308",
+	0x03AC:  "\u03B1\u0301",
+	0x03AD:  "\u03B5\u0301",
+	0x03AE:  "\u03B7\u0301",
+	0x03AF:  "\u03B9\u0301",
+	0x03B0:  "\u03C5\u0308\u0301",
+	0x03CA:  "\u03B9\u0308",
+	0x03CB:  "\u03C5\u0308",
+	0x03CC:  "\u03BF\u0301",
+	0x03CD:  "\u03C5\u0301",
+	0x03CE:  "\u03C9\u0301",
+	0x03D3:  "\u03D2\u0301",
+	0x03D4:  "\u03D2\u0308",
+	0x0400:  "\u0415\u0300",
+	0x0401:  "\u0415\u0308",
+	0x0403:  "\u0413\u0301",
+	0x0407:  "\u0406\u0308",
+	0x040C:  "\u041A\u0301",
+	0x040D:  "\u0418\u0300",
+	0x040E:  "\u0423\u0306",
+	0x0419:  "\u0418\u0306",
+	0x0439:  "\u0438\u0306",
+	0x0450:  "\u0435\u0300",
+	0x0451:  "\u0435\u0308",
+	0x0453:  "\u0433\u0301",
+	0x0457:  "\u0456\u0308",
+	0x045C:  "\u043A\u0301",
+	0x045D:  "\u0438\u0300",
+	0x045E:  "\u0443\u0306",
+	0x0476:  "\u0474\u030F",
+	0x0477:  "\u0475\u030F",
+	0x04C1:  "\u0416\u0306",
+	0x04C2:  "\u0436\u0306",
+	0x04D0:  "\u0410\u0306",
+	0x04D1:  "\u0430\u0306",
+	0x04D2:  "\u0410\u0308",
+	0x04D3:  "\u0430\u0308",
+	0x04D6:  "\u0415\u0306",
+	0x04D7:  "\u0435\u0306",
+	0x04DA:  "\u04D8\u0308",
+	0x04DB:  "\u04D9\u0308",
+	0x04DC:  "\u0416\u0308",
+	0x04DD:  "\u0436\u0308",
+	0x04DE:  "\u0417\u0308",
+	0x04DF:  "\u0437\u0308",
+	0x04E2:  "\u0418\u0304",
+	0x04E3:  "\u0438\u0304",
+	0x04E4:  "\u0418\u0308",
+	0x04E5:  "\u0438\u0308",
+	0x04E6:  "\u041E\u0308",
+	0x04E7:  "\u043E\u0308",
+	0x04EA:  "\u04E8\u0308",
+	0x04EB:  "\u04E9\u0308",
+	0x04EC:  "\u042D\u0308",
+	0x04ED:  "\u044D\u0308",
+	0x04EE:  "\u0423\u0304",
+	0x04EF:  "\u0443\u0304",
+	0x04F0:  "\u0423\u0308",
+	0x04F1:  "\u0443\u0308",
+	0x04F2:  "\u0423\u030B",
+	0x04F3:  "\u0443\u030B",
+	0x04F4:  "\u0427\u0308",
+	0x04F5:  "\u0447\u0308",
+	0x04F8:  "\u042B\u0308",
+	0x04F9:  "\u044B\u0308",
+	0x0622:  "\u0627\u0653",
+	0x0623:  "\u0627\u0654",
+	0x0624:  "\u0648\u0654",
+	0x0625:  "\u0627\u0655",
+	0x0626:  "\u064A\u0654",
+	0x06C0:  "\u06D5\u0654",
+	0x06C2:  "\u06C1\u0654",
+	0x06D3:  "\u06D2\u0654",
+	0x0929:  "\u0928\u093C",
+	0x0931:  "\u0930\u093C",
+	0x0934:  "\u0933\u093C",
+	0x0958:  "\u0915\u093C",
+	0x0959:  "\u0916\u093C",
+	0x095A:  "\u0917\u093C",
+	0x095B:  "\u091C\u093C",
+	0x095C:  "\u0921\u093C",
+	0x095D:  "\u0922\u093C",
+	0x095E:  "\u092B\u093C",
+	0x095F:  "\u092F\u093C",
+	0x09CB:  "\u09C7\u09BE",
+	0x09CC:  "\u09C7\u09D7",
+	0x09DC:  "\u09A1\u09BC",
+	0x09DD:  "\u09A2\u09BC",
+	0x09DF:  "\u09AF\u09BC",
+	0x0A33:  "\u0A32\u0A3C",
+	0x0A36:  "\u0A38\u0A3C",
+	0x0A59:  "\u0A16\u0A3C",
+	0x0A5A:  "\u0A17\u0A3C",
+	0x0A5B:  "\u0A1C\u0A3C",
+	0x0A5E:  "\u0A2B\u0A3C",
+	0x0B48:  "\u0B47\u0B56",
+	0x0B4B:  "\u0B47\u0B3E",
+	0x0B4C:  "\u0B47\u0B57",
+	0x0B5C:  "\u0B21\u0B3C",
+	0x0B5D:  "\u0B22\u0B3C",
+	0x0B94:  "\u0B92\u0BD7",
+	0x0BCA:  "\u0BC6\u0BBE",
+	0x0BCB:  "\u0BC7\u0BBE",
+	0x0BCC:  "\u0BC6\u0BD7",
+	0x0C48:  "\u0C46\u0C56",
+	0x0CC0:  "\u0CBF\u0CD5",
+	0x0CC7:  "\u0CC6\u0CD5",
+	0x0CC8:  "\u0CC6\u0CD6",
+	0x0CCA:  "\u0CC6\u0CC2",
+	0x0CCB:  "\u0CC6\u0CC2\u0CD5",
+	0x0D4A:  "\u0D46\u0D3E",
+	0x0D4B:  "\u0D47\u0D3E",
+	0x0D4C:  "\u0D46\u0D57",
+	0x0DDA:  "\u0DD9\u0DCA",
+	0x0DDC:  "\u0DD9\u0DCF",
+	0x0DDD:  "\u0DD9\u0DCF\u0DCA",
+	0x0DDE:  "\u0DD9\u0DDF",
+	0x0F43:  "\u0F42\u0FB7",
+	0x0F4D:  "\u0F4C\u0FB7",
+	0x0F52:  "\u0F51\u0FB7",
+	0x0F57:  "\u0F56\u0FB7",
+	0x0F5C:  "\u0F5B\u0FB7",
+	0x0F69:  "\u0F40\u0FB5",
+	0x0F73:  "\u0F71\u0F72",
+	0x0F75:  "\u0F71\u0F74",
+	0x0F76:  "\u0FB2\u0F80",
+	0x0F78:  "\u0FB3\u0F80",
+	0x0F81:  "\u0F71\u0F80",
+	0x0F93:  "\u0F92\u0FB7",
+	0x0F9D:  "\u0F9C\u0FB7",
+	0x0FA2:  "\u0FA1\u0FB7",
+	0x0FA7:  "\u0FA6\u0FB7",
+	0x0FAC:  "\u0FAB\u0FB7",
+	0x0FB9:  "\u0F90\u0FB5",
+	0x1026:  "\u1025\u102E",
+	0x1B06:  "\u1B05\u1B35",
+	0x1B08:  "\u1B07\u1B35",
+	0x1B0A:  "\u1B09\u1B35",
+	0x1B0C:  "\u1B0B\u1B35",
+	0x1B0E:  "\u1B0D\u1B35",
+	0x1B12:  "\u1B11\u1B35",
+	0x1B3B:  "\u1B3A\u1B35",
+	0x1B3D:  "\u1B3C\u1B35",
+	0x1B40:  "\u1B3E\u1B35",
+	0x1B41:  "\u1B3F\u1B35",
+	0x1B43:  "\u1B42\u1B35",
+	0x1E00:  "A\u0325",
+	0x1E01:  "a\u0325",
+	0x1E02:  "B\u0307",
+	0x1E03:  "b\u0307",
+	0x1E04:  "B\u0323",
+	0x1E05:  "b\u0323",
+	0x1E06:  "B\u0331",
+	0x1E07:  "b\u0331",
+	0x1E08:  "C\u0327\u0301",
+	0x1E09:  "c\u0327\u0301",
+	0x1E0A:  "D\u0307",
+	0x1E0B:  "d\u0307",
+	0x1E0C:  "D\u0323",
+	0x1E0D:  "d\u0323",
+	0x1E0E:  "D\u0331",
+	0x1E0F:  "d\u0331",
+	0x1E10:  "D\u0327",
+	0x1E11:  "d\u0327",
+	0x1E12:  "D\u032D",
+	0x1E13:  "d\u032D",
+	0x1E14:  "E\u0304\u0300",
+	0x1E15:  "e\u0304\u0300",
+	0x1E16:  "E\u0304\u0301",
+	0x1E17:  "e\u0304\u0301",
+	0x1E18:  "E\u032D",
+	0x1E19:  "e\u032D",
+	0x1E1A:  "E\u0330",
+	0x1E1B:  "e\u0330",
+	0x1E1C:  "E\u0327\u0306",
+	0x1E1D:  "e\u0327\u0306",
+	0x1E1E:  "F\u0307",
+	0x1E1F:  "f\u0307",
+	0x1E20:  "G\u0304",
+	0x1E21:  "g\u0304",
+	0x1E22:  "H\u0307",
+	0x1E23:  "h\u0307",
+	0x1E24:  "H\u0323",
+	0x1E25:  "h\u0323",
+	0x1E26:  "H\u0308",
+	0x1E27:  "h\u0308",
+	0x1E28:  "H\u0327",
+	0x1E29:  "h\u0327",
+	0x1E2A:  "H\u032E",
+	0x1E2B:  "h\u032E",
+	0x1E2C:  "I\u0330",
+	0x1E2D:  "i\u0330",
+	0x1E2E:  "I\u0308\u0301",
+	0x1E2F:  "i\u0308\u0301",
+	0x1E30:  "K\u0301",
+	0x1E31:  "k\u0301",
+	0x1E32:  "K\u0323",
+	0x1E33:  "k\u0323",
+	0x1E34:  "K\u0331",
+	0x1E35:  "k\u0331",
+	0x1E36:  "L\u0323",
+	0x1E37:  "l\u0323",
+	0x1E38:  "L\u0323\u0304",
+	0x1E39:  "l\u0323\u0304",
+	0x1E3A:  "L\u0331",
+	0x1E3B:  "l\u0331",
+	0x1E3C:  "L\u032D",
+	0x1E3D:  "l\u032D",
+	0x1E3E:  "M\u0301",
+	0x1E3F:  "m\u0301",
+	0x1E40:  "M\u0307",
+	0x1E41:  "m\u0307",
+	0x1E42:  "M\u0323",
+	0x1E43:  "m\u0323",
+	0x1E44:  "N\u0307",
+	0x1E45:  "n\u0307",
+	0x1E46:  "N\u0323",
+	0x1E47:  "n\u0323",
+	0x1E48:  "N\u0331",
+	0x1E49:  "n\u0331",
+	0x1E4A:  "N\u032D",
+	0x1E4B:  "n\u032D",
+	0x1E4C:  "O\u0303\u0301",
+	0x1E4D:  "o\u0303\u0301",
+	0x1E4E:  "O\u0303\u0308",
+	0x1E4F:  "o\u0303\u0308",
+	0x1E50:  "O\u0304\u0300",
+	0x1E51:  "o\u0304\u0300",
+	0x1E52:  "O\u0304\u0301",
+	0x1E53:  "o\u0304\u0301",
+	0x1E54:  "P\u0301",
+	0x1E55:  "p\u0301",
+	0x1E56:  "P\u0307",
+	0x1E57:  "p\u0307",
+	0x1E58:  "R\u0307",
+	0x1E59:  "r\u0307",
+	0x1E5A:  "R\u0323",
+	0x1E5B:  "r\u0323",
+	0x1E5C:  "R\u0323\u0304",
+	0x1E5D:  "r\u0323\u0304",
+	0x1E5E:  "R\u0331",
+	0x1E5F:  "r\u0331",
+	0x1E60:  "S\u0307",
+	0x1E61:  "s\u0307",
+	0x1E62:  "S\u0323",
+	0x1E63:  "s\u0323",
+	0x1E64:  "S\u0301\u0307",
+	0x1E65:  "s\u0301\u0307",
+	0x1E66:  "S\u030C\u0307",
+	0x1E67:  "s\u030C\u0307",
+	0x1E68:  "S\u0323\u0307",
+	0x1E69:  "s\u0323\u0307",
+	0x1E6A:  "T\u0307",
+	0x1E6B:  "t\u0307",
+	0x1E6C:  "T\u0323",
+	0x1E6D:  "t\u0323",
+	0x1E6E:  "T\u0331",
+	0x1E6F:  "t\u0331",
+	0x1E70:  "T\u032D",
+	0x1E71:  "t\u032D",
+	0x1E72:  "U\u0324",
+	0x1E73:  "u\u0324",
+	0x1E74:  "U\u0330",
+	0x1E75:  "u\u0330",
+	0x1E76:  "U\u032D",
+	0x1E77:  "u\u032D",
+	0x1E78:  "U\u0303\u0301",
+	0x1E79:  "u\u0303\u0301",
+	0x1E7A:  "U\u0304\u0308",
+	0x1E7B:  "u\u0304\u0308",
+	0x1E7C:  "V\u0303",
+	0x1E7D:  "v\u0303",
+	0x1E7E:  "V\u0323",
+	0x1E7F:  "v\u0323",
+	0x1E80:  "W\u0300",
+	0x1E81:  "w\u0300",
+	0x1E82:  "W\u0301",
+	0x1E83:  "w\u0301",
+	0x1E84:  "W\u0308",
+	0x1E85:  "w\u0308",
+	0x1E86:  "W\u0307",
+	0x1E87:  "w\u0307",
+	0x1E88:  "W\u0323",
+	0x1E89:  "w\u0323",
+	0x1E8A:  "X\u0307",
+	0x1E8B:  "x\u0307",
+	0x1E8C:  "X\u0308",
+	0x1E8D:  "x\u0308",
+	0x1E8E:  "Y\u0307",
+	0x1E8F:  "y\u0307",
+	0x1E90:  "Z\u0302",
+	0x1E91:  "z\u0302",
+	0x1E92:  "Z\u0323",
+	0x1E93:  "z\u0323",
+	0x1E94:  "Z\u0331",
+	0x1E95:  "z\u0331",
+	0x1E96:  "h\u0331",
+	0x1E97:  "t\u0308",
+	0x1E98:  "w\u030A",
+	0x1E99:  "y\u030A",
+	0x1E9B:  "\u017F\u0307",
+	0x1EA0:  "A\u0323",
+	0x1EA1:  "a\u0323",
+	0x1EA2:  "A\u0309",
+	0x1EA3:  "a\u0309",
+	0x1EA4:  "A\u0302\u0301",
+	0x1EA5:  "a\u0302\u0301",
+	0x1EA6:  "A\u0302\u0300",
+	0x1EA7:  "a\u0302\u0300",
+	0x1EA8:  "A\u0302\u0309",
+	0x1EA9:  "a\u0302\u0309",
+	0x1EAA:  "A\u0302\u0303",
+	0x1EAB:  "a\u0302\u0303",
+	0x1EAC:  "A\u0323\u0302",
+	0x1EAD:  "a\u0323\u0302",
+	0x1EAE:  "A\u0306\u0301",
+	0x1EAF:  "a\u0306\u0301",
+	0x1EB0:  "A\u0306\u0300",
+	0x1EB1:  "a\u0306\u0300",
+	0x1EB2:  "A\u0306\u0309",
+	0x1EB3:  "a\u0306\u0309",
+	0x1EB4:  "A\u0306\u0303",
+	0x1EB5:  "a\u0306\u0303",
+	0x1EB6:  "A\u0323\u0306",
+	0x1EB7:  "a\u0323\u0306",
+	0x1EB8:  "E\u0323",
+	0x1EB9:  "e\u0323",
+	0x1EBA:  "E\u0309",
+	0x1EBB:  "e\u0309",
+	0x1EBC:  "E\u0303",
+	0x1EBD:  "e\u0303",
+	0x1EBE:  "E\u0302\u0301",
+	0x1EBF:  "e\u0302\u0301",
+	0x1EC0:  "E\u0302\u0300",
+	0x1EC1:  "e\u0302\u0300",
+	0x1EC2:  "E\u0302\u0309",
+	0x1EC3:  "e\u0302\u0309",
+	0x1EC4:  "E\u0302\u0303",
+	0x1EC5:  "e\u0302\u0303",
+	0x1EC6:  "E\u0323\u0302",
+	0x1EC7:  "e\u0323\u0302",
+	0x1EC8:  "I\u0309",
+	0x1EC9:  "i\u0309",
+	0x1ECA:  "I\u0323",
+	0x1ECB:  "i\u0323",
+	0x1ECC:  "O\u0323",
+	0x1ECD:  "o\u0323",
+	0x1ECE:  "O\u0309",
+	0x1ECF:  "o\u0309",
+	0x1ED0:  "O\u0302\u0301",
+	0x1ED1:  "o\u0302\u0301",
+	0x1ED2:  "O\u0302\u0300",
+	0x1ED3:  "o\u0302\u0300",
+	0x1ED4:  "O\u0302\u0309",
+	0x1ED5:  "o\u0302\u0309",
+	0x1ED6:  "O\u0302\u0303",
+	0x1ED7:  "o\u0302\u0303",
+	0x1ED8:  "O\u0323\u0302",
+	0x1ED9:  "o\u0323\u0302",
+	0x1EDA:  "O\u031B\u0301",
+	0x1EDB:  "o\u031B\u0301",
+	0x1EDC:  "O\u031B\u0300",
+	0x1EDD:  "o\u031B\u0300",
+	0x1EDE:  "O\u031B\u0309",
+	0x1EDF:  "o\u031B\u0309",
+	0x1EE0:  "O\u031B\u0303",
+	0x1EE1:  "o\u031B\u0303",
+	0x1EE2:  "O\u031B\u0323",
+	0x1EE3:  "o\u031B\u0323",
+	0x1EE4:  "U\u0323",
+	0x1EE5:  "u\u0323",
+	0x1EE6:  "U\u0309",
+	0x1EE7:  "u\u0309",
+	0x1EE8:  "U\u031B\u0301",
+	0x1EE9:  "u\u031B\u0301",
+	0x1EEA:  "U\u031B\u0300",
+	0x1EEB:  "u\u031B\u0300",
+	0x1EEC:  "U\u031B\u0309",
+	0x1EED:  "u\u031B\u0309",
+	0x1EEE:  "U\u031B\u0303",
+	0x1EEF:  "u\u031B\u0303",
+	0x1EF0:  "U\u031B\u0323",
+	0x1EF1:  "u\u031B\u0323",
+	0x1EF2:  "Y\u0300",
+	0x1EF3:  "y\u0300",
+	0x1EF4:  "Y\u0323",
+	0x1EF5:  "y\u0323",
+	0x1EF6:  "Y\u0309",
+	0x1EF7:  "y\u0309",
+	0x1EF8:  "Y\u0303",
+	0x1EF9:  "y\u0303",
+	0x1F00:  "\u03B1\u0313",
+	0x1F01:  "\u03B1\u0314",
+	0x1F02:  "\u03B1\u0313\u0300",
+	0x1F03:  "\u03B1\u0314\u0300",
+	0x1F04:  "\u03B1\u0313\u0301",
+	0x1F05:  "\u03B1\u0314\u0301",
+	0x1F06:  "\u03B1\u0313\u0342",
+	0x1F07:  "\u03B1\u0314\u0342",
+	0x1F08:  "\u0391\u0313",
+	0x1F09:  "\u0391\u0314",
+	0x1F0A:  "\u0391\u0313\u0300",
+	0x1F0B:  "\u0391\u0314\u0300",
+	0x1F0C:  "\u0391\u0313\u0301",
+	0x1F0D:  "\u0391\u0314\u0301",
+	0x1F0E:  "\u0391\u0313\u0342",
+	0x1F0F:  "\u0391\u0314\u0342",
+	0x1F10:  "\u03B5\u0313",
+	0x1F11:  "\u03B5\u0314",
+	0x1F12:  "\u03B5\u0313\u0300",
+	0x1F13:  "\u03B5\u0314\u0300",
+	0x1F14:  "\u03B5\u0313\u0301",
+	0x1F15:  "\u03B5\u0314\u0301",
+	0x1F18:  "\u0395\u0313",
+	0x1F19:  "\u0395\u0314",
+	0x1F1A:  "\u0395\u0313\u0300",
+	0x1F1B:  "\u0395\u0314\u0300",
+	0x1F1C:  "\u0395\u0313\u0301",
+	0x1F1D:  "\u0395\u0314\u0301",
+	0x1F20:  "\u03B7\u0313",
+	0x1F21:  "\u03B7\u0314",
+	0x1F22:  "\u03B7\u0313\u0300",
+	0x1F23:  "\u03B7\u0314\u0300",
+	0x1F24:  "\u03B7\u0313\u0301",
+	0x1F25:  "\u03B7\u0314\u0301",
+	0x1F26:  "\u03B7\u0313\u0342",
+	0x1F27:  "\u03B7\u0314\u0342",
+	0x1F28:  "\u0397\u0313",
+	0x1F29:  "\u0397\u0314",
+	0x1F2A:  "\u0397\u0313\u0300",
+	0x1F2B:  "\u0397\u0314\u0300",
+	0x1F2C:  "\u0397\u0313\u0301",
+	0x1F2D:  "\u0397\u0314\u0301",
+	0x1F2E:  "\u0397\u0313\u0342",
+	0x1F2F:  "\u0397\u0314\u0342",
+	0x1F30:  "\u03B9\u0313",
+	0x1F31:  "\u03B9\u0314",
+	0x1F32:  "\u03B9\u0313\u0300",
+	0x1F33:  "\u03B9\u0314\u0300",
+	0x1F34:  "\u03B9\u0313\u0301",
+	0x1F35:  "\u03B9\u0314\u0301",
+	0x1F36:  "\u03B9\u0313\u0342",
+	0x1F37:  "\u03B9\u0314\u0342",
+	0x1F38:  "\u0399\u0313",
+	0x1F39:  "\u0399\u0314",
+	0x1F3A:  "\u0399\u0313\u0300",
+	0x1F3B:  "\u0399\u0314\u0300",
+	0x1F3C:  "\u0399\u0313\u0301",
+	0x1F3D:  "\u0399\u0314\u0301",
+	0x1F3E:  "\u0399\u0313\u0342",
+	0x1F3F:  "\u0399\u0314\u0342",
+	0x1F40:  "\u03BF\u0313",
+	0x1F41:  "\u03BF\u0314",
+	0x1F42:  "\u03BF\u0313\u0300",
+	0x1F43:  "\u03BF\u0314\u0300",
+	0x1F44:  "\u03BF\u0313\u0301",
+	0x1F45:  "\u03BF\u0314\u0301",
+	0x1F48:  "\u039F\u0313",
+	0x1F49:  "\u039F\u0314",
+	0x1F4A:  "\u039F\u0313\u0300",
+	0x1F4B:  "\u039F\u0314\u0300",
+	0x1F4C:  "\u039F\u0313\u0301",
+	0x1F4D:  "\u039F\u0314\u0301",
+	0x1F50:  "\u03C5\u0313",
+	0x1F51:  "\u03C5\u0314",
+	0x1F52:  "\u03C5\u0313\u0300",
+	0x1F53:  "\u03C5\u0314\u0300",
+	0x1F54:  "\u03C5\u0313\u0301",
+	0x1F55:  "\u03C5\u0314\u0301",
+	0x1F56:  "\u03C5\u0313\u0342",
+	0x1F57:  "\u03C5\u0314\u0342",
+	0x1F59:  "\u03A5\u0314",
+	0x1F5B:  "\u03A5\u0314\u0300",
+	0x1F5D:  "\u03A5\u0314\u0301",
+	0x1F5F:  "\u03A5\u0314\u0342",
+	0x1F60:  "\u03C9\u0313",
+	0x1F61:  "\u03C9\u0314",
+	0x1F62:  "\u03C9\u0313\u0300",
+	0x1F63:  "\u03C9\u0314\u0300",
+	0x1F64:  "\u03C9\u0313\u0301",
+	0x1F65:  "\u03C9\u0314\u0301",
+	0x1F66:  "\u03C9\u0313\u0342",
+	0x1F67:  "\u03C9\u0314\u0342",
+	0x1F68:  "\u03A9\u0313",
+	0x1F69:  "\u03A9\u0314",
+	0x1F6A:  "\u03A9\u0313\u0300",
+	0x1F6B:  "\u03A9\u0314\u0300",
+	0x1F6C:  "\u03A9\u0313\u0301",
+	0x1F6D:  "\u03A9\u0314\u0301",
+	0x1F6E:  "\u03A9\u0313\u0342",
+	0x1F6F:  "\u03A9\u0314\u0342",
+	0x1F70:  "\u03B1\u0300",
+	0x1F71:  "\u03B1\u0301",
+	0x1F72:  "\u03B5\u0300",
+	0x1F73:  "\u03B5\u0301",
+	0x1F74:  "\u03B7\u0300",
+	0x1F75:  "\u03B7\u0301",
+	0x1F76:  "\u03B9\u0300",
+	0x1F77:  "\u03B9\u0301",
+	0x1F78:  "\u03BF\u0300",
+	0x1F79:  "\u03BF\u0301",
+	0x1F7A:  "\u03C5\u0300",
+	0x1F7B:  "\u03C5\u0301",
+	0x1F7C:  "\u03C9\u0300",
+	0x1F7D:  "\u03C9\u0301",
+	0x1F80:  "\u03B1\u0313\u0345",
+	0x1F81:  "\u03B1\u0314\u0345",
+	0x1F82:  "\u03B1\u0313\u0300\u0345",
+	0x1F83:  "\u03B1\u0314\u0300\u0345",
+	0x1F84:  "\u03B1\u0313\u0301\u0345",
+	0x1F85:  "\u03B1\u0314\u0301\u0345",
+	0x1F86:  "\u03B1\u0313\u0342\u0345",
+	0x1F87:  "\u03B1\u0314\u0342\u0345",
+	0x1F88:  "\u0391\u0313\u0345",
+	0x1F89:  "\u0391\u0314\u0345",
+	0x1F8A:  "\u0391\u0313\u0300\u0345",
+	0x1F8B:  "\u0391\u0314\u0300\u0345",
+	0x1F8C:  "\u0391\u0313\u0301\u0345",
+	0x1F8D:  "\u0391\u0314\u0301\u0345",
+	0x1F8E:  "\u0391\u0313\u0342\u0345",
+	0x1F8F:  "\u0391\u0314\u0342\u0345",
+	0x1F90:  "\u03B7\u0313\u0345",
+	0x1F91:  "\u03B7\u0314\u0345",
+	0x1F92:  "\u03B7\u0313\u0300\u0345",
+	0x1F93:  "\u03B7\u0314\u0300\u0345",
+	0x1F94:  "\u03B7\u0313\u0301\u0345",
+	0x1F95:  "\u03B7\u0314\u0301\u0345",
+	0x1F96:  "\u03B7\u0313\u0342\u0345",
+	0x1F97:  "\u03B7\u0314\u0342\u0345",
+	0x1F98:  "\u0397\u0313\u0345",
+	0x1F99:  "\u0397\u0314\u0345",
+	0x1F9A:  "\u0397\u0313\u0300\u0345",
+	0x1F9B:  "\u0397\u0314\u0300\u0345",
+	0x1F9C:  "\u0397\u0313\u0301\u0345",
+	0x1F9D:  "\u0397\u0314\u0301\u0345",
+	0x1F9E:  "\u0397\u0313\u0342\u0345",
+	0x1F9F:  "\u0397\u0314\u0342\u0345",
+	0x1FA0:  "\u03C9\u0313\u0345",
+	0x1FA1:  "\u03C9\u0314\u0345",
+	0x1FA2:  "\u03C9\u0313\u0300\u0345",
+	0x1FA3:  "\u03C9\u0314\u0300\u0345",
+	0x1FA4:  "\u03C9\u0313\u0301\u0345",
+	0x1FA5:  "\u03C9\u0314\u0301\u0345",
+	0x1FA6:  "\u03C9\u0313\u0342\u0345",
+	0x1FA7:  "\u03C9\u0314\u0342\u0345",
+	0x1FA8:  "\u03A9\u0313\u0345",
+	0x1FA9:  "\u03A9\u0314\u0345",
+	0x1FAA:  "\u03A9\u0313\u0300\u0345",
+	0x1FAB:  "\u03A9\u0314\u0300\u0345",
+	0x1FAC:  "\u03A9\u0313\u0301\u0345",
+	0x1FAD:  "\u03A9\u0314\u0301\u0345",
+	0x1FAE:  "\u03A9\u0313\u0342\u0345",
+	0x1FAF:  "\u03A9\u0314\u0342\u0345",
+	0x1FB0:  "\u03B1\u0306",
+	0x1FB1:  "\u03B1\u0304",
+	0x1FB2:  "\u03B1\u0300\u0345",
+	0x1FB3:  "\u03B1\u0345",
+	0x1FB4:  "\u03B1\u0301\u0345",
+	0x1FB6:  "\u03B1\u0342",
+	0x1FB7:  "\u03B1\u0342\u0345",
+	0x1FB8:  "\u0391\u0306",
+	0x1FB9:  "\u0391\u0304",
+	0x1FBA:  "\u0391\u0300",
+	0x1FBB:  "\u0391\u0301",
+	0x1FBC:  "\u0391\u0345",
+	0x1FBE:  "\u03B9",
+	0x1FC1:  "\u00A8\u0342",
+	0x1FC2:  "\u03B7\u0300\u0345",
+	0x1FC3:  "\u03B7\u0345",
+	0x1FC4:  "\u03B7\u0301\u0345",
+	0x1FC6:  "\u03B7\u0342",
+	0x1FC7:  "\u03B7\u0342\u0345",
+	0x1FC8:  "\u0395\u0300",
+	0x1FC9:  "\u0395\u0301",
+	0x1FCA:  "\u0397\u0300",
+	0x1FCB:  "\u0397\u0301",
+	0x1FCC:  "\u0397\u0345",
+	0x1FCD:  "\u1FBF\u0300",
+	0x1FCE:  "\u1FBF\u0301",
+	0x1FCF:  "\u1FBF\u0342",
+	0x1FD0:  "\u03B9\u0306",
+	0x1FD1:  "\u03B9\u0304",
+	0x1FD2:  "\u03B9\u0308\u0300",
+	0x1FD3:  "\u03B9\u0308\u0301",
+	0x1FD6:  "\u03B9\u0342",
+	0x1FD7:  "\u03B9\u0308\u0342",
+	0x1FD8:  "\u0399\u0306",
+	0x1FD9:  "\u0399\u0304",
+	0x1FDA:  "\u0399\u0300",
+	0x1FDB:  "\u0399\u0301",
+	0x1FDD:  "\u1FFE\u0300",
+	0x1FDE:  "\u1FFE\u0301",
+	0x1FDF:  "\u1FFE\u0342",
+	0x1FE0:  "\u03C5\u0306",
+	0x1FE1:  "\u03C5\u0304",
+	0x1FE2:  "\u03C5\u0308\u0300",
+	0x1FE3:  "\u03C5\u0308\u0301",
+	0x1FE4:  "\u03C1\u0313",
+	0x1FE5:  "\u03C1\u0314",
+	0x1FE6:  "\u03C5\u0342",
+	0x1FE7:  "\u03C5\u0308\u0342",
+	0x1FE8:  "\u03A5\u0306",
+	0x1FE9:  "\u03A5\u0304",
+	0x1FEA:  "\u03A5\u0300",
+	0x1FEB:  "\u03A5\u0301",
+	0x1FEC:  "\u03A1\u0314",
+	0x1FED:  "\u00A8\u0300",
+	0x1FEE:  "\u00A8\u0301",
+	0x1FEF:  "`",
+	0x1FF2:  "\u03C9\u0300\u0345",
+	0x1FF3:  "\u03C9\u0345",
+	0x1FF4:  "\u03C9\u0301\u0345",
+	0x1FF6:  "\u03C9\u0342",
+	0x1FF7:  "\u03C9\u0342\u0345",
+	0x1FF8:  "\u039F\u0300",
+	0x1FF9:  "\u039F\u0301",
+	0x1FFA:  "\u03A9\u0300",
+	0x1FFB:  "\u03A9\u0301",
+	0x1FFC:  "\u03A9\u0345",
+	0x1FFD:  "\u00B4",
+	0x2000:  "\u2002",
+	0x2001:  "\u2003",
+	0x2126:  "\u03A9",
+	0x212A:  "K",
+	0x212B:  "A\u030A",
+	0x219A:  "\u2190\u0338",
+	0x219B:  "\u2192\u0338",
+	0x21AE:  "\u2194\u0338",
+	0x21CD:  "\u21D0\u0338",
+	0x21CE:  "\u21D4\u0338",
+	0x21CF:  "\u21D2\u0338",
+	0x2204:  "\u2203\u0338",
+	0x2209:  "\u2208\u0338",
+	0x220C:  "\u220B\u0338",
+	0x2224:  "\u2223\u0338",
+	0x2226:  "\u2225\u0338",
+	0x2241:  "\u223C\u0338",
+	0x2244:  "\u2243\u0338",
+	0x2247:  "\u2245\u0338",
+	0x2249:  "\u2248\u0338",
+	0x2260:  "=\u0338",
+	0x2262:  "\u2261\u0338",
+	0x226D:  "\u224D\u0338",
+	0x226E:  "<\u0338",
+	0x226F:  ">\u0338",
+	0x2270:  "\u2264\u0338",
+	0x2271:  "\u2265\u0338",
+	0x2274:  "\u2272\u0338",
+	0x2275:  "\u2273\u0338",
+	0x2278:  "\u2276\u0338",
+	0x2279:  "\u2277\u0338",
+	0x2280:  "\u227A\u0338",
+	0x2281:  "\u227B\u0338",
+	0x2284:  "\u2282\u0338",
+	0x2285:  "\u2283\u0338",
+	0x2288:  "\u2286\u0338",
+	0x2289:  "\u2287\u0338",
+	0x22AC:  "\u22A2\u0338",
+	0x22AD:  "\u22A8\u0338",
+	0x22AE:  "\u22A9\u0338",
+	0x22AF:  "\u22AB\u0338",
+	0x22E0:  "\u227C\u0338",
+	0x22E1:  "\u227D\u0338",
+	0x22E2:  "\u2291\u0338",
+	0x22E3:  "\u2292\u0338",
+	0x22EA:  "\u22B2\u0338",
+	0x22EB:  "\u22B3\u0338",
+	0x22EC:  "\u22B4\u0338",
+	0x22ED:  "\u22B5\u0338",
+	0x2329:  "\u3008",
+	0x232A:  "\u3009",
+	0x2ADC:  "\u2ADD\u0338",
+	0x304C:  "\u304B\u3099",
+	0x304E:  "\u304D\u3099",
+	0x3050:  "\u304F\u3099",
+	0x3052:  "\u3051\u3099",
+	0x3054:  "\u3053\u3099",
+	0x3056:  "\u3055\u3099",
+	0x3058:  "\u3057\u3099",
+	0x305A:  "\u3059\u3099",
+	0x305C:  "\u305B\u3099",
+	0x305E:  "\u305D\u3099",
+	0x3060:  "\u305F\u3099",
+	0x3062:  "\u3061\u3099",
+	0x3065:  "\u3064\u3099",
+	0x3067:  "\u3066\u3099",
+	0x3069:  "\u3068\u3099",
+	0x3070:  "\u306F\u3099",
+	0x3071:  "\u306F\u309A",
+	0x3073:  "\u3072\u3099",
+	0x3074:  "\u3072\u309A",
+	0x3076:  "\u3075\u3099",
+	0x3077:  "\u3075\u309A",
+	0x3079:  "\u3078\u3099",
+	0x307A:  "\u3078\u309A",
+	0x307C:  "\u307B\u3099",
+	0x307D:  "\u307B\u309A",
+	0x3094:  "\u3046\u3099",
+	0x309E:  "\u309D\u3099",
+	0x30AC:  "\u30AB\u3099",
+	0x30AE:  "\u30AD\u3099",
+	0x30B0:  "\u30AF\u3099",
+	0x30B2:  "\u30B1\u3099",
+	0x30B4:  "\u30B3\u3099",
+	0x30B6:  "\u30B5\u3099",
+	0x30B8:  "\u30B7\u3099",
+	0x30BA:  "\u30B9\u3099",
+	0x30BC:  "\u30BB\u3099",
+	0x30BE:  "\u30BD\u3099",
+	0x30C0:  "\u30BF\u3099",
+	0x30C2:  "\u30C1\u3099",
+	0x30C5:  "\u30C4\u3099",
+	0x30C7:  "\u30C6\u3099",
+	0x30C9:  "\u30C8\u3099",
+	0x30D0:  "\u30CF\u3099",
+	0x30D1:  "\u30CF\u309A",
+	0x30D3:  "\u30D2\u3099",
+	0x30D4:  "\u30D2\u309A",
+	0x30D6:  "\u30D5\u3099",
+	0x30D7:  "\u30D5\u309A",
+	0x30D9:  "\u30D8\u3099",
+	0x30DA:  "\u30D8\u309A",
+	0x30DC:  "\u30DB\u3099",
+	0x30DD:  "\u30DB\u309A",
+	0x30F4:  "\u30A6\u3099",
+	0x30F7:  "\u30EF\u3099",
+	0x30F8:  "\u30F0\u3099",
+	0x30F9:  "\u30F1\u3099",
+	0x30FA:  "\u30F2\u3099",
+	0x30FE:  "\u30FD\u3099",
+	0xFB1D:  "\u05D9\u05B4",
+	0xFB1F:  "\u05F2\u05B7",
+	0xFB2A:  "\u05E9\u05C1",
+	0xFB2B:  "\u05E9\u05C2",
+	0xFB2C:  "\u05E9\u05BC\u05C1",
+	0xFB2D:  "\u05E9\u05BC\u05C2",
+	0xFB2E:  "\u05D0\u05B7",
+	0xFB2F:  "\u05D0\u05B8",
+	0xFB30:  "\u05D0\u05BC",
+	0xFB31:  "\u05D1\u05BC",
+	0xFB32:  "\u05D2\u05BC",
+	0xFB33:  "\u05D3\u05BC",
+	0xFB34:  "\u05D4\u05BC",
+	0xFB35:  "\u05D5\u05BC",
+	0xFB36:  "\u05D6\u05BC",
+	0xFB38:  "\u05D8\u05BC",
+	0xFB39:  "\u05D9\u05BC",
+	0xFB3A:  "\u05DA\u05BC",
+	0xFB3B:  "\u05DB\u05BC",
+	0xFB3C:  "\u05DC\u05BC",
+	0xFB3E:  "\u05DE\u05BC",
+	0xFB40:  "\u05E0\u05BC",
+	0xFB41:  "\u05E1\u05BC",
+	0xFB43:  "\u05E3\u05BC",
+	0xFB44:  "\u05E4\u05BC",
+	0xFB46:  "\u05E6\u05BC",
+	0xFB47:  "\u05E7\u05BC",
+	0xFB48:  "\u05E8\u05BC",
+	0xFB49:  "\u05E9\u05BC",
+	0xFB4A:  "\u05EA\u05BC",
+	0xFB4B:  "\u05D5\u05B9",
+	0xFB4C:  "\u05D1\u05BF",
+	0xFB4D:  "\u05DB\u05BF",
+	0xFB4E:  "\u05E4\u05BF",
+	0x1109A: "\U00011099\U000110BA",
+	0x1109C: "\U0001109B\U000110BA",
+	0x110AB: "\U000110A5\U000110BA",
+	0x1112E: "\U00011131\U00011127",
+	0x1112F: "\U00011132\U00011127",
+	0x1134B: "\U00011347\U0001133E",
+	0x1134C: "\U00011347\U00011357",
+	0x114BB: "\U000114B9\U000114BA",
+	0x114BC: "\U000114B9\U000114B0",
+	0x114BE: "\U000114B9\U000114BD",
+	0x115BA: "\U000115B8\U000115AF",
+	0x115BB: "\U000115B9\U000115AF",
+	0x11938: "\U00011935\U00011930",
+	0x1D15E: "\U0001D157\U0001D165",
+	0x1D15F: "\U0001D158\U0001D165",
+	0x1D160: "\U0001D158\U0001D165\U0001D16E",
+	0x1D161: "\U0001D158\U0001D165\U0001D16F",
+	0x1D162: "\U0001D158\U0001D165\U0001D170",
+	0x1D163: "\U0001D158\U0001D165\U0001D171",
+	0x1D164: "\U0001D158\U0001D165\U0001D172",
+	0x1D1BB: "\U0001D1B9\U0001D165",
+	0x1D1BC: "\U0001D1BA\U0001D165",
+	0x1D1BD: "\U0001D1B9\U0001D165\U0001D16E",
+	0x1D1BE: "\U0001D1BA\U0001D165\U0001D16E",
+	0x1D1BF: "\U0001D1B9\U0001D165\U0001D16F",
+	0x1D1C0: "\U0001D1BA\U0001D165\U0001D16F",
+}
+
+// combiningClasses are the runs of characters with a non-zero canonical
+// combining class, in code point order.
+var combiningClasses = []combiningRange{
+	{0x0300, 0x0314, 230},
+	{0x0315, 0x0315, 232},
+	{0x0316, 0x0319, 220},
+	{0x031A, 0x031A, 232},
+	{0x031B, 0x031B, 216},
+	{0x031C, 0x0320, 220},
+	{0x0321, 0x0322, 202},
+	{0x0323, 0x0326, 220},
+	{0x0327, 0x0328, 202},
+	{0x0329, 0x0333, 220},
+	{0x0334, 0x0338, 1},
+	{0x0339, 0x033C, 220},
+	{0x033D, 0x0344, 230},
+	{0x0345, 0x0345, 240},
+	{0x0346, 0x0346, 230},
+	{0x0347, 0x0349, 220},
+	{0x034A, 0x034C, 230},
+	{0x034D, 0x034E, 220},
+	{0x0350, 0x0352, 230},
+	{0x0353, 0x0356, 220},
+	{0x0357, 0x0357, 230},
+	{0x0358, 0x0358, 232},
+	{0x0359, 0x035A, 220},
+	{0x035B, 0x035B, 230},
+	{0x035C, 0x035C, 233},
+	{0x035D, 0x035E, 234},
+	{0x035F, 0x035F, 233},
+	{0x0360, 0x0361, 234},
+	{0x0362, 0x0362, 233},
+	{0x0363, 0x036F, 230},
+	{0x0483, 0x0487, 230},
+	{0x0591, 0x0591, 220},
+	{0x0592, 0x0595, 230},
+	{0x0596, 0x0596, 220},
+	{0x0597, 0x0599, 230},
+	{0x059A, 0x059A, 222},
+	{0x059B, 0x059B, 220},
+	{0x059C, 0x05A1, 230},
+	{0x05A2, 0x05A7, 220},
+	{0x05A8, 0x05A9, 230},
+	{0x05AA, 0x05AA, 220},
+	{0x05AB, 0x05AC, 230},
+	{0x05AD, 0x05AD, 222},
+	{0x05AE, 0x05AE, 228},
+	{0x05AF, 0x05AF, 230},
+	{0x05B0, 0x05B0, 10},
+	{0x05B1, 0x05B1, 11},
+	{0x05B2, 0x05B2, 12},
+	{0x05B3, 0x05B3, 13},
+	{0x05B4, 0x05B4, 14},
+	{0x05B5, 0x05B5, 15},
+	{0x05B6, 0x05B6, 16},
+	{0x05B7, 0x05B7, 17},
+	{0x05B8, 0x05B8, 18},
+	{0x05B9, 0x05BA, 19},
+	{0x05BB, 0x05BB, 20},
+	{0x05BC, 0x05BC, 21},
+	{0x05BD, 0x05BD, 22},
+	{0x05BF, 0x05BF, 23},
+	{0x05C1, 0x05C1, 24},
+	{0x05C2, 0x05C2, 25},
+	{0x05C4, 0x05C4, 230},
+	{0x05C5, 0x05C5, 220},
+	{0x05C7, 0x05C7, 18},
+	{0x0610, 0x0617, 230},
+	{0x0618, 0x0618, 30},
+	{0x0619, 0x0619, 31},
+	{0x061A, 0x061A, 32},
+	{0x064B, 0x064B, 27},
+	{0x064C, 0x064C, 28},
+	{0x064D, 0x064D, 29},
+	{0x064E, 0x064E, 30},
+	{0x064F, 0x064F, 31},
+	{0x0650, 0x0650, 32},
+	{0x0651, 0x0651, 33},
+	{0x0652, 0x0652, 34},
+	{0x0653, 0x0654, 230},
+	{0x0655, 0x0656, 220},
+	{0x0657, 0x065B, 230},
+	{0x065C, 0x065C, 220},
+	{0x065D, 0x065E, 230},
+	{0x065F, 0x065F, 220},
+	{0x0670, 0x0670, 35},
+	{0x06D6, 0x06DC, 230},
+	{0x06DF, 0x06E2, 230},
+	{0x06E3, 0x06E3, 220},
+	{0x06E4, 0x06E4, 230},
+	{0x06E7, 0x06E8, 230},
+	{0x06EA, 0x06EA, 220},
+	{0x06EB, 0x06EC, 230},
+	{0x06ED, 0x06ED, 220},
+	{0x0711, 0x0711, 36},
+	{0x0730, 0x0730, 230},
+	{0x0731, 0x0731, 220},
+	{0x0732, 0x0733, 230},
+	{0x0734, 0x0734, 220},
+	{0x0735, 0x0736, 230},
+	{0x0737, 0x0739, 220},
+	{0x073A, 0x073A, 230},
+	{0x073B, 0x073C, 220},
+	{0x073D, 0x073D, 230},
+	{0x073E, 0x073E, 220},
+	{0x073F, 0x0741, 230},
+	{0x0742, 0x0742, 220},
+	{0x0743, 0x0743, 230},
+	{0x0744, 0x0744, 220},
+	{0x0745, 0x0745, 230},
+	{0x0746, 0x0746, 220},
+	{0x0747, 0x0747, 230},
+	{0x0748, 0x0748, 220},
+	{0x0749, 0x074A, 230},
+	{0x07EB, 0x07F1, 230},
+	{0x07F2, 0x07F2, 220},
+	{0x07F3, 0x07F3, 230},
+	{0x07FD, 0x07FD, 220},
+	{0x0816, 0x0819, 230},
+	{0x081B, 0x0823, 230},
+	{0x0825, 0x0827, 230},
+	{0x0829, 0x082D, 230},
+	{0x0859, 0x085B, 220},
+	{0x0898, 0x0898, 230},
+	{0x0899, 0x089B, 220},
+	{0x089C, 0x089F, 230},
+	{0x08CA, 0x08CE, 230},
+	{0x08CF, 0x08D3, 220},
+	{0x08D4, 0x08E1, 230},
+	{0x08E3, 0x08E3, 220},
+	{0x08E4, 0x08E5, 230},
+	{0x08E6, 0x08E6, 220},
+	{0x08E7, 0x08E8, 230},
+	{0x08E9, 0x08E9, 220},
+	{0x08EA, 0x08EC, 230},
+	{0x08ED, 0x08EF, 220},
+	{0x08F0, 0x08F0, 27},
+	{0x08F1, 0x08F1, 28},
+	{0x08F2, 0x08F2, 29},
+	{0x08F3, 0x08F5, 230},
+	{0x08F6, 0x08F6, 220},
+	{0x08F7, 0x08F8, 230},
+	{0x08F9, 0x08FA, 220},
+	{0x08FB, 0x08FF, 230},
+	{0x093C, 0x093C, 7},
+	{0x094D, 0x094D, 9},
+	{0x0951, 0x0951, 230},
+	{0x0952, 0x0952, 220},
+	{0x0953, 0x0954, 230},
+	{0x09BC, 0x09BC, 7},
+	{0x09CD, 0x09CD, 9},
+	{0x09FE, 0x09FE, 230},
+	{0x0A3C, 0x0A3C, 7},
+	{0x0A4D, 0x0A4D, 9},
+	{0x0ABC, 0x0ABC, 7},
+	{0x0ACD, 0x0ACD, 9},
+	{0x0B3C, 0x0B3C, 7},
+	{0x0B4D, 0x0B4D, 9},
+	{0x0BCD, 0x0BCD, 9},
+	{0x0C3C, 0x0C3C, 7},
+	{0x0C4D, 0x0C4D, 9},
+	{0x0C55, 0x0C55, 84},
+	{0x0C56, 0x0C56, 91},
+	{0x0CBC, 0x0CBC, 7},
+	{0x0CCD, 0x0CCD, 9},
+	{0x0D3B, 0x0D3C, 9},
+	{0x0D4D, 0x0D4D, 9},
+	{0x0DCA, 0x0DCA, 9},
+	{0x0E38, 0x0E39, 103},
+	{0x0E3A, 0x0E3A, 9},
+	{0x0E48, 0x0E4B, 107},
+	{0x0EB8, 0x0EB9, 118},
+	{0x0EBA, 0x0EBA, 9},
+	{0x0EC8, 0x0ECB, 122},
+	{0x0F18, 0x0F19, 220},
+	{0x0F35, 0x0F35, 220},
+	{0x0F37, 0x0F37, 220},
+	{0x0F39, 0x0F39, 216},
+	{0x0F71, 0x0F71, 129},
+	{0x0F72, 0x0F72, 130},
+	{0x0F74, 0x0F74, 132},
+	{0x0F7A, 0x0F7D, 130},
+	{0x0F80, 0x0F80, 130},
+	{0x0F82, 0x0F83, 230},
+	{0x0F84, 0x0F84, 9},
+	{0x0F86, 0x0F87, 230},
+	{0x0FC6, 0x0FC6, 220},
+	{0x1037, 0x1037, 7},
+	{0x1039, 0x103A, 9},
+	{0x108D, 0x108D, 220},
+	{0x135D, 0x135F, 230},
+	{0x1714, 0x1715, 9},
+	{0x1734, 0x1734, 9},
+	{0x17D2, 0x17D2, 9},
+	{0x17DD, 0x17DD, 230},
+	{0x18A9, 0x18A9, 228},
+	{0x1939, 0x1939, 222},
+	{0x193A, 0x193A, 230},
+	{0x193B, 0x193B, 220},
+	{0x1A17, 0x1A17, 230},
+	{0x1A18, 0x1A18, 220},
+	{0x1A60, 0x1A60, 9},
+	{0x1A75, 0x1A7C, 230},
+	{0x1A7F, 0x1A7F, 220},
+	{0x1AB0, 0x1AB4, 230},
+	{0x1AB5, 0x1ABA, 220},
+	{0x1ABB, 0x1ABC, 230},
+	{0x1ABD, 0x1ABD, 220},
+	{0x1ABF, 0x1AC0, 220},
+	{0x1AC1, 0x1AC2, 230},
+	{0x1AC3, 0x1AC4, 220},
+	{0x1AC5, 0x1AC9, 230},
+	{0x1ACA, 0x1ACA, 220},
+	{0x1ACB, 0x1ACE, 230},
+	{0x1B34, 0x1B34, 7},
+	{0x1B44, 0x1B44, 9},
+	{0x1B6B, 0x1B6B, 230},
+	{0x1B6C, 0x1B6C, 220},
+	{0x1B6D, 0x1B73, 230},
+	{0x1BAA, 0x1BAB, 9},
+	{0x1BE6, 0x1BE6, 7},
+	{0x1BF2, 0x1BF3, 9},
+	{0x1C37, 0x1C37, 7},
+	{0x1CD0, 0x1CD2, 230},
+	{0x1CD4, 0x1CD4, 1},
+	{0x1CD5, 0x1CD9, 220},
+	{0x1CDA, 0x1CDB, 230},
+	{0x1CDC, 0x1CDF, 220},
+	{0x1CE0, 0x1CE0, 230},
+	{0x1CE2, 0x1CE8, 1},
+	{0x1CED, 0x1CED, 220},
+	{0x1CF4, 0x1CF4, 230},
+	{0x1CF8, 0x1CF9, 230},
+	{0x1DC0, 0x1DC1, 230},
+	{0x1DC2, 0x1DC2, 220},
+	{0x1DC3, 0x1DC9, 230},
+	{0x1DCA, 0x1DCA, 220},
+	{0x1DCB, 0x1DCC, 230},
+	{0x1DCD, 0x1DCD, 234},
+	{0x1DCE, 0x1DCE, 214},
+	{0x1DCF, 0x1DCF, 220},
+	{0x1DD0, 0x1DD0, 202},
+	{0x1DD1, 0x1DF5, 230},
+	{0x1DF6, 0x1DF6, 232},
+	{0x1DF7, 0x1DF8, 228},
+	{0x1DF9, 0x1DF9, 220},
+	{0x1DFA, 0x1DFA, 218},
+	{0x1DFB, 0x1DFB, 230},
+	{0x1DFC, 0x1DFC, 233},
+	{0x1DFD, 0x1DFD, 220},
+	{0x1DFE, 0x1DFE, 230},
+	{0x1DFF, 0x1DFF, 220},
+	{0x20D0, 0x20D1, 230},
+	{0x20D2, 0x20D3, 1},
+	{0x20D4, 0x20D7, 230},
+	{0x20D8, 0x20DA, 1},
+	{0x20DB, 0x20DC, 230},
+	{0x20E1, 0x20E1, 230},
+	{0x20E5, 0x20E6, 1},
+	{0x20E7, 0x20E7, 230},
+	{0x20E8, 0x20E8, 220},
+	{0x20E9, 0x20E9, 230},
+	{0x20EA, 0x20EB, 1},
+	{0x20EC, 0x20EF, 220},
+	{0x20F0, 0x20F0, 230},
+	{0x2CEF, 0x2CF1, 230},
+	{0x2D7F, 0x2D7F, 9},
+	{0x2DE0, 0x2DFF, 230},
+	{0x302A, 0x302A, 218},
+	{0x302B, 0x302B, 228},
+	{0x302C, 0x302C, 232},
+	{0x302D, 0x302D, 222},
+	{0x302E, 0x302F, 224},
+	{0x3099, 0x309A, 8},
+	{0xA66F, 0xA66F, 230},
+	{0xA674, 0xA67D, 230},
+	{0xA69E, 0xA69F, 230},
+	{0xA6F0, 0xA6F1, 230},
+	{0xA806, 0xA806, 9},
+	{0xA82C, 0xA82C, 9},
+	{0xA8C4, 0xA8C4, 9},
+	{0xA8E0, 0xA8F1, 230},
+	{0xA92B, 0xA92D, 220},
+	{0xA953, 0xA953, 9},
+	{0xA9B3, 0xA9B3, 7},
+	{0xA9C0, 0xA9C0, 9},
+	{0xAAB0, 0xAAB0, 230},
+	{0xAAB2, 0xAAB3, 230},
+	{0xAAB4, 0xAAB4, 220},
+	{0xAAB7, 0xAAB8, 230},
+	{0xAABE, 0xAABF, 230},
+	{0xAAC1, 0xAAC1, 230},
+	{0xAAF6, 0xAAF6, 9},
+	{0xABED, 0xABED, 9},
+	{0xFB1E, 0xFB1E, 26},
+	{0xFE20, 0xFE26, 230},
+	{0xFE27, 0xFE2D, 220},
+	{0xFE2E, 0xFE2F, 230},
+	{0x101FD, 0x101FD, 220},
+	{0x102E0, 0x102E0, 220},
+	{0x10376, 0x1037A, 230},
+	{0x10A0D, 0x10A0D, 220},
+	{0x10A0F, 0x10A0F, 230},
+	{0x10A38, 0x10A38, 230},
+	{0x10A39, 0x10A39, 1},
+	{0x10A3A, 0x10A3A, 220},
+	{0x10A3F, 0x10A3F, 9},
+	{0x10AE5, 0x10AE5, 230},
+	{0x10AE6, 0x10AE6, 220},
+	{0x10D24, 0x10D27, 230},
+	{0x10EAB, 0x10EAC, 230},
+	{0x10F46, 0x10F47, 220},
+	{0x10F48, 0x10F4A, 230},
+	{0x10F4B, 0x10F4B, 220},
+	{0x10F4C, 0x10F4C, 230},
+	{0x10F4D, 0x10F50, 220},
+	{0x10F82, 0x10F82, 230},
+	{0x10F83, 0x10F83, 220},
+	{0x10F84, 0x10F84, 230},
+	{0x10F85, 0x10F85, 220},
+	{0x11046, 0x11046, 9},
+	{0x11070, 0x11070, 9},
+	{0x1107F, 0x1107F, 9},
+	{0x110B9, 0x110B9, 9},
+	{0x110BA, 0x110BA, 7},
+	{0x11100, 0x11102, 230},
+	{0x11133, 0x11134, 9},
+	{0x11173, 0x11173, 7},
+	{0x111C0, 0x111C0, 9},
+	{0x111CA, 0x111CA, 7},
+	{0x11235, 0x11235, 9},
+	{0x11236, 0x11236, 7},
+	{0x112E9, 0x112E9, 7},
+	{0x112EA, 0x112EA, 9},
+	{0x1133B, 0x1133C, 7},
+	{0x1134D, 0x1134D, 9},
+	{0x11366, 0x1136C, 230},
+	{0x11370, 0x11374, 230},
+	{0x11442, 0x11442, 9},
+	{0x11446, 0x11446, 7},
+	{0x1145E, 0x1145E, 230},
+	{0x114C2, 0x114C2, 9},
+	{0x114C3, 0x114C3, 7},
+	{0x115BF, 0x115BF, 9},
+	{0x115C0, 0x115C0, 7},
+	{0x1163F, 0x1163F, 9},
+	{0x116B6, 0x116B6, 9},
+	{0x116B7, 0x116B7, 7},
+	{0x1172B, 0x1172B, 9},
+	{0x11839, 0x11839, 9},
+	{0x1183A, 0x1183A, 7},
+	{0x1193D, 0x1193E, 9},
+	{0x11943, 0x11943, 7},
+	{0x119E0, 0x119E0, 9},
+	{0x11A34, 0x11A34, 9},
+	{0x11A47, 0x11A47, 9},
+	{0x11A99, 0x11A99, 9},
+	{0x11C3F, 0x11C3F, 9},
+	{0x11D42, 0x11D42, 7},
+	{0x11D44, 0x11D45, 9},
+	{0x11D97, 0x11D97, 9},
+	{0x16AF0, 0x16AF4, 1},
+	{0x16B30, 0x16B36, 230},
+	{0x16FF0, 0x16FF1, 6},
+	{0x1BC9E, 0x1BC9E, 1},
+	{0x1D165, 0x1D166, 216},
+	{0x1D167, 0x1D169, 1},
+	{0x1D16D, 0x1D16D, 226},
+	{0x1D16E, 0x1D172, 216},
+	{0x1D17B, 0x1D182, 220},
+	{0x1D185, 0x1D189, 230},
+	{0x1D18A, 0x1D18B, 220},
+	{0x1D1AA, 0x1D1AD, 230},
+	{0x1D242, 0x1D244, 230},
+	{0x1E000, 0x1E006, 230},
+	{0x1E008, 0x1E018, 230},
+	{0x1E01B, 0x1E021, 230},
+	{0x1E023, 0x1E024, 230},
+	{0x1E026, 0x1E02A, 230},
+	{0x1E130, 0x1E136, 230},
+	{0x1E2AE, 0x1E2AE, 230},
+	{0x1E2EC, 0x1E2EF, 230},
+	{0x1E8D0, 0x1E8D6, 220},
+	{0x1E944, 0x1E949, 230},
+	{0x1E94A, 0x1E94A, 7},
+}
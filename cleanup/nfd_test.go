@@ -0,0 +1,30 @@
+package cleanup
+
+import "testing"
+
+// ============================================================================
+// Tests for nfd
+// ============================================================================
+
+func TestNFD(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"ascii", "Movie (2001).mkv", "Movie (2001).mkv"},
+		{"precomposed", "Am\u00E9lie", "Ame\u0301lie"},
+		{"already decomposed", "Ame\u0301lie", "Ame\u0301lie"},
+		{"recursive", "\u1EAB", "a\u0302\u0303"},
+		{"marks reordered", "a\u0301\u0323", "a\u0323\u0301"},
+		{"singleton", "\u212B", "A\u030A"},
+		{"hangul", "\uD55C", "\u1112\u1161\u11AB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nfd(tt.input); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
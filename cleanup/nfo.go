@@ -0,0 +1,126 @@
+package cleanup
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// nfoInfo is the part of a Kodi/Jellyfin NFO document the scanner reads.
+// The root element (movie, tvshow, episodedetails...) does not matter.
+type nfoInfo struct {
+	Title         string `xml:"title"`
+	OriginalTitle string `xml:"originaltitle"`
+	Year          string `xml:"year"`
+	Premiered     string `xml:"premiered"`
+}
+
+// parseNFO reads the metadata of an NFO document. Anything after the root
+// element, such as the scraper URL some tools append, is ignored.
+func parseNFO(data []byte) (nfoInfo, error) {
+	var info nfoInfo
+	err := xml.Unmarshal(data, &info)
+	return info, err
+}
+
+var yearPattern = regexp.MustCompile(`^\d{4}`)
+
+// year returns the release year of the NFO, from its year element or
+// failing that its premiered date, or "" if it has neither.
+func (n nfoInfo) year() string {
+	for _, value := range []string{n.Year, n.Premiered} {
+		if year := yearPattern.FindString(strings.TrimSpace(value)); year != "" {
+			return year
+		}
+	}
+	return ""
+}
+
+// matchesTitle reports whether the NFO's title or original title is the
+// folder title, or it has neither. Titles are compared by their letters and
+// digits only, so "Alien: Covenant" matches "Alien - Covenant" and the
+// like, as characters Windows refuses are commonly replaced.
+func (n nfoInfo) matchesTitle(folder string) bool {
+	want := titleKey(folder)
+	titles := []string{n.Title, n.OriginalTitle}
+	known := false
+	for _, title := range titles {
+		key := titleKey(title)
+		if key == "" {
+			continue
+		}
+		if key == want {
+			return true
+		}
+		known = true
+	}
+	return !known
+}
+
+// titleKey reduces title to its nameKey form and drops everything but
+// letters, their accents and digits.
+func titleKey(title string) string {
+	var b strings.Builder
+	for _, r := range nameKey(title) {
+		if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// folderYearPattern matches the year of a "Title (Year)" folder name.
+var folderYearPattern = regexp.MustCompile(`\((\d{4})\)\s*$`)
+
+// folderYear returns the year of a "Title (Year)" folder name, or "".
+func folderYear(name string) string {
+	if m := folderYearPattern.FindStringSubmatch(name); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// folderTitle returns the title of a "Title (Year)" folder name, or the
+// whole name when it has no year.
+func folderTitle(name string) string {
+	if loc := folderYearPattern.FindStringIndex(name); loc != nil {
+		name = name[:loc[0]]
+	}
+	return strings.TrimSpace(name)
+}
+
+// checkNFOs reports the NFO files in titlePath whose year, and with the
+// title check their title, differ from the folder name: the folder was
+// renamed without refreshing its metadata, or the metadata was matched to
+// the wrong release. Season folders are not named after a title, so titles
+// are only compared in movie layouts.
+func (r *scanRun) checkNFOs(titlePath string, nfoNames []string) {
+	folder := filepath.Base(titlePath)
+	expected := folderYear(folder)
+	checkTitle := r.nfoTitleCheck && !r.layout.Episodes
+	if expected == "" && !checkTitle {
+		return
+	}
+	for _, name := range nfoNames {
+		data, err := r.fsys.ReadFile(filepath.Join(titlePath, name))
+		if err != nil {
+			r.fail(err)
+			continue
+		}
+		info, err := parseNFO(data)
+		if err != nil {
+			continue // Not XML; some NFOs only hold a URL
+		}
+		if checkTitle && !info.matchesTitle(folderTitle(folder)) {
+			r.emit(Finding{Category: CategoryMetadataMismatch, Path: titlePath,
+				Message: fmt.Sprintf("Title mismatch: folder says %q, %s says %q", folderTitle(folder), name, strings.TrimSpace(info.Title))})
+		}
+		if year := info.year(); expected != "" && year != "" && year != expected {
+			r.emit(Finding{Category: CategoryMetadataMismatch, Path: titlePath,
+				Message: fmt.Sprintf("Year mismatch: folder says %s, %s says %s", expected, name, year)})
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for NFO year checks
+// ============================================================================
+
+func TestParseNFO_Year(t *testing.T) {
+	tests := []struct {
+		nfo      string
+		expected string
+	}{
+		{`<movie><title>A</title><year>2019</year></movie>`, "2019"},
+		{`<movie><premiered>2020-03-01</premiered></movie>`, "2020"},
+		{`<?xml version="1.0"?><movie><year> </year><premiered>2018-01-01</premiered></movie>
+https://www.themoviedb.org/movie/1`, "2018"},
+		{`<tvshow><title>B</title></tvshow>`, ""},
+	}
+	for _, tt := range tests {
+		info, err := parseNFO([]byte(tt.nfo))
+		if err != nil {
+			t.Fatalf("parseNFO(%q) returned error: %v", tt.nfo, err)
+		}
+		if got := info.year(); got != tt.expected {
+			t.Errorf("Expected year %q for %q, got %q", tt.expected, tt.nfo, got)
+		}
+	}
+}
+
+func TestFolderYear(t *testing.T) {
+	tests := map[string]string{
+		"Movie (2019)":             "2019",
+		"Movie (2019) ":            "2019",
+		"2001 A Space Odyssey":     "",
+		"Movie (2019) [1080p]":     "",
+		"Blade Runner 2049 (2017)": "2017",
+	}
+	for name, expected := range tests {
+		if got := folderYear(name); got != expected {
+			t.Errorf("folderYear(%q): expected %q, got %q", name, expected, got)
+		}
+	}
+}
+
+func TestWithNFOYearCheck(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Match (2019)").Video("movie.mkv").File("movie.nfo", []byte("<movie><year>2019</year></movie>")).
+		Title("Mismatch (2019)").Video("movie.mkv").File("movie.nfo", []byte("<movie><premiered>2021-05-01</premiered></movie>")).
+		Title("No Year").Video("movie.mkv").File("movie.nfo", []byte("<movie><year>2021</year></movie>")).
+		Title("Not XML (2019)").Video("movie.mkv").File("movie.nfo", []byte("https://www.imdb.com/title/tt0000001/")).
+		Title("Orphan (2019)").File("movie.nfo", []byte("<movie><year>2021</year></movie>")).
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings without the check, got %v", result.StructureWarnings)
+	}
+
+	result = &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithNFOYearCheck(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	mismatches := result.ByCategory(CategoryMetadataMismatch)
+	expected := "Year mismatch: folder says 2019, movie.nfo says 2021: Studio/Mismatch (2019)"
+	if len(mismatches) != 1 || mismatches[0].String() != expected {
+		t.Errorf("Expected only %q, got %v", expected, mismatches)
+	}
+}
+
+func TestMatchesTitle(t *testing.T) {
+	tests := []struct {
+		nfo      string
+		folder   string
+		expected bool
+	}{
+		{`<movie><title>Alien: Covenant</title></movie>`, "Alien - Covenant", true},
+		{`<movie><title>AMÉLIE</title></movie>`, "Amélie", true},
+		{`<movie><title>Die fabelhafte Welt der Amélie</title><originaltitle>Amélie</originaltitle></movie>`, "Amélie", true},
+		{"<movie><title>Ame\u0301lie</title></movie>", "Am\u00E9lie", true},
+		{`<movie><title>Heat</title></movie>`, "Ronin", false},
+		{`<movie><year>2019</year></movie>`, "Ronin", true},
+	}
+	for _, tt := range tests {
+		info, err := parseNFO([]byte(tt.nfo))
+		if err != nil {
+			t.Fatalf("parseNFO(%q) returned error: %v", tt.nfo, err)
+		}
+		if got := info.matchesTitle(tt.folder); got != tt.expected {
+			t.Errorf("matchesTitle(%q) for %q: expected %v, got %v", tt.folder, tt.nfo, tt.expected, got)
+		}
+	}
+}
+
+func TestWithNFOCheck(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Heat (1995)").Video("movie.mkv").File("movie.nfo", []byte("<movie><title>Heat</title><year>1995</year></movie>")).
+		Title("Ronin (1998)").Video("movie.mkv").File("movie.nfo", []byte("<movie><title>Heat</title><year>1995</year></movie>")).
+		Title("Untitled").Video("movie.mkv").File("movie.nfo", []byte("https://www.imdb.com/title/tt0000001/")).
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithNFOCheck(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	var messages []string
+	for _, f := range result.ByCategory(CategoryMetadataMismatch) {
+		messages = append(messages, f.String())
+	}
+	expected := []string{
+		`Title mismatch: folder says "Ronin", movie.nfo says "Heat": Studio/Ronin (1998)`,
+		"Year mismatch: folder says 1998, movie.nfo says 1995: Studio/Ronin (1998)",
+	}
+	if len(messages) != 2 || messages[0] != expected[0] || messages[1] != expected[1] {
+		t.Errorf("Expected %q, got %q", expected, messages)
+	}
+
+	// Season folders are not named after the show
+	fsys = cleanuptest.New().
+		Studio("Show").
+		Title("Season 01").Video("Show S01E01.mkv").File("Show S01E01.nfo", []byte("<episodedetails><title>Pilot</title></episodedetails>")).
+		MapFS()
+	result = &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithLayout(TVLayout), WithNFOCheck(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if mismatches := result.ByCategory(CategoryMetadataMismatch); len(mismatches) != 0 {
+		t.Errorf("Expected no title check in season folders, got %v", mismatches)
+	}
+}
@@ -0,0 +1,311 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PermissionPolicy is the ownership and mode every file and folder in a
+// library is expected to have. Zero modes and negative ids are not checked.
+type PermissionPolicy struct {
+	UID      int // -1 to skip the owner check
+	GID      int // -1 to skip the group check
+	DirMode  fs.FileMode
+	FileMode fs.FileMode
+
+	owner, group string // as given, for messages
+}
+
+// ParsePermissionPolicy builds a policy from chown-style "user[:group]"
+// ownership (names or numeric ids, empty to skip) and octal modes such as
+// "0775" (empty to skip).
+func ParsePermissionPolicy(ownership, dirMode, fileMode string) (PermissionPolicy, error) {
+	p := PermissionPolicy{UID: -1, GID: -1}
+
+	owner, group, _ := strings.Cut(ownership, ":")
+	if owner != "" {
+		uid, err := lookupID(owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return p, fmt.Errorf("owner %q: %w", owner, err)
+		}
+		p.UID, p.owner = uid, owner
+	}
+	if group != "" {
+		gid, err := lookupID(group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return p, fmt.Errorf("group %q: %w", group, err)
+		}
+		p.GID, p.group = gid, group
+	}
+
+	var err error
+	if p.DirMode, err = parseMode(dirMode); err != nil {
+		return p, fmt.Errorf("directory mode: %w", err)
+	}
+	if p.FileMode, err = parseMode(fileMode); err != nil {
+		return p, fmt.Errorf("file mode: %w", err)
+	}
+	return p, nil
+}
+
+// lookupID returns nameOrID as a number, resolving names with lookup.
+func lookupID(nameOrID string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		return id, nil
+	}
+	idStr, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(idStr)
+}
+
+func parseMode(s string) (fs.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o7777 {
+		return 0, fmt.Errorf("invalid octal mode %q", s)
+	}
+	return fs.FileMode(mode), nil
+}
+
+// expectedMode returns the mode info should have, or 0 if it is unchecked.
+func (p PermissionPolicy) expectedMode(info fs.FileInfo) fs.FileMode {
+	if info.IsDir() {
+		return p.DirMode
+	}
+	return p.FileMode
+}
+
+// Check describes how info differs from the policy, or returns "" if it
+// conforms. Symlinks are never checked: their own mode and owner are
+// meaningless to the programs reading through them.
+func (p PermissionPolicy) Check(info fs.FileInfo) string {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return ""
+	}
+
+	var problems []string
+	if uid, gid, ok := fileOwner(info); ok {
+		if p.UID >= 0 && uid != p.UID {
+			problems = append(problems, fmt.Sprintf("owner %d, expected %s", uid, p.describeID(p.owner, p.UID)))
+		}
+		if p.GID >= 0 && gid != p.GID {
+			problems = append(problems, fmt.Sprintf("group %d, expected %s", gid, p.describeID(p.group, p.GID)))
+		}
+	}
+	if want := p.expectedMode(info); want != 0 && permBits(info.Mode()) != want {
+		problems = append(problems, fmt.Sprintf("mode %04o, expected %04o", permBits(info.Mode()), want))
+	}
+	return strings.Join(problems, ", ")
+}
+
+func (p PermissionPolicy) describeID(name string, id int) string {
+	if name == "" || name == strconv.Itoa(id) {
+		return strconv.Itoa(id)
+	}
+	return fmt.Sprintf("%s (%d)", name, id)
+}
+
+// Fix changes the owner, group and mode of path to match the policy, leaving
+// unchecked attributes alone. It does nothing for symlinks.
+func (p PermissionPolicy) Fix(path string) error {
+	info, err := os.Lstat(longPath(path))
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return nil
+	}
+
+	// Ownership first: chown may clear setuid/setgid bits
+	if uid, gid, ok := fileOwner(info); ok && (p.UID >= 0 && uid != p.UID || p.GID >= 0 && gid != p.GID) {
+		if err := os.Lchown(longPath(path), p.UID, p.GID); err != nil {
+			return err
+		}
+	}
+	if want := p.expectedMode(info); want != 0 && permBits(info.Mode()) != want {
+		if err := os.Chmod(longPath(path), fileModeFromBits(want)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// permBits returns the permission and special bits of mode in the numeric
+// form chmod uses.
+func permBits(mode fs.FileMode) fs.FileMode {
+	bits := mode.Perm()
+	if mode&fs.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return bits
+}
+
+// fileModeFromBits converts chmod-style numeric bits to an fs.FileMode.
+func fileModeFromBits(bits fs.FileMode) fs.FileMode {
+	mode := bits.Perm()
+	if bits&0o4000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if bits&0o2000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if bits&0o1000 != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
+
+// PermissionFixReport summarizes a PermissionFixer run.
+type PermissionFixReport struct {
+	Fixed    []Finding
+	Skipped  []Finding // gone since the scan, e.g. deleted in the same run
+	Failures []error   // one *PermissionFixError per item, or *PanicError
+}
+
+// PermissionFixer applies a PermissionPolicy to the permission mismatches of
+// a result. It mirrors Deleter: nothing is changed until Fix is called, which
+// callers only do in execute mode.
+type PermissionFixer struct {
+	policy   PermissionPolicy
+	workers  int
+	progress ProgressFunc
+}
+
+// FixerOption configures a PermissionFixer.
+type FixerOption func(*PermissionFixer)
+
+// WithFixWorkers sets how many items are fixed concurrently (default 1).
+func WithFixWorkers(n int) FixerOption {
+	return func(f *PermissionFixer) {
+		f.workers = n
+	}
+}
+
+// WithFixProgress calls fn with a PermissionFixed event after every item.
+func WithFixProgress(fn ProgressFunc) FixerOption {
+	return func(f *PermissionFixer) {
+		f.progress = fn
+	}
+}
+
+// NewPermissionFixer returns a PermissionFixer enforcing policy.
+func NewPermissionFixer(policy PermissionPolicy, opts ...FixerOption) *PermissionFixer {
+	f := &PermissionFixer{policy: policy, workers: 1}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Fix chowns and chmods every CategoryPermissionMismatch finding in result.
+// Like Deleter.Delete, it stops before the next item once ctx is done and
+// only returns an error in that case; per-item failures are collected in
+// the report.
+func (f *PermissionFixer) Fix(ctx context.Context, result *CleanupResult) (*PermissionFixReport, error) {
+	report := &PermissionFixReport{}
+	items := result.ByCategory(CategoryPermissionMismatch)
+
+	var mu sync.Mutex
+	done := 0
+	err := RunPool(ctx, f.workers, items, func(finding Finding) error {
+		if _, err := os.Lstat(longPath(finding.Path)); errors.Is(err, fs.ErrNotExist) {
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			report.Skipped = append(report.Skipped, finding)
+			return nil
+		}
+
+		err := f.policy.Fix(finding.Path)
+
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		var failure error
+		if err != nil {
+			failure = &PermissionFixError{Path: finding.Path, Cause: err}
+			report.Failures = append(report.Failures, failure)
+		} else {
+			report.Fixed = append(report.Fixed, finding)
+		}
+		if f.progress != nil {
+			f.progress(PermissionFixed{Finding: finding, Err: failure, Done: done, Total: len(items)})
+		}
+		return nil
+	})
+	report.Failures = append(report.Failures, SplitErrors(err)...)
+	return report, ctx.Err()
+}
+
+// auditEntries checks the entries of dirPath against the permission policy,
+// for portable names and for OS junk, when any of these is enabled.
+func (r *scanRun) auditEntries(dirPath string, entries []fs.DirEntry) {
+	if r.junkFiles {
+		r.reportJunk(dirPath, entries)
+	}
+	if r.auditPortableNames {
+		r.auditNames(dirPath, entries)
+	}
+	if r.permissions == nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // Vanished since the listing
+		}
+		path := filepath.Join(dirPath, entry.Name())
+		if problem := r.permissions.Check(info); problem != "" {
+			r.emit(Finding{Category: CategoryPermissionMismatch, Path: path, Message: problem})
+		}
+	}
+}
+
+// auditTree audits everything below dirPath. Title folders use it for their
+// subdirectories, which the scan itself does not descend into.
+func (r *scanRun) auditTree(dirPath string) {
+	if (r.permissions == nil && !r.auditPortableNames && !r.junkFiles) || r.ctx.Err() != nil {
+		return
+	}
+	entries, err := r.fsys.ReadDir(dirPath)
+	if err != nil {
+		r.fail(&ErrUnreadableDir{Path: dirPath, Err: err})
+		return
+	}
+	r.auditEntries(dirPath, entries)
+	for _, entry := range entries {
+		if entry.IsDir() && !isRecycleBin(entry.Name()) {
+			r.auditTree(filepath.Join(dirPath, entry.Name()))
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for PermissionPolicy
+// ============================================================================
+
+func TestParsePermissionPolicy(t *testing.T) {
+	p, err := ParsePermissionPolicy("1000:1001", "0775", "664")
+	if err != nil {
+		t.Fatalf("ParsePermissionPolicy returned error: %v", err)
+	}
+	if p.UID != 1000 || p.GID != 1001 || p.DirMode != 0o775 || p.FileMode != 0o664 {
+		t.Errorf("Unexpected policy %+v", p)
+	}
+
+	p, err = ParsePermissionPolicy("", "", "")
+	if err != nil {
+		t.Fatalf("ParsePermissionPolicy returned error: %v", err)
+	}
+	if p.UID != -1 || p.GID != -1 || p.DirMode != 0 || p.FileMode != 0 {
+		t.Errorf("Expected empty values to disable checks, got %+v", p)
+	}
+
+	for _, bad := range [][3]string{
+		{"", "0999", ""},
+		{"", "", "rw-r--r--"},
+		{"no-such-user-xyz", "", ""},
+		{":no-such-group-xyz", "", ""},
+	} {
+		if _, err := ParsePermissionPolicy(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestPermissionPolicy_CheckMode(t *testing.T) {
+	p := PermissionPolicy{UID: -1, GID: -1, DirMode: 0o775, FileMode: 0o664}
+
+	tests := []struct {
+		name     string
+		mode     fs.FileMode
+		expected string
+	}{
+		{"conforming dir", fs.ModeDir | 0o775, ""},
+		{"conforming file", 0o664, ""},
+		{"wrong dir", fs.ModeDir | 0o755, "mode 0755, expected 0775"},
+		{"wrong file", 0o600, "mode 0600, expected 0664"},
+		{"setgid dir", fs.ModeDir | fs.ModeSetgid | 0o775, "mode 2775, expected 0775"},
+		{"symlink", fs.ModeSymlink | 0o777, ""},
+	}
+
+	for _, tt := range tests {
+		info := fakeFileInfo{mode: tt.mode}
+		if got := p.Check(info); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestPermissionPolicy_CheckOwner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no numeric ownership on Windows")
+	}
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	file := filepath.Join(tempDir, "movie.nfo")
+	createFile(t, file)
+	info, err := os.Lstat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	own := PermissionPolicy{UID: os.Getuid(), GID: os.Getgid()}
+	if got := own.Check(info); got != "" {
+		t.Errorf("Expected own file to conform, got %q", got)
+	}
+
+	other := PermissionPolicy{UID: os.Getuid() + 1, GID: -1}
+	if got := other.Check(info); !strings.HasPrefix(got, "owner ") {
+		t.Errorf("Expected owner mismatch, got %q", got)
+	}
+}
+
+type fakeFileInfo struct {
+	mode fs.FileMode
+}
+
+func (f fakeFileInfo) Name() string       { return "fake" }
+func (f fakeFileInfo) Size() int64        { return 0 }
+func (f fakeFileInfo) Mode() fs.FileMode  { return f.mode }
+func (f fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (f fakeFileInfo) IsDir() bool        { return f.mode.IsDir() }
+func (f fakeFileInfo) Sys() any           { return nil }
+
+// ============================================================================
+// Tests for the permission audit during scans
+// ============================================================================
+
+func TestScan_PermissionAudit(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Movie").Video("movie.mkv").Metadata("movie.nfo").Dir("movie.trickplay", "1.jpg").
+		MapFS()
+	// Builder output is 0755/0644; make a few items conform to the policy
+	for _, p := range []string{"Studio", "Studio/Movie"} {
+		fsys[p] = &fstest.MapFile{Mode: fs.ModeDir | 0o775}
+	}
+	fsys["Studio/Movie/movie.mkv"].Mode = 0o664
+
+	policy := PermissionPolicy{UID: -1, GID: -1, DirMode: 0o775, FileMode: 0o664}
+	result := &CleanupResult{}
+	scanner := NewScanner(WithFS(IOFS(fsys)), WithPermissionAudit(policy))
+	if err := scanner.Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	var paths []string
+	for _, f := range result.ByCategory(CategoryPermissionMismatch) {
+		paths = append(paths, filepath.ToSlash(f.Path))
+	}
+	expected := []string{
+		"Studio/Movie/movie.nfo",
+		"Studio/Movie/movie.trickplay",
+		"Studio/Movie/movie.trickplay/1.jpg",
+	}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected mismatches %v, got %v", expected, paths)
+	}
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Audit should not change other findings, got %v", result.OrphanedFolders)
+	}
+}
+
+func TestScan_NoPermissionAuditByDefault(t *testing.T) {
+	fsys := cleanuptest.New().Studio("Studio").Title("Movie").Video("movie.mkv").MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if n := len(result.ByCategory(CategoryPermissionMismatch)); n != 0 {
+		t.Errorf("Expected no permission findings without the audit, got %d", n)
+	}
+}
+
+// ============================================================================
+// Tests for PermissionFixer
+// ============================================================================
+
+func TestPermissionFixer_FixesModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("chmod only toggles the read-only bit on Windows")
+	}
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	dir := filepath.Join(tempDir, "Studio", "Movie")
+	file := filepath.Join(dir, "movie.nfo")
+	createFile(t, file)
+	if err := os.Chmod(file, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := PermissionPolicy{UID: -1, GID: -1, DirMode: 0o775, FileMode: 0o664}
+	result := &CleanupResult{}
+	if err := NewScanner(WithPermissionAudit(policy)).Scan(context.Background(), tempDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	var events []PermissionFixed
+	report, err := NewPermissionFixer(policy, WithFixProgress(func(ev ProgressEvent) {
+		events = append(events, ev.(PermissionFixed))
+	})).Fix(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Fix returned error: %v", err)
+	}
+	if len(report.Failures) != 0 {
+		t.Fatalf("Expected no failures, got %v", report.Failures)
+	}
+
+	for path, want := range map[string]fs.FileMode{dir: 0o775, file: 0o664} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("Expected %s to be %04o, got %04o", path, want, info.Mode().Perm())
+		}
+	}
+	if len(events) != len(report.Fixed) {
+		t.Errorf("Expected one progress event per fixed item, got %d for %d", len(events), len(report.Fixed))
+	}
+}
+
+func TestPermissionFixer_SkipsDeletedItems(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryPermissionMismatch, Path: filepath.Join(tempDir, "gone.nfo"), Message: "mode 0600, expected 0664"})
+
+	report, err := NewPermissionFixer(PermissionPolicy{UID: -1, GID: -1, FileMode: 0o664}).Fix(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Fix returned error: %v", err)
+	}
+	if len(report.Skipped) != 1 || len(report.Failures) != 0 {
+		t.Errorf("Expected 1 skipped and no failures, got %d skipped and %v", len(report.Skipped), report.Failures)
+	}
+}
+
+func TestPermissionFixer_CancelledContext(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	file := filepath.Join(tempDir, "movie.nfo")
+	createFile(t, file)
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryPermissionMismatch, Path: file})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := NewPermissionFixer(PermissionPolicy{UID: -1, GID: -1, FileMode: 0o600}).Fix(ctx, result)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(report.Fixed) != 0 {
+		t.Errorf("Expected nothing fixed after cancellation, got %d", len(report.Fixed))
+	}
+}
+
+func TestPermissionFixError(t *testing.T) {
+	err := error(&PermissionFixError{Path: "/lib/Studio/movie.nfo", Cause: fs.ErrPermission})
+
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Error("PermissionFixError should unwrap to its cause")
+	}
+	if err.Error() != "failed to fix permissions of /lib/Studio/movie.nfo: permission denied" {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cleanup
+
+import "io/fs"
+
+// fileOwner returns the numeric owner and group of info.
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	st, ok := statOf(info)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
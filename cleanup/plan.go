@@ -0,0 +1,208 @@
+package cleanup
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PlanVersion is the version of the Plan file format.
+const PlanVersion = 1
+
+// Plan is a reviewed list of deletions, to be applied later exactly as
+// written. Each action records a fingerprint of what is on disk, so a plan
+// is refused if anything it covers changed since it was made, and the plan
+// is signed so edits to the file are refused too.
+type Plan struct {
+	Version   int          `json:"plan_version"`
+	Created   time.Time    `json:"created"`
+	Libraries []string     `json:"libraries,omitempty"`
+	Actions   []PlanAction `json:"actions"`
+	// Signature is the hex HMAC-SHA256 of the plan without its signature.
+	Signature string `json:"signature,omitempty"`
+}
+
+// PlanAction is one deletion of a plan: the finding to dispose of and the
+// fingerprint of its contents when the plan was made.
+type PlanAction struct {
+	Finding
+	Fingerprint string `json:"fingerprint"`
+}
+
+// NewPlan builds an unsigned plan holding the orphaned folders, orphaned
+// files, empty folders and junk files of result, the findings a Deleter
+// would dispose of, fingerprinted as they are now.
+func NewPlan(result *CleanupResult, now time.Time) (*Plan, error) {
+	plan := &Plan{Version: PlanVersion, Created: now.UTC(), Libraries: result.Libraries, Actions: []PlanAction{}}
+	for _, f := range result.Findings {
+		switch f.Category {
+		case CategoryOrphanedFolder, CategoryOrphanedFile, CategoryEmptyFolder, CategoryJunkFile:
+		default:
+			continue
+		}
+		fingerprint, err := Fingerprint(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		plan.Actions = append(plan.Actions, PlanAction{Finding: f, Fingerprint: fingerprint})
+	}
+	return plan, nil
+}
+
+// ReadPlan reads the plan file at path and checks it with Validate. It does
+// not check the signature or the fingerprints; see Verify.
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("plan %s: %w", path, err)
+	}
+	if err := plan.Validate(); err != nil {
+		return nil, fmt.Errorf("plan %s: %w", path, err)
+	}
+	return &plan, nil
+}
+
+// Validate checks that p has the shape of the plan definition of
+// schema.json: a supported version, its creation time and signature, and
+// actions that each delete a path of a deletable category with its
+// fingerprint. A file that is not a plan is refused with a clear error
+// rather than as a signature mismatch.
+func (p *Plan) Validate() error {
+	switch {
+	case p.Version == 0:
+		return errors.New("plan_version missing, not a plan file")
+	case p.Version > PlanVersion:
+		return fmt.Errorf("version %d is newer than supported version %d", p.Version, PlanVersion)
+	case p.Created.IsZero():
+		return errors.New("created missing")
+	case p.Actions == nil:
+		return errors.New("actions missing")
+	case p.Signature == "":
+		return errors.New("signature missing")
+	}
+	for i, action := range p.Actions {
+		switch action.Category {
+		case CategoryOrphanedFolder, CategoryOrphanedFile, CategoryEmptyFolder, CategoryJunkFile:
+		default:
+			return fmt.Errorf("action %d: category %q cannot be deleted", i+1, action.Category)
+		}
+		if action.Path == "" {
+			return fmt.Errorf("action %d: path missing", i+1)
+		}
+		if action.Fingerprint == "" {
+			return fmt.Errorf("action %d: fingerprint missing", i+1)
+		}
+	}
+	return nil
+}
+
+// Write saves the plan to path as indented JSON, readable only by its owner.
+func (p *Plan) Write(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Sign sets the signature of p with key.
+func (p *Plan) Sign(key []byte) error {
+	sum, err := p.mac(key)
+	if err != nil {
+		return err
+	}
+	p.Signature = hex.EncodeToString(sum)
+	return nil
+}
+
+// Verify checks that p was signed with key and that nothing it covers has
+// changed on disk since. It returns ErrPlanSignature if the plan was edited
+// or signed with another key, and a *PlanChangedError listing every changed
+// path otherwise.
+func (p *Plan) Verify(key []byte) error {
+	sum, err := p.mac(key)
+	if err != nil {
+		return err
+	}
+	signature, err := hex.DecodeString(p.Signature)
+	if err != nil || !hmac.Equal(sum, signature) {
+		return ErrPlanSignature
+	}
+
+	var changed []string
+	for _, action := range p.Actions {
+		if fingerprint, err := Fingerprint(action.Path); err != nil || fingerprint != action.Fingerprint {
+			changed = append(changed, action.Path)
+		}
+	}
+	if len(changed) > 0 {
+		return &PlanChangedError{Paths: changed}
+	}
+	return nil
+}
+
+// Result returns the actions of p as a result for Deleter.Delete.
+func (p *Plan) Result() *CleanupResult {
+	result := &CleanupResult{Libraries: p.Libraries}
+	for _, action := range p.Actions {
+		result.add(action.Finding)
+	}
+	return result
+}
+
+func (p *Plan) mac(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errors.New("empty plan key")
+	}
+	unsigned := *p
+	unsigned.Signature = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// Fingerprint hashes the names, types, sizes and modification times of
+// path and everything below it, without following symlinks. Any file or
+// folder added, removed, resized or rewritten changes it.
+func Fingerprint(path string) (string, error) {
+	h := sha256.New()
+	err := walkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		modTime := int64(0)
+		if !d.IsDir() {
+			// A folder's time changes with its entries, which are hashed anyway
+			modTime = info.ModTime().UnixNano()
+		}
+		fmt.Fprintf(h, "%s\x00%v\x00%d\x00%d\n", filepath.ToSlash(rel), d.Type(), info.Size(), modTime)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,198 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPlanKey = []byte("0123456789abcdef0123456789abcdef")
+
+// signedPlan builds and signs a plan for the deletable findings of a fresh
+// library, then writes it to disk and reads it back as apply would.
+func signedPlan(t *testing.T, tempDir string) (*Plan, []string) {
+	result, paths := deletableResult(t, filepath.Join(tempDir, "Library"))
+	result.add(Finding{Category: CategoryStructureWarning, Path: filepath.Join(tempDir, "Library", "Loose"), Message: "not a folder"})
+
+	plan, err := NewPlan(result, time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatalf("NewPlan failed: %v", err)
+	}
+	if err := plan.Sign(testPlanKey); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	planPath := filepath.Join(tempDir, "plan.json")
+	if err := plan.Write(planPath); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	read, err := ReadPlan(planPath)
+	if err != nil {
+		t.Fatalf("ReadPlan failed: %v", err)
+	}
+	return read, paths
+}
+
+// ============================================================================
+// Tests for Plan
+// ============================================================================
+
+func TestPlan_KeepsOnlyDeletableFindings(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	plan, paths := signedPlan(t, tempDir)
+
+	if len(plan.Actions) != len(paths) {
+		t.Fatalf("Expected %d actions, got %d", len(paths), len(plan.Actions))
+	}
+	for i, action := range plan.Actions {
+		if action.Path != paths[i] {
+			t.Errorf("Expected action %d for %s, got %s", i, paths[i], action.Path)
+		}
+		if action.Fingerprint == "" {
+			t.Errorf("Expected a fingerprint for %s", action.Path)
+		}
+	}
+}
+
+func TestPlan_VerifyUnchanged(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	plan, _ := signedPlan(t, tempDir)
+
+	if err := plan.Verify(testPlanKey); err != nil {
+		t.Errorf("Expected an unchanged plan to verify, got %v", err)
+	}
+}
+
+func TestPlan_VerifyRejectsEditedPlan(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	plan, _ := signedPlan(t, tempDir)
+	plan.Actions[0].Path = filepath.Join(tempDir, "Library", "Studio")
+
+	if err := plan.Verify(testPlanKey); !errors.Is(err, ErrPlanSignature) {
+		t.Errorf("Expected ErrPlanSignature, got %v", err)
+	}
+}
+
+func TestPlan_VerifyRejectsOtherKey(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	plan, _ := signedPlan(t, tempDir)
+
+	if err := plan.Verify([]byte("another key")); !errors.Is(err, ErrPlanSignature) {
+		t.Errorf("Expected ErrPlanSignature, got %v", err)
+	}
+}
+
+func TestPlan_VerifyRejectsChangedFiles(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	plan, paths := signedPlan(t, tempDir)
+	// A video restored into the orphaned folder, and the empty folder gone
+	createFile(t, filepath.Join(paths[0], "movie.mkv"))
+	if err := os.Remove(paths[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	err := plan.Verify(testPlanKey)
+	var changed *PlanChangedError
+	if !errors.As(err, &changed) {
+		t.Fatalf("Expected a PlanChangedError, got %v", err)
+	}
+	if len(changed.Paths) != 2 || changed.Paths[0] != paths[0] || changed.Paths[1] != paths[2] {
+		t.Errorf("Expected changed paths %v, got %v", []string{paths[0], paths[2]}, changed.Paths)
+	}
+}
+
+func TestPlan_VerifyRejectsRewrittenFile(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	plan, paths := signedPlan(t, tempDir)
+	if err := os.WriteFile(paths[1], []byte("rewritten"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var changed *PlanChangedError
+	if err := plan.Verify(testPlanKey); !errors.As(err, &changed) {
+		t.Errorf("Expected a PlanChangedError, got %v", err)
+	}
+}
+
+func TestPlan_ResultDeletesPlannedItems(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	plan, paths := signedPlan(t, tempDir)
+
+	report, err := NewDeleter(PermanentStrategy{}).Delete(context.Background(), plan.Result())
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(report.Deleted) != len(paths) {
+		t.Errorf("Expected %d deletions, got %d", len(paths), len(report.Deleted))
+	}
+	for _, path := range paths {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be deleted", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "Library", "Studio")); err != nil {
+		t.Errorf("Expected the studio folder to remain, got %v", err)
+	}
+}
+
+func TestReadPlan_RejectsNewerVersion(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	planPath := filepath.Join(tempDir, "plan.json")
+	if err := os.WriteFile(planPath, []byte(`{"plan_version": 99, "actions": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadPlan(planPath); err == nil {
+		t.Error("Expected an error for a newer plan version")
+	}
+}
+
+func TestReadPlan_RejectsMalformedPlan(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	const action = `{"category": "orphaned_folder", "path": "/lib/S/M", "fingerprint": "ab"}`
+	tests := []struct {
+		name string
+		plan string
+		want string
+	}{
+		{"not a plan", `{"findings": []}`, "plan_version missing"},
+		{"no creation time", `{"plan_version": 1, "actions": [], "signature": "ab"}`, "created missing"},
+		{"no actions", `{"plan_version": 1, "created": "2024-01-01T00:00:00Z", "signature": "ab"}`, "actions missing"},
+		{"unsigned", `{"plan_version": 1, "created": "2024-01-01T00:00:00Z", "actions": [` + action + `]}`, "signature missing"},
+		{"not deletable", `{"plan_version": 1, "created": "2024-01-01T00:00:00Z", "signature": "ab", "actions": [{"category": "structure_warning", "path": "/lib/x", "fingerprint": "ab"}]}`, "cannot be deleted"},
+		{"no fingerprint", `{"plan_version": 1, "created": "2024-01-01T00:00:00Z", "signature": "ab", "actions": [{"category": "empty_folder", "path": "/lib/x"}]}`, "fingerprint missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planPath := filepath.Join(tempDir, "plan.json")
+			if err := os.WriteFile(planPath, []byte(tt.plan), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadPlan(planPath)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error about %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+)
+
+// RunPool calls fn for every item on a bounded pool of goroutines. workers
+// is clamped to at least one, so a misconfigured pool still makes progress
+// instead of silently doing nothing.
+//
+// Items are handed out in order; once ctx is done no further items are
+// started, but those already running finish. Callers check ctx.Err() to
+// tell a cancelled run from a complete one. A panic in fn is recovered and
+// reported as a *PanicError. The errors returned by fn are joined with
+// errors.Join; RunPool returns nil when every call succeeded.
+func RunPool[T any](ctx context.Context, workers int, items []T, fn func(T) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	itemChan := make(chan T)
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range itemChan {
+				if err := callRecovering(fn, item); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, item := range items {
+		// Checked first: select picks at random when both cases are ready
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case itemChan <- item:
+		case <-ctx.Done():
+		}
+	}
+	close(itemChan)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// callRecovering calls fn(item), turning a panic into a *PanicError.
+func callRecovering[T any](fn func(T) error, item T) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn(item)
+}
+
+// WorkerBudget caps how many folders are processed at once across every
+// Scanner sharing it, so that several libraries can be scanned concurrently
+// without multiplying the number of workers. Create one with NewWorkerBudget.
+type WorkerBudget struct {
+	slots chan struct{}
+}
+
+// NewWorkerBudget returns a budget of n slots. Values below one are treated
+// as one.
+func NewWorkerBudget(n int) *WorkerBudget {
+	if n < 1 {
+		n = 1
+	}
+	return &WorkerBudget{slots: make(chan struct{}, n)}
+}
+
+// do calls fn once a slot is free and releases the slot afterwards. A nil
+// budget calls fn right away. do returns false without calling fn if ctx is
+// done before a slot frees up.
+func (b *WorkerBudget) do(ctx context.Context, fn func()) bool {
+	if b == nil {
+		fn()
+		return true
+	}
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	defer func() { <-b.slots }()
+	fn()
+	return true
+}
@@ -0,0 +1,187 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// ============================================================================
+// Tests for RunPool
+// ============================================================================
+
+func TestRunPool_ProcessesAllItems(t *testing.T) {
+	for _, workers := range []int{-1, 0, 1, 4, 100} {
+		var mu sync.Mutex
+		seen := map[int]bool{}
+		items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+
+		err := RunPool(context.Background(), workers, items, func(i int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[i] = true
+			return nil
+		})
+		if err != nil {
+			t.Errorf("workers=%d: unexpected error %v", workers, err)
+		}
+		if len(seen) != len(items) {
+			t.Errorf("workers=%d: expected %d items processed, got %d", workers, len(items), len(seen))
+		}
+	}
+}
+
+func TestRunPool_BoundsConcurrency(t *testing.T) {
+	var running, peak int32
+	items := make([]int, 50)
+
+	if err := RunPool(context.Background(), 3, items, func(int) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		atomic.AddInt32(&running, -1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if peak > 3 {
+		t.Errorf("Expected at most 3 concurrent calls, got %d", peak)
+	}
+}
+
+func TestRunPool_AggregatesErrors(t *testing.T) {
+	errOdd := errors.New("odd")
+	err := RunPool(context.Background(), 2, []int{1, 2, 3, 4}, func(i int) error {
+		if i%2 == 1 {
+			return errOdd
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errOdd) {
+		t.Fatalf("Expected joined errors to contain errOdd, got %v", err)
+	}
+	if got := len(SplitErrors(err)); got != 2 {
+		t.Errorf("Expected 2 errors, got %d", got)
+	}
+}
+
+func TestRunPool_RecoversPanics(t *testing.T) {
+	var processed int32
+	err := RunPool(context.Background(), 2, []int{1, 2, 3}, func(i int) error {
+		if i == 2 {
+			panic("boom")
+		}
+		atomic.AddInt32(&processed, 1)
+		return nil
+	})
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected PanicError, got %v", err)
+	}
+	if panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("Expected panic value and stack, got %v and %d bytes", panicErr.Value, len(panicErr.Stack))
+	}
+	if processed != 2 {
+		t.Errorf("Expected the other items to be processed, got %d", processed)
+	}
+}
+
+func TestRunPool_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var processed int32
+
+	err := RunPool(ctx, 1, make([]int, 100), func(int) error {
+		if atomic.AddInt32(&processed, 1) == 5 {
+			cancel()
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("Cancellation should not be reported as an item error, got %v", err)
+	}
+	if processed > 6 {
+		t.Errorf("Expected the pool to stop shortly after cancellation, processed %d", processed)
+	}
+}
+
+func TestRunPool_NoItems(t *testing.T) {
+	if err := RunPool(context.Background(), 4, nil, func(int) error { return nil }); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+// ============================================================================
+// Tests for WorkerBudget
+// ============================================================================
+
+func TestWorkerBudget_BoundsConcurrencyAcrossPools(t *testing.T) {
+	budget := NewWorkerBudget(2)
+	var running, peak int32
+	items := make([]int, 20)
+
+	var wg sync.WaitGroup
+	for pool := 0; pool < 3; pool++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = RunPool(context.Background(), 4, items, func(int) error {
+				budget.do(context.Background(), func() {
+					n := atomic.AddInt32(&running, 1)
+					for {
+						p := atomic.LoadInt32(&peak)
+						if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+							break
+						}
+					}
+					atomic.AddInt32(&running, -1)
+				})
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent calls, got %d", peak)
+	}
+}
+
+func TestWorkerBudget_StopsWaitingOnCancel(t *testing.T) {
+	budget := NewWorkerBudget(1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go budget.do(context.Background(), func() {
+		close(started)
+		<-release
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	if budget.do(ctx, func() { called = true }) {
+		t.Error("Expected do to give up on a cancelled context")
+	}
+	if called {
+		t.Error("Expected fn not to be called without a slot")
+	}
+}
+
+func TestWorkerBudget_NilRunsImmediately(t *testing.T) {
+	var budget *WorkerBudget
+	called := false
+	if !budget.do(context.Background(), func() { called = true }) || !called {
+		t.Error("Expected a nil budget to call fn right away")
+	}
+}
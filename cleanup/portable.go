@@ -0,0 +1,225 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxNameBytes is the longest file name most filesystems accept.
+const maxNameBytes = 255
+
+// reservedNameChars cannot appear in names on Windows, exFAT and SMB shares.
+const reservedNameChars = `<>:"\|?*`
+
+// reservedDeviceNames are refused by Windows as a name, with or without an
+// extension.
+var reservedDeviceNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// nameProblems returns why name would break when copied to a Windows, exFAT
+// or SMB target, or nil if it would not.
+func nameProblems(name string) []string {
+	var problems []string
+
+	var reserved []rune
+	control := false
+	for _, c := range name {
+		switch {
+		case c < 0x20:
+			control = true
+		case strings.ContainsRune(reservedNameChars, c) && !strings.ContainsRune(string(reserved), c):
+			reserved = append(reserved, c)
+		}
+	}
+	if len(reserved) > 0 {
+		problems = append(problems, fmt.Sprintf("reserved characters %s", string(reserved)))
+	}
+	if control {
+		problems = append(problems, "control characters")
+	}
+	if strings.HasSuffix(name, " ") || strings.HasSuffix(name, ".") {
+		problems = append(problems, "trailing space or dot")
+	}
+	if base, _, _ := strings.Cut(name, "."); reservedDeviceNames[strings.ToLower(strings.TrimRight(base, " "))] {
+		problems = append(problems, "reserved device name")
+	}
+	if len(name) > maxNameBytes {
+		problems = append(problems, fmt.Sprintf("%d bytes long (limit %d)", len(name), maxNameBytes))
+	}
+	return problems
+}
+
+// portableName returns name with reserved and control characters replaced
+// or dropped and trailing spaces and dots trimmed, or "" if nothing is left.
+// Colons and pipes become dashes, so "Alien: Covenant" reads "Alien - Covenant"
+// and "1:2" reads "1-2".
+func portableName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, c := range runes {
+		switch {
+		case c == ':' || c == '|':
+			if i+1 < len(runes) && runes[i+1] == ' ' && !strings.HasSuffix(b.String(), " ") {
+				b.WriteString(" -")
+			} else {
+				b.WriteRune('-')
+			}
+		case c == '"':
+			b.WriteRune('\'')
+		case c < 0x20 || strings.ContainsRune(reservedNameChars, c):
+			// Dropped
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return strings.TrimRight(strings.Join(strings.Fields(b.String()), " "), " .")
+}
+
+// holdsTitles reports whether the folders in dirPath are title folders.
+func (r *scanRun) holdsTitles(dirPath string) bool {
+	rel, err := filepath.Rel(r.library, dirPath)
+	if err != nil {
+		return false
+	}
+	depth := 0
+	if rel != "." {
+		depth = len(strings.Split(rel, string(filepath.Separator)))
+	}
+	return depth == len(r.layout.Levels)-1
+}
+
+// auditNames reports the entries of dirPath whose names are not portable,
+// including names that only differ by case from a sibling. Title folders
+// whose name can be made portable get the renamed path as Target, unless a
+// sibling already has that name.
+func (r *scanRun) auditNames(dirPath string, entries []fs.DirEntry) {
+	titles := r.holdsTitles(dirPath)
+	taken := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		taken[strings.ToLower(entry.Name())] = true
+	}
+	seen := make(map[string]string, len(entries)) // lowercase name -> first name
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dirPath, name)
+		if problems := nameProblems(name); len(problems) > 0 {
+			f := Finding{Category: CategoryIncompatibleName, Path: path,
+				Message: "Name not portable (" + strings.Join(problems, ", ") + ")"}
+			if fixed := portableName(name); titles && entry.IsDir() && fixed != "" && !taken[strings.ToLower(fixed)] {
+				f.Target = filepath.Join(dirPath, fixed)
+			}
+			r.emit(f)
+		}
+		folded := strings.ToLower(name)
+		if other, ok := seen[folded]; ok {
+			r.emit(Finding{Category: CategoryIncompatibleName, Path: path,
+				Message: fmt.Sprintf("Name differs only by case from %s", other)})
+			continue
+		}
+		seen[folded] = name
+	}
+}
+
+// NameFixReport summarizes a NameFixer run.
+type NameFixReport struct {
+	Renamed  []Finding
+	Skipped  []Finding // gone since the scan
+	Failures []error   // one *MoveError per item, or *PanicError
+}
+
+// NameFixer renames title folders whose names are not portable to the name
+// the scan proposed. It mirrors StructureFixer: nothing is changed until Fix
+// is called, which callers only do in execute mode.
+type NameFixer struct {
+	progress ProgressFunc
+}
+
+// NameFixerOption configures a NameFixer.
+type NameFixerOption func(*NameFixer)
+
+// WithRenameProgress calls fn with a FileMoved event after every item.
+func WithRenameProgress(fn ProgressFunc) NameFixerOption {
+	return func(f *NameFixer) {
+		f.progress = fn
+	}
+}
+
+// NewNameFixer returns a NameFixer.
+func NewNameFixer(opts ...NameFixerOption) *NameFixer {
+	f := &NameFixer{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Renamable returns the incompatible names of result that have a portable
+// replacement, in the order Fix renames them.
+func Renamable(result *CleanupResult) []Finding {
+	var items []Finding
+	for _, f := range result.ByCategory(CategoryIncompatibleName) {
+		if f.Target != "" {
+			items = append(items, f)
+		}
+	}
+	return items
+}
+
+// Fix renames every Renamable finding of result to its target, never
+// replacing an existing file or folder. Like StructureFixer.Fix, it stops
+// before the next item once ctx is done and only returns an error in that
+// case.
+func (f *NameFixer) Fix(ctx context.Context, result *CleanupResult) (*NameFixReport, error) {
+	report := &NameFixReport{}
+	items := Renamable(result)
+
+	var mu sync.Mutex
+	done := 0
+	err := RunPool(ctx, 1, items, func(finding Finding) error {
+		if _, err := os.Lstat(longPath(finding.Path)); errors.Is(err, fs.ErrNotExist) {
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			report.Skipped = append(report.Skipped, finding)
+			return nil
+		}
+
+		err := renameTo(finding.Path, finding.Target)
+
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		var failure error
+		if err != nil {
+			failure = &MoveError{Path: finding.Path, Target: finding.Target, Cause: err}
+			report.Failures = append(report.Failures, failure)
+		} else {
+			report.Renamed = append(report.Renamed, finding)
+		}
+		if f.progress != nil {
+			f.progress(FileMoved{Finding: finding, Err: failure, Done: done, Total: len(items)})
+		}
+		return nil
+	})
+	report.Failures = append(report.Failures, SplitErrors(err)...)
+	return report, ctx.Err()
+}
+
+// renameTo renames path to target unless something already has that name.
+func renameTo(path, target string) error {
+	if _, err := os.Lstat(longPath(target)); err == nil {
+		return fs.ErrExist
+	}
+	return os.Rename(longPath(path), longPath(target))
+}
@@ -0,0 +1,192 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for portable name checks
+// ============================================================================
+
+func TestNameProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Movie (2020).mkv", ""},
+		{"Alien: Covenant (2017)", "reserved characters :"},
+		{`What? Why* "Now"`, `reserved characters ?*"`},
+		{"Tab\there", "control characters"},
+		{"Trailing ", "trailing space or dot"},
+		{"Trailing.", "trailing space or dot"},
+		{"con.nfo", "reserved device name"},
+		{"LPT1", "reserved device name"},
+		{"Console.nfo", ""},
+		{strings.Repeat("a", 256), "256 bytes long (limit 255)"},
+		{strings.Repeat("é", 128), "256 bytes long (limit 255)"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(nameProblems(tt.name), ", ")
+		if got != tt.expected {
+			t.Errorf("nameProblems(%q): expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestWithNameAudit(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Alien: Covenant (2017)").Video("movie.mkv").
+		Title("Clean (2020)").Video("movie.mkv").Metadata("Movie.nfo", "movie.NFO").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if names := result.ByCategory(CategoryIncompatibleName); len(names) != 0 {
+		t.Errorf("Expected no name findings without the audit, got %v", names)
+	}
+
+	result = &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithNameAudit(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	names := result.ByCategory(CategoryIncompatibleName)
+	if len(names) != 2 {
+		t.Fatalf("Expected 2 name findings, got %d: %v", len(names), names)
+	}
+	for _, f := range names {
+		switch f.Path {
+		case "Studio/Alien: Covenant (2017)":
+			if !strings.Contains(f.Message, "reserved characters :") {
+				t.Errorf("Expected reserved character message, got %q", f.Message)
+			}
+		case "Studio/Clean (2020)/movie.NFO", "Studio/Clean (2020)/movie.nfo", "Studio/Clean (2020)/Movie.nfo":
+			if !strings.Contains(f.Message, "differs only by case") {
+				t.Errorf("Expected case collision message, got %q", f.Message)
+			}
+		default:
+			t.Errorf("Unexpected name finding %v", f)
+		}
+	}
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Expected name findings not to affect orphan detection, got %v", result.OrphanedFolders)
+	}
+}
+
+func TestPortableName(t *testing.T) {
+	tests := map[string]string{
+		"Alien: Covenant (2017)": "Alien - Covenant (2017)",
+		"Face/Off (1997)":        "Face/Off (1997)",
+		"What? Why* Now (2001)":  "What Why Now (2001)",
+		`Say "Cheese" (2002)`:    "Say 'Cheese' (2002)",
+		"Mission 1:2 (2003)":     "Mission 1-2 (2003)",
+		"Dots... ":               "Dots",
+		"Tab\there (2004)":       "Tabhere (2004)",
+		"Good | Bad (2005)":      "Good - Bad (2005)",
+		"???":                    "",
+	}
+	for name, expected := range tests {
+		if got := portableName(name); got != expected {
+			t.Errorf("portableName(%q): expected %q, got %q", name, expected, got)
+		}
+	}
+}
+
+func TestWithNameAudit_RenameTargets(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio: One").
+		Title("Alien: Covenant (2017)").Video("movie: cut.mkv").
+		Title("Taken? (2008)").Video("movie.mkv").
+		Title("Taken (2008)").Video("movie.mkv").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithNameAudit(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"Studio: One": "",
+		filepath.Join("Studio: One", "Alien: Covenant (2017)"):                   filepath.Join("Studio: One", "Alien - Covenant (2017)"),
+		filepath.Join("Studio: One", "Alien: Covenant (2017)", "movie: cut.mkv"): "",
+		filepath.Join("Studio: One", "Taken? (2008)"):                            "",
+	}
+	names := result.ByCategory(CategoryIncompatibleName)
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %d name findings, got %v", len(expected), names)
+	}
+	for _, f := range names {
+		if want, ok := expected[f.Path]; !ok || f.Target != want {
+			t.Errorf("Expected %s to target %q, got %q", f.Path, want, f.Target)
+		}
+	}
+}
+
+// ============================================================================
+// Tests for NameFixer
+// ============================================================================
+
+func TestNameFixer_RenamesTitleFolders(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, filepath.Join(tempDir, "Studio", "Alien: Covenant (2017)", "movie.mkv"))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithNameAudit(true)).Scan(context.Background(), tempDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	var events []FileMoved
+	report, err := NewNameFixer(WithRenameProgress(func(ev ProgressEvent) {
+		events = append(events, ev.(FileMoved))
+	})).Fix(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Fix returned error: %v", err)
+	}
+	if len(report.Renamed) != 1 || len(report.Failures) != 0 {
+		t.Fatalf("Expected 1 renamed and no failures, got %d renamed and %v", len(report.Renamed), report.Failures)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "Studio", "Alien - Covenant (2017)", "movie.mkv")); err != nil {
+		t.Errorf("Expected the video in the renamed folder: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected one progress event per renamed folder, got %d", len(events))
+	}
+}
+
+func TestNameFixer_NeverReplaces(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "Studio", "Taken? (2008)")
+	target := filepath.Join(tempDir, "Studio", "Taken (2008)")
+	createDir(t, path)
+	createDir(t, target)
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryIncompatibleName, Path: path, Target: target})
+	result.add(Finding{Category: CategoryIncompatibleName, Path: filepath.Join(tempDir, "gone"), Target: filepath.Join(tempDir, "gone2")})
+
+	report, err := NewNameFixer().Fix(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Fix returned error: %v", err)
+	}
+	var moveErr *MoveError
+	if len(report.Failures) != 1 || !errors.As(report.Failures[0], &moveErr) || !errors.Is(moveErr, fs.ErrExist) {
+		t.Fatalf("Expected a MoveError for the existing folder, got %v", report.Failures)
+	}
+	if len(report.Skipped) != 1 {
+		t.Errorf("Expected the vanished folder to be skipped, got %v", report.Skipped)
+	}
+}
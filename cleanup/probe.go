@@ -0,0 +1,76 @@
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// VideoProber inspects the content of video files for WithVideoProbe.
+// Probe returns a description of what is wrong with the video at path, or
+// "" if it plays. err is set only when the video could not be probed at
+// all, e.g. the tool is missing; that says nothing about the video.
+type VideoProber interface {
+	Probe(ctx context.Context, path string) (problem string, err error)
+}
+
+// FFProbe is a VideoProber running ffprobe from FFmpeg. A video is corrupt
+// when ffprobe cannot parse its container or reports no duration.
+type FFProbe struct {
+	// Path is the ffprobe executable; "ffprobe" looked up in PATH if empty.
+	Path string
+}
+
+func (p FFProbe) Probe(ctx context.Context, path string) (string, error) {
+	bin := p.Path
+	if bin == "" {
+		bin = "ffprobe"
+	}
+	cmd := exec.CommandContext(ctx, bin, "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if _, ok := err.(*exec.ExitError); ok {
+		// The first line names the problem; the rest repeats it per stream
+		msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "ffprobe cannot read it: " + strings.TrimSpace(msg), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("probe %s: %w", path, err)
+	}
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil || duration <= 0 {
+		return "Zero or unknown duration", nil
+	}
+	return "", nil
+}
+
+// probeVideo reports the video entry of dirPath as corrupt if the prober
+// finds a problem with it.
+func (r *scanRun) probeVideo(dirPath, name string) {
+	if r.prober == nil || isDiscImage(name) || !r.checks(CategoryCorruptVideo) {
+		return
+	}
+	path := filepath.Join(dirPath, name)
+	problem, err := r.prober.Probe(r.ctx, path)
+	if err != nil {
+		if r.ctx.Err() == nil {
+			r.fail(err)
+		}
+		return
+	}
+	if problem != "" {
+		r.emit(Finding{Category: CategoryCorruptVideo, Path: path, Message: problem})
+	}
+}
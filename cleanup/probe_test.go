@@ -0,0 +1,117 @@
+package cleanup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// fakeProber reports the videos named in problems.
+type fakeProber struct {
+	problems map[string]string
+}
+
+func (p fakeProber) Probe(_ context.Context, path string) (string, error) {
+	return p.problems[filepath.Base(path)], nil
+}
+
+// fakeFFProbe writes a shell script standing in for ffprobe.
+func fakeFFProbe(t *testing.T, script string) FFProbe {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "ffprobe")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return FFProbe{Path: path}
+}
+
+// ============================================================================
+// Tests for WithVideoProbe
+// ============================================================================
+
+func TestScan_VideoProbe(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Broken (2001)").Video("broken.mkv").
+		Title("Fine (2002)").Video("fine.mkv").
+		MapFS()
+
+	prober := fakeProber{problems: map[string]string{"broken.mkv": "Zero or unknown duration"}}
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithVideoProbe(prober)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	corrupt := result.ByCategory(CategoryCorruptVideo)
+	expected := filepath.Join("Studio", "Broken (2001)", "broken.mkv")
+	if len(corrupt) != 1 || corrupt[0].Path != expected {
+		t.Errorf("Expected %s as the only corrupt video, got %v", expected, corrupt)
+	}
+}
+
+func TestScan_TruncatedVideosAreNotProbed(t *testing.T) {
+	fsys := cleanuptest.New().Studio("Studio").Title("Stub (2001)").File("movie.mkv", nil).MapFS()
+
+	prober := fakeProber{problems: map[string]string{"movie.mkv": "Zero or unknown duration"}}
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithMinVideoSize(1), WithVideoProbe(prober)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.ByCategory(CategoryCorruptVideo)) != 0 || len(result.ByCategory(CategoryTruncatedVideo)) != 1 {
+		t.Errorf("Expected only a truncated video, got %v", result.Findings)
+	}
+}
+
+func TestScan_DiscImagesAreNotProbed(t *testing.T) {
+	fsys := cleanuptest.New().Studio("Studio").Title("Disc (2001)").Video("movie.ISO").MapFS()
+
+	prober := fakeProber{problems: map[string]string{"movie.ISO": "ffprobe cannot read it: Invalid data found when processing input"}}
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithVideoProbe(prober)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.Findings) != 0 {
+		t.Errorf("Expected a disc image to keep its title without being probed, got %v", result.Findings)
+	}
+}
+
+// ============================================================================
+// Tests for FFProbe
+// ============================================================================
+
+func TestFFProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{"plays", "echo 5400.120000", ""},
+		{"zero duration", "echo 0.000000", "Zero or unknown duration"},
+		{"no duration", "echo N/A", "Zero or unknown duration"},
+		{"unreadable", "echo 'movie.mkv: Invalid data found when processing input' >&2; exit 1", "ffprobe cannot read it: movie.mkv: Invalid data found when processing input"},
+	}
+	for _, tt := range tests {
+		problem, err := fakeFFProbe(t, tt.script).Probe(context.Background(), "movie.mkv")
+		if err != nil || problem != tt.expected {
+			t.Errorf("%s: expected %q, got %q (%v)", tt.name, tt.expected, problem, err)
+		}
+	}
+}
+
+func TestFFProbe_Missing(t *testing.T) {
+	problem, err := FFProbe{Path: filepath.Join(t.TempDir(), "ffprobe")}.Probe(context.Background(), "movie.mkv")
+	if err == nil || problem != "" {
+		t.Errorf("Expected an error and no verdict on the video, got %q (%v)", problem, err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "movie.mkv") {
+		t.Errorf("Expected the error to name the video, got %v", err)
+	}
+}
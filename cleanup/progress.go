@@ -0,0 +1,108 @@
+package cleanup
+
+// ProgressEvent is a typed progress notification from a scan, deletion or
+// permission fix run. It is one of LibraryStarted, StudioScanned,
+// TitleScanned, FolderSkipped, DeletionDone, PermissionFixed or FileMoved;
+// switch on the concrete type to render it.
+type ProgressEvent interface {
+	progressEvent()
+}
+
+// LibraryStarted is sent once a library root has been validated and its
+// top-level folders listed, before any of them is scanned.
+type LibraryStarted struct {
+	Library string
+	Studios int // top-level folders to scan
+	Resumed int // of which finished by an interrupted scan, see Checkpoint
+}
+
+// StudioScanned is sent when a top-level folder (a studio in the default
+// layout) and everything below it has been scanned.
+type StudioScanned struct {
+	Library string
+	Path    string
+	Done    int // top-level folders finished so far, including this one
+	Total   int
+}
+
+// TitleScanned is sent when a title folder has been scanned.
+type TitleScanned struct {
+	Library string
+	Path    string
+	// Decision is what the scan made of the folder as a whole, one of the
+	// Decision constants, for a trace of the scan
+	Decision string
+}
+
+// Decisions a TitleScanned event carries.
+const (
+	DecisionKept       = "has a video, kept"
+	DecisionOrphaned   = "no video, reported as orphaned"
+	DecisionEmpty      = "empty, reported"
+	DecisionMisfiled   = "only video is in an extras subfolder, reported as misfiled"
+	DecisionInProgress = "no video but partial downloads, reported as in progress"
+	DecisionIgnored    = "no video but ignored entries, kept"
+	DecisionUnchanged  = "unchanged since the last scan, findings reused"
+)
+
+// FolderSkipped is sent for a folder the scan does not enter because the
+// include and exclude patterns or an ignore file leave it out.
+type FolderSkipped struct {
+	Library string
+	Path    string
+	Reason  string // SkippedByFilter, SkippedByIgnoreFile or SkippedRecycleBin
+}
+
+// Reasons a FolderSkipped event carries.
+const (
+	SkippedByFilter     = "left out by the include and exclude patterns"
+	SkippedByIgnoreFile = "listed in an ignore file"
+	SkippedRecycleBin   = "recycle bin managed by the NAS"
+)
+
+// DeletionDone is sent by a Deleter after each item it handles.
+type DeletionDone struct {
+	Finding Finding
+	Err     error // nil when the item was deleted
+	Kept    bool  // hardlinked files were left in place (WithPreserveHardlinks)
+	Done    int   // items handled so far, including this one
+	Total   int
+}
+
+// PermissionFixed is sent by a PermissionFixer after each item it handles.
+type PermissionFixed struct {
+	Finding Finding
+	Err     error // nil when the item was fixed
+	Done    int   // items handled so far, including this one
+	Total   int
+}
+
+// FileMoved is sent by a StructureFixer or NameFixer after each item it
+// handles.
+type FileMoved struct {
+	Finding Finding
+	Err     error // nil when the item was moved
+	Done    int   // items handled so far, including this one
+	Total   int
+}
+
+func (LibraryStarted) progressEvent()  {}
+func (StudioScanned) progressEvent()   {}
+func (TitleScanned) progressEvent()    {}
+func (FolderSkipped) progressEvent()   {}
+func (DeletionDone) progressEvent()    {}
+func (PermissionFixed) progressEvent() {}
+func (FileMoved) progressEvent()       {}
+
+// ProgressFunc receives progress events. Scanner, Deleter and the fixers
+// never call it concurrently.
+type ProgressFunc func(ProgressEvent)
+
+// ProgressChannel returns a ProgressFunc that sends every event on ch. Sends
+// block, so ch must be buffered or drained while the run is in progress; the
+// caller closes ch once the run has returned.
+func ProgressChannel(ch chan<- ProgressEvent) ProgressFunc {
+	return func(ev ProgressEvent) {
+		ch <- ev
+	}
+}
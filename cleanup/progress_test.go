@@ -0,0 +1,120 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for progress events
+// ============================================================================
+
+func TestScan_ProgressEvents(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio1").
+		Title("Movie1").Video("movie.mkv").
+		Title("Movie2").Metadata("movie.nfo").
+		Studio("Studio2").
+		Title("Movie3").
+		Studio("Empty Studio").
+		MapFS()
+
+	var events []ProgressEvent
+	scanner := NewScanner(WithFS(IOFS(fsys)), WithWorkers(2), WithProgress(func(ev ProgressEvent) {
+		events = append(events, ev)
+	}))
+	if err := scanner.Scan(context.Background(), ".", func(Finding) error { return nil }); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(events) == 0 {
+		t.Fatal("Expected progress events")
+	}
+	started, ok := events[0].(LibraryStarted)
+	if !ok || started.Library != "." || started.Studios != 3 {
+		t.Errorf("Expected LibraryStarted for 3 studios first, got %#v", events[0])
+	}
+
+	var titles, studios int
+	for _, ev := range events[1:] {
+		switch ev := ev.(type) {
+		case TitleScanned:
+			titles++
+		case StudioScanned:
+			studios++
+			if ev.Done != studios || ev.Total != 3 {
+				t.Errorf("Expected studio %d/3, got %d/%d", studios, ev.Done, ev.Total)
+			}
+		default:
+			t.Errorf("Unexpected event %#v", ev)
+		}
+	}
+	if titles != 3 {
+		t.Errorf("Expected 3 TitleScanned events, got %d", titles)
+	}
+	if studios != 3 {
+		t.Errorf("Expected 3 StudioScanned events, got %d", studios)
+	}
+}
+
+func TestScan_ProgressDecisions(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Movie1").Video("movie.mkv").
+		Title("Movie2").Metadata("movie.nfo").
+		Title("Movie3").
+		Studio("Staging").
+		Title("Movie4").Video("movie.mkv").
+		MapFS()
+
+	decisions := map[string]string{}
+	scanner := NewScanner(WithFS(IOFS(fsys)), WithPathFilter(PathFilter{Exclude: []string{"Staging"}}),
+		WithProgress(func(ev ProgressEvent) {
+			switch ev := ev.(type) {
+			case TitleScanned:
+				decisions[ev.Path] = ev.Decision
+			case FolderSkipped:
+				decisions[ev.Path] = ev.Reason
+			}
+		}))
+	if err := scanner.Scan(context.Background(), ".", func(Finding) error { return nil }); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"Studio/Movie1": DecisionKept,
+		"Studio/Movie2": DecisionOrphaned,
+		"Studio/Movie3": DecisionEmpty,
+		"Staging":       SkippedByFilter,
+	}
+	if len(decisions) != len(expected) {
+		t.Errorf("Expected %d decisions, got %v", len(expected), decisions)
+	}
+	for path, want := range expected {
+		if got := decisions[path]; got != want {
+			t.Errorf("Expected %q for %s, got %q", want, path, got)
+		}
+	}
+}
+
+func TestProgressChannel(t *testing.T) {
+	fsys := cleanuptest.New().Studio("Studio").Title("Movie").Video("movie.mkv").MapFS()
+
+	ch := make(chan ProgressEvent, 16)
+	scanner := NewScanner(WithFS(IOFS(fsys)), WithProgress(ProgressChannel(ch)))
+	if err := scanner.Scan(context.Background(), ".", func(Finding) error { return nil }); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	close(ch)
+
+	var count int
+	for range ch {
+		count++
+	}
+	// LibraryStarted, TitleScanned, StudioScanned
+	if count != 3 {
+		t.Errorf("Expected 3 events on the channel, got %d", count)
+	}
+}
@@ -0,0 +1,191 @@
+package cleanup
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ProtectedPaths are glob patterns of paths that no action may touch,
+// whatever their findings. They are matched against whole paths using
+// forward slashes, with the elements of PathFilter patterns: each
+// "/"-separated element follows path.Match and "**" matches any number of
+// folders, so "/mnt/media/Movies/Criterion/**" protects everything below the
+// Criterion folder and "**/Keep" every folder named Keep. Absolute patterns
+// are matched against absolute paths; relative ones, like PathFilter
+// patterns, against the path of a finding relative to its library root, so
+// "Criterion/**" protects the Criterion folder of every library.
+//
+// A path is protected when a pattern matches it or a folder above it. An
+// orphaned folder, or a folder to rename, is protected too when a pattern
+// matches something inside it, at any depth, as deleting or renaming the
+// folder would take it along.
+type ProtectedPaths []string
+
+// Validate reports the first malformed pattern.
+func (p ProtectedPaths) Validate() error {
+	return PathFilter{Exclude: p}.Validate()
+}
+
+// ProtectedFinding is a finding whose action touches a protected path.
+type ProtectedFinding struct {
+	Finding Finding
+	Pattern string // the pattern protecting the path
+}
+
+// Check returns a *ProtectedError listing the findings whose action would
+// touch a protected path, at Path or, for moves and renames, at Target.
+// Callers pass the findings they are about to act on, with their Library set
+// for relative patterns to apply. The folders of those findings are read
+// from the local filesystem.
+func (p ProtectedPaths) Check(findings []Finding) error {
+	return p.CheckFS(osFS{}, findings)
+}
+
+// CheckFS is Check reading the folders from fsys, for findings of a remote
+// library.
+func (p ProtectedPaths) CheckFS(fsys FS, findings []Finding) error {
+	var touched []ProtectedFinding
+	for _, f := range findings {
+		// Only these actions carry what is inside the folder along
+		container := f.Category == CategoryOrphanedFolder || f.Category == CategoryIncompatibleName
+		pattern, ok := p.protects(f.Library, f.Path, container)
+		if !ok && container {
+			pattern, ok = p.protectsBelow(fsys, f.Library, f.Path)
+		}
+		if !ok && f.Target != "" {
+			pattern, ok = p.protects(f.Library, f.Target, false)
+		}
+		if ok {
+			touched = append(touched, ProtectedFinding{Finding: f, Pattern: pattern})
+		}
+	}
+	if len(touched) > 0 {
+		return &ProtectedError{Findings: touched}
+	}
+	return nil
+}
+
+// protectPattern is the pattern p[index] split into elements. It is matched
+// against the elements of a path after the first skip, those of the library
+// root for a relative pattern.
+type protectPattern struct {
+	index int
+	elems []string
+	skip  int
+}
+
+// patterns splits p for a path of library with the elements elems. Relative
+// patterns are left out when the path is not in library.
+func (p ProtectedPaths) patterns(library string, elems []string) []protectPattern {
+	var root []string
+	if library != "" {
+		root = pathElems(library)
+	}
+	var patterns []protectPattern
+	for i, pattern := range p {
+		pp := protectPattern{index: i, elems: strings.Split(strings.TrimSuffix(filepath.ToSlash(pattern), "/"), "/")}
+		if relativePattern(pattern) {
+			if root == nil || len(elems) < len(root) || !slices.Equal(elems[:len(root)], root) {
+				continue
+			}
+			pp.skip = len(root)
+		}
+		patterns = append(patterns, pp)
+	}
+	return patterns
+}
+
+// relativePattern reports whether pattern is matched below the library root
+// rather than against whole paths. Patterns starting with "**" match
+// anywhere either way.
+func relativePattern(pattern string) bool {
+	return !strings.HasPrefix(pattern, "**") && !strings.HasPrefix(filepath.ToSlash(pattern), "/") &&
+		!filepath.IsAbs(pattern) && !strings.Contains(pattern, "://")
+}
+
+// pathElems returns the elements of name made absolute, with forward
+// slashes.
+func pathElems(name string) []string {
+	if !strings.Contains(name, "://") {
+		if abs, err := filepath.Abs(name); err == nil {
+			name = abs
+		}
+	}
+	return strings.Split(strings.TrimSuffix(filepath.ToSlash(filepath.Clean(name)), "/"), "/")
+}
+
+// protects returns the pattern protecting name, a path of library, if any.
+// With container, a pattern naming something below name protects it too.
+func (p ProtectedPaths) protects(library, name string, container bool) (string, bool) {
+	elems := pathElems(name)
+	for _, pattern := range p.patterns(library, elems) {
+		rel := elems[pattern.skip:]
+		for n := 1; n <= len(rel); n++ {
+			if matchElems(pattern.elems, rel[:n]) {
+				return p[pattern.index], true
+			}
+		}
+		if container && containsMatch(pattern.elems, rel) {
+			return p[pattern.index], true
+		}
+	}
+	return "", false
+}
+
+// protectsBelow returns the pattern protecting something at any depth in the
+// folder dir of library, if any. Only the folders a pattern could match below
+// are read; those that cannot be read are skipped.
+func (p ProtectedPaths) protectsBelow(fsys FS, library, dir string) (string, bool) {
+	elems := pathElems(dir)
+	return p.walkBelow(fsys, dir, elems, p.patterns(library, elems))
+}
+
+func (p ProtectedPaths) walkBelow(fsys FS, dir string, elems []string, patterns []protectPattern) (string, bool) {
+	below := false
+	for _, pattern := range patterns {
+		below = below || matchesBelow(pattern.elems, elems[pattern.skip:])
+	}
+	if !below {
+		return "", false
+	}
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		childElems := append(elems[:len(elems):len(elems)], entry.Name())
+		for _, pattern := range patterns {
+			if matchElems(pattern.elems, childElems[pattern.skip:]) {
+				return p[pattern.index], true
+			}
+		}
+		if !entry.IsDir() {
+			continue
+		}
+		child := filepath.Join(dir, entry.Name())
+		if strings.Contains(dir, "://") {
+			child = dir + "/" + entry.Name()
+		}
+		if pattern, ok := p.walkBelow(fsys, child, childElems, patterns); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// containsMatch reports whether the pattern names a path below elems before
+// reaching a "**", which could match below any folder.
+func containsMatch(pattern, elems []string) bool {
+	for len(elems) > 0 {
+		if len(pattern) == 0 || pattern[0] == "**" {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(pattern) > 0
+}
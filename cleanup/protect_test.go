@@ -0,0 +1,104 @@
+package cleanup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ============================================================================
+// Tests for ProtectedPaths
+// ============================================================================
+
+func TestProtectedPaths_Check(t *testing.T) {
+	protected := ProtectedPaths{"/lib/Criterion/**", "/lib/*/Keep", "**/Archive"}
+
+	tests := []struct {
+		name    string
+		finding Finding
+		pattern string
+	}{
+		{"below a protected folder", Finding{Category: CategoryOrphanedFolder, Path: "/lib/Criterion/Movie (2001)"}, "/lib/Criterion/**"},
+		{"the protected folder itself", Finding{Category: CategoryEmptyFolder, Path: "/lib/Studio/Keep"}, "/lib/*/Keep"},
+		{"inside the protected folder", Finding{Category: CategoryOrphanedFile, Path: "/lib/Studio/Keep/movie.nfo"}, "/lib/*/Keep"},
+		{"orphan holding a protected folder", Finding{Category: CategoryOrphanedFolder, Path: "/lib/Studio"}, "/lib/*/Keep"},
+		{"rename of a folder above", Finding{Category: CategoryIncompatibleName, Path: "/lib/Criterion", Target: "/lib/Criterion_"}, "/lib/Criterion/**"},
+		{"move into a protected folder", Finding{Category: CategoryStructureWarning, Path: "/lib/S/m.mkv", Target: "/lib/S/Archive"}, "**/Archive"},
+		{"empty folder above", Finding{Category: CategoryEmptyFolder, Path: "/lib/Studio"}, ""},
+		{"orphan beside", Finding{Category: CategoryOrphanedFolder, Path: "/lib/Studio/Movie (2001)"}, ""},
+		// "**" could match below any folder; only what it names is protected
+		{"orphan above a ** pattern", Finding{Category: CategoryOrphanedFolder, Path: "/lib/S/Movie (2001)"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := protected.Check([]Finding{tt.finding})
+			var touched *ProtectedError
+			if !errors.As(err, &touched) {
+				if tt.pattern != "" {
+					t.Fatalf("Expected %s protected by %q, got %v", tt.finding.Path, tt.pattern, err)
+				}
+				return
+			}
+			if tt.pattern == "" {
+				t.Fatalf("Expected %s not protected, got %v", tt.finding.Path, err)
+			}
+			if touched.Findings[0].Pattern != tt.pattern {
+				t.Errorf("Expected pattern %q, got %q", tt.pattern, touched.Findings[0].Pattern)
+			}
+		})
+	}
+}
+
+func TestProtectedPaths_CheckRelative(t *testing.T) {
+	protected := ProtectedPaths{"Criterion/**", "*/Keep"}
+
+	tests := []struct {
+		name    string
+		finding Finding
+		pattern string
+	}{
+		{"below the library root", Finding{Category: CategoryOrphanedFolder, Path: "/lib/Criterion/Movie (2001)", Library: "/lib"}, "Criterion/**"},
+		{"in another library", Finding{Category: CategoryOrphanedFolder, Path: "/other/Criterion/Movie (2001)", Library: "/other"}, "Criterion/**"},
+		{"orphan holding a protected folder", Finding{Category: CategoryOrphanedFolder, Path: "/lib/Studio", Library: "/lib"}, "*/Keep"},
+		{"deeper than the pattern", Finding{Category: CategoryOrphanedFolder, Path: "/lib/Movies/Criterion/Movie (2001)", Library: "/lib"}, ""},
+		{"library named like the pattern", Finding{Category: CategoryEmptyFolder, Path: "/Criterion/Movie (2001)", Library: "/Criterion"}, ""},
+		{"without a library", Finding{Category: CategoryOrphanedFolder, Path: "/lib/Criterion/Movie (2001)"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := protected.Check([]Finding{tt.finding})
+			var touched *ProtectedError
+			if !errors.As(err, &touched) {
+				if tt.pattern != "" {
+					t.Fatalf("Expected %s protected by %q, got %v", tt.finding.Path, tt.pattern, err)
+				}
+				return
+			}
+			if tt.pattern == "" {
+				t.Fatalf("Expected %s not protected, got %v", tt.finding.Path, err)
+			}
+			if touched.Findings[0].Pattern != tt.pattern {
+				t.Errorf("Expected pattern %q, got %q", tt.pattern, touched.Findings[0].Pattern)
+			}
+		})
+	}
+}
+
+func TestProtectedPaths_CheckNestedInOrphan(t *testing.T) {
+	dir := setupTestDir(t)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	orphan := filepath.Join(dir, "Studio", "Movie (2001)")
+	createFile(t, filepath.Join(orphan, "Extras", "Keep", "notes.txt"))
+	createFile(t, filepath.Join(dir, "Studio", "Other (2002)", "movie.nfo"))
+	protected := ProtectedPaths{"**/Keep"}
+
+	err := protected.Check([]Finding{{Category: CategoryOrphanedFolder, Path: orphan}})
+	var touched *ProtectedError
+	if !errors.As(err, &touched) || touched.Findings[0].Pattern != "**/Keep" {
+		t.Fatalf("Expected %s protected by %q, got %v", orphan, "**/Keep", err)
+	}
+	if err := protected.Check([]Finding{{Category: CategoryOrphanedFolder, Path: filepath.Join(dir, "Studio", "Other (2002)")}}); err != nil {
+		t.Errorf("Expected the orphan without a Keep folder not protected, got %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package cleanup
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// QuarantineManifest is the name of the manifest kept at the root of a
+// quarantine directory.
+const QuarantineManifest = "manifest.jsonl"
+
+// QuarantineEntry records one quarantined item in the manifest.
+type QuarantineEntry struct {
+	Original    string    `json:"original"`    // absolute path the item was moved from
+	Quarantined string    `json:"quarantined"` // absolute path it was moved to
+	Category    Category  `json:"category"`
+	Library     string    `json:"library,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// QuarantineStrategy moves items into Dir like TrashDirStrategy, and records
+// each move in Dir's manifest so RestoreQuarantine can put items back.
+// Items are purged once they are older than the retention, see
+// PurgeQuarantine. Use NewQuarantine; it is safe for concurrent use.
+type QuarantineStrategy struct {
+	Dir string
+
+	mu sync.Mutex // serializes manifest updates
+}
+
+// NewQuarantine returns the strategy quarantining items into dir.
+func NewQuarantine(dir string) *QuarantineStrategy {
+	return &QuarantineStrategy{Dir: dir}
+}
+
+func (*QuarantineStrategy) Name() string { return "quarantine" }
+
+func (q *QuarantineStrategy) Delete(f Finding) error {
+	_, err := q.Move(f)
+	return err
+}
+
+func (q *QuarantineStrategy) Move(f Finding) (Moved, error) {
+	original, err := filepath.Abs(f.Path)
+	if err != nil {
+		return Moved{}, err
+	}
+	dir, err := filepath.Abs(q.Dir)
+	if err != nil {
+		return Moved{}, err
+	}
+	rel, err := filepath.Rel(f.Library, f.Path)
+	if err != nil || f.Library == "" || !IsWithin(f.Library, f.Path) {
+		// Not under a known library: keep just the name
+		rel = filepath.Base(f.Path)
+	}
+	target := uniquePath(filepath.Join(dir, filepath.Base(f.Library), rel))
+	if err := os.MkdirAll(longPath(filepath.Dir(target)), 0755); err != nil {
+		return Moved{}, err
+	}
+	if err := movePath(f.Path, target); err != nil {
+		return Moved{}, err
+	}
+
+	entry := QuarantineEntry{Original: original, Quarantined: target, Category: f.Category, Library: f.Library, Time: time.Now().UTC()}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := appendManifest(dir, entry); err != nil {
+		// An item missing from the manifest could never be restored
+		if moveErr := movePath(target, f.Path); moveErr != nil {
+			return Moved{}, fmt.Errorf("%w (and the item stays unrecorded in %s: %v)", err, target, moveErr)
+		}
+		return Moved{}, err
+	}
+	return Moved{To: target, Record: dir}, nil
+}
+
+// CheckLibraries refuses a quarantine directory inside one of libraryPaths,
+// like TrashDirStrategy.CheckLibraries.
+func (q *QuarantineStrategy) CheckLibraries(libraryPaths []string) error {
+	return TrashDirStrategy{Dir: q.Dir}.CheckLibraries(libraryPaths)
+}
+
+// ReadQuarantine returns the manifest of the quarantine directory dir, oldest
+// first. A directory without manifest holds nothing.
+func ReadQuarantine(dir string) ([]QuarantineEntry, error) {
+	file, err := os.Open(filepath.Join(dir, QuarantineManifest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []QuarantineEntry
+	lines := bufio.NewScanner(file)
+	lines.Buffer(nil, 1<<20)
+	for n := 1; lines.Scan(); n++ {
+		if len(lines.Bytes()) == 0 {
+			continue
+		}
+		var entry QuarantineEntry
+		if err := json.Unmarshal(lines.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", QuarantineManifest, n, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, lines.Err()
+}
+
+// RestoreQuarantine moves the items of the quarantine directory dir that
+// match back to where they were, and drops them from the manifest. An item
+// is not restored over something that took its place since; that is
+// reported as a *RestoreError, as are items that cannot be moved.
+func RestoreQuarantine(dir string, match func(QuarantineEntry) bool) (restored []QuarantineEntry, err error) {
+	var errs []error
+	err = updateManifest(dir, func(entry QuarantineEntry) bool {
+		if !match(entry) {
+			return true
+		}
+		if _, err := os.Lstat(longPath(entry.Original)); err == nil {
+			errs = append(errs, &RestoreError{Path: entry.Original, Cause: fs.ErrExist})
+			return true
+		}
+		if err := os.MkdirAll(longPath(filepath.Dir(entry.Original)), 0755); err != nil {
+			errs = append(errs, &RestoreError{Path: entry.Original, Cause: err})
+			return true
+		}
+		if err := movePath(entry.Quarantined, entry.Original); err != nil {
+			errs = append(errs, &RestoreError{Path: entry.Original, Cause: err})
+			return true
+		}
+		restored = append(restored, entry)
+		return false
+	})
+	if err != nil {
+		return restored, err
+	}
+	return restored, errors.Join(errs...)
+}
+
+// PurgeQuarantine removes the items of the quarantine directory dir that
+// were quarantined before cutoff, for good, and drops them from the
+// manifest. Items that cannot be removed stay listed.
+func PurgeQuarantine(dir string, cutoff time.Time) (purged []QuarantineEntry, err error) {
+	var errs []error
+	err = updateManifest(dir, func(entry QuarantineEntry) bool {
+		if !entry.Time.Before(cutoff) {
+			return true
+		}
+		if err := os.RemoveAll(longPath(entry.Quarantined)); err != nil {
+			errs = append(errs, &DeletionError{Path: entry.Quarantined, Cause: err})
+			return true
+		}
+		purged = append(purged, entry)
+		return false
+	})
+	if err != nil {
+		return purged, err
+	}
+	return purged, errors.Join(errs...)
+}
+
+// updateManifest rewrites the manifest of dir with the entries keep returns
+// true for. The new manifest replaces the old one atomically.
+func updateManifest(dir string, keep func(QuarantineEntry) bool) error {
+	entries, err := ReadQuarantine(dir)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	var kept []QuarantineEntry
+	for _, entry := range entries {
+		if keep(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == len(entries) {
+		return nil
+	}
+
+	path := filepath.Join(dir, QuarantineManifest)
+	tmp, err := os.CreateTemp(dir, QuarantineManifest+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	enc := json.NewEncoder(tmp)
+	for _, entry := range kept {
+		if err := enc.Encode(entry); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func appendManifest(dir string, entry QuarantineEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, QuarantineManifest), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,158 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// quarantined deletes a fresh deletable result into a quarantine directory.
+func quarantined(t *testing.T, tempDir string) (string, []string) {
+	result, paths := deletableResult(t, filepath.Join(tempDir, "Library"))
+	dir := filepath.Join(tempDir, "Quarantine")
+
+	report, err := NewDeleter(NewQuarantine(dir), WithDeleteWorkers(4)).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(report.Failures) > 0 {
+		t.Fatalf("Unexpected failures %v", report.Failures)
+	}
+	return dir, paths
+}
+
+// ============================================================================
+// Tests for QuarantineStrategy
+// ============================================================================
+
+func TestQuarantine_MovesAndRecords(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	dir, paths := quarantined(t, tempDir)
+
+	entries, err := ReadQuarantine(dir)
+	if err != nil {
+		t.Fatalf("ReadQuarantine failed: %v", err)
+	}
+	if len(entries) != len(paths) {
+		t.Fatalf("Expected %d entries, got %d", len(paths), len(entries))
+	}
+	for _, entry := range entries {
+		if _, err := os.Lstat(entry.Original); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected %s to be moved away", entry.Original)
+		}
+		if _, err := os.Lstat(entry.Quarantined); err != nil {
+			t.Errorf("Expected %s in quarantine, got %v", entry.Quarantined, err)
+		}
+		if !filepath.IsAbs(entry.Original) || !filepath.IsAbs(entry.Quarantined) {
+			t.Errorf("Expected absolute paths, got %+v", entry)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Library", "Studio", "Orphaned", "movie.nfo")); err != nil {
+		t.Errorf("Expected the library layout to be kept in quarantine, got %v", err)
+	}
+}
+
+func TestQuarantine_RestoreAll(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	dir, paths := quarantined(t, tempDir)
+
+	restored, err := RestoreQuarantine(dir, func(QuarantineEntry) bool { return true })
+	if err != nil {
+		t.Fatalf("RestoreQuarantine failed: %v", err)
+	}
+	if len(restored) != len(paths) {
+		t.Errorf("Expected %d restored items, got %d", len(paths), len(restored))
+	}
+	for _, path := range paths {
+		if _, err := os.Lstat(path); err != nil {
+			t.Errorf("Expected %s to be restored, got %v", path, err)
+		}
+	}
+	if entries, _ := ReadQuarantine(dir); len(entries) != 0 {
+		t.Errorf("Expected an empty manifest, got %v", entries)
+	}
+}
+
+func TestQuarantine_RestoreSelectedKeepsOthers(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	dir, paths := quarantined(t, tempDir)
+	orphanedFolder, _ := filepath.Abs(paths[0])
+
+	restored, err := RestoreQuarantine(dir, func(e QuarantineEntry) bool { return e.Original == orphanedFolder })
+	if err != nil {
+		t.Fatalf("RestoreQuarantine failed: %v", err)
+	}
+	if len(restored) != 1 {
+		t.Fatalf("Expected 1 restored item, got %d", len(restored))
+	}
+	if _, err := os.Stat(filepath.Join(paths[0], "movie.nfo")); err != nil {
+		t.Errorf("Expected the folder to be restored with its contents, got %v", err)
+	}
+	if entries, _ := ReadQuarantine(dir); len(entries) != len(paths)-1 {
+		t.Errorf("Expected %d entries left, got %d", len(paths)-1, len(entries))
+	}
+}
+
+func TestQuarantine_RestoreRefusesOverwrite(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	dir, paths := quarantined(t, tempDir)
+	createFile(t, paths[1])
+
+	restored, err := RestoreQuarantine(dir, func(QuarantineEntry) bool { return true })
+	var restoreErr *RestoreError
+	if !errors.As(err, &restoreErr) || !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("Expected a RestoreError for the replaced file, got %v", err)
+	}
+	if len(restored) != len(paths)-1 {
+		t.Errorf("Expected the %d other items to be restored, got %d", len(paths)-1, len(restored))
+	}
+	if entries, _ := ReadQuarantine(dir); len(entries) != 1 {
+		t.Errorf("Expected the unrestored item to stay listed, got %v", entries)
+	}
+}
+
+func TestQuarantine_PurgeOlderThanCutoff(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	dir, paths := quarantined(t, tempDir)
+
+	purged, err := PurgeQuarantine(dir, time.Now().Add(-time.Hour))
+	if err != nil || len(purged) != 0 {
+		t.Fatalf("Expected nothing purged before the cutoff, got %v (%v)", purged, err)
+	}
+	purged, err = PurgeQuarantine(dir, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeQuarantine failed: %v", err)
+	}
+	if len(purged) != len(paths) {
+		t.Errorf("Expected %d purged items, got %d", len(paths), len(purged))
+	}
+	for _, entry := range purged {
+		if _, err := os.Lstat(entry.Quarantined); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected %s to be removed", entry.Quarantined)
+		}
+	}
+	if entries, _ := ReadQuarantine(dir); len(entries) != 0 {
+		t.Errorf("Expected an empty manifest, got %v", entries)
+	}
+}
+
+func TestReadQuarantine_MissingManifest(t *testing.T) {
+	entries, err := ReadQuarantine(t.TempDir())
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries and no error, got %v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,196 @@
+package cleanup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RcloneTarget is a library on an rclone remote, given as
+// rclone:remote:path, e.g. rclone:gdrive:Media/Movies. Any remote rclone is
+// configured for works: Google Drive, Dropbox, WebDAV, OneDrive, ...
+type RcloneTarget struct {
+	Remote string // name of the remote in rclone.conf
+	Path   string // path on the remote, as rclone takes it
+}
+
+// IsRcloneURL reports whether s names a library on an rclone remote.
+func IsRcloneURL(s string) bool {
+	return strings.HasPrefix(s, "rclone:")
+}
+
+// ParseRcloneURL parses an rclone:remote:path library.
+func ParseRcloneURL(s string) (RcloneTarget, error) {
+	remote, p, ok := strings.Cut(strings.TrimPrefix(s, "rclone:"), ":")
+	if !IsRcloneURL(s) || !ok || remote == "" || strings.Contains(remote, "/") {
+		return RcloneTarget{}, fmt.Errorf("invalid library %q (expected rclone:remote:path)", s)
+	}
+	if p != "" {
+		p = path.Clean(p)
+	}
+	return RcloneTarget{Remote: remote, Path: p}, nil
+}
+
+// String returns t in its rclone: form.
+func (t RcloneTarget) String() string {
+	return "rclone:" + t.Remote + ":" + t.Path
+}
+
+// Root returns the path to scan t at with an RcloneFS, /remote/path.
+// Findings are reported with paths of that form.
+func (t RcloneTarget) Root() string {
+	return path.Join("/", t.Remote, t.Path)
+}
+
+// spec returns the rclone remote:path of name, a path below Root.
+func (t RcloneTarget) spec(name string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(remotePath(name), t.Root()), "/")
+	p := t.Path
+	if rel != "" {
+		p = path.Join(p, rel)
+	}
+	return t.Remote + ":" + p
+}
+
+// rclone runs the rclone command with args and returns its standard output.
+// An rclone "not found" exit status is returned as fs.ErrNotExist.
+func rclone(command string, args ...string) ([]byte, error) {
+	if command == "" {
+		command = "rclone"
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(command, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err == nil {
+		return out, nil
+	}
+	var exitErr *exec.ExitError
+	// rclone exits with 3 for a missing directory and 4 for a missing file
+	if errors.As(err, &exitErr) && (exitErr.ExitCode() == 3 || exitErr.ExitCode() == 4) {
+		return nil, fs.ErrNotExist
+	}
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if msg := lines[len(lines)-1]; msg != "" {
+		return nil, fmt.Errorf("rclone %s: %w: %s", args[0], err, msg)
+	}
+	return nil, fmt.Errorf("rclone %s: %w", args[0], err)
+}
+
+// RcloneFS is a read-only FS over a library on an rclone remote, for
+// WithFS. It runs the rclone command, which must already be configured for
+// the remote. The whole library is listed with one "rclone lsjson" on first
+// use; files are fetched with "rclone cat" when read. Paths have the form
+// /remote/path, so the library root to scan is Target.Root().
+type RcloneFS struct {
+	Target RcloneTarget
+	// Command is the rclone binary; "rclone" looked up in PATH if empty.
+	Command string
+
+	once sync.Once
+	err  error
+	tree *remoteTree
+}
+
+// rcloneItem is one entry of "rclone lsjson" output.
+type rcloneItem struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// load lists the library.
+func (r *RcloneFS) load() error {
+	r.once.Do(func() {
+		out, err := rclone(r.Command, "lsjson", "--recursive", "--no-mimetype", "--fast-list", r.Target.spec(r.Target.Root()))
+		if err != nil {
+			r.err = fmt.Errorf("%s: %w", r.Target, err)
+			return
+		}
+		var items []rcloneItem
+		if err := json.Unmarshal(out, &items); err != nil {
+			r.err = fmt.Errorf("%s: %w", r.Target, err)
+			return
+		}
+		root := r.Target.Root()
+		r.tree = newRemoteTree()
+		r.tree.add(root, &remoteEntry{mode: fs.ModeDir | 0755}, root)
+		for _, item := range items {
+			p := path.Join(root, item.Path)
+			entry := &remoteEntry{size: item.Size, mode: 0644, modTime: item.ModTime}
+			if item.IsDir {
+				entry.mode = fs.ModeDir | 0755
+				entry.size = 0
+			}
+			r.tree.addFolders(path.Dir(p), root)
+			r.tree.add(p, entry, root)
+		}
+	})
+	return r.err
+}
+
+func (r *RcloneFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r.tree.readDir(name)
+}
+
+func (r *RcloneFS) Stat(name string) (fs.FileInfo, error) {
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r.tree.lstat(name)
+}
+
+func (r *RcloneFS) ReadFile(name string) ([]byte, error) {
+	if _, err := r.Stat(name); err != nil {
+		return nil, err
+	}
+	data, err := rclone(r.Command, "cat", r.Target.spec(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// RcloneStrategy deletes findings of an RcloneFS scan from the remote:
+// orphaned folders with "rclone purge", files with "rclone deletefile" and
+// empty folders with "rclone rmdir", which refuses if anything appeared in
+// them since the scan.
+type RcloneStrategy struct {
+	Target  RcloneTarget
+	Command string // "rclone" if empty
+}
+
+func (RcloneStrategy) Name() string { return "rclone" }
+
+func (s RcloneStrategy) Delete(f Finding) error {
+	command := "deletefile"
+	switch f.Category {
+	case CategoryOrphanedFolder:
+		command = "purge"
+	case CategoryEmptyFolder:
+		command = "rmdir"
+	}
+	_, err := rclone(s.Command, command, s.Target.spec(f.Path))
+	return err
+}
+
+// Exists reports whether path is still on the remote, so the Deleter skips
+// items deleted along with a parent.
+func (s RcloneStrategy) Exists(path string) (bool, error) {
+	_, err := rclone(s.Command, "lsjson", "--stat", "--no-mimetype", s.Target.spec(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,154 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeRclone writes a shell script standing in for rclone: "lsjson" prints
+// listing, "lsjson --stat" fails like rclone does for a missing item unless
+// present is set, and every call is appended to the returned log.
+func fakeRclone(t *testing.T, listing string, present bool) (command, log string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	log = filepath.Join(dir, "calls.log")
+	if err := os.WriteFile(filepath.Join(dir, "listing.json"), []byte(listing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stat := "exit 3"
+	if present {
+		stat = "echo '{}'"
+	}
+	script := "#!/bin/sh\necho \"$@\" >> '" + log + "'\n" +
+		"case \"$1 $2\" in\n" +
+		"'lsjson --stat') " + stat + " ;;\n" +
+		"lsjson*) cat '" + filepath.Join(dir, "listing.json") + "' ;;\n" +
+		"cat*) echo '<movie><year>2001</year></movie>' ;;\n" +
+		"esac\n"
+	command = filepath.Join(dir, "rclone")
+	if err := os.WriteFile(command, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return command, log
+}
+
+const rcloneListing = `[
+{"Path":"Studio","Name":"Studio","Size":-1,"ModTime":"2020-01-02T03:04:05Z","IsDir":true},
+{"Path":"Studio/Movie (2001)/movie.mkv","Name":"movie.mkv","Size":5,"ModTime":"2020-01-02T03:04:05Z","IsDir":false},
+{"Path":"Studio/Orphan (2002)/movie.nfo","Name":"movie.nfo","Size":8,"ModTime":"2020-01-02T03:04:05Z","IsDir":false},
+{"Path":"Studio/Empty (2003)","Name":"Empty (2003)","Size":-1,"ModTime":"2020-01-02T03:04:05Z","IsDir":true},
+{"Path":"Studio/stray.nfo","Name":"stray.nfo","Size":8,"ModTime":"2020-01-02T03:04:05Z","IsDir":false}
+]`
+
+// ============================================================================
+// Tests for ParseRcloneURL
+// ============================================================================
+
+func TestParseRcloneURL(t *testing.T) {
+	tests := map[string]RcloneTarget{
+		"rclone:gdrive:Media/Movies/": {Remote: "gdrive", Path: "Media/Movies"},
+		"rclone:nas:/data/Movies":     {Remote: "nas", Path: "/data/Movies"},
+		"rclone:dropbox:":             {Remote: "dropbox"},
+	}
+	for s, expected := range tests {
+		target, err := ParseRcloneURL(s)
+		if err != nil || target != expected {
+			t.Errorf("%s: expected %+v, got %+v (%v)", s, expected, target, err)
+		}
+	}
+	if root := (RcloneTarget{Remote: "nas", Path: "/data/Movies"}).Root(); root != "/nas/data/Movies" {
+		t.Errorf("Expected /nas/data/Movies, got %s", root)
+	}
+	for _, s := range []string{"rclone:gdrive", "rclone::Movies", "/mnt/rclone:x"} {
+		if _, err := ParseRcloneURL(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+// ============================================================================
+// Tests for RcloneFS and RcloneStrategy
+// ============================================================================
+
+func TestRcloneFS_Scan(t *testing.T) {
+	command, log := fakeRclone(t, rcloneListing, false)
+	target := RcloneTarget{Remote: "gdrive", Path: "Media/Movies"}
+
+	result := &CleanupResult{}
+	scanner := NewScanner(WithFS(&RcloneFS{Target: target, Command: command}), WithNFOYearCheck(true))
+	if err := scanner.Scan(context.Background(), target.Root(), result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	expected := map[Category]string{
+		CategoryOrphanedFolder: "/gdrive/Media/Movies/Studio/Orphan (2002)",
+		CategoryEmptyFolder:    "/gdrive/Media/Movies/Studio/Empty (2003)",
+		CategoryOrphanedFile:   "/gdrive/Media/Movies/Studio/stray.nfo",
+	}
+	if len(result.Findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %v", len(expected), result.Findings)
+	}
+	for _, f := range result.Findings {
+		if expected[f.Category] != f.Path {
+			t.Errorf("Expected %s for %s, got %s", expected[f.Category], f.Category, f.Path)
+		}
+	}
+
+	calls, _ := os.ReadFile(log)
+	if !strings.HasPrefix(string(calls), "lsjson --recursive --no-mimetype --fast-list gdrive:Media/Movies\n") {
+		t.Errorf("Expected one recursive listing of the library first, got %q", calls)
+	}
+}
+
+func TestRcloneFS_MissingLibrary(t *testing.T) {
+	command, _ := fakeRclone(t, "", false)
+	// The fake fails every listing of a missing item alike
+	script, _ := os.ReadFile(command)
+	os.WriteFile(command, []byte(strings.Replace(string(script), "lsjson*) cat", "lsjson*) exit 3; cat", 1)), 0755)
+
+	fsys := &RcloneFS{Target: RcloneTarget{Remote: "gdrive", Path: "Films"}, Command: command}
+	if _, err := fsys.Stat("/gdrive/Films"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestRcloneStrategy_Delete(t *testing.T) {
+	command, log := fakeRclone(t, rcloneListing, true)
+	target := RcloneTarget{Remote: "gdrive", Path: "Media/Movies"}
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: "/gdrive/Media/Movies/Studio/Orphan (2002)"})
+	result.add(Finding{Category: CategoryEmptyFolder, Path: "/gdrive/Media/Movies/Studio/Empty (2003)"})
+	result.add(Finding{Category: CategoryOrphanedFile, Path: "/gdrive/Media/Movies/Studio/stray.nfo"})
+
+	report, err := NewDeleter(RcloneStrategy{Target: target, Command: command}).Delete(context.Background(), result)
+	if err != nil || len(report.Failures) != 0 {
+		t.Fatalf("Delete returned %v, %v", err, report.Failures)
+	}
+	calls, _ := os.ReadFile(log)
+	for _, call := range []string{
+		"purge gdrive:Media/Movies/Studio/Orphan (2002)",
+		"rmdir gdrive:Media/Movies/Studio/Empty (2003)",
+		"deletefile gdrive:Media/Movies/Studio/stray.nfo",
+	} {
+		if !strings.Contains(string(calls), call+"\n") {
+			t.Errorf("Expected %q in %q", call, calls)
+		}
+	}
+}
+
+func TestRcloneStrategy_Exists(t *testing.T) {
+	command, _ := fakeRclone(t, rcloneListing, false)
+	exists, err := RcloneStrategy{Target: RcloneTarget{Remote: "gdrive"}, Command: command}.Exists("/gdrive/Studio/stray.nfo")
+	if exists || err != nil {
+		t.Errorf("Expected an item rclone cannot find not to exist, got %v, %v", exists, err)
+	}
+}
@@ -0,0 +1,53 @@
+package cleanup
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Recycle bins NAS systems keep in every shared folder: Synology (#recycle),
+// QNAP (@Recycle) and desktop environments on Linux (.Trash-<uid>)
+var recycleBinNames = map[string]bool{
+	"#recycle": true,
+	"@recycle": true,
+}
+
+// isRecycleBin reports whether the folder name is a recycle bin managed by
+// the NAS, which the scan never enters.
+func isRecycleBin(name string) bool {
+	lower := strings.ToLower(name)
+	return recycleBinNames[lower] || strings.HasPrefix(lower, ".trash-")
+}
+
+// skipRecycleBin reports the recycle bin at path as skipped and, with
+// WithRecycleBinUsage, the space it holds.
+func (r *scanRun) skipRecycleBin(path string) {
+	r.report(FolderSkipped{Library: r.library, Path: path, Reason: SkippedRecycleBin})
+	if !r.recycleBinUsage {
+		return
+	}
+	entries, err := r.fsys.ReadDir(path)
+	if err != nil {
+		r.fail(&ErrUnreadableDir{Path: path, Err: err})
+		return
+	}
+	usage := r.treeUsage(path, entries)
+	r.emit(Finding{Category: CategoryRecycleBin, Path: path, Usage: usage,
+		Message: fmt.Sprintf("Holds %s", FormatBytes(usage.Bytes))})
+}
+
+// withoutRecycleBins skips the recycle bins among the entries of dirPath,
+// returning the other entries and whether any were skipped.
+func (r *scanRun) withoutRecycleBins(dirPath string, entries []fs.DirEntry) ([]fs.DirEntry, bool) {
+	var kept []fs.DirEntry
+	for _, entry := range entries {
+		if entry.IsDir() && isRecycleBin(entry.Name()) {
+			r.skipRecycleBin(filepath.Join(dirPath, entry.Name()))
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept, len(kept) < len(entries)
+}
@@ -0,0 +1,88 @@
+package cleanup
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for recycle bins
+// ============================================================================
+
+// recycleLibrary has recycle bins at the library, studio and title levels,
+// each holding what would otherwise be findings.
+func recycleLibrary() fstest.MapFS {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Movie (2001)").Video("movie.mkv").
+		Title("Deleted (2002)").Metadata("movie.nfo").
+		MapFS()
+	fsys["#recycle/Studio/Old (1999)/movie.nfo"] = &fstest.MapFile{Data: []byte("nfo")}
+	fsys["Studio/@Recycle/Gone (2000)/poster.jpg"] = &fstest.MapFile{Data: []byte("jpg")}
+	fsys["Studio/Deleted (2002)/.Trash-1000/files/movie.mkv"] = &fstest.MapFile{Data: []byte("mkv")}
+	fsys["Studio/Movie (2001)/#Recycle"] = &fstest.MapFile{Mode: fs.ModeDir | 0755}
+	return fsys
+}
+
+func TestScan_SkipsRecycleBins(t *testing.T) {
+	var skipped []string
+	result := &CleanupResult{}
+	scanner := NewScanner(WithFS(IOFS(recycleLibrary())), WithWorkers(1), WithProgress(func(ev ProgressEvent) {
+		if ev, ok := ev.(FolderSkipped); ok && ev.Reason == SkippedRecycleBin {
+			skipped = append(skipped, ev.Path)
+		}
+	}))
+	if err := scanner.Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(skipped) != 4 {
+		t.Errorf("Expected 4 recycle bins skipped, got %v", skipped)
+	}
+	// The bin in Deleted (2002) would be deleted along with the folder
+	if len(result.Findings) != 0 {
+		t.Errorf("Expected no findings, got %v", result.Findings)
+	}
+}
+
+func TestScan_RecycleBinUsage(t *testing.T) {
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(recycleLibrary())), WithRecycleBinUsage(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	bins := result.ByCategory(CategoryRecycleBin)
+	if len(bins) != 4 {
+		t.Fatalf("Expected 4 recycle bins reported, got %v", bins)
+	}
+	for _, f := range bins {
+		if f.Path == filepath.Join("Studio", "@Recycle") && (f.Bytes != 3 || f.Message != "Holds 3 B") {
+			t.Errorf("Expected the @Recycle bin to hold 3 bytes, got %+v", f)
+		}
+	}
+	if usage := result.Usage(); usage.Bytes != 0 {
+		t.Errorf("Expected recycle bins to reclaim nothing, got %+v", usage)
+	}
+}
+
+func TestIsRecycleBin(t *testing.T) {
+	tests := map[string]bool{
+		"#recycle":    true,
+		"@Recycle":    true,
+		".Trash-1000": true,
+		".trash-0":    true,
+		"Recycle":     false,
+		".Trash":      false,
+		"#recycled":   false,
+	}
+	for name, expected := range tests {
+		if got := isRecycleBin(name); got != expected {
+			t.Errorf("isRecycleBin(%q): expected %v, got %v", name, expected, got)
+		}
+	}
+}
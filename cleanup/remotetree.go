@@ -0,0 +1,95 @@
+package cleanup
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// remoteTree is a listing of a remote library taken in one go, which
+// SSHFS, S3FS and RcloneFS answer ReadDir and Stat from. Paths are
+// slash-separated and absolute.
+type remoteTree struct {
+	entries  map[string]*remoteEntry   // by path
+	children map[string][]*remoteEntry // by folder path
+}
+
+func newRemoteTree() *remoteTree {
+	return &remoteTree{entries: map[string]*remoteEntry{}, children: map[string][]*remoteEntry{}}
+}
+
+// remoteEntry is a file or folder of a remoteTree. It serves as both the
+// fs.DirEntry and the fs.FileInfo of the entry.
+type remoteEntry struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	link    string // symlink target
+	target  byte   // find %Y type of the symlink target, 'N' if missing
+}
+
+func (e *remoteEntry) Name() string               { return e.name }
+func (e *remoteEntry) Size() int64                { return e.size }
+func (e *remoteEntry) Mode() fs.FileMode          { return e.mode }
+func (e *remoteEntry) ModTime() time.Time         { return e.modTime }
+func (e *remoteEntry) IsDir() bool                { return e.mode.IsDir() }
+func (e *remoteEntry) Sys() any                   { return nil }
+func (e *remoteEntry) Type() fs.FileMode          { return e.mode.Type() }
+func (e *remoteEntry) Info() (fs.FileInfo, error) { return e, nil }
+
+// add records entry at p, replacing anything recorded there before, and
+// lists it in its parent folder unless p is root.
+func (t *remoteTree) add(p string, entry *remoteEntry, root string) {
+	entry.name = path.Base(p)
+	if old := t.entries[p]; old != nil {
+		*old = *entry
+		return
+	}
+	t.entries[p] = entry
+	if p != root {
+		t.children[path.Dir(p)] = append(t.children[path.Dir(p)], entry)
+	}
+}
+
+// addFolders adds the folder dir, and those between it and root, unless
+// they were added before. Stores without real folders only imply them by
+// the paths of the files below.
+func (t *remoteTree) addFolders(dir, root string) {
+	if t.entries[dir] != nil || !strings.HasPrefix(dir, root) {
+		return
+	}
+	t.addFolders(path.Dir(dir), root)
+	t.add(dir, &remoteEntry{mode: fs.ModeDir | 0755}, root)
+}
+
+// remotePath converts a path given to the scanner back to the remote form.
+func remotePath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+func (t *remoteTree) lstat(name string) (*remoteEntry, error) {
+	entry := t.entries[remotePath(name)]
+	if entry == nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return entry, nil
+}
+
+func (t *remoteTree) readDir(name string) ([]fs.DirEntry, error) {
+	entry, err := t.lstat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNotADirectory}
+	}
+	children := t.children[remotePath(name)]
+	entries := make([]fs.DirEntry, len(children))
+	for i, child := range children {
+		entries[i] = child
+	}
+	return entries, nil
+}
@@ -0,0 +1,160 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// trailingPart matches multi-part numbering at the end of a file name, so
+// "Movie (2001) - cd2" and "Movie (2001) - cd1" share a title folder.
+var trailingPart = regexp.MustCompile(`(?i)[\s._-]*(cd|disc|disk|dvd|part|pt)[\s._-]*\d+$`)
+
+// seasonNumber matches the season of episode numbering such as S01E02 or 1x02.
+var seasonNumber = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:s(\d{1,4})e\d{1,4}|(\d{1,2})x\d{2,4})`)
+
+// titleFolderName returns the title folder a movie file belongs in: its name
+// without extension and part number.
+func titleFolderName(name string) string {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.TrimSpace(trailingPart.ReplaceAllString(stem, ""))
+}
+
+// seasonFolderName returns the season folder an episode belongs in, e.g.
+// "Season 01" for "Show S01E02.mkv", or "" if the name has no numbering.
+func seasonFolderName(name string) string {
+	m := seasonNumber.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	season, _ := strconv.Atoi(m[1] + m[2])
+	return fmt.Sprintf("Season %02d", season)
+}
+
+// leafTarget returns the folder the video name, found directly in dirPath at
+// level, belongs in, or "" if that cannot be told. Only the level right
+// above the leaf has an answer, the library itself in a flat layout; a
+// video at library level could belong to any studio otherwise.
+func (r *scanRun) leafTarget(dirPath, level, name string) string {
+	levels := r.layout.Levels
+	parent := "library"
+	if len(levels) > 1 {
+		parent = levels[len(levels)-2]
+	}
+	if level != parent {
+		return ""
+	}
+	folder := titleFolderName(name)
+	if r.layout.Episodes {
+		folder = seasonFolderName(name)
+	}
+	if folder == "" {
+		return ""
+	}
+	return filepath.Join(dirPath, folder)
+}
+
+// StructureFixReport summarizes a StructureFixer run.
+type StructureFixReport struct {
+	Moved    []Finding
+	Skipped  []Finding // gone since the scan
+	Failures []error   // one *MoveError per item, or *PanicError
+}
+
+// StructureFixer moves misplaced videos and their metadata into the folder
+// they belong in. It mirrors PermissionFixer: nothing is changed until Fix
+// is called, which callers only do in execute mode.
+type StructureFixer struct {
+	progress ProgressFunc
+}
+
+// StructureFixerOption configures a StructureFixer.
+type StructureFixerOption func(*StructureFixer)
+
+// WithMoveProgress calls fn with a FileMoved event after every item.
+func WithMoveProgress(fn ProgressFunc) StructureFixerOption {
+	return func(f *StructureFixer) {
+		f.progress = fn
+	}
+}
+
+// NewStructureFixer returns a StructureFixer.
+func NewStructureFixer(opts ...StructureFixerOption) *StructureFixer {
+	f := &StructureFixer{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Movable returns the structure warnings of result that have a target
+// folder, in the order Fix moves them.
+func Movable(result *CleanupResult) []Finding {
+	var items []Finding
+	for _, f := range result.ByCategory(CategoryStructureWarning) {
+		if f.Target != "" {
+			items = append(items, f)
+		}
+	}
+	return items
+}
+
+// Fix moves every Movable finding of result into its target folder,
+// creating the folder if needed. Files already present in the target are
+// never overwritten. Items are moved one at a time, since videos and their
+// metadata share target folders. Like PermissionFixer.Fix, it stops before
+// the next item once ctx is done and only returns an error in that case.
+func (f *StructureFixer) Fix(ctx context.Context, result *CleanupResult) (*StructureFixReport, error) {
+	report := &StructureFixReport{}
+	items := Movable(result)
+
+	var mu sync.Mutex
+	done := 0
+	err := RunPool(ctx, 1, items, func(finding Finding) error {
+		if _, err := os.Lstat(longPath(finding.Path)); errors.Is(err, fs.ErrNotExist) {
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			report.Skipped = append(report.Skipped, finding)
+			return nil
+		}
+
+		err := moveInto(finding.Path, finding.Target)
+
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		var failure error
+		if err != nil {
+			failure = &MoveError{Path: finding.Path, Target: finding.Target, Cause: err}
+			report.Failures = append(report.Failures, failure)
+		} else {
+			report.Moved = append(report.Moved, finding)
+		}
+		if f.progress != nil {
+			f.progress(FileMoved{Finding: finding, Err: failure, Done: done, Total: len(items)})
+		}
+		return nil
+	})
+	report.Failures = append(report.Failures, SplitErrors(err)...)
+	return report, ctx.Err()
+}
+
+// moveInto moves path into the folder dir under the same name.
+func moveInto(path, dir string) error {
+	dst := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Lstat(longPath(dst)); err == nil {
+		return fs.ErrExist
+	}
+	if err := os.MkdirAll(longPath(dir), 0755); err != nil {
+		return err
+	}
+	return movePath(path, dst)
+}
@@ -0,0 +1,203 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for misplaced file targets
+// ============================================================================
+
+func TestScan_MisplacedFileTargets(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Kept (2001)").Video("kept.mkv").
+		Up().Video("Loose (2002).mkv").Metadata("Loose (2002).nfo", "Loose (2002)-poster.jpg").
+		Root().Video("stray.mkv").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := filepath.Join("Studio", "Loose (2002)")
+	targets := map[string]string{}
+	for _, f := range result.ByCategory(CategoryStructureWarning) {
+		targets[filepath.Base(f.Path)] = f.Target
+	}
+	for _, name := range []string{"Loose (2002).mkv", "Loose (2002).nfo", "Loose (2002)-poster.jpg"} {
+		if targets[name] != expected {
+			t.Errorf("Expected %s to target %s, got %q", name, expected, targets[name])
+		}
+	}
+	if target, ok := targets["stray.mkv"]; !ok || target != "" {
+		t.Errorf("Expected a warning without target for the library-level video, got %q (reported %v)", target, ok)
+	}
+}
+
+func TestScan_MisplacedEpisodeTargets(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Show").
+		Title("Season 01").Video("Show S01E01.mkv").
+		Up().Video("Show S02E03.mkv", "Show 1x04.mkv", "Show Special.mkv").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithLayout(TVLayout)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"Show S02E03.mkv":  filepath.Join("Show", "Season 02"),
+		"Show 1x04.mkv":    filepath.Join("Show", "Season 01"),
+		"Show Special.mkv": "",
+	}
+	for _, f := range result.ByCategory(CategoryStructureWarning) {
+		if want := expected[filepath.Base(f.Path)]; f.Target != want {
+			t.Errorf("Expected %s to target %q, got %q", f.Path, want, f.Target)
+		}
+	}
+}
+
+func TestScan_FlatLayout(t *testing.T) {
+	// Without studios, every top-level folder is a title
+	fsys := cleanuptest.New().
+		Studio("Kept (2001)").Video("kept.mkv").Metadata("kept.nfo").
+		Studio("Orphaned (2002)").Metadata("movie.nfo").
+		Studio("Empty (2003)").
+		Root().Video("Loose (2004).mkv").Metadata("Loose (2004).nfo", "Gone (2005).nfo").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithLayout(FlatLayout)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] != "Orphaned (2002)" {
+		t.Errorf("Expected only Orphaned (2002) orphaned, got %v", result.OrphanedFolders)
+	}
+	if len(result.EmptyFolders) != 1 || result.EmptyFolders[0] != "Empty (2003)" {
+		t.Errorf("Expected only Empty (2003) empty, got %v", result.EmptyFolders)
+	}
+	if len(result.OrphanedFiles) != 1 || result.OrphanedFiles[0] != "Gone (2005).nfo" {
+		t.Errorf("Expected only Gone (2005).nfo orphaned, got %v", result.OrphanedFiles)
+	}
+	// A loose video belongs in a title folder of the library itself
+	for _, f := range result.ByCategory(CategoryStructureWarning) {
+		if f.Target != "Loose (2004)" {
+			t.Errorf("Expected %s to target Loose (2004), got %q", f.Path, f.Target)
+		}
+	}
+	if len(result.StructureWarnings) != 2 {
+		t.Errorf("Expected warnings for the loose video and its NFO, got %v", result.StructureWarnings)
+	}
+}
+
+func TestTitleFolderName(t *testing.T) {
+	tests := map[string]string{
+		"Movie (2001).mkv":       "Movie (2001)",
+		"Movie (2001) - cd2.avi": "Movie (2001)",
+		"Movie.Part1.mkv":        "Movie",
+	}
+	for name, expected := range tests {
+		if got := titleFolderName(name); got != expected {
+			t.Errorf("titleFolderName(%q): expected %q, got %q", name, expected, got)
+		}
+	}
+}
+
+// ============================================================================
+// Tests for StructureFixer
+// ============================================================================
+
+func TestStructureFixer_MovesIntoTitleFolder(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	studio := filepath.Join(tempDir, "Studio")
+	createFile(t, filepath.Join(studio, "Loose (2002).mkv"))
+	createFile(t, filepath.Join(studio, "Loose (2002).nfo"))
+
+	result := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), tempDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	var events []FileMoved
+	report, err := NewStructureFixer(WithMoveProgress(func(ev ProgressEvent) {
+		events = append(events, ev.(FileMoved))
+	})).Fix(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Fix returned error: %v", err)
+	}
+	if len(report.Moved) != 2 || len(report.Failures) != 0 {
+		t.Fatalf("Expected 2 moved and no failures, got %d moved and %v", len(report.Moved), report.Failures)
+	}
+	for _, name := range []string{"Loose (2002).mkv", "Loose (2002).nfo"} {
+		if _, err := os.Stat(filepath.Join(studio, "Loose (2002)", name)); err != nil {
+			t.Errorf("Expected %s in the title folder: %v", name, err)
+		}
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected one progress event per moved item, got %d", len(events))
+	}
+
+	// The fixed library scans clean
+	rescan := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), tempDir, rescan.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(rescan.Findings) != 0 {
+		t.Errorf("Expected no findings after the move, got %v", rescan.Findings)
+	}
+}
+
+func TestStructureFixer_NeverOverwrites(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	loose := filepath.Join(tempDir, "Studio", "movie.mkv")
+	target := filepath.Join(tempDir, "Studio", "movie")
+	createFile(t, loose)
+	createFile(t, filepath.Join(target, "movie.mkv"))
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryStructureWarning, Path: loose, Target: target})
+
+	report, err := NewStructureFixer().Fix(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Fix returned error: %v", err)
+	}
+	var moveErr *MoveError
+	if len(report.Failures) != 1 || !errors.As(report.Failures[0], &moveErr) || !errors.Is(moveErr, fs.ErrExist) {
+		t.Fatalf("Expected a MoveError for the existing file, got %v", report.Failures)
+	}
+	if _, err := os.Stat(loose); err != nil {
+		t.Errorf("Expected %s to be left in place: %v", loose, err)
+	}
+}
+
+func TestStructureFixer_SkipsGoneItems(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryStructureWarning, Path: filepath.Join(tempDir, "gone.mkv"), Target: filepath.Join(tempDir, "gone")})
+	result.add(Finding{Category: CategoryStructureWarning, Path: filepath.Join(tempDir, "stray.mkv")})
+
+	report, err := NewStructureFixer().Fix(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Fix returned error: %v", err)
+	}
+	if len(report.Skipped) != 1 || len(report.Moved) != 0 || len(report.Failures) != 0 {
+		t.Errorf("Expected 1 skipped item only, got %d skipped, %d moved and %v", len(report.Skipped), len(report.Moved), report.Failures)
+	}
+}
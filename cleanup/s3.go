@@ -0,0 +1,412 @@
+package cleanup
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Target is a library stored in an S3-compatible bucket, given as
+// s3://bucket/prefix. The "folders" below prefix, i.e. the key prefixes up
+// to each "/", are its studios and titles.
+type S3Target struct {
+	Bucket string
+	Prefix string // without leading or trailing slash, empty for the whole bucket
+}
+
+// IsS3URL reports whether s names a library with the s3:// scheme.
+func IsS3URL(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "s3://")
+}
+
+// ParseS3URL parses an s3://bucket/prefix library URL.
+func ParseS3URL(s string) (S3Target, error) {
+	if !IsS3URL(s) {
+		return S3Target{}, fmt.Errorf("invalid library URL %q (expected s3://bucket/prefix)", s)
+	}
+	bucket, prefix, _ := strings.Cut(s[len("s3://"):], "/")
+	if bucket == "" {
+		return S3Target{}, fmt.Errorf("invalid library URL %q (expected s3://bucket/prefix)", s)
+	}
+	prefix = strings.Trim(path.Clean("/"+prefix), "/")
+	return S3Target{Bucket: bucket, Prefix: prefix}, nil
+}
+
+// String returns t in its s3:// form.
+func (t S3Target) String() string {
+	return "s3://" + path.Join(t.Bucket, t.Prefix)
+}
+
+// Root returns the path to scan t at with an S3FS, /bucket/prefix.
+// Findings are reported with paths of that form.
+func (t S3Target) Root() string {
+	return "/" + path.Join(t.Bucket, t.Prefix)
+}
+
+// S3Client makes the few S3 API calls S3FS and S3Strategy need. Buckets are
+// addressed by path (https://endpoint/bucket/key), which AWS, MinIO,
+// Backblaze B2, Wasabi and other S3-compatible stores accept.
+type S3Client struct {
+	Endpoint     string // e.g. https://s3.eu-west-1.amazonaws.com
+	Region       string
+	AccessKey    string // requests are anonymous if empty
+	SecretKey    string
+	SessionToken string // for temporary credentials, optional
+	HTTPClient   *http.Client
+}
+
+// s3Object is a listed object.
+type s3Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// request sends a signed request for key in bucket and returns the response
+// body, or an *S3Error if the store answered with an error status.
+func (c *S3Client) request(method, bucket, key string, query url.Values, header http.Header, body []byte) ([]byte, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("S3 endpoint: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = awsEscape(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	sum := sha256.Sum256(body)
+	c.sign(req, hex.EncodeToString(sum[:]), time.Now())
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		s3Err := &S3Error{Status: resp.StatusCode}
+		_ = xml.Unmarshal(data, s3Err)
+		return nil, s3Err
+	}
+	return data, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req, signing every header it
+// already has along with the host.
+func (c *S3Client) sign(req *http.Request, payloadHash string, now time.Time) {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.AccessKey == "" {
+		return
+	}
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery,
+		canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := amzDate[:8] + "/" + c.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + c.SecretKey)
+	for _, part := range []string{amzDate[:8], c.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%x",
+		c.AccessKey, scope, signedHeaders, hmacSHA256(key, stringToSign)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape percent-encodes s the way signatures expect: everything but
+// letters, digits and "-_.~", and "/" unless encodeSlash is set.
+func awsEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', strings.IndexByte("-_.~", c) >= 0:
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes query sorted by name, as signatures expect.
+func canonicalQuery(query url.Values) string {
+	var parts []string
+	for name, values := range query {
+		for _, value := range values {
+			parts = append(parts, awsEscape(name, true)+"="+awsEscape(value, true))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "&")
+}
+
+// list returns the objects whose key starts with prefix, at most limit of
+// them if limit is positive.
+func (c *S3Client) list(bucket, prefix string, limit int) ([]s3Object, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if limit > 0 {
+		query.Set("max-keys", strconv.Itoa(limit))
+	}
+	var objects []s3Object
+	for {
+		data, err := c.request(http.MethodGet, bucket, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents              []s3Object
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err := xml.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("listing s3://%s/%s: %w", bucket, prefix, err)
+		}
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated || (limit > 0 && len(objects) >= limit) {
+			return objects, nil
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+// deleteObjects deletes keys from bucket, up to 1000 per request.
+func (c *S3Client) deleteObjects(bucket string, keys []string) error {
+	var errs []error
+	for start := 0; start < len(keys); start += 1000 {
+		end := min(start+1000, len(keys))
+		var request struct {
+			XMLName xml.Name `xml:"Delete"`
+			Quiet   bool
+			Object  []struct{ Key string }
+		}
+		request.Quiet = true
+		for _, key := range keys[start:end] {
+			request.Object = append(request.Object, struct{ Key string }{key})
+		}
+		body, err := xml.Marshal(request)
+		if err != nil {
+			return err
+		}
+		sum := md5.Sum(body)
+		header := http.Header{"Content-Md5": {base64.StdEncoding.EncodeToString(sum[:])}}
+		data, err := c.request(http.MethodPost, bucket, "", url.Values{"delete": {""}}, header, body)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			Error []struct{ Key, Code, Message string }
+		}
+		if err := xml.Unmarshal(data, &result); err != nil {
+			return err
+		}
+		for _, e := range result.Error {
+			errs = append(errs, fmt.Errorf("%s: %s", e.Key, (&S3Error{Code: e.Code, Message: e.Message}).Error()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// S3FS is a read-only FS over a library in an S3-compatible bucket, for
+// WithFS. Every key below the library prefix is listed on first use;
+// objects are fetched when read. Paths have the form /bucket/key, so the
+// library root to scan is Target.Root(). A key ending in "/" is the marker
+// object of an otherwise empty folder, as consoles and sync tools create.
+type S3FS struct {
+	Client *S3Client
+	Target S3Target
+
+	once sync.Once
+	err  error
+	tree *remoteTree
+}
+
+// load lists every object of the library.
+func (s *S3FS) load() error {
+	s.once.Do(func() {
+		prefix := s.Target.Prefix
+		if prefix != "" {
+			prefix += "/"
+		}
+		objects, err := s.Client.list(s.Target.Bucket, prefix, 0)
+		if err != nil {
+			s.err = fmt.Errorf("%s: %w", s.Target, err)
+			return
+		}
+		root := s.Target.Root()
+		s.tree = newRemoteTree()
+		if prefix == "" || len(objects) > 0 {
+			s.tree.add(root, &remoteEntry{mode: fs.ModeDir | 0755}, root)
+		}
+		for _, object := range objects {
+			p := path.Join("/", s.Target.Bucket, object.Key)
+			if strings.HasSuffix(object.Key, "/") {
+				s.tree.addFolders(p, root)
+				if folder := s.tree.entries[p]; folder != nil {
+					folder.modTime = object.LastModified
+				}
+				continue
+			}
+			s.tree.addFolders(path.Dir(p), root)
+			s.tree.add(p, &remoteEntry{size: object.Size, mode: 0644, modTime: object.LastModified}, root)
+		}
+	})
+	return s.err
+}
+
+// s3Key returns the object key of the path name of bucket.
+func s3Key(bucket, name string) string {
+	return strings.TrimPrefix(remotePath(name), "/"+bucket+"/")
+}
+
+func (s *S3FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.tree.readDir(name)
+}
+
+func (s *S3FS) Stat(name string) (fs.FileInfo, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.tree.lstat(name)
+}
+
+func (s *S3FS) ReadFile(name string) ([]byte, error) {
+	if _, err := s.Stat(name); err != nil {
+		return nil, err
+	}
+	data, err := s.Client.request(http.MethodGet, s.Target.Bucket, s3Key(s.Target.Bucket, name), nil, nil, nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// S3Strategy deletes findings of an S3FS scan from Bucket through the S3
+// API. An orphaned folder loses every object below it; an empty folder is
+// its marker object, deleted only while nothing else is stored below it.
+type S3Strategy struct {
+	Client *S3Client
+	Bucket string
+}
+
+func (S3Strategy) Name() string { return "s3" }
+
+// maxEmptyFolderObjects is more objects than a folder holding nothing but
+// its marker and OS junk has.
+const maxEmptyFolderObjects = 10
+
+func (s S3Strategy) Delete(f Finding) error {
+	key := s3Key(s.Bucket, f.Path)
+	switch f.Category {
+	case CategoryOrphanedFolder:
+		objects, err := s.Client.list(s.Bucket, key+"/", 0)
+		if err != nil {
+			return err
+		}
+		keys := make([]string, len(objects))
+		for i, object := range objects {
+			keys[i] = object.Key
+		}
+		return s.Client.deleteObjects(s.Bucket, keys)
+	case CategoryEmptyFolder:
+		// The folder marker, and OS junk, which is deleted along with it
+		objects, err := s.Client.list(s.Bucket, key+"/", maxEmptyFolderObjects)
+		if err != nil {
+			return err
+		}
+		if len(objects) == maxEmptyFolderObjects {
+			return fmt.Errorf("folder is no longer empty")
+		}
+		keys := []string{key + "/"}
+		for _, object := range objects {
+			name := strings.TrimPrefix(object.Key, key+"/")
+			if name == "" {
+				continue
+			}
+			if strings.Contains(name, "/") || !isJunk(name) {
+				return fmt.Errorf("folder is no longer empty")
+			}
+			keys = append(keys, object.Key)
+		}
+		return s.Client.deleteObjects(s.Bucket, keys)
+	}
+	return s.Client.deleteObjects(s.Bucket, []string{key})
+}
+
+// Exists reports whether an object is still stored at or below path, so
+// the Deleter skips items deleted along with a parent.
+func (s S3Strategy) Exists(path string) (bool, error) {
+	key := s3Key(s.Bucket, path)
+	_, err := s.Client.request(http.MethodHead, s.Bucket, key, nil, nil, nil)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	objects, err := s.Client.list(s.Bucket, key+"/", 1)
+	return len(objects) > 0, err
+}
@@ -0,0 +1,1178 @@
+// Package cleanup finds orphaned metadata, empty folders and structure
+// problems in Emby and Jellyfin media libraries, and disposes of them. It is
+// the engine of the video-folder-cleanup command and can be embedded in
+// other programs:
+//
+//	scanner := cleanup.NewScanner(cleanup.WithWorkers(8), cleanup.WithWorkerBudget(cleanup.NewWorkerBudget(8)))
+//	result, err := scanner.ScanAll(ctx, []string{"/mnt/media/Movies", "/mnt/media/Kids"})
+//	if err != nil {
+//		return err // cancelled, result is partial
+//	}
+//	report, err := cleanup.NewDeleter(cleanup.TrashDirStrategy{Dir: "/mnt/media/.trash"}).Delete(ctx, result)
+//
+// Scanner.Scan streams the findings of one library to a callback instead.
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Layout describes the directory hierarchy below a library root. Levels are
+// listed outermost first; the last level is the title folder that is
+// expected to hold the video files.
+type Layout struct {
+	Name   string
+	Levels []string
+	// MetadataLevels lists the intermediate levels whose folders carry
+	// metadata of their own, such as tvshow.nfo and artwork in show folders.
+	// Their files only count as orphaned once no video is left below them.
+	MetadataLevels []string
+	// Episodes means a title folder holds many videos, such as the episodes
+	// of a season: the metadata of each one is matched against the videos,
+	// and unrelated video names are expected.
+	Episodes bool
+}
+
+// MovieLayout is the default library/studio/title/video.mkv structure.
+var MovieLayout = Layout{Name: "movies", Levels: []string{"studio", "title"}}
+
+// TVLayout is the library/show/season/episode.mkv structure of television
+// libraries.
+var TVLayout = Layout{Name: "tv", Levels: []string{"show", "season"}, MetadataLevels: []string{"show"}, Episodes: true}
+
+// FlatLayout is the library/title/video.mkv structure of movie libraries
+// without studio folders.
+var FlatLayout = Layout{Name: "flat", Levels: []string{"title"}}
+
+// LayoutByName returns the built-in layout called name.
+func LayoutByName(name string) (Layout, error) {
+	for _, layout := range []Layout{MovieLayout, TVLayout, FlatLayout} {
+		if layout.Name == name {
+			return layout, nil
+		}
+	}
+	return Layout{}, fmt.Errorf("unknown structure %q (expected movies, tv or flat)", name)
+}
+
+// levelName matches the name of a level in a layout template.
+var levelName = regexp.MustCompile(`^\{([a-z][a-z0-9_]*)\}$`)
+
+// ParseLayout returns the layout described by a template such as
+// "{library}/{studio}/{collection}/{title}": the library root first, then
+// one level per folder, the last one holding the videos. Level names are
+// free and show in structure warnings.
+func ParseLayout(template string) (Layout, error) {
+	segments := strings.Split(strings.Trim(strings.TrimSpace(template), "/"), "/")
+	layout := Layout{Name: template}
+	seen := map[string]bool{}
+	for i, segment := range segments {
+		m := levelName.FindStringSubmatch(strings.TrimSpace(segment))
+		if m == nil {
+			return Layout{}, fmt.Errorf("layout %q: %q is not a {level} placeholder", template, segment)
+		}
+		if (i == 0) != (m[1] == "library") {
+			return Layout{}, fmt.Errorf("layout %q: must start with {library}, and only there", template)
+		}
+		if seen[m[1]] {
+			return Layout{}, fmt.Errorf("layout %q: {%s} appears twice", template, m[1])
+		}
+		seen[m[1]] = true
+		if i > 0 {
+			layout.Levels = append(layout.Levels, m[1])
+		}
+	}
+	if len(layout.Levels) == 0 {
+		return Layout{}, fmt.Errorf("layout %q: needs at least one level below {library}", template)
+	}
+	return layout, nil
+}
+
+// hasMetadata reports whether folders at level carry their own metadata.
+func (l Layout) hasMetadata(level string) bool {
+	for _, known := range l.MetadataLevels {
+		if known == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Scanner finds orphaned metadata, empty folders and structure problems in
+// media libraries. Create one with NewScanner; a Scanner is safe to reuse
+// across libraries.
+type Scanner struct {
+	extensions         map[string]bool
+	stubExtensions     map[string]bool
+	metadataDirs       []string
+	metadataDirNames   []string
+	layout             Layout
+	workers            int
+	budget             *WorkerBudget
+	fsys               FS
+	classifier         Classifier
+	progress           ProgressFunc
+	permissions        *PermissionPolicy
+	followSymlinks     bool
+	detectDuplicates   bool
+	hashDuplicates     bool
+	auditPortableNames bool
+	nfoYearCheck       bool
+	nfoTitleCheck      bool
+	missingMetadata    bool
+	filter             PathFilter
+	minAge             time.Duration
+	recycleBinUsage    bool
+	junkFiles          bool
+	categories         map[Category]bool // nil for every category
+	minVideoSize       int64
+	prober             VideoProber
+	limiter            *RateLimiter
+	smb                bool
+	state              *ScanState
+	checkpoint         *Checkpoint
+	listings           *ListingCache
+}
+
+// Option configures a Scanner.
+type Option func(*Scanner)
+
+// WithExtensions replaces the set of video extensions (e.g. ".mkv", "ts").
+// Matching is case-insensitive and the leading dot is optional. It has no
+// effect when a custom Classifier is supplied with WithClassifier.
+func WithExtensions(exts ...string) Option {
+	return func(s *Scanner) {
+		s.extensions = extensionSet(exts)
+	}
+}
+
+// WithStubExtensions replaces the extensions of stub files (default
+// ".strm"), which hold a link to a stream and count as the video of their
+// title. Stubs are tiny and hold no video, so WithMinVideoSize,
+// WithVideoProbe and WithDuplicateDetection leave them alone. With
+// WithClassifier, the Classifier must still report them as KindVideo.
+func WithStubExtensions(exts ...string) Option {
+	return func(s *Scanner) {
+		s.stubExtensions = extensionSet(exts)
+	}
+}
+
+// extensionSet returns exts lowercased and with a leading dot.
+func extensionSet(exts []string) map[string]bool {
+	set := make(map[string]bool, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		set[ext] = true
+	}
+	return set
+}
+
+// WithRateLimiter makes every directory listing, stat and file read of the
+// scan wait for limiter, e.g. to spare a NAS serving playback.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(s *Scanner) {
+		s.limiter = limiter
+	}
+}
+
+// WithMetadataDirs replaces the suffixes of the subdirectories that belong
+// to a video, such as ".trickplay" for "movie.trickplay". Matching is
+// case-insensitive. It has no effect when a custom Classifier is supplied
+// with WithClassifier.
+func WithMetadataDirs(suffixes ...string) Option {
+	return func(s *Scanner) {
+		s.metadataDirs = make([]string, len(suffixes))
+		for i, suffix := range suffixes {
+			s.metadataDirs[i] = nameKey(suffix)
+		}
+	}
+}
+
+// WithMetadataDirNames replaces the names of the subdirectories that belong
+// to a title, such as "extrafanart" or ".actors". Matching is
+// case-insensitive. Like WithMetadataDirs, it has no effect with
+// WithClassifier.
+func WithMetadataDirNames(names ...string) Option {
+	return func(s *Scanner) {
+		s.metadataDirNames = names
+	}
+}
+
+// WithLayout sets the expected directory hierarchy (default MovieLayout).
+func WithLayout(layout Layout) Option {
+	return func(s *Scanner) {
+		s.layout = layout
+	}
+}
+
+// WithWorkers sets how many top-level folders are processed concurrently.
+// Values below one are treated as one.
+func WithWorkers(n int) Option {
+	return func(s *Scanner) {
+		s.workers = n
+	}
+}
+
+// WithWorkerBudget makes the scanner take a slot from budget for every
+// top-level folder it processes, on top of the WithWorkers limit. Scanners
+// sharing a budget can scan libraries concurrently while processing at most
+// as many folders at once as the budget allows.
+func WithWorkerBudget(budget *WorkerBudget) Option {
+	return func(s *Scanner) {
+		s.budget = budget
+	}
+}
+
+// WithFS sets the filesystem to scan (default: the local disk).
+func WithFS(fsys FS) Option {
+	return func(s *Scanner) {
+		s.fsys = fsys
+	}
+}
+
+// WithClassifier overrides how entries are recognized as videos and metadata
+// directories.
+func WithClassifier(c Classifier) Option {
+	return func(s *Scanner) {
+		s.classifier = c
+	}
+}
+
+// WithProgress calls fn with LibraryStarted, StudioScanned, TitleScanned and
+// FolderSkipped events as the scan advances.
+func WithProgress(fn ProgressFunc) Option {
+	return func(s *Scanner) {
+		s.progress = fn
+	}
+}
+
+// WithFollowSymlinks makes a symlink named like a video count as one when
+// its target exists and is a regular file. By default symlinks never count
+// as videos, so a title folder holding only a video symlink is orphaned.
+func WithFollowSymlinks(follow bool) Option {
+	return func(s *Scanner) {
+		s.followSymlinks = follow
+	}
+}
+
+// WithDuplicateDetection reports videos of identical size within a library
+// as CategoryDuplicateVideo findings once the library has been scanned.
+// Hard links to the same file are recognized and never reported.
+func WithDuplicateDetection(detect bool) Option {
+	return func(s *Scanner) {
+		s.detectDuplicates = detect
+	}
+}
+
+// WithDuplicateHashing confirms the same-size videos of duplicate detection
+// by their contents, reporting only byte-identical copies. It implies
+// WithDuplicateDetection. Every candidate is read in full, so it is only
+// supported on filesystems that can stream files, such as the local one.
+func WithDuplicateHashing(hash bool) Option {
+	return func(s *Scanner) {
+		s.hashDuplicates = hash
+		if hash {
+			s.detectDuplicates = true
+		}
+	}
+}
+
+// WithPermissionAudit reports every file and folder whose owner, group or
+// mode differs from policy as a CategoryPermissionMismatch finding.
+func WithPermissionAudit(policy PermissionPolicy) Option {
+	return func(s *Scanner) {
+		s.permissions = &policy
+	}
+}
+
+// WithNameAudit reports every file and folder below the library root whose
+// name would break on Windows, exFAT or SMB targets (reserved characters,
+// trailing spaces or dots, reserved device names, names over 255 bytes, or
+// siblings differing only by case) as a CategoryIncompatibleName finding.
+func WithNameAudit(audit bool) Option {
+	return func(s *Scanner) {
+		s.auditPortableNames = audit
+	}
+}
+
+// WithNFOYearCheck reads the NFO files of title folders that have a video
+// and reports a CategoryMetadataMismatch when their year or premiered date
+// differs from the year in a "Title (Year)" folder name, which usually means
+// a wrong metadata match.
+func WithNFOYearCheck(check bool) Option {
+	return func(s *Scanner) {
+		s.nfoYearCheck = check
+	}
+}
+
+// WithNFOCheck checks the year of NFO files as WithNFOYearCheck does,
+// whatever that option says, and also reports a CategoryMetadataMismatch
+// when neither their title nor their original title is the one in the
+// folder name, which usually means the folder was renamed without
+// refreshing its metadata. Titles are only compared in movie layouts.
+func WithNFOCheck(check bool) Option {
+	return func(s *Scanner) {
+		s.nfoTitleCheck = check
+	}
+}
+
+// WithMissingMetadataCheck reports the title folders that have a video but
+// no NFO or no poster as CategoryMissingMetadata, or in episode layouts the
+// episodes without an NFO, for a metadata refresh in the media server.
+func WithMissingMetadataCheck(check bool) Option {
+	return func(s *Scanner) {
+		s.missingMetadata = check
+	}
+}
+
+// WithRecycleBinUsage reports the recycle bins the scan skips, such as
+// Synology's #recycle, as CategoryRecycleBin findings with the space they
+// hold, which means reading everything in them.
+func WithRecycleBinUsage(report bool) Option {
+	return func(s *Scanner) {
+		s.recycleBinUsage = report
+	}
+}
+
+// WithJunkFiles reports the OS junk files found anywhere in the library,
+// such as .DS_Store or Thumbs.db, as CategoryJunkFile findings.
+func WithJunkFiles(report bool) Option {
+	return func(s *Scanner) {
+		s.junkFiles = report
+	}
+}
+
+// WithCategories limits the scan to the findings of categories: the checks
+// of the others are skipped where they cost anything, such as the size of
+// orphaned folders or probing videos, and their findings dropped. Folders
+// are still classified in full, so a title whose video is misfiled is never
+// reported as orphaned because misfiled videos are left out. Categories of
+// optional checks need their own option as well, e.g. WithJunkFiles. Without
+// categories, every category is reported.
+func WithCategories(categories ...Category) Option {
+	return func(s *Scanner) {
+		s.categories = nil
+		if len(categories) == 0 {
+			return
+		}
+		s.categories = map[Category]bool{}
+		for _, c := range categories {
+			s.categories[c] = true
+		}
+	}
+}
+
+// checks reports whether findings of category c are reported.
+func (s *Scanner) checks(c Category) bool {
+	return s.categories == nil || s.categories[c]
+}
+
+// WithPathFilter limits Scan to the studio and title folders filter
+// selects. Folders left out produce no findings at all. ScanTitle ignores
+// the filter: the title to check is given explicitly.
+func WithPathFilter(filter PathFilter) Option {
+	return func(s *Scanner) {
+		s.filter = filter
+	}
+}
+
+// WithMinAge leaves alone orphaned folders, orphaned files and empty
+// folders with anything modified within the last age: they are reported as
+// structure warnings instead, so nothing still being imported gets deleted.
+// With WithCategories, they are reported only if structure warnings are
+// among the categories.
+func WithMinAge(age time.Duration) Option {
+	return func(s *Scanner) {
+		s.minAge = age
+	}
+}
+
+// WithMinVideoSize reports videos in title folders smaller than size bytes
+// as CategoryTruncatedVideo, so a title holding only a broken stub does not
+// pass for healthy. 0, the default, disables the check; 1 reports empty
+// files only.
+func WithMinVideoSize(size int64) Option {
+	return func(s *Scanner) {
+		s.minVideoSize = size
+	}
+}
+
+// WithVideoProbe has prober inspect every video of a title folder and
+// reports those it finds a problem with as CategoryCorruptVideo. Videos are
+// probed at their native path, so this only makes sense with the default
+// filesystem; expect it to slow the scan down considerably.
+func WithVideoProbe(prober VideoProber) Option {
+	return func(s *Scanner) {
+		s.prober = prober
+	}
+}
+
+// NewScanner returns a Scanner with the default movie layout, video
+// extensions and 10 workers, modified by opts.
+func NewScanner(opts ...Option) *Scanner {
+	s := &Scanner{
+		extensions:       videoExtensions,
+		stubExtensions:   stubExtensions,
+		metadataDirs:     metadataSubdirSuffixes,
+		metadataDirNames: metadataSubdirNames,
+		layout:           MovieLayout,
+		workers:          10,
+		fsys:             osFS{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.limiter != nil {
+		s.fsys = throttledFS{fsys: s.fsys, limiter: s.limiter}
+	}
+	// Above the limiter, so cached listings do not wait for it
+	if s.listings != nil {
+		s.fsys = cachedFS{fsys: s.fsys, cache: s.listings}
+	}
+	if s.classifier == nil {
+		names := make(map[string]bool, len(s.metadataDirNames))
+		for _, name := range s.metadataDirNames {
+			names[nameKey(name)] = true
+		}
+		s.classifier = extensionClassifier{
+			extensions:             s.extensions,
+			stubExtensions:         s.stubExtensions,
+			metadataSubdirSuffixes: s.metadataDirs,
+			metadataSubdirNames:    names,
+		}
+	}
+	return s
+}
+
+// leafLevel is the name of the level that holds video files, e.g. "title".
+func (s *Scanner) leafLevel() string {
+	return s.layout.Levels[len(s.layout.Levels)-1]
+}
+
+// Scan walks the library at root and calls fn for every finding as soon as
+// it is discovered, with Finding.Library set to root. fn is never called
+// concurrently, so it may update shared state without locking.
+//
+// Scan stops early and returns the error if fn returns one, if ctx is
+// cancelled, or if root cannot be scanned at all (ErrNotADirectory,
+// *ErrUnreadableDir). Folders below root that cannot be read do not stop the
+// scan; they are returned together, as one *ErrUnreadableDir each joined with
+// errors.Join, once everything else has been visited.
+func (s *Scanner) Scan(ctx context.Context, root string, fn func(Finding) error) error {
+	return s.run(ctx, root, fn, func(r *scanRun) error {
+		return r.scanLibrary(root)
+	})
+}
+
+// ScanAll scans every library in roots concurrently and returns their
+// findings merged in roots order, each attributed to its library. Libraries
+// and folders that cannot be scanned are recorded in CleanupResult.Errors
+// rather than returned; the error is only set when ctx stopped the scan, and
+// the result is then partial. Give the scanner a WithWorkerBudget to bound
+// the folders processed at once across libraries.
+func (s *Scanner) ScanAll(ctx context.Context, roots []string) (*CleanupResult, error) {
+	results := make([]*CleanupResult, len(roots))
+	for i, root := range roots {
+		results[i] = &CleanupResult{Libraries: []string{root}}
+	}
+	err := RunPool(ctx, len(results), results, func(result *CleanupResult) error {
+		result.Errors = SplitErrors(s.Scan(ctx, result.Libraries[0], result.Add))
+		return nil
+	})
+
+	merged := MergeResults(results...)
+	// Only panics come back from the pool
+	merged.Errors = append(merged.Errors, SplitErrors(err)...)
+	return merged, ctx.Err()
+}
+
+// ScanTitle classifies the single title folder titlePath exactly as Scan
+// would, calling fn for its findings with Finding.Library set to the library
+// root the layout places it under (see TitleLibrary). It returns
+// ErrNotADirectory or *ErrUnreadableDir if titlePath cannot be scanned.
+func (s *Scanner) ScanTitle(ctx context.Context, titlePath string, fn func(Finding) error) error {
+	return s.run(ctx, s.TitleLibrary(titlePath), fn, func(r *scanRun) error {
+		info, err := r.fsys.Stat(titlePath)
+		if err != nil {
+			return &ErrUnreadableDir{Path: titlePath, Err: err}
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("title %s: %w", titlePath, ErrNotADirectory)
+		}
+		r.budget.do(r.ctx, func() { r.processTitleFolder(titlePath) })
+		return r.ctx.Err()
+	})
+}
+
+// TitleLibrary returns the library root of the title folder titlePath, one
+// folder up per level of the layout.
+func (s *Scanner) TitleLibrary(titlePath string) string {
+	library := filepath.Clean(titlePath)
+	for range s.layout.Levels {
+		library = filepath.Dir(library)
+	}
+	return library
+}
+
+// run calls scan with a scanRun that passes findings to fn, and handles
+// errors and early stops as documented on Scan.
+func (s *Scanner) run(ctx context.Context, root string, fn func(Finding) error, scan func(*scanRun) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := &scanRun{Scanner: s, ctx: ctx, fsys: s.fsys, library: root, ignoreRules: map[string][]ignoreRule{}}
+	if s.smb {
+		run.fsys = newSMBFS(s.fsys)
+	}
+
+	var mu sync.Mutex
+	var fnErr error
+	cutoff := time.Now().Add(-s.minAge)
+	emit := func(f Finding) {
+		if s.minAge > 0 {
+			// A held finding is a structure warning now, left out unless
+			// those are checked
+			if f = run.holdRecent(f, cutoff); !s.checks(f.Category) {
+				return
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if fnErr != nil {
+			return
+		}
+		f.Library = root
+		if err := fn(f); err != nil {
+			fnErr = err
+			cancel()
+		}
+	}
+
+	run.emit = func(f Finding) {
+		if !s.checks(f.Category) {
+			return
+		}
+		if f.Severity == "" {
+			f.Severity = f.Category.Severity()
+		}
+		f = run.stampModified(f)
+		run.titles.record(f)
+		run.folders.record(f)
+		if !run.ignored(f.Path, run.statIsDir(f.Path)) {
+			emit(f)
+		}
+	}
+	err := scan(run)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return err
+	}
+	return errors.Join(run.errs...)
+}
+
+// scanRun holds the state of a single Scan call. Its methods may run on
+// several goroutines at once.
+type scanRun struct {
+	*Scanner
+	ctx  context.Context
+	emit func(Finding)
+	fsys FS // the Scanner's, behind a per-scan listing cache with WithSMB
+	// titles is the part of the scan state this scan uses, nil without
+	// WithScanState or when checking a single title
+	titles *stateScan
+	// folders is the part of the checkpoint this scan uses, nil without
+	// WithCheckpoint or when checking a single title
+	folders *checkpointScan
+
+	mu          sync.Mutex
+	errs        []error // non-fatal errors, returned once the scan completes
+	library     string
+	studiosDone int
+	videos      []videoFile             // for duplicate detection
+	ignoreRules map[string][]ignoreRule // by folder, nil if it has no ignore file
+}
+
+// fail records a non-fatal error and lets the scan carry on.
+func (r *scanRun) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+// report sends ev to the progress callback, if any, one event at a time.
+func (r *scanRun) report(ev ProgressEvent) {
+	if r.progress == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress(ev)
+}
+
+// studioDone reports a finished top-level folder out of total.
+func (r *scanRun) studioDone(dirPath string, total int) {
+	if r.progress == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.studiosDone++
+	r.progress(StudioScanned{Library: r.library, Path: dirPath, Done: r.studiosDone, Total: total})
+}
+
+// scanLibrary scans one library root. It returns ctx.Err() if the context is
+// cancelled before every top-level folder has been processed.
+func (r *scanRun) scanLibrary(libraryPath string) error {
+	if len(r.layout.Levels) == 0 {
+		return fmt.Errorf("layout %q has no levels", r.layout.Name)
+	}
+
+	// Validate library path exists
+	info, err := r.fsys.Stat(libraryPath)
+	if err != nil {
+		return &ErrUnreadableDir{Path: libraryPath, Err: err}
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("library %s: %w", libraryPath, ErrNotADirectory)
+	}
+
+	// Get all top-level folders (studios in the default layout)
+	entries, err := r.fsys.ReadDir(libraryPath)
+	if err != nil {
+		return &ErrUnreadableDir{Path: libraryPath, Err: err}
+	}
+
+	// Check for files directly in library (structure violation), unless
+	// --include picks folders below it
+	if r.filter.selects(".") {
+		r.checkDirectChildren(libraryPath, "library")
+	}
+	r.auditEntries(libraryPath, entries)
+
+	var topDirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			topDirs = append(topDirs, filepath.Join(libraryPath, entry.Name()))
+		}
+	}
+
+	var resumed map[string][]Finding
+	if r.checkpoint != nil {
+		r.folders = r.checkpoint.begin(libraryPath, r.checkpointKey(), !r.detectDuplicates)
+		resumed = r.folders.resumed
+		for dirPath, findings := range resumed {
+			if !r.stillCurrent(findings) {
+				r.folders.forget(dirPath)
+			}
+		}
+		defer func() {
+			r.folders.end(r.ctx.Err() == nil)
+		}()
+	}
+	resumedCount := 0
+	for _, dirPath := range topDirs {
+		if _, ok := resumed[dirPath]; ok {
+			resumedCount++
+		}
+	}
+	r.report(LibraryStarted{Library: r.library, Studios: len(topDirs), Resumed: resumedCount})
+
+	if r.state != nil {
+		r.titles = r.state.begin(libraryPath, r.optionsKey())
+		// Title folders below the resumed folders were not visited, so
+		// what is remembered of them is kept
+		defer func() {
+			r.titles.end(r.ctx.Err() == nil && len(r.filter.Include) == 0 && len(r.filter.Exclude) == 0 && resumedCount == 0)
+		}()
+	}
+
+	// Process top-level folders concurrently. Unreadable folders are recorded
+	// through fail; only panics come back from the pool.
+	err = RunPool(r.ctx, r.workers, topDirs, func(dirPath string) error {
+		if findings, ok := resumed[dirPath]; ok {
+			for _, f := range findings {
+				r.emit(f)
+			}
+			r.studioDone(dirPath, len(topDirs))
+			return nil
+		}
+		r.folders.start(dirPath)
+		if !r.budget.do(r.ctx, func() { r.processDir(dirPath, 0) }) {
+			r.folders.finish(dirPath, false)
+			return nil
+		}
+		r.folders.finish(dirPath, r.ctx.Err() == nil && !r.failedUnder(dirPath))
+		if r.ctx.Err() == nil {
+			r.studioDone(dirPath, len(topDirs))
+		}
+		return nil
+	})
+	for _, panicErr := range SplitErrors(err) {
+		r.fail(panicErr)
+	}
+	if r.ctx.Err() == nil {
+		r.reportDuplicates()
+	}
+
+	return r.ctx.Err()
+}
+
+// processDir dispatches dirPath, found at the given depth below the library
+// root, to the container or title handler.
+func (r *scanRun) processDir(dirPath string, depth int) {
+	leaf := depth == len(r.layout.Levels)-1
+	if isRecycleBin(filepath.Base(dirPath)) {
+		r.skipRecycleBin(dirPath)
+		return
+	}
+	if rel, err := filepath.Rel(r.library, dirPath); err == nil && !r.filter.allows(rel, leaf) {
+		r.report(FolderSkipped{Library: r.library, Path: dirPath, Reason: SkippedByFilter})
+		return
+	}
+	if r.ignored(dirPath, func() bool { return true }) {
+		r.report(FolderSkipped{Library: r.library, Path: dirPath, Reason: SkippedByIgnoreFile})
+		return
+	}
+	if leaf {
+		r.scanTitleFolder(dirPath)
+		return
+	}
+	r.processContainer(dirPath, depth)
+}
+
+// processContainer handles an intermediate level such as a studio folder:
+// stray files are checked, child folders processed, and the folder itself
+// reported if it has nothing in it.
+func (r *scanRun) processContainer(dirPath string, depth int) {
+	level := r.layout.Levels[depth]
+
+	entries, err := r.fsys.ReadDir(dirPath)
+	if err != nil {
+		r.fail(&ErrUnreadableDir{Path: dirPath, Err: err})
+		return
+	}
+
+	// Check for files directly in this folder (structure violation), unless
+	// it is only visited for the included folders below it
+	selected := true
+	if rel, err := filepath.Rel(r.library, dirPath); err == nil {
+		selected = r.filter.selects(rel)
+	}
+	if selected {
+		r.checkDirectChildren(dirPath, level)
+	}
+	r.auditEntries(dirPath, entries)
+
+	for _, entry := range entries {
+		if r.ctx.Err() != nil {
+			return
+		}
+		if !entry.IsDir() {
+			continue // Files are handled by checkDirectChildren
+		}
+		r.processDir(filepath.Join(dirPath, entry.Name()), depth+1)
+	}
+
+	if selected && len(withoutJunk(entries)) == 0 {
+		r.emit(Finding{Category: CategoryEmptyFolder, Path: dirPath})
+	}
+}
+
+func (r *scanRun) processTitleFolder(titlePath string) {
+	level := r.leafLevel()
+
+	entries, err := r.fsys.ReadDir(titlePath)
+	if err != nil {
+		r.fail(&ErrUnreadableDir{Path: titlePath, Err: err})
+		return
+	}
+
+	// Recycle bins, like ignored entries, are left out of every check and
+	// keep the folder from being deleted, which would take them along
+	entries, hasRecycleBin := r.withoutRecycleBins(titlePath, entries)
+
+	r.auditEntries(titlePath, entries)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			r.auditTree(filepath.Join(titlePath, entry.Name()))
+		}
+	}
+
+	// Ignored entries are left out of every check, and keep the folder from
+	// being deleted as empty or orphaned, which would take them along
+	entries, hasIgnored := r.withoutIgnored(titlePath, entries)
+	hasIgnored = hasIgnored || hasRecycleBin
+	// OS junk is deleted along with the folder whatever it is found to be
+	entries = withoutJunk(entries)
+
+	// Check if folder is empty
+	if len(entries) == 0 {
+		decision := DecisionIgnored
+		if !hasIgnored {
+			r.emit(Finding{Category: CategoryEmptyFolder, Path: titlePath})
+			decision = DecisionEmpty
+		}
+		r.report(TitleScanned{Library: r.library, Path: titlePath, Decision: decision})
+		return
+	}
+
+	// Check for video files and subdirectories
+	hasVideoFile := false
+	hasDisc := false
+	var videoNames []string
+	var unexpectedSubdirs []string
+	var subtitleDirs []string
+	var nfoNames []string
+	var partials []string
+
+	for _, entry := range entries {
+		switch r.classifier.Classify(entry.Name(), entry.IsDir()) {
+		case KindVideo:
+			if r.isVideo(titlePath, entry) {
+				hasVideoFile = true
+				videoNames = append(videoNames, entry.Name())
+				if r.isStub(entry.Name()) {
+					continue
+				}
+				r.recordVideo(titlePath, entry)
+				if r.checkVideoSize(titlePath, entry) {
+					r.probeVideo(titlePath, entry.Name())
+				}
+			} else if entry.Type()&fs.ModeSymlink != 0 {
+				r.checkSymlink(titlePath, entry)
+			}
+		case KindUnexpectedDir:
+			if isSubtitlesDir(entry.Name()) {
+				subtitleDirs = append(subtitleDirs, entry.Name())
+			} else {
+				unexpectedSubdirs = append(unexpectedSubdirs, entry.Name())
+			}
+		case KindMetadata:
+			if strings.EqualFold(filepath.Ext(entry.Name()), ".nfo") {
+				nfoNames = append(nfoNames, entry.Name())
+			}
+			if isPartialDownload(entry.Name()) {
+				partials = append(partials, entry.Name())
+			}
+		case KindMetadataDir:
+			// Known metadata subdirectory (e.g. movie.trickplay). These are
+			// only valid alongside their video, see checkMetadataDirs
+		case KindDiscDir:
+			hasDisc = true
+		}
+	}
+
+	// Warn about unexpected subdirectories in title folder
+	for _, subdir := range unexpectedSubdirs {
+		r.emit(Finding{Category: CategoryStructureWarning, Path: filepath.Join(titlePath, subdir),
+			Message: fmt.Sprintf("Unexpected subdirectory in %s folder", level)})
+	}
+
+	// Subtitle folders are expected, but only as long as their videos exist.
+	// Without any video the whole title is orphaned anyway.
+	if hasVideoFile {
+		for _, subdir := range subtitleDirs {
+			r.checkSubtitles(filepath.Join(titlePath, subdir), videoNames)
+		}
+	}
+
+	if hasVideoFile && (r.nfoYearCheck || r.nfoTitleCheck) {
+		r.checkNFOs(titlePath, nfoNames)
+	}
+
+	if hasVideoFile && r.layout.Episodes {
+		r.checkEpisodeMetadata(titlePath, entries, videoNames)
+	}
+
+	if hasVideoFile && r.missingMetadata {
+		r.checkMissingMetadata(titlePath, entries, videoNames)
+	}
+
+	if hasVideoFile && r.checks(CategoryOrphanedFolder) {
+		r.checkMetadataDirs(titlePath, entries)
+	}
+
+	// Several videos that are not parts, editions or extras of one name
+	// usually mean two titles were merged into one folder
+	if !r.layout.Episodes && !relatedVideoNames(videoNames) {
+		r.emit(Finding{Category: CategoryStructureWarning, Path: titlePath, Severity: SeverityError,
+			Message: fmt.Sprintf("Multiple unrelated videos in %s folder (%s)", level, strings.Join(videoNames, ", "))})
+	}
+
+	// A download still being written keeps its folder, video or not
+	if len(partials) > 0 {
+		r.emit(Finding{Category: CategoryDownloadInProgress, Path: titlePath,
+			Message: fmt.Sprintf("Partial download (%s)", strings.Join(partials, ", "))})
+		if !hasVideoFile && !hasDisc {
+			r.report(TitleScanned{Library: r.library, Path: titlePath, Decision: DecisionInProgress})
+			return
+		}
+	}
+
+	// A title whose only video sits in an extras subfolder has most likely
+	// had its main feature misfiled; it must not be deleted as orphaned
+	if !hasVideoFile && !hasDisc {
+		for _, subdir := range unexpectedSubdirs {
+			if !isExtrasDir(subdir) {
+				continue
+			}
+			if video := r.findVideo(filepath.Join(titlePath, subdir)); video != "" {
+				rel, _ := filepath.Rel(titlePath, video)
+				r.emit(Finding{Category: CategoryMisfiledVideo, Path: titlePath,
+					Message: fmt.Sprintf("Only video is in an extras subfolder (%s)", rel)})
+				r.report(TitleScanned{Library: r.library, Path: titlePath, Decision: DecisionMisfiled})
+				return
+			}
+		}
+	}
+
+	// If no video file but has content (metadata files, subdirs), mark as orphaned
+	decision := DecisionKept
+	switch {
+	case hasDisc:
+		// A disc backup is played as a whole, whatever files it holds
+	case !hasVideoFile && !hasIgnored && !r.ignoredBelow(titlePath, entries):
+		if r.checks(CategoryOrphanedFolder) {
+			r.emit(Finding{Category: CategoryOrphanedFolder, Path: titlePath, Usage: r.treeUsage(titlePath, entries)})
+		}
+		decision = DecisionOrphaned
+	case !hasVideoFile:
+		decision = DecisionIgnored
+	}
+	r.report(TitleScanned{Library: r.library, Path: titlePath, Decision: decision})
+}
+
+// checkMetadataDirs reports the metadata folders of titlePath named after
+// a video that is not there, such as "movie.trickplay" left behind when
+// "movie.mkv" was replaced by "movie-1080p.mkv", as orphaned folders.
+// Folders matched by name rather than suffix belong to the whole title and
+// are left alone, as are episode folders, which checkEpisodeMetadata covers.
+func (r *scanRun) checkMetadataDirs(titlePath string, entries []fs.DirEntry) {
+	names := map[string]bool{}
+	for _, entry := range entries {
+		// Videos that do not count, such as unfollowed symlinks, still keep
+		// their metadata
+		if !entry.IsDir() && r.classifier.Classify(entry.Name(), false) == KindVideo {
+			for _, base := range videoBases(entry.Name()) {
+				names[base] = true
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || r.classifier.Classify(name, true) != KindMetadataDir {
+			continue
+		}
+		if r.layout.Episodes && isEpisodeName(name) {
+			continue
+		}
+		base, ok := r.metadataDirBase(name)
+		if !ok || names[base] {
+			continue
+		}
+		path := filepath.Join(titlePath, name)
+		children, err := r.fsys.ReadDir(path)
+		if err != nil {
+			r.fail(&ErrUnreadableDir{Path: path, Err: err})
+			continue
+		}
+		if r.ignoredBelow(path, children) {
+			continue
+		}
+		r.emit(Finding{Category: CategoryOrphanedFolder, Path: path, Usage: r.treeUsage(path, children)})
+	}
+}
+
+// metadataDirBase returns the video name, in nameKey form, the metadata
+// folder name belongs to, e.g. "movie" for "Movie.trickplay", and false if
+// name does not end with one of the metadata suffixes or is nothing but one.
+func (r *scanRun) metadataDirBase(name string) (string, bool) {
+	lower := nameKey(name)
+	for _, suffix := range r.metadataDirs {
+		if base := strings.TrimSuffix(lower, suffix); base != lower && base != "" {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// findVideo returns the path of the first video or disc backup folder found
+// below dirPath, or "".
+func (r *scanRun) findVideo(dirPath string) string {
+	entries, err := r.fsys.ReadDir(dirPath)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && r.isVideo(dirPath, entry) {
+			return filepath.Join(dirPath, entry.Name())
+		}
+		if entry.IsDir() && r.classifier.Classify(entry.Name(), true) == KindDiscDir {
+			return filepath.Join(dirPath, entry.Name())
+		}
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !isRecycleBin(entry.Name()) {
+			if video := r.findVideo(filepath.Join(dirPath, entry.Name())); video != "" {
+				return video
+			}
+		}
+	}
+	return ""
+}
+
+// isStub reports whether the video file name is a stub linking to a stream.
+func (r *scanRun) isStub(name string) bool {
+	return r.stubExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// isVideo reports whether the file entry in dirPath counts as a video. A
+// symlink only counts when following symlinks and its target is a regular
+// file; a symlink that does not count is treated like any other metadata.
+func (r *scanRun) isVideo(dirPath string, entry fs.DirEntry) bool {
+	if r.classifier.Classify(entry.Name(), false) != KindVideo {
+		return false
+	}
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return true
+	}
+	if !r.followSymlinks {
+		return false
+	}
+	info, err := r.fsys.Stat(filepath.Join(dirPath, entry.Name()))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// checkSymlink reports the symlinked video entry of dirPath if its target
+// does not exist.
+func (r *scanRun) checkSymlink(dirPath string, entry fs.DirEntry) {
+	path := filepath.Join(dirPath, entry.Name())
+	if _, err := r.fsys.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	message := "Symlink target is missing"
+	if links, ok := r.fsys.(linkReader); ok {
+		if target, err := links.Readlink(path); err == nil {
+			message = fmt.Sprintf("Symlink target %s is missing", target)
+		}
+	}
+	r.emit(Finding{Category: CategoryBrokenSymlink, Path: path, Message: message})
+}
+
+// checkVideoSize reports the video entry of dirPath if it is smaller than
+// the minimum size, and returns false then. Symlinked videos are measured
+// through their target.
+func (r *scanRun) checkVideoSize(dirPath string, entry fs.DirEntry) bool {
+	if r.minVideoSize <= 0 {
+		return true
+	}
+	path := filepath.Join(dirPath, entry.Name())
+	info, err := entry.Info()
+	if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		info, err = r.fsys.Stat(path)
+	}
+	if err != nil || info.Size() >= r.minVideoSize {
+		return true
+	}
+	message := "Video file is empty"
+	if info.Size() > 0 {
+		message = fmt.Sprintf("Video file is only %s, below the minimum of %s", FormatBytes(info.Size()), FormatBytes(r.minVideoSize))
+	}
+	r.emit(Finding{Category: CategoryTruncatedVideo, Path: path, Message: message})
+	return false
+}
+
+func (r *scanRun) checkDirectChildren(dirPath string, level string) {
+	entries, err := r.fsys.ReadDir(dirPath)
+	if err != nil {
+		return
+	}
+	leaf := r.leafLevel()
+
+	// First pass: collect all files and check for video files
+	var files []fs.DirEntry
+	videoBasenames := make(map[string]string) // basenames of video files (without extension) to their target
+
+	for _, entry := range entries {
+		if !entry.IsDir() && !isJunk(entry.Name()) {
+			files = append(files, entry)
+
+			if r.isVideo(dirPath, entry) {
+				// Store the basename without extension, and without the
+				// part number for parts of a multi-part video
+				for _, base := range videoBases(entry.Name()) {
+					if _, ok := videoBasenames[base]; !ok {
+						videoBasenames[base] = r.leafTarget(dirPath, level, entry.Name())
+					}
+				}
+			}
+		}
+	}
+
+	// Folders with metadata of their own keep it while a video is left below
+	ownMetadata := false
+	if r.layout.hasMetadata(level) {
+		ownMetadata = r.findVideo(dirPath) != ""
+	}
+
+	// Second pass: categorize files
+	for _, entry := range files {
+		filename := entry.Name()
+		filePath := filepath.Join(dirPath, filename)
+
+		if r.isVideo(dirPath, entry) {
+			// Video file at wrong level - warn, with where it belongs if known
+			basename := strings.TrimSuffix(filename, filepath.Ext(filename))
+			r.emit(Finding{Category: CategoryStructureWarning, Path: filePath, Target: videoBasenames[nameKey(basename)], Severity: SeverityError,
+				Message: fmt.Sprintf("Video file at %s level (should be in %s folder)", level, leaf)})
+		} else {
+			// Non-video file - check if it's orphaned metadata
+			basename := strings.TrimSuffix(filename, filepath.Ext(filename))
+			// Check if there's a video with matching basename prefix
+			// e.g., "movie.nfo" matches "movie.mkv", "movie-poster.jpg" matches "movie.mkv"
+			// The longest match wins, so "movie 2.nfo" follows "movie 2.mkv"
+			hasMatchingVideo := false
+			matched, target := "", ""
+			for videoBase, videoTarget := range videoBasenames {
+				if strings.HasPrefix(nameKey(basename), videoBase) && (!hasMatchingVideo || len(videoBase) > len(matched)) {
+					hasMatchingVideo = true
+					matched, target = videoBase, videoTarget
+				}
+			}
+
+			if hasMatchingVideo {
+				// Metadata file with matching video - it moves along with it
+				r.emit(Finding{Category: CategoryStructureWarning, Path: filePath, Target: target,
+					Message: fmt.Sprintf("Metadata file at %s level (should be in %s folder)", level, leaf)})
+			} else if ownMetadata {
+				// The folder's own metadata, e.g. tvshow.nfo or poster.jpg
+				continue
+			} else if isPartialDownload(filename) {
+				r.emit(Finding{Category: CategoryDownloadInProgress, Path: filePath, Message: "Partial download"})
+			} else {
+				// Orphaned metadata file - no matching video
+				var usage Usage
+				if info, err := entry.Info(); err == nil {
+					usage = fileUsage(filePath, info)
+				}
+				r.emit(Finding{Category: CategoryOrphanedFile, Path: filePath, Usage: usage})
+			}
+		}
+	}
+}
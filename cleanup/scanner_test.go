@@ -0,0 +1,673 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for Scanner options
+// ============================================================================
+
+func TestNewScanner_Defaults(t *testing.T) {
+	s := NewScanner()
+
+	if s.workers != 10 {
+		t.Errorf("Expected 10 workers by default, got %d", s.workers)
+	}
+	if s.layout.Name != MovieLayout.Name {
+		t.Errorf("Expected default layout %q, got %q", MovieLayout.Name, s.layout.Name)
+	}
+	if s.classifier.Classify("movie.mkv", false) != KindVideo {
+		t.Error("Default classifier should recognize .mkv as video")
+	}
+}
+
+func TestWithExtensions_ReplacesDefaults(t *testing.T) {
+	s := NewScanner(WithExtensions("ts", ".WEBM"))
+
+	tests := []struct {
+		name     string
+		expected EntryKind
+	}{
+		{"movie.ts", KindVideo},
+		{"movie.webm", KindVideo},
+		{"movie.WebM", KindVideo},
+		{"movie.mkv", KindMetadata}, // Defaults are replaced, not extended
+		{"movie.nfo", KindMetadata},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := s.classifier.Classify(tc.name, false); got != tc.expected {
+				t.Errorf("Classify(%q) = %v, want %v", tc.name, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWithMetadataDirs_ReplacesDefaults(t *testing.T) {
+	s := NewScanner(WithMetadataDirs(".BIF", "-extrafanart"))
+
+	tests := []struct {
+		name     string
+		expected EntryKind
+	}{
+		{"movie.bif", KindMetadataDir},
+		{"movie-extrafanart", KindMetadataDir},
+		{"movie.trickplay", KindUnexpectedDir}, // Defaults are replaced, not extended
+	}
+
+	for _, tc := range tests {
+		if got := s.classifier.Classify(tc.name, true); got != tc.expected {
+			t.Errorf("Classify(%q) = %v, want %v", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestMetadataDirNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		scanner  *Scanner
+		dir      string
+		expected EntryKind
+	}{
+		{"default name", NewScanner(), "extrafanart", KindMetadataDir},
+		{"default hidden name", NewScanner(), ".actors", KindMetadataDir},
+		{"case-insensitive", NewScanner(), "Backdrops", KindMetadataDir},
+		{"exact names only", NewScanner(), "old-extrafanart", KindUnexpectedDir},
+		{"replaced names", NewScanner(WithMetadataDirNames("Posters")), "posters", KindMetadataDir},
+		{"defaults replaced", NewScanner(WithMetadataDirNames("Posters")), "extrafanart", KindUnexpectedDir},
+		{"suffixes kept", NewScanner(WithMetadataDirNames("Posters")), "movie.trickplay", KindMetadataDir},
+		{"Blu-ray folder", NewScanner(), "BDMV", KindDiscDir},
+		{"DVD folder", NewScanner(WithMetadataDirNames("Posters")), "video_ts", KindDiscDir},
+	}
+
+	for _, tc := range tests {
+		if got := tc.scanner.classifier.Classify(tc.dir, true); got != tc.expected {
+			t.Errorf("%s: Classify(%q) = %v, want %v", tc.name, tc.dir, got, tc.expected)
+		}
+	}
+}
+
+func TestWithStubExtensions(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Streamed (2001)").File("Streamed (2001).strm", []byte("http://nas/stream/1")).Metadata("Streamed (2001).nfo").
+		Title("Linked (2002)").File("Linked (2002).STRM", []byte("http://nas/stream/1")).
+		MapFS()
+
+	prober := fakeProber{problems: map[string]string{"Streamed (2001).strm": "Zero or unknown duration"}}
+	result := &CleanupResult{}
+	scanner := NewScanner(WithFS(IOFS(fsys)), WithMinVideoSize(1024), WithVideoProbe(prober), WithDuplicateDetection(true))
+	if err := scanner.Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	// Neither truncated, corrupt nor duplicates of each other
+	if len(result.Findings) != 0 {
+		t.Errorf("Expected stubs to keep their titles without any finding, got %v", result.Findings)
+	}
+
+	result = &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithStubExtensions()).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.OrphanedFolders) != 2 {
+		t.Errorf("Expected both titles orphaned without stub extensions, got %v", result.OrphanedFolders)
+	}
+}
+
+func TestWithClassifier_OverridesExtensions(t *testing.T) {
+	custom := ClassifierFunc(func(name string, isDir bool) EntryKind {
+		if isDir {
+			return KindMetadataDir
+		}
+		if filepath.Ext(name) == ".iso" {
+			return KindVideo
+		}
+		return KindMetadata
+	})
+
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	titleDir := filepath.Join(tempDir, "title")
+	createFile(t, filepath.Join(titleDir, "movie.iso"))
+	createDir(t, filepath.Join(titleDir, "extras"))
+
+	result := &CleanupResult{}
+	newTestRun(NewScanner(WithExtensions(".mkv"), WithClassifier(custom)), result).processTitleFolder(titleDir)
+
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Custom classifier should recognize .iso as video, got %d orphaned", len(result.OrphanedFolders))
+	}
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Custom classifier accepts all subdirectories, got %d warnings: %v",
+			len(result.StructureWarnings), result.StructureWarnings)
+	}
+}
+
+func TestWithLayout_SingleLevel(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	createFile(t, filepath.Join(libraryDir, "Movie1", "movie.mkv"))
+	createFile(t, filepath.Join(libraryDir, "Movie2", "movie.nfo"))
+	createDir(t, filepath.Join(libraryDir, "Movie3"))
+
+	result := &CleanupResult{}
+	s := NewScanner(WithLayout(Layout{Name: "flat", Levels: []string{"title"}}), WithWorkers(2))
+	if err := s.Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %d: %v", len(result.OrphanedFolders), result.OrphanedFolders)
+	}
+	if len(result.EmptyFolders) != 1 {
+		t.Errorf("Expected 1 empty folder, got %d: %v", len(result.EmptyFolders), result.EmptyFolders)
+	}
+}
+
+func TestWithLayout_ThreeLevels(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	createFile(t, filepath.Join(libraryDir, "Studio", "Collection", "Movie1", "movie.mkv"))
+	createFile(t, filepath.Join(libraryDir, "Studio", "Collection", "Movie2", "poster.jpg"))
+	createFile(t, filepath.Join(libraryDir, "Studio", "Collection", "stray.nfo"))
+	createDir(t, filepath.Join(libraryDir, "Studio", "EmptyCollection"))
+
+	result := &CleanupResult{}
+	layout := Layout{Name: "collections", Levels: []string{"studio", "collection", "title"}}
+	if err := NewScanner(WithLayout(layout)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %d: %v", len(result.OrphanedFolders), result.OrphanedFolders)
+	}
+	if len(result.OrphanedFiles) != 1 {
+		t.Errorf("Expected 1 orphaned file at collection level, got %d: %v", len(result.OrphanedFiles), result.OrphanedFiles)
+	}
+	if len(result.EmptyFolders) != 1 {
+		t.Errorf("Expected 1 empty collection folder, got %d: %v", len(result.EmptyFolders), result.EmptyFolders)
+	}
+}
+
+func TestWithLayout_NoLevels(t *testing.T) {
+	result := &CleanupResult{}
+	err := NewScanner(WithLayout(Layout{Name: "broken"})).Scan(context.Background(), ".", result.Add)
+	if err == nil {
+		t.Error("Expected an error for a layout without levels")
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	layout, err := ParseLayout("{library}/{studio}/{collection}/{title}/")
+	if err != nil {
+		t.Fatalf("ParseLayout returned error: %v", err)
+	}
+	if expected := []string{"studio", "collection", "title"}; !reflect.DeepEqual(layout.Levels, expected) {
+		t.Errorf("Expected levels %v, got %v", expected, layout.Levels)
+	}
+
+	for _, template := range []string{
+		"{library}",
+		"{studio}/{title}",
+		"{library}/{title}/{library}",
+		"{library}/{title}/{title}",
+		"{library}/studio/{title}",
+		"{library}/{Title}",
+	} {
+		if _, err := ParseLayout(template); err == nil {
+			t.Errorf("Expected an error for %q", template)
+		}
+	}
+}
+
+func TestScan_ParsedLayoutWarnings(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Collection").Video("stray.mkv").
+		MapFS()
+
+	layout, err := ParseLayout("{library}/{studio}/{collection}/{film}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithLayout(layout)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	expected := "Video file at collection level (should be in film folder): Studio/Collection/stray.mkv"
+	if len(result.StructureWarnings) != 1 || result.StructureWarnings[0] != expected {
+		t.Errorf("Expected only %q, got %v", expected, result.StructureWarnings)
+	}
+}
+
+func TestWithFS_MapFS(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Movie1").Video("movie.mkv").Metadata("movie-1.nfo").Dir("movie.trickplay", "1.jpg").
+		Title("Movie2").Metadata("movie.nfo").
+		Title("Movie3").
+		Up().Metadata("orphan-poster.jpg").
+		Studio("EmptyStudio").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %d: %v", len(result.OrphanedFolders), result.OrphanedFolders)
+	}
+	if len(result.OrphanedFiles) != 1 {
+		t.Errorf("Expected 1 orphaned file, got %d: %v", len(result.OrphanedFiles), result.OrphanedFiles)
+	}
+	if len(result.EmptyFolders) != 2 {
+		t.Errorf("Expected 2 empty folders, got %d: %v", len(result.EmptyFolders), result.EmptyFolders)
+	}
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings, got %d: %v", len(result.StructureWarnings), result.StructureWarnings)
+	}
+}
+
+// symlinkLibrary builds a library whose titles hold only symlinks into a
+// download pool: one valid, one dangling.
+func symlinkLibrary(t *testing.T, tempDir string) string {
+	t.Helper()
+	pool := filepath.Join(tempDir, "pool")
+	createFile(t, filepath.Join(pool, "movie.mkv"))
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	valid := filepath.Join(libraryDir, "Studio", "Linked")
+	dangling := filepath.Join(libraryDir, "Studio", "Dangling")
+	createDir(t, valid)
+	createDir(t, dangling)
+	if err := os.Symlink(filepath.Join(pool, "movie.mkv"), filepath.Join(valid, "movie.mkv")); err != nil {
+		t.Skipf("symlinks not supported here: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(pool, "gone.mkv"), filepath.Join(dangling, "gone.mkv")); err != nil {
+		t.Fatal(err)
+	}
+	return libraryDir
+}
+
+func TestWithFollowSymlinks_Disabled(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	libraryDir := symlinkLibrary(t, tempDir)
+
+	result := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.OrphanedFolders) != 2 {
+		t.Errorf("Expected symlinked videos not to count by default, got %d orphaned: %v", len(result.OrphanedFolders), result.OrphanedFolders)
+	}
+}
+
+func TestWithFollowSymlinks_Enabled(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	libraryDir := symlinkLibrary(t, tempDir)
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFollowSymlinks(true)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := filepath.Join(libraryDir, "Studio", "Dangling")
+	if len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] != expected {
+		t.Errorf("Expected only the dangling symlink's folder orphaned, got %v", result.OrphanedFolders)
+	}
+}
+
+func TestScan_BrokenSymlinks(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	libraryDir := symlinkLibrary(t, tempDir)
+
+	for _, follow := range []bool{false, true} {
+		result := &CleanupResult{}
+		if err := NewScanner(WithFollowSymlinks(follow)).Scan(context.Background(), libraryDir, result.Add); err != nil {
+			t.Fatalf("Scan returned error: %v", err)
+		}
+
+		broken := result.ByCategory(CategoryBrokenSymlink)
+		expected := filepath.Join(libraryDir, "Studio", "Dangling", "gone.mkv")
+		if len(broken) != 1 || broken[0].Path != expected {
+			t.Errorf("follow %v: expected %s as the only broken symlink, got %v", follow, expected, broken)
+			continue
+		}
+		if !strings.Contains(broken[0].Message, filepath.Join(tempDir, "pool", "gone.mkv")) {
+			t.Errorf("Expected the message to name the missing target, got %q", broken[0].Message)
+		}
+	}
+}
+
+func TestScan_VideoOnlyInExtrasIsMisfiled(t *testing.T) {
+	libraryDir := cleanuptest.New().
+		Studio("Studio").
+		Title("Misfiled (2020)").Metadata("movie.nfo").Dir("extras", "movie.mkv").
+		Title("Nested (2021)").Metadata("movie.nfo").Dir("Featurettes/part", "clip.mp4").
+		Title("Other Subdir (2022)").Metadata("movie.nfo").Dir("random", "movie.mkv").
+		Title("Has Video (2023)").Video("movie.mkv").Dir("extras", "trailer.mp4").
+		TempDir(t)
+
+	result := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	misfiled := result.ByCategory(CategoryMisfiledVideo)
+	if len(misfiled) != 2 {
+		t.Fatalf("Expected 2 misfiled videos, got %d: %v", len(misfiled), misfiled)
+	}
+	paths := map[string]bool{}
+	for _, f := range misfiled {
+		paths[f.Path] = true
+	}
+	for _, title := range []string{"Misfiled (2020)", "Nested (2021)"} {
+		if !paths[filepath.Join(libraryDir, "Studio", title)] {
+			t.Errorf("Expected %s to be misfiled, got %v", title, misfiled)
+		}
+	}
+
+	// A video in a subfolder that is not a recognized extras folder leaves
+	// the title orphaned as before
+	expected := filepath.Join(libraryDir, "Studio", "Other Subdir (2022)")
+	if len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] != expected {
+		t.Errorf("Expected only %s orphaned, got %v", expected, result.OrphanedFolders)
+	}
+}
+
+func TestScanTitle(t *testing.T) {
+	libraryDir := cleanuptest.New().
+		Studio("Studio").
+		Title("Orphan (2020)").Metadata("movie.nfo").
+		Title("Neighbour (2021)").Metadata("movie.nfo").
+		TempDir(t)
+	titlePath := filepath.Join(libraryDir, "Studio", "Orphan (2020)")
+
+	scanner := NewScanner()
+	if got := scanner.TitleLibrary(titlePath); got != libraryDir {
+		t.Errorf("Expected library %s, got %s", libraryDir, got)
+	}
+
+	result := &CleanupResult{}
+	if err := scanner.ScanTitle(context.Background(), titlePath, result.Add); err != nil {
+		t.Fatalf("ScanTitle returned error: %v", err)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Path != titlePath || result.Findings[0].Library != libraryDir {
+		t.Errorf("Expected only the checked title orphaned, got %+v", result.Findings)
+	}
+
+	err := scanner.ScanTitle(context.Background(), filepath.Join(titlePath, "movie.nfo"), result.Add)
+	if !errors.Is(err, ErrNotADirectory) {
+		t.Errorf("Expected ErrNotADirectory for a file, got %v", err)
+	}
+}
+
+// ============================================================================
+// Tests for Scanner.Scan callback
+// ============================================================================
+
+func TestScan_CallbackReceivesFindings(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	createFile(t, filepath.Join(libraryDir, "Studio", "Orphaned", "movie.nfo"))
+	createFile(t, filepath.Join(libraryDir, "Studio", "Movie", "movie.mkv"))
+	createDir(t, filepath.Join(libraryDir, "Studio", "Empty"))
+	createDir(t, filepath.Join(libraryDir, "Studio", "Movie", "extras"))
+
+	counts := make(map[Category]int)
+	err := NewScanner().Scan(context.Background(), libraryDir, func(f Finding) error {
+		counts[f.Category]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if counts[CategoryOrphanedFolder] != 1 {
+		t.Errorf("Expected 1 orphaned folder finding, got %d", counts[CategoryOrphanedFolder])
+	}
+	if counts[CategoryEmptyFolder] != 1 {
+		t.Errorf("Expected 1 empty folder finding, got %d", counts[CategoryEmptyFolder])
+	}
+	if counts[CategoryStructureWarning] != 1 {
+		t.Errorf("Expected 1 structure warning finding, got %d", counts[CategoryStructureWarning])
+	}
+}
+
+func TestScan_CallbackErrorStopsScan(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	for i := 0; i < 20; i++ {
+		createDir(t, filepath.Join(libraryDir, "Studio"+string(rune('A'+i)), "Empty"))
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err := NewScanner(WithWorkers(1)).Scan(context.Background(), libraryDir, func(f Finding) error {
+		calls++
+		return errStop
+	})
+
+	if !errors.Is(err, errStop) {
+		t.Fatalf("Expected callback error to be returned, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected callback to be called once before stopping, got %d", calls)
+	}
+}
+
+func TestScan_CallbackNotConcurrent(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 5; j++ {
+			createDir(t, filepath.Join(libraryDir, "Studio"+string(rune('A'+i)), "Empty"+string(rune('0'+j))))
+		}
+	}
+
+	// Unsynchronized counter: the race detector flags concurrent callbacks
+	count := 0
+	err := NewScanner(WithWorkers(8)).Scan(context.Background(), libraryDir, func(f Finding) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if count != 100 {
+		t.Errorf("Expected 100 findings, got %d", count)
+	}
+}
+
+func TestFinding_String(t *testing.T) {
+	plain := Finding{Category: CategoryOrphanedFolder, Path: "/lib/Studio/Movie"}
+	if plain.String() != "/lib/Studio/Movie" {
+		t.Errorf("Unexpected String() for finding without message: %q", plain.String())
+	}
+
+	warning := Finding{Category: CategoryStructureWarning, Path: "/lib/x.mkv", Message: "Video file at library level"}
+	if warning.String() != "Video file at library level: /lib/x.mkv" {
+		t.Errorf("Unexpected String() for warning: %q", warning.String())
+	}
+}
+
+// ============================================================================
+// Tests for structured errors
+// ============================================================================
+
+// failingFS wraps osFS and fails to list the given directories
+type failingFS struct {
+	osFS
+	fail map[string]bool
+}
+
+func (f failingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if f.fail[name] {
+		return nil, fs.ErrPermission
+	}
+	return f.osFS.ReadDir(name)
+}
+
+func TestScan_UnreadableSubdirectoriesAreCollected(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	libraryDir := filepath.Join(tempDir, "Library")
+	lockedStudio := filepath.Join(libraryDir, "Locked Studio")
+	lockedTitle := filepath.Join(libraryDir, "Studio", "Locked Movie")
+	createFile(t, filepath.Join(lockedStudio, "Movie", "movie.mkv"))
+	createFile(t, filepath.Join(lockedTitle, "movie.mkv"))
+	createFile(t, filepath.Join(libraryDir, "Studio", "Orphaned", "movie.nfo"))
+
+	fsys := failingFS{fail: map[string]bool{lockedStudio: true, lockedTitle: true}}
+	result := &CleanupResult{}
+	err := NewScanner(WithFS(fsys)).Scan(context.Background(), libraryDir, result.Add)
+
+	errs := SplitErrors(err)
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	for _, e := range errs {
+		var unreadable *ErrUnreadableDir
+		if !errors.As(e, &unreadable) {
+			t.Errorf("Expected *ErrUnreadableDir, got %T: %v", e, e)
+			continue
+		}
+		if unreadable.Path != lockedStudio && unreadable.Path != lockedTitle {
+			t.Errorf("Unexpected unreadable path %s", unreadable.Path)
+		}
+		if !errors.Is(e, fs.ErrPermission) {
+			t.Errorf("Expected error to wrap fs.ErrPermission, got %v", e)
+		}
+	}
+
+	// The rest of the library is still scanned
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %d", len(result.OrphanedFolders))
+	}
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Unreadable folders should not be reported as warnings, got %v", result.StructureWarnings)
+	}
+}
+
+func TestDeletionError(t *testing.T) {
+	err := error(&DeletionError{Path: "/lib/Studio/Movie", Cause: fs.ErrPermission})
+
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Error("DeletionError should unwrap to its cause")
+	}
+	var deletionErr *DeletionError
+	if !errors.As(err, &deletionErr) || deletionErr.Path != "/lib/Studio/Movie" {
+		t.Errorf("errors.As should expose the failed path, got %v", deletionErr)
+	}
+}
+
+func TestScan_AttributesFindingsToLibrary(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	movies := filepath.Join(tempDir, "Movies")
+	shows := filepath.Join(tempDir, "Shows")
+	createFile(t, filepath.Join(movies, "Studio", "Orphan", "movie.nfo"))
+	createDir(t, filepath.Join(shows, "Network", "Empty"))
+
+	s := NewScanner()
+	moviesResult := &CleanupResult{Libraries: []string{movies}}
+	showsResult := &CleanupResult{Libraries: []string{shows}}
+	if err := s.Scan(context.Background(), movies, moviesResult.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if err := s.Scan(context.Background(), shows, showsResult.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	merged := MergeResults(moviesResult, showsResult)
+	for _, f := range merged.Findings {
+		if (f.Path == filepath.Join(movies, "Studio", "Orphan") && f.Library != movies) ||
+			(f.Path == filepath.Join(shows, "Network", "Empty") && f.Library != shows) {
+			t.Errorf("Finding %s attributed to wrong library %q", f.Path, f.Library)
+		}
+	}
+	if len(merged.ForLibrary(movies).OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder in %s", movies)
+	}
+	if len(merged.ForLibrary(shows).EmptyFolders) != 1 {
+		t.Errorf("Expected 1 empty folder in %s", shows)
+	}
+}
+
+func TestScanAll(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	movies := filepath.Join(tempDir, "Movies")
+	kids := filepath.Join(tempDir, "Kids")
+	missing := filepath.Join(tempDir, "Missing")
+	createFile(t, filepath.Join(movies, "Studio", "Orphan", "movie.nfo"))
+	createFile(t, filepath.Join(movies, "Studio", "Kept", "movie.mkv"))
+	createDir(t, filepath.Join(kids, "Studio", "Empty"))
+
+	s := NewScanner(WithWorkerBudget(NewWorkerBudget(2)))
+	result, err := s.ScanAll(context.Background(), []string{movies, missing, kids})
+	if err != nil {
+		t.Fatalf("ScanAll returned error: %v", err)
+	}
+
+	expected := []string{movies, missing, kids}
+	if len(result.Libraries) != len(expected) {
+		t.Fatalf("Expected libraries %v, got %v", expected, result.Libraries)
+	}
+	for i := range expected {
+		if result.Libraries[i] != expected[i] {
+			t.Errorf("Expected libraries %v in order, got %v", expected, result.Libraries)
+			break
+		}
+	}
+	if len(result.ForLibrary(movies).OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder in %s, got %v", movies, result.OrphanedFolders)
+	}
+	if len(result.ForLibrary(kids).EmptyFolders) != 1 {
+		t.Errorf("Expected 1 empty folder in %s, got %v", kids, result.EmptyFolders)
+	}
+	var unreadable *ErrUnreadableDir
+	if len(result.Errors) != 1 || !errors.As(result.Errors[0], &unreadable) || unreadable.Path != missing {
+		t.Errorf("Expected the missing library as the only error, got %v", result.Errors)
+	}
+}
+
+func TestScanAll_Cancelled(t *testing.T) {
+	fsys := cleanuptest.New().Studio("Studio").Title("Orphan").Metadata("movie.nfo").MapFS()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewScanner(WithFS(IOFS(fsys))).ScanAll(ctx, []string{"."}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package cleanup
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WithSMB tunes the scan for a library on a mounted SMB/CIFS share, where
+// every directory listing and stat is a network round trip. Each folder is
+// then listed once per scan and the attributes of its entries are fetched
+// at most once, so the many checks that revisit a title folder cost
+// nothing; errors typical of a flaky share (connection reset, timeout,
+// stale handle) are retried a few times before the folder is given up on.
+func WithSMB(smb bool) Option {
+	return func(s *Scanner) {
+		s.smb = smb
+	}
+}
+
+// smbRetries is how many times an operation failing with a transient
+// network error is retried, smbBackoff the delay before the first retry,
+// doubled for each further one.
+const (
+	smbRetries = 3
+	smbBackoff = 200 * time.Millisecond
+)
+
+// smbFS caches the listings of one scan in front of fsys. Listed entries
+// answer Stat for their path, unless they are symlinks, which Stat follows.
+type smbFS struct {
+	fsys FS
+
+	mu      sync.Mutex
+	dirs    map[string][]fs.DirEntry
+	entries map[string]*smbEntry // by path, from the listing of its folder
+	stats   map[string]fs.FileInfo
+}
+
+func newSMBFS(fsys FS) *smbFS {
+	return &smbFS{
+		fsys:    fsys,
+		dirs:    map[string][]fs.DirEntry{},
+		entries: map[string]*smbEntry{},
+		stats:   map[string]fs.FileInfo{},
+	}
+}
+
+// smbEntry is a listed entry whose Info is fetched on first use only.
+type smbEntry struct {
+	fs.DirEntry
+	once sync.Once
+	info fs.FileInfo
+	err  error
+}
+
+func (e *smbEntry) Info() (fs.FileInfo, error) {
+	e.once.Do(func() {
+		e.err = retryTransient(func() error {
+			var err error
+			e.info, err = e.DirEntry.Info()
+			return err
+		})
+	})
+	return e.info, e.err
+}
+
+func (c *smbFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.mu.Lock()
+	cached, ok := c.dirs[name]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var listed []fs.DirEntry
+	err := retryTransient(func() error {
+		var err error
+		listed, err = c.fsys.ReadDir(name)
+		return err
+	})
+	if err != nil {
+		return listed, err
+	}
+	entries := make([]fs.DirEntry, len(listed))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, entry := range listed {
+		e := &smbEntry{DirEntry: entry}
+		entries[i] = e
+		c.entries[filepath.Join(name, entry.Name())] = e
+	}
+	c.dirs[name] = entries
+	return entries, nil
+}
+
+func (c *smbFS) Stat(name string) (fs.FileInfo, error) {
+	c.mu.Lock()
+	entry := c.entries[name]
+	info, ok := c.stats[name]
+	c.mu.Unlock()
+	if ok {
+		return info, nil
+	}
+	if entry != nil && entry.Type()&fs.ModeSymlink == 0 {
+		return entry.Info()
+	}
+
+	err := retryTransient(func() error {
+		var err error
+		info, err = c.fsys.Stat(name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.stats[name] = info
+	c.mu.Unlock()
+	return info, nil
+}
+
+func (c *smbFS) ReadFile(name string) ([]byte, error) {
+	var data []byte
+	err := retryTransient(func() error {
+		var err error
+		data, err = c.fsys.ReadFile(name)
+		return err
+	})
+	return data, err
+}
+
+func (c *smbFS) Open(name string) (fs.File, error) {
+	opener, ok := c.fsys.(fileOpener)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	var file fs.File
+	err := retryTransient(func() error {
+		var err error
+		file, err = opener.Open(name)
+		return err
+	})
+	return file, err
+}
+
+func (c *smbFS) Readlink(name string) (string, error) {
+	links, ok := c.fsys.(linkReader)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	return links.Readlink(name)
+}
+
+// retryTransient runs op until it succeeds, fails with an error that is not
+// a transient network error, or has been retried smbRetries times.
+func retryTransient(op func() error) error {
+	delay := smbBackoff
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt == smbRetries || !isTransientNetError(err) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,89 @@
+package cleanup
+
+import (
+	"context"
+	"io/fs"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// countingFS counts the listings of each folder and can fail the first ones
+// with a dropped connection.
+type countingFS struct {
+	FS
+	mu       sync.Mutex
+	listings map[string]int
+	drops    int
+}
+
+func (c *countingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.mu.Lock()
+	c.listings[name]++
+	drop := c.drops > 0
+	if drop {
+		c.drops--
+	}
+	c.mu.Unlock()
+	if drop {
+		return nil, &fs.PathError{Op: "readdirent", Path: name, Err: syscall.ECONNRESET}
+	}
+	return c.FS.ReadDir(name)
+}
+
+// ============================================================================
+// Tests for WithSMB
+// ============================================================================
+
+func TestScan_SMBListsEachFolderOnce(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Movie (2001)").Video("movie.mkv").Metadata("movie.nfo").
+		Title("Orphan (2002)").Metadata("movie.nfo").
+		Title("Empty (2003)").
+		MapFS()
+
+	scan := func(smb bool) (*CleanupResult, *countingFS) {
+		counting := &countingFS{FS: IOFS(fsys), listings: map[string]int{}}
+		result := &CleanupResult{}
+		err := NewScanner(WithFS(counting), WithSMB(smb), WithMinAge(24*time.Hour)).Scan(context.Background(), ".", result.Add)
+		if err != nil {
+			t.Fatalf("Scan returned error: %v", err)
+		}
+		return result, counting
+	}
+	plain, _ := scan(false)
+	cached, counting := scan(true)
+
+	if len(cached.Findings) != len(plain.Findings) {
+		t.Errorf("Expected the same findings as without WithSMB, got %v and %v", cached.Findings, plain.Findings)
+	}
+	for path, n := range counting.listings {
+		if n != 1 {
+			t.Errorf("Expected %s to be listed once, got %d", path, n)
+		}
+	}
+}
+
+func TestScan_SMBRetriesDroppedConnection(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ECONNRESET is not a Windows share error")
+	}
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Orphan (2002)").Metadata("movie.nfo").
+		MapFS()
+
+	counting := &countingFS{FS: IOFS(fsys), listings: map[string]int{}, drops: 1}
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(counting), WithSMB(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Expected the dropped listing to be retried, got %v", err)
+	}
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected 1 orphaned folder, got %v", result.Findings)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package cleanup
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isTransientNetError reports whether err is one a network share recovers
+// from, such as a dropped connection the client reconnects.
+func isTransientNetError(err error) bool {
+	for _, errno := range []syscall.Errno{
+		syscall.EAGAIN, syscall.EINTR, syscall.ETIMEDOUT, syscall.ECONNRESET,
+		syscall.ECONNABORTED, syscall.EHOSTDOWN, syscall.EHOSTUNREACH, syscall.ENETRESET, syscall.ESTALE,
+	} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
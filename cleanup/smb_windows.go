@@ -0,0 +1,24 @@
+package cleanup
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Windows errors of a share whose connection dropped or timed out.
+const (
+	errorUnexpNetErr    syscall.Errno = 59  // ERROR_UNEXP_NET_ERR
+	errorNetnameDeleted syscall.Errno = 64  // ERROR_NETNAME_DELETED
+	errorSemTimeout     syscall.Errno = 121 // ERROR_SEM_TIMEOUT
+)
+
+// isTransientNetError reports whether err is one a network share recovers
+// from, such as a dropped connection the client reconnects.
+func isTransientNetError(err error) bool {
+	for _, errno := range []syscall.Errno{errorUnexpNetErr, errorNetnameDeleted, errorSemTimeout} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
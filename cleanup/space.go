@@ -0,0 +1,199 @@
+package cleanup
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Usage is the disk space held by a finding.
+type Usage struct {
+	Bytes       int64 `json:"bytes,omitempty"`             // apparent size of every file
+	Reclaimable int64 `json:"reclaimable_bytes,omitempty"` // bytes actually freed by deleting it
+	Hardlinked  int   `json:"hardlinked,omitempty"`        // files with other hard links, which free nothing
+
+	// links identifies the hardlinked files counted above, so results can
+	// tell when every link to a file is being deleted. A pointer keeps
+	// Finding comparable; it is not serialized.
+	links *linkSet
+}
+
+// fileID identifies a file across hard links: the same device and inode is
+// the same data, whatever the path.
+type fileID struct {
+	dev, ino uint64
+}
+
+// hardlink is one path to a file with more than one link.
+type hardlink struct {
+	id    fileID
+	links uint64
+	size  int64
+	path  string
+}
+
+type linkSet struct {
+	files []hardlink
+}
+
+func (u *Usage) add(other Usage) {
+	u.Bytes += other.Bytes
+	u.Reclaimable += other.Reclaimable
+	u.Hardlinked += other.Hardlinked
+	if other.links != nil {
+		merged := &linkSet{}
+		if u.links != nil {
+			merged.files = append(merged.files, u.links.files...)
+		}
+		merged.files = append(merged.files, other.links.files...)
+		u.links = merged
+	}
+}
+
+// isHardlinked reports whether info's file has other hard links, i.e.
+// deleting it keeps the data on disk.
+func isHardlinked(info fs.FileInfo) bool {
+	links, ok := linkCount(info)
+	return ok && !info.IsDir() && links > 1
+}
+
+// fileUsage is the usage of the file at path.
+func fileUsage(path string, info fs.FileInfo) Usage {
+	if !info.Mode().IsRegular() {
+		return Usage{}
+	}
+	u := Usage{Bytes: info.Size()}
+	if !isHardlinked(info) {
+		u.Reclaimable = info.Size()
+		return u
+	}
+	u.Hardlinked = 1
+	if id, ok := fileIDOf(info); ok {
+		links, _ := linkCount(info)
+		u.links = &linkSet{files: []hardlink{{id: id, links: links, size: info.Size(), path: path}}}
+	}
+	return u
+}
+
+// treeUsage is the usage of everything in dirPath, whose entries have
+// already been listed.
+func (r *scanRun) treeUsage(dirPath string, entries []fs.DirEntry) Usage {
+	var u Usage
+	for _, entry := range entries {
+		if entry.IsDir() {
+			sub := filepath.Join(dirPath, entry.Name())
+			subEntries, err := r.fsys.ReadDir(sub)
+			if err != nil {
+				continue // Size is informational; the scan itself already reports unreadable folders
+			}
+			u.add(r.treeUsage(sub, subEntries))
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			u.add(fileUsage(filepath.Join(dirPath, entry.Name()), info))
+		}
+	}
+	return u
+}
+
+// Usage sums the usage of every finding in r, counting each hardlinked file
+// once by device and inode. Such a file is reclaimable after all when every
+// one of its links is among the findings, e.g. two copies in the same
+// orphaned folder.
+func (r *CleanupResult) Usage() Usage {
+	type inode struct {
+		size  int64
+		links uint64
+		paths map[string]bool
+	}
+	inodes := map[fileID]*inode{}
+
+	var u Usage
+	for _, f := range r.Findings {
+		if f.Category == CategoryRecycleBin || f.Category == CategoryDuplicateVideo {
+			continue // Never deleted, so nothing is reclaimed
+		}
+		if f.links == nil {
+			// No identities, e.g. read back from JSON: take it as reported
+			u.Bytes += f.Bytes
+			u.Reclaimable += f.Reclaimable
+			u.Hardlinked += f.Hardlinked
+			continue
+		}
+		var linkedBytes int64
+		for _, h := range f.links.files {
+			linkedBytes += h.size
+			in := inodes[h.id]
+			if in == nil {
+				in = &inode{size: h.size, links: h.links, paths: map[string]bool{}}
+				inodes[h.id] = in
+			}
+			in.paths[h.path] = true
+		}
+		u.Bytes += f.Bytes - linkedBytes
+		u.Reclaimable += f.Reclaimable
+		u.Hardlinked += f.Hardlinked - len(f.links.files)
+	}
+
+	for _, in := range inodes {
+		u.Bytes += in.size
+		if uint64(len(in.paths)) >= in.links {
+			u.Reclaimable += in.size
+		} else {
+			u.Hardlinked++
+		}
+	}
+	return u
+}
+
+// ParseBytes parses a size such as "512", "100M", "1.5GiB" or "700MB".
+// Units are binary, like FormatBytes: K is 1024 bytes, M 1024 K, and so on.
+func ParseBytes(s string) (int64, error) {
+	number := strings.TrimSpace(s)
+	for _, suffix := range []string{"iB", "B"} {
+		if trimmed, ok := strings.CutSuffix(number, suffix); ok {
+			number = trimmed
+			break
+		}
+	}
+	multiplier := int64(1)
+	if n := len(number); n > 0 {
+		if exp := strings.IndexByte("KMGTPE", number[n-1]&^0x20); exp >= 0 {
+			number = number[:n-1]
+			for ; exp >= 0; exp-- {
+				multiplier *= 1024
+			}
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// CategoryUsage is the Usage of the findings of r in category c, with each
+// hardlinked file counted once as in Usage.
+func (r *CleanupResult) CategoryUsage(c Category) Usage {
+	sub := &CleanupResult{}
+	for _, f := range r.ByCategory(c) {
+		sub.add(f)
+	}
+	return sub.Usage()
+}
+
+// FormatBytes renders n with a binary unit, e.g. "1.5 GiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,382 @@
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for disk usage and hardlinks
+// ============================================================================
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024 * 1024, "5.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.expected {
+			t.Errorf("FormatBytes(%d): expected %q, got %q", tt.n, tt.expected, got)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"100K", 100 * 1024},
+		{"100m", 100 * 1024 * 1024},
+		{"700MB", 700 * 1024 * 1024},
+		{"1.5GiB", 1536 * 1024 * 1024},
+	}
+	for _, tt := range tests {
+		if got, err := ParseBytes(tt.input); err != nil || got != tt.expected {
+			t.Errorf("ParseBytes(%q): expected %d, got %d (%v)", tt.input, tt.expected, got, err)
+		}
+	}
+	for _, input := range []string{"", "M", "-1", "ten"} {
+		if _, err := ParseBytes(input); err == nil {
+			t.Errorf("ParseBytes(%q): expected an error", input)
+		}
+	}
+}
+
+func TestCleanupResult_CategoryUsage(t *testing.T) {
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: "/lib/S/A", Usage: Usage{Bytes: 2048, Reclaimable: 1024, Hardlinked: 1}})
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: "/lib/S/B", Usage: Usage{Bytes: 512, Reclaimable: 512}})
+	result.add(Finding{Category: CategoryOrphanedFile, Path: "/lib/S/old.nfo", Usage: Usage{Bytes: 100, Reclaimable: 100}})
+
+	folders := result.CategoryUsage(CategoryOrphanedFolder)
+	if folders.Bytes != 2560 || folders.Reclaimable != 1536 || folders.Hardlinked != 1 {
+		t.Errorf("Expected 2560 bytes, 1536 reclaimable and 1 hardlinked, got %+v", folders)
+	}
+	if empty := result.CategoryUsage(CategoryEmptyFolder); empty.Bytes != 0 {
+		t.Errorf("Expected no usage for empty folders, got %+v", empty)
+	}
+}
+
+func TestScan_TruncatedVideos(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Stub (2001)").File("movie.mkv", nil).Metadata("movie.nfo").
+		Title("Short (2002)").File("movie.mkv", make([]byte, 100)).
+		Title("Fine (2003)").File("movie.mkv", make([]byte, 2048)).
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithMinVideoSize(1024)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	truncated := result.ByCategory(CategoryTruncatedVideo)
+	if len(truncated) != 2 {
+		t.Fatalf("Expected 2 truncated videos, got %v", truncated)
+	}
+	if truncated[0].Message != "Video file is empty" && truncated[1].Message != "Video file is empty" {
+		t.Errorf("Expected the stub to be reported as empty, got %v", truncated)
+	}
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Expected titles with a stub video not to be orphaned, got %v", result.OrphanedFolders)
+	}
+}
+
+func TestScan_NoVideoSizeCheckByDefault(t *testing.T) {
+	fsys := cleanuptest.New().Studio("Studio").Title("Stub (2001)").File("movie.mkv", nil).MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.Findings) != 0 {
+		t.Errorf("Expected no findings, got %v", result.Findings)
+	}
+}
+
+func TestScan_OrphanUsage(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// createFile writes 12 bytes
+	createFile(t, filepath.Join(tempDir, "Studio", "Orphaned", "movie.nfo"))
+	createFile(t, filepath.Join(tempDir, "Studio", "Orphaned", "movie.trickplay", "1.jpg"))
+	createFile(t, filepath.Join(tempDir, "Studio", "stray.nfo"))
+
+	result := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), tempDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	folder := result.ByCategory(CategoryOrphanedFolder)
+	if len(folder) != 1 || folder[0].Bytes != 24 || folder[0].Reclaimable != 24 {
+		t.Errorf("Expected orphaned folder holding 24 reclaimable bytes, got %+v", folder)
+	}
+	file := result.ByCategory(CategoryOrphanedFile)
+	if len(file) != 1 || file[0].Bytes != 12 || file[0].Reclaimable != 12 {
+		t.Errorf("Expected orphaned file holding 12 reclaimable bytes, got %+v", file)
+	}
+	if usage := result.Usage(); usage.Reclaimable != 36 {
+		t.Errorf("Expected 36 reclaimable bytes in total, got %d", usage.Reclaimable)
+	}
+}
+
+// hardlinkFixture creates an orphaned title folder whose movie.nfo is also
+// linked from a seeding directory, next to an unlinked poster.
+func hardlinkFixture(t *testing.T, tempDir string) (orphaned, seeded string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("link counts are not available on Windows")
+	}
+	orphaned = filepath.Join(tempDir, "Library", "Studio", "Orphaned")
+	seeded = filepath.Join(tempDir, "seeding", "movie.nfo")
+	createFile(t, seeded)
+	createDir(t, orphaned)
+	if err := os.Link(seeded, filepath.Join(orphaned, "movie.nfo")); err != nil {
+		t.Skipf("hard links not supported here: %v", err)
+	}
+	createFile(t, filepath.Join(orphaned, "poster.jpg"))
+	return orphaned, seeded
+}
+
+func TestScan_HardlinkedFilesAreNotReclaimable(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	hardlinkFixture(t, tempDir)
+
+	result := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), filepath.Join(tempDir, "Library"), result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	usage := result.Usage()
+	if usage.Bytes != 24 || usage.Reclaimable != 12 || usage.Hardlinked != 1 {
+		t.Errorf("Expected 24 bytes, 12 reclaimable and 1 hardlinked file, got %+v", usage)
+	}
+}
+
+func TestDeleter_PreserveHardlinks(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	orphaned, seeded := hardlinkFixture(t, tempDir)
+
+	library := filepath.Join(tempDir, "Library")
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: orphaned, Library: library})
+	result.add(Finding{Category: CategoryOrphanedFile, Path: filepath.Join(orphaned, "movie.nfo"), Library: library})
+
+	report, err := NewDeleter(nil, WithPreserveHardlinks(true)).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Kept) != 2 || len(report.Deleted) != 0 || len(report.Failures) != 0 {
+		t.Errorf("Expected both items kept, got kept=%d deleted=%d failures=%v",
+			len(report.Kept), len(report.Deleted), report.Failures)
+	}
+
+	if _, err := os.Stat(filepath.Join(orphaned, "movie.nfo")); err != nil {
+		t.Errorf("Hardlinked file should be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(orphaned, "poster.jpg")); !os.IsNotExist(err) {
+		t.Errorf("Unlinked file next to it should be deleted")
+	}
+	if _, err := os.Stat(seeded); err != nil {
+		t.Errorf("Seeded file must be untouched: %v", err)
+	}
+}
+
+func TestDeleter_HardlinksDeletedByDefault(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	orphaned, seeded := hardlinkFixture(t, tempDir)
+
+	result := &CleanupResult{}
+	result.add(Finding{Category: CategoryOrphanedFolder, Path: orphaned})
+
+	report, err := NewDeleter(nil).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Deleted) != 1 {
+		t.Errorf("Expected orphaned folder deleted, got %d deleted", len(report.Deleted))
+	}
+	// Only the library's link goes away; the seeding copy survives regardless
+	if _, err := os.Stat(seeded); err != nil {
+		t.Errorf("Seeded file must be untouched: %v", err)
+	}
+}
+
+func TestCleanupResult_UsageCountsInodesOnce(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	if runtime.GOOS == "windows" {
+		t.Skip("inodes are not available on Windows")
+	}
+
+	// Two links to the same poster, both inside one orphaned folder: deleting
+	// the folder frees the poster after all
+	orphaned := filepath.Join(tempDir, "Studio", "Orphaned")
+	createFile(t, filepath.Join(orphaned, "poster.jpg"))
+	if err := os.Link(filepath.Join(orphaned, "poster.jpg"), filepath.Join(orphaned, "folder.jpg")); err != nil {
+		t.Skipf("hard links not supported here: %v", err)
+	}
+
+	result := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), tempDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	folder := result.ByCategory(CategoryOrphanedFolder)
+	if len(folder) != 1 || folder[0].Bytes != 24 || folder[0].Hardlinked != 2 {
+		t.Fatalf("Expected per-finding usage of 24 bytes in 2 hardlinked files, got %+v", folder)
+	}
+	usage := result.Usage()
+	if usage.Bytes != 12 || usage.Reclaimable != 12 || usage.Hardlinked != 0 {
+		t.Errorf("Expected the shared inode counted once and reclaimable, got %+v", usage)
+	}
+}
+
+func TestScan_Duplicates(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	// createFile writes the same 12 bytes everywhere
+	createFile(t, filepath.Join(tempDir, "Studio A", "Movie (2020)", "movie.mkv"))
+	createFile(t, filepath.Join(tempDir, "Studio B", "Movie (2020)", "movie.mkv"))
+	createDir(t, filepath.Join(tempDir, "Studio B", "Other (2021)"))
+	if err := os.WriteFile(filepath.Join(tempDir, "Studio B", "Other (2021)", "other.mkv"), []byte("different size"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithDuplicateDetection(true)).Scan(context.Background(), tempDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	duplicates := result.ByCategory(CategoryDuplicateVideo)
+	if len(duplicates) != 2 {
+		t.Fatalf("Expected both same-size copies reported, got %v", duplicates)
+	}
+	if duplicates[0].Path != filepath.Join(tempDir, "Studio A", "Movie (2020)", "movie.mkv") {
+		t.Errorf("Expected duplicates in path order, got %s first", duplicates[0].Path)
+	}
+}
+
+func TestScan_HardlinkedVideosAreNotDuplicates(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	if runtime.GOOS == "windows" {
+		t.Skip("inodes are not available on Windows")
+	}
+
+	original := filepath.Join(tempDir, "Studio A", "Movie (2020)", "movie.mkv")
+	linked := filepath.Join(tempDir, "Studio B", "Movie (2020)", "movie.mkv")
+	createFile(t, original)
+	createDir(t, filepath.Dir(linked))
+	if err := os.Link(original, linked); err != nil {
+		t.Skipf("hard links not supported here: %v", err)
+	}
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithDuplicateDetection(true)).Scan(context.Background(), tempDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if duplicates := result.ByCategory(CategoryDuplicateVideo); len(duplicates) != 0 {
+		t.Errorf("Expected hard links not to be reported as duplicates, got %v", duplicates)
+	}
+}
+
+func TestReportDuplicates_SameFileWithoutIDs(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	original := filepath.Join(tempDir, "Studio A", "Movie (2020)", "movie.mkv")
+	other := filepath.Join(tempDir, "Studio B", "Movie (2020)", "movie.mkv")
+	createFile(t, original)
+	createFile(t, other)
+
+	// As on Windows: no file ids, the same file reached by two paths
+	result := &CleanupResult{}
+	run := newTestRun(NewScanner(WithDuplicateDetection(true)), result)
+	for _, path := range []string{original, filepath.Join(tempDir, "Studio A", "Movie (2020)", ".", "movie.mkv"), other} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		run.videos = append(run.videos, videoFile{path: path, size: info.Size(), info: info})
+	}
+	run.reportDuplicates()
+
+	if duplicates := result.ByCategory(CategoryDuplicateVideo); len(duplicates) != 2 {
+		t.Errorf("Expected the same file reported once, got %v", duplicates)
+	}
+}
+
+func TestScan_DuplicateHashing(t *testing.T) {
+	// Same size, past the sampled start and end; the third copy only
+	// differs in the middle, the fourth at the start
+	video := bytes.Repeat([]byte("x"), 3*hashSampleSize)
+	middle := bytes.Clone(video)
+	middle[len(middle)/2] = 'y'
+	start := bytes.Clone(video)
+	start[0] = 'y'
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("A (2001)").File("movie.mkv", video).
+		Title("B (2001)").File("movie.mkv", video).
+		Title("C (2001)").File("movie.mkv", middle).
+		Title("D (2001)").File("movie.mkv", start).
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithDuplicateHashing(true)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	duplicates := result.ByCategory(CategoryDuplicateVideo)
+	if len(duplicates) != 2 {
+		t.Fatalf("Expected only the identical copies reported, got %v", duplicates)
+	}
+	first, second := filepath.Join("Studio", "A (2001)", "movie.mkv"), filepath.Join("Studio", "B (2001)", "movie.mkv")
+	if duplicates[0].Path != first || duplicates[0].Message != "Identical to "+second || duplicates[0].Bytes != 0 {
+		t.Errorf("Expected %s first, without a size, got %+v", first, duplicates[0])
+	}
+	if duplicates[1].Path != second || duplicates[1].Bytes != int64(len(video)) {
+		t.Errorf("Expected %s to carry the wasted space, got %+v", second, duplicates[1])
+	}
+	if usage := result.Usage(); usage.Bytes != 0 {
+		t.Errorf("Expected duplicates to reclaim nothing, got %+v", usage)
+	}
+}
+
+func TestScan_NoDuplicatesByDefault(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	createFile(t, filepath.Join(tempDir, "Studio A", "Movie", "movie.mkv"))
+	createFile(t, filepath.Join(tempDir, "Studio B", "Movie", "movie.mkv"))
+
+	result := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), tempDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if duplicates := result.ByCategory(CategoryDuplicateVideo); len(duplicates) != 0 {
+		t.Errorf("Expected no duplicate findings without detection, got %v", duplicates)
+	}
+}
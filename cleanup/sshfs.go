@@ -0,0 +1,244 @@
+package cleanup
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSHTarget is a library on another machine, given as
+// ssh://[user@]host[:port]/path and reached with ssh.
+type SSHTarget struct {
+	User string
+	Host string
+	Port int // 0 for the ssh default
+	Path string
+}
+
+// IsSSHURL reports whether s names a library with the ssh:// scheme.
+func IsSSHURL(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "ssh://")
+}
+
+// ParseSSHURL parses an ssh://[user@]host[:port]/path library URL.
+func ParseSSHURL(s string) (SSHTarget, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return SSHTarget{}, err
+	}
+	if !strings.EqualFold(u.Scheme, "ssh") || u.Hostname() == "" {
+		return SSHTarget{}, fmt.Errorf("invalid library URL %q (expected ssh://[user@]host[:port]/path)", s)
+	}
+	t := SSHTarget{User: u.User.Username(), Host: u.Hostname(), Path: path.Clean("/" + u.Path)}
+	if err := t.check(); err != nil {
+		return SSHTarget{}, fmt.Errorf("invalid library URL %q: %w", s, err)
+	}
+	if port := u.Port(); port != "" {
+		if t.Port, err = strconv.Atoi(port); err != nil {
+			return SSHTarget{}, fmt.Errorf("invalid port in library URL %q", s)
+		}
+	}
+	return t, nil
+}
+
+// check refuses a user or host ssh would take for an option.
+func (t SSHTarget) check() error {
+	if strings.HasPrefix(t.User, "-") || strings.HasPrefix(t.Host, "-") {
+		return fmt.Errorf("user and host must not start with -")
+	}
+	return nil
+}
+
+// String returns t in its ssh:// form.
+func (t SSHTarget) String() string {
+	u := url.URL{Scheme: "ssh", Host: t.Host, Path: t.Path}
+	if t.Port != 0 {
+		u.Host += ":" + strconv.Itoa(t.Port)
+	}
+	if t.User != "" {
+		u.User = url.User(t.User)
+	}
+	return u.String()
+}
+
+// SSHFS is a read-only FS over a library on another machine, for WithFS.
+// It runs the ssh client, which must log in without prompting (keys or an
+// agent), and runs commands there: the account needs a shell, which rules
+// out SFTP-only ones, and the host GNU find, which BusyBox and BSD lack.
+// The whole tree below Target.Path is listed in one go on first use; file
+// contents are fetched when read. Paths are the remote ones, so the library
+// root to scan is Target.Path.
+type SSHFS struct {
+	Target SSHTarget
+	// Command is the ssh client; "ssh" looked up in PATH if empty.
+	Command string
+
+	once sync.Once
+	err  error
+	tree *remoteTree
+}
+
+// listingFields is the number of NUL-separated fields find prints per entry.
+const listingFields = 7
+
+// run runs command on the remote host and returns its standard output.
+func (s *SSHFS) run(command string) ([]byte, error) {
+	if err := s.Target.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Target, err)
+	}
+	bin := s.Command
+	if bin == "" {
+		bin = "ssh"
+	}
+	args := []string{"-o", "BatchMode=yes"}
+	if s.Target.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(s.Target.Port))
+	}
+	host := s.Target.Host
+	if s.Target.User != "" {
+		host = s.Target.User + "@" + host
+	}
+	args = append(args, host, "--", command)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+			return out, fmt.Errorf("%s: %w: %s", s.Target, err, msg)
+		}
+		return out, fmt.Errorf("%s: %w", s.Target, err)
+	}
+	return out, nil
+}
+
+// load lists the tree below Target.Path.
+func (s *SSHFS) load() error {
+	s.once.Do(func() {
+		root := s.Target.Path
+		out, err := s.run("find " + shellQuote(root) + ` -printf '%y\0%Y\0%m\0%s\0%T@\0%l\0%P\0'`)
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.tree = newRemoteTree()
+		fields := strings.Split(string(out), "\x00")
+		for i := 0; i+listingFields <= len(fields); i += listingFields {
+			entry, rel := parseListing(fields[i : i+listingFields])
+			s.tree.add(path.Join(root, rel), entry, root)
+		}
+		if s.tree.entries[root] == nil {
+			s.err = fmt.Errorf("%s: nothing listed", s.Target)
+		}
+	})
+	return s.err
+}
+
+// parseListing turns the find fields of one entry into a remoteEntry and
+// its path relative to the root.
+func parseListing(f []string) (*remoteEntry, string) {
+	e := &remoteEntry{link: f[5]}
+	perm, _ := strconv.ParseUint(f[2], 8, 32)
+	e.mode = fs.FileMode(perm) & fs.ModePerm
+	switch f[0] {
+	case "d":
+		e.mode |= fs.ModeDir
+	case "l":
+		e.mode |= fs.ModeSymlink
+	case "f":
+	default:
+		e.mode |= fs.ModeIrregular
+	}
+	if f[1] != "" {
+		e.target = f[1][0]
+	}
+	e.size, _ = strconv.ParseInt(f[3], 10, 64)
+	if secs, err := strconv.ParseFloat(f[4], 64); err == nil {
+		e.modTime = time.Unix(0, int64(secs*float64(time.Second)))
+	}
+	return e, f[6]
+}
+
+func (s *SSHFS) lstat(name string) (*remoteEntry, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.tree.lstat(name)
+}
+
+func (s *SSHFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s.tree.readDir(name)
+}
+
+// Stat follows symlinks: a target inside the listed tree is looked up
+// there, any other is stat'ed on the remote host.
+func (s *SSHFS) Stat(name string) (fs.FileInfo, error) {
+	entry, err := s.lstat(name)
+	if err != nil || entry.mode&fs.ModeSymlink == 0 {
+		return entry, err
+	}
+	switch entry.target {
+	case 'N':
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	case 'L':
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fmt.Errorf("too many levels of symbolic links")}
+	}
+	target := entry.link
+	if !path.IsAbs(target) {
+		target = path.Join(path.Dir(remotePath(name)), target)
+	}
+	if resolved := s.tree.entries[path.Clean(target)]; resolved != nil && resolved.mode&fs.ModeSymlink == 0 {
+		followed := *resolved
+		followed.name = entry.name
+		return &followed, nil
+	}
+	out, err := s.run("find -L " + shellQuote(remotePath(name)) + ` -maxdepth 0 -printf '%y\0%Y\0%m\0%s\0%T@\0%l\0\0'`)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	fields := strings.Split(string(out), "\x00")
+	if len(fields) < listingFields {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	followed, _ := parseListing(fields[:listingFields])
+	followed.name = entry.name
+	return followed, nil
+}
+
+func (s *SSHFS) ReadFile(name string) ([]byte, error) {
+	if _, err := s.lstat(name); err != nil {
+		return nil, err
+	}
+	data, err := s.run("cat -- " + shellQuote(remotePath(name)))
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+func (s *SSHFS) Readlink(name string) (string, error) {
+	entry, err := s.lstat(name)
+	if err != nil {
+		return "", err
+	}
+	if entry.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return entry.link, nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
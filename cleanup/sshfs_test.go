@@ -0,0 +1,146 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+// fakeSSH writes a shell script standing in for ssh: it runs the remote
+// command locally, so SSHFS reads the local tree.
+func fakeSSH(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("needs a POSIX shell and GNU find")
+	}
+	path := filepath.Join(t.TempDir(), "ssh")
+	script := "#!/bin/sh\nwhile [ \"$1\" != -- ]; do shift; done\nshift\nexec sh -c \"$1\"\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// ============================================================================
+// Tests for ParseSSHURL
+// ============================================================================
+
+func TestParseSSHURL(t *testing.T) {
+	target, err := ParseSSHURL("ssh://me@seedbox.example:2222/data/Movies/")
+	if err != nil {
+		t.Fatalf("ParseSSHURL returned error: %v", err)
+	}
+	expected := SSHTarget{User: "me", Host: "seedbox.example", Port: 2222, Path: "/data/Movies"}
+	if target != expected {
+		t.Errorf("Expected %+v, got %+v", expected, target)
+	}
+	if got := target.String(); got != "ssh://me@seedbox.example:2222/data/Movies" {
+		t.Errorf("Expected the URL back, got %s", got)
+	}
+
+	for _, s := range []string{"ssh:///data", "ftp://host/data", "sftp://host/data", "ssh://host:port/data", "ssh://-oProxyCommand=x/data", "ssh://-l@host/data"} {
+		if _, err := ParseSSHURL(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+// ============================================================================
+// Tests for SSHFS
+// ============================================================================
+
+func TestSSHFS_ScanMatchesLocal(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	createFile(t, filepath.Join(tempDir, "Studio", "Movie (2001)", "movie.mkv"))
+	createFile(t, filepath.Join(tempDir, "Studio", "Orphan (2002)", "movie.nfo"))
+	createDir(t, filepath.Join(tempDir, "Studio", "Empty (2003)"))
+	createFile(t, filepath.Join(tempDir, "Studio", "stray.mkv"))
+	createFile(t, filepath.Join(tempDir, "Studio", "Ignored (2004)", "movie.nfo"))
+	if err := os.WriteFile(filepath.Join(tempDir, "Studio", IgnoreFileName), []byte("Ignored*\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(opts ...Option) []string {
+		result := &CleanupResult{}
+		if err := NewScanner(opts...).Scan(context.Background(), tempDir, result.Add); err != nil {
+			t.Fatalf("Scan returned error: %v", err)
+		}
+		var findings []string
+		for _, f := range result.Findings {
+			findings = append(findings, string(f.Category)+" "+f.Path)
+		}
+		sort.Strings(findings)
+		return findings
+	}
+	local := scan()
+	remote := scan(WithFS(&SSHFS{Target: SSHTarget{Host: "seedbox", Path: tempDir}, Command: fakeSSH(t)}))
+
+	if len(local) != 3 {
+		t.Fatalf("Expected 3 local findings, got %v", local)
+	}
+	if len(remote) != len(local) {
+		t.Fatalf("Expected %v, got %v", local, remote)
+	}
+	for i := range local {
+		if remote[i] != local[i] {
+			t.Errorf("Expected %s, got %s", local[i], remote[i])
+		}
+	}
+}
+
+func TestSSHFS_Symlinks(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	video := filepath.Join(tempDir, "Studio", "Movie (2001)", "movie.mkv")
+	createFile(t, video)
+	if err := os.Symlink(video, filepath.Join(tempDir, "link.mkv")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(tempDir, "gone.mkv"), filepath.Join(tempDir, "broken.mkv")); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := &SSHFS{Target: SSHTarget{Host: "seedbox", Path: tempDir}, Command: fakeSSH(t)}
+	info, err := fsys.Stat(filepath.Join(tempDir, "link.mkv"))
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	if !info.Mode().IsRegular() || info.Size() != int64(len("test content")) || info.Name() != "link.mkv" {
+		t.Errorf("Expected the followed video, got %v %d %s", info.Mode(), info.Size(), info.Name())
+	}
+	if target, err := fsys.Readlink(filepath.Join(tempDir, "link.mkv")); err != nil || target != video {
+		t.Errorf("Expected %s, got %s (%v)", video, target, err)
+	}
+	if _, err := fsys.Stat(filepath.Join(tempDir, "broken.mkv")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist for a broken link, got %v", err)
+	}
+	if data, err := fsys.ReadFile(video); err != nil || string(data) != "test content" {
+		t.Errorf("Expected the file contents, got %q (%v)", data, err)
+	}
+}
+
+func TestSSHFS_ConnectionFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "ssh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho 'Permission denied (publickey).' >&2\nexit 255\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewScanner(WithFS(&SSHFS{Target: SSHTarget{Host: "seedbox", Path: "/data"}, Command: path})).
+		Scan(context.Background(), "/data", (&CleanupResult{}).Add)
+	var unreadable *ErrUnreadableDir
+	if !errors.As(err, &unreadable) {
+		t.Fatalf("Expected ErrUnreadableDir, got %v", err)
+	}
+	if got := err.Error(); !containsSubstring(got, "Permission denied") {
+		t.Errorf("Expected the ssh error in %q", got)
+	}
+}
@@ -0,0 +1,321 @@
+package cleanup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scanStateVersion is bumped whenever what a title's findings depend on
+// changes, so states written by older versions are dropped.
+const scanStateVersion = 2
+
+// ScanState remembers, between scans, the findings of every title folder
+// along with the modification times of the title folder and the folders
+// below it, and the size and modification time of every file in them. A
+// Scanner given one with WithScanState lists the title folders it remembers
+// and, when nothing changed, reports the findings it remembers for them
+// instead of reading, probing and checking them again.
+//
+// Editing an ignore file above the title folder is not noticed. Set Refresh
+// from time to time to scan everything again. Folders without a modification
+// time, such as the prefixes of an object store, are always scanned.
+//
+// What is remembered of a library is dropped when the options that shape its
+// findings (extensions, layout, audits...) change. A ScanState is safe for
+// concurrent use by scans of different libraries.
+type ScanState struct {
+	// Refresh makes scans visit every title folder, updating the state
+	// without using what it remembers.
+	Refresh bool
+
+	mu        sync.Mutex
+	libraries map[string]*libraryState // by library root
+}
+
+// libraryState is what a ScanState remembers of one library.
+type libraryState struct {
+	Options string                 `json:"options"` // hash of the scanner options
+	Titles  map[string]*titleState `json:"titles"`  // by title folder path
+}
+
+// titleState is what a ScanState remembers of one title folder.
+type titleState struct {
+	// Folders are the modification times of the title folder and of the
+	// folders below it, by path relative to the title folder
+	Folders map[string]time.Time `json:"folders"`
+	// Files are the sizes and modification times of the files in them, by
+	// path relative to the title folder
+	Files    map[string]fileStamp `json:"files,omitempty"`
+	Findings []Finding            `json:"findings,omitempty"`
+}
+
+// fileStamp is the size and modification time of a file. Findings such as
+// truncated videos or NFO mismatches depend on what a file holds, which can
+// be rewritten in place without changing the time of its folder.
+type fileStamp struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// unchanged reports whether folders and files are those remembered in t.
+func (t *titleState) unchanged(folders map[string]time.Time, files map[string]fileStamp) bool {
+	if len(t.Folders) == 0 || len(folders) != len(t.Folders) || len(files) != len(t.Files) {
+		return false
+	}
+	for rel, modTime := range folders {
+		if old, ok := t.Folders[rel]; !ok || !old.Equal(modTime) {
+			return false
+		}
+	}
+	for rel, stamp := range files {
+		if old, ok := t.Files[rel]; !ok || old.Size != stamp.Size || !old.ModTime.Equal(stamp.ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanStateFile is the JSON form of a ScanState.
+type scanStateFile struct {
+	Version   int                      `json:"version"`
+	Libraries map[string]*libraryState `json:"libraries"`
+}
+
+// NewScanState returns a ScanState that remembers nothing yet.
+func NewScanState() *ScanState {
+	return &ScanState{libraries: map[string]*libraryState{}}
+}
+
+// LoadScanState reads the state saved at path. A missing file, or one saved
+// by another version, gives an empty state.
+func LoadScanState(path string) (*ScanState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewScanState(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan state: %w", err)
+	}
+	var file scanStateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("scan state %s: %w", path, err)
+	}
+	state := NewScanState()
+	if file.Version == scanStateVersion && file.Libraries != nil {
+		state.libraries = file.Libraries
+	}
+	return state, nil
+}
+
+// Save writes the state to path, replacing the file at once so an
+// interrupted save leaves the previous state.
+func (s *ScanState) Save(path string) error {
+	s.mu.Lock()
+	data, err := json.Marshal(scanStateFile{Version: scanStateVersion, Libraries: s.libraries})
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("scan state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("scan state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("scan state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("scan state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("scan state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("scan state: %w", err)
+	}
+	return nil
+}
+
+// WithScanState makes Scan skip the title folders state remembers as
+// unchanged, and record in state what it finds in the others. Duplicate
+// detection needs every video, so with WithDuplicateDetection every title
+// folder is read and state is only updated.
+func WithScanState(state *ScanState) Option {
+	return func(s *Scanner) {
+		s.state = state
+	}
+}
+
+// optionsKey hashes the options title folder findings depend on. Options
+// that only pick which folders are scanned, or that change findings as they
+// are reported (ignore files, WithMinAge), are left out.
+func (s *Scanner) optionsKey() string {
+	var permissions PermissionPolicy
+	if s.permissions != nil {
+		permissions = *s.permissions
+	}
+	var categories []Category
+	for _, c := range Categories {
+		if s.checks(c) {
+			categories = append(categories, c)
+		}
+	}
+	key := fmt.Sprintf("%+v|%+v|%+v|%v|%v|%v|%v|%v|%v|%T|%+v|%v|%v|%v", s.classifier, s.layout, permissions,
+		s.followSymlinks, s.auditPortableNames, s.nfoYearCheck, s.nfoTitleCheck, s.missingMetadata, s.minVideoSize, s.prober, s.prober,
+		s.recycleBinUsage, s.junkFiles, categories)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// stateScan is the part of a ScanState one Scan reads and updates.
+type stateScan struct {
+	state    *ScanState
+	root     string
+	previous map[string]*titleState // as the scan started
+
+	mu      sync.Mutex
+	titles  map[string]*titleState // scanned or kept by this scan
+	pending map[string]*titleState // being scanned, collecting findings
+}
+
+// begin starts a scan of the library at root with options, dropping what is
+// remembered of it if the options changed.
+func (s *ScanState) begin(root, options string) *stateScan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	library := s.libraries[root]
+	if library == nil || library.Options != options {
+		library = &libraryState{Options: options, Titles: map[string]*titleState{}}
+		s.libraries[root] = library
+	}
+	scan := &stateScan{state: s, root: root, previous: library.Titles,
+		titles: map[string]*titleState{}, pending: map[string]*titleState{}}
+	if s.Refresh {
+		scan.previous = nil
+	}
+	return scan
+}
+
+// end stores what the scan found. A complete scan of the whole library
+// replaces what was remembered of it, so deleted title folders are
+// forgotten; other scans only add to it.
+func (t *stateScan) end(complete bool) {
+	t.state.mu.Lock()
+	defer t.state.mu.Unlock()
+	library := t.state.libraries[t.root]
+	if complete {
+		library.Titles = t.titles
+		return
+	}
+	for path, title := range t.titles {
+		library.Titles[path] = title
+	}
+}
+
+// record adds f to the title folder being scanned that holds it, if any.
+func (t *stateScan) record(f Finding) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for path, title := range t.pending {
+		if f.Path == path || strings.HasPrefix(f.Path, path+string(filepath.Separator)) {
+			title.Findings = append(title.Findings, f)
+			return
+		}
+	}
+}
+
+// scanTitleFolder processes the title folder titlePath, or reports what the
+// scan state remembers of it if nothing in it changed since.
+func (r *scanRun) scanTitleFolder(titlePath string) {
+	t := r.titles
+	if t == nil {
+		r.processTitleFolder(titlePath)
+		return
+	}
+	// The times are taken first, so changes made while the folder is read
+	// show next time
+	folders, files, ok := r.fingerprint(titlePath)
+	if old := t.previous[titlePath]; old != nil && ok && !r.detectDuplicates && old.unchanged(folders, files) {
+		t.mu.Lock()
+		t.titles[titlePath] = old
+		t.mu.Unlock()
+		for _, f := range old.Findings {
+			r.emit(f)
+		}
+		r.report(TitleScanned{Library: r.library, Path: titlePath, Decision: DecisionUnchanged})
+		return
+	}
+
+	title := &titleState{Folders: folders, Files: files}
+	failures := r.failures()
+	t.mu.Lock()
+	t.pending[titlePath] = title
+	t.mu.Unlock()
+
+	r.processTitleFolder(titlePath)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, titlePath)
+	// A folder that could not be read in full is scanned again next time
+	if ok && r.failures() == failures && r.ctx.Err() == nil {
+		t.titles[titlePath] = title
+	}
+}
+
+// failures returns the number of errors recorded so far.
+func (r *scanRun) failures() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.errs)
+}
+
+// fingerprint returns the modification times of titlePath and of every
+// folder below it, and the sizes and modification times of the files in
+// them, by path relative to titlePath. ok is false if one could not be read
+// or has no time.
+func (r *scanRun) fingerprint(titlePath string) (folders map[string]time.Time, files map[string]fileStamp, ok bool) {
+	info, err := r.fsys.Stat(titlePath)
+	if err != nil || info.ModTime().IsZero() {
+		return nil, nil, false
+	}
+	folders = map[string]time.Time{".": info.ModTime()}
+	files = map[string]fileStamp{}
+	var walk func(dir, rel string) bool
+	walk = func(dir, rel string) bool {
+		entries, err := r.fsys.ReadDir(dir)
+		if err != nil {
+			return false
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || info.ModTime().IsZero() {
+				return false
+			}
+			childRel := filepath.Join(rel, entry.Name())
+			if !entry.IsDir() {
+				files[childRel] = fileStamp{Size: info.Size(), ModTime: info.ModTime()}
+				continue
+			}
+			folders[childRel] = info.ModTime()
+			if !walk(filepath.Join(dir, entry.Name()), childRel) {
+				return false
+			}
+		}
+		return true
+	}
+	return folders, files, walk(titlePath, ".")
+}
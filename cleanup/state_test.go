@@ -0,0 +1,205 @@
+package cleanup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Tests for WithScanState
+// ============================================================================
+
+// stateLibrary creates a library with a title folder that has a video, an
+// orphaned one and an empty one.
+func stateLibrary(t *testing.T) string {
+	t.Helper()
+	dir := setupTestDir(t)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	createFile(t, filepath.Join(dir, "Studio", "Movie (2001)", "movie.mkv"))
+	createFile(t, filepath.Join(dir, "Studio", "Movie (2001)", "Subs", "movie.en.srt"))
+	createFile(t, filepath.Join(dir, "Studio", "Orphan (2002)", "movie.nfo"))
+	createDir(t, filepath.Join(dir, "Studio", "Empty (2003)"))
+	return dir
+}
+
+// scanWithState scans dir with state and returns the sorted findings and
+// the folders that were listed.
+func scanWithState(t *testing.T, dir string, state *ScanState, opts ...Option) ([]string, *countingFS) {
+	t.Helper()
+	fsys := &countingFS{FS: osFS{}, listings: map[string]int{}}
+	result := &CleanupResult{}
+	opts = append([]Option{WithFS(fsys), WithScanState(state)}, opts...)
+	if err := NewScanner(opts...).Scan(context.Background(), dir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	var findings []string
+	for _, f := range result.Findings {
+		rel, _ := filepath.Rel(dir, f.Path)
+		findings = append(findings, string(f.Category)+" "+rel)
+	}
+	sort.Strings(findings)
+	return findings, fsys
+}
+
+func TestScanState_SkipsUnchangedTitles(t *testing.T) {
+	dir := stateLibrary(t)
+	state := NewScanState()
+
+	first, _ := scanWithState(t, dir, state)
+	if len(first) != 2 {
+		t.Fatalf("Expected 2 findings, got %v", first)
+	}
+	second, fsys := scanWithState(t, dir, state)
+	if len(second) != 2 || second[0] != first[0] || second[1] != first[1] {
+		t.Errorf("Expected the remembered findings %v, got %v", first, second)
+	}
+	// Listed once to be compared, not read again
+	for _, title := range []string{"Movie (2001)", "Orphan (2002)", "Empty (2003)"} {
+		if n := fsys.listings[filepath.Join(dir, "Studio", title)]; n != 1 {
+			t.Errorf("Expected %s to be listed once, got %d listings", title, n)
+		}
+	}
+	if n := fsys.listings[filepath.Join(dir, "Studio")]; n == 0 {
+		t.Error("Expected the studio folder to be listed")
+	}
+}
+
+func TestScanState_RescansChangedTitles(t *testing.T) {
+	dir := stateLibrary(t)
+	state := NewScanState()
+	scanWithState(t, dir, state)
+
+	// The orphan gets its video; a subtitle folder of the movie changes
+	orphan := filepath.Join(dir, "Studio", "Orphan (2002)")
+	createFile(t, filepath.Join(orphan, "movie.mkv"))
+	subs := filepath.Join(dir, "Studio", "Movie (2001)", "Subs")
+	createFile(t, filepath.Join(subs, "movie.fr.srt"))
+	later := time.Now().Add(time.Minute)
+	for _, folder := range []string{orphan, subs} {
+		if err := os.Chtimes(folder, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	findings, fsys := scanWithState(t, dir, state)
+	if len(findings) != 1 || findings[0] != "empty_folder "+filepath.Join("Studio", "Empty (2003)") {
+		t.Errorf("Expected only the empty folder, got %v", findings)
+	}
+	for _, title := range []string{"Movie (2001)", "Orphan (2002)"} {
+		if fsys.listings[filepath.Join(dir, "Studio", title)] < 2 {
+			t.Errorf("Expected %s to be read again", title)
+		}
+	}
+	if n := fsys.listings[filepath.Join(dir, "Studio", "Empty (2003)")]; n != 1 {
+		t.Errorf("Expected the empty folder to be listed once, got %d listings", n)
+	}
+}
+
+func TestScanState_RescansFilesRewrittenInPlace(t *testing.T) {
+	dir := setupTestDir(t)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	title := filepath.Join(dir, "Studio", "Movie (2001)")
+	video := filepath.Join(title, "movie.mkv")
+	if err := os.MkdirAll(title, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(video, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := NewScanState()
+	if findings, _ := scanWithState(t, dir, state, WithMinVideoSize(1)); len(findings) != 0 {
+		t.Fatalf("Expected no findings, got %v", findings)
+	}
+
+	// The video is truncated in place; its folder keeps its time
+	info, err := os.Stat(title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(video, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(title, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, _ := scanWithState(t, dir, state, WithMinVideoSize(1))
+	if len(findings) != 1 || findings[0] != "truncated_video "+filepath.Join("Studio", "Movie (2001)", "movie.mkv") {
+		t.Errorf("Expected the truncated video reported, got %v", findings)
+	}
+}
+
+func TestScanState_OptionsChange(t *testing.T) {
+	dir := stateLibrary(t)
+	state := NewScanState()
+	scanWithState(t, dir, state)
+
+	// .nfo files count as videos now: the orphan is not one any more
+	findings, _ := scanWithState(t, dir, state, WithExtensions(".mkv", ".nfo"))
+	if len(findings) != 1 {
+		t.Errorf("Expected the state dropped after the options changed, got %v", findings)
+	}
+}
+
+func TestScanState_Refresh(t *testing.T) {
+	dir := stateLibrary(t)
+	state := NewScanState()
+	scanWithState(t, dir, state)
+
+	state.Refresh = true
+	findings, fsys := scanWithState(t, dir, state)
+	if len(findings) != 2 {
+		t.Errorf("Expected 2 findings, got %v", findings)
+	}
+	if fsys.listings[filepath.Join(dir, "Studio", "Orphan (2002)")] < 2 {
+		t.Error("Expected every title folder to be read with Refresh")
+	}
+}
+
+func TestScanState_ForgetsDeletedTitles(t *testing.T) {
+	dir := stateLibrary(t)
+	state := NewScanState()
+	scanWithState(t, dir, state)
+	if err := os.RemoveAll(filepath.Join(dir, "Studio", "Orphan (2002)")); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, _ := scanWithState(t, dir, state)
+	if len(findings) != 1 {
+		t.Errorf("Expected only the empty folder, got %v", findings)
+	}
+	if _, ok := state.libraries[dir].Titles[filepath.Join(dir, "Studio", "Orphan (2002)")]; ok {
+		t.Error("Expected the deleted title folder to be forgotten")
+	}
+}
+
+func TestScanState_SaveAndLoad(t *testing.T) {
+	dir := stateLibrary(t)
+	state := NewScanState()
+	first, _ := scanWithState(t, dir, state)
+
+	path := filepath.Join(setupTestDir(t), "state", "scan.json")
+	if err := state.Save(path); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	loaded, err := LoadScanState(path)
+	if err != nil {
+		t.Fatalf("LoadScanState returned error: %v", err)
+	}
+	findings, fsys := scanWithState(t, dir, loaded)
+	if len(findings) != len(first) {
+		t.Errorf("Expected %v, got %v", first, findings)
+	}
+	if n := fsys.listings[filepath.Join(dir, "Studio", "Orphan (2002)")]; n != 1 {
+		t.Errorf("Expected the loaded state to be used, got %d listings", n)
+	}
+
+	missing, err := LoadScanState(filepath.Join(dir, "missing.json"))
+	if err != nil || len(missing.libraries) != 0 {
+		t.Errorf("Expected an empty state for a missing file, got %v (%v)", missing.libraries, err)
+	}
+}
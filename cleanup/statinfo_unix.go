@@ -0,0 +1,38 @@
+//go:build !windows
+
+package cleanup
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// statOf returns the device, inode, link count and owner of info's file,
+// as stat reported them or a ListingCache kept them.
+func statOf(info fs.FileInfo) (*fileStat, bool) {
+	switch sys := info.Sys().(type) {
+	case *fileStat:
+		return sys, sys != nil
+	case *syscall.Stat_t:
+		return &fileStat{Dev: uint64(sys.Dev), Ino: uint64(sys.Ino), Nlink: uint64(sys.Nlink), Uid: sys.Uid, Gid: sys.Gid}, true
+	}
+	return nil, false
+}
+
+// linkCount returns the number of hard links to info's file.
+func linkCount(info fs.FileInfo) (uint64, bool) {
+	st, ok := statOf(info)
+	if !ok {
+		return 0, false
+	}
+	return st.Nlink, true
+}
+
+// fileIDOf returns the device and inode of info's file.
+func fileIDOf(info fs.FileInfo) (fileID, bool) {
+	st, ok := statOf(info)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: st.Dev, ino: st.Ino}, true
+}
@@ -0,0 +1,21 @@
+package cleanup
+
+import "io/fs"
+
+// statOf has nothing to return on Windows, where os.Lstat reports neither
+// inodes, link counts nor numeric owners.
+func statOf(info fs.FileInfo) (*fileStat, bool) {
+	return nil, false
+}
+
+// linkCount is unknown on Windows: os.Lstat does not report link counts, so
+// every file is treated as having a single link.
+func linkCount(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// fileIDOf is unknown on Windows for the same reason; files are then told
+// apart by path only.
+func fileIDOf(info fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
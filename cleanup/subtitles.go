@@ -0,0 +1,85 @@
+package cleanup
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Subtitle subfolder names expected inside title folders
+var subtitlesDirNames = map[string]bool{
+	"subs":      true,
+	"subtitles": true,
+}
+
+// isSubtitlesDir reports whether name is a recognized subtitle subfolder.
+func isSubtitlesDir(name string) bool {
+	return subtitlesDirNames[strings.ToLower(name)]
+}
+
+// subtitleLanguages are the language names release-style subtitle folders
+// use as file names, e.g. "English.srt" or "2_English.srt".
+var subtitleLanguages = map[string]bool{
+	"arabic": true, "chinese": true, "czech": true, "danish": true,
+	"dutch": true, "english": true, "finnish": true, "french": true,
+	"german": true, "greek": true, "hebrew": true, "hindi": true,
+	"hungarian": true, "indonesian": true, "italian": true, "japanese": true,
+	"korean": true, "norwegian": true, "polish": true, "portuguese": true,
+	"romanian": true, "russian": true, "spanish": true, "swedish": true,
+	"thai": true, "turkish": true, "ukrainian": true, "vietnamese": true,
+}
+
+// genericSubtitleName splits an optional "2_" numbering and the first word
+// off a subtitle name.
+var genericSubtitleName = regexp.MustCompile(`^(?:\d+_)?([a-z]+)`)
+
+// isGenericSubtitleName reports whether the lowercase name only gives a
+// language, as a name or a two or three letter code ("en.srt", "eng.srt").
+func isGenericSubtitleName(name string) bool {
+	m := genericSubtitleName.FindStringSubmatch(name)
+	if m == nil {
+		return false
+	}
+	rest := name[len(m[0]):]
+	if rest != "" && !strings.ContainsRune(" ._(-", rune(rest[0])) {
+		return false
+	}
+	return subtitleLanguages[m[1]] || (len(m[1]) <= 3 && strings.HasPrefix(rest, "."))
+}
+
+// subtitleMatchesVideo reports whether a subtitle file or per-video folder
+// called name belongs to one of videoNames. Like Jellyfin, it matches names
+// starting with a video's name without extension, or without its part
+// number; generic language-only names are assumed to belong to the title's
+// videos.
+func subtitleMatchesVideo(name string, videoNames []string) bool {
+	lower := nameKey(name)
+	for _, video := range videoNames {
+		for _, base := range videoBases(video) {
+			if strings.HasPrefix(lower, base) {
+				return true
+			}
+		}
+	}
+	return isGenericSubtitleName(lower)
+}
+
+// checkSubtitles warns about the entries of the subtitle folder subsPath
+// that match none of the title's videos, i.e. whose video no longer exists.
+func (r *scanRun) checkSubtitles(subsPath string, videoNames []string) {
+	entries, err := r.fsys.ReadDir(subsPath)
+	if err != nil {
+		r.fail(&ErrUnreadableDir{Path: subsPath, Err: err})
+		return
+	}
+	var stale []string
+	for _, entry := range entries {
+		if !subtitleMatchesVideo(entry.Name(), videoNames) {
+			stale = append(stale, entry.Name())
+		}
+	}
+	if len(stale) > 0 {
+		r.emit(Finding{Category: CategoryStructureWarning, Path: subsPath,
+			Message: fmt.Sprintf("Subtitles for missing videos (%s)", strings.Join(stale, ", "))})
+	}
+}
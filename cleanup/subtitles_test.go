@@ -0,0 +1,71 @@
+package cleanup
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for subtitle folders
+// ============================================================================
+
+func TestSubtitleMatchesVideo(t *testing.T) {
+	videos := []string{"Movie (2020).mkv"}
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"Movie (2020).en.srt", true},
+		{"movie (2020).forced.ass", true},
+		{"Movie (2020)", true}, // per-video folder
+		{"English.srt", true},
+		{"2_English.srt", true},
+		{"eng.srt", true},
+		{"Old Cut (2019).en.srt", false},
+		{"Batman.srt", false},
+	}
+	for _, tt := range tests {
+		if got := subtitleMatchesVideo(tt.name, videos); got != tt.expected {
+			t.Errorf("subtitleMatchesVideo(%q): expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	// Subtitles of a multi-part video may be named after the whole video
+	for _, name := range []string{"Movie.en.srt", "Movie-cd2.en.srt"} {
+		if !subtitleMatchesVideo(name, []string{"Movie-cd1.mkv", "Movie-cd2.mkv"}) {
+			t.Errorf("Expected %q to belong to the parts of Movie", name)
+		}
+	}
+}
+
+func TestScan_SubtitleFolders(t *testing.T) {
+	libraryDir := cleanuptest.New().
+		Studio("Studio").
+		Title("Matched (2020)").Video("Matched (2020).mkv").Dir("Subs", "Matched (2020).en.srt", "2_English.srt").
+		Title("Stale (2021)").Video("Stale (2021) 2160p.mkv").Dir("Subtitles", "Stale (2021) 1080p.en.srt").
+		Title("No Video (2022)").Metadata("movie.nfo").Dir("Subs", "English.srt").
+		TempDir(t)
+
+	result := &CleanupResult{}
+	if err := NewScanner().Scan(context.Background(), libraryDir, result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.StructureWarnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d: %v", len(result.StructureWarnings), result.StructureWarnings)
+	}
+	warning := result.StructureWarnings[0]
+	if !strings.Contains(warning, "Subtitles for missing videos (Stale (2021) 1080p.en.srt)") ||
+		!strings.HasSuffix(warning, filepath.Join("Stale (2021)", "Subtitles")) {
+		t.Errorf("Expected a stale subtitle warning for Stale (2021), got %q", warning)
+	}
+
+	expected := filepath.Join(libraryDir, "Studio", "No Video (2022)")
+	if len(result.OrphanedFolders) != 1 || result.OrphanedFolders[0] != expected {
+		t.Errorf("Expected only %s orphaned, got %v", expected, result.OrphanedFolders)
+	}
+}
@@ -0,0 +1,40 @@
+package cleanup
+
+// DeletionThreshold caps what one run may delete, so that a run pointed at
+// the wrong folder, or given the wrong layout, does not wipe a healthy
+// library in which every title folder looks orphaned. Zero fields are not
+// checked.
+type DeletionThreshold struct {
+	MaxCount int // deletable findings
+	// MaxPercent caps the deletable findings as a percentage of the title
+	// folders scanned
+	MaxPercent float64
+}
+
+// Check returns a *ThresholdError when the deletable findings of result, the
+// orphans and empty folders a Deleter would dispose of, exceed t. titles is
+// the number of title folders scanned, or -1 if it is not known, in which
+// case MaxPercent is not checked. Junk files are not counted: a library a
+// Mac has browsed has more of them than title folders.
+func (t DeletionThreshold) Check(result *CleanupResult, titles int) error {
+	deletions := 0
+	for _, phase := range deletionPhases(result) {
+		for _, f := range phase {
+			if f.Category != CategoryJunkFile {
+				deletions++
+			}
+		}
+	}
+	if deletions == 0 {
+		return nil
+	}
+	exceeded := t.MaxCount > 0 && deletions > t.MaxCount
+	if t.MaxPercent > 0 && titles >= 0 {
+		// Nothing scanned but something to delete is over any percentage
+		exceeded = exceeded || titles == 0 || float64(deletions)*100 > t.MaxPercent*float64(titles)
+	}
+	if exceeded {
+		return &ThresholdError{Deletions: deletions, Titles: titles, Threshold: t}
+	}
+	return nil
+}
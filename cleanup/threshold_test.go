@@ -0,0 +1,52 @@
+package cleanup
+
+import (
+	"errors"
+	"testing"
+)
+
+// ============================================================================
+// Tests for DeletionThreshold
+// ============================================================================
+
+func TestDeletionThreshold_Check(t *testing.T) {
+	result := &CleanupResult{}
+	result.Add(Finding{Category: CategoryOrphanedFolder, Path: "/lib/S/A (2001)"})
+	result.Add(Finding{Category: CategoryOrphanedFile, Path: "/lib/S/a.nfo"})
+	result.Add(Finding{Category: CategoryEmptyFolder, Path: "/lib/S/B (2002)"})
+	// Warnings are never deleted and do not count
+	result.Add(Finding{Category: CategoryStructureWarning, Path: "/lib/S/x.txt"})
+	// Junk files are deleted but do not count either
+	result.Add(Finding{Category: CategoryJunkFile, Path: "/lib/S/.DS_Store"})
+
+	tests := []struct {
+		name      string
+		threshold DeletionThreshold
+		titles    int
+		exceeded  bool
+	}{
+		{"no limits", DeletionThreshold{}, 3, false},
+		{"count at the limit", DeletionThreshold{MaxCount: 3}, 3, false},
+		{"count over the limit", DeletionThreshold{MaxCount: 2}, 100, true},
+		{"percent at the limit", DeletionThreshold{MaxPercent: 10}, 30, false},
+		{"percent over the limit", DeletionThreshold{MaxPercent: 10}, 29, true},
+		{"percent with nothing scanned", DeletionThreshold{MaxPercent: 10}, 0, true},
+		{"percent with titles unknown", DeletionThreshold{MaxPercent: 10}, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.threshold.Check(result, tt.titles)
+			var exceeded *ThresholdError
+			if errors.As(err, &exceeded) != tt.exceeded {
+				t.Fatalf("Expected exceeded %v, got %v", tt.exceeded, err)
+			}
+			if tt.exceeded && exceeded.Deletions != 3 {
+				t.Errorf("Expected 3 deletions, got %d", exceeded.Deletions)
+			}
+		})
+	}
+
+	if err := (DeletionThreshold{MaxCount: 1, MaxPercent: 1}).Check(&CleanupResult{}, 0); err != nil {
+		t.Errorf("Expected nothing to delete to pass, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces out filesystem operations so that at most a given
+// number start per second, to keep a scan or cleanup from saturating a NAS.
+// One limiter may be shared by scanners and deleters; it is safe for
+// concurrent use.
+type RateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next operation
+}
+
+// NewRateLimiter returns a limiter allowing perSecond operations per second,
+// or nil, which never waits, if perSecond is not positive.
+func NewRateLimiter(perSecond int) *RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// Wait blocks until the next operation may start, or ctx is done. A nil
+// limiter returns at once.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttledFS waits for its limiter before every read of fsys. The scan
+// checks its context between folders, so reads are not cancellable.
+type throttledFS struct {
+	fsys    FS
+	limiter *RateLimiter
+}
+
+func (t throttledFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	_ = t.limiter.Wait(context.Background())
+	return t.fsys.ReadDir(name)
+}
+
+func (t throttledFS) Stat(name string) (fs.FileInfo, error) {
+	_ = t.limiter.Wait(context.Background())
+	return t.fsys.Stat(name)
+}
+
+func (t throttledFS) ReadFile(name string) ([]byte, error) {
+	_ = t.limiter.Wait(context.Background())
+	return t.fsys.ReadFile(name)
+}
+
+func (t throttledFS) Open(name string) (fs.File, error) {
+	opener, ok := t.fsys.(fileOpener)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	_ = t.limiter.Wait(context.Background())
+	return opener.Open(name)
+}
+
+func (t throttledFS) Readlink(name string) (string, error) {
+	links, ok := t.fsys.(linkReader)
+	if !ok {
+		return "", errors.ErrUnsupported
+	}
+	_ = t.limiter.Wait(context.Background())
+	return links.Readlink(name)
+}
@@ -0,0 +1,104 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for RateLimiter
+// ============================================================================
+
+func TestRateLimiter_SpacesOperations(t *testing.T) {
+	limiter := NewRateLimiter(100) // 10ms apart
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait returned error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected 6 operations to take at least 50ms, got %v", elapsed)
+	}
+}
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	limiter := NewRateLimiter(0)
+	if limiter != nil {
+		t.Fatalf("Expected no limiter for 0, got %v", limiter)
+	}
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("Expected a nil limiter never to wait, got %v", err)
+	}
+}
+
+func TestRateLimiter_Cancelled(t *testing.T) {
+	limiter := NewRateLimiter(1)
+	_ = limiter.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected Wait to return once cancelled, took %v", elapsed)
+	}
+}
+
+// ============================================================================
+// Tests for WithRateLimiter and WithDeleteRateLimiter
+// ============================================================================
+
+func TestScan_RateLimited(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Movie (2001)").Video("movie.mkv").
+		Title("Orphan (2002)").Metadata("movie.nfo").
+		MapFS()
+
+	result := &CleanupResult{}
+	start := time.Now()
+	err := NewScanner(WithFS(IOFS(fsys)), WithRateLimiter(NewRateLimiter(200))).Scan(context.Background(), ".", result.Add)
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	// At least the library, studio and both titles are listed, 5ms apart
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("Expected the scan to be slowed down, took %v", elapsed)
+	}
+	if len(result.OrphanedFolders) != 1 {
+		t.Errorf("Expected the same findings as without a limit, got %v", result.Findings)
+	}
+}
+
+func TestDeleter_RateLimited(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	result := &CleanupResult{}
+	for _, name := range []string{"a.nfo", "b.nfo", "c.nfo", "d.nfo"} {
+		path := filepath.Join(tempDir, name)
+		createFile(t, path)
+		result.add(Finding{Category: CategoryOrphanedFile, Path: path})
+	}
+
+	start := time.Now()
+	report, err := NewDeleter(nil, WithDeleteWorkers(4), WithDeleteRateLimiter(NewRateLimiter(100))).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Deleted) != 4 {
+		t.Errorf("Expected 4 deleted, got %d", len(report.Deleted))
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected 4 deletions 10ms apart even with 4 workers, took %v", elapsed)
+	}
+}
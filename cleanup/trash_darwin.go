@@ -0,0 +1,23 @@
+package cleanup
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// moveToSystemTrash moves path into ~/.Trash.
+func moveToSystemTrash(path string) (Moved, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Moved{}, err
+	}
+	trash := filepath.Join(home, ".Trash")
+	if err := os.MkdirAll(trash, 0700); err != nil {
+		return Moved{}, err
+	}
+	target := uniquePath(filepath.Join(trash, filepath.Base(path)))
+	if err := movePath(path, target); err != nil {
+		return Moved{}, err
+	}
+	return Moved{To: target}, nil
+}
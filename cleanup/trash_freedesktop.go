@@ -0,0 +1,150 @@
+//go:build !windows && !darwin
+
+package cleanup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// moveToSystemTrash moves path into the freedesktop.org trash: the home
+// trash when it is on the same filesystem, otherwise $topdir/.Trash-$uid at
+// the root of the filesystem holding path. A .trashinfo file is written so
+// file managers can restore the item.
+func moveToSystemTrash(path string) (Moved, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Moved{}, err
+	}
+	trash, topDir, err := freedesktopTrashFor(abs)
+	if err != nil {
+		return Moved{}, err
+	}
+	filesDir := filepath.Join(trash, "files")
+	infoDir := filepath.Join(trash, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return Moved{}, err
+		}
+	}
+
+	// Paths in a topdir trash are relative to the topdir
+	original := abs
+	if topDir != "" {
+		if original, err = filepath.Rel(topDir, abs); err != nil {
+			return Moved{}, err
+		}
+	}
+
+	name, infoPath, err := reserveTrashInfo(infoDir, filepath.Base(abs), original)
+	if err != nil {
+		return Moved{}, err
+	}
+	target := filepath.Join(filesDir, name)
+	if err := os.Rename(abs, target); err != nil {
+		os.Remove(infoPath)
+		return Moved{}, err
+	}
+	return Moved{To: target, Record: infoPath}, nil
+}
+
+// freedesktopTrashFor returns the trash directory to use for abs, and the
+// topdir it belongs to when it is not the home trash.
+func freedesktopTrashFor(abs string) (trash, topDir string, err error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	home := filepath.Join(dataHome, "Trash")
+	if err := os.MkdirAll(home, 0700); err == nil && sameDevice(abs, home) {
+		return home, "", nil
+	}
+
+	topDir, err = mountRoot(abs)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(topDir, ".Trash-"+strconv.Itoa(os.Getuid())), topDir, nil
+}
+
+// reserveTrashInfo creates the .trashinfo file for an item called base,
+// picking the first free name (base, base.2, base.3, ...). Creating the info
+// file exclusively is what reserves the name, as the spec requires.
+func reserveTrashInfo(infoDir, base, original string) (name, infoPath string, err error) {
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: original}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+
+	for i := 1; ; i++ {
+		name = base
+		if i > 1 {
+			name = base + "." + strconv.Itoa(i)
+		}
+		infoPath = filepath.Join(infoDir, name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := f.WriteString(info); err != nil {
+			f.Close()
+			os.Remove(infoPath)
+			return "", "", err
+		}
+		if err := f.Close(); err != nil {
+			os.Remove(infoPath)
+			return "", "", err
+		}
+		return name, infoPath, nil
+	}
+}
+
+// sameDevice reports whether a and b are on the same filesystem.
+func sameDevice(a, b string) bool {
+	devA, errA := deviceOf(a)
+	devB, errB := deviceOf(b)
+	return errA == nil && errB == nil && devA == devB
+}
+
+// mountRoot returns the topmost ancestor of path on the same filesystem.
+func mountRoot(path string) (string, error) {
+	dev, err := deviceOf(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path, nil
+		}
+		parentDev, err := deviceOf(parent)
+		if err != nil || parentDev != dev {
+			return path, nil
+		}
+		path = parent
+	}
+}
+
+func deviceOf(path string) (uint64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("cannot determine device of %s", path)
+	}
+	return uint64(st.Dev), nil
+}
@@ -0,0 +1,9 @@
+package cleanup
+
+import "errors"
+
+// moveToSystemTrash is not implemented on Windows: the Recycle Bin is only
+// reachable through the shell API.
+func moveToSystemTrash(path string) (Moved, error) {
+	return Moved{}, errors.New("system trash is not supported on Windows, use the trash strategy with a trash directory")
+}
@@ -0,0 +1,68 @@
+package cleanup
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// episodeMarker matches the episode numbering in a file name: S01E02,
+// s1e2, 1x02 and the like.
+var episodeMarker = regexp.MustCompile(`(?i)(^|[^a-z0-9])(s\d{1,4}e\d{1,4}|\d{1,2}x\d{2,4})`)
+
+// isEpisodeName reports whether name carries episode numbering, which sets
+// an episode's metadata apart from the season's own (season.nfo, poster.jpg).
+func isEpisodeName(name string) bool {
+	return episodeMarker.MatchString(name)
+}
+
+// checkEpisodeMetadata reports the metadata of episodes that are gone from
+// the season folder seasonPath: files named after an episode (e.g.
+// "Show S01E02.nfo" or "Show S01E02-thumb.jpg") that no video name is a
+// prefix of become orphaned files, and metadata folders such as
+// "Show S01E02.trickplay" orphaned folders. Season metadata is left alone.
+func (r *scanRun) checkEpisodeMetadata(seasonPath string, entries []fs.DirEntry, videoNames []string) {
+	var bases []string
+	for _, name := range videoNames {
+		bases = append(bases, videoBases(name)...)
+	}
+	hasVideo := func(name string) bool {
+		lower := nameKey(name)
+		for _, base := range bases {
+			if strings.HasPrefix(lower, base) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !isEpisodeName(name) || hasVideo(name) {
+			continue
+		}
+		path := filepath.Join(seasonPath, name)
+		switch r.classifier.Classify(name, entry.IsDir()) {
+		case KindMetadata:
+			var usage Usage
+			if info, err := entry.Info(); err == nil {
+				usage = fileUsage(path, info)
+			}
+			r.emit(Finding{Category: CategoryOrphanedFile, Path: path, Usage: usage})
+		case KindMetadataDir:
+			if !r.checks(CategoryOrphanedFolder) {
+				continue
+			}
+			children, err := r.fsys.ReadDir(path)
+			if err != nil {
+				r.fail(&ErrUnreadableDir{Path: path, Err: err})
+				continue
+			}
+			if r.ignoredBelow(path, children) {
+				continue
+			}
+			r.emit(Finding{Category: CategoryOrphanedFolder, Path: path, Usage: r.treeUsage(path, children)})
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package cleanup
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for the TV layout
+// ============================================================================
+
+func TestIsEpisodeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"Show S01E02.nfo", true},
+		{"show.s1e2-thumb.jpg", true},
+		{"Show - 1x02.nfo", true},
+		{"Show S01E02.trickplay", true},
+		{"season.nfo", false},
+		{"poster.jpg", false},
+		{"season01-poster.jpg", false},
+		{"1920x1080.jpg", false},
+	}
+	for _, tt := range tests {
+		if got := isEpisodeName(tt.name); got != tt.expected {
+			t.Errorf("isEpisodeName(%q): expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestLayoutByName(t *testing.T) {
+	for _, name := range []string{"movies", "tv", "flat"} {
+		if layout, err := LayoutByName(name); err != nil || layout.Name != name {
+			t.Errorf("Expected layout %q, got %v, %v", name, layout.Name, err)
+		}
+	}
+	if _, err := LayoutByName("anime"); err == nil {
+		t.Error("Expected unknown structure to fail")
+	}
+}
+
+func TestScan_TVLayout(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Show A").Metadata("tvshow.nfo", "poster.jpg").
+		Title("Season 01").Video("Show A S01E01.mkv", "Show A S01E02.mkv").
+		Metadata("season.nfo", "Show A S01E01.nfo", "Show A S01E02-thumb.jpg", "Show A S01E03.nfo", "Show A S01E03-thumb.jpg").
+		Dir("Show A S01E01.trickplay", "1.jpg").Dir("Show A S01E03.trickplay", "1.jpg").
+		Title("Season 02").Metadata("season.nfo", "Show A S02E01.nfo").
+		Title("Season 03").
+		Studio("Gone Show").Metadata("tvshow.nfo").
+		Title("Season 01").Metadata("Gone Show S01E01.nfo").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithLayout(TVLayout)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.StructureWarnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", result.StructureWarnings)
+	}
+
+	expectedFolders := []string{
+		filepath.Join("Gone Show", "Season 01"),
+		filepath.Join("Show A", "Season 01", "Show A S01E03.trickplay"),
+		filepath.Join("Show A", "Season 02"),
+	}
+	sort.Strings(result.OrphanedFolders)
+	if len(result.OrphanedFolders) != len(expectedFolders) {
+		t.Fatalf("Expected orphaned folders %v, got %v", expectedFolders, result.OrphanedFolders)
+	}
+	for i, expected := range expectedFolders {
+		if result.OrphanedFolders[i] != expected {
+			t.Errorf("Expected orphaned folder %s, got %s", expected, result.OrphanedFolders[i])
+		}
+	}
+
+	// The metadata of a show with videos left is kept, that of a show
+	// without any goes
+	expectedFiles := []string{
+		filepath.Join("Gone Show", "tvshow.nfo"),
+		filepath.Join("Show A", "Season 01", "Show A S01E03-thumb.jpg"),
+		filepath.Join("Show A", "Season 01", "Show A S01E03.nfo"),
+	}
+	sort.Strings(result.OrphanedFiles)
+	if len(result.OrphanedFiles) != len(expectedFiles) {
+		t.Fatalf("Expected orphaned files %v, got %v", expectedFiles, result.OrphanedFiles)
+	}
+	for i, expected := range expectedFiles {
+		if result.OrphanedFiles[i] != expected {
+			t.Errorf("Expected orphaned file %s, got %s", expected, result.OrphanedFiles[i])
+		}
+	}
+
+	if len(result.EmptyFolders) != 1 || result.EmptyFolders[0] != filepath.Join("Show A", "Season 03") {
+		t.Errorf("Expected the empty Season 03, got %v", result.EmptyFolders)
+	}
+}
+
+func TestScan_TVLayout_VideoAtShowLevel(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Show").Video("Show S01E01.mkv").Metadata("tvshow.nfo").
+		Title("Season 01").Video("Show S01E02.mkv").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys)), WithLayout(TVLayout)).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	if len(result.StructureWarnings) != 1 {
+		t.Errorf("Expected 1 warning for the episode at show level, got %v", result.StructureWarnings)
+	}
+	if len(result.OrphanedFiles) != 0 {
+		t.Errorf("Expected tvshow.nfo to be kept, got %v", result.OrphanedFiles)
+	}
+}
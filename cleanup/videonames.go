@@ -0,0 +1,140 @@
+package cleanup
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// partMarker matches multi-part numbering at the end of a name: cd1,
+// disc 2, part-3, pt4 and the like.
+var partMarker = regexp.MustCompile(`^(cd|disc|disk|dvd|part|pt)\d+$`)
+
+// extraSuffixes are the Emby/Jellyfin suffixes marking a file as an extra
+// of the title it is named after, e.g. "Movie-trailer.mkv".
+var extraSuffixes = []string{
+	"-trailer", "-sample", "-featurette", "-behindthescenes", "-deleted",
+	"-deletedscene", "-interview", "-scene", "-short", "-clip", "-other", "-extra",
+}
+
+// releaseTokens are words describing a version of a title rather than the
+// title itself: quality, source, codec and edition tags.
+var releaseTokens = map[string]bool{
+	"480p": true, "576p": true, "720p": true, "1080p": true, "1080i": true, "2160p": true,
+	"4k": true, "uhd": true, "hd": true, "sd": true, "hdr": true, "hdr10": true, "dv": true,
+	"bluray": true, "bdrip": true, "brrip": true, "web": true, "webdl": true, "webrip": true,
+	"dl": true, "hdtv": true, "dvdrip": true, "remux": true,
+	"x264": true, "x265": true, "h264": true, "h265": true, "hevc": true, "avc": true, "av1": true,
+	"extended": true, "directors": true, "director": true, "cut": true, "uncut": true,
+	"theatrical": true, "unrated": true, "imax": true, "remastered": true, "edition": true,
+	"special": true, "final": true, "ultimate": true, "version": true, "s": true,
+}
+
+// nameKey returns the form file names are compared in when matching
+// metadata to videos. Case-insensitive filesystems (NTFS, APFS, SMB shares)
+// treat "Movie.NFO" as the name of "movie.mkv", so names are case-folded:
+// each letter is upper- then lowercased, which also brings together
+// variants lowercasing alone leaves apart, such as "ς" and "σ" or the
+// Kelvin sign and "k". Names are decomposed first (see nfd), so accented
+// letters match however they were written.
+func nameKey(name string) string {
+	return strings.Map(func(r rune) rune { return unicode.ToLower(unicode.ToUpper(r)) }, nfd(name))
+}
+
+// videoBases returns the names, in nameKey form, the metadata of the video
+// file name is named after: its name without extension and, for a part of a
+// multi-part video such as "movie-cd1.mkv" or "movie - part2.mkv", the name
+// without the part number, which Emby and Jellyfin name the metadata of the
+// whole video after ("movie.nfo").
+func videoBases(name string) []string {
+	stem := nameKey(strings.TrimSuffix(name, filepath.Ext(name)))
+	bases := []string{stem}
+	if stacked := strings.TrimSpace(trailingPart.ReplaceAllString(stem, "")); stacked != stem && stacked != "" {
+		bases = append(bases, stacked)
+	}
+	return bases
+}
+
+// titleWords reduces a video file name to the words naming its title:
+// lowercased, without extension, extra suffix, bracketed tags, part numbers
+// and release tokens. Names of the same title reduce to the same words, or
+// one to a prefix of the other.
+func titleWords(name string) []string {
+	stem := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	for _, suffix := range extraSuffixes {
+		if strings.HasSuffix(stem, suffix) {
+			stem = strings.TrimSuffix(stem, suffix)
+			break
+		}
+	}
+	// Plex-style {edition-...} and [tags] describe the version
+	stem = stripBracketed(stem, '{', '}')
+	stem = stripBracketed(stem, '[', ']')
+
+	fields := strings.FieldsFunc(stem, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var words []string
+	for i := 0; i < len(fields); i++ {
+		w := fields[i]
+		// "cd 1" and "part 2" are split by the separator
+		if i+1 < len(fields) && partMarker.MatchString(w+fields[i+1]) {
+			i++
+			continue
+		}
+		if partMarker.MatchString(w) || releaseTokens[w] {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+func stripBracketed(s string, open, close byte) string {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == open:
+			depth++
+		case s[i] == close && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// relatedVideoNames reports whether the video file names all look like the
+// same title: parts (cd1/cd2), editions or qualities of one name, or extras
+// named after it.
+func relatedVideoNames(names []string) bool {
+	if len(names) < 2 {
+		return true
+	}
+	first := titleWords(names[0])
+	for _, name := range names[1:] {
+		words := titleWords(name)
+		if len(first) == 0 || len(words) == 0 {
+			continue // Nothing left to compare, e.g. "1080p.mkv"
+		}
+		if !isWordPrefix(first, words) && !isWordPrefix(words, first) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordPrefix(prefix, words []string) bool {
+	if len(prefix) == 0 || len(prefix) > len(words) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != words[i] {
+			return false
+		}
+	}
+	return true
+}
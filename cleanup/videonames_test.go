@@ -0,0 +1,118 @@
+package cleanup
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for video name relatedness
+// ============================================================================
+
+func TestTitleWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"The Matrix (1999).mkv", []string{"the", "matrix", "1999"}},
+		{"The.Matrix.1999.1080p.BluRay.x264.mkv", []string{"the", "matrix", "1999"}},
+		{"movie-cd1.avi", []string{"movie"}},
+		{"Movie Part 2.mkv", []string{"movie"}},
+		{"Movie (2020) {edition-Director's Cut}.mkv", []string{"movie", "2020"}},
+		{"Movie (2020) [Extended].mkv", []string{"movie", "2020"}},
+		{"Movie (2020)-trailer.mp4", []string{"movie", "2020"}},
+	}
+	for _, tt := range tests {
+		if got := titleWords(tt.name); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("titleWords(%q): expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestNameKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"Movie.NFO", "movie.nfo"},
+		{"ΟΔΥΣΣΕΥΣ", "Οδυσσευς"},  // final sigma
+		{"\u212Aelvin", "kelvin"}, // Kelvin sign
+		{"Stra\u017Fe", "STRASE"}, // long s
+	}
+	for _, tt := range tests {
+		if nameKey(tt.a) != nameKey(tt.b) {
+			t.Errorf("Expected %q and %q to match, got %q and %q", tt.a, tt.b, nameKey(tt.a), nameKey(tt.b))
+		}
+	}
+	if nameKey("movie") == nameKey("movie 2") {
+		t.Error("Expected different names to stay apart")
+	}
+}
+
+func TestVideoBases(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"Movie.mkv", []string{"movie"}},
+		{"movie-cd1.mkv", []string{"movie-cd1", "movie"}},
+		{"Movie - Part2.mkv", []string{"movie - part2", "movie"}},
+		{"Movie.Disc 2.avi", []string{"movie.disc 2", "movie"}},
+		{"cd1.mkv", []string{"cd1"}},
+		{"Apartment 2.mkv", []string{"apartment 2"}},
+	}
+	for _, tt := range tests {
+		if got := videoBases(tt.name); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("videoBases(%q): expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestRelatedVideoNames(t *testing.T) {
+	tests := []struct {
+		names    []string
+		expected bool
+	}{
+		{[]string{"movie.mkv"}, true},
+		{[]string{"movie-cd1.avi", "movie-cd2.avi"}, true},
+		{[]string{"Movie (2020) - 1080p.mkv", "Movie (2020) - 2160p.mkv"}, true},
+		{[]string{"Movie (2020).mkv", "Movie (2020) - Director's Cut.mkv"}, true},
+		{[]string{"Movie (2020).mkv", "Movie (2020)-trailer.mp4"}, true},
+		{[]string{"Movie.mkv", "Movie.Extended.mkv"}, true},
+		{[]string{"1080p.mkv", "Movie.mkv"}, true},
+		{[]string{"The Matrix (1999).mkv", "Jurassic Park (1993).mkv"}, false},
+		{[]string{"Movie (2020).mkv", "Movie (2020).mkv", "Other.mkv"}, false},
+	}
+	for _, tt := range tests {
+		if got := relatedVideoNames(tt.names); got != tt.expected {
+			t.Errorf("relatedVideoNames(%q): expected %v, got %v", tt.names, tt.expected, got)
+		}
+	}
+}
+
+func TestScan_UnrelatedVideosWarning(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio").
+		Title("Merged").Video("The Matrix (1999).mkv", "Jurassic Park (1993).mkv").
+		Title("Parts").Video("movie-cd1.avi", "movie-cd2.avi").
+		MapFS()
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(IOFS(fsys))).Scan(context.Background(), ".", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(result.StructureWarnings) != 1 {
+		t.Fatalf("Expected 1 warning for the merged folder, got %v", result.StructureWarnings)
+	}
+	if !strings.HasPrefix(result.StructureWarnings[0], "Multiple unrelated videos in title folder") ||
+		!strings.HasSuffix(result.StructureWarnings[0], "Merged") {
+		t.Errorf("Unexpected warning %q", result.StructureWarnings[0])
+	}
+	if len(result.OrphanedFolders) != 0 {
+		t.Errorf("Folders with videos should not be orphaned, got %v", result.OrphanedFolders)
+	}
+}
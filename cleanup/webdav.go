@@ -0,0 +1,326 @@
+package cleanup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WebDAVTarget is a library on a WebDAV server such as Nextcloud, given as
+// webdav://[user@]host[:port]/path, or webdavs:// for https.
+type WebDAVTarget struct {
+	Endpoint string // http(s)://host[:port]
+	User     string
+	Path     string // URL path of the library, unescaped
+}
+
+// IsWebDAVURL reports whether s names a library with the webdav:// or
+// webdavs:// scheme.
+func IsWebDAVURL(s string) bool {
+	s = strings.ToLower(s)
+	return strings.HasPrefix(s, "webdav://") || strings.HasPrefix(s, "webdavs://")
+}
+
+// ParseWebDAVURL parses a webdav:// or webdavs:// library URL.
+func ParseWebDAVURL(s string) (WebDAVTarget, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return WebDAVTarget{}, err
+	}
+	scheme := map[string]string{"webdav": "http", "webdavs": "https"}[strings.ToLower(u.Scheme)]
+	if scheme == "" || u.Host == "" {
+		return WebDAVTarget{}, fmt.Errorf("invalid library URL %q (expected webdav[s]://[user@]host[:port]/path)", s)
+	}
+	return WebDAVTarget{Endpoint: scheme + "://" + u.Host, User: u.User.Username(), Path: path.Clean("/" + u.Path)}, nil
+}
+
+// String returns t in its webdav:// or webdavs:// form.
+func (t WebDAVTarget) String() string {
+	scheme, host, _ := strings.Cut(t.Endpoint, "://")
+	u := url.URL{Scheme: "webdav", Host: host, Path: t.Path}
+	if scheme == "https" {
+		u.Scheme = "webdavs"
+	}
+	if t.User != "" {
+		u.User = url.User(t.User)
+	}
+	return u.String()
+}
+
+// WebDAVClient makes the PROPFIND, GET and DELETE requests WebDAVFS and
+// WebDAVStrategy need, with basic authentication if User is set.
+type WebDAVClient struct {
+	Endpoint string // http(s)://host[:port]
+	User     string
+	Password string
+	// PageSize is the number of entries asked per PROPFIND on servers that
+	// paginate listings (Nextcloud); 1000 if 0.
+	PageSize   int
+	HTTPClient *http.Client
+}
+
+// propfindBody asks for the only properties the scanner uses.
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/><d:getlastmodified/></d:prop></d:propfind>`
+
+// davResponse is one resource of a PROPFIND multistatus answer.
+type davResponse struct {
+	Href     string `xml:"DAV: href"`
+	Propstat []struct {
+		Status string `xml:"DAV: status"`
+		Prop   struct {
+			Collection *struct{} `xml:"DAV: resourcetype>collection"`
+			Length     int64     `xml:"DAV: getcontentlength"`
+			Modified   string    `xml:"DAV: getlastmodified"`
+		} `xml:"DAV: prop"`
+	} `xml:"DAV: propstat"`
+}
+
+// request sends a request for the resource at p and returns the body and
+// headers of the answer.
+func (c *WebDAVClient) request(method, p string, header http.Header, body []byte) ([]byte, http.Header, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("WebDAV endpoint: %w", err)
+	}
+	u.Path = p
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Password)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, nil, &WebDAVError{Method: method, Status: resp.StatusCode}
+	}
+	return data, resp.Header, nil
+}
+
+// propfind lists the resource at p, and its members if depth is 1.
+// Nextcloud answers huge folders a page at a time when asked to with its
+// X-NC-Paginate headers; other servers ignore them and answer in full.
+func (c *WebDAVClient) propfind(p string, depth int) ([]*davEntry, error) {
+	size := c.PageSize
+	if size == 0 {
+		size = 1000
+	}
+	header := http.Header{
+		"Depth":               {strconv.Itoa(depth)},
+		"Content-Type":        {"application/xml; charset=utf-8"},
+		"X-Nc-Paginate":       {"true"},
+		"X-Nc-Paginate-Count": {strconv.Itoa(size)},
+	}
+	var entries []*davEntry
+	for offset := 0; ; offset += size {
+		data, answer, err := c.request("PROPFIND", p, header, []byte(propfindBody))
+		if err != nil {
+			return nil, err
+		}
+		var multistatus struct {
+			Responses []davResponse `xml:"DAV: response"`
+		}
+		if err := xml.Unmarshal(data, &multistatus); err != nil {
+			return nil, fmt.Errorf("PROPFIND %s: %w", p, err)
+		}
+		for _, response := range multistatus.Responses {
+			entry, err := newDAVEntry(response)
+			if err != nil {
+				return nil, fmt.Errorf("PROPFIND %s: %w", p, err)
+			}
+			entries = append(entries, entry)
+		}
+		// Later pages are fetched with the token of the first one
+		if offset == 0 {
+			header.Set("X-Nc-Paginate-Token", answer.Get("X-Nc-Paginate-Token"))
+		}
+		total, _ := strconv.Atoi(answer.Get("X-Nc-Paginate-Total"))
+		if header.Get("X-Nc-Paginate-Token") == "" || offset+size >= total {
+			return entries, nil
+		}
+		header.Set("X-Nc-Paginate-Offset", strconv.Itoa(offset+size))
+	}
+}
+
+// davEntry is a listed resource, at its unescaped URL path.
+type davEntry struct {
+	path string
+	*remoteEntry
+}
+
+func newDAVEntry(response davResponse) (*davEntry, error) {
+	u, err := url.Parse(response.Href)
+	if err != nil {
+		return nil, err
+	}
+	p := path.Clean("/" + u.Path)
+	entry := &davEntry{path: p, remoteEntry: &remoteEntry{name: path.Base(p), mode: 0644}}
+	for _, propstat := range response.Propstat {
+		// Properties a resource does not have come with a 404 status
+		if !strings.Contains(propstat.Status, " 200") {
+			continue
+		}
+		if propstat.Prop.Collection != nil {
+			entry.mode = fs.ModeDir | 0755
+		}
+		entry.size = propstat.Prop.Length
+		if modified, err := http.ParseTime(propstat.Prop.Modified); err == nil {
+			entry.modTime = modified
+		}
+	}
+	if entry.IsDir() {
+		entry.size = 0
+	}
+	return entry, nil
+}
+
+// WebDAVFS is a read-only FS over a library on a WebDAV server, for WithFS.
+// Each folder is listed with one PROPFIND when the scanner reads it, and
+// the items found there are stat'ed from that listing. Paths are the URL
+// paths on the server, so the library root to scan is WebDAVTarget.Path.
+type WebDAVFS struct {
+	Client *WebDAVClient
+
+	mu      sync.Mutex
+	entries map[string]*remoteEntry  // by path
+	listed  map[string][]fs.DirEntry // by folder path
+}
+
+// list returns the members of the folder p, listing it once.
+func (w *WebDAVFS) list(p string) ([]fs.DirEntry, error) {
+	w.mu.Lock()
+	children, ok := w.listed[p]
+	w.mu.Unlock()
+	if ok {
+		return children, nil
+	}
+	listing, err := w.Client.propfind(p, 1)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.entries == nil {
+		w.entries = map[string]*remoteEntry{}
+		w.listed = map[string][]fs.DirEntry{}
+	}
+	children = []fs.DirEntry{}
+	for _, entry := range listing {
+		w.entries[entry.path] = entry.remoteEntry
+		if entry.path != p {
+			children = append(children, entry.remoteEntry)
+		}
+	}
+	if self := w.entries[p]; self == nil || !self.IsDir() {
+		return nil, ErrNotADirectory
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+	w.listed[p] = children
+	return children, nil
+}
+
+func (w *WebDAVFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	children, err := w.list(remotePath(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return children, nil
+}
+
+// Stat answers from the listing of the parent folder once it was read,
+// and asks the server otherwise.
+func (w *WebDAVFS) Stat(name string) (fs.FileInfo, error) {
+	p := remotePath(name)
+	w.mu.Lock()
+	entry := w.entries[p]
+	_, parentListed := w.listed[path.Dir(p)]
+	w.mu.Unlock()
+	if entry != nil {
+		return entry, nil
+	}
+	if parentListed {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	listing, err := w.Client.propfind(p, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if len(listing) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return listing[0].remoteEntry, nil
+}
+
+func (w *WebDAVFS) ReadFile(name string) ([]byte, error) {
+	data, _, err := w.Client.request(http.MethodGet, remotePath(name), nil, nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// WebDAVStrategy deletes findings of a WebDAVFS scan from the server. A
+// DELETE of a folder removes everything in it, so an empty folder is
+// listed again first and only deleted while it is still empty.
+type WebDAVStrategy struct {
+	Client *WebDAVClient
+}
+
+func (WebDAVStrategy) Name() string { return "webdav" }
+
+func (s WebDAVStrategy) Delete(f Finding) error {
+	p := remotePath(f.Path)
+	if f.Category == CategoryEmptyFolder {
+		listing, err := s.Client.propfind(p, 1)
+		if err != nil {
+			return err
+		}
+		// The folder itself comes first; OS junk is deleted along with it
+		for _, entry := range listing[1:] {
+			if entry.IsDir() || !isJunk(entry.Name()) {
+				return fmt.Errorf("folder is no longer empty")
+			}
+		}
+	}
+	_, _, err := s.Client.request(http.MethodDelete, p, nil, nil)
+	return err
+}
+
+// Exists reports whether path is still on the server, so the Deleter skips
+// items deleted along with a parent.
+func (s WebDAVStrategy) Exists(path string) (bool, error) {
+	_, err := s.Client.propfind(remotePath(path), 0)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,229 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDAV serves the WebDAV requests WebDAVFS and WebDAVStrategy make.
+// Folders are the paths ending in "/"; listings are paginated the way
+// Nextcloud does it when asked to.
+type fakeDAV struct {
+	mu         sync.Mutex
+	items      map[string]string
+	laterPages int // PROPFINDs answered past the first page
+}
+
+func (f *fakeDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if user, password, _ := r.BasicAuth(); user != "me" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p := path.Clean(r.URL.Path)
+	_, isFile := f.items[p]
+	_, isDir := f.items[p+"/"]
+	if !isFile && !isDir {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		fmt.Fprint(w, f.items[p])
+	case http.MethodDelete:
+		for item := range f.items {
+			if item == p || strings.HasPrefix(item, p+"/") {
+				delete(f.items, item)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "PROPFIND":
+		listing := []string{p}
+		if isDir && r.Header.Get("Depth") == "1" {
+			for item := range f.items {
+				if child := strings.TrimSuffix(item, "/"); path.Dir(child) == p && child != p {
+					listing = append(listing, child)
+				}
+			}
+			sort.Strings(listing[1:])
+		}
+		if r.Header.Get("X-Nc-Paginate") == "true" {
+			offset, _ := strconv.Atoi(r.Header.Get("X-Nc-Paginate-Offset"))
+			count, _ := strconv.Atoi(r.Header.Get("X-Nc-Paginate-Count"))
+			w.Header().Set("X-Nc-Paginate-Token", "token")
+			w.Header().Set("X-Nc-Paginate-Total", strconv.Itoa(len(listing)))
+			listing = listing[offset:min(offset+count, len(listing))]
+			if offset > 0 {
+				f.laterPages++
+			}
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+		for _, item := range listing {
+			href := (&url.URL{Path: item}).EscapedPath()
+			props := `<d:resourcetype/><d:getcontentlength>` + strconv.Itoa(len(f.items[item])) + `</d:getcontentlength>`
+			if _, ok := f.items[item+"/"]; ok {
+				href += "/"
+				props = `<d:resourcetype><d:collection/></d:resourcetype>`
+			}
+			fmt.Fprintf(w, `<d:response><d:href>%s</d:href><d:propstat><d:prop>%s<d:getlastmodified>Thu, 02 Jan 2020 03:04:05 GMT</d:getlastmodified></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, href, props)
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeDAV(t *testing.T, items map[string]string) (*fakeDAV, *WebDAVClient) {
+	t.Helper()
+	fake := &fakeDAV{items: items}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, &WebDAVClient{Endpoint: server.URL, User: "me", Password: "secret", PageSize: 2}
+}
+
+// ============================================================================
+// Tests for ParseWebDAVURL
+// ============================================================================
+
+func TestParseWebDAVURL(t *testing.T) {
+	tests := map[string]WebDAVTarget{
+		"webdavs://me@cloud.example/remote.php/dav/files/me/Movies/": {Endpoint: "https://cloud.example", User: "me", Path: "/remote.php/dav/files/me/Movies"},
+		"webdav://nas:8080/Video%20Library":                          {Endpoint: "http://nas:8080", Path: "/Video Library"},
+	}
+	for s, expected := range tests {
+		target, err := ParseWebDAVURL(s)
+		if err != nil || target != expected {
+			t.Errorf("%s: expected %+v, got %+v (%v)", s, expected, target, err)
+		}
+	}
+	if s := (WebDAVTarget{Endpoint: "https://cloud.example", User: "me", Path: "/Movies"}).String(); s != "webdavs://me@cloud.example/Movies" {
+		t.Errorf("Expected webdavs://me@cloud.example/Movies, got %s", s)
+	}
+	for _, s := range []string{"webdav:///Movies", "http://nas/Movies"} {
+		if _, err := ParseWebDAVURL(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+// ============================================================================
+// Tests for WebDAVFS and WebDAVStrategy
+// ============================================================================
+
+var davLibrary = map[string]string{
+	"/dav/Movies/":                            "",
+	"/dav/Movies/Studio/":                     "",
+	"/dav/Movies/Studio/Movie (2001)/":        "",
+	"/dav/Movies/Studio/Movie (2001)/a.mkv":   "video",
+	"/dav/Movies/Studio/Movie (2001)/a.nfo":   "<movie/>",
+	"/dav/Movies/Studio/Orphan (2002)/":       "",
+	"/dav/Movies/Studio/Orphan (2002)/a.nfo":  "<movie/>",
+	"/dav/Movies/Studio/Orphan (2002)/b.jpg":  "jpg",
+	"/dav/Movies/Studio/Orphan (2002)/c.jpg":  "jpg",
+	"/dav/Movies/Studio/Empty (2003)/":        "",
+	"/dav/Movies/Studio/stray.nfo":            "<movie/>",
+	"/dav/Movies/Studio/Other (2004)/":        "",
+	"/dav/Movies/Studio/Other (2004)/b.mkv":   "video",
+	"/dav/Movies/Studio/Another (2005)/":      "",
+	"/dav/Movies/Studio/Another (2005)/c.mkv": "video",
+}
+
+func copyItems(items map[string]string) map[string]string {
+	copied := make(map[string]string, len(items))
+	for k, v := range items {
+		copied[k] = v
+	}
+	return copied
+}
+
+func TestWebDAVFS_Scan(t *testing.T) {
+	fake, client := newFakeDAV(t, copyItems(davLibrary))
+
+	result := &CleanupResult{}
+	if err := NewScanner(WithFS(&WebDAVFS{Client: client})).Scan(context.Background(), "/dav/Movies", result.Add); err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+	expected := map[Category]string{
+		CategoryOrphanedFolder: "/dav/Movies/Studio/Orphan (2002)",
+		CategoryEmptyFolder:    "/dav/Movies/Studio/Empty (2003)",
+		CategoryOrphanedFile:   "/dav/Movies/Studio/stray.nfo",
+	}
+	if len(result.Findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %v", len(expected), result.Findings)
+	}
+	for _, f := range result.Findings {
+		if expected[f.Category] != f.Path {
+			t.Errorf("Expected %s for %s, got %s", expected[f.Category], f.Category, f.Path)
+		}
+	}
+	// The studio folder and its 6 items take 4 pages of 2
+	if fake.laterPages < 3 {
+		t.Errorf("Expected the studio folder to be listed a page at a time, got %d later pages", fake.laterPages)
+	}
+}
+
+func TestWebDAVFS_MissingLibrary(t *testing.T) {
+	_, client := newFakeDAV(t, copyItems(davLibrary))
+
+	err := NewScanner(WithFS(&WebDAVFS{Client: client})).Scan(context.Background(), "/dav/Films", (&CleanupResult{}).Add)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestWebDAVStrategy_Delete(t *testing.T) {
+	fake, client := newFakeDAV(t, copyItems(davLibrary))
+	result := &CleanupResult{}
+	for category, path := range map[Category]string{
+		CategoryOrphanedFolder: "/dav/Movies/Studio/Orphan (2002)",
+		CategoryEmptyFolder:    "/dav/Movies/Studio/Empty (2003)",
+		CategoryOrphanedFile:   "/dav/Movies/Studio/stray.nfo",
+	} {
+		result.add(Finding{Category: category, Path: path, Library: "/dav/Movies"})
+	}
+	// Already deleted with its parent
+	result.add(Finding{Category: CategoryOrphanedFile, Path: "/dav/Movies/Studio/Orphan (2002)/a.nfo", Library: "/dav/Movies"})
+
+	report, err := NewDeleter(WebDAVStrategy{Client: client}).Delete(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(report.Failures) != 0 {
+		t.Fatalf("Expected no failures, got %v", report.Failures)
+	}
+	if len(report.Deleted) != 3 || len(report.Skipped) != 1 {
+		t.Errorf("Expected 3 deleted and 1 skipped, got %d and %d", len(report.Deleted), len(report.Skipped))
+	}
+	for item := range fake.items {
+		if strings.Contains(item, "Orphan") || strings.Contains(item, "Empty") || strings.Contains(item, "stray") {
+			t.Errorf("Expected %s to be deleted", item)
+		}
+	}
+}
+
+func TestWebDAVStrategy_EmptyFolderFilledSinceScan(t *testing.T) {
+	fake, client := newFakeDAV(t, map[string]string{
+		"/dav/Movies/Studio/Empty (2003)/":      "",
+		"/dav/Movies/Studio/Empty (2003)/a.mkv": "video",
+	})
+	finding := Finding{Category: CategoryEmptyFolder, Path: "/dav/Movies/Studio/Empty (2003)"}
+	if err := (WebDAVStrategy{Client: client}).Delete(finding); err == nil {
+		t.Errorf("Expected an error for a folder that is no longer empty")
+	}
+	if len(fake.items) != 2 {
+		t.Errorf("Expected nothing deleted, got %v", fake.items)
+	}
+}
@@ -0,0 +1,196 @@
+// Package cleanuptest builds fixture library trees for tests of
+// video-folder-cleanup and of code that drives it.
+//
+// A Builder describes a library with a fluent, cursor-based API: Studio and
+// Title move the cursor into a new folder, and Video, Metadata, Junk and Dir
+// add entries to the folder under the cursor. The same description can then
+// be materialized as an fstest.MapFS or written to disk:
+//
+//	lib := cleanuptest.New().
+//		Studio("Warner Bros").
+//		Title("The Matrix (1999)").Video("The Matrix.mkv").Metadata("The Matrix.nfo", "poster.jpg").
+//		Title("Deleted Movie (2020)").Metadata("Deleted Movie.nfo").
+//		Studio("Empty Studio")
+//
+//	fsys := lib.MapFS()    // scan "." through an fs.FS
+//	root := lib.TempDir(t) // or scan a real directory
+package cleanuptest
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+// Placeholder contents written to fixture files.
+var (
+	VideoContent    = []byte("video")
+	MetadataContent = []byte("metadata")
+	JunkContent     = []byte("junk")
+)
+
+type entry struct {
+	dir  bool
+	data []byte
+}
+
+// Builder describes a fixture library tree. The zero value is not usable;
+// create one with New.
+type Builder struct {
+	entries map[string]entry // slash-separated paths relative to the library root
+	studio  string
+	title   string
+}
+
+// New returns a Builder for an empty library.
+func New() *Builder {
+	return &Builder{entries: map[string]entry{}}
+}
+
+// Studio adds a studio folder at the library root and moves the cursor into
+// it. A studio left without titles is an empty folder.
+func (b *Builder) Studio(name string) *Builder {
+	b.studio, b.title = name, ""
+	b.addDir(name)
+	return b
+}
+
+// Title adds a title folder in the current studio and moves the cursor into
+// it. A title left without entries is an empty folder. Title panics if no
+// studio was started.
+func (b *Builder) Title(name string) *Builder {
+	if b.studio == "" {
+		panic("cleanuptest: Title called before Studio")
+	}
+	b.title = name
+	b.addDir(path.Join(b.studio, name))
+	return b
+}
+
+// Root moves the cursor back to the library root, so following entries are
+// added at library level.
+func (b *Builder) Root() *Builder {
+	b.studio, b.title = "", ""
+	return b
+}
+
+// Up moves the cursor from a title back to its studio, so following entries
+// are added at studio level.
+func (b *Builder) Up() *Builder {
+	b.title = ""
+	return b
+}
+
+// Video adds video files to the current folder.
+func (b *Builder) Video(names ...string) *Builder {
+	return b.files(VideoContent, names)
+}
+
+// Metadata adds metadata files (.nfo, images, ...) to the current folder.
+func (b *Builder) Metadata(names ...string) *Builder {
+	return b.files(MetadataContent, names)
+}
+
+// Junk adds unrelated files (Thumbs.db, .DS_Store, ...) to the current folder.
+func (b *Builder) Junk(names ...string) *Builder {
+	return b.files(JunkContent, names)
+}
+
+// File adds a file with the given content to the current folder, e.g. an
+// NFO whose fields a test depends on.
+func (b *Builder) File(name string, data []byte) *Builder {
+	return b.files(data, []string{name})
+}
+
+// Dir adds a subdirectory of the current folder, e.g. "movie.trickplay", with
+// optional files inside it. The cursor does not move.
+func (b *Builder) Dir(name string, files ...string) *Builder {
+	dir := path.Join(b.cwd(), name)
+	b.addDir(dir)
+	for _, f := range files {
+		b.entries[path.Join(dir, f)] = entry{data: MetadataContent}
+	}
+	return b
+}
+
+func (b *Builder) files(data []byte, names []string) *Builder {
+	for _, name := range names {
+		b.entries[path.Join(b.cwd(), name)] = entry{data: data}
+	}
+	return b
+}
+
+func (b *Builder) addDir(p string) {
+	if _, ok := b.entries[p]; !ok {
+		b.entries[p] = entry{dir: true}
+	}
+}
+
+// cwd is the folder under the cursor, relative to the library root.
+func (b *Builder) cwd() string {
+	return path.Join(b.studio, b.title)
+}
+
+// Paths returns every file and folder in the tree as slash-separated paths
+// relative to the library root, sorted.
+func (b *Builder) Paths() []string {
+	paths := make([]string, 0, len(b.entries))
+	for p := range b.entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// MapFS returns the tree as an in-memory filesystem rooted at the library.
+func (b *Builder) MapFS() fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for p, e := range b.entries {
+		if e.dir {
+			fsys[p] = &fstest.MapFile{Mode: fs.ModeDir | 0755}
+		} else {
+			fsys[p] = &fstest.MapFile{Data: e.data, Mode: 0644}
+		}
+	}
+	return fsys
+}
+
+// Create writes the tree below root, creating root if needed.
+func (b *Builder) Create(root string) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	// Sorted paths put every folder before its contents
+	for _, p := range b.Paths() {
+		target := filepath.Join(root, filepath.FromSlash(p))
+		e := b.entries[p]
+		if e.dir {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, e.data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TempDir writes the tree to a fresh temporary directory, removed when the
+// test ends, and returns its path.
+func (b *Builder) TempDir(tb testing.TB) string {
+	tb.Helper()
+	root := tb.TempDir()
+	if err := b.Create(root); err != nil {
+		tb.Fatalf("Failed to create fixture library: %v", err)
+	}
+	return root
+}
@@ -0,0 +1,83 @@
+package cleanuptest
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func sampleLibrary() *Builder {
+	return New().
+		Metadata("library.nfo").
+		Studio("Studio A").
+		Title("Movie (2020)").Video("movie.mkv").Metadata("movie.nfo").Dir("movie.trickplay", "1.jpg").
+		Title("Gone (2019)").
+		Up().Junk("Thumbs.db").
+		Studio("Empty Studio")
+}
+
+var samplePaths = []string{
+	"Empty Studio",
+	"Studio A",
+	"Studio A/Gone (2019)",
+	"Studio A/Movie (2020)",
+	"Studio A/Movie (2020)/movie.mkv",
+	"Studio A/Movie (2020)/movie.nfo",
+	"Studio A/Movie (2020)/movie.trickplay",
+	"Studio A/Movie (2020)/movie.trickplay/1.jpg",
+	"Studio A/Thumbs.db",
+	"library.nfo",
+}
+
+func TestBuilder_Paths(t *testing.T) {
+	if got := sampleLibrary().Paths(); !reflect.DeepEqual(got, samplePaths) {
+		t.Errorf("Expected paths %v, got %v", samplePaths, got)
+	}
+}
+
+func TestBuilder_MapFS(t *testing.T) {
+	fsys := sampleLibrary().MapFS()
+
+	if err := fstest.TestFS(fsys, samplePaths...); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.Stat(fsys, "Studio A/Gone (2019)")
+	if err != nil || !info.IsDir() {
+		t.Errorf("Expected empty title to be a directory, got %v, %v", info, err)
+	}
+	data, err := fs.ReadFile(fsys, "Studio A/Movie (2020)/movie.mkv")
+	if err != nil || string(data) != string(VideoContent) {
+		t.Errorf("Expected video content, got %q, %v", data, err)
+	}
+}
+
+func TestBuilder_File(t *testing.T) {
+	fsys := New().Studio("S").Title("T").File("movie.nfo", []byte("<movie/>")).MapFS()
+	if got := string(fsys["S/T/movie.nfo"].Data); got != "<movie/>" {
+		t.Errorf("Expected custom content, got %q", got)
+	}
+}
+
+func TestBuilder_TempDir(t *testing.T) {
+	root := sampleLibrary().TempDir(t)
+
+	if err := fstest.TestFS(os.DirFS(root), samplePaths...); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "Empty Studio"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected empty studio folder, got %d entries, %v", len(entries), err)
+	}
+}
+
+func TestBuilder_TitleWithoutStudioPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Title without Studio to panic")
+		}
+	}()
+	New().Title("Movie")
+}
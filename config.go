@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// libraryConfig is one library of a --config file. Omitted fields take the
+// value of the corresponding command-line flag.
+type libraryConfig struct {
+	Path             string   `json:"path"`
+	Structure        string   `json:"structure,omitempty"`
+	Layout           string   `json:"layout,omitempty"`
+	VideoExt         string   `json:"video_ext,omitempty"`
+	OnlyVideoExt     bool     `json:"only_video_ext,omitempty"`
+	StubExt          []string `json:"stub_ext,omitempty"`
+	MetadataDirs     []string `json:"metadata_dirs,omitempty"`
+	MetadataDirNames []string `json:"metadata_dir_names,omitempty"`
+	Include          []string `json:"include,omitempty"`
+	Exclude          []string `json:"exclude,omitempty"`
+	SMB              bool     `json:"smb,omitempty"`
+}
+
+// configFile is the JSON form of a --config file, e.g.
+//
+//	{"libraries": [
+//	  {"path": "/mnt/media/Movies"},
+//	  {"path": "/mnt/media/TV", "structure": "tv"},
+//	  {"path": "/mnt/media/Anime", "layout": "{library}/{studio}/{series}/{title}"},
+//	  {"path": "/mnt/media/Music Videos", "video_ext": "webm", "exclude": ["**/Live/**"]}
+//	 ],
+//	 "protect": ["/mnt/media/Movies/Criterion/**"]}
+type configFile struct {
+	Libraries []libraryConfig `json:"libraries"`
+	// Protect lists paths no action may touch, on top of --protect
+	Protect []string `json:"protect,omitempty"`
+}
+
+// loadConfig reads a --config file. Unknown fields are refused, so a typo
+// does not silently fall back to the defaults, and library paths other than
+// remote URLs are made absolute.
+func loadConfig(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var config configFile
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	seen := map[string]bool{}
+	for i, library := range config.Libraries {
+		if library.Path == "" {
+			return nil, fmt.Errorf("config %s: library %d has no path", path, i+1)
+		}
+		abs, err := libraryKey(library.Path)
+		if err != nil {
+			return nil, err
+		}
+		if library.Structure != "" && library.Layout != "" {
+			return nil, fmt.Errorf("config %s: library %s has both a structure and a layout", path, library.Path)
+		}
+		if seen[abs] {
+			return nil, fmt.Errorf("config %s: library %s is listed twice", path, library.Path)
+		}
+		seen[abs] = true
+		config.Libraries[i].Path = abs
+	}
+	return &config, nil
+}
+
+// libraryOptions holds the options that may differ between libraries.
+type libraryOptions struct {
+	structure    string
+	layout       string // template replacing structure
+	videoExt     string
+	onlyVideoExt bool
+	stubExt      []string
+	metadataDirs []string
+	dirNames     []string
+	include      []string
+	exclude      []string
+	smb          bool
+}
+
+// override returns o with the fields set in library replacing its own.
+func (o libraryOptions) override(library libraryConfig) libraryOptions {
+	if library.Structure != "" {
+		o.structure, o.layout = library.Structure, ""
+	}
+	if library.Layout != "" {
+		o.layout = library.Layout
+	}
+	if library.VideoExt != "" || library.OnlyVideoExt {
+		o.videoExt, o.onlyVideoExt = library.VideoExt, library.OnlyVideoExt
+	}
+	if library.StubExt != nil {
+		o.stubExt = library.StubExt
+	}
+	if library.MetadataDirs != nil {
+		o.metadataDirs = library.MetadataDirs
+	}
+	if library.MetadataDirNames != nil {
+		o.dirNames = library.MetadataDirNames
+	}
+	if library.Include != nil {
+		o.include = library.Include
+	}
+	if library.Exclude != nil {
+		o.exclude = library.Exclude
+	}
+	if library.SMB {
+		o.smb = true
+	}
+	return o
+}
+
+// scanOptions returns the scanner options for o, and the layout they set.
+func (o libraryOptions) scanOptions() ([]cleanup.Option, cleanup.Layout, error) {
+	layout, err := cleanup.LayoutByName(o.structure)
+	if o.layout != "" {
+		layout, err = cleanup.ParseLayout(o.layout)
+	}
+	if err != nil {
+		return nil, layout, err
+	}
+	opts := []cleanup.Option{cleanup.WithLayout(layout)}
+	if o.videoExt != "" || o.onlyVideoExt {
+		exts, err := cleanup.ParseExtensions(o.videoExt)
+		if err != nil {
+			return nil, layout, err
+		}
+		if o.onlyVideoExt && len(exts) == 0 {
+			return nil, layout, fmt.Errorf("--only-video-ext needs at least one extension in --video-ext")
+		}
+		if !o.onlyVideoExt {
+			exts = append(exts, cleanup.DefaultExtensions()...)
+		}
+		opts = append(opts, cleanup.WithExtensions(exts...))
+	}
+	if o.stubExt != nil {
+		exts, err := cleanup.ParseExtensions(strings.Join(o.stubExt, ","))
+		if err != nil {
+			return nil, layout, err
+		}
+		opts = append(opts, cleanup.WithStubExtensions(exts...))
+	}
+	if o.metadataDirs != nil {
+		opts = append(opts, cleanup.WithMetadataDirs(o.metadataDirs...))
+	}
+	if o.dirNames != nil {
+		opts = append(opts, cleanup.WithMetadataDirNames(o.dirNames...))
+	}
+	if len(o.include) > 0 || len(o.exclude) > 0 {
+		filter := cleanup.PathFilter{Include: o.include, Exclude: o.exclude}
+		if err := filter.Validate(); err != nil {
+			return nil, layout, err
+		}
+		opts = append(opts, cleanup.WithPathFilter(filter))
+	}
+	if o.smb {
+		opts = append(opts, cleanup.WithSMB(true))
+	}
+	return opts, layout, nil
+}
+
+// libraryScanners picks the scanner of each library: the one configured for
+// it, or fallback.
+type libraryScanners struct {
+	fallback   *cleanup.Scanner
+	configured map[string]*cleanup.Scanner // by absolute library path
+	remote     map[string]*cleanup.Scanner // by ssh:// URL
+}
+
+// forPath returns the scanner of the library path is, or in a check run is
+// a title of.
+func (s libraryScanners) forPath(path string) *cleanup.Scanner {
+	if scanner, ok := s.remote[path]; ok {
+		return scanner
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return s.fallback
+	}
+	if scanner, ok := s.configured[abs]; ok {
+		return scanner
+	}
+	for library, scanner := range s.configured {
+		if cleanup.IsWithin(library, abs) && scanner.TitleLibrary(abs) == library {
+			return scanner
+		}
+	}
+	return s.fallback
+}
+
+// splitList splits a comma-separated flag value, dropping blanks. An empty
+// value gives nil, leaving the defaults in place.
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// categoryAliases are the short names --only and --skip accept for one or
+// more categories.
+var categoryAliases = map[string][]cleanup.Category{
+	"empty":      {cleanup.CategoryEmptyFolder},
+	"orphans":    {cleanup.CategoryOrphanedFolder, cleanup.CategoryOrphanedFile},
+	"warnings":   {cleanup.CategoryStructureWarning},
+	"junk":       {cleanup.CategoryJunkFile},
+	"duplicates": {cleanup.CategoryDuplicateVideo},
+}
+
+// parseCategories returns the categories named in a comma-separated --only
+// or --skip value: report categories, with dashes or underscores and
+// singular or plural ("orphaned-folders"), or categoryAliases.
+func parseCategories(value string) ([]cleanup.Category, error) {
+	var categories []cleanup.Category
+	for _, name := range splitList(value) {
+		key := strings.ReplaceAll(strings.ToLower(name), "-", "_")
+		if aliased, ok := categoryAliases[key]; ok {
+			categories = append(categories, aliased...)
+			continue
+		}
+		category := cleanup.Category(key)
+		if !category.Valid() {
+			category = cleanup.Category(strings.TrimSuffix(key, "s"))
+		}
+		if !category.Valid() {
+			return nil, fmt.Errorf("unknown category %q (expected empty, orphans, warnings, junk, duplicates or a report category such as orphaned-folders)", name)
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// selectCategories returns the categories --only picks, every one by
+// default, less those --skip names. It returns nil when neither is given.
+func selectCategories(only, skip string) ([]cleanup.Category, error) {
+	if only == "" && skip == "" {
+		return nil, nil
+	}
+	picked := cleanup.Categories
+	if only != "" {
+		var err error
+		if picked, err = parseCategories(only); err != nil {
+			return nil, fmt.Errorf("--only: %w", err)
+		}
+	}
+	skipped, err := parseCategories(skip)
+	if err != nil {
+		return nil, fmt.Errorf("--skip: %w", err)
+	}
+	var categories []cleanup.Category
+	for _, c := range cleanup.Categories {
+		if slices.Contains(picked, c) && !slices.Contains(skipped, c) {
+			categories = append(categories, c)
+		}
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("--only and --skip leave no category to check")
+	}
+	return categories, nil
+}
+
+// protectedPaths returns the --protect patterns along with those of config,
+// if any. Absolute paths are cleaned up; relative ones, matched below each
+// library root, and patterns starting with "**", which match anywhere, are
+// kept as they are.
+func protectedPaths(patterns []string, config *configFile) (cleanup.ProtectedPaths, error) {
+	if config != nil {
+		patterns = append(append([]string{}, patterns...), config.Protect...)
+	}
+	var protected cleanup.ProtectedPaths
+	for _, pattern := range patterns {
+		if filepath.IsAbs(pattern) || strings.HasPrefix(filepath.ToSlash(pattern), "/") {
+			abs, err := libraryKey(pattern)
+			if err != nil {
+				return nil, err
+			}
+			pattern = abs
+		}
+		protected = append(protected, pattern)
+	}
+	if err := protected.Validate(); err != nil {
+		return nil, fmt.Errorf("--protect: %w", err)
+	}
+	return protected, nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for --config files
+// ============================================================================
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	movies := filepath.Join(tempDir, "Movies")
+	path := writeConfig(t, tempDir, `{"libraries": [
+		{"path": "`+filepath.ToSlash(movies)+`"},
+		{"path": "TV", "structure": "tv", "metadata_dirs": [".trickplay", ".bif"]}
+	]}`)
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig returned error: %v", err)
+	}
+	if len(config.Libraries) != 2 {
+		t.Fatalf("Expected 2 libraries, got %v", config.Libraries)
+	}
+	if config.Libraries[0].Path != movies {
+		t.Errorf("Expected %s, got %s", movies, config.Libraries[0].Path)
+	}
+	if !filepath.IsAbs(config.Libraries[1].Path) {
+		t.Errorf("Expected the relative path to be made absolute, got %s", config.Libraries[1].Path)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	tests := map[string]string{
+		"unknown field": `{"libraries": [{"path": "/m", "structur": "tv"}]}`,
+		"no path":       `{"libraries": [{"structure": "tv"}]}`,
+		"listed twice":  `{"libraries": [{"path": "/m"}, {"path": "/m/"}]}`,
+	}
+	for name, content := range tests {
+		if _, err := loadConfig(writeConfig(t, tempDir, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestProtectedPaths(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	config, err := loadConfig(writeConfig(t, tempDir, `{"libraries": [], "protect": ["**/Keep"]}`))
+	if err != nil {
+		t.Fatalf("loadConfig returned error: %v", err)
+	}
+
+	protected, err := protectedPaths([]string{"/m/Criterion/../Archive/**", "Criterion/**"}, config)
+	if err != nil {
+		t.Fatalf("protectedPaths returned error: %v", err)
+	}
+	if len(protected) != 3 || !filepath.IsAbs(protected[0]) || filepath.Base(filepath.Dir(protected[0])) != "Archive" ||
+		protected[1] != "Criterion/**" || protected[2] != "**/Keep" {
+		t.Errorf("Expected a cleaned absolute path, the relative pattern and **/Keep, got %v", protected)
+	}
+	if _, err := protectedPaths([]string{"/m/[Studio"}, nil); err == nil {
+		t.Error("Expected an error for a malformed pattern")
+	}
+}
+
+func TestLibraryOptions_Override(t *testing.T) {
+	flags := libraryOptions{structure: "movies", videoExt: "ts", exclude: []string{"Staging"}}
+
+	got := flags.override(libraryConfig{Structure: "tv", Include: []string{"Kids/*"}})
+	if got.structure != "tv" || got.videoExt != "ts" || len(got.include) != 1 || len(got.exclude) != 1 {
+		t.Errorf("Expected only structure and include to change, got %+v", got)
+	}
+	got = flags.override(libraryConfig{VideoExt: "webm", OnlyVideoExt: true, Exclude: []string{}})
+	if got.videoExt != "webm" || !got.onlyVideoExt || len(got.exclude) != 0 {
+		t.Errorf("Expected the extensions replaced and no exclusions left, got %+v", got)
+	}
+	if got = flags.override(libraryConfig{SMB: true}); !got.smb {
+		t.Errorf("Expected smb to be turned on, got %+v", got)
+	}
+	flags.layout = "{library}/{title}"
+	if got = flags.override(libraryConfig{Structure: "tv"}); got.structure != "tv" || got.layout != "" {
+		t.Errorf("Expected the structure of the library to replace the layout flag, got %+v", got)
+	}
+	flags.layout = ""
+	if got = flags.override(libraryConfig{Layout: "{library}/{studio}/{series}/{title}"}); got.layout == "" {
+		t.Errorf("Expected the layout of the library, got %+v", got)
+	}
+	flags.dirNames = []string{"extrafanart"}
+	if got = flags.override(libraryConfig{MetadataDirNames: []string{"posters", ".actors"}}); len(got.dirNames) != 2 {
+		t.Errorf("Expected the metadata folder names replaced, got %+v", got)
+	}
+	flags.stubExt = []string{"strm"}
+	if got = flags.override(libraryConfig{StubExt: []string{}}); got.stubExt == nil || len(got.stubExt) != 0 {
+		t.Errorf("Expected the stub extensions cleared, got %+v", got)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if list := splitList(""); list != nil {
+		t.Errorf("Expected nil for an empty value, got %q", list)
+	}
+	if list := splitList(" extrafanart, .actors ,,backdrops"); len(list) != 3 || list[0] != "extrafanart" || list[1] != ".actors" {
+		t.Errorf("Expected 3 trimmed names, got %q", list)
+	}
+}
+
+func TestSelectCategories(t *testing.T) {
+	if categories, err := selectCategories("", ""); err != nil || categories != nil {
+		t.Errorf("Expected nil without --only or --skip, got %v (%v)", categories, err)
+	}
+	categories, err := selectCategories("orphaned-folders, empty", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []cleanup.Category{cleanup.CategoryOrphanedFolder, cleanup.CategoryEmptyFolder}
+	if fmt.Sprint(categories) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, categories)
+	}
+
+	categories, err = selectCategories("", "warnings,Junk_File")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != len(cleanup.Categories)-2 || slices.Contains(categories, cleanup.CategoryStructureWarning) || slices.Contains(categories, cleanup.CategoryJunkFile) {
+		t.Errorf("Expected every category but warnings and junk files, got %v", categories)
+	}
+
+	categories, err = selectCategories("orphans", "orphaned_file")
+	if err != nil || fmt.Sprint(categories) != fmt.Sprint([]cleanup.Category{cleanup.CategoryOrphanedFolder}) {
+		t.Errorf("Expected only orphaned folders, got %v (%v)", categories, err)
+	}
+
+	for _, flags := range [][2]string{{"orphaned", ""}, {"", "everything"}, {"empty", "empty-folders"}} {
+		if _, err := selectCategories(flags[0], flags[1]); err == nil {
+			t.Errorf("Expected an error for --only %q --skip %q", flags[0], flags[1])
+		}
+	}
+}
+
+func TestLibraryOptions_ScanOptions(t *testing.T) {
+	tests := map[string]libraryOptions{
+		"unknown structure":    {structure: "music"},
+		"only without any ext": {structure: "movies", onlyVideoExt: true},
+		"malformed pattern":    {structure: "movies", exclude: []string{"[broken"}},
+		"malformed video ext":  {structure: "movies", videoExt: "mkv.part"},
+		"malformed stub ext":   {structure: "movies", stubExt: []string{"strm/x"}},
+		"malformed layout":     {structure: "movies", layout: "{library}/studio"},
+	}
+	for name, o := range tests {
+		if _, _, err := o.scanOptions(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLibraryScanners(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	// Movies keeps the default rules, the music videos library only knows webm
+	createFile(t, filepath.Join(tempDir, "Movies", "Studio", "Film (2001)", "film.webm"))
+	createFile(t, filepath.Join(tempDir, "Music Videos", "Artist", "Song (2001)", "song.webm"))
+
+	music, layout, err := libraryOptions{structure: "movies", videoExt: "webm", onlyVideoExt: true}.scanOptions()
+	if err != nil || layout.Name != "movies" {
+		t.Fatalf("scanOptions returned %v, %v", layout.Name, err)
+	}
+	musicLibrary := filepath.Join(tempDir, "Music Videos")
+	scanners := libraryScanners{
+		fallback:   cleanup.NewScanner(),
+		configured: map[string]*cleanup.Scanner{musicLibrary: cleanup.NewScanner(music...)},
+	}
+
+	for library, orphans := range map[string]int{"Movies": 1, "Music Videos": 0} {
+		path := filepath.Join(tempDir, library)
+		result := &cleanup.CleanupResult{}
+		if err := scanners.forPath(path).Scan(context.Background(), path, result.Add); err != nil {
+			t.Fatalf("Scan returned error: %v", err)
+		}
+		if len(result.OrphanedFolders) != orphans {
+			t.Errorf("%s: expected %d orphaned folders, got %v", library, orphans, result.OrphanedFolders)
+		}
+	}
+
+	title := filepath.Join(musicLibrary, "Artist", "Song (2001)")
+	if scanners.forPath(title) != scanners.configured[musicLibrary] {
+		t.Errorf("Expected titles of a configured library to be checked with its scanner")
+	}
+	if scanners.forPath(filepath.Join(musicLibrary, "Artist")) != scanners.fallback {
+		t.Errorf("Expected a studio not to be taken for a title of the library")
+	}
+}
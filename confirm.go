@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// isDeletable reports whether --execute deletes f.
+func isDeletable(f cleanup.Finding) bool {
+	switch f.Category {
+	case cleanup.CategoryOrphanedFolder, cleanup.CategoryOrphanedFile, cleanup.CategoryEmptyFolder, cleanup.CategoryJunkFile:
+		return true
+	}
+	return false
+}
+
+// countDeletable returns the number of findings of result --execute deletes.
+func countDeletable(result *cleanup.CleanupResult) int {
+	n := 0
+	for _, f := range result.Findings {
+		if isDeletable(f) {
+			n++
+		}
+	}
+	return n
+}
+
+// deletionsByLibrary counts the deletable findings of result per library,
+// and returns the libraries in the order of their first finding.
+func deletionsByLibrary(result *cleanup.CleanupResult) (libraries []string, counts map[string]int) {
+	counts = map[string]int{}
+	for _, f := range result.Findings {
+		if !isDeletable(f) {
+			continue
+		}
+		if counts[f.Library] == 0 {
+			libraries = append(libraries, f.Library)
+		}
+		counts[f.Library]++
+	}
+	return libraries, counts
+}
+
+// confirmDeletions asks on w to type the name of every library result
+// deletes from, as cloud CLIs do before destroying a resource, reading the
+// answers from r. It reports whether every name was typed as asked; the
+// first wrong answer, the end of r or ctx being done cancels.
+func confirmDeletions(ctx context.Context, r io.Reader, w io.Writer, lang *Language, result *cleanup.CleanupResult) bool {
+	answers := bufio.NewReader(r)
+	libraries, counts := deletionsByLibrary(result)
+	for _, library := range libraries {
+		name := filepath.Base(library)
+		lang.Fprintf(w, "\n⚠️  About to delete %d items from %s\n", counts[library], library)
+		lang.Fprintf(w, "Type %s to confirm: ", name)
+		// Read in the background, so that Ctrl-C cancels without a newline
+		answer := make(chan string, 1)
+		go func() {
+			line, _ := answers.ReadString('\n')
+			answer <- line
+		}()
+		select {
+		case <-ctx.Done():
+			return false
+		case line := <-answer:
+			if strings.TrimSpace(line) != name {
+				return false
+			}
+		}
+	}
+	return true
+}
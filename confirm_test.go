@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for typed confirmation
+// ============================================================================
+
+// confirmResult has deletions in two libraries and a warning, which is never
+// deleted.
+func confirmResult() *cleanup.CleanupResult {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Library: "/media/Movies", Path: "/media/Movies/S/A"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Library: "/media/Movies", Path: "/media/Movies/S/B"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Library: "/media/Kids", Path: "/media/Kids/x.txt"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Library: "/media/TV", Path: "/media/TV/x.nfo"})
+	return result
+}
+
+func TestConfirmDeletions(t *testing.T) {
+	tests := []struct {
+		name      string
+		answers   string
+		confirmed bool
+	}{
+		{"every name typed", "Movies\n  TV  \n", true},
+		{"wrong name", "Movies\nMovies\n", false},
+		{"empty answer", "\n", false},
+		{"end of input", "Movies\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			confirmed := confirmDeletions(context.Background(), strings.NewReader(tt.answers), &buf, nil, confirmResult())
+			if confirmed != tt.confirmed {
+				t.Errorf("Expected confirmed %v, got %v", tt.confirmed, confirmed)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	confirmDeletions(context.Background(), strings.NewReader("Movies\nTV\n"), &buf, nil, confirmResult())
+	if !strings.Contains(buf.String(), "About to delete 2 items from /media/Movies\nType Movies to confirm: ") {
+		t.Errorf("Expected the Movies prompt with its count, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "Kids") {
+		t.Errorf("Expected no prompt for a library without deletions, got %q", buf.String())
+	}
+}
+
+func TestConfirmDeletions_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Nothing is ever typed
+	r, w := io.Pipe()
+	defer w.Close()
+	if confirmDeletions(ctx, r, io.Discard, nil, confirmResult()) {
+		t.Error("Expected a cancelled context to cancel the confirmation")
+	}
+}
+
+func TestCountDeletable(t *testing.T) {
+	if n := countDeletable(confirmResult()); n != 3 {
+		t.Errorf("Expected 3 deletions, got %d", n)
+	}
+}
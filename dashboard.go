@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// dashboardHistory is the number of runs the dashboard lists.
+const dashboardHistory = 50
+
+// dashboard is the web UI of "serve": the findings of the latest scan per
+// library, deletion of the findings approved there, and the runs since the
+// process started. One run happens at a time. A nil *dashboard records
+// nothing, so runs outside "serve" can report to it unconditionally.
+type dashboard struct {
+	lang  *Language
+	style *ReportStyle
+	// addr is the loopback address the dashboard listens on, the only host
+	// name requests may give besides localhost
+	addr string
+	// run scans the libraries and deletes the approved findings, if any,
+	// returning the exit code of the run.
+	run func(approved map[string]bool) int
+
+	template *template.Template
+
+	runs      sync.WaitGroup
+	mu        sync.Mutex
+	running   *dashboardRun // nil when idle
+	result    *cleanup.CleanupResult
+	scannedAt time.Time
+	history   []*dashboardRun // most recent first
+}
+
+// dashboardRun is one run started from the dashboard or its schedule.
+type dashboardRun struct {
+	Start     time.Time
+	Duration  time.Duration
+	Approved  int // findings approved for deletion, 0 for a scan
+	Findings  int
+	Deleted   int
+	Failures  int
+	Reclaimed int64
+	ExitCode  int
+}
+
+func newDashboard(lang *Language, style *ReportStyle) *dashboard {
+	if style == nil {
+		style = DefaultReportStyle()
+	}
+	page := template.Must(template.New("dashboard").Funcs(template.FuncMap{
+		"t":     lang.T,
+		"bytes": cleanup.FormatBytes,
+		"time":  func(t time.Time) string { return t.Local().Format(time.DateTime) },
+		"round": func(d time.Duration) time.Duration { return d.Round(time.Second) },
+	}).Parse(dashboardPage))
+	return &dashboard{lang: lang, style: style, template: page}
+}
+
+// scanned keeps result as the latest scan.
+func (d *dashboard) scanned(result *cleanup.CleanupResult) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.result, d.scannedAt = result, time.Now()
+	if d.running != nil {
+		d.running.Findings = len(result.Findings)
+	}
+}
+
+// deleted records the outcome of an approved deletion, and drops what is
+// gone from the latest scan.
+func (d *dashboard) deleted(report *cleanup.DeletionReport) {
+	if d == nil || report == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running != nil {
+		d.running.Deleted, d.running.Failures = len(report.Deleted), len(report.Failures)
+		for _, f := range report.Deleted {
+			d.running.Reclaimed += f.Reclaimable
+		}
+	}
+	if d.result == nil {
+		return
+	}
+	gone := append(append([]cleanup.Finding{}, report.Deleted...), report.Skipped...)
+	left := &cleanup.CleanupResult{Libraries: d.result.Libraries, Errors: d.result.Errors}
+	for _, f := range d.result.Findings {
+		kept := true
+		for _, g := range gone {
+			if cleanup.IsWithin(g.Path, f.Path) {
+				kept = false
+				break
+			}
+		}
+		if kept {
+			_ = left.Add(f)
+		}
+	}
+	d.result = left
+}
+
+// start runs d.run in the background unless a run is already going on, and
+// reports whether it did.
+func (d *dashboard) start(approved map[string]bool) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running != nil {
+		return false
+	}
+	run := &dashboardRun{Start: time.Now(), Approved: len(approved)}
+	d.running = run
+	d.runs.Add(1)
+	go func() {
+		defer d.runs.Done()
+		code := d.run(approved)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		run.Duration, run.ExitCode = time.Since(run.Start), code
+		d.history = append([]*dashboardRun{run}, d.history...)
+		if len(d.history) > dashboardHistory {
+			d.history = d.history[:dashboardHistory]
+		}
+		d.running = nil
+	}()
+	return true
+}
+
+// wait blocks until the run in progress, if any, has finished.
+func (d *dashboard) wait() {
+	d.runs.Wait()
+}
+
+// approvedFindings returns the findings of result at the approved paths.
+func approvedFindings(result *cleanup.CleanupResult, approved map[string]bool) *cleanup.CleanupResult {
+	selected := &cleanup.CleanupResult{Libraries: result.Libraries}
+	for _, f := range result.Findings {
+		if approved[f.Path] {
+			_ = selected.Add(f)
+		}
+	}
+	return selected
+}
+
+// dashboardCategories are the sections of a library, deletable ones first.
+var dashboardCategories = []cleanup.Category{
+	cleanup.CategoryOrphanedFolder,
+	cleanup.CategoryOrphanedFile,
+	cleanup.CategoryEmptyFolder,
+	cleanup.CategoryJunkFile,
+	cleanup.CategoryStructureWarning,
+	cleanup.CategoryMisfiledVideo,
+	cleanup.CategoryDownloadInProgress,
+	cleanup.CategoryTruncatedVideo,
+	cleanup.CategoryCorruptVideo,
+	cleanup.CategoryBrokenSymlink,
+	cleanup.CategoryDuplicateVideo,
+	cleanup.CategoryPermissionMismatch,
+	cleanup.CategoryMetadataMismatch,
+	cleanup.CategoryMissingMetadata,
+	cleanup.CategoryIncompatibleName,
+	cleanup.CategoryRecycleBin,
+}
+
+// dashboardSection is one category of findings of a library, as shown.
+type dashboardSection struct {
+	Title     string
+	Deletable bool
+	Findings  []cleanup.Finding
+}
+
+// dashboardLibrary is one library of the latest scan, as shown.
+type dashboardLibrary struct {
+	Path     string
+	Sections []dashboardSection
+}
+
+func (d *dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !d.knownHost(r.Host) {
+		http.Error(w, "unknown host refused", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPost && !sameOrigin(r) {
+		http.Error(w, "cross-origin request refused", http.StatusForbidden)
+		return
+	}
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		d.page(w)
+	case r.URL.Path == "/scan" && r.Method == http.MethodPost:
+		d.start(nil)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	case r.URL.Path == "/delete" && r.Method == http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		approved := map[string]bool{}
+		for _, path := range r.PostForm["path"] {
+			approved[path] = true
+		}
+		if len(approved) > 0 {
+			d.start(approved)
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// sameOrigin reports whether a form was posted from the dashboard itself,
+// so another site open in the browser cannot approve deletions.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return r.Header.Get("Sec-Fetch-Site") != "cross-site"
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// knownHost reports whether a request names the dashboard by the address it
+// listens on, or by localhost, 127.0.0.1 or ::1 with its port, so a site
+// whose name was rebound to this machine cannot read the page or post to
+// it.
+func (d *dashboard) knownHost(host string) bool {
+	if strings.EqualFold(host, d.addr) {
+		return true
+	}
+	_, port, err := net.SplitHostPort(d.addr)
+	if err != nil {
+		return false
+	}
+	for _, name := range []string{"localhost", "127.0.0.1", "::1"} {
+		if strings.EqualFold(host, net.JoinHostPort(name, port)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopback reports whether addr only listens on this machine: localhost
+// or a loopback IP, not all interfaces as ":8080" or "0.0.0.0:8080" do.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (d *dashboard) page(w http.ResponseWriter) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	data := struct {
+		Running   *dashboardRun
+		Scanned   time.Time
+		Libraries []dashboardLibrary
+		History   []*dashboardRun
+	}{Running: d.running, Scanned: d.scannedAt, History: d.history}
+	if d.result != nil {
+		for _, path := range d.result.Libraries {
+			library := dashboardLibrary{Path: path}
+			libResult := d.result.ForLibrary(path)
+			for _, category := range dashboardCategories {
+				if findings := libResult.ByCategory(category); len(findings) > 0 {
+					library.Sections = append(library.Sections, dashboardSection{
+						Title:     d.style.title(string(category), d.lang),
+						Deletable: isDeletable(cleanup.Finding{Category: category}),
+						Findings:  findings,
+					})
+				}
+			}
+			data.Libraries = append(data.Libraries, library)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := d.template.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveDashboard serves board at addr until ctx is done and the run in
+// progress has stopped, after starting a first scan; s, if not nil,
+// schedules the rescans.
+func serveDashboard(ctx context.Context, addr string, board *dashboard, s schedule, logger *slog.Logger) {
+	board.addr = addr
+	board.start(nil)
+	if s != nil {
+		go func() {
+			for start := time.Now(); ; start = time.Now() {
+				timer := time.NewTimer(time.Until(s.next(start)))
+				select {
+				case <-timer.C:
+					board.start(nil)
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+			}
+		}()
+	}
+	server := &http.Server{Addr: addr, Handler: board, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		if err := server.Shutdown(context.Background()); err != nil {
+			logger.Warn("dashboard not shut down cleanly", "error", err)
+		}
+	}()
+	logger.Info("serving dashboard", "addr", addr)
+	fmt.Fprintf(os.Stderr, "Dashboard at http://%s/\n", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Cannot serve the dashboard on %s: %v\n", addr, err)
+		os.Exit(exitFailure)
+	}
+	// The run in progress stops at its next item
+	board.wait()
+	logger.Info("dashboard stopped", "reason", context.Cause(ctx))
+}
+
+const dashboardPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>video-folder-cleanup</title>
+{{if .Running}}<meta http-equiv="refresh" content="5">{{end}}
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+.path { font-family: monospace; }
+.status { background: #fff3cd; padding: 0.5em; }
+</style>
+</head>
+<body>
+<h1>video-folder-cleanup</h1>
+{{if .Running}}<p class="status">{{t "A run is in progress, this page refreshes by itself"}}</p>
+{{else}}<form method="post" action="/scan"><button>{{t "Rescan"}}</button></form>{{end}}
+{{if .Scanned.IsZero}}<p>{{t "No scan yet"}}</p>{{else}}<p>{{t "Last scan"}}: {{time .Scanned}}</p>{{end}}
+
+<form method="post" action="/delete" onsubmit="return confirm({{t "Delete the selected items?"}})">
+{{range .Libraries}}
+<h2 class="path">{{.Path}}</h2>
+{{range .Sections}}
+<h3>{{.Title}} ({{len .Findings}})</h3>
+<table>
+{{$deletable := .Deletable}}{{range .Findings}}<tr>
+<td>{{if $deletable}}<input type="checkbox" name="path" value="{{.Path}}">{{end}}</td>
+<td class="path">{{.}}</td>
+<td>{{if .Bytes}}{{bytes .Reclaimable}}{{end}}</td>
+</tr>
+{{end}}</table>
+{{else}}<p>✓ {{t "Nothing to clean up"}}</p>
+{{end}}
+{{end}}
+{{if and .Libraries (not .Running)}}<p><button>{{t "Delete selected"}}</button></p>{{end}}
+</form>
+
+{{if .History}}
+<h2>{{t "History"}}</h2>
+<table>
+<tr><th>{{t "Started"}}</th><th>{{t "Run"}}</th><th>{{t "Duration"}}</th><th>{{t "Findings"}}</th><th>{{t "Deleted"}}</th><th>{{t "Failures"}}</th><th>{{t "Reclaimed"}}</th><th>{{t "Exit code"}}</th></tr>
+{{range .History}}<tr>
+<td>{{time .Start}}</td>
+<td>{{if .Approved}}{{t "Deletion"}} ({{.Approved}}){{else}}{{t "Scan"}}{{end}}</td>
+<td>{{round .Duration}}</td>
+<td>{{.Findings}}</td>
+<td>{{if .Approved}}{{.Deleted}}{{end}}</td>
+<td>{{if .Approved}}{{.Failures}}{{end}}</td>
+<td>{{if .Reclaimed}}{{bytes .Reclaimed}}{{end}}</td>
+<td>{{.ExitCode}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for the serve dashboard
+// ============================================================================
+
+// idle waits for the run started on d to finish.
+func idle(t *testing.T, d *dashboard) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		d.mu.Lock()
+		running := d.running
+		d.mu.Unlock()
+		if running == nil {
+			return
+		}
+	}
+	t.Fatal("Expected the run to finish")
+}
+
+func TestDashboard(t *testing.T) {
+	scan := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	scan.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Gone (2001)", Library: "/lib"})
+	scan.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Also (2002)", Library: "/lib"})
+	scan.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/x.mkv", Library: "/lib", Message: "Video file at library level"})
+
+	d := newDashboard(English, nil)
+	d.addr = "127.0.0.1:8080"
+	var runs []map[string]bool
+	d.run = func(approved map[string]bool) int {
+		runs = append(runs, approved)
+		d.scanned(scan)
+		if approved != nil {
+			d.deleted(&cleanup.DeletionReport{Deleted: approvedFindings(scan, approved).Findings})
+		}
+		return exitFindings
+	}
+	d.start(nil)
+	idle(t, d)
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://127.0.0.1:8080/", nil))
+		return rec.Body.String()
+	}
+	page := get()
+	for _, expected := range []string{`value="/lib/S/Gone (2001)"`, `value="/lib/S/Also (2002)"`, "Video file at library level: /lib/x.mkv"} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected %q in the page, got:\n%s", expected, page)
+		}
+	}
+	if strings.Contains(page, `value="/lib/x.mkv"`) {
+		t.Errorf("Expected structure warnings not to be selectable")
+	}
+
+	post := func(host, origin string) int {
+		form := url.Values{"path": {"/lib/S/Gone (2001)"}}
+		req := httptest.NewRequest(http.MethodPost, "http://"+host+"/delete", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := post("127.0.0.1:8080", "http://elsewhere.example"); code != http.StatusForbidden {
+		t.Errorf("Expected a cross-origin post to be refused, got %d", code)
+	}
+	// A site rebound to this machine posts to itself, under its own name
+	if code := post("evil.example:8080", "http://evil.example:8080"); code != http.StatusForbidden {
+		t.Errorf("Expected a post to a foreign host to be refused, got %d", code)
+	}
+	if code := post("localhost:8080", "http://localhost:8080"); code != http.StatusSeeOther {
+		t.Fatalf("Expected a redirect to the dashboard, got %d", code)
+	}
+	idle(t, d)
+
+	if len(runs) != 2 || !runs[1]["/lib/S/Gone (2001)"] || len(runs[1]) != 1 {
+		t.Fatalf("Expected a scan and the approved deletion, got %v", runs)
+	}
+	page = get()
+	if strings.Contains(page, `value="/lib/S/Gone (2001)"`) || !strings.Contains(page, `value="/lib/S/Also (2002)"`) {
+		t.Errorf("Expected only the deleted folder to leave the page, got:\n%s", page)
+	}
+	if len(d.history) != 2 || d.history[0].Approved != 1 || d.history[0].Deleted != 1 || d.history[1].Findings != 3 {
+		t.Errorf("Expected the deletion then the scan in the history, got %+v %+v", d.history[0], d.history[1])
+	}
+}
+
+func TestDashboard_RefusesForeignHost(t *testing.T) {
+	d := newDashboard(English, nil)
+	d.addr = "127.0.0.1:8080"
+	for host, expected := range map[string]int{
+		"127.0.0.1:8080":    http.StatusOK,
+		"localhost:8080":    http.StatusOK,
+		"[::1]:8080":        http.StatusOK,
+		"192.168.1.2:8080":  http.StatusForbidden,
+		"evil.example":      http.StatusForbidden,
+		"evil.example:8080": http.StatusForbidden,
+		"localhost:9090":    http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, req)
+		if rec.Code != expected {
+			t.Errorf("Expected %d for Host %s, got %d", expected, host, rec.Code)
+		}
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"127.0.0.1:8080":   true,
+		"localhost:8080":   true,
+		"[::1]:8080":       true,
+		":8080":            false,
+		"0.0.0.0:8080":     false,
+		"[::]:8080":        false,
+		"192.168.1.2:8080": false,
+		"nas.lan:8080":     false,
+		"127.0.0.1":        false,
+	} {
+		if got := isLoopback(addr); got != expected {
+			t.Errorf("Expected %v for %s, got %v", expected, addr, got)
+		}
+	}
+}
+
+func TestDashboard_Nil(t *testing.T) {
+	var d *dashboard
+	d.scanned(&cleanup.CleanupResult{})
+	d.deleted(&cleanup.DeletionReport{})
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// findingKey identifies a finding across runs.
+type findingKey struct {
+	category cleanup.Category
+	path     string
+	message  string
+}
+
+func keyOf(f cleanup.Finding) findingKey {
+	return findingKey{f.Category, f.Path, f.Message}
+}
+
+// ReportDiff is what changed between a previous report and the current one.
+type ReportDiff struct {
+	// New holds the findings and scan errors the previous report did not
+	// have.
+	New *cleanup.CleanupResult
+	// Resolved holds the findings of the previous report the current one no
+	// longer has.
+	Resolved *cleanup.CleanupResult
+}
+
+// diffResults compares the current result against the previous one.
+func diffResults(previous, current *cleanup.CleanupResult) ReportDiff {
+	d := ReportDiff{
+		New:      &cleanup.CleanupResult{Libraries: current.Libraries},
+		Resolved: &cleanup.CleanupResult{Libraries: previous.Libraries},
+	}
+	before := make(map[findingKey]bool)
+	for _, f := range previous.Findings {
+		before[keyOf(f)] = true
+	}
+	now := make(map[findingKey]bool)
+	for _, f := range current.Findings {
+		now[keyOf(f)] = true
+		if !before[keyOf(f)] {
+			d.New.Add(f)
+		}
+	}
+	for _, f := range previous.Findings {
+		if !now[keyOf(f)] {
+			d.Resolved.Add(f)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, err := range previous.Errors {
+		seen[err.Error()] = true
+	}
+	for _, err := range current.Errors {
+		if !seen[err.Error()] {
+			d.New.Errors = append(d.New.Errors, err)
+		}
+	}
+	return d
+}
+
+// empty reports whether nothing changed.
+func (d ReportDiff) empty() bool {
+	return len(d.New.Findings) == 0 && len(d.New.Errors) == 0 && len(d.Resolved.Findings) == 0
+}
+
+// loadReport reads a report written by --format json, and when it was
+// written. A missing file is an empty report, so the first run reports every
+// finding as new.
+func loadReport(path string) (*cleanup.CleanupResult, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cleanup.CleanupResult{}, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	result := &cleanup.CleanupResult{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, time.Time{}, fmt.Errorf("report %s: %w", path, err)
+	}
+	var written time.Time
+	if info, err := os.Stat(path); err == nil {
+		written = info.ModTime()
+	}
+	return result, written, nil
+}
+
+// saveReport writes result to path as --format json does, replacing the
+// previous file atomically.
+func saveReport(path string, result *cleanup.CleanupResult) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeJSON(tmp, result); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// runDiff writes to w with rw what changed since the report at path, then
+// replaces it with result, so the next run compares against this one. It
+// returns the new findings.
+func runDiff(w io.Writer, path string, rw reportWriter, result *cleanup.CleanupResult) (*cleanup.CleanupResult, error) {
+	previous, written, err := loadReport(path)
+	if err != nil {
+		return nil, err
+	}
+	d := diffResults(previous, result)
+	if err := rw.writeDiff(w, d, written); err != nil {
+		return nil, err
+	}
+	return d.New, saveReport(path, result)
+}
+
+// writeDiff writes d to w. The text report lists the new findings as usual
+// and the resolved ones in a section of their own; the JSON formats mark
+// them. written is when the previous report was saved, zero on a first run.
+func (rw reportWriter) writeDiff(w io.Writer, d ReportDiff, written time.Time) error {
+	switch rw.format {
+	case "json":
+		return writeDiffJSON(w, d)
+	case "jsonl":
+		return writeDiffJSONL(w, d)
+	}
+	if rw.summary {
+		var line strings.Builder
+		printSummary(&line, d.New, rw.elapsed)
+		fmt.Fprintf(w, "%s resolved=%d\n", strings.TrimSuffix(line.String(), "\n"), len(d.Resolved.Findings))
+		return nil
+	}
+	style := rw.style
+	if style == nil {
+		style = DefaultReportStyle()
+	}
+	if rw.quiet {
+		rw.printCounts(w, d.New)
+		if n := len(d.Resolved.Findings); n > 0 {
+			fmt.Fprintf(w, "%s: %d\n", rw.heading(sectionResolved), n)
+		}
+		return nil
+	}
+
+	if !written.IsZero() {
+		rw.lang.Fprintf(w, "\n🔀 Changes since the report of %s\n", written.Format("2006-01-02 15:04"))
+	}
+	if d.empty() {
+		rw.lang.Fprintf(w, "✨ No changes\n")
+		return nil
+	}
+	if len(d.New.Findings) > 0 || len(d.New.Errors) > 0 {
+		rw.printReport(w, d.New)
+	}
+	if n := len(d.Resolved.Findings); n > 0 {
+		rw.lang.Fprintf(w, "\n%s (%d):\n", rw.heading(sectionResolved), n)
+		for _, f := range d.Resolved.Findings {
+			fmt.Fprintf(w, "%s%s\n", style.ItemPrefix, rw.lang.Finding(f))
+		}
+	}
+	return nil
+}
+
+// diffJSON is the --format json output of --diff: the report of the new
+// findings, with the resolved ones alongside.
+type diffJSON struct {
+	SchemaVersion int               `json:"schema_version"`
+	Libraries     []string          `json:"libraries,omitempty"`
+	Findings      []cleanup.Finding `json:"findings"`
+	Resolved      []cleanup.Finding `json:"resolved"`
+	Errors        []string          `json:"errors,omitempty"`
+}
+
+func writeDiffJSON(w io.Writer, d ReportDiff) error {
+	out := diffJSON{
+		SchemaVersion: cleanup.SchemaVersion,
+		Libraries:     d.New.Libraries,
+		Findings:      d.New.Findings,
+		Resolved:      d.Resolved.Findings,
+	}
+	if out.Findings == nil {
+		out.Findings = []cleanup.Finding{}
+	}
+	if out.Resolved == nil {
+		out.Resolved = []cleanup.Finding{}
+	}
+	for _, err := range d.New.Errors {
+		out.Errors = append(out.Errors, err.Error())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// changedFinding is a --format jsonl line of --diff.
+type changedFinding struct {
+	cleanup.Finding
+	Change string `json:"change"` // new or resolved
+}
+
+func writeDiffJSONL(w io.Writer, d ReportDiff) error {
+	enc := json.NewEncoder(w)
+	for _, f := range d.New.Findings {
+		if err := enc.Encode(changedFinding{f, "new"}); err != nil {
+			return err
+		}
+	}
+	for _, f := range d.Resolved.Findings {
+		if err := enc.Encode(changedFinding{f, "resolved"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for report diffs
+// ============================================================================
+
+func TestDiffResults(t *testing.T) {
+	previous := &cleanup.CleanupResult{Libraries: []string{"/lib"}, Errors: []error{errors.New("/lib/S: permission denied")}}
+	previous.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/S/x.txt", Message: "Unexpected file"})
+	previous.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A"})
+
+	current := &cleanup.CleanupResult{Libraries: []string{"/lib"}, Errors: []error{errors.New("/lib/S: permission denied")}}
+	current.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/S/x.txt", Message: "Unexpected file"})
+	current.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/B"})
+
+	d := diffResults(previous, current)
+	if len(d.New.Findings) != 1 || d.New.Findings[0].Path != "/lib/S/B" {
+		t.Errorf("Expected only /lib/S/B as new, got %v", d.New.Findings)
+	}
+	if len(d.New.EmptyFolders) != 1 {
+		t.Errorf("Expected per-category lists built, got %v", d.New.EmptyFolders)
+	}
+	if len(d.Resolved.Findings) != 1 || d.Resolved.Findings[0].Path != "/lib/S/A" {
+		t.Errorf("Expected only /lib/S/A as resolved, got %v", d.Resolved.Findings)
+	}
+	if len(d.New.Errors) != 0 {
+		t.Errorf("Expected the known scan error left out, got %v", d.New.Errors)
+	}
+}
+
+func TestRunDiff_ComparesAgainstPreviousRun(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "last.json")
+
+	first := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	first.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/S/x.txt", Message: "Unexpected file"})
+	first.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A"})
+
+	var buf bytes.Buffer
+	if _, err := runDiff(&buf, path, reportWriter{format: "text"}, first); err != nil {
+		t.Fatalf("runDiff returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "/lib/S/A") || !strings.Contains(buf.String(), "/lib/S/x.txt") {
+		t.Errorf("Expected every finding new on the first run, got %q", buf.String())
+	}
+
+	second := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	second.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/S/x.txt", Message: "Unexpected file"})
+	buf.Reset()
+	if _, err := runDiff(&buf, path, reportWriter{format: "text"}, second); err != nil {
+		t.Fatalf("runDiff returned error: %v", err)
+	}
+	output := buf.String()
+	if strings.Contains(output, "/lib/S/x.txt") {
+		t.Errorf("Expected the known warning left out, got %q", output)
+	}
+	if !strings.Contains(output, "Resolved findings (1):\n   /lib/S/A\n") {
+		t.Errorf("Expected /lib/S/A resolved, got %q", output)
+	}
+
+	buf.Reset()
+	if _, err := runDiff(&buf, path, reportWriter{format: "text"}, second); err != nil {
+		t.Fatalf("runDiff returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No changes") {
+		t.Errorf("Expected no changes, got %q", buf.String())
+	}
+}
+
+func TestRunDiff_InvalidReport(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "last.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := runDiff(&buf, path, reportWriter{format: "text"}, &cleanup.CleanupResult{}); err == nil {
+		t.Error("Expected an error for an invalid report")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "not json" {
+		t.Errorf("Expected the report left alone, got %q", data)
+	}
+}
+
+func TestWriteDiff_Formats(t *testing.T) {
+	d := ReportDiff{New: &cleanup.CleanupResult{}, Resolved: &cleanup.CleanupResult{}}
+	d.New.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/B"})
+	d.Resolved.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A"})
+
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "jsonl"}).writeDiff(&buf, d, time.Time{}); err != nil {
+		t.Fatalf("writeDiff returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	var line changedFinding
+	if err := json.Unmarshal([]byte(lines[1]), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Path != "/lib/S/A" || line.Change != "resolved" {
+		t.Errorf("Expected /lib/S/A resolved, got %+v", line)
+	}
+
+	buf.Reset()
+	if err := (reportWriter{format: "text", summary: true}).writeDiff(&buf, d, time.Time{}); err != nil {
+		t.Fatalf("writeDiff returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "empty_folder=1 ") || !strings.HasSuffix(buf.String(), " resolved=1\n") {
+		t.Errorf("Expected the new counts and the resolved ones, got %q", buf.String())
+	}
+
+	buf.Reset()
+	empty := ReportDiff{New: &cleanup.CleanupResult{}, Resolved: &cleanup.CleanupResult{}}
+	if err := (reportWriter{format: "text", quiet: true}).writeDiff(&buf, empty, time.Time{}); err != nil {
+		t.Fatalf("writeDiff returned error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output with --quiet and no changes, got %q", buf.String())
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// DigestState accumulates the findings of scheduled runs between two
+// digests. It is stored as JSON in the file given to --digest.
+type DigestState struct {
+	// Since is when the current digest period started.
+	Since time.Time `json:"since"`
+	// Runs counts the scans merged into Result during the period.
+	Runs   int                    `json:"runs"`
+	Result *cleanup.CleanupResult `json:"result"`
+}
+
+// LoadDigest reads the digest state at path. A missing file starts a new
+// period at now.
+func LoadDigest(path string, now time.Time) (*DigestState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &DigestState{Since: now, Result: &cleanup.CleanupResult{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &DigestState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("digest %s: %w", path, err)
+	}
+	if state.Result == nil {
+		state.Result = &cleanup.CleanupResult{}
+	}
+	return state, nil
+}
+
+// Save writes the state to path, replacing the previous file atomically so
+// an interrupted run never leaves a truncated digest behind.
+func (d *DigestState) Save(path string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Add merges the findings of one run into the digest. A finding reported by
+// several runs is kept once, with the values of the latest run.
+func (d *DigestState) Add(result *cleanup.CleanupResult) {
+	merged := &cleanup.CleanupResult{Libraries: d.Result.Libraries}
+	index := make(map[findingKey]int)
+	for _, f := range append(d.Result.Findings, result.Findings...) {
+		k := keyOf(f)
+		if i, ok := index[k]; ok {
+			merged.Findings[i] = f
+			continue
+		}
+		index[k] = len(merged.Findings)
+		merged.Findings = append(merged.Findings, f)
+	}
+	merged.Merge(&cleanup.CleanupResult{Libraries: result.Libraries})
+	seen := make(map[string]bool)
+	for _, err := range append(d.Result.Errors, result.Errors...) {
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			merged.Errors = append(merged.Errors, err)
+		}
+	}
+
+	// Rebuild the per-category lists from the deduplicated findings
+	d.Result = &cleanup.CleanupResult{Libraries: merged.Libraries, Errors: merged.Errors}
+	for _, f := range merged.Findings {
+		d.Result.Add(f)
+	}
+	d.Runs++
+}
+
+// Due reports whether a digest period of length every has elapsed at now.
+func (d *DigestState) Due(now time.Time, every time.Duration) bool {
+	return !now.Before(d.Since.Add(every))
+}
+
+// runDigest adds result to the digest at path and, once every has elapsed
+// since the last digest, writes the accumulated findings to w with rw, posts
+// them to webhook if it is set, and starts a new period. Runs that are not
+// due write nothing, so a cron job only mails its output when a digest is
+// sent. A digest the webhook does not accept is sent again by the next run.
+func runDigest(w io.Writer, path string, every time.Duration, webhook string, rw reportWriter, result *cleanup.CleanupResult, now time.Time) error {
+	state, err := LoadDigest(path, now)
+	if err != nil {
+		return err
+	}
+	state.Add(result)
+
+	if state.Due(now, every) {
+		if webhook != "" {
+			rw.color = false
+		}
+		var digest bytes.Buffer
+		if rw.format == "text" {
+			rw.lang.Fprintf(&digest, "📬 Digest of %d runs since %s\n", state.Runs, state.Since.Format("2006-01-02 15:04"))
+		}
+		if err := rw.write(&digest, state.Result); err != nil {
+			return err
+		}
+		if _, err := w.Write(digest.Bytes()); err != nil {
+			return err
+		}
+		if webhook != "" {
+			if err := postDigest(webhook, rw.format, digest.Bytes()); err != nil {
+				return errors.Join(err, state.Save(path))
+			}
+		}
+		state = &DigestState{Since: now, Result: &cleanup.CleanupResult{}}
+	}
+	return state.Save(path)
+}
+
+// digestTimeout bounds a --digest-webhook request.
+const digestTimeout = 30 * time.Second
+
+// postDigest posts a digest written in format to webhook: the text report
+// as plain text, which ntfy and most relays take as the message, or the
+// JSON as it is. The URL is left out of errors, as it often holds a token.
+func postDigest(webhook, format string, digest []byte) error {
+	contentType := "text/plain; charset=utf-8"
+	switch format {
+	case "json":
+		contentType = "application/json"
+	case "jsonl":
+		contentType = "application/x-ndjson"
+	}
+	client := &http.Client{Timeout: digestTimeout}
+	resp, err := client.Post(webhook, contentType, bytes.NewReader(digest))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("digest webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("digest webhook: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for digest state
+// ============================================================================
+
+func TestDigestState_AddDeduplicates(t *testing.T) {
+	state := &DigestState{Result: &cleanup.CleanupResult{}}
+
+	first := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	first.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A", Usage: cleanup.Usage{Bytes: 10}})
+	state.Add(first)
+
+	second := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	second.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A", Usage: cleanup.Usage{Bytes: 20}})
+	second.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/B"})
+	state.Add(second)
+
+	if state.Runs != 2 {
+		t.Errorf("Expected 2 runs, got %d", state.Runs)
+	}
+	if len(state.Result.Findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(state.Result.Findings), state.Result.Findings)
+	}
+	if state.Result.Findings[0].Bytes != 20 {
+		t.Errorf("Expected the latest run's values, got %d bytes", state.Result.Findings[0].Bytes)
+	}
+	if len(state.Result.OrphanedFolders) != 1 || len(state.Result.EmptyFolders) != 1 {
+		t.Errorf("Expected per-category lists rebuilt, got %v and %v", state.Result.OrphanedFolders, state.Result.EmptyFolders)
+	}
+	if len(state.Result.Libraries) != 1 {
+		t.Errorf("Expected 1 library, got %v", state.Result.Libraries)
+	}
+}
+
+func TestRunDigest_ReportsOnlyWhenDue(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "digest.json")
+	start := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	result := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A"})
+
+	var buf bytes.Buffer
+	for day := 0; day < 7; day++ {
+		if err := runDigest(&buf, path, week, "", reportWriter{format: "text"}, result, start.Add(time.Duration(day)*24*time.Hour)); err != nil {
+			t.Fatalf("runDigest returned error: %v", err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no output before the digest is due, got %q", buf.String())
+	}
+
+	if err := runDigest(&buf, path, week, "", reportWriter{format: "text"}, result, start.Add(week)); err != nil {
+		t.Fatalf("runDigest returned error: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, "Digest of 8 runs since 2024-01-01 03:00") {
+		t.Errorf("Expected digest header, got %q", output)
+	}
+	if strings.Count(output, "/lib/S/A") != 1 {
+		t.Errorf("Expected the finding reported once, got %q", output)
+	}
+
+	state, err := LoadDigest(path, time.Time{})
+	if err != nil {
+		t.Fatalf("LoadDigest returned error: %v", err)
+	}
+	if state.Runs != 0 || len(state.Result.Findings) != 0 || !state.Since.Equal(start.Add(week)) {
+		t.Errorf("Expected a new period after the digest, got %+v", state)
+	}
+}
+
+func TestRunDigest_PostsToWebhook(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "digest.json")
+	start := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	status := http.StatusServiceUnavailable
+	var posts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+			t.Errorf("Expected a plain text POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		posts = append(posts, string(body))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	webhook := server.URL + "/secret-topic"
+
+	result := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A"})
+	var buf bytes.Buffer
+	if err := runDigest(&buf, path, week, webhook, reportWriter{format: "text"}, result, start); err != nil {
+		t.Fatalf("runDigest returned error: %v", err)
+	}
+	if len(posts) != 0 {
+		t.Fatalf("Expected nothing posted before the digest is due, got %q", posts)
+	}
+
+	// A refused digest is kept for the next run
+	err := runDigest(&buf, path, week, webhook, reportWriter{format: "text"}, result, start.Add(week))
+	if err == nil || strings.Contains(err.Error(), "secret-topic") {
+		t.Fatalf("Expected an error without the URL, got %v", err)
+	}
+	if state, err := LoadDigest(path, time.Time{}); err != nil || state.Runs != 2 {
+		t.Fatalf("Expected the digest kept after a refused post, got %+v (%v)", state, err)
+	}
+
+	status = http.StatusOK
+	if err := runDigest(&buf, path, week, webhook, reportWriter{format: "text"}, result, start.Add(week+24*time.Hour)); err != nil {
+		t.Fatalf("runDigest returned error: %v", err)
+	}
+	if len(posts) != 2 || !strings.Contains(posts[1], "Digest of 3 runs since 2024-01-01 03:00") || !strings.Contains(posts[1], "/lib/S/A") {
+		t.Errorf("Expected the digest of 3 runs posted, got %q", posts)
+	}
+	if state, err := LoadDigest(path, time.Time{}); err != nil || state.Runs != 0 {
+		t.Errorf("Expected a new period once the digest was posted, got %+v (%v)", state, err)
+	}
+}
@@ -0,0 +1,651 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// Language translates the human-readable output: the text report, with the
+// messages of its findings, and what the CLI prints on stdout and stderr.
+// Messages are looked up by their English format string; anything without a
+// translation is printed in English. The JSON/JSONL formats are never
+// translated.
+type Language struct {
+	Code     string
+	messages map[string]string
+}
+
+// English is the default Language.
+var English = &Language{Code: "en"}
+
+// Languages lists the supported languages by code.
+var Languages = map[string]*Language{
+	"en": English,
+	"fr": {Code: "fr", messages: frenchMessages},
+	"de": {Code: "de", messages: germanMessages},
+}
+
+// LanguageFor returns the Language for a code or locale name such as "fr",
+// "de-DE" or "fr_FR.UTF-8". An empty name selects English.
+func LanguageFor(name string) (*Language, error) {
+	if name == "" {
+		return English, nil
+	}
+	code := strings.ToLower(name)
+	if i := strings.IndexAny(code, "_-.@"); i >= 0 {
+		code = code[:i]
+	}
+	if code == "c" || code == "posix" {
+		return English, nil
+	}
+	if lang, ok := Languages[code]; ok {
+		return lang, nil
+	}
+	return nil, fmt.Errorf("unsupported language %q (expected en, fr or de)", name)
+}
+
+// languageFromEnv picks the language from the usual locale variables, in
+// the order the C library consults them. Unsupported locales fall back to
+// English rather than failing.
+func languageFromEnv() *Language {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if lang, err := LanguageFor(value); err == nil {
+				return lang
+			}
+			return English
+		}
+	}
+	return English
+}
+
+// T returns the translation of msg, or msg itself.
+func (l *Language) T(msg string) string {
+	if l == nil {
+		return msg
+	}
+	if translated, ok := l.messages[msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Sprintf formats the translation of format.
+func (l *Language) Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(l.T(format), args...)
+}
+
+// findingMessages are the formats of the finding messages the scan writes
+// with values in them, so that the text report can translate them. Messages
+// without values, such as "Missing NFO", are translated as they are.
+var findingMessages = []string{
+	"%s modified within the last %s, left for now",
+	"Same size (%s) as %s",
+	"Identical to %s",
+	"Title mismatch: folder says %q, %s says %q",
+	"Year mismatch: folder says %s, %s says %s",
+	"owner %d, expected %s",
+	"group %d, expected %s",
+	"mode %04o, expected %04o",
+	"Name not portable (%s)",
+	"reserved characters %s",
+	"%d bytes long (limit %d)",
+	"Name differs only by case from %s",
+	"ffprobe cannot read it: %s",
+	"Holds %s",
+	"Unexpected subdirectory in %s folder",
+	"Multiple unrelated videos in %s folder (%s)",
+	"Partial download (%s)",
+	"Only video is in an extras subfolder (%s)",
+	"Symlink target %s is missing",
+	"Video file is only %s, below the minimum of %s",
+	"Video file at %s level (should be in %s folder)",
+	"Metadata file at %s level (should be in %s folder)",
+	"Subtitles for missing videos (%s)",
+}
+
+// messageVerb matches a formatting verb such as %s or %04o.
+var messageVerb = regexp.MustCompile(`%[-+# 0-9]*[a-z]`)
+
+// findingPatterns match the messages made from findingMessages, in the same
+// order, capturing their values.
+var findingPatterns = func() []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(findingMessages))
+	for i, format := range findingMessages {
+		patterns[i] = regexp.MustCompile("^" + messageVerb.ReplaceAllString(regexp.QuoteMeta(format), "(.+?)") + "$")
+	}
+	return patterns
+}()
+
+// Message returns the translation of the finding message msg: the format of
+// findingMessages it was made from is translated and filled in with the same
+// values, themselves translated when the catalog has them, such as layout
+// levels or the problems of a name. Other messages are returned as they are.
+func (l *Language) Message(msg string) string {
+	if l == nil || l.messages == nil {
+		return msg
+	}
+	if translated, ok := l.messages[msg]; ok {
+		return translated
+	}
+	for i, pattern := range findingPatterns {
+		translated, ok := l.messages[findingMessages[i]]
+		values := pattern.FindStringSubmatch(msg)
+		if !ok || values == nil {
+			continue
+		}
+		args := make([]any, len(values)-1)
+		for j, value := range values[1:] {
+			parts := strings.Split(value, ", ")
+			for k, part := range parts {
+				parts[k] = l.Message(part)
+			}
+			args[j] = strings.Join(parts, ", ")
+		}
+		return fmt.Sprintf(messageVerb.ReplaceAllString(translated, "%s"), args...)
+	}
+	return msg
+}
+
+// Finding returns the line of f in the text report, as Finding.String does
+// with its message translated.
+func (l *Language) Finding(f cleanup.Finding) string {
+	if f.Message == "" {
+		return f.Path
+	}
+	return l.Message(f.Message) + ": " + f.Path
+}
+
+// Fprintf writes the translation of format to w.
+func (l *Language) Fprintf(w io.Writer, format string, args ...any) {
+	fmt.Fprintf(w, l.T(format), args...)
+}
+
+var frenchMessages = map[string]string{
+	// Report
+	"\n%s (%d):\n":       "\n%s (%d) :\n",
+	"\n%s: %s":           "\n%s : %s",
+	"\n%s:\n":            "\n%s :\n",
+	"Structure warnings": "Avertissements de structure",
+	"Misfiled videos (main feature in an extras folder, not deleted)": "Vidéos mal rangées (film principal dans un dossier de bonus, non supprimées)",
+	"Downloads in progress (not deleted)":                             "Téléchargements en cours (non supprimés)",
+	"Empty or truncated videos (not deleted)":                         "Vidéos vides ou tronquées (non supprimées)",
+	"Corrupt videos (ffprobe failed, not deleted)":                    "Vidéos corrompues (échec de ffprobe, non supprimées)",
+	"Broken video symlinks (target missing)":                          "Liens symboliques de vidéos cassés (cible absente)",
+	"Missing metadata (NFO or poster)":                                "Métadonnées manquantes (NFO ou affiche)",
+	"Orphaned metadata folders (no video file)":                       "Dossiers de métadonnées orphelins (aucune vidéo)",
+	"Orphaned metadata files (no video file at same level)":           "Fichiers de métadonnées orphelins (aucune vidéo au même niveau)",
+	"Empty folders":                             "Dossiers vides",
+	"OS junk files":                             "Fichiers parasites du système",
+	"Reclaimable space":                         "Espace récupérable",
+	"Possible duplicate videos":                 "Doublons de vidéos possibles",
+	"Space wasted by identical copies":          "Espace gaspillé par les copies identiques",
+	"Permission mismatches":                     "Permissions incorrectes",
+	"Metadata mismatches (NFO vs folder name)":  "Métadonnées incohérentes (NFO / nom du dossier)",
+	"Names incompatible with Windows/exFAT/SMB": "Noms incompatibles avec Windows/exFAT/SMB",
+	"NAS recycle bins (not scanned or deleted)": "Corbeilles du NAS (non analysées ni supprimées)",
+	"Per-library summary":                       "Résumé par bibliothèque",
+	"Resolved findings":                         "Problèmes résolus",
+	"Largest orphans":                           "Plus gros orphelins",
+	"Oldest orphans":                            "Plus vieux orphelins",
+	"Scan errors":                               "Erreurs d'analyse",
+	" (another %s is in %d hardlinked files and stays on disk)":                   " (%s de plus dans %d fichiers à liens physiques restent sur le disque)",
+	"%s: %d orphaned folders, %d orphaned files, %d empty folders, %d warnings\n": "%s : %d dossiers orphelins, %d fichiers orphelins, %d dossiers vides, %d avertissements\n",
+	"📬 Digest of %d runs since %s\n":                                              "📬 Synthèse de %d analyses depuis le %s\n",
+	"\n🔀 Changes since the report of %s\n":                                        "\n🔀 Changements depuis le rapport du %s\n",
+	"✨ No changes\n": "✨ Aucun changement\n",
+
+	// Check
+	"\n🔎 %s: %s\n":                                "\n🔎 %s : %s\n",
+	"delete, metadata without a video file":       "à supprimer, métadonnées sans fichier vidéo",
+	"delete, empty folder":                        "à supprimer, dossier vide",
+	"keep, the main video is in an extras folder": "à conserver, la vidéo principale est dans un dossier de bonus",
+	"keep, a download is still in progress":       "à conserver, un téléchargement est en cours",
+	"keep, see the findings above":                "à conserver, voir les problèmes ci-dessus",
+	"keep, nothing to clean up":                   "à conserver, rien à nettoyer",
+
+	// Trace (--verbose)
+	"%s: %d top-level folders to scan\n":                         "%s : %d dossiers de premier niveau à analyser\n",
+	"%s: skipped, %s\n":                                          "%s : ignoré, %s\n",
+	"left out by the include and exclude patterns":               "exclu par les motifs d'inclusion et d'exclusion",
+	"listed in an ignore file":                                   "listé dans un fichier d'exclusion",
+	"has a video, kept":                                          "a une vidéo, conservé",
+	"no video, reported as orphaned":                             "pas de vidéo, signalé comme orphelin",
+	"empty, reported":                                            "vide, signalé",
+	"only video is in an extras subfolder, reported as misfiled": "seule vidéo dans un sous-dossier de bonus, signalée comme mal rangée",
+	"no video but ignored entries, kept":                         "pas de vidéo mais des entrées ignorées, conservé",
+	"unchanged since the last scan, findings reused":             "inchangé depuis la dernière analyse, problèmes repris",
+
+	// CLI
+	"=== DRY RUN MODE (use --execute to actually delete) ===\n\n": "=== SIMULATION (utilisez --execute pour supprimer réellement) ===\n\n",
+	"Checking title: %s\n":                           "Vérification du titre : %s\n",
+	"Installed %s, running every %s: %s\n":           "%s installé, exécuté toutes les %s : %s\n",
+	"Uninstalled %s\n":                               "%s désinstallé\n",
+	"Applying plan %s (%d items, made %s)\n":         "Application du plan %s (%d éléments, établi le %s)\n",
+	"\n🛡️  Kept %d items still wanted by %s\n":       "\n🛡️  %d éléments conservés, encore attendus par %s\n",
+	"Report written to %s\n":                         "Rapport écrit dans %s\n",
+	"\nCancelled, nothing was deleted\n":             "\nAnnulé, rien n'a été supprimé\n",
+	"Type %s to confirm: ":                           "Tapez %s pour confirmer : ",
+	"   %s (protected by %s)\n":                      "   %s (protégé par %s)\n",
+	"⚠️  Rescan request failed: %v\n":                "⚠️  Échec de la demande de réanalyse : %v\n",
+	"↩️  Restored: %s\n":                             "↩️  Restauré : %s\n",
+	"\nRestored %d items, %d failures\n":             "\n%d éléments restaurés, %d échecs\n",
+	"Undoing run %s\n":                               "Annulation de l'exécution %s\n",
+	"Nothing to undo in %s\n":                        "Rien à annuler dans %s\n",
+	"⚠️  Deleted for good, not restorable: %s\n":     "⚠️  Supprimé définitivement, impossible à restaurer : %s\n",
+	"📝 Recorded in %s as run %s\n":                   "📝 Enregistré dans %s comme exécution %s\n",
+	"🗄️  Backed up %d items to %s\n":                 "🗄️  %d éléments sauvegardés dans %s\n",
+	"⚠️  Backup archive may be incomplete: %v\n":     "⚠️  L'archive de sauvegarde est peut-être incomplète : %v\n",
+	"Purged %d items quarantined more than %s ago\n": "%d éléments mis en quarantaine il y a plus de %s purgés\n",
+	"⚠️  Quarantine purge failed: %v\n":              "⚠️  Échec de la purge de la quarantaine : %v\n",
+	"\n📝 Plan with %d items written to %s, review it and run: video-folder-cleanup apply %s\n": "\n📝 Plan de %d éléments écrit dans %s, relisez-le puis lancez : video-folder-cleanup apply %s\n",
+	"\n⏹️  Interrupted, finishing the items in progress (interrupt again to stop at once)\n":   "\n⏹️  Interrompu, fin des éléments en cours (interrompre à nouveau pour arrêter immédiatement)\n",
+	"\n🛑 Deletions refused, nothing was deleted: %v\n":                                         "\n🛑 Suppressions refusées, rien n'a été supprimé : %v\n",
+	"\n⚠️  About to delete %d items from %s\n":                                                 "\n⚠️  %d éléments vont être supprimés de %s\n",
+	"\n🔒 Protected paths would be touched, nothing was done:\n":                                "\n🔒 Des chemins protégés seraient touchés, rien n'a été fait :\n",
+	"%s: %d top-level folders to scan, %d finished by the interrupted scan\n":                  "%s : %d dossiers de premier niveau à analyser, %d terminés par l'analyse interrompue\n",
+	"↪️  Resuming %s: %d of %d top-level folders were finished by the interrupted scan\n":      "↪️  Reprise de %s : %d dossiers de premier niveau sur %d terminés par l'analyse interrompue\n",
+	"Scanning library: %s\n": "Analyse de la bibliothèque : %s\n",
+	"⚠️  ffprobe not found in PATH, videos are not probed\n":                                                "⚠️  ffprobe introuvable dans le PATH, les vidéos ne sont pas analysées\n",
+	"%d/%d studios, %d titles scanned, %d items found":                                                      "%d/%d studios, %d titres analysés, %d éléments trouvés",
+	"%d/%d shows, %d seasons scanned, %d items found":                                                       "%d/%d séries, %d saisons analysées, %d éléments trouvés",
+	"%d/%d titles scanned, %[4]d items found":                                                               "%d/%d titres analysés, %[4]d éléments trouvés",
+	"\n⚠️  Scan aborted (%v), results above are partial and nothing was deleted\n":                          "\n⚠️  Analyse interrompue (%v), les résultats ci-dessus sont partiels et rien n'a été supprimé\n",
+	"\n⚠️  Stopped after %d findings (--max-findings), results above are partial and nothing was deleted\n": "\n⚠️  Arrêt après %d problèmes (--max-findings), les résultats ci-dessus sont partiels et rien n'a été supprimé\n",
+	"Executing deletions...\n":                                                                              "Suppression en cours...\n",
+	"🔗 Kept hardlinked files in: %s\n":                                                                      "🔗 Fichiers à liens physiques conservés dans : %s\n",
+	"✓ Deleted: %s\n":                                                                                       "✓ Supprimé : %s\n",
+	"\nDeleted %d items, %d failures\n":                                                                     "\n%d éléments supprimés, %d échecs\n",
+	"Kept %d items with hardlinked files\n":                                                                 "%d éléments conservés à cause de liens physiques\n",
+	"⚠️  Deletion aborted: %v\n":                                                                            "⚠️  Suppression interrompue : %v\n",
+	"\nFixing permissions...\n":                                                                             "\nCorrection des permissions...\n",
+	"✓ Fixed: %s\n":                                                                                         "✓ Corrigé : %s\n",
+	"\nFixed %d items, %d failures\n":                                                                       "\n%d éléments corrigés, %d échecs\n",
+	"⚠️  Permission fix aborted: %v\n":                                                                      "⚠️  Correction des permissions interrompue : %v\n",
+	"\n💡 Run with --execute to delete %d items\n":                                                           "\n💡 Relancez avec --execute pour supprimer %d éléments\n",
+	"\n💡 %d items to delete on ssh:// libraries, run the tool on that machine with --execute to delete them\n": "\n💡 %d éléments à supprimer dans des bibliothèques ssh://, lancez l'outil sur cette machine avec --execute pour les supprimer\n",
+	"\n💡 Run with --execute to delete %d items and reclaim ~%s\n":                                              "\n💡 Relancez avec --execute pour supprimer %d éléments et récupérer ~%s\n",
+	"\n💡 Run with --execute to fix permissions of %d items\n":                                                  "\n💡 Relancez avec --execute pour corriger les permissions de %d éléments\n",
+	"\nMoving misplaced files...\n":                                                                            "\nDéplacement des fichiers mal placés...\n",
+	"✓ Moved: %s → %s\n":                                                                                       "✓ Déplacé : %s → %s\n",
+	"\nMoved %d files, %d failures\n":                                                                          "\n%d fichiers déplacés, %d échecs\n",
+	"⚠️  Move aborted: %v\n":                                                                                   "⚠️  Déplacement interrompu : %v\n",
+	"\n💡 Run with --execute to move %d misplaced files:\n":                                                     "\n💡 Relancez avec --execute pour déplacer %d fichiers mal placés :\n",
+	"\nRenaming title folders...\n":                                                                            "\nRenommage des dossiers de titres...\n",
+	"✓ Renamed: %s → %s\n":                                                                                     "✓ Renommé : %s → %s\n",
+	"\nRenamed %d folders, %d failures\n":                                                                      "\n%d dossiers renommés, %d échecs\n",
+	"⚠️  Rename aborted: %v\n":                                                                                 "⚠️  Renommage interrompu : %v\n",
+	"\n💡 Run with --execute to rename %d title folders:\n":                                                     "\n💡 Relancez avec --execute pour renommer %d dossiers de titres :\n",
+	"\n✓ Nothing to clean up\n":                                                                                "\n✓ Rien à nettoyer\n",
+	// Dashboard
+	"A run is in progress, this page refreshes by itself": "Une exécution est en cours, cette page se rafraîchit d'elle-même",
+	"Rescan":                     "Relancer l'analyse",
+	"No scan yet":                "Aucune analyse pour l'instant",
+	"Last scan":                  "Dernière analyse",
+	"Delete the selected items?": "Supprimer les éléments sélectionnés ?",
+	"Nothing to clean up":        "Rien à nettoyer",
+	"Delete selected":            "Supprimer la sélection",
+	"History":                    "Historique",
+	"Started":                    "Début",
+	"Run":                        "Exécution",
+	"Duration":                   "Durée",
+	"Findings":                   "Résultats",
+	"Deleted":                    "Supprimés",
+	"Failures":                   "Échecs",
+	"Reclaimed":                  "Récupéré",
+	"Exit code":                  "Code de sortie",
+	"Deletion":                   "Suppression",
+	"Scan":                       "Analyse",
+
+	// Terminal UI
+	"↑↓ move, space select, enter open/preview, a all, x delete selected, q quit": "↑↓ déplacer, espace sélectionner, entrée ouvrir/aperçu, a tout, x supprimer la sélection, q quitter",
+	"(empty)":                             "(vide)",
+	"%d selected, ~%s to reclaim":         "%d sélectionnés, ~%s à récupérer",
+	"Delete %d selected items? (y/n)":     "Supprimer les %d éléments sélectionnés ? (y/n)",
+	"Nothing selected, nothing deleted\n": "Rien de sélectionné, rien n'a été supprimé\n",
+	// Errors and warnings printed on stderr
+	"undo takes a single run id\n":                                     "undo ne prend qu'un seul identifiant d'exécution\n",
+	"plan needs --out FILE\n":                                          "plan nécessite --out FICHIER\n",
+	"%s: a remote library %s\n":                                        "%s : une bibliothèque distante %s\n",
+	"cannot be planned, checked or probed (no plan, check or --probe)": "ne peut être ni planifiée, ni vérifiée, ni sondée (pas de plan, check ou --probe)",
+	"can only be scanned and reported on (no --execute)":               "peut seulement être analysée (pas de --execute)",
+	"only supports permanent deletions (no --fix, --fix-names, --fix-perms, --preserve-hardlinks, trash, quarantine or --backup-to)":              "ne permet que les suppressions définitives (pas de --fix, --fix-names, --fix-perms, --preserve-hardlinks, corbeille, quarantaine ou --backup-to)",
+	"Unknown format %q (expected text, json, jsonl or ndjson)\n":                                                                                  "Format %q inconnu (attendu : text, json, jsonl ou ndjson)\n",
+	"Unknown --sort order %q (expected size or age)\n":                                                                                            "Ordre --sort %q inconnu (attendu : size ou age)\n",
+	"Unknown --fail-on severity %q (expected warning or error)\n":                                                                                 "Gravité --fail-on %q inconnue (attendu : warning ou error)\n",
+	"--every must be at least 1m, got %s\n":                                                                                                       "--every doit valoir au moins 1m, reçu %s\n",
+	"--every and --schedule cannot be combined\n":                                                                                                 "--every et --schedule ne peuvent pas être combinés\n",
+	"--every and --schedule cannot be combined with check, plan, apply, restore, undo or service\n":                                               "--every et --schedule ne peuvent pas être combinés avec check, plan, apply, restore, undo ou service\n",
+	"serve cannot be combined with --execute, --fix, --fix-names, --fix-perms or --digest, deletions are approved in the dashboard\n":             "serve ne peut pas être combiné avec --execute, --fix, --fix-names, --fix-perms ou --digest, les suppressions sont approuvées dans le tableau de bord\n",
+	"serve --addr %s is not a loopback address: the dashboard has no login, reach it through a reverse proxy\n":                                   "serve --addr %s n'est pas une adresse de bouclage : le tableau de bord n'a pas d'authentification, accédez-y via un proxy inverse\n",
+	"--diff cannot be combined with --execute, --digest, --interactive, serve, plan, apply, restore or undo\n":                                    "--diff ne peut pas être combiné avec --execute, --digest, --interactive, serve, plan, apply, restore ou undo\n",
+	"--interactive cannot be combined with --execute, --every, --schedule, --digest, --format, --fix, --fix-names, --fix-perms or a subcommand\n": "--interactive ne peut pas être combiné avec --execute, --every, --schedule, --digest, --format, --fix, --fix-names, --fix-perms ou une sous-commande\n",
+	"--interactive needs a terminal\n":                                                                                                            "--interactive nécessite un terminal\n",
+	"service install with --execute needs --yes (or --confirm-over 0), the scheduled runs cannot ask for confirmation\n":                          "service install avec --execute nécessite --yes (ou --confirm-over 0), les exécutions planifiées ne peuvent pas demander de confirmation\n",
+	"Failed to install the service: %v\n":                                                                                                         "Échec de l'installation du service : %v\n",
+	"Failed to uninstall the service: %v\n":                                                                                                       "Échec de la désinstallation du service : %v\n",
+	"Unknown service command %q (expected install or uninstall)\n":                                                                                "Commande de service %q inconnue (attendu : install ou uninstall)\n",
+	"--summary only applies to --format text\n":                                                                                                   "--summary ne s'applique qu'à --format text\n",
+	"--quiet cannot be combined with --verbose or --summary\n":                                                                                    "--quiet ne peut pas être combiné avec --verbose ou --summary\n",
+	"--format ndjson cannot be combined with --sort, --digest, --diff or --output\n":                                                              "--format ndjson ne peut pas être combiné avec --sort, --digest, --diff ou --output\n",
+	"--output cannot be combined with --digest, --diff or --interactive\n":                                                                        "--output ne peut pas être combiné avec --digest, --diff ou --interactive\n",
+	"--trash cannot be combined with --delete-mode %s\n":                                                                                          "--trash ne peut pas être combiné avec --delete-mode %s\n",
+	"--quarantine cannot be combined with --trash or --delete-mode\n":                                                                             "--quarantine ne peut pas être combiné avec --trash ou --delete-mode\n",
+	"restore needs --quarantine DIR\n":                                                                                                            "restore nécessite --quarantine DOSSIER\n",
+	"undo needs --manifest FILE\n":                                                                                                                "undo nécessite --manifest FICHIER\n",
+	"--metrics needs --every or --schedule\n":                                                                                                     "--metrics nécessite --every ou --schedule\n",
+	"--resume needs --checkpoint\n":                                                                                                               "--resume nécessite --checkpoint\n",
+	"--digest cannot be combined with --execute\n":                                                                                                "--digest ne peut pas être combiné avec --execute\n",
+	"--digest-webhook needs --digest\n":                                                                                                           "--digest-webhook nécessite --digest\n",
+	"--digest-webhook must be an http:// or https:// URL\n":                                                                                       "--digest-webhook doit être une URL http:// ou https://\n",
+	"plan cannot be combined with --execute, use apply once the plan is reviewed\n":                                                               "plan ne peut pas être combiné avec --execute, utilisez apply une fois le plan relu\n",
+	"--radarr-url needs --radarr-api-key or RADARR_API_KEY\n":                                                                                     "--radarr-url nécessite --radarr-api-key ou RADARR_API_KEY\n",
+	"--sonarr-url needs --sonarr-api-key or SONARR_API_KEY\n":                                                                                     "--sonarr-url nécessite --sonarr-api-key ou SONARR_API_KEY\n",
+	"--sonarr-url needs --structure tv\n":                                                                                                         "--sonarr-url nécessite --structure tv\n",
+	"--plex-url needs --plex-token or PLEX_TOKEN\n":                                                                                               "--plex-url nécessite --plex-token ou PLEX_TOKEN\n",
+	"--plex-scan needs --plex-url\n":                                                                                                              "--plex-scan nécessite --plex-url\n",
+	"Cannot reach %s, nothing was scanned: %v\n":                                                                                                  "%s injoignable, rien n'a été analysé : %v\n",
+	"--max-iops must not be negative, got %d\n":                                                                                                   "--max-iops ne doit pas être négatif, reçu %d\n",
+	"--max-delete-count and --max-delete-percent must not be negative\n":                                                                          "--max-delete-count et --max-delete-percent ne doivent pas être négatifs\n",
+	"Plan %s not applied, nothing was deleted: %v\n":                                                                                              "Plan %s non appliqué, rien n'a été supprimé : %v\n",
+	"Nothing was scanned: %v\n":                                                                                                                   "Rien n'a été analysé : %v\n",
+	"⚠️  Library lock not released, later runs may find the library locked: %v\n":                                                                 "⚠️  Verrou de bibliothèque non libéré, les exécutions suivantes pourraient la trouver verrouillée : %v\n",
+	"Invalid permission policy: %v\n":                                                                                                             "Politique de permissions invalide : %v\n",
+	"Scan state not saved, the next scan reads every title folder: %v\n":                                                                          "État d'analyse non enregistré, la prochaine analyse lira chaque dossier de titre : %v\n",
+	"Checkpoint not saved, an interrupted scan cannot be resumed: %v\n":                                                                           "Point de reprise non enregistré, une analyse interrompue ne pourra pas reprendre : %v\n",
+	"Listing cache not saved, the next scan lists every folder: %v\n":                                                                             "Cache des listes non enregistré, la prochaine analyse listera chaque dossier : %v\n",
+	"Scan aborted (%v), digest not updated\n":                                                                                                     "Analyse interrompue (%v), résumé non mis à jour\n",
+	"Scan aborted (%v), not compared and %s not updated\n":                                                                                        "Analyse interrompue (%v), pas de comparaison et %s non mis à jour\n",
+	"Failed to write report: %v\n":                                                                                                                "Échec de l'écriture du rapport : %v\n",
+	"Failed to write the plan: %v\n":                                                                                                              "Échec de l'écriture du plan : %v\n",
+
+	// Finding messages, see findingMessages, and the values they hold
+	"%s modified within the last %s, left for now": "%s modifié au cours des derniers %s, laissé pour l'instant",
+	"Orphaned folder":        "Dossier orphelin",
+	"Orphaned file":          "Fichier orphelin",
+	"Empty folder":           "Dossier vide",
+	"Same size (%s) as %s":   "Même taille (%s) que %s",
+	"Identical to %s":        "Identique à %s",
+	"Missing NFO":            "NFO manquant",
+	"Missing poster":         "Affiche manquante",
+	"Missing NFO and poster": "NFO et affiche manquants",
+	"Title mismatch: folder says %q, %s says %q":         "Titre différent : le dossier indique %q, %s indique %q",
+	"Year mismatch: folder says %s, %s says %s":          "Année différente : le dossier indique %s, %s indique %s",
+	"owner %d, expected %s":                              "propriétaire %d, attendu %s",
+	"group %d, expected %s":                              "groupe %d, attendu %s",
+	"mode %04o, expected %04o":                           "mode %04o, attendu %04o",
+	"Name not portable (%s)":                             "Nom non portable (%s)",
+	"reserved characters %s":                             "caractères réservés %s",
+	"control characters":                                 "caractères de contrôle",
+	"trailing space or dot":                              "espace ou point final",
+	"reserved device name":                               "nom de périphérique réservé",
+	"%d bytes long (limit %d)":                           "%d octets (limite %d)",
+	"Name differs only by case from %s":                  "Nom ne différant que par la casse de %s",
+	"ffprobe cannot read it: %s":                         "illisible pour ffprobe : %s",
+	"Zero or unknown duration":                           "Durée nulle ou inconnue",
+	"Holds %s":                                           "Contient %s",
+	"Unexpected subdirectory in %s folder":               "Sous-dossier inattendu dans un dossier %s",
+	"Multiple unrelated videos in %s folder (%s)":        "Plusieurs vidéos sans rapport dans un dossier %s (%s)",
+	"Partial download":                                   "Téléchargement partiel",
+	"Partial download (%s)":                              "Téléchargement partiel (%s)",
+	"Only video is in an extras subfolder (%s)":          "La seule vidéo est dans un sous-dossier de bonus (%s)",
+	"Symlink target is missing":                          "La cible du lien symbolique est absente",
+	"Symlink target %s is missing":                       "La cible %s du lien symbolique est absente",
+	"Video file is empty":                                "Le fichier vidéo est vide",
+	"Video file is only %s, below the minimum of %s":     "Le fichier vidéo ne fait que %s, sous le minimum de %s",
+	"Video file at %s level (should be in %s folder)":    "Fichier vidéo au niveau %s (devrait être dans un dossier %s)",
+	"Metadata file at %s level (should be in %s folder)": "Fichier de métadonnées au niveau %s (devrait être dans un dossier %s)",
+	"Subtitles for missing videos (%s)":                  "Sous-titres de vidéos absentes (%s)",
+	"library":                                            "bibliothèque",
+	"studio":                                             "studio",
+	"title":                                              "titre",
+	"show":                                               "série",
+	"season":                                             "saison",
+}
+
+var germanMessages = map[string]string{
+	// Report
+	"\n%s (%d):\n":       "\n%s (%d):\n",
+	"\n%s: %s":           "\n%s: %s",
+	"\n%s:\n":            "\n%s:\n",
+	"Structure warnings": "Strukturwarnungen",
+	"Misfiled videos (main feature in an extras folder, not deleted)": "Falsch abgelegte Videos (Hauptfilm in einem Extras-Ordner, nicht gelöscht)",
+	"Downloads in progress (not deleted)":                             "Laufende Downloads (nicht gelöscht)",
+	"Empty or truncated videos (not deleted)":                         "Leere oder abgeschnittene Videos (nicht gelöscht)",
+	"Corrupt videos (ffprobe failed, not deleted)":                    "Beschädigte Videos (ffprobe fehlgeschlagen, nicht gelöscht)",
+	"Broken video symlinks (target missing)":                          "Defekte Video-Symlinks (Ziel fehlt)",
+	"Missing metadata (NFO or poster)":                                "Fehlende Metadaten (NFO oder Poster)",
+	"Orphaned metadata folders (no video file)":                       "Verwaiste Metadatenordner (keine Videodatei)",
+	"Orphaned metadata files (no video file at same level)":           "Verwaiste Metadatendateien (keine Videodatei auf gleicher Ebene)",
+	"Empty folders":                             "Leere Ordner",
+	"OS junk files":                             "Systemmüll-Dateien",
+	"Reclaimable space":                         "Freizugebender Speicher",
+	"Possible duplicate videos":                 "Mögliche doppelte Videos",
+	"Space wasted by identical copies":          "Durch identische Kopien verschwendeter Speicher",
+	"Permission mismatches":                     "Abweichende Berechtigungen",
+	"Metadata mismatches (NFO vs folder name)":  "Abweichende Metadaten (NFO / Ordnername)",
+	"Names incompatible with Windows/exFAT/SMB": "Mit Windows/exFAT/SMB inkompatible Namen",
+	"NAS recycle bins (not scanned or deleted)": "Papierkörbe des NAS (nicht durchsucht oder gelöscht)",
+	"Per-library summary":                       "Zusammenfassung pro Bibliothek",
+	"Resolved findings":                         "Behobene Funde",
+	"Largest orphans":                           "Größte verwaiste Einträge",
+	"Oldest orphans":                            "Älteste verwaiste Einträge",
+	"Scan errors":                               "Scanfehler",
+	" (another %s is in %d hardlinked files and stays on disk)":                   " (weitere %s in %d hartverlinkten Dateien bleiben auf der Festplatte)",
+	"%s: %d orphaned folders, %d orphaned files, %d empty folders, %d warnings\n": "%s: %d verwaiste Ordner, %d verwaiste Dateien, %d leere Ordner, %d Warnungen\n",
+	"📬 Digest of %d runs since %s\n":                                              "📬 Zusammenfassung von %d Läufen seit %s\n",
+	"\n🔀 Changes since the report of %s\n":                                        "\n🔀 Änderungen seit dem Bericht vom %s\n",
+	"✨ No changes\n": "✨ Keine Änderungen\n",
+
+	// Check
+	"\n🔎 %s: %s\n":                                "\n🔎 %s: %s\n",
+	"delete, metadata without a video file":       "löschen, Metadaten ohne Videodatei",
+	"delete, empty folder":                        "löschen, leerer Ordner",
+	"keep, the main video is in an extras folder": "behalten, das Hauptvideo liegt in einem Extras-Ordner",
+	"keep, a download is still in progress":       "behalten, ein Download läuft noch",
+	"keep, see the findings above":                "behalten, siehe Funde oben",
+	"keep, nothing to clean up":                   "behalten, nichts aufzuräumen",
+
+	// Trace (--verbose)
+	"%s: %d top-level folders to scan\n":                         "%s: %d Ordner der obersten Ebene zu durchsuchen\n",
+	"%s: skipped, %s\n":                                          "%s: übersprungen, %s\n",
+	"left out by the include and exclude patterns":               "durch die Einschluss- und Ausschlussmuster ausgelassen",
+	"listed in an ignore file":                                   "in einer Ignorierdatei aufgeführt",
+	"has a video, kept":                                          "hat ein Video, behalten",
+	"no video, reported as orphaned":                             "kein Video, als verwaist gemeldet",
+	"empty, reported":                                            "leer, gemeldet",
+	"only video is in an extras subfolder, reported as misfiled": "einziges Video in einem Extras-Unterordner, als falsch abgelegt gemeldet",
+	"no video but ignored entries, kept":                         "kein Video, aber ignorierte Einträge, behalten",
+	"unchanged since the last scan, findings reused":             "seit dem letzten Durchlauf unverändert, Funde übernommen",
+
+	// CLI
+	"=== DRY RUN MODE (use --execute to actually delete) ===\n\n": "=== TESTLAUF (mit --execute wird tatsächlich gelöscht) ===\n\n",
+	"Checking title: %s\n":                           "Prüfe Titel: %s\n",
+	"Installed %s, running every %s: %s\n":           "%s installiert, läuft alle %s: %s\n",
+	"Uninstalled %s\n":                               "%s deinstalliert\n",
+	"Applying plan %s (%d items, made %s)\n":         "Wende Plan %s an (%d Einträge, erstellt am %s)\n",
+	"\n🛡️  Kept %d items still wanted by %s\n":       "\n🛡️  %d Einträge behalten, von %s noch benötigt\n",
+	"Report written to %s\n":                         "Bericht geschrieben nach %s\n",
+	"\nCancelled, nothing was deleted\n":             "\nAbgebrochen, nichts wurde gelöscht\n",
+	"Type %s to confirm: ":                           "Geben Sie %s zur Bestätigung ein: ",
+	"   %s (protected by %s)\n":                      "   %s (geschützt durch %s)\n",
+	"⚠️  Rescan request failed: %v\n":                "⚠️  Anfrage zum erneuten Scannen fehlgeschlagen: %v\n",
+	"↩️  Restored: %s\n":                             "↩️  Wiederhergestellt: %s\n",
+	"\nRestored %d items, %d failures\n":             "\n%d Einträge wiederhergestellt, %d Fehler\n",
+	"Undoing run %s\n":                               "Mache Lauf %s rückgängig\n",
+	"Nothing to undo in %s\n":                        "Nichts rückgängig zu machen in %s\n",
+	"⚠️  Deleted for good, not restorable: %s\n":     "⚠️  Endgültig gelöscht, nicht wiederherstellbar: %s\n",
+	"📝 Recorded in %s as run %s\n":                   "📝 In %s als Lauf %s festgehalten\n",
+	"🗄️  Backed up %d items to %s\n":                 "🗄️  %d Einträge in %s gesichert\n",
+	"⚠️  Backup archive may be incomplete: %v\n":     "⚠️  Das Sicherungsarchiv ist möglicherweise unvollständig: %v\n",
+	"Purged %d items quarantined more than %s ago\n": "%d Einträge, die vor mehr als %s in Quarantäne kamen, endgültig gelöscht\n",
+	"⚠️  Quarantine purge failed: %v\n":              "⚠️  Leeren der Quarantäne fehlgeschlagen: %v\n",
+	"\n📝 Plan with %d items written to %s, review it and run: video-folder-cleanup apply %s\n": "\n📝 Plan mit %d Einträgen nach %s geschrieben, prüfen Sie ihn und führen Sie dann aus: video-folder-cleanup apply %s\n",
+	"\n⏹️  Interrupted, finishing the items in progress (interrupt again to stop at once)\n":   "\n⏹️  Unterbrochen, laufende Elemente werden abgeschlossen (erneut unterbrechen, um sofort anzuhalten)\n",
+	"\n🛑 Deletions refused, nothing was deleted: %v\n":                                         "\n🛑 Löschungen verweigert, nichts wurde gelöscht: %v\n",
+	"\n⚠️  About to delete %d items from %s\n":                                                 "\n⚠️  %d Einträge werden aus %s gelöscht\n",
+	"\n🔒 Protected paths would be touched, nothing was done:\n":                                "\n🔒 Geschützte Pfade wären betroffen, nichts wurde ausgeführt:\n",
+	"%s: %d top-level folders to scan, %d finished by the interrupted scan\n":                  "%s: %d Ordner der obersten Ebene zu durchsuchen, %d von der unterbrochenen Suche abgeschlossen\n",
+	"↪️  Resuming %s: %d of %d top-level folders were finished by the interrupted scan\n":      "↪️  Fortsetzung von %s: %d von %d Ordnern der obersten Ebene wurden von der unterbrochenen Suche abgeschlossen\n",
+	"Scanning library: %s\n": "Durchsuche Bibliothek: %s\n",
+	"⚠️  ffprobe not found in PATH, videos are not probed\n":                                                "⚠️  ffprobe nicht im PATH gefunden, Videos werden nicht geprüft\n",
+	"%d/%d studios, %d titles scanned, %d items found":                                                      "%d/%d Studios, %d Titel durchsucht, %d Einträge gefunden",
+	"%d/%d shows, %d seasons scanned, %d items found":                                                       "%d/%d Serien, %d Staffeln durchsucht, %d Einträge gefunden",
+	"%d/%d titles scanned, %[4]d items found":                                                               "%d/%d Titel durchsucht, %[4]d Einträge gefunden",
+	"\n⚠️  Scan aborted (%v), results above are partial and nothing was deleted\n":                          "\n⚠️  Scan abgebrochen (%v), die Ergebnisse oben sind unvollständig und nichts wurde gelöscht\n",
+	"\n⚠️  Stopped after %d findings (--max-findings), results above are partial and nothing was deleted\n": "\n⚠️  Nach %d Funden angehalten (--max-findings), die Ergebnisse oben sind unvollständig und nichts wurde gelöscht\n",
+	"Executing deletions...\n":                                                                              "Lösche...\n",
+	"🔗 Kept hardlinked files in: %s\n":                                                                      "🔗 Hartverlinkte Dateien behalten in: %s\n",
+	"✓ Deleted: %s\n":                                                                                       "✓ Gelöscht: %s\n",
+	"\nDeleted %d items, %d failures\n":                                                                     "\n%d Einträge gelöscht, %d Fehler\n",
+	"Kept %d items with hardlinked files\n":                                                                 "%d Einträge mit hartverlinkten Dateien behalten\n",
+	"⚠️  Deletion aborted: %v\n":                                                                            "⚠️  Löschen abgebrochen: %v\n",
+	"\nFixing permissions...\n":                                                                             "\nKorrigiere Berechtigungen...\n",
+	"✓ Fixed: %s\n":                                                                                         "✓ Korrigiert: %s\n",
+	"\nFixed %d items, %d failures\n":                                                                       "\n%d Einträge korrigiert, %d Fehler\n",
+	"⚠️  Permission fix aborted: %v\n":                                                                      "⚠️  Korrektur der Berechtigungen abgebrochen: %v\n",
+	"\n💡 Run with --execute to delete %d items\n":                                                           "\n💡 Mit --execute ausführen, um %d Einträge zu löschen\n",
+	"\n💡 %d items to delete on ssh:// libraries, run the tool on that machine with --execute to delete them\n": "\n💡 %d Einträge in ssh://-Bibliotheken zu löschen, führen Sie das Tool mit --execute auf diesem Rechner aus\n",
+	"\n💡 Run with --execute to delete %d items and reclaim ~%s\n":                                              "\n💡 Mit --execute ausführen, um %d Einträge zu löschen und ~%s freizugeben\n",
+	"\n💡 Run with --execute to fix permissions of %d items\n":                                                  "\n💡 Mit --execute ausführen, um die Berechtigungen von %d Einträgen zu korrigieren\n",
+	"\nMoving misplaced files...\n":                                                                            "\nVerschiebe falsch abgelegte Dateien...\n",
+	"✓ Moved: %s → %s\n":                                                                                       "✓ Verschoben: %s → %s\n",
+	"\nMoved %d files, %d failures\n":                                                                          "\n%d Dateien verschoben, %d Fehler\n",
+	"⚠️  Move aborted: %v\n":                                                                                   "⚠️  Verschieben abgebrochen: %v\n",
+	"\n💡 Run with --execute to move %d misplaced files:\n":                                                     "\n💡 Mit --execute ausführen, um %d falsch abgelegte Dateien zu verschieben:\n",
+	"\nRenaming title folders...\n":                                                                            "\nBenenne Titelordner um...\n",
+	"✓ Renamed: %s → %s\n":                                                                                     "✓ Umbenannt: %s → %s\n",
+	"\nRenamed %d folders, %d failures\n":                                                                      "\n%d Ordner umbenannt, %d Fehler\n",
+	"⚠️  Rename aborted: %v\n":                                                                                 "⚠️  Umbenennen abgebrochen: %v\n",
+	"\n💡 Run with --execute to rename %d title folders:\n":                                                     "\n💡 Mit --execute ausführen, um %d Titelordner umzubenennen:\n",
+	"\n✓ Nothing to clean up\n":                                                                                "\n✓ Nichts aufzuräumen\n",
+	// Dashboard
+	"A run is in progress, this page refreshes by itself": "Ein Lauf ist im Gange, diese Seite aktualisiert sich selbst",
+	"Rescan":                     "Erneut scannen",
+	"No scan yet":                "Noch kein Scan",
+	"Last scan":                  "Letzter Scan",
+	"Delete the selected items?": "Ausgewählte Einträge löschen?",
+	"Nothing to clean up":        "Nichts aufzuräumen",
+	"Delete selected":            "Auswahl löschen",
+	"History":                    "Verlauf",
+	"Started":                    "Beginn",
+	"Run":                        "Lauf",
+	"Duration":                   "Dauer",
+	"Findings":                   "Funde",
+	"Deleted":                    "Gelöscht",
+	"Failures":                   "Fehler",
+	"Reclaimed":                  "Freigegeben",
+	"Exit code":                  "Exit-Code",
+	"Deletion":                   "Löschung",
+	"Scan":                       "Scan",
+
+	// Terminal UI
+	"↑↓ move, space select, enter open/preview, a all, x delete selected, q quit": "↑↓ bewegen, Leertaste auswählen, Enter öffnen/Vorschau, a alle, x Auswahl löschen, q beenden",
+	"(empty)":                             "(leer)",
+	"%d selected, ~%s to reclaim":         "%d ausgewählt, ~%s freizugeben",
+	"Delete %d selected items? (y/n)":     "%d ausgewählte Einträge löschen? (y/n)",
+	"Nothing selected, nothing deleted\n": "Nichts ausgewählt, nichts gelöscht\n",
+	// Errors and warnings printed on stderr
+	"undo takes a single run id\n":                                     "undo nimmt nur eine Lauf-ID\n",
+	"plan needs --out FILE\n":                                          "plan braucht --out DATEI\n",
+	"%s: a remote library %s\n":                                        "%s: eine entfernte Bibliothek %s\n",
+	"cannot be planned, checked or probed (no plan, check or --probe)": "kann weder geplant, geprüft noch untersucht werden (kein plan, check oder --probe)",
+	"can only be scanned and reported on (no --execute)":               "kann nur durchsucht werden (kein --execute)",
+	"only supports permanent deletions (no --fix, --fix-names, --fix-perms, --preserve-hardlinks, trash, quarantine or --backup-to)":              "unterstützt nur endgültiges Löschen (kein --fix, --fix-names, --fix-perms, --preserve-hardlinks, Papierkorb, Quarantäne oder --backup-to)",
+	"Unknown format %q (expected text, json, jsonl or ndjson)\n":                                                                                  "Unbekanntes Format %q (erwartet: text, json, jsonl oder ndjson)\n",
+	"Unknown --sort order %q (expected size or age)\n":                                                                                            "Unbekannte --sort-Reihenfolge %q (erwartet: size oder age)\n",
+	"Unknown --fail-on severity %q (expected warning or error)\n":                                                                                 "Unbekannter --fail-on-Schweregrad %q (erwartet: warning oder error)\n",
+	"--every must be at least 1m, got %s\n":                                                                                                       "--every muss mindestens 1m sein, erhalten: %s\n",
+	"--every and --schedule cannot be combined\n":                                                                                                 "--every und --schedule können nicht kombiniert werden\n",
+	"--every and --schedule cannot be combined with check, plan, apply, restore, undo or service\n":                                               "--every und --schedule können nicht mit check, plan, apply, restore, undo oder service kombiniert werden\n",
+	"serve cannot be combined with --execute, --fix, --fix-names, --fix-perms or --digest, deletions are approved in the dashboard\n":             "serve kann nicht mit --execute, --fix, --fix-names, --fix-perms oder --digest kombiniert werden, Löschungen werden im Dashboard freigegeben\n",
+	"serve --addr %s is not a loopback address: the dashboard has no login, reach it through a reverse proxy\n":                                   "serve --addr %s ist keine Loopback-Adresse: das Dashboard hat keine Anmeldung, erreichen Sie es über einen Reverse-Proxy\n",
+	"--diff cannot be combined with --execute, --digest, --interactive, serve, plan, apply, restore or undo\n":                                    "--diff kann nicht mit --execute, --digest, --interactive, serve, plan, apply, restore oder undo kombiniert werden\n",
+	"--interactive cannot be combined with --execute, --every, --schedule, --digest, --format, --fix, --fix-names, --fix-perms or a subcommand\n": "--interactive kann nicht mit --execute, --every, --schedule, --digest, --format, --fix, --fix-names, --fix-perms oder einem Unterbefehl kombiniert werden\n",
+	"--interactive needs a terminal\n":                                                                                                            "--interactive braucht ein Terminal\n",
+	"service install with --execute needs --yes (or --confirm-over 0), the scheduled runs cannot ask for confirmation\n":                          "service install mit --execute braucht --yes (oder --confirm-over 0), geplante Läufe können nicht um Bestätigung bitten\n",
+	"Failed to install the service: %v\n":                                                                                                         "Dienst konnte nicht installiert werden: %v\n",
+	"Failed to uninstall the service: %v\n":                                                                                                       "Dienst konnte nicht deinstalliert werden: %v\n",
+	"Unknown service command %q (expected install or uninstall)\n":                                                                                "Unbekannter Dienstbefehl %q (erwartet: install oder uninstall)\n",
+	"--summary only applies to --format text\n":                                                                                                   "--summary gilt nur für --format text\n",
+	"--quiet cannot be combined with --verbose or --summary\n":                                                                                    "--quiet kann nicht mit --verbose oder --summary kombiniert werden\n",
+	"--format ndjson cannot be combined with --sort, --digest, --diff or --output\n":                                                              "--format ndjson kann nicht mit --sort, --digest, --diff oder --output kombiniert werden\n",
+	"--output cannot be combined with --digest, --diff or --interactive\n":                                                                        "--output kann nicht mit --digest, --diff oder --interactive kombiniert werden\n",
+	"--trash cannot be combined with --delete-mode %s\n":                                                                                          "--trash kann nicht mit --delete-mode %s kombiniert werden\n",
+	"--quarantine cannot be combined with --trash or --delete-mode\n":                                                                             "--quarantine kann nicht mit --trash oder --delete-mode kombiniert werden\n",
+	"restore needs --quarantine DIR\n":                                                                                                            "restore braucht --quarantine VERZEICHNIS\n",
+	"undo needs --manifest FILE\n":                                                                                                                "undo braucht --manifest DATEI\n",
+	"--metrics needs --every or --schedule\n":                                                                                                     "--metrics braucht --every oder --schedule\n",
+	"--resume needs --checkpoint\n":                                                                                                               "--resume braucht --checkpoint\n",
+	"--digest cannot be combined with --execute\n":                                                                                                "--digest kann nicht mit --execute kombiniert werden\n",
+	"--digest-webhook needs --digest\n":                                                                                                           "--digest-webhook braucht --digest\n",
+	"--digest-webhook must be an http:// or https:// URL\n":                                                                                       "--digest-webhook muss eine http://- oder https://-URL sein\n",
+	"plan cannot be combined with --execute, use apply once the plan is reviewed\n":                                                               "plan kann nicht mit --execute kombiniert werden, verwenden Sie apply, sobald der Plan geprüft ist\n",
+	"--radarr-url needs --radarr-api-key or RADARR_API_KEY\n":                                                                                     "--radarr-url braucht --radarr-api-key oder RADARR_API_KEY\n",
+	"--sonarr-url needs --sonarr-api-key or SONARR_API_KEY\n":                                                                                     "--sonarr-url braucht --sonarr-api-key oder SONARR_API_KEY\n",
+	"--sonarr-url needs --structure tv\n":                                                                                                         "--sonarr-url braucht --structure tv\n",
+	"--plex-url needs --plex-token or PLEX_TOKEN\n":                                                                                               "--plex-url braucht --plex-token oder PLEX_TOKEN\n",
+	"--plex-scan needs --plex-url\n":                                                                                                              "--plex-scan braucht --plex-url\n",
+	"Cannot reach %s, nothing was scanned: %v\n":                                                                                                  "%s nicht erreichbar, nichts wurde durchsucht: %v\n",
+	"--max-iops must not be negative, got %d\n":                                                                                                   "--max-iops darf nicht negativ sein, erhalten: %d\n",
+	"--max-delete-count and --max-delete-percent must not be negative\n":                                                                          "--max-delete-count und --max-delete-percent dürfen nicht negativ sein\n",
+	"Plan %s not applied, nothing was deleted: %v\n":                                                                                              "Plan %s nicht angewendet, nichts wurde gelöscht: %v\n",
+	"Nothing was scanned: %v\n":                                                                                                                   "Nichts wurde durchsucht: %v\n",
+	"⚠️  Library lock not released, later runs may find the library locked: %v\n":                                                                 "⚠️  Bibliothekssperre nicht freigegeben, spätere Läufe könnten die Bibliothek gesperrt vorfinden: %v\n",
+	"Invalid permission policy: %v\n":                                                                                                             "Ungültige Berechtigungsrichtlinie: %v\n",
+	"Scan state not saved, the next scan reads every title folder: %v\n":                                                                          "Scanstatus nicht gespeichert, der nächste Scan liest jeden Titelordner: %v\n",
+	"Checkpoint not saved, an interrupted scan cannot be resumed: %v\n":                                                                           "Checkpoint nicht gespeichert, ein unterbrochener Scan kann nicht fortgesetzt werden: %v\n",
+	"Listing cache not saved, the next scan lists every folder: %v\n":                                                                             "Listen-Cache nicht gespeichert, der nächste Scan listet jeden Ordner: %v\n",
+	"Scan aborted (%v), digest not updated\n":                                                                                                     "Scan abgebrochen (%v), Zusammenfassung nicht aktualisiert\n",
+	"Scan aborted (%v), not compared and %s not updated\n":                                                                                        "Scan abgebrochen (%v), nicht verglichen und %s nicht aktualisiert\n",
+	"Failed to write report: %v\n":                                                                                                                "Bericht konnte nicht geschrieben werden: %v\n",
+	"Failed to write the plan: %v\n":                                                                                                              "Plan konnte nicht geschrieben werden: %v\n",
+
+	// Finding messages, see findingMessages, and the values they hold
+	"%s modified within the last %s, left for now": "%s in den letzten %s geändert, vorerst belassen",
+	"Orphaned folder":        "Verwaister Ordner",
+	"Orphaned file":          "Verwaiste Datei",
+	"Empty folder":           "Leerer Ordner",
+	"Same size (%s) as %s":   "Gleiche Größe (%s) wie %s",
+	"Identical to %s":        "Identisch mit %s",
+	"Missing NFO":            "NFO fehlt",
+	"Missing poster":         "Poster fehlt",
+	"Missing NFO and poster": "NFO und Poster fehlen",
+	"Title mismatch: folder says %q, %s says %q":         "Titel weicht ab: Ordner sagt %q, %s sagt %q",
+	"Year mismatch: folder says %s, %s says %s":          "Jahr weicht ab: Ordner sagt %s, %s sagt %s",
+	"owner %d, expected %s":                              "Besitzer %d, erwartet %s",
+	"group %d, expected %s":                              "Gruppe %d, erwartet %s",
+	"mode %04o, expected %04o":                           "Modus %04o, erwartet %04o",
+	"Name not portable (%s)":                             "Name nicht portabel (%s)",
+	"reserved characters %s":                             "reservierte Zeichen %s",
+	"control characters":                                 "Steuerzeichen",
+	"trailing space or dot":                              "Leerzeichen oder Punkt am Ende",
+	"reserved device name":                               "reservierter Gerätename",
+	"%d bytes long (limit %d)":                           "%d Bytes lang (Grenze %d)",
+	"Name differs only by case from %s":                  "Name unterscheidet sich nur in der Groß-/Kleinschreibung von %s",
+	"ffprobe cannot read it: %s":                         "ffprobe kann sie nicht lesen: %s",
+	"Zero or unknown duration":                           "Dauer null oder unbekannt",
+	"Holds %s":                                           "Enthält %s",
+	"Unexpected subdirectory in %s folder":               "Unerwarteter Unterordner im Ordner der Ebene %s",
+	"Multiple unrelated videos in %s folder (%s)":        "Mehrere unzusammenhängende Videos im Ordner der Ebene %s (%s)",
+	"Partial download":                                   "Unvollständiger Download",
+	"Partial download (%s)":                              "Unvollständiger Download (%s)",
+	"Only video is in an extras subfolder (%s)":          "Einziges Video liegt in einem Extras-Unterordner (%s)",
+	"Symlink target is missing":                          "Ziel des symbolischen Links fehlt",
+	"Symlink target %s is missing":                       "Ziel %s des symbolischen Links fehlt",
+	"Video file is empty":                                "Videodatei ist leer",
+	"Video file is only %s, below the minimum of %s":     "Videodatei hat nur %s, unter dem Minimum von %s",
+	"Video file at %s level (should be in %s folder)":    "Videodatei auf Ebene %s (gehört in einen Ordner der Ebene %s)",
+	"Metadata file at %s level (should be in %s folder)": "Metadatendatei auf Ebene %s (gehört in einen Ordner der Ebene %s)",
+	"Subtitles for missing videos (%s)":                  "Untertitel für fehlende Videos (%s)",
+	"library":                                            "Bibliothek",
+	"studio":                                             "Studio",
+	"title":                                              "Titel",
+	"show":                                               "Serie",
+	"season":                                             "Staffel",
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for Language
+// ============================================================================
+
+func TestLanguageFor(t *testing.T) {
+	tests := map[string]string{
+		"":            "en",
+		"C":           "en",
+		"fr":          "fr",
+		"fr_FR.UTF-8": "fr",
+		"de-DE":       "de",
+		"DE_at@euro":  "de",
+		"en_US":       "en",
+	}
+	for name, expected := range tests {
+		lang, err := LanguageFor(name)
+		if err != nil {
+			t.Errorf("LanguageFor(%q) returned error: %v", name, err)
+			continue
+		}
+		if lang.Code != expected {
+			t.Errorf("LanguageFor(%q): expected %s, got %s", name, expected, lang.Code)
+		}
+	}
+	if _, err := LanguageFor("xx"); err == nil {
+		t.Error("Expected error for unsupported language")
+	}
+}
+
+func TestLanguageFromEnv_Fallback(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "ja_JP.UTF-8")
+	if lang := languageFromEnv(); lang != English {
+		t.Errorf("Expected English for an unsupported locale, got %s", lang.Code)
+	}
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	if lang := languageFromEnv(); lang.Code != "de" {
+		t.Errorf("Expected LC_ALL to win, got %s", lang.Code)
+	}
+}
+
+func TestLanguages_SameMessages(t *testing.T) {
+	for key := range frenchMessages {
+		if _, ok := germanMessages[key]; !ok {
+			t.Errorf("Missing German translation for %q", key)
+		}
+	}
+	for key := range germanMessages {
+		if _, ok := frenchMessages[key]; !ok {
+			t.Errorf("Missing French translation for %q", key)
+		}
+	}
+	for key, translated := range frenchMessages {
+		if strings.Count(key, "%") != strings.Count(translated, "%") {
+			t.Errorf("Verb count differs between %q and %q", key, translated)
+		}
+	}
+}
+
+func TestPrintReport_Translated(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Gone"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/x.mkv", Message: "Video file at library level (should be in title folder)"})
+
+	var buf bytes.Buffer
+	reportWriter{lang: Languages["fr"]}.printReport(&buf, result)
+	output := buf.String()
+	if !strings.Contains(output, "Dossiers de métadonnées orphelins (aucune vidéo) (1) :") {
+		t.Errorf("Expected French section header, got %q", output)
+	}
+	if !strings.Contains(output, "Fichier vidéo au niveau bibliothèque (devrait être dans un dossier titre): /lib/x.mkv") {
+		t.Errorf("Expected the finding message translated, got %q", output)
+	}
+}
+
+func TestLanguage_Message(t *testing.T) {
+	de := Languages["de"]
+	tests := map[string]string{
+		"Missing NFO and poster":                                           "NFO und Poster fehlen",
+		"Unexpected subdirectory in season folder":                         "Unerwarteter Unterordner im Ordner der Ebene Staffel",
+		"Orphaned folder modified within the last 7d, left for now":        "Verwaister Ordner in den letzten 7d geändert, vorerst belassen",
+		`Title mismatch: folder says "Alien", movie.nfo says "Aliens"`:     `Titel weicht ab: Ordner sagt "Alien", movie.nfo sagt "Aliens"`,
+		"Name not portable (reserved characters :, trailing space or dot)": "Name nicht portabel (reservierte Zeichen :, Leerzeichen oder Punkt am Ende)",
+		"Something new": "Something new",
+	}
+	for msg, expected := range tests {
+		if got := de.Message(msg); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, msg, got)
+		}
+	}
+	if got := English.Message("Missing NFO"); got != "Missing NFO" {
+		t.Errorf("Expected English messages unchanged, got %q", got)
+	}
+	for _, format := range findingMessages {
+		if _, ok := frenchMessages[format]; !ok {
+			t.Errorf("Missing French translation for the finding message %q", format)
+		}
+	}
+}
+
+// TestLanguages_CoverCLIMessages checks that every message the CLI passes
+// to Language.Fprintf has a translation.
+func TestLanguages_CoverCLIMessages(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "Fprintf" {
+				return true
+			}
+			switch x := sel.X.(type) {
+			case *ast.Ident:
+				if x.Name != "lang" {
+					return true
+				}
+			case *ast.SelectorExpr:
+				if x.Sel.Name != "lang" {
+					return true
+				}
+			default:
+				return true
+			}
+			lit, ok := call.Args[1].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			format, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := frenchMessages[format]; !ok && strings.Trim(format, "%sdv:\n ") != "" {
+				t.Errorf("%s: missing translation for %q", fset.Position(lit.Pos()), format)
+			}
+			return true
+		})
+	}
+}
+
+func TestReportWriter_JSONNotTranslated(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/Empty"})
+
+	var en, de bytes.Buffer
+	if err := (reportWriter{format: "json", lang: English}).write(&en, result); err != nil {
+		t.Fatal(err)
+	}
+	if err := (reportWriter{format: "json", lang: Languages["de"]}).write(&de, result); err != nil {
+		t.Fatal(err)
+	}
+	if en.String() != de.String() {
+		t.Errorf("Expected identical JSON in every language, got %q and %q", en.String(), de.String())
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// errInterrupted is the cause of the run context once SIGINT or SIGTERM was
+// received.
+var errInterrupted = errors.New("interrupted")
+
+// interruptContext returns a context cancelled with errInterrupted on the
+// first SIGINT (Ctrl-C) or SIGTERM (docker stop, systemctl stop), after a
+// notice to w. The items in progress are finished and the run reports what
+// it got to; a second signal stops the process at once.
+func interruptContext(w io.Writer, lang *Language) context.Context {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	return cancelOnSignal(signals, func() { signal.Stop(signals) }, w, lang)
+}
+
+// cancelOnSignal returns a context cancelled with errInterrupted on the
+// first value of signals. stop is called first, to restore the default
+// handling of the signals.
+func cancelOnSignal(signals <-chan os.Signal, stop func(), w io.Writer, lang *Language) context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go func() {
+		<-signals
+		stop()
+		lang.Fprintf(w, "\n⏹️  Interrupted, finishing the items in progress (interrupt again to stop at once)\n")
+		cancel(errInterrupted)
+	}()
+	return ctx
+}
+
+// abortCause returns the reason ctx stopped a run that returned err: the
+// cause ctx was cancelled with, such as errInterrupted, rather than the bare
+// context.Canceled. Other errors are returned as they are.
+func abortCause(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return context.Cause(ctx)
+	}
+	return err
+}
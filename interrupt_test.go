@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Tests for interruption
+// ============================================================================
+
+func TestCancelOnSignal(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	stopped := false
+	var buf bytes.Buffer
+	ctx := cancelOnSignal(signals, func() { stopped = true }, &buf, nil)
+	if ctx.Err() != nil {
+		t.Fatalf("Expected the context live before a signal, got %v", ctx.Err())
+	}
+
+	signals <- os.Interrupt
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the context cancelled by the signal")
+	}
+	if !errors.Is(context.Cause(ctx), errInterrupted) {
+		t.Errorf("Expected cause %v, got %v", errInterrupted, context.Cause(ctx))
+	}
+	if !stopped {
+		t.Error("Expected the default signal handling restored")
+	}
+	if !strings.Contains(buf.String(), "Interrupted") {
+		t.Errorf("Expected a notice, got %q", buf.String())
+	}
+}
+
+func TestAbortCause(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	other := errors.New("disk full")
+	if got := abortCause(ctx, other); got != other {
+		t.Errorf("Expected %v, got %v", other, got)
+	}
+
+	cancel(errInterrupted)
+	if got := abortCause(ctx, context.Canceled); got != errInterrupted {
+		t.Errorf("Expected %v, got %v", errInterrupted, got)
+	}
+	if got := abortCause(ctx, other); got != other {
+		t.Errorf("Expected %v, got %v", other, got)
+	}
+
+	timeout, stop := context.WithTimeout(context.Background(), 0)
+	defer stop()
+	<-timeout.Done()
+	if got := abortCause(timeout, timeout.Err()); !errors.Is(got, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, got)
+	}
+}
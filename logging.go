@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp inserted in the name of rotated logs,
+// e.g. cleanup-20240102T030405.000.log.
+const backupTimeFormat = "20060102T150405.000"
+
+// RotatingFile is an append-only log file that is rotated once it would
+// exceed MaxSize bytes. Rotated files are kept next to it with a timestamp
+// in their name; those older than MaxAge, or beyond the newest MaxBackups,
+// are removed at each rotation. Zero values disable each limit.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	now  func() time.Time // for tests
+}
+
+// Write appends p to the log, rotating it first if needed. It is safe for
+// concurrent use.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the current log file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate moves the current log aside, starts a new one and prunes backups.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	if err := os.Rename(f.Path, f.backupName(f.clock().UTC())); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.prune()
+	return nil
+}
+
+func (f *RotatingFile) clock() time.Time {
+	if f.now != nil {
+		return f.now()
+	}
+	return time.Now()
+}
+
+// backupName returns the name the log is rotated to at t.
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.Path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(f.Path, ext), t.Format(backupTimeFormat), ext)
+}
+
+// prune removes the backups that are too old or too many. Failures are
+// ignored: a leftover backup must not stop logging.
+func (f *RotatingFile) prune() {
+	ext := filepath.Ext(f.Path)
+	prefix := strings.TrimSuffix(filepath.Base(f.Path), ext) + "-"
+	entries, err := os.ReadDir(filepath.Dir(f.Path))
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path string
+		time time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+		if err != nil {
+			continue // Not one of ours
+		}
+		backups = append(backups, backup{filepath.Join(filepath.Dir(f.Path), name), t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.After(backups[j].time) })
+
+	cutoff := f.clock().Add(-f.MaxAge)
+	for i, b := range backups {
+		if (f.MaxBackups > 0 && i >= f.MaxBackups) || (f.MaxAge > 0 && b.time.Before(cutoff)) {
+			os.Remove(b.path)
+		}
+	}
+}
+
+// newLogger returns a slog.Logger writing records at level or above to w in
+// format, "text" or "json". level is debug, info, warn or error, info if
+// empty.
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{}
+	if level != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", level)
+		}
+		opts.Level = l
+	}
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (expected text or json)", format)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Tests for RotatingFile
+// ============================================================================
+
+func logBackups(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != "cleanup.log" {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func TestRotatingFile_RotatesBySize(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	clock := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	f := &RotatingFile{Path: filepath.Join(tempDir, "logs", "cleanup.log"), MaxSize: 10, now: func() time.Time { return clock }}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		clock = clock.Add(time.Second)
+	}
+
+	current, err := os.ReadFile(f.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != "third\n" {
+		t.Errorf("Expected the current log to hold the last line, got %q", current)
+	}
+	backups := logBackups(t, filepath.Dir(f.Path))
+	expected := []string{"cleanup-20240101T030001.000.log", "cleanup-20240101T030002.000.log"}
+	if strings.Join(backups, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected backups %v, got %v", expected, backups)
+	}
+}
+
+func TestRotatingFile_PrunesBackups(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+
+	clock := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	f := &RotatingFile{Path: filepath.Join(tempDir, "cleanup.log"), MaxSize: 1, MaxBackups: 3, MaxAge: 36 * time.Hour,
+		now: func() time.Time { return clock }}
+	defer f.Close()
+	createFile(t, filepath.Join(tempDir, "cleanup-notes.log")) // not a backup, never removed
+
+	for day := 0; day < 6; day++ {
+		if _, err := f.Write([]byte("x")); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		clock = clock.Add(24 * time.Hour)
+	}
+
+	// Rotations on days 1 to 5; at the last one only backups under 36h old survive
+	backups := logBackups(t, tempDir)
+	expected := []string{"cleanup-20240105T030000.000.log", "cleanup-20240106T030000.000.log", "cleanup-notes.log"}
+	if strings.Join(backups, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, backups)
+	}
+}
+
+func TestNewLogger_Formats(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("deleted", "path", "/lib/S/A")
+	if !strings.Contains(buf.String(), `"msg":"deleted","path":"/lib/S/A"`) {
+		t.Errorf("Expected a JSON log line, got %q", buf.String())
+	}
+
+	if _, err := newLogger(&buf, "xml", ""); err == nil {
+		t.Error("Expected error for unknown log format")
+	}
+}
+
+func TestNewLogger_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "text", "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("finding", "path", "/lib/S/A")
+	logger.Warn("scan error", "path", "/lib/S/B")
+	if strings.Contains(buf.String(), "finding") || !strings.Contains(buf.String(), "level=WARN") {
+		t.Errorf("Expected only the warning, got %q", buf.String())
+	}
+
+	if _, err := newLogger(&buf, "text", "verbose"); err == nil {
+		t.Error("Expected error for unknown log level")
+	}
+}
@@ -1,367 +1,1104 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
-	"sync"
-)
-
-var videoExtensions = map[string]bool{
-	".mkv": true,
-	".mp4": true,
-	".avi": true,
-	".m4v": true,
-}
+	"time"
 
-// Known metadata subdirectory suffixes that are expected in title folders
-var metadataSubdirSuffixes = []string{
-	".trickplay",
-}
+	"video-folder-cleanup/cleanup"
+)
 
-type CleanupResult struct {
-	OrphanedFolders  []string // Folders with metadata but no video
-	OrphanedFiles    []string // Metadata files at wrong level with no video
-	EmptyFolders     []string // Completely empty folders
-	StructureWarnings []string // Files/folders not matching expected structure
-}
+// Exit codes, so that scripts can tell what a run found without parsing its
+// output. When several apply, the highest wins.
+const (
+	exitClean          = 0 // nothing found, or everything found was dealt with
+	exitFindings       = 1 // findings were left in place, e.g. in a dry run
+	exitActionFailures = 2 // items could not be deleted, fixed or restored
+	exitScanErrors     = 3 // folders could not be scanned, or the scan was aborted
+	exitFailure        = 4 // the run could not be carried out at all: invalid flags, unreachable services, ...
+)
 
 func main() {
 	execute := flag.Bool("execute", false, "Actually delete folders (default is dry-run)")
 	workers := flag.Int("workers", 10, "Number of concurrent workers")
-	flag.Parse()
+	s3Endpoint := flag.String("s3-endpoint", "", "S3 API endpoint for s3:// libraries, e.g. http://minio:9000 (default $AWS_ENDPOINT_URL, else AWS in --s3-region)")
+	s3Region := flag.String("s3-region", "", "Region of s3:// libraries (default $AWS_REGION, else us-east-1)")
+	smb := flag.Bool("smb", false, "Tune the scan for libraries on a mounted SMB/CIFS share: list each folder once, fetch attributes only when needed, retry dropped connections")
+	maxIOPS := flag.Int("max-iops", 0, "Limit directory reads and deletions to this many per second, to spare a NAS serving playback (0 = no limit)")
+	timeout := flag.Duration("timeout", 0, "Abort scanning and deletion after this duration (e.g. 30m, 0 = no limit)")
+	format := flag.String("format", "text", "Report format: text, json, jsonl, or ndjson to stream each finding as soon as it is found")
+	sortBy := flag.String("sort", "", "Order of the findings in each section of the report: size (biggest first) or age (oldest first); default the order they were found in")
+	auditPerms := flag.Bool("audit-perms", false, "Report files and folders whose owner, group or mode differ from --owner, --dir-mode and --file-mode")
+	structure := flag.String("structure", "movies", "Library layout: movies (library/studio/title/video), tv (library/show/season/episode) or flat (library/title/video)")
+	layoutTemplate := flag.String("layout", "", "Library layout as a template such as \"{library}/{studio}/{collection}/{title}\", replacing --structure; the last level holds the videos")
+	videoExt := flag.String("video-ext", "", "Comma-separated video extensions to recognize on top of mkv, mp4, avi, m4v and iso (e.g. ts,webm,wmv,mpg)")
+	onlyVideoExt := flag.Bool("only-video-ext", false, "Recognize only the --video-ext extensions, not the built-in ones")
+	stubExt := flag.String("stub-ext", "strm", "Comma-separated extensions of stub files linking to a stream, counted as videos; empty for none")
+	metadataDirs := flag.String("metadata-dirs", "", "Comma-separated suffixes of the subfolders that belong to a video, replacing the default .trickplay (e.g. .trickplay,-extrafanart)")
+	metadataDirNames := flag.String("metadata-dir-names", "", "Comma-separated names of the subfolders expected in title folders, replacing the defaults extrafanart, extrathumbs, backdrops and .actors")
+	configPath := flag.String("config", "", "JSON file listing libraries, each with its own structure, video extensions, metadata folders and include/exclude patterns")
+	var includes, excludes, protects, outputs stringList
+	flag.Var(&includes, "include", "Only scan studio and title folders matching this glob, relative to the library (e.g. \"Studio A\"; repeatable)")
+	flag.Var(&excludes, "exclude", "Skip studio and title folders matching this glob, relative to the library (e.g. \"**/Staging/**\"; repeatable)")
+	flag.Var(&protects, "protect", "Never delete, move, rename or fix this path or glob, nor anything below it; --execute fails if an action would touch it (e.g. \"/mnt/media/Movies/Criterion/**\"; repeatable)")
+	flag.Var(&outputs, "output", "Write the full report to this file, in the format its extension picks (.json, .jsonl, .ndjson, or --format for any other), and print only the counts; repeatable")
+	minAge := flag.String("min-age", "", "Leave orphans and empty folders with anything modified more recently than this, e.g. still being imported (e.g. 7d, 36h)")
+	statePath := flag.String("state", "", "Remember the findings of each title folder in this file, and skip the title folders that have not changed since on later scans")
+	fullScan := flag.Bool("full-scan", false, "With --state, scan every title folder again and refresh the state")
+	checkpointPath := flag.String("checkpoint", "", "Record which top-level folders library scans have finished in this file, every minute at most, so that --resume can skip them")
+	resume := flag.Bool("resume", false, "Resume an interrupted scan: skip the top-level folders it finished, as recorded in --checkpoint")
+	cachePath := flag.String("cache", "", "Keep the listings of local folders in this file, and reuse those of the folders that have not changed since on later scans")
+	minVideoSize := flag.String("min-video-size", "1", "Report videos smaller than this as empty or truncated, e.g. 100M (default 1 = empty files only, 0 = off)")
+	probe := flag.Bool("probe", false, "Run ffprobe on every video and report those it cannot read or that have no duration (slow)")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Count symlinked videos with a valid target as video files")
+	duplicates := flag.Bool("duplicates", false, "Report videos of identical size (hard links excluded) as possible duplicates")
+	dedupe := flag.Bool("dedupe", false, "Hash same-size videos and report only byte-identical copies, with the space they waste")
+	checkYears := flag.Bool("check-years", false, "Report titles whose NFO year differs from the one in their \"Title (Year)\" folder name")
+	missingMetadata := flag.Bool("missing-metadata", false, "Report titles that have a video but no NFO or poster, for a metadata refresh")
+	onlyCategories := flag.String("only", "", "Comma-separated categories to check, all others skipped: empty, orphans, warnings, junk, duplicates or report categories such as orphaned-folders")
+	skipCategories := flag.String("skip", "", "Comma-separated categories not to check, in the names of --only")
+	junk := flag.Bool("junk", false, "Report OS junk files (.DS_Store, Thumbs.db, ._*) anywhere in the library; --execute deletes them")
+	recycleUsage := flag.Bool("recycle-usage", false, "Report the space held by the NAS recycle bins the scan skips (#recycle, @Recycle, .Trash-*)")
+	checkNFO := flag.Bool("check-nfo", false, "Report titles whose NFO title or year differs from their \"Title (Year)\" folder name")
+	auditNames := flag.Bool("audit-names", false, "Report names that break on Windows, exFAT or SMB targets (reserved characters, trailing spaces, long names, case collisions)")
+	fixNames := flag.Bool("fix-names", false, "Like --audit-names, and with --execute rename title folders to a portable name (reserved characters replaced, trailing dots and spaces trimmed)")
+	preserveHardlinks := flag.Bool("preserve-hardlinks", false, "With --execute, leave files that have other hard links (e.g. seeding torrents) in place")
+	fixPerms := flag.Bool("fix-perms", false, "Audit permissions and, with --execute, chown/chmod mismatches to the expected values")
+	fixStructure := flag.Bool("fix", false, "Move videos and their metadata found at studio or show level into the title or season folder named after them (with --execute)")
+	owner := flag.String("owner", "", "Expected owner as user[:group], names or ids (default not checked)")
+	dirMode := flag.String("dir-mode", "0775", "Expected octal mode of folders for --audit-perms (empty = not checked)")
+	fileMode := flag.String("file-mode", "0664", "Expected octal mode of files for --audit-perms (empty = not checked)")
+	deleteMode := flag.String("delete-mode", "permanent", "How --execute disposes of items: permanent, trash (see --trash), system-trash or rename")
+	trashDir := flag.String("trash", "", "With --execute, move items into this directory, keeping their path below the library, instead of deleting them")
+	quarantineDir := flag.String("quarantine", "", "With --execute, move items into this directory and list them in its manifest, so \"restore\" can put them back")
+	manifestPath := flag.String("manifest", "", "With --execute, append everything deleted or moved, with sizes and checksums, to this file, so \"undo\" can move back what a run moved")
+	backupTo := flag.String("backup-to", "", "With --execute, first write everything about to be deleted to this new archive (.tar.gz, .tar.zst with zstd, or .tar); {run} is replaced by the run id")
+	quarantineRetention := flag.Duration("quarantine-retention", 30*24*time.Hour, "Purge items quarantined longer ago than this after each --quarantine run (0 = keep forever)")
+	digestPath := flag.String("digest", "", "Accumulate findings in this file and only report them once per --digest-every (for cron)")
+	digestEvery := flag.Duration("digest-every", 7*24*time.Hour, "How often --digest reports the accumulated findings")
+	digestWebhook := flag.String("digest-webhook", "", "Also POST each digest to this URL, e.g. an ntfy topic or a chat webhook relay")
+	diffPath := flag.String("diff", "", "Report only the findings that are new or resolved since the JSON report in this file, then replace it with the current findings")
+	langName := flag.String("lang", "", "Language of the text report and messages: en, fr or de (default from LC_ALL, LC_MESSAGES or LANG)")
+	reportStyle := flag.String("report-style", "", "JSON file overriding the symbols, labels and item prefix of the text report")
+	noColor := flag.Bool("no-color", false, "Never color the headings of the text report; by default they are colored on a terminal unless NO_COLOR is set")
+	noEmoji := flag.Bool("no-emoji", false, "Spell the symbols of all output out in ASCII, for logs that mangle emoji (systemd journal, Windows Task Scheduler)")
+	showProgress := flag.Bool("progress", true, "Redraw a status line with the studios, titles and findings so far on stderr while scanning, when it is a terminal")
+	summary := flag.Bool("summary", false, "Print only the finding counts, reclaimable space and scan duration, on one line")
+	quiet := flag.Bool("quiet", false, "Print the finding counts instead of every item, drop the progress chatter, and print nothing at all when there is nothing to report")
+	verbose := flag.Bool("verbose", false, "Trace on stderr what the scan decided about every folder, instead of the status line")
+	printSchema := flag.Bool("schema", false, "Print the JSON Schema of the json and jsonl report formats and of plan files, and exit")
+	maxDeleteCount := flag.Int("max-delete-count", 0, "With --execute, delete nothing if more than N items would be deleted (0 = no limit)")
+	maxDeletePercent := flag.Float64("max-delete-percent", 0, "With --execute, delete nothing if the items to delete are more than this percentage of the title folders scanned, e.g. 10 (0 = no limit)")
+	confirmOver := flag.Int("confirm-over", 100, "With --execute, ask to type the library name before deleting more than N items (0 = never ask)")
+	yes := flag.Bool("yes", false, "With --execute, delete without asking for confirmation, for scripts and services")
+	failOn := flag.String("fail-on", "", "Exit with code 1 only for findings of this severity or above, warning or error, whatever is deletable; with --diff, only for new ones")
+	maxFindings := flag.Int("max-findings", 0, "Stop scanning after N findings and exit with code 1 (0 = no limit)")
+	logFile := flag.String("log-file", "", "Write a structured operational log to this file, separate from the report")
+	logFormat := flag.String("log-format", "text", "Format of --log-file: text or json")
+	logLevel := flag.String("log-level", "info", "Lowest level written to --log-file: debug, info, warn or error")
+	logMaxSize := flag.Int("log-max-size", 10, "Rotate --log-file once it reaches this many MiB (0 = never)")
+	logMaxAge := flag.Duration("log-max-age", 0, "Remove rotated logs older than this (e.g. 720h, 0 = keep)")
+	logMaxBackups := flag.Int("log-max-backups", 5, "Number of rotated logs to keep (0 = all)")
+	radarrURL := flag.String("radarr-url", "", "Radarr URL (e.g. http://localhost:7878): keep what monitored movies still need and ask Radarr to rescan after deletions")
+	radarrAPIKey := flag.String("radarr-api-key", "", "Radarr API key (default $RADARR_API_KEY)")
+	sonarrURL := flag.String("sonarr-url", "", "Sonarr URL (e.g. http://localhost:8989), with --structure tv: keep what monitored series still missing episodes need and ask Sonarr to rescan after deletions")
+	sonarrAPIKey := flag.String("sonarr-api-key", "", "Sonarr API key (default $SONARR_API_KEY)")
+	plexURL := flag.String("plex-url", "", "Plex Media Server URL (e.g. http://localhost:32400): keep what videos Plex still lists as available need")
+	plexToken := flag.String("plex-token", "", "Plex token (default $PLEX_TOKEN)")
+	plexScan := flag.Bool("plex-scan", false, "With --plex-url, ask Plex to scan the folders deletions happened in")
+	pathMapping := flag.String("path-map", "", "Comma-separated remote=local prefixes translating the paths Radarr, Sonarr and Plex report (e.g. /movies=/mnt/media/Movies)")
+	lockDir := flag.String("lock-dir", cleanup.DefaultLockDir(), "Directory of the lock files that keep two runs off the same library; share it between hosts to lock across them")
+	planKey := flag.String("plan-key", "", "Key signing \"plan\" files and checked by \"apply\" (default plan.key in the user config directory)")
+	serviceEvery := flag.Duration("service-every", 24*time.Hour, "How often the run registered by \"service install\" repeats")
+	every := flag.Duration("every", 0, "Keep running and repeat the run this often, counting from the start of each run (daemon mode, e.g. 6h)")
+	scheduleExpr := flag.String("schedule", "", "Keep running and repeat the run on this cron schedule, in local time (daemon mode, e.g. \"0 3 * * *\")")
+	metricsAddr := flag.String("metrics", "", "With --every or --schedule, serve Prometheus metrics at http://ADDR/metrics (e.g. :9090)")
+	interactive := flag.Bool("interactive", false, "After the scan, pick the items to delete in a terminal UI")
+	// Bad flags exit with exitFailure rather than the flag package's 2
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(exitFailure)
+	}
+
+	if *printSchema {
+		os.Stdout.Write(reportSchema)
+		return
+	}
+	// Messages are translated from here on
+	lang := languageFromEnv()
+	if *langName != "" {
+		var err error
+		if lang, err = LanguageFor(*langName); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitFailure)
+		}
+	}
 
+	// "check <title-path>..." scans single title folders instead of libraries
 	libraryPaths := flag.Args()
-	if len(libraryPaths) == 0 {
-		fmt.Println("Usage: video-folder-cleanup [--execute] [--workers N] <library-path> [library-path...]")
+	checkMode := len(libraryPaths) > 0 && libraryPaths[0] == "check"
+	if checkMode {
+		libraryPaths = libraryPaths[1:]
+	}
+	// "service install <library-path>..." schedules this command line,
+	// "service uninstall" removes it again
+	var serviceCommand string
+	if len(libraryPaths) > 1 && libraryPaths[0] == "service" {
+		serviceCommand, libraryPaths = libraryPaths[1], libraryPaths[2:]
+	}
+	// "plan --out FILE <library-path>..." writes the deletions to a signed
+	// plan, "apply <plan-file>" carries out exactly that plan later
+	var planOut, applyPath string
+	planMode := len(libraryPaths) > 0 && libraryPaths[0] == "plan"
+	if planMode {
+		planFlags := flag.NewFlagSet("plan", flag.ContinueOnError)
+		planFlags.StringVar(&planOut, "out", "", "Write the plan to this file")
+		if err := planFlags.Parse(libraryPaths[1:]); err != nil {
+			os.Exit(exitFailure)
+		}
+		libraryPaths = planFlags.Args()
+		// A plan may be applied from another directory
+		for i, path := range libraryPaths {
+			if abs, err := filepath.Abs(path); err == nil {
+				libraryPaths[i] = abs
+			}
+		}
+	}
+	// "serve [--addr ADDR] <library-path>..." scans in the background and
+	// deletes what is approved in a web dashboard
+	var serveAddr string
+	serveMode := len(libraryPaths) > 0 && libraryPaths[0] == "serve"
+	if serveMode {
+		serveFlags := flag.NewFlagSet("serve", flag.ContinueOnError)
+		serveFlags.StringVar(&serveAddr, "addr", "127.0.0.1:8080", "Serve the dashboard on this address")
+		if err := serveFlags.Parse(libraryPaths[1:]); err != nil {
+			os.Exit(exitFailure)
+		}
+		libraryPaths = serveFlags.Args()
+	}
+	if len(libraryPaths) == 2 && libraryPaths[0] == "apply" {
+		applyPath, libraryPaths = libraryPaths[1], nil
+	}
+	// "restore [path...]" puts quarantined items back, all of them or those
+	// at or below the given paths
+	var restorePaths []string
+	restoreMode := len(libraryPaths) > 0 && libraryPaths[0] == "restore"
+	if restoreMode {
+		restorePaths, libraryPaths = libraryPaths[1:], nil
+	}
+	// "undo [run-id]" moves back what a run recorded in --manifest moved,
+	// the last run by default
+	var undoRun string
+	undoMode := len(libraryPaths) > 0 && libraryPaths[0] == "undo"
+	if undoMode {
+		if len(libraryPaths) > 2 {
+			lang.Fprintf(os.Stderr, "undo takes a single run id\n")
+			os.Exit(exitFailure)
+		}
+		if len(libraryPaths) == 2 {
+			undoRun = libraryPaths[1]
+		}
+		libraryPaths = nil
+	}
+	// Libraries of a --config file are scanned along with those given
+	var config *configFile
+	if *configPath != "" {
+		var err error
+		if config, err = loadConfig(*configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitFailure)
+		}
+		if !checkMode && applyPath == "" && !restoreMode && !undoMode && serviceCommand != "uninstall" {
+			listed := map[string]bool{}
+			for _, path := range libraryPaths {
+				if key, err := libraryKey(path); err == nil {
+					listed[key] = true
+				}
+			}
+			for _, library := range config.Libraries {
+				if !listed[library.Path] {
+					libraryPaths = append(libraryPaths, library.Path)
+				}
+			}
+		}
+	}
+	protected, err := protectedPaths(protects, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitFailure)
+	}
+	if len(libraryPaths) == 0 && serviceCommand != "uninstall" && applyPath == "" && !restoreMode && !undoMode {
+		fmt.Println("Usage: video-folder-cleanup [--execute] [--config FILE] [--workers N] [--max-iops N] [--smb] [--s3-endpoint URL] [--timeout D] [--state FILE [--full-scan]] [--checkpoint FILE] [--resume] [--cache FILE] [--format F] [--output FILE]... [--sort ORDER] [--structure S | --layout TEMPLATE] [--video-ext LIST [--only-video-ext]] [--stub-ext LIST] [--metadata-dirs LIST] [--metadata-dir-names LIST] [--include GLOB]... [--exclude GLOB]... [--protect GLOB]... [--delete-mode M | --trash DIR | --quarantine DIR] [--manifest FILE] [--backup-to FILE] [--follow-symlinks] [--duplicates | --dedupe] [--check-years | --check-nfo] [--missing-metadata] [--recycle-usage] [--junk] [--only LIST] [--skip LIST] [--audit-names | --fix-names] [--preserve-hardlinks] [--audit-perms | --fix-perms] [--fix] [--radarr-url URL | --sonarr-url URL] [--plex-url URL [--plex-scan]] [--path-map LIST] [--confirm-over N | --yes] [--max-delete-count N] [--max-delete-percent P] [--max-findings N] [--fail-on S] [--digest FILE [--digest-every D] [--digest-webhook URL]] [--diff FILE] [--lang L] [--report-style FILE] [--no-color] [--no-emoji] [--summary | --quiet | --verbose] [--log-file FILE] [--lock-dir DIR] [--every D | --schedule CRON] [--metrics ADDR] [--interactive] <library-path> [library-path...]\n       video-folder-cleanup [options] check <title-path> [title-path...]\n       video-folder-cleanup [options] [--service-every D] service install <library-path> [library-path...]\n       video-folder-cleanup service uninstall\n       video-folder-cleanup [options] serve [--addr ADDR] <library-path> [library-path...]\n       video-folder-cleanup [options] plan --out FILE <library-path> [library-path...]\n       video-folder-cleanup [--delete-mode M | --trash DIR | --quarantine DIR] [--plan-key FILE] apply <plan-file>\n       video-folder-cleanup --quarantine DIR restore [path...]\n       video-folder-cleanup --manifest FILE undo [run-id]\n       video-folder-cleanup --schema")
 		fmt.Println("\nOptions:")
-		fmt.Println("  --execute    Actually delete folders (default is dry-run mode)")
-		fmt.Println("  --workers N  Number of concurrent workers (default 10)")
-		fmt.Println("\nExpected structure: library/studio/title/video.mkv")
-		os.Exit(1)
+		fmt.Println("  --execute                 Actually delete folders (default is dry-run mode)")
+		fmt.Println("  --workers N               Number of concurrent workers (default 10)")
+		fmt.Println("  --max-iops N              Limit directory reads and deletions per second (default no limit)")
+		fmt.Println("  --s3-endpoint URL         S3 API endpoint of s3:// libraries, e.g. http://minio:9000 (default AWS)")
+		fmt.Println("  --s3-region R             Region of s3:// libraries (default $AWS_REGION, else us-east-1)")
+		fmt.Println("  --smb                     Tune the scan for libraries on a mounted SMB/CIFS share (fewer round trips, retries)")
+		fmt.Println("  --timeout D               Abort after duration D, e.g. 30m or 2h (default no limit)")
+		fmt.Println("  --format F                Report format: text, json, jsonl or ndjson (streamed as found; default text)")
+		fmt.Println("  --output FILE             Write the full report to FILE (format from .json/.jsonl/.ndjson, else --format) and print only the counts; repeatable")
+		fmt.Println("  --sort ORDER              List the findings of each section by size, biggest first, or age, oldest first (default scan order)")
+		fmt.Println("  --structure S             Library layout: movies, tv (show/season/episode) or flat (title/video) (default movies)")
+		fmt.Println("  --layout TEMPLATE         Library layout of any depth, e.g. \"{library}/{studio}/{collection}/{title}\"")
+		fmt.Println("  --video-ext LIST          Also count these extensions as videos, e.g. ts,webm,wmv,mpg")
+		fmt.Println("  --only-video-ext          Replace the built-in extensions (mkv, mp4, avi, m4v, iso) with --video-ext")
+		fmt.Println("  --stub-ext LIST           Extensions of stub files counted as videos (default strm, empty for none)")
+		fmt.Println("  --metadata-dirs LIST      Suffixes of subfolders that belong to a video (default .trickplay)")
+		fmt.Println("  --metadata-dir-names LIST Names of subfolders expected in title folders (default extrafanart,extrathumbs,backdrops,.actors)")
+		fmt.Println("  --config FILE             Scan the libraries listed in FILE, each with its own structure, extensions and patterns")
+		fmt.Println("  --include GLOB            Only scan studio/title folders matching GLOB, e.g. \"Studio A\" (repeatable)")
+		fmt.Println("  --exclude GLOB            Skip studio/title folders matching GLOB, e.g. \"**/Staging/**\" (repeatable)")
+		fmt.Println("  --protect GLOB            Never touch this path or glob and what is below it; --execute fails if it would (repeatable)")
+		fmt.Println("  --min-video-size SIZE     Report smaller videos as truncated, e.g. 100M (default 1 = empty only, 0 = off)")
+		fmt.Println("  --probe                   Report videos ffprobe cannot read or with no duration (slow, needs ffprobe)")
+		fmt.Println("  --min-age AGE             Leave orphans/empty folders modified within AGE, e.g. 7d (mid-import)")
+		fmt.Println("  --state FILE              Remember findings in FILE and skip unchanged title folders on later scans")
+		fmt.Println("  --full-scan               With --state, scan every title folder again and refresh FILE")
+		fmt.Println("  --checkpoint FILE         Record which top-level folders scans have finished in FILE, for --resume")
+		fmt.Println("  --resume                  Resume an interrupted scan, skipping the top-level folders it finished")
+		fmt.Println("  --cache FILE              Keep folder listings in FILE and reuse those of unchanged folders on later scans")
+		fmt.Println("  --delete-mode M           With --execute: permanent, system-trash or rename (default permanent)")
+		fmt.Println("  --trash DIR               With --execute, move items into DIR (recoverable) instead of deleting them")
+		fmt.Println("  --quarantine DIR          Like --trash with a manifest: \"restore\" puts items back, old ones are purged")
+		fmt.Println("  --manifest FILE           With --execute, record what is deleted or moved in FILE; \"undo\" moves a run back")
+		fmt.Println("  --backup-to FILE          With --execute, first archive what is deleted into FILE, e.g. backup-{run}.tar.gz")
+		fmt.Println("  --quarantine-retention D  Purge quarantined items older than D (default 720h, i.e. 30 days)")
+		fmt.Println("  --follow-symlinks         Count symlinked videos with a valid target as present")
+		fmt.Println("  --duplicates              Report same-size videos (hard links excluded) as possible duplicates")
+		fmt.Println("  --dedupe                  Hash same-size videos and report byte-identical copies and the space they waste")
+		fmt.Println("  --check-years             Report titles whose NFO year/premiered differs from the folder's (Year)")
+		fmt.Println("  --check-nfo               Report titles whose NFO title or year differs from the folder name")
+		fmt.Println("  --missing-metadata        Report titles with a video but no NFO or poster (episodes without NFO)")
+		fmt.Println("  --recycle-usage           Report the space held by NAS recycle bins, which are never scanned")
+		fmt.Println("  --junk                    Report OS junk files (.DS_Store, Thumbs.db, ._*) anywhere; --execute deletes them")
+		fmt.Println("  --only LIST               Check only these categories, e.g. empty,orphaned-folders; nothing else is computed or deleted")
+		fmt.Println("  --skip LIST               Leave these categories out of the scan, e.g. warnings")
+		fmt.Println("  --audit-names             Report names that would break when copied to Windows, exFAT or SMB")
+		fmt.Println("  --fix-names               Like --audit-names, and with --execute rename title folders to a portable name")
+		fmt.Println("  --preserve-hardlinks      With --execute, keep files still hardlinked elsewhere (seedbox-safe)")
+		fmt.Println("  --audit-perms             Report items not matching --owner user[:group], --dir-mode (0775) and --file-mode (0664)")
+		fmt.Println("  --fix-perms               Like --audit-perms, and with --execute fix the mismatches")
+		fmt.Println("  --fix                     With --execute, move misplaced videos and their metadata into a title folder named after them")
+		fmt.Println("  --confirm-over N          With --execute, type the library name before deleting more than N items (default 100)")
+		fmt.Println("  --yes                     Delete without asking for confirmation, for scripts and services")
+		fmt.Println("  --max-delete-count N      With --execute, delete nothing if more than N items would be deleted")
+		fmt.Println("  --max-delete-percent P    With --execute, delete nothing if more than P% of the title folders would go")
+		fmt.Println("  --max-findings N          Stop at the Nth finding and exit with code 1 (quick health check)")
+		fmt.Println("  --fail-on S               Exit with code 1 only for findings of severity S (warning or error) or above, new ones with --diff")
+		fmt.Println("  --digest FILE             Collect findings in FILE and report them only once per --digest-every")
+		fmt.Println("  --digest-every D          Digest period for --digest (default 168h, i.e. weekly)")
+		fmt.Println("  --digest-webhook URL      Also POST each digest to this URL")
+		fmt.Println("  --diff FILE               Only new and resolved findings since the report in FILE, then save this one there")
+		fmt.Println("  --lang L                  Report language: en, fr or de (default from LANG)")
+		fmt.Println("  --report-style FILE       Override text report symbols, labels and item prefix (JSON)")
+		fmt.Println("  --no-color                Do not color the report headings (also with NO_COLOR set or off a terminal)")
+		fmt.Println("  --no-emoji                Spell symbols out in ASCII, e.g. [!] and [ok], for logs that mangle emoji")
+		fmt.Println("  --progress=false          Do not redraw the scan status line on stderr (shown on terminals only)")
+		fmt.Println("  --summary                 One-line summary: counts per category, reclaimable space, duration")
+		fmt.Println("  --quiet                   Counts instead of items, no chatter, no output at all when there is nothing to report")
+		fmt.Println("  --verbose                 Trace on stderr what the scan decided about every folder, and why")
+		fmt.Println("  --log-file FILE           Structured operational log, rotated by size (see -help for --log-*)")
+		fmt.Println("  --lock-dir DIR            Where the per-library lock files go (default video-folder-cleanup in the temp dir)")
+		fmt.Println("  --radarr-url URL          Keep folders of movies Radarr monitors, ask it to rescan after deletions")
+		fmt.Println("  --radarr-api-key KEY      Radarr API key (default $RADARR_API_KEY)")
+		fmt.Println("  --sonarr-url URL          With --structure tv, keep folders of monitored series still missing episodes")
+		fmt.Println("  --sonarr-api-key KEY      Sonarr API key (default $SONARR_API_KEY)")
+		fmt.Println("  --plex-url URL            Keep items whose video Plex still lists as available")
+		fmt.Println("  --plex-token TOKEN        Plex token (default $PLEX_TOKEN)")
+		fmt.Println("  --plex-scan               With --plex-url, have Plex scan the folders of deleted items")
+		fmt.Println("  --path-map LIST           Translate Radarr/Sonarr/Plex paths to local ones, e.g. /movies=/data/Movies")
+		fmt.Println("  --plan-key FILE           Key signing plans, checked by apply (default in the user config directory)")
+		fmt.Println("  --service-every D         How often \"service install\" runs the given command line, from a systemd timer or a Windows scheduled task (default 24h)")
+		fmt.Println("  --interactive             After the scan, pick the items to delete in a terminal UI")
+		fmt.Println("  --every D                 Keep running and repeat the run every D, e.g. 6h (daemon mode)")
+		fmt.Println("  --schedule CRON           Keep running and repeat the run on a cron schedule, e.g. \"0 3 * * *\" (daemon mode)")
+		fmt.Println("  --metrics ADDR            With --every or --schedule, serve Prometheus metrics at http://ADDR/metrics, e.g. :9090")
+		fmt.Println("  --schema                  Print the JSON Schema of the json/jsonl formats and plans, exit")
+		fmt.Println("\nExpected structure: library/studio/title/video.mkv, library/show/season/episode.mkv with --structure tv, or library/title/video.mkv with --structure flat")
+		fmt.Println("A library on another machine is given as ssh://[user@]host[:port]/path (scan and report only, needs ssh keys, a shell and GNU find there)")
+		fmt.Println("or s3://bucket/prefix (credentials from $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY)")
+		fmt.Println("or rclone:remote:path for any remote configured in rclone (Google Drive, Dropbox, WebDAV, ...)")
+		fmt.Println("or webdav[s]://[user@]host[:port]/path, e.g. on Nextcloud (password from $WEBDAV_PASSWORD)")
+		os.Exit(exitFailure)
 	}
-
-	if !*execute {
-		fmt.Println("=== DRY RUN MODE (use --execute to actually delete) ===")
-		fmt.Println()
+	if planMode && planOut == "" {
+		lang.Fprintf(os.Stderr, "plan needs --out FILE\n")
+		os.Exit(exitFailure)
+	}
+	// Remote libraries are not on the local disk: plans, checks and ffprobe
+	// need local files, ssh:// libraries are read-only and the others only
+	// have their items deleted
+	remotes, err := remoteLibraries(libraryPaths, s3Client(*s3Endpoint, *s3Region))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitFailure)
+	}
+	readOnlyRemote := false
+	for url, remote := range remotes {
+		readOnlyRemote = readOnlyRemote || remote.strategy == nil
+		var problem string
+		switch {
+		case planMode || checkMode || *probe:
+			problem = "cannot be planned, checked or probed (no plan, check or --probe)"
+		case (*execute || *interactive) && remote.strategy == nil:
+			problem = "can only be scanned and reported on (no --execute)"
+		case (*execute || *interactive) && (*fixStructure || *fixNames || *fixPerms || *preserveHardlinks || *deleteMode != "permanent" || *trashDir != "" || *quarantineDir != "" || *backupTo != ""):
+			problem = "only supports permanent deletions (no --fix, --fix-names, --fix-perms, --preserve-hardlinks, trash, quarantine or --backup-to)"
+		}
+		if problem != "" {
+			lang.Fprintf(os.Stderr, "%s: a remote library %s\n", url, lang.T(problem))
+			os.Exit(exitFailure)
+		}
 	}
 
-	result := &CleanupResult{}
-	var resultMu sync.Mutex
-
-	for _, libraryPath := range libraryPaths {
-		fmt.Printf("Scanning library: %s\n", libraryPath)
-		scanLibrary(libraryPath, *workers, result, &resultMu)
+	// --no-emoji spells out the symbols of everything written for people;
+	// JSON reports go to os.Stdout as they are
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if *noEmoji {
+		stdout, stderr = asciiWriter{os.Stdout}, asciiWriter{os.Stderr}
 	}
 
-	// Print results
-	fmt.Println("\n" + strings.Repeat("=", 60))
+	// Machine-readable reports own stdout; progress and deletion output move
+	// to stderr so the report can be piped into other tools.
+	out, reportOut := stdout, stdout
+	switch *format {
+	case "text":
+	case "json", "jsonl", "ndjson":
+		out, reportOut = stderr, os.Stdout
+	default:
+		lang.Fprintf(os.Stderr, "Unknown format %q (expected text, json, jsonl or ndjson)\n", *format)
+		os.Exit(exitFailure)
+	}
+	if _, ok := findingOrders[*sortBy]; *sortBy != "" && !ok {
+		lang.Fprintf(os.Stderr, "Unknown --sort order %q (expected size or age)\n", *sortBy)
+		os.Exit(exitFailure)
+	}
+	failLevel := cleanup.Severity(*failOn)
+	if *failOn != "" && failLevel != cleanup.SeverityWarning && failLevel != cleanup.SeverityError {
+		lang.Fprintf(os.Stderr, "Unknown --fail-on severity %q (expected warning or error)\n", *failOn)
+		os.Exit(exitFailure)
+	}
 
-	if len(result.StructureWarnings) > 0 {
-		fmt.Printf("\n⚠️  Structure warnings (%d):\n", len(result.StructureWarnings))
-		for _, warning := range result.StructureWarnings {
-			fmt.Printf("   %s\n", warning)
+	// Daemon runs scan libraries; they do not check titles, write or apply
+	// plans, restore, undo or get scheduled by the service manager
+	if *every < 0 || (*every > 0 && *every < time.Minute) {
+		lang.Fprintf(os.Stderr, "--every must be at least 1m, got %s\n", *every)
+		os.Exit(exitFailure)
+	}
+	var daemon schedule
+	switch {
+	case *every > 0 && *scheduleExpr != "":
+		lang.Fprintf(os.Stderr, "--every and --schedule cannot be combined\n")
+		os.Exit(exitFailure)
+	case *every > 0:
+		daemon = everySchedule(*every)
+	case *scheduleExpr != "":
+		cron, err := parseCron(*scheduleExpr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitFailure)
 		}
+		daemon = cron
 	}
-
-	if len(result.OrphanedFolders) > 0 {
-		fmt.Printf("\n🗑️  Orphaned metadata folders (no video file) (%d):\n", len(result.OrphanedFolders))
-		for _, folder := range result.OrphanedFolders {
-			fmt.Printf("   %s\n", folder)
+	if daemon != nil && (checkMode || planMode || applyPath != "" || restoreMode || undoMode || serviceCommand != "") {
+		lang.Fprintf(os.Stderr, "--every and --schedule cannot be combined with check, plan, apply, restore, undo or service\n")
+		os.Exit(exitFailure)
+	}
+	// The dashboard deletes nothing but what is approved in it
+	if serveMode && (*execute || *fixStructure || *fixNames || *fixPerms || *digestPath != "") {
+		lang.Fprintf(os.Stderr, "serve cannot be combined with --execute, --fix, --fix-names, --fix-perms or --digest, deletions are approved in the dashboard\n")
+		os.Exit(exitFailure)
+	}
+	// Anyone who can reach the dashboard can approve deletions
+	if serveMode && !isLoopback(serveAddr) {
+		lang.Fprintf(os.Stderr, "serve --addr %s is not a loopback address: the dashboard has no login, reach it through a reverse proxy\n", serveAddr)
+		os.Exit(exitFailure)
+	}
+	// A diff leaves out the known findings, which --execute would still delete
+	if *diffPath != "" && (*execute || *digestPath != "" || *interactive || serveMode || planMode || applyPath != "" || restoreMode || undoMode) {
+		lang.Fprintf(os.Stderr, "--diff cannot be combined with --execute, --digest, --interactive, serve, plan, apply, restore or undo\n")
+		os.Exit(exitFailure)
+	}
+	// The terminal UI takes the place of the report and of --execute
+	if *interactive && (*execute || checkMode || planMode || applyPath != "" || restoreMode || undoMode || serviceCommand != "" || serveMode || daemon != nil || *digestPath != "" || *format != "text" || *fixStructure || *fixNames || *fixPerms) {
+		lang.Fprintf(os.Stderr, "--interactive cannot be combined with --execute, --every, --schedule, --digest, --format, --fix, --fix-names, --fix-perms or a subcommand\n")
+		os.Exit(exitFailure)
+	}
+	if *interactive && !(isTerminal(os.Stdin) && isTerminal(os.Stdout)) {
+		lang.Fprintf(os.Stderr, "--interactive needs a terminal\n")
+		os.Exit(exitFailure)
+	}
+	switch serviceCommand {
+	case "":
+	case "install":
+		// Nobody is there to confirm the deletions of a scheduled run
+		if *execute && !*yes && *confirmOver > 0 {
+			lang.Fprintf(os.Stderr, "service install with --execute needs --yes (or --confirm-over 0), the scheduled runs cannot ask for confirmation\n")
+			os.Exit(exitFailure)
+		}
+		spec, err := serviceSpec(flag.CommandLine, libraryPaths, *serviceEvery)
+		if err == nil {
+			err = installService(spec)
+		}
+		if err != nil {
+			lang.Fprintf(os.Stderr, "Failed to install the service: %v\n", err)
+			os.Exit(exitFailure)
 		}
+		lang.Fprintf(os.Stdout, "Installed %s, running every %s: %s\n", serviceName, spec.Every, strings.Join(spec.Args, " "))
+		return
+	case "uninstall":
+		if err := uninstallService(); err != nil {
+			lang.Fprintf(os.Stderr, "Failed to uninstall the service: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		lang.Fprintf(os.Stdout, "Uninstalled %s\n", serviceName)
+		return
+	default:
+		lang.Fprintf(os.Stderr, "Unknown service command %q (expected install or uninstall)\n", serviceCommand)
+		os.Exit(exitFailure)
 	}
 
-	if len(result.OrphanedFiles) > 0 {
-		fmt.Printf("\n🗑️  Orphaned metadata files (no video file at same level) (%d):\n", len(result.OrphanedFiles))
-		for _, file := range result.OrphanedFiles {
-			fmt.Printf("   %s\n", file)
+	rw := reportWriter{format: *format, lang: lang, summary: *summary, quiet: *quiet, sort: *sortBy, verbose: *verbose, color: useColor(os.Stdout, *noColor)}
+	if *summary && *format != "text" {
+		lang.Fprintf(os.Stderr, "--summary only applies to --format text\n")
+		os.Exit(exitFailure)
+	}
+	if *quiet && (*verbose || *summary) {
+		lang.Fprintf(os.Stderr, "--quiet cannot be combined with --verbose or --summary\n")
+		os.Exit(exitFailure)
+	}
+	if *format == "ndjson" && (*sortBy != "" || *digestPath != "" || *diffPath != "" || len(outputs) > 0) {
+		lang.Fprintf(os.Stderr, "--format ndjson cannot be combined with --sort, --digest, --diff or --output\n")
+		os.Exit(exitFailure)
+	}
+	if len(outputs) > 0 && (*digestPath != "" || *diffPath != "" || *interactive) {
+		lang.Fprintf(os.Stderr, "--output cannot be combined with --digest, --diff or --interactive\n")
+		os.Exit(exitFailure)
+	}
+	if *reportStyle != "" {
+		var err error
+		if rw.style, err = LoadReportStyle(*reportStyle); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitFailure)
 		}
 	}
 
-	if len(result.EmptyFolders) > 0 {
-		fmt.Printf("\n📁 Empty folders (%d):\n", len(result.EmptyFolders))
-		for _, folder := range result.EmptyFolders {
-			fmt.Printf("   %s\n", folder)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if *logFile != "" {
+		logOut := &RotatingFile{Path: *logFile, MaxSize: int64(*logMaxSize) << 20, MaxAge: *logMaxAge, MaxBackups: *logMaxBackups}
+		defer logOut.Close()
+		var err error
+		if logger, err = newLogger(logOut, *logFormat, *logLevel); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitFailure)
 		}
 	}
-
-	// Execute deletions if requested
-	if *execute {
-		fmt.Println("\n" + strings.Repeat("=", 60))
-		fmt.Println("Executing deletions...")
-
-		deleted := 0
-		failed := 0
-
-		// Delete orphaned folders first
-		for _, folder := range result.OrphanedFolders {
-			if err := os.RemoveAll(folder); err != nil {
-				fmt.Printf("❌ Failed to delete %s: %v\n", folder, err)
-				failed++
-			} else {
-				fmt.Printf("✓ Deleted: %s\n", folder)
-				deleted++
-			}
+	// --trash DIR is short for --delete-mode trash with that directory
+	if *trashDir != "" {
+		if *deleteMode != "permanent" && *deleteMode != "trash" {
+			lang.Fprintf(os.Stderr, "--trash cannot be combined with --delete-mode %s\n", *deleteMode)
+			os.Exit(exitFailure)
 		}
-
-		// Delete orphaned files
-		for _, file := range result.OrphanedFiles {
-			if _, err := os.Stat(file); os.IsNotExist(err) {
-				continue
-			}
-			if err := os.Remove(file); err != nil {
-				fmt.Printf("❌ Failed to delete %s: %v\n", file, err)
-				failed++
-			} else {
-				fmt.Printf("✓ Deleted: %s\n", file)
-				deleted++
-			}
+		*deleteMode = "trash"
+		if err := (cleanup.TrashDirStrategy{Dir: *trashDir}).CheckLibraries(libraryPaths); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitFailure)
 		}
-
-		// Delete empty folders (in reverse order to handle nested empties)
-		for i := len(result.EmptyFolders) - 1; i >= 0; i-- {
-			folder := result.EmptyFolders[i]
-			// Check if still empty (might have been deleted as part of parent)
-			if _, err := os.Stat(folder); os.IsNotExist(err) {
-				continue
-			}
-			if err := os.Remove(folder); err != nil {
-				fmt.Printf("❌ Failed to delete %s: %v\n", folder, err)
-				failed++
-			} else {
-				fmt.Printf("✓ Deleted: %s\n", folder)
-				deleted++
-			}
+	}
+	if *quarantineDir != "" && (*trashDir != "" || *deleteMode != "permanent") {
+		lang.Fprintf(os.Stderr, "--quarantine cannot be combined with --trash or --delete-mode\n")
+		os.Exit(exitFailure)
+	}
+	if restoreMode {
+		if *quarantineDir == "" {
+			lang.Fprintf(os.Stderr, "restore needs --quarantine DIR\n")
+			os.Exit(exitFailure)
 		}
+		if err := runRestore(out, lang, logger, *quarantineDir, restorePaths); err != nil {
+			os.Exit(exitActionFailures)
+		}
+		return
+	}
+	if undoMode {
+		if *manifestPath == "" {
+			lang.Fprintf(os.Stderr, "undo needs --manifest FILE\n")
+			os.Exit(exitFailure)
+		}
+		if err := runUndo(out, lang, logger, *manifestPath, undoRun); err != nil {
+			os.Exit(exitActionFailures)
+		}
+		return
+	}
+	var strategy cleanup.DeleteStrategy
+	if *quarantineDir != "" {
+		quarantine := cleanup.NewQuarantine(*quarantineDir)
+		if err := quarantine.CheckLibraries(libraryPaths); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitFailure)
+		}
+		strategy = quarantine
+	} else if strategy, err = cleanup.DeleteStrategyByName(*deleteMode, *trashDir); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitFailure)
+	}
+	for _, remote := range remotes {
+		if remote.strategy == nil {
+			continue
+		}
+		routed, ok := strategy.(libraryStrategies)
+		if !ok {
+			routed = libraryStrategies{fallback: strategy, byLibrary: map[string]cleanup.DeleteStrategy{}}
+		}
+		routed.byLibrary[remote.root] = remote.strategy
+		strategy = routed
+	}
 
-		fmt.Printf("\nDeleted %d items, %d failures\n", deleted, failed)
-	} else {
-		total := len(result.OrphanedFolders) + len(result.OrphanedFiles) + len(result.EmptyFolders)
-		if total > 0 {
-			fmt.Printf("\n💡 Run with --execute to delete %d items\n", total)
-		} else {
-			fmt.Println("\n✓ Nothing to clean up")
+	if *resume && *checkpointPath == "" {
+		lang.Fprintf(os.Stderr, "--resume needs --checkpoint\n")
+		os.Exit(exitFailure)
+	}
+	// Digest runs stay silent until a digest is due, so cron only mails the
+	// digest itself
+	if *digestPath != "" {
+		if *execute {
+			lang.Fprintf(os.Stderr, "--digest cannot be combined with --execute\n")
+			os.Exit(exitFailure)
 		}
+		out = io.Discard
+	}
+	if *digestWebhook != "" && *digestPath == "" {
+		lang.Fprintf(os.Stderr, "--digest-webhook needs --digest\n")
+		os.Exit(exitFailure)
+	}
+	if u, err := url.Parse(*digestWebhook); *digestWebhook != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+		lang.Fprintf(os.Stderr, "--digest-webhook must be an http:// or https:// URL\n")
+		os.Exit(exitFailure)
+	}
+	if planMode && *execute {
+		lang.Fprintf(os.Stderr, "plan cannot be combined with --execute, use apply once the plan is reviewed\n")
+		os.Exit(exitFailure)
 	}
-}
 
-func scanLibrary(libraryPath string, numWorkers int, result *CleanupResult, resultMu *sync.Mutex) {
-	// Validate library path exists
-	info, err := os.Stat(libraryPath)
+	// Radarr, Sonarr and Plex are loaded again by every run
+	var managers []libraryManager
+	paths, err := parsePathMap(*pathMapping)
 	if err != nil {
-		fmt.Printf("Error accessing library path %s: %v\n", libraryPath, err)
-		return
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitFailure)
 	}
-	if !info.IsDir() {
-		fmt.Printf("Library path is not a directory: %s\n", libraryPath)
-		return
+	if *radarrURL != "" {
+		apiKey := orEnv(*radarrAPIKey, "RADARR_API_KEY")
+		if apiKey == "" {
+			lang.Fprintf(os.Stderr, "--radarr-url needs --radarr-api-key or RADARR_API_KEY\n")
+			os.Exit(exitFailure)
+		}
+		managers = append(managers, newRadarr(*radarrURL, apiKey, paths))
+	}
+	if *sonarrURL != "" {
+		apiKey := orEnv(*sonarrAPIKey, "SONARR_API_KEY")
+		if apiKey == "" {
+			lang.Fprintf(os.Stderr, "--sonarr-url needs --sonarr-api-key or SONARR_API_KEY\n")
+			os.Exit(exitFailure)
+		}
+		if *structure != "tv" || *layoutTemplate != "" {
+			lang.Fprintf(os.Stderr, "--sonarr-url needs --structure tv\n")
+			os.Exit(exitFailure)
+		}
+		managers = append(managers, newSonarr(*sonarrURL, apiKey, paths))
+	}
+	if *plexURL != "" {
+		token := orEnv(*plexToken, "PLEX_TOKEN")
+		if token == "" {
+			lang.Fprintf(os.Stderr, "--plex-url needs --plex-token or PLEX_TOKEN\n")
+			os.Exit(exitFailure)
+		}
+		managers = append(managers, newPlex(*plexURL, token, libraryPaths, *plexScan, paths))
+	} else if *plexScan {
+		lang.Fprintf(os.Stderr, "--plex-scan needs --plex-url\n")
+		os.Exit(exitFailure)
 	}
 
-	// Check for files directly in library (structure violation)
-	checkDirectChildren(libraryPath, "library", result, resultMu)
+	// Scans and deletions share one --max-iops budget
+	if *maxIOPS < 0 {
+		lang.Fprintf(os.Stderr, "--max-iops must not be negative, got %d\n", *maxIOPS)
+		os.Exit(exitFailure)
+	}
+	limiter := cleanup.NewRateLimiter(*maxIOPS)
+	// A wrong path or layout makes a healthy library look orphaned; the
+	// threshold keeps such a run from deleting it
+	if *maxDeleteCount < 0 || *maxDeletePercent < 0 {
+		lang.Fprintf(os.Stderr, "--max-delete-count and --max-delete-percent must not be negative\n")
+		os.Exit(exitFailure)
+	}
+	threshold := cleanup.DeletionThreshold{MaxCount: *maxDeleteCount, MaxPercent: *maxDeletePercent}
 
-	// Get all studio folders
-	studioEntries, err := os.ReadDir(libraryPath)
+	// What the scans check, the same for every library and every run
+	checkOpts := []cleanup.Option{cleanup.WithWorkers(*workers), cleanup.WithFollowSymlinks(*followSymlinks), cleanup.WithDuplicateDetection(*duplicates), cleanup.WithDuplicateHashing(*dedupe), cleanup.WithNameAudit(*auditNames || *fixNames), cleanup.WithNFOYearCheck(*checkYears), cleanup.WithNFOCheck(*checkNFO), cleanup.WithMissingMetadataCheck(*missingMetadata), cleanup.WithRecycleBinUsage(*recycleUsage), cleanup.WithJunkFiles(*junk), cleanup.WithRateLimiter(limiter)}
+	if categories, err := selectCategories(*onlyCategories, *skipCategories); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitFailure)
+	} else if categories != nil {
+		checkOpts = append(checkOpts, cleanup.WithCategories(categories...))
+	}
+	if size, err := cleanup.ParseBytes(*minVideoSize); err != nil {
+		fmt.Fprintf(os.Stderr, "--min-video-size: %v\n", err)
+		os.Exit(exitFailure)
+	} else if size > 0 {
+		checkOpts = append(checkOpts, cleanup.WithMinVideoSize(size))
+	}
+	if *probe {
+		if _, err := exec.LookPath("ffprobe"); err != nil {
+			lang.Fprintf(stderr, "⚠️  ffprobe not found in PATH, videos are not probed\n")
+		} else {
+			checkOpts = append(checkOpts, cleanup.WithVideoProbe(cleanup.FFProbe{}))
+		}
+	}
+	if *minAge != "" {
+		age, err := cleanup.ParseAge(*minAge)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "--min-age: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		checkOpts = append(checkOpts, cleanup.WithMinAge(age))
+	}
+	var policy cleanup.PermissionPolicy
+	if *auditPerms || *fixPerms {
+		if policy, err = cleanup.ParsePermissionPolicy(*owner, *dirMode, *fileMode); err != nil {
+			lang.Fprintf(os.Stderr, "Invalid permission policy: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		checkOpts = append(checkOpts, cleanup.WithPermissionAudit(policy))
+	}
+	// Structure, extensions and patterns may be overridden per library
+	flagOptions := libraryOptions{structure: *structure, layout: *layoutTemplate, videoExt: *videoExt, onlyVideoExt: *onlyVideoExt, stubExt: append([]string{}, splitList(*stubExt)...), metadataDirs: splitList(*metadataDirs), dirNames: splitList(*metadataDirNames), include: includes, exclude: excludes, smb: *smb}
+	libraryOpts, layout, err := flagOptions.scanOptions()
 	if err != nil {
-		fmt.Printf("Error reading library directory %s: %v\n", libraryPath, err)
-		return
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitFailure)
 	}
-
-	// Collect studio directories
-	var studioDirs []string
-	for _, entry := range studioEntries {
-		if entry.IsDir() {
-			studioDirs = append(studioDirs, filepath.Join(libraryPath, entry.Name()))
+	var configuredOpts map[string][]cleanup.Option
+	if config != nil {
+		configuredOpts = map[string][]cleanup.Option{}
+		for _, library := range config.Libraries {
+			opts, _, err := flagOptions.override(library).scanOptions()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "config: library %s: %v\n", library.Path, err)
+				os.Exit(exitFailure)
+			}
+			configuredOpts[library.Path] = opts
 		}
 	}
+	deleterOpts := []cleanup.DeleterOption{cleanup.WithDeleteWorkers(*workers), cleanup.WithPreserveHardlinks(*preserveHardlinks), cleanup.WithDeleteRateLimiter(limiter)}
 
-	// Process studios concurrently
-	studioChan := make(chan string, len(studioDirs))
-	var wg sync.WaitGroup
+	var metrics *runMetrics
+	if *metricsAddr != "" {
+		if daemon == nil {
+			lang.Fprintf(os.Stderr, "--metrics needs --every or --schedule\n")
+			os.Exit(exitFailure)
+		}
+		metrics = newRunMetrics()
+		go serveMetrics(*metricsAddr, metrics, logger)
+	}
 
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for studioPath := range studioChan {
-				processStudio(studioPath, result, resultMu)
-			}
-		}()
+	var board *dashboard
+	if serveMode {
+		board = newDashboard(lang, rw.style)
 	}
 
-	for _, studioDir := range studioDirs {
-		studioChan <- studioDir
+	// A run scans, reports and deletes once; --every and --schedule repeat
+	// it. Ctrl-C and docker stop let the items in progress finish and the
+	// run report what it got to
+	interrupted := interruptContext(stderr, lang)
+	r := &runner{
+		lang: lang, logger: logger, interrupted: interrupted, timeout: *timeout, metrics: metrics, board: board,
+		libraryPaths: libraryPaths, remotes: remotes, checkMode: checkMode, planMode: planMode, planOut: planOut, applyPath: applyPath, planKey: *planKey, lockDir: *lockDir,
+		workers: *workers, checkOpts: checkOpts, libraryOpts: libraryOpts, configuredOpts: configuredOpts, layout: layout,
+		statePath: *statePath, fullScan: *fullScan, checkpointPath: *checkpointPath, resume: *resume, cachePath: *cachePath, maxFindings: *maxFindings, managers: managers,
+		rw: rw, out: out, reportOut: reportOut, stdout: stdout, stderr: stderr, format: *format, outputs: outputs,
+		summary: *summary, quiet: *quiet, verbose: *verbose, interactive: *interactive, showProgress: *showProgress,
+		digestPath: *digestPath, digestEvery: *digestEvery, digestHook: *digestWebhook, diffPath: *diffPath, failOn: *failOn, failLevel: failLevel,
+		execute: *execute, fixPerms: *fixPerms, fixStructure: *fixStructure, fixNames: *fixNames, policy: policy,
+		strategy: strategy, deleterOpts: deleterOpts, threshold: threshold, protected: protected, confirmOver: *confirmOver, yes: *yes, readOnlyRemote: readOnlyRemote,
+		manifestPath: *manifestPath, backupTo: *backupTo, trashDir: *trashDir, quarantineDir: *quarantineDir, quarantineRetention: *quarantineRetention,
 	}
-	close(studioChan)
-	wg.Wait()
 
-	// After processing all title folders, check for empty studio folders
-	for _, studioPath := range studioDirs {
-		if isEmpty, _ := isDirEmpty(studioPath); isEmpty {
-			resultMu.Lock()
-			result.EmptyFolders = append(result.EmptyFolders, studioPath)
-			resultMu.Unlock()
+	if serveMode {
+		board.run = func(approved map[string]bool) int {
+			start := time.Now()
+			code := r.runOnce(approved)
+			metrics.finished(code, start)
+			return code
 		}
+		serveDashboard(interrupted, serveAddr, board, daemon, logger)
+		os.Exit(exitClean)
 	}
+	if *interactive {
+		code := r.runOnce(nil)
+		if r.lastResult == nil {
+			os.Exit(code)
+		}
+		approved, err := runPicker(newPicker(r.lastResult, lang, rw.style, func(f cleanup.Finding) ([]fs.DirEntry, error) {
+			for _, remote := range remotes {
+				if remote.root == f.Library {
+					return remote.fsys.ReadDir(f.Path)
+				}
+			}
+			return os.ReadDir(f.Path)
+		}), os.Stdin, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitFailure)
+		}
+		if len(approved) == 0 {
+			lang.Fprintf(out, "Nothing selected, nothing deleted\n")
+			os.Exit(code)
+		}
+		os.Exit(r.runOnce(approved))
+	}
+	if daemon == nil {
+		os.Exit(r.runOnce(nil))
+	}
+	runDaemon(interrupted, daemon, metrics, logger, func() int { return r.runOnce(nil) })
 }
 
-func processStudio(studioPath string, result *CleanupResult, resultMu *sync.Mutex) {
-	// Check for files directly in studio folder (structure violation)
-	checkDirectChildren(studioPath, "studio", result, resultMu)
-
-	// Get all title folders in this studio
-	titleEntries, err := os.ReadDir(studioPath)
-	if err != nil {
-		resultMu.Lock()
-		result.StructureWarnings = append(result.StructureWarnings,
-			fmt.Sprintf("Cannot read studio directory: %s (%v)", studioPath, err))
-		resultMu.Unlock()
-		return
+// severeFindings returns the findings of result of severity min or above,
+// with its scan errors, for --fail-on.
+func severeFindings(result *cleanup.CleanupResult, min cleanup.Severity) *cleanup.CleanupResult {
+	severe := &cleanup.CleanupResult{Libraries: result.Libraries, Errors: result.Errors}
+	for _, f := range result.Findings {
+		if cleanup.SeverityOf(f).AtLeast(min) {
+			severe.Add(f)
+		}
 	}
+	return severe
+}
 
-	for _, entry := range titleEntries {
-		if !entry.IsDir() {
-			continue // Files in studio are handled by checkDirectChildren
+// exitCode is the exit code of a run that went through: exitScanErrors if
+// folders could not be scanned, exitActionFailures if deletions or fixes
+// failed, exitFindings if findings are left in place, exitClean otherwise.
+// Findings inside a deleted folder went with it. The reports are nil when
+// nothing was deleted, fixed, moved or renamed.
+func exitCode(result *cleanup.CleanupResult, report *cleanup.DeletionReport, fixReport *cleanup.PermissionFixReport, moveReport *cleanup.StructureFixReport, renameReport *cleanup.NameFixReport) int {
+	if len(result.Errors) > 0 {
+		return exitScanErrors
+	}
+	var gone []string
+	if report != nil {
+		if len(report.Failures) > 0 {
+			return exitActionFailures
+		}
+		for _, f := range append(report.Deleted, report.Skipped...) {
+			gone = append(gone, f.Path)
+		}
+	}
+	fixed := map[string]bool{}
+	if fixReport != nil {
+		if len(fixReport.Failures) > 0 {
+			return exitActionFailures
+		}
+		for _, f := range append(fixReport.Fixed, fixReport.Skipped...) {
+			fixed[f.Path] = true
+		}
+	}
+	moved := map[string]bool{}
+	if moveReport != nil {
+		if len(moveReport.Failures) > 0 {
+			return exitActionFailures
+		}
+		for _, f := range moveReport.Moved {
+			moved[f.Path] = true
+		}
+	}
+	renamed := map[string]bool{}
+	if renameReport != nil {
+		if len(renameReport.Failures) > 0 {
+			return exitActionFailures
+		}
+		for _, f := range renameReport.Renamed {
+			renamed[f.Path] = true
 		}
-
-		titlePath := filepath.Join(studioPath, entry.Name())
-		processTitleFolder(titlePath, result, resultMu)
 	}
+	for _, f := range result.Findings {
+		if f.Category == cleanup.CategoryPermissionMismatch && fixed[f.Path] {
+			continue
+		}
+		if f.Category == cleanup.CategoryStructureWarning && moved[f.Path] {
+			continue
+		}
+		if f.Category == cleanup.CategoryIncompatibleName && renamed[f.Path] {
+			continue
+		}
+		left := true
+		for _, path := range gone {
+			if cleanup.IsWithin(path, f.Path) {
+				left = false
+				break
+			}
+		}
+		if left {
+			return exitFindings
+		}
+	}
+	return exitClean
 }
 
-func processTitleFolder(titlePath string, result *CleanupResult, resultMu *sync.Mutex) {
-	entries, err := os.ReadDir(titlePath)
-	if err != nil {
-		resultMu.Lock()
-		result.StructureWarnings = append(result.StructureWarnings,
-			fmt.Sprintf("Cannot read title directory: %s (%v)", titlePath, err))
-		resultMu.Unlock()
-		return
-	}
+// stringList is a flag that may be repeated, collecting every value.
+type stringList []string
 
-	// Check if folder is empty
-	if len(entries) == 0 {
-		resultMu.Lock()
-		result.EmptyFolders = append(result.EmptyFolders, titlePath)
-		resultMu.Unlock()
-		return
-	}
+func (l *stringList) String() string { return strings.Join(*l, ",") }
 
-	// Check for video files and subdirectories
-	hasVideoFile := false
-	var unexpectedSubdirs []string
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			// Check if this is a known metadata subdirectory (e.g. movie.trickplay)
-			// These are ignored - they're only valid alongside a video file
-			if !isMetadataSubdir(entry.Name()) {
-				unexpectedSubdirs = append(unexpectedSubdirs, entry.Name())
-			}
-			continue
-		}
+// runDeletions disposes of the deletable findings of result with strategy,
+// printing each item to items and the totals and failures to out. The
+// error, already printed and logged, is the one that aborted the deletion.
+func runDeletions(ctx context.Context, out, items io.Writer, lang *Language, logger *slog.Logger, strategy cleanup.DeleteStrategy, manifest *cleanup.Manifest, backupPath string, result *cleanup.CleanupResult, opts ...cleanup.DeleterOption) (*cleanup.DeletionReport, error) {
+	fmt.Fprintln(items, "\n"+strings.Repeat("=", 60))
+	lang.Fprintf(items, "Executing deletions...\n")
 
-		ext := strings.ToLower(filepath.Ext(entry.Name()))
-		if videoExtensions[ext] {
-			hasVideoFile = true
+	if manifest != nil {
+		opts = append(opts, cleanup.WithManifest(manifest))
+	}
+	var backup *cleanup.Backup
+	if backupPath != "" {
+		var err error
+		if backup, err = cleanup.CreateBackup(backupPath); err != nil {
+			logger.Error("deletion aborted", "error", err)
+			lang.Fprintf(out, "⚠️  Deletion aborted: %v\n", err)
+			return &cleanup.DeletionReport{Strategy: strategy.Name()}, err
+		}
+		opts = append(opts, cleanup.WithBackup(backup))
+	}
+	opts = append(opts, cleanup.WithDeleteProgress(func(ev cleanup.ProgressEvent) {
+		done := ev.(cleanup.DeletionDone)
+		logDeletion(logger, done)
+		if done.Err != nil {
+			fmt.Fprintf(out, "❌ %v\n", done.Err)
+		} else if done.Kept {
+			lang.Fprintf(items, "🔗 Kept hardlinked files in: %s\n", done.Finding.Path)
+		} else {
+			lang.Fprintf(items, "✓ Deleted: %s\n", done.Finding.Path)
+		}
+	}))
+	report, err := cleanup.NewDeleter(strategy, opts...).Delete(ctx, result)
+	err = abortCause(ctx, err)
+	if backup != nil {
+		if closeErr := backup.Close(); closeErr != nil {
+			logger.Error("backup incomplete", "backup", backupPath, "error", closeErr)
+			lang.Fprintf(out, "⚠️  Backup archive may be incomplete: %v\n", closeErr)
+		} else if backup.Items() > 0 {
+			logger.Info("backup written", "backup", backupPath, "items", backup.Items())
+			lang.Fprintf(out, "🗄️  Backed up %d items to %s\n", backup.Items(), backupPath)
 		}
 	}
+	logger.Info("deletion finished", "strategy", report.Strategy, "deleted", len(report.Deleted),
+		"kept", len(report.Kept), "skipped", len(report.Skipped), "failures", len(report.Failures))
+	lang.Fprintf(totalsOut(out, items, len(report.Deleted)+len(report.Kept)+len(report.Failures)), "\nDeleted %d items, %d failures\n", len(report.Deleted), len(report.Failures))
+	if len(report.Kept) > 0 {
+		lang.Fprintf(out, "Kept %d items with hardlinked files\n", len(report.Kept))
+	}
+	if manifest != nil && len(report.Deleted) > 0 {
+		logger.Info("deletions recorded", "manifest", manifest.Path, "run", manifest.Run)
+		lang.Fprintf(out, "📝 Recorded in %s as run %s\n", manifest.Path, manifest.Run)
+	}
+	if err != nil {
+		logger.Error("deletion aborted", "error", err)
+		lang.Fprintf(out, "⚠️  Deletion aborted: %v\n", err)
+	}
+	return report, err
+}
 
-	// Warn about unexpected subdirectories in title folder
-	for _, subdir := range unexpectedSubdirs {
-		resultMu.Lock()
-		result.StructureWarnings = append(result.StructureWarnings,
-			fmt.Sprintf("Unexpected subdirectory in title folder: %s", filepath.Join(titlePath, subdir)))
-		resultMu.Unlock()
+// totalsOut returns out for the totals of a deletion or fix that handled n
+// items, and items, which --quiet discards, when it handled none.
+func totalsOut(out, items io.Writer, n int) io.Writer {
+	if n == 0 {
+		return items
 	}
+	return out
+}
 
-	// If no video file but has content (metadata files, subdirs), mark as orphaned
-	if !hasVideoFile && len(entries) > 0 {
-		resultMu.Lock()
-		result.OrphanedFolders = append(result.OrphanedFolders, titlePath)
-		resultMu.Unlock()
+// actedOn returns the findings a run with --execute acts on: the deletable
+// ones of toDelete, and those of result the fixers enabled move, rename or
+// fix.
+func actedOn(toDelete, result *cleanup.CleanupResult, fixPerms, fixStructure, fixNames bool) []cleanup.Finding {
+	var findings []cleanup.Finding
+	for _, f := range toDelete.Findings {
+		if isDeletable(f) {
+			findings = append(findings, f)
+		}
+	}
+	if fixPerms {
+		findings = append(findings, result.ByCategory(cleanup.CategoryPermissionMismatch)...)
 	}
+	if fixStructure {
+		findings = append(findings, cleanup.Movable(result)...)
+	}
+	if fixNames {
+		findings = append(findings, cleanup.Renamable(result)...)
+	}
+	return findings
 }
 
-func checkDirectChildren(dirPath string, level string, result *CleanupResult, resultMu *sync.Mutex) {
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
+// refuseProtected prints the findings of err, a *cleanup.ProtectedError,
+// to w.
+func refuseProtected(w io.Writer, lang *Language, err error) {
+	var protected *cleanup.ProtectedError
+	if !errors.As(err, &protected) {
 		return
 	}
+	lang.Fprintf(w, "\n🔒 Protected paths would be touched, nothing was done:\n")
+	for _, touched := range protected.Findings {
+		lang.Fprintf(w, "   %s (protected by %s)\n", touched.Finding.Path, touched.Pattern)
+	}
+}
 
-	// First pass: collect all files and check for video files
-	var files []string
-	videoBasenames := make(map[string]bool) // basenames of video files (without extension)
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			filePath := filepath.Join(dirPath, entry.Name())
-			files = append(files, filePath)
-
-			ext := strings.ToLower(filepath.Ext(entry.Name()))
-			if videoExtensions[ext] {
-				// Store the basename without extension
-				basename := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
-				videoBasenames[strings.ToLower(basename)] = true
+// lockLibraries locks every library in dir, or none of them when one is
+// locked by another run. unlock releases the locks, and returns why any
+// could not be.
+func lockLibraries(dir string, libraries []string) (unlock func() error, err error) {
+	var locks []*cleanup.LibraryLock
+	unlock = func() error {
+		var errs []error
+		for _, lock := range locks {
+			if err := lock.Unlock(); err != nil {
+				errs = append(errs, err)
 			}
 		}
+		return errors.Join(errs...)
 	}
-
-	// Second pass: categorize files
-	for _, filePath := range files {
-		filename := filepath.Base(filePath)
-		ext := strings.ToLower(filepath.Ext(filename))
-
-		if videoExtensions[ext] {
-			// Video file at wrong level - just warn
-			resultMu.Lock()
-			result.StructureWarnings = append(result.StructureWarnings,
-				fmt.Sprintf("Video file at %s level (should be in title folder): %s", level, filePath))
-			resultMu.Unlock()
-		} else {
-			// Non-video file - check if it's orphaned metadata
-			basename := strings.TrimSuffix(filename, ext)
-			// Check if there's a video with matching basename prefix
-			// e.g., "movie.nfo" matches "movie.mkv", "movie-poster.jpg" matches "movie.mkv"
-			hasMatchingVideo := false
-			for videoBase := range videoBasenames {
-				if strings.HasPrefix(strings.ToLower(basename), videoBase) {
-					hasMatchingVideo = true
-					break
-				}
+	for _, library := range libraries {
+		lock, err := cleanup.LockLibrary(dir, library)
+		if err != nil {
+			if unlockErr := unlock(); unlockErr != nil {
+				return nil, errors.Join(err, unlockErr)
 			}
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return unlock, nil
+}
 
-			if hasMatchingVideo {
-				// Metadata file with matching video - just warn about location
-				resultMu.Lock()
-				result.StructureWarnings = append(result.StructureWarnings,
-					fmt.Sprintf("Metadata file at %s level (should be in title folder): %s", level, filePath))
-				resultMu.Unlock()
-			} else {
-				// Orphaned metadata file - no matching video
-				resultMu.Lock()
-				result.OrphanedFiles = append(result.OrphanedFiles, filePath)
-				resultMu.Unlock()
+// runRestore puts the items quarantined in dir back, those at or below one
+// of paths, or all of them if paths is empty. The error, already printed,
+// is set if anything could not be restored.
+func runRestore(out io.Writer, lang *Language, logger *slog.Logger, dir string, paths []string) error {
+	var prefixes []string
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return err
+		}
+		prefixes = append(prefixes, abs)
+	}
+	restored, err := cleanup.RestoreQuarantine(dir, func(entry cleanup.QuarantineEntry) bool {
+		for _, prefix := range prefixes {
+			if cleanup.IsWithin(prefix, entry.Original) {
+				return true
 			}
 		}
+		return len(prefixes) == 0
+	})
+	for _, entry := range restored {
+		logger.Info("restored", "path", entry.Original, "from", entry.Quarantined)
+		lang.Fprintf(out, "↩️  Restored: %s\n", entry.Original)
 	}
+	failures := cleanup.SplitErrors(err)
+	for _, err := range failures {
+		logger.Warn("restore failed", "error", err)
+		fmt.Fprintf(out, "❌ %v\n", err)
+	}
+	lang.Fprintf(out, "\nRestored %d items, %d failures\n", len(restored), len(failures))
+	return err
 }
 
-func isDirEmpty(dirPath string) (bool, error) {
-	entries, err := os.ReadDir(dirPath)
+// runUndo moves back what run, or the last run recorded in the manifest at
+// path if run is empty, moved. The error, already printed, is set if
+// anything could not be moved back, including items deleted for good.
+func runUndo(out io.Writer, lang *Language, logger *slog.Logger, path, run string) error {
+	if run == "" {
+		entries, err := cleanup.ReadManifest(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return err
+		}
+		if run = cleanup.LastRun(entries); run == "" {
+			lang.Fprintf(out, "Nothing to undo in %s\n", path)
+			return nil
+		}
+	}
+	manifest, err := cleanup.OpenManifest(path, run)
 	if err != nil {
-		return false, err
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return err
+	}
+	defer manifest.Close()
+
+	lang.Fprintf(out, "Undoing run %s\n", run)
+	restored, lost, err := manifest.Undo(run)
+	for _, entry := range restored {
+		logger.Info("restored", "path", entry.Path, "from", entry.MovedTo, "run", run)
+		lang.Fprintf(out, "↩️  Restored: %s\n", entry.Path)
+	}
+	for _, entry := range lost {
+		logger.Warn("not restorable", "path", entry.Path, "strategy", entry.Strategy, "run", run)
+		lang.Fprintf(out, "⚠️  Deleted for good, not restorable: %s\n", entry.Path)
+	}
+	failures := cleanup.SplitErrors(err)
+	for _, err := range failures {
+		logger.Warn("restore failed", "error", err)
+		fmt.Fprintf(out, "❌ %v\n", err)
+	}
+	lang.Fprintf(out, "\nRestored %d items, %d failures\n", len(restored), len(failures)+len(lost))
+	if err == nil && len(lost) > 0 {
+		err = fmt.Errorf("%d items of run %s were deleted for good", len(lost), run)
 	}
-	return len(entries) == 0, nil
+	return err
 }
 
-func isMetadataSubdir(name string) bool {
-	for _, suffix := range metadataSubdirSuffixes {
-		if strings.HasSuffix(strings.ToLower(name), suffix) {
-			return true
-		}
+// purgeQuarantine removes the items quarantined in dir longer ago than
+// retention. Failures are only warnings; the items are retried next run.
+func purgeQuarantine(out io.Writer, lang *Language, logger *slog.Logger, dir string, retention time.Duration) {
+	purged, err := cleanup.PurgeQuarantine(dir, time.Now().Add(-retention))
+	for _, entry := range purged {
+		logger.Info("purged from quarantine", "path", entry.Quarantined, "original", entry.Original)
+	}
+	if len(purged) > 0 {
+		lang.Fprintf(out, "Purged %d items quarantined more than %s ago\n", len(purged), retention)
+	}
+	for _, err := range cleanup.SplitErrors(err) {
+		logger.Warn("quarantine purge failed", "error", err)
+		lang.Fprintf(out, "⚠️  Quarantine purge failed: %v\n", err)
+	}
+}
+
+// logDeletion records the outcome of one deletion in the operational log.
+func logDeletion(logger *slog.Logger, done cleanup.DeletionDone) {
+	switch {
+	case done.Err != nil:
+		logger.Warn("delete failed", "path", done.Finding.Path, "error", done.Err)
+	case done.Kept:
+		logger.Info("kept hardlinked files", "path", done.Finding.Path)
+	default:
+		logger.Info("deleted", "path", done.Finding.Path, "category", done.Finding.Category)
 	}
-	return false
 }
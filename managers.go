@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// libraryManager is an application managing the media of the scanned
+// libraries, such as Radarr or Sonarr. It is consulted before anything is deleted and
+// told about the deletions afterwards, so the cleanup does not fight it.
+type libraryManager interface {
+	Name() string
+	// Load fetches what the manager knows about; it is called once, before
+	// scanning.
+	Load(ctx context.Context) error
+	// Wanted reports whether path is, or lies inside, media the manager
+	// still wants, and describes that media.
+	Wanted(path string) (string, bool)
+	// Rescan asks the manager to rescan the media the deleted paths belonged
+	// to.
+	Rescan(ctx context.Context, deleted []string) error
+}
+
+// wantedFilter drops the deletable findings some library manager still
+// wants before they are recorded, keeping count per manager.
+type wantedFilter struct {
+	managers []libraryManager
+	logger   *slog.Logger
+
+	mu   sync.Mutex
+	kept map[string]int
+}
+
+// wrap returns add skipping the findings wanted by a manager. It is safe
+// for concurrent use, like the callbacks Scanner.Scan calls.
+func (w *wantedFilter) wrap(add func(cleanup.Finding) error) func(cleanup.Finding) error {
+	return func(f cleanup.Finding) error {
+		switch f.Category {
+		case cleanup.CategoryOrphanedFolder, cleanup.CategoryOrphanedFile, cleanup.CategoryEmptyFolder:
+		default:
+			return add(f)
+		}
+		path, err := filepath.Abs(f.Path)
+		if err != nil {
+			return add(f)
+		}
+		for _, m := range w.managers {
+			if media, ok := m.Wanted(path); ok {
+				w.logger.Info("kept, still wanted", "manager", m.Name(), "media", media, "path", f.Path, "category", f.Category)
+				w.mu.Lock()
+				if w.kept == nil {
+					w.kept = map[string]int{}
+				}
+				w.kept[m.Name()]++
+				w.mu.Unlock()
+				return nil
+			}
+		}
+		return add(f)
+	}
+}
+
+// notifyManagers asks every manager to rescan after the deletions of
+// report. A manager that cannot be reached is only a warning: the files are
+// gone either way.
+func notifyManagers(ctx context.Context, managers []libraryManager, report *cleanup.DeletionReport, logger *slog.Logger) []error {
+	if report == nil || len(report.Deleted) == 0 {
+		return nil
+	}
+	var deleted []string
+	for _, f := range report.Deleted {
+		if path, err := filepath.Abs(f.Path); err == nil {
+			deleted = append(deleted, path)
+		}
+	}
+	var errs []error
+	for _, m := range managers {
+		if err := m.Rescan(ctx, deleted); err != nil {
+			logger.Warn("rescan request failed", "manager", m.Name(), "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", m.Name(), err))
+			continue
+		}
+		logger.Info("rescan requested", "manager", m.Name())
+	}
+	return errs
+}
+
+// callJSON sends a request to url with header and body encoded as JSON, and
+// decodes the JSON response into out unless it is nil.
+func callJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s", method, req.URL.Path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: %w", method, req.URL.Path, err)
+	}
+	return nil
+}
+
+// pathMap translates the paths a manager reports, as seen from its own host
+// or container, to the paths of the scanned libraries.
+type pathMap []pathMapping
+
+type pathMapping struct {
+	Remote, Local string
+}
+
+// parsePathMap parses a comma-separated list of remote=local prefixes, such
+// as "/movies=/mnt/media/Movies,/tv=/mnt/media/TV".
+func parsePathMap(value string) (pathMap, error) {
+	var m pathMap
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		remote, local, ok := strings.Cut(pair, "=")
+		if !ok || remote == "" || local == "" {
+			return nil, fmt.Errorf("invalid path mapping %q (expected remote=local)", pair)
+		}
+		m = append(m, pathMapping{Remote: remote, Local: local})
+	}
+	return m, nil
+}
+
+// Local returns the local, absolute form of the remote path. The first
+// matching prefix wins; paths matching none are taken as they are.
+func (m pathMap) Local(remote string) string {
+	for _, mapping := range m {
+		prefix := strings.TrimRight(mapping.Remote, `/\`)
+		if rest, ok := strings.CutPrefix(remote, prefix); ok && (rest == "" || rest[0] == '/' || rest[0] == '\\') {
+			remote = mapping.Local + filepath.FromSlash(strings.ReplaceAll(rest, `\`, "/"))
+			break
+		}
+	}
+	if abs, err := filepath.Abs(remote); err == nil {
+		return abs
+	}
+	return filepath.Clean(remote)
+}
+
+// Remote is the reverse of Local: it returns local as the manager sees it,
+// using the separator of the matching remote prefix. Paths matching no
+// mapping are returned unchanged.
+func (m pathMap) Remote(local string) string {
+	for _, mapping := range m {
+		prefix, err := filepath.Abs(mapping.Local)
+		if err != nil || !cleanup.IsWithin(prefix, local) {
+			continue
+		}
+		rel, _ := filepath.Rel(prefix, local)
+		remote := strings.TrimRight(mapping.Remote, `/\`)
+		if rel == "." {
+			return remote
+		}
+		sep := "/"
+		if strings.Contains(mapping.Remote, `\`) {
+			sep = `\`
+		}
+		return remote + sep + strings.ReplaceAll(filepath.ToSlash(rel), "/", sep)
+	}
+	return local
+}
+
+// orEnv returns value, or the environment variable name if value is empty.
+// API keys are better passed this way than on a command line others can see.
+func orEnv(value, name string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(name)
+}
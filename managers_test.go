@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// fakeManager wants everything below its folders and records rescans.
+type fakeManager struct {
+	wanted  []string
+	rescans [][]string
+}
+
+func (m *fakeManager) Name() string                   { return "Fake" }
+func (m *fakeManager) Load(ctx context.Context) error { return nil }
+
+func (m *fakeManager) Wanted(path string) (string, bool) {
+	for _, dir := range m.wanted {
+		if cleanup.IsWithin(dir, path) {
+			return filepath.Base(dir), true
+		}
+	}
+	return "", false
+}
+
+func (m *fakeManager) Rescan(ctx context.Context, deleted []string) error {
+	m.rescans = append(m.rescans, deleted)
+	return nil
+}
+
+// ============================================================================
+// Tests for pathMap
+// ============================================================================
+
+func TestParsePathMap(t *testing.T) {
+	m, err := parsePathMap("/movies=/mnt/media/Movies, /tv/=/mnt/media/TV")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(m) != 2 {
+		t.Fatalf("Expected 2 mappings, got %d", len(m))
+	}
+	if _, err := parsePathMap("/movies"); err == nil {
+		t.Error("Expected an error for a mapping without =")
+	}
+}
+
+func TestPathMap_Local(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "Movies")
+	m := pathMap{{Remote: "/movies/", Local: local}}
+
+	tests := []struct {
+		remote, expected string
+	}{
+		{"/movies/Studio A/Old Movie (2019)", filepath.Join(local, "Studio A", "Old Movie (2019)")},
+		{"/movies", local},
+		{`/movies\Studio A`, filepath.Join(local, "Studio A")},
+	}
+	for _, tt := range tests {
+		if got := m.Local(tt.remote); got != tt.expected {
+			t.Errorf("Local(%q): expected %s, got %s", tt.remote, tt.expected, got)
+		}
+	}
+	// Prefixes match whole path elements only
+	unmapped, _ := filepath.Abs("/movies-old/x")
+	if got := m.Local("/movies-old/x"); got != unmapped {
+		t.Errorf("Expected /movies-old/x not to be mapped, got %s", got)
+	}
+}
+
+// ============================================================================
+// Tests for wantedFilter
+// ============================================================================
+
+func TestWantedFilter_SkipsWantedDeletions(t *testing.T) {
+	library := t.TempDir()
+	title := filepath.Join(library, "Studio", "Upcoming (2025)")
+	manager := &fakeManager{wanted: []string{title}}
+	filter := &wantedFilter{managers: []libraryManager{manager}, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	result := &cleanup.CleanupResult{Libraries: []string{library}}
+	add := filter.wrap(result.Add)
+	findings := []cleanup.Finding{
+		{Category: cleanup.CategoryOrphanedFolder, Path: title, Library: library},
+		{Category: cleanup.CategoryOrphanedFile, Path: filepath.Join(title, "poster.jpg"), Library: library},
+		{Category: cleanup.CategoryStructureWarning, Path: filepath.Join(title, "x.txt"), Library: library, Message: "not a video"},
+		{Category: cleanup.CategoryOrphanedFolder, Path: filepath.Join(library, "Studio", "Gone (2001)"), Library: library},
+	}
+	for _, f := range findings {
+		if err := add(f); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if len(result.Findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %v", len(result.Findings), result.Findings)
+	}
+	if result.Findings[0].Category != cleanup.CategoryStructureWarning {
+		t.Errorf("Expected warnings to be kept, got %v", result.Findings[0])
+	}
+	if filter.kept["Fake"] != 2 {
+		t.Errorf("Expected 2 kept items, got %d", filter.kept["Fake"])
+	}
+}
+
+func TestNotifyManagers_OnlyAfterDeletions(t *testing.T) {
+	manager := &fakeManager{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	library := t.TempDir()
+
+	notifyManagers(context.Background(), []libraryManager{manager}, &cleanup.DeletionReport{}, logger)
+	if len(manager.rescans) != 0 {
+		t.Errorf("Expected no rescan without deletions, got %v", manager.rescans)
+	}
+
+	deleted := filepath.Join(library, "Studio", "Gone (2001)")
+	report := &cleanup.DeletionReport{Deleted: []cleanup.Finding{{Category: cleanup.CategoryOrphanedFolder, Path: deleted}}}
+	if errs := notifyManagers(context.Background(), []libraryManager{manager}, report, logger); len(errs) != 0 {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if len(manager.rescans) != 1 || len(manager.rescans[0]) != 1 || manager.rescans[0][0] != deleted {
+		t.Errorf("Expected one rescan of %s, got %v", deleted, manager.rescans)
+	}
+}
+
+func TestPathMap_Remote(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "Movies")
+	m := pathMap{{Remote: "/data/movies/", Local: local}, {Remote: `D:\TV`, Local: filepath.Join(local, "..", "TV")}}
+
+	tests := []struct {
+		local, expected string
+	}{
+		{filepath.Join(local, "Studio A"), "/data/movies/Studio A"},
+		{local, "/data/movies"},
+		{filepath.Join(local, "..", "TV", "Show", "Season 01"), `D:\TV\Show\Season 01`},
+		{"/elsewhere", "/elsewhere"},
+	}
+	for _, tt := range tests {
+		if got := m.Remote(tt.local); got != tt.expected {
+			t.Errorf("Remote(%q): expected %s, got %s", tt.local, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// metricsPrefix starts the name of every metric served at /metrics.
+const metricsPrefix = "video_folder_cleanup_"
+
+// runMetrics keeps the figures of daemon runs, by library root, for
+// Prometheus. Gauges describe the last scan of a library; counters add up
+// every run since the daemon started. A nil *runMetrics records nothing.
+type runMetrics struct {
+	mu        sync.Mutex
+	libraries map[string]*libraryMetrics
+	runs      map[int]int // by exit code
+	lastRun   time.Time
+	lastTook  time.Duration
+}
+
+// libraryMetrics are the figures of one library.
+type libraryMetrics struct {
+	findings     map[cleanup.Category]int
+	scanDuration time.Duration
+	scanErrors   int
+	deleted      int
+	reclaimed    int64
+}
+
+func newRunMetrics() *runMetrics {
+	return &runMetrics{libraries: map[string]*libraryMetrics{}, runs: map[int]int{}}
+}
+
+func (m *runMetrics) library(root string) *libraryMetrics {
+	if m.libraries[root] == nil {
+		m.libraries[root] = &libraryMetrics{findings: map[cleanup.Category]int{}}
+	}
+	return m.libraries[root]
+}
+
+// scanned records the scan of the library at root: how long it took, the
+// findings in result and the folders that could not be read.
+func (m *runMetrics) scanned(root string, took time.Duration, result *cleanup.CleanupResult, errors int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	library := m.library(root)
+	library.scanDuration = took
+	library.scanErrors = errors
+	library.findings = map[cleanup.Category]int{}
+	for _, f := range result.Findings {
+		library.findings[f.Category]++
+	}
+}
+
+// deleted adds the items of report, and the space they held, to the
+// counters of their library.
+func (m *runMetrics) deleted(report *cleanup.DeletionReport) {
+	if m == nil || report == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range report.Deleted {
+		library := m.library(f.Library)
+		library.deleted++
+		library.reclaimed += f.Reclaimable
+	}
+}
+
+// finished records a run that started at start and exited with code.
+func (m *runMetrics) finished(code int, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[code]++
+	m.lastRun = start
+	m.lastTook = time.Since(start)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *runMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+func (m *runMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	roots := make([]string, 0, len(m.libraries))
+	for root := range m.libraries {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	perLibrary := func(name, kind, help string, value func(*libraryMetrics) any) {
+		header(w, name, kind, help)
+		for _, root := range roots {
+			fmt.Fprintf(w, "%s%s{library=%s} %v\n", metricsPrefix, name, labelValue(root), value(m.libraries[root]))
+		}
+	}
+
+	header(w, "findings", "gauge", "Findings of the last scan of the library, by category.")
+	for _, root := range roots {
+		for _, category := range cleanup.Categories {
+			fmt.Fprintf(w, "%sfindings{library=%s,category=%s} %d\n", metricsPrefix, labelValue(root), labelValue(string(category)), m.libraries[root].findings[category])
+		}
+	}
+	perLibrary("scan_duration_seconds", "gauge", "How long the last scan of the library took.", func(l *libraryMetrics) any { return l.scanDuration.Seconds() })
+	perLibrary("scan_errors", "gauge", "Folders the last scan of the library could not read.", func(l *libraryMetrics) any { return l.scanErrors })
+	perLibrary("deleted_items_total", "counter", "Items deleted from the library.", func(l *libraryMetrics) any { return l.deleted })
+	perLibrary("reclaimed_bytes_total", "counter", "Disk space freed by deleting items from the library.", func(l *libraryMetrics) any { return l.reclaimed })
+
+	header(w, "runs_total", "counter", "Runs since the daemon started, by exit code.")
+	codes := make([]int, 0, len(m.runs))
+	for code := range m.runs {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "%sruns_total{exit_code=\"%d\"} %d\n", metricsPrefix, code, m.runs[code])
+	}
+	if !m.lastRun.IsZero() {
+		header(w, "last_run_timestamp_seconds", "gauge", "When the last run started, in seconds since the epoch.")
+		fmt.Fprintf(w, "%slast_run_timestamp_seconds %d\n", metricsPrefix, m.lastRun.Unix())
+		header(w, "last_run_duration_seconds", "gauge", "How long the last run took, scans and deletions included.")
+		fmt.Fprintf(w, "%slast_run_duration_seconds %v\n", metricsPrefix, m.lastTook.Seconds())
+	}
+}
+
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+}
+
+// labelValue quotes s as a Prometheus label value.
+func labelValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// serveMetrics serves m at http://addr/metrics for as long as the process
+// runs. A port that cannot be listened on ends the process, as the daemon
+// would otherwise run unobserved.
+func serveMetrics(addr string, m *runMetrics, logger *slog.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	logger.Info("serving metrics", "addr", addr)
+	if err := server.ListenAndServe(); err != nil {
+		logger.Error("metrics server stopped", "error", err)
+		fmt.Fprintf(os.Stderr, "Cannot serve metrics on %s: %v\n", addr, err)
+		os.Exit(exitFailure)
+	}
+}
+
+// runDaemon repeats run on schedule until ctx is done, once the run in
+// progress, if any, has stopped. A first run that cannot be carried out at
+// all ends the daemon, as its flags or services are likely wrong; later ones
+// are tried again at the next run.
+func runDaemon(ctx context.Context, s schedule, m *runMetrics, logger *slog.Logger, run func() int) {
+	next := s.next(time.Time{})
+	for first := true; ; first = false {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Info("daemon stopped", "reason", context.Cause(ctx))
+			return
+		}
+		start := time.Now()
+		code := run()
+		m.finished(code, start)
+		if first && code == exitFailure {
+			os.Exit(code)
+		}
+		if ctx.Err() != nil {
+			logger.Info("daemon stopped", "reason", context.Cause(ctx), "exit_code", code)
+			return
+		}
+		next = s.next(start)
+		logger.Info("waiting for the next run", "exit_code", code, "next", next)
+	}
+}
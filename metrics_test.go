@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for the daemon metrics
+// ============================================================================
+
+func TestRunMetrics(t *testing.T) {
+	m := newRunMetrics()
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/mnt/Movies/A/B (2001)", Library: "/mnt/Movies"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/mnt/Movies/A/C (2002)", Library: "/mnt/Movies"})
+	m.scanned("/mnt/Movies", 1500*time.Millisecond, result, 1)
+	for i := 0; i < 2; i++ {
+		m.deleted(&cleanup.DeletionReport{Deleted: []cleanup.Finding{
+			{Category: cleanup.CategoryOrphanedFolder, Path: "/mnt/Movies/A/B (2001)", Library: "/mnt/Movies", Usage: cleanup.Usage{Reclaimable: 1024}},
+		}})
+	}
+	m.finished(exitClean, time.Unix(1700000000, 0))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		`video_folder_cleanup_findings{library="/mnt/Movies",category="orphaned_folder"} 2`,
+		`video_folder_cleanup_findings{library="/mnt/Movies",category="empty_folder"} 0`,
+		`video_folder_cleanup_scan_duration_seconds{library="/mnt/Movies"} 1.5`,
+		`video_folder_cleanup_scan_errors{library="/mnt/Movies"} 1`,
+		`video_folder_cleanup_deleted_items_total{library="/mnt/Movies"} 2`,
+		`video_folder_cleanup_reclaimed_bytes_total{library="/mnt/Movies"} 2048`,
+		`video_folder_cleanup_runs_total{exit_code="0"} 1`,
+		`video_folder_cleanup_last_run_timestamp_seconds 1700000000`,
+		`# TYPE video_folder_cleanup_deleted_items_total counter`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in:\n%s", line, body)
+		}
+	}
+
+	// A rescan replaces the gauges but keeps the counters
+	m.scanned("/mnt/Movies", time.Second, &cleanup.CleanupResult{}, 0)
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body = rec.Body.String()
+	for _, line := range []string{
+		`video_folder_cleanup_findings{library="/mnt/Movies",category="orphaned_folder"} 0`,
+		`video_folder_cleanup_deleted_items_total{library="/mnt/Movies"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q after a rescan, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestRunMetrics_Nil(t *testing.T) {
+	var m *runMetrics
+	m.scanned("/mnt/Movies", time.Second, &cleanup.CleanupResult{}, 0)
+	m.deleted(&cleanup.DeletionReport{})
+	m.finished(exitClean, time.Now())
+}
+
+func TestLabelValue(t *testing.T) {
+	if got := labelValue("C:\\Movies \"4K\"\n"); got != `"C:\\Movies \"4K\"\n"` {
+		t.Errorf("Expected escaped label, got %s", got)
+	}
+}
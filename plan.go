@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// defaultPlanKeyPath returns where the key signing plans is kept unless
+// --plan-key says otherwise.
+func defaultPlanKeyPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, serviceName, "plan.key"), nil
+}
+
+// loadPlanKey reads the plan signing key at path, or at defaultPlanKeyPath
+// if path is empty. With create, a missing key is generated and saved
+// readable only by its owner; "plan" creates it, "apply" only ever reads it.
+func loadPlanKey(path string, create bool) ([]byte, error) {
+	if path == "" {
+		var err error
+		if path, err = defaultPlanKeyPath(); err != nil {
+			return nil, err
+		}
+	}
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) == 0 {
+			return nil, fmt.Errorf("plan key %s is empty", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) || !create {
+		return nil, fmt.Errorf("cannot read plan key: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// writePlan writes the deletions of result to a plan at path, signed with
+// the key at keyPath.
+func writePlan(path, keyPath string, result *cleanup.CleanupResult, now time.Time) (*cleanup.Plan, error) {
+	key, err := loadPlanKey(keyPath, true)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := cleanup.NewPlan(result, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := plan.Sign(key); err != nil {
+		return nil, err
+	}
+	return plan, plan.Write(path)
+}
+
+// readVerifiedPlan reads the plan at path and checks it against the key at
+// keyPath and the filesystem. A plan moving items into a trash or
+// quarantine directory inside one of its libraries is refused like --trash
+// and --quarantine would be.
+func readVerifiedPlan(path, keyPath string, strategy cleanup.DeleteStrategy) (*cleanup.Plan, error) {
+	plan, err := cleanup.ReadPlan(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadPlanKey(keyPath, false)
+	if err != nil {
+		return nil, err
+	}
+	if checker, ok := strategy.(interface{ CheckLibraries([]string) error }); ok {
+		if err := checker.CheckLibraries(plan.Libraries); err != nil {
+			return nil, err
+		}
+	}
+	if err := plan.Verify(key); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
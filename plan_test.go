@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for loadPlanKey
+// ============================================================================
+
+func TestLoadPlanKey_CreatesOnlyWhenAsked(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	keyPath := filepath.Join(tempDir, "config", "plan.key")
+
+	if _, err := loadPlanKey(keyPath, false); err == nil {
+		t.Fatal("Expected an error for a missing key without create")
+	}
+	key, err := loadPlanKey(keyPath, true)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(key) != 32 {
+		t.Errorf("Expected a 32-byte key, got %d bytes", len(key))
+	}
+	again, err := loadPlanKey(keyPath, false)
+	if err != nil || !bytes.Equal(again, key) {
+		t.Errorf("Expected the saved key to be read back, got %x (%v)", again, err)
+	}
+}
+
+// ============================================================================
+// Tests for writePlan and readVerifiedPlan
+// ============================================================================
+
+func TestReadVerifiedPlan_RoundTrip(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	library := filepath.Join(tempDir, "Library")
+	orphan := filepath.Join(library, "Studio", "Orphaned")
+	createFile(t, filepath.Join(orphan, "movie.nfo"))
+	keyPath := filepath.Join(tempDir, "plan.key")
+	planPath := filepath.Join(tempDir, "plan.json")
+
+	result := &cleanup.CleanupResult{Libraries: []string{library}}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: orphan, Library: library})
+	if _, err := writePlan(planPath, keyPath, result, time.Now()); err != nil {
+		t.Fatalf("writePlan failed: %v", err)
+	}
+
+	plan, err := readVerifiedPlan(planPath, keyPath, cleanup.PermanentStrategy{})
+	if err != nil {
+		t.Fatalf("readVerifiedPlan failed: %v", err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Path != orphan {
+		t.Errorf("Expected one action for %s, got %+v", orphan, plan.Actions)
+	}
+
+	createFile(t, filepath.Join(orphan, "movie.mkv"))
+	var changed *cleanup.PlanChangedError
+	if _, err := readVerifiedPlan(planPath, keyPath, cleanup.PermanentStrategy{}); !errors.As(err, &changed) {
+		t.Errorf("Expected a PlanChangedError, got %v", err)
+	}
+}
+
+func TestReadVerifiedPlan_RejectsTrashInsideLibrary(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	library := filepath.Join(tempDir, "Library")
+	createFile(t, filepath.Join(library, "Studio", "Orphaned", "movie.nfo"))
+	keyPath := filepath.Join(tempDir, "plan.key")
+	planPath := filepath.Join(tempDir, "plan.json")
+
+	result := &cleanup.CleanupResult{Libraries: []string{library}}
+	if _, err := writePlan(planPath, keyPath, result, time.Now()); err != nil {
+		t.Fatalf("writePlan failed: %v", err)
+	}
+
+	trash := cleanup.TrashDirStrategy{Dir: filepath.Join(library, ".trash")}
+	if _, err := readVerifiedPlan(planPath, keyPath, trash); err == nil {
+		t.Error("Expected an error for a trash directory inside the library")
+	}
+}
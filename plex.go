@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// plexClient is a libraryManager backed by a Plex Media Server: findings
+// that would take away a video Plex still lists as available are kept, and
+// with scan set, Plex rescans the folders deletions happened in.
+type plexClient struct {
+	baseURL   string
+	token     string
+	libraries []string // scanned libraries; only sections overlapping them are read, all if empty
+	scan      bool
+	paths     pathMap
+	client    *http.Client
+
+	sections []plexSection
+	files    []plexFile
+}
+
+// plexSection is a Plex library section and its folders, as local paths.
+type plexSection struct {
+	Key       string
+	Type      string // movie or show
+	Title     string
+	Locations []string
+}
+
+// plexFile is a video file of an available item of a section.
+type plexFile struct {
+	Path  string
+	Title string
+}
+
+// newPlex returns the client of the Plex Media Server at baseURL. paths maps
+// the folders Plex reports to the scanned ones.
+func newPlex(baseURL, token string, libraries []string, scan bool, paths pathMap) *plexClient {
+	abs := make([]string, 0, len(libraries))
+	for _, lib := range libraries {
+		if path, err := filepath.Abs(lib); err == nil {
+			abs = append(abs, path)
+		}
+	}
+	return &plexClient{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		token:     token,
+		libraries: abs,
+		scan:      scan,
+		paths:     paths,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (c *plexClient) Name() string { return "Plex" }
+
+// Load reads the sections whose folders overlap the scanned libraries, and
+// the files of their movies or episodes. Parts Plex reports as missing or
+// inaccessible are left out.
+func (c *plexClient) Load(ctx context.Context) error {
+	var sections struct {
+		MediaContainer struct {
+			Directory []struct {
+				Key      string `json:"key"`
+				Type     string `json:"type"`
+				Title    string `json:"title"`
+				Location []struct {
+					Path string `json:"path"`
+				} `json:"Location"`
+			} `json:"Directory"`
+		} `json:"MediaContainer"`
+	}
+	if err := c.get(ctx, "/library/sections", nil, &sections); err != nil {
+		return err
+	}
+
+	for _, dir := range sections.MediaContainer.Directory {
+		section := plexSection{Key: dir.Key, Type: dir.Type, Title: dir.Title}
+		overlaps := len(c.libraries) == 0
+		for _, location := range dir.Location {
+			path := c.paths.Local(location.Path)
+			section.Locations = append(section.Locations, path)
+			for _, lib := range c.libraries {
+				overlaps = overlaps || cleanup.IsWithin(path, lib) || cleanup.IsWithin(lib, path)
+			}
+		}
+		if !overlaps {
+			continue
+		}
+		c.sections = append(c.sections, section)
+
+		// Plex item types: 1 is a movie, 4 an episode
+		itemType := "1"
+		if section.Type == "show" {
+			itemType = "4"
+		} else if section.Type != "movie" {
+			continue
+		}
+		var items struct {
+			MediaContainer struct {
+				Metadata []struct {
+					Title            string `json:"title"`
+					GrandparentTitle string `json:"grandparentTitle"`
+					Media            []struct {
+						Part []struct {
+							File       string `json:"file"`
+							Exists     *bool  `json:"exists"`
+							Accessible *bool  `json:"accessible"`
+						} `json:"Part"`
+					} `json:"Media"`
+				} `json:"Metadata"`
+			} `json:"MediaContainer"`
+		}
+		if err := c.get(ctx, "/library/sections/"+url.PathEscape(section.Key)+"/all", url.Values{"type": {itemType}}, &items); err != nil {
+			return err
+		}
+		for _, item := range items.MediaContainer.Metadata {
+			title := item.Title
+			if item.GrandparentTitle != "" {
+				title = item.GrandparentTitle + " - " + item.Title
+			}
+			for _, media := range item.Media {
+				for _, part := range media.Part {
+					if part.File == "" || (part.Exists != nil && !*part.Exists) || (part.Accessible != nil && !*part.Accessible) {
+						continue
+					}
+					c.files = append(c.files, plexFile{Path: c.paths.Local(part.File), Title: title})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Wanted reports the available item whose video is inside path, or whose
+// video shares the folder and name of the file at path (its NFO, poster or
+// subtitles).
+func (c *plexClient) Wanted(path string) (string, bool) {
+	dir, base := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	for _, file := range c.files {
+		if cleanup.IsWithin(path, file.Path) {
+			return file.Title, true
+		}
+		if filepath.Dir(file.Path) == dir {
+			name := filepath.Base(file.Path)
+			stem := strings.TrimSuffix(name, filepath.Ext(name))
+			if rest, ok := strings.CutPrefix(base, stem); ok && stem != "" && (rest == "" || strings.ContainsRune(".-_", rune(rest[0]))) {
+				return file.Title, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Rescan asks Plex to scan the folder each deleted path was in, once per
+// folder, if partial scans were requested. Paths outside every section are
+// ignored.
+func (c *plexClient) Rescan(ctx context.Context, deleted []string) error {
+	if !c.scan {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, path := range deleted {
+		dir := filepath.Dir(path)
+		for _, section := range c.sections {
+			for _, location := range section.Locations {
+				if !cleanup.IsWithin(location, dir) || seen[section.Key+"\x00"+dir] {
+					continue
+				}
+				seen[section.Key+"\x00"+dir] = true
+				// Plex expects the folder as it sees it
+				remote := c.paths.Remote(dir)
+				if err := c.get(ctx, "/library/sections/"+url.PathEscape(section.Key)+"/refresh", url.Values{"path": {remote}}, nil); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// get sends a GET request authenticated with the token; see callJSON.
+func (c *plexClient) get(ctx context.Context, path string, query url.Values, out any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return callJSON(ctx, c.client, http.MethodGet, u, http.Header{"X-Plex-Token": {c.token}}, nil, out)
+}
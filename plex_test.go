@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fakePlex serves one movie section at /data/movies and one show section
+// elsewhere, and records the partial scans it is asked for.
+func fakePlex(t *testing.T) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var scans []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/library/sections":
+			w.Write([]byte(`{"MediaContainer": {"Directory": [
+				{"key": "1", "type": "movie", "title": "Movies", "Location": [{"path": "/data/movies"}]},
+				{"key": "2", "type": "show", "title": "TV", "Location": [{"path": "/data/tv"}]}]}}`))
+		case "/library/sections/1/all":
+			if r.URL.Query().Get("type") != "1" {
+				t.Errorf("Expected movies to be listed, got type %s", r.URL.Query().Get("type"))
+			}
+			json.NewEncoder(w).Encode(map[string]any{"MediaContainer": map[string]any{"Metadata": []any{
+				map[string]any{"title": "Kept", "Media": []any{map[string]any{"Part": []any{map[string]any{"file": "/data/movies/Studio/Kept (2019)/Kept.mkv"}}}}},
+				map[string]any{"title": "Missing", "Media": []any{map[string]any{"Part": []any{map[string]any{"file": "/data/movies/Studio/Missing (2001)/Missing.mkv", "exists": false}}}}},
+			}}})
+		case "/library/sections/2/all":
+			t.Error("Expected the TV section outside the scanned library not to be read")
+		case "/library/sections/1/refresh":
+			mu.Lock()
+			scans = append(scans, r.URL.Query().Get("path"))
+			mu.Unlock()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &scans
+}
+
+// ============================================================================
+// Tests for plexClient
+// ============================================================================
+
+func TestPlex_WantedAvailableItems(t *testing.T) {
+	library := t.TempDir()
+	server, _ := fakePlex(t)
+	plex := newPlex(server.URL, "token", []string{library}, false, pathMap{{Remote: "/data/movies", Local: library}})
+	if err := plex.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		wanted bool
+	}{
+		{filepath.Join(library, "Studio", "Kept (2019)"), true},
+		{filepath.Join(library, "Studio", "Kept (2019)", "Kept.nfo"), true},
+		{filepath.Join(library, "Studio", "Kept (2019)", "Kept-poster.jpg"), true},
+		{filepath.Join(library, "Studio", "Kept (2019)", "Kept 2.nfo"), false},
+		{filepath.Join(library, "Studio", "Kept (2019)", "extrafanart"), false},
+		{filepath.Join(library, "Studio", "Missing (2001)"), false},
+	}
+	for _, tt := range tests {
+		if _, wanted := plex.Wanted(tt.path); wanted != tt.wanted {
+			t.Errorf("Wanted(%s): expected %v, got %v", tt.path, tt.wanted, wanted)
+		}
+	}
+}
+
+func TestPlex_ScansFoldersOfDeletedItems(t *testing.T) {
+	library := t.TempDir()
+	server, scans := fakePlex(t)
+	plex := newPlex(server.URL, "token", []string{library}, true, pathMap{{Remote: "/data/movies", Local: library}})
+	if err := plex.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	deleted := []string{
+		filepath.Join(library, "Studio", "Missing (2001)"),
+		filepath.Join(library, "Studio", "Gone (1999)"),
+		filepath.Join(library, "Studio", "Kept (2019)", "Kept 2.nfo"),
+	}
+	if err := plex.Rescan(context.Background(), deleted); err != nil {
+		t.Fatalf("Rescan failed: %v", err)
+	}
+	expected := []string{"/data/movies/Studio", "/data/movies/Studio/Kept (2019)"}
+	if len(*scans) != len(expected) || (*scans)[0] != expected[0] || (*scans)[1] != expected[1] {
+		t.Errorf("Expected scans of %v, got %v", expected, *scans)
+	}
+}
+
+func TestPlex_NoScanUnlessAsked(t *testing.T) {
+	library := t.TempDir()
+	server, scans := fakePlex(t)
+	plex := newPlex(server.URL, "token", []string{library}, false, pathMap{{Remote: "/data/movies", Local: library}})
+	if err := plex.Load(context.Background()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if err := plex.Rescan(context.Background(), []string{filepath.Join(library, "Studio", "Gone (1999)")}); err != nil {
+		t.Fatalf("Rescan failed: %v", err)
+	}
+	if len(*scans) != 0 {
+		t.Errorf("Expected no scan without --plex-scan, got %v", *scans)
+	}
+}
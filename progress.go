@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// spinnerFrames are drawn one after the other while a scan is running.
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// progressBarWidth is the number of cells of the studio progress bar.
+const progressBarWidth = 20
+
+// scanProgress redraws a single status line with the studios processed,
+// titles scanned and items found while libraries are being scanned. Feed
+// it scanner events with event and findings with foundOne; it is safe for
+// concurrent use.
+type scanProgress struct {
+	w      io.Writer
+	lang   *Language
+	format string // counts line, worded after the layout
+
+	mu        sync.Mutex
+	studios   int // top-level folders of the libraries started so far
+	done      int
+	titles    int
+	found     int
+	frame     int
+	lastWidth int
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+// newScanProgress returns a display writing to w for libraries with the
+// given layout. Nothing is drawn before start.
+func newScanProgress(w io.Writer, lang *Language, layout cleanup.Layout) *scanProgress {
+	format := "%d/%d studios, %d titles scanned, %d items found"
+	switch layout.Name {
+	case cleanup.TVLayout.Name:
+		format = "%d/%d shows, %d seasons scanned, %d items found"
+	case cleanup.FlatLayout.Name:
+		// Every top-level folder is a title
+		format = "%d/%d titles scanned, %[4]d items found"
+	}
+	return &scanProgress{w: w, lang: lang, format: format, stop: make(chan struct{}), stopped: make(chan struct{})}
+}
+
+// start redraws the line every interval until finish is called.
+func (p *scanProgress) start(interval time.Duration) {
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.draw()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// isTerminal reports whether f is attached to a terminal rather than a
+// file or pipe, where a redrawn line would only add noise.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// event records a scanner progress event.
+func (p *scanProgress) event(ev cleanup.ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch ev := ev.(type) {
+	case cleanup.LibraryStarted:
+		p.studios += ev.Studios
+	case cleanup.StudioScanned:
+		p.done++
+	case cleanup.TitleScanned:
+		p.titles++
+	}
+}
+
+// foundOne records a finding.
+func (p *scanProgress) foundOne() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.found++
+}
+
+// line renders the status line for the current counts.
+func (p *scanProgress) line() string {
+	filled := 0
+	if p.studios > 0 {
+		filled = p.done * progressBarWidth / p.studios
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+	return fmt.Sprintf("%c %s ", spinnerFrames[p.frame%len(spinnerFrames)], bar) +
+		p.lang.Sprintf(p.format, p.done, p.studios, p.titles, p.found)
+}
+
+func (p *scanProgress) draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frame++
+	line := p.line()
+	width := len([]rune(line))
+	// Pad over what is left of a longer previous line
+	fmt.Fprintf(p.w, "\r%s%s", line, strings.Repeat(" ", max(p.lastWidth-width, 0)))
+	p.lastWidth = width
+}
+
+// finish ends the redraws and clears the status line, so the report starts
+// on a clean line.
+func (p *scanProgress) finish() {
+	close(p.stop)
+	<-p.stopped
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lastWidth > 0 {
+		fmt.Fprintf(p.w, "\r%s\r", strings.Repeat(" ", p.lastWidth))
+	}
+}
+
+// traceEvent writes what the scan decided about a folder, as ev tells it, on
+// a line of its own to w, for --verbose.
+func traceEvent(w io.Writer, lang *Language, ev cleanup.ProgressEvent) {
+	switch ev := ev.(type) {
+	case cleanup.LibraryStarted:
+		if ev.Resumed > 0 {
+			lang.Fprintf(w, "%s: %d top-level folders to scan, %d finished by the interrupted scan\n", ev.Library, ev.Studios, ev.Resumed)
+			return
+		}
+		lang.Fprintf(w, "%s: %d top-level folders to scan\n", ev.Library, ev.Studios)
+	case cleanup.FolderSkipped:
+		lang.Fprintf(w, "%s: skipped, %s\n", ev.Path, lang.T(ev.Reason))
+	case cleanup.TitleScanned:
+		fmt.Fprintf(w, "%s: %s\n", ev.Path, lang.T(ev.Decision))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+	"video-folder-cleanup/cleanuptest"
+)
+
+// ============================================================================
+// Tests for scanProgress
+// ============================================================================
+
+func TestScanProgress_CountsScannerEvents(t *testing.T) {
+	fsys := cleanuptest.New().
+		Studio("Studio A").
+		Title("Movie (2001)").Video("movie.mkv").
+		Title("Orphan (2002)").Metadata("movie.nfo").
+		Studio("Studio B").
+		Title("Other (2003)").Video("other.mkv").
+		MapFS()
+
+	progress := newScanProgress(&bytes.Buffer{}, English, cleanup.MovieLayout)
+	scanner := cleanup.NewScanner(cleanup.WithFS(cleanup.IOFS(fsys)), cleanup.WithProgress(progress.event))
+	err := scanner.Scan(context.Background(), ".", func(cleanup.Finding) error {
+		progress.foundOne()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	expected := "2/2 studios, 3 titles scanned, 1 items found"
+	if line := progress.line(); !strings.HasSuffix(line, expected) {
+		t.Errorf("Expected a line ending with %q, got %q", expected, line)
+	}
+	if line := progress.line(); !strings.Contains(line, strings.Repeat("█", progressBarWidth)) {
+		t.Errorf("Expected a full bar, got %q", line)
+	}
+}
+
+func TestScanProgress_TVWording(t *testing.T) {
+	progress := newScanProgress(&bytes.Buffer{}, English, cleanup.TVLayout)
+	progress.event(cleanup.LibraryStarted{Studios: 4})
+	progress.event(cleanup.StudioScanned{})
+
+	expected := "1/4 shows, 0 seasons scanned, 0 items found"
+	if line := progress.line(); !strings.HasSuffix(line, expected) {
+		t.Errorf("Expected a line ending with %q, got %q", expected, line)
+	}
+}
+
+func TestScanProgress_FlatWording(t *testing.T) {
+	progress := newScanProgress(&bytes.Buffer{}, English, cleanup.FlatLayout)
+	progress.event(cleanup.LibraryStarted{Studios: 4})
+	progress.event(cleanup.StudioScanned{})
+	progress.event(cleanup.TitleScanned{})
+
+	expected := "1/4 titles scanned, 0 items found"
+	if line := progress.line(); !strings.HasSuffix(line, expected) {
+		t.Errorf("Expected a line ending with %q, got %q", expected, line)
+	}
+}
+
+func TestTraceEvent(t *testing.T) {
+	var buf bytes.Buffer
+	traceEvent(&buf, English, cleanup.LibraryStarted{Library: "/lib", Studios: 2})
+	traceEvent(&buf, English, cleanup.FolderSkipped{Path: "/lib/Staging", Reason: cleanup.SkippedByFilter})
+	traceEvent(&buf, English, cleanup.TitleScanned{Path: "/lib/S/A", Decision: cleanup.DecisionOrphaned})
+	traceEvent(&buf, English, cleanup.StudioScanned{Path: "/lib/S"})
+
+	expected := "/lib: 2 top-level folders to scan\n" +
+		"/lib/Staging: skipped, left out by the include and exclude patterns\n" +
+		"/lib/S/A: no video, reported as orphaned\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected trace:\n got: %q\nwant: %q", buf.String(), expected)
+	}
+}
+
+func TestScanProgress_FinishClearsLine(t *testing.T) {
+	var buf bytes.Buffer
+	progress := newScanProgress(&buf, English, cleanup.MovieLayout)
+	progress.start(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	progress.finish()
+
+	output := buf.String()
+	if !strings.Contains(output, "studios") {
+		t.Fatalf("Expected the status line to be drawn, got %q", output)
+	}
+	// The last redraw is overwritten with spaces, then the cursor goes back
+	segments := strings.Split(output, "\r")
+	if cleared := segments[len(segments)-2]; cleared == "" || strings.TrimSpace(cleared) != "" || segments[len(segments)-1] != "" {
+		t.Errorf("Expected the line to end cleared, got %q", output)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// remoteLibrary is a library given as a URL and read through its own
+// filesystem rather than from the local disk.
+type remoteLibrary struct {
+	root     string // the folder to scan, as the remote filesystem names it
+	fsys     cleanup.FS
+	strategy cleanup.DeleteStrategy // nil if nothing can be changed there
+}
+
+// isRemote reports whether path is the URL of a remote library.
+func isRemote(path string) bool {
+	return isSFTPURL(path) || cleanup.IsSSHURL(path) || cleanup.IsS3URL(path) || cleanup.IsRcloneURL(path) || cleanup.IsWebDAVURL(path)
+}
+
+// isSFTPURL reports whether path uses the sftp:// scheme, which is refused:
+// SSHFS needs a shell on the remote host, not just the SFTP subsystem.
+func isSFTPURL(path string) bool {
+	return strings.HasPrefix(strings.ToLower(path), "sftp://")
+}
+
+// remoteLibraries sets up the ssh://, s3://, rclone: and webdav[s]://
+// libraries among paths, by URL. Findings are filed under the remote path
+// that was scanned, so it must not be the path of another library.
+func remoteLibraries(paths []string, s3 *cleanup.S3Client) (map[string]remoteLibrary, error) {
+	remotes := map[string]remoteLibrary{}
+	roots := map[string]string{}
+	for _, path := range paths {
+		root := path
+		switch {
+		case isSFTPURL(path):
+			return nil, fmt.Errorf("%s: sftp:// is not supported, use ssh:// with an account that has a shell, or an rclone sftp remote", path)
+		case cleanup.IsSSHURL(path):
+			target, err := cleanup.ParseSSHURL(path)
+			if err != nil {
+				return nil, err
+			}
+			remotes[path] = remoteLibrary{root: target.Path, fsys: &cleanup.SSHFS{Target: target}}
+			root = target.Path
+		case cleanup.IsS3URL(path):
+			target, err := cleanup.ParseS3URL(path)
+			if err != nil {
+				return nil, err
+			}
+			remotes[path] = remoteLibrary{root: target.Root(), fsys: &cleanup.S3FS{Client: s3, Target: target},
+				strategy: cleanup.S3Strategy{Client: s3, Bucket: target.Bucket}}
+			root = target.Root()
+		case cleanup.IsRcloneURL(path):
+			target, err := cleanup.ParseRcloneURL(path)
+			if err != nil {
+				return nil, err
+			}
+			remotes[path] = remoteLibrary{root: target.Root(), fsys: &cleanup.RcloneFS{Target: target},
+				strategy: cleanup.RcloneStrategy{Target: target}}
+			root = target.Root()
+		case cleanup.IsWebDAVURL(path):
+			target, err := cleanup.ParseWebDAVURL(path)
+			if err != nil {
+				return nil, err
+			}
+			client := &cleanup.WebDAVClient{Endpoint: target.Endpoint, User: target.User, Password: os.Getenv("WEBDAV_PASSWORD")}
+			remotes[path] = remoteLibrary{root: target.Path, fsys: &cleanup.WebDAVFS{Client: client},
+				strategy: cleanup.WebDAVStrategy{Client: client}}
+			root = target.Path
+		}
+		if other, ok := roots[root]; ok && other != path {
+			return nil, fmt.Errorf("libraries %s and %s have the same path", other, path)
+		}
+		roots[root] = path
+	}
+	return remotes, nil
+}
+
+// s3Client returns the client for s3:// libraries. Credentials come from
+// the usual AWS environment variables; the endpoint defaults to AWS in the
+// region.
+func s3Client(endpoint, region string) *cleanup.S3Client {
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	return &cleanup.S3Client{
+		Endpoint:     endpoint,
+		Region:       region,
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// scanRoot returns the folder to scan for the library path: the remote path
+// of a remote library, path itself otherwise.
+func scanRoot(remotes map[string]remoteLibrary, path string) string {
+	if remote, ok := remotes[path]; ok {
+		return remote.root
+	}
+	return path
+}
+
+// checkProtected is protected.Check reading the folders of findings of
+// remote libraries from their remote filesystem.
+func checkProtected(protected cleanup.ProtectedPaths, remotes map[string]remoteLibrary, findings []cleanup.Finding) error {
+	var local []cleanup.Finding
+	var urls []string
+	byRemote := map[string][]cleanup.Finding{}
+	for _, f := range findings {
+		url, ok := remoteFor(remotes, f.Library)
+		if !ok {
+			local = append(local, f)
+			continue
+		}
+		if _, seen := byRemote[url]; !seen {
+			urls = append(urls, url)
+		}
+		byRemote[url] = append(byRemote[url], f)
+	}
+	touched := &cleanup.ProtectedError{}
+	for _, url := range urls {
+		if err := protected.CheckFS(remotes[url].fsys, byRemote[url]); err != nil {
+			touched.Findings = append(touched.Findings, err.(*cleanup.ProtectedError).Findings...)
+		}
+	}
+	if err := protected.Check(local); err != nil {
+		touched.Findings = append(touched.Findings, err.(*cleanup.ProtectedError).Findings...)
+	}
+	if len(touched.Findings) > 0 {
+		return touched
+	}
+	return nil
+}
+
+// remoteFor returns the URL of the remote library scanned at root, if any.
+func remoteFor(remotes map[string]remoteLibrary, root string) (string, bool) {
+	for url, remote := range remotes {
+		if remote.root == root {
+			return url, true
+		}
+	}
+	return "", false
+}
+
+// libraryKey identifies a library given on the command line or in a
+// --config file: its absolute path, or its URL if it is remote.
+func libraryKey(path string) (string, error) {
+	if isRemote(path) {
+		return path, nil
+	}
+	return filepath.Abs(path)
+}
+
+// libraryStrategies deletes the findings of remote libraries with their own
+// strategy and everything else with fallback.
+type libraryStrategies struct {
+	fallback  cleanup.DeleteStrategy
+	byLibrary map[string]cleanup.DeleteStrategy // by scan root
+}
+
+func (s libraryStrategies) Name() string { return s.fallback.Name() }
+
+func (s libraryStrategies) strategyFor(library string) cleanup.DeleteStrategy {
+	if strategy, ok := s.byLibrary[library]; ok {
+		return strategy
+	}
+	return s.fallback
+}
+
+func (s libraryStrategies) Delete(f cleanup.Finding) error {
+	return s.strategyFor(f.Library).Delete(f)
+}
+
+// Move moves f with its library's strategy if that one moves items, so the
+// deletion manifest learns where it went.
+func (s libraryStrategies) Move(f cleanup.Finding) (cleanup.Moved, error) {
+	strategy := s.strategyFor(f.Library)
+	if mover, ok := strategy.(cleanup.MovingStrategy); ok {
+		return mover.Move(f)
+	}
+	return cleanup.Moved{}, strategy.Delete(f)
+}
+
+// Exists tells the Deleter whether an item is still there, asking the
+// remote store for its findings.
+func (s libraryStrategies) Exists(path string) (bool, error) {
+	for root, strategy := range s.byLibrary {
+		if checker, ok := strategy.(interface{ Exists(string) (bool, error) }); ok && cleanup.IsWithin(root, path) {
+			return checker.Exists(path)
+		}
+	}
+	_, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for remote libraries
+// ============================================================================
+
+func TestRemoteLibraries(t *testing.T) {
+	paths := []string{"/mnt/Movies", "ssh://me@seedbox/data/Movies", "s3://media/Movies", "rclone:gdrive:Movies", "webdavs://me@cloud/dav/Movies"}
+	remotes, err := remoteLibraries(paths, &cleanup.S3Client{})
+	if err != nil {
+		t.Fatalf("remoteLibraries returned error: %v", err)
+	}
+	if len(remotes) != 4 {
+		t.Fatalf("Expected the seedbox, bucket, drive and cloud libraries, got %+v", remotes)
+	}
+	for path, root := range map[string]string{
+		"ssh://me@seedbox/data/Movies":  "/data/Movies",
+		"s3://media/Movies":             "/media/Movies",
+		"rclone:gdrive:Movies":          "/gdrive/Movies",
+		"webdavs://me@cloud/dav/Movies": "/dav/Movies",
+		"/mnt/Movies":                   "/mnt/Movies",
+	} {
+		if got := scanRoot(remotes, path); got != root {
+			t.Errorf("Expected %s for %s, got %s", root, path, got)
+		}
+	}
+	if remotes["ssh://me@seedbox/data/Movies"].strategy != nil {
+		t.Errorf("Expected ssh:// libraries to be read-only")
+	}
+	if remotes["s3://media/Movies"].strategy == nil {
+		t.Errorf("Expected s3:// libraries to be deletable")
+	}
+	if remotes["rclone:gdrive:Movies"].strategy == nil {
+		t.Errorf("Expected rclone: libraries to be deletable")
+	}
+	if remotes["webdavs://me@cloud/dav/Movies"].strategy == nil {
+		t.Errorf("Expected webdav:// libraries to be deletable")
+	}
+
+	for name, paths := range map[string][]string{
+		"same remote path": {"ssh://a/data/Movies", "ssh://b/data/Movies"},
+		"same local path":  {"/data/Movies", "ssh://a/data/Movies"},
+		"same bucket path": {"s3://media/Movies", "ssh://a/media/Movies"},
+		"no host":          {"ssh:///data/Movies"},
+		"no bucket":        {"s3:///Movies"},
+		"same drive path":  {"rclone:media:Movies", "s3://media/Movies"},
+		"no remote":        {"rclone::Movies"},
+		"no server":        {"webdav:///dav/Movies"},
+		"sftp":             {"sftp://me@seedbox/data/Movies"},
+		"option host":      {"ssh://-oProxyCommand=x/data/Movies"},
+	} {
+		if _, err := remoteLibraries(paths, &cleanup.S3Client{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// recordingStrategy records the findings it is asked to delete.
+type recordingStrategy struct {
+	deleted *[]string
+}
+
+func (recordingStrategy) Name() string { return "recording" }
+
+func (s recordingStrategy) Delete(f cleanup.Finding) error {
+	*s.deleted = append(*s.deleted, f.Path)
+	return nil
+}
+
+func (recordingStrategy) Exists(path string) (bool, error) {
+	return false, errors.New("unreachable")
+}
+
+func TestLibraryStrategies(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	local := filepath.Join(tempDir, "Movies", "stray.nfo")
+	createFile(t, local)
+
+	var remote []string
+	strategies := libraryStrategies{
+		fallback:  cleanup.PermanentStrategy{},
+		byLibrary: map[string]cleanup.DeleteStrategy{"/media/Movies": recordingStrategy{deleted: &remote}},
+	}
+	if strategies.Name() != "permanent" {
+		t.Errorf("Expected the fallback's name, got %s", strategies.Name())
+	}
+	if err := strategies.Delete(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Path: "/media/Movies/a.nfo", Library: "/media/Movies"}); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := strategies.Delete(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Path: local, Library: filepath.Join(tempDir, "Movies")}); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if len(remote) != 1 || remote[0] != "/media/Movies/a.nfo" {
+		t.Errorf("Expected the bucket finding to go to its strategy, got %v", remote)
+	}
+	if exists, err := strategies.Exists(local); exists || err != nil {
+		t.Errorf("Expected the local file to be gone, got %v, %v", exists, err)
+	}
+	if _, err := strategies.Exists("/media/Movies/a.nfo"); err == nil {
+		t.Errorf("Expected the bucket strategy to be asked")
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// printReport writes the human-readable report for result to w.
+func (rw reportWriter) printReport(w io.Writer, result *cleanup.CleanupResult) {
+	lang, style := rw.lang, rw.style
+	if style == nil {
+		style = DefaultReportStyle()
+	}
+	section := func(name string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		lang.Fprintf(w, "\n%s (%d):\n", rw.heading(name), len(items))
+		for _, item := range items {
+			fmt.Fprintf(w, "%s%s\n", style.ItemPrefix, item)
+		}
+	}
+	lines := func(findings []cleanup.Finding) []string {
+		var items []string
+		for _, f := range findings {
+			items = append(items, lang.Finding(f))
+		}
+		return items
+	}
+
+	fmt.Fprintln(w, "\n"+strings.Repeat("=", 60))
+
+	section(string(cleanup.CategoryStructureWarning), lines(result.ByCategory(cleanup.CategoryStructureWarning)))
+	section(string(cleanup.CategoryMisfiledVideo), lines(result.ByCategory(cleanup.CategoryMisfiledVideo)))
+	section(string(cleanup.CategoryDownloadInProgress), lines(result.ByCategory(cleanup.CategoryDownloadInProgress)))
+	section(string(cleanup.CategoryTruncatedVideo), lines(result.ByCategory(cleanup.CategoryTruncatedVideo)))
+	section(string(cleanup.CategoryCorruptVideo), lines(result.ByCategory(cleanup.CategoryCorruptVideo)))
+	section(string(cleanup.CategoryBrokenSymlink), lines(result.ByCategory(cleanup.CategoryBrokenSymlink)))
+	section(string(cleanup.CategoryOrphanedFolder), result.OrphanedFolders)
+	section(string(cleanup.CategoryOrphanedFile), result.OrphanedFiles)
+	section(string(cleanup.CategoryEmptyFolder), result.EmptyFolders)
+	section(string(cleanup.CategoryJunkFile), lines(result.ByCategory(cleanup.CategoryJunkFile)))
+
+	if usage := result.Usage(); usage.Bytes > 0 {
+		lang.Fprintf(w, "\n%s: %s", rw.heading(sectionReclaimableSpace), cleanup.FormatBytes(usage.Reclaimable))
+		if usage.Hardlinked > 0 {
+			lang.Fprintf(w, " (another %s is in %d hardlinked files and stays on disk)",
+				cleanup.FormatBytes(usage.Bytes-usage.Reclaimable), usage.Hardlinked)
+		}
+		fmt.Fprintln(w)
+		// Break the total down once several categories contribute to it
+		var parts []cleanup.Category
+		for _, c := range []cleanup.Category{cleanup.CategoryOrphanedFolder, cleanup.CategoryOrphanedFile, cleanup.CategoryJunkFile} {
+			if result.CategoryUsage(c).Bytes > 0 {
+				parts = append(parts, c)
+			}
+		}
+		if len(parts) > 1 {
+			for _, c := range parts {
+				fmt.Fprintf(w, "%s%s: %s\n", style.ItemPrefix, style.label(string(c), lang), cleanup.FormatBytes(result.CategoryUsage(c).Reclaimable))
+			}
+		}
+		// The top lists come with the order they triage by, or on request
+		if largest := largestOrphans(result); len(largest) > 1 && (rw.sort == "size" || rw.verbose) {
+			lang.Fprintf(w, "\n%s (%d):\n", rw.heading(sectionLargestOrphans), len(largest))
+			for _, f := range largest {
+				fmt.Fprintf(w, "%s%10s  %s\n", style.ItemPrefix, cleanup.FormatBytes(f.Bytes), f.Path)
+			}
+		}
+	}
+	if oldest := oldestOrphans(result); len(oldest) > 1 && (rw.sort == "age" || rw.verbose) {
+		lang.Fprintf(w, "\n%s (%d):\n", rw.heading(sectionOldestOrphans), len(oldest))
+		for _, f := range oldest {
+			fmt.Fprintf(w, "%s%s  %s\n", style.ItemPrefix, f.Modified.Local().Format("2006-01-02 15:04"), f.Path)
+		}
+	}
+
+	section(string(cleanup.CategoryDuplicateVideo), lines(result.ByCategory(cleanup.CategoryDuplicateVideo)))
+	// Only the redundant copies of identical videos carry a size
+	var wasted int64
+	for _, f := range result.ByCategory(cleanup.CategoryDuplicateVideo) {
+		wasted += f.Bytes
+	}
+	if wasted > 0 {
+		lang.Fprintf(w, "\n%s: %s\n", rw.heading(sectionDuplicateWaste), cleanup.FormatBytes(wasted))
+	}
+	section(string(cleanup.CategoryPermissionMismatch), lines(result.ByCategory(cleanup.CategoryPermissionMismatch)))
+	section(string(cleanup.CategoryMetadataMismatch), lines(result.ByCategory(cleanup.CategoryMetadataMismatch)))
+	section(string(cleanup.CategoryMissingMetadata), lines(result.ByCategory(cleanup.CategoryMissingMetadata)))
+	section(string(cleanup.CategoryIncompatibleName), lines(result.ByCategory(cleanup.CategoryIncompatibleName)))
+	section(string(cleanup.CategoryRecycleBin), lines(result.ByCategory(cleanup.CategoryRecycleBin)))
+
+	if len(result.Libraries) > 1 {
+		lang.Fprintf(w, "\n%s:\n", rw.heading(sectionLibrarySummary))
+		for _, lib := range result.Libraries {
+			libResult := result.ForLibrary(lib)
+			fmt.Fprint(w, style.ItemPrefix)
+			lang.Fprintf(w, "%s: %d orphaned folders, %d orphaned files, %d empty folders, %d warnings\n",
+				lib, len(libResult.OrphanedFolders), len(libResult.OrphanedFiles),
+				len(libResult.EmptyFolders), len(libResult.StructureWarnings))
+		}
+	}
+
+	var errs []string
+	for _, err := range result.Errors {
+		errs = append(errs, err.Error())
+	}
+	section(sectionScanErrors, errs)
+}
+
+// topOrphans is how many orphans the text report lists by size and by age.
+const topOrphans = 10
+
+// largestOrphans returns the topOrphans biggest orphaned folders and files
+// of result that hold anything, biggest first, to triage the space first.
+func largestOrphans(result *cleanup.CleanupResult) []cleanup.Finding {
+	var orphans []cleanup.Finding
+	for _, f := range result.Findings {
+		if (f.Category == cleanup.CategoryOrphanedFolder || f.Category == cleanup.CategoryOrphanedFile) && f.Bytes > 0 {
+			orphans = append(orphans, f)
+		}
+	}
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return findingOrders["size"](orphans[i], orphans[j])
+	})
+	return orphans[:min(len(orphans), topOrphans)]
+}
+
+// oldestOrphans returns the topOrphans orphaned folders and files of result
+// left untouched the longest, oldest first: those are safe to delete, while
+// one modified an hour ago may still be being imported.
+func oldestOrphans(result *cleanup.CleanupResult) []cleanup.Finding {
+	var orphans []cleanup.Finding
+	for _, f := range result.Findings {
+		if (f.Category == cleanup.CategoryOrphanedFolder || f.Category == cleanup.CategoryOrphanedFile) && f.Modified != nil {
+			orphans = append(orphans, f)
+		}
+	}
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return findingOrders["age"](orphans[i], orphans[j])
+	})
+	return orphans[:min(len(orphans), topOrphans)]
+}
+
+// findingOrders are the orders --sort puts the findings of a report in, by
+// name. Without --sort, findings are reported in the order they were found.
+var findingOrders = map[string]func(a, b cleanup.Finding) bool{
+	// Biggest first
+	"size": func(a, b cleanup.Finding) bool { return a.Bytes > b.Bytes },
+	// Oldest first, findings without a modification time last
+	"age": func(a, b cleanup.Finding) bool {
+		if a.Modified == nil || b.Modified == nil {
+			return a.Modified != nil && b.Modified == nil
+		}
+		return a.Modified.Before(*b.Modified)
+	},
+}
+
+// reportWriter renders results in the format, language and style chosen on
+// the command line.
+type reportWriter struct {
+	format  string // text, json or jsonl; ndjson is streamed during the scan
+	lang    *Language
+	style   *ReportStyle  // DefaultReportStyle if nil
+	summary bool          // one summary line instead of the text report
+	quiet   bool          // counts instead of the items in the text report
+	elapsed time.Duration // scan duration, shown in the summary
+	sort    string        // a findingOrders name, or empty for scan order
+	verbose bool          // the largest and oldest orphans whatever the sort
+	color   bool          // headings in their sectionColors, for a terminal
+}
+
+// heading returns the title of section in the text report, colored with
+// rw.color.
+func (rw reportWriter) heading(section string) string {
+	style := rw.style
+	if style == nil {
+		style = DefaultReportStyle()
+	}
+	title := style.title(section, rw.lang)
+	if rw.color {
+		return colorize(section, title)
+	}
+	return title
+}
+
+// write writes result to w. Only the text format is translated and styled.
+func (rw reportWriter) write(w io.Writer, result *cleanup.CleanupResult) error {
+	if less := findingOrders[rw.sort]; less != nil {
+		result = result.Sorted(less)
+	}
+	switch rw.format {
+	case "json":
+		return writeJSON(w, result)
+	case "jsonl":
+		return writeJSONL(w, result)
+	}
+	if rw.summary {
+		printSummary(w, result, rw.elapsed)
+		return nil
+	}
+	if rw.quiet {
+		rw.printCounts(w, result)
+		return nil
+	}
+	rw.printReport(w, result)
+	return nil
+}
+
+// outputFormat returns the report format for a --output file: the one its
+// extension names, or format for any other extension.
+func outputFormat(path, format string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	}
+	return format
+}
+
+// writeOutputs writes the full report of result to each of paths, in the
+// format outputFormat picks, then the counts to w as --quiet does, or the
+// one-line summary with --summary.
+func (rw reportWriter) writeOutputs(w io.Writer, paths []string, result *cleanup.CleanupResult) error {
+	for _, path := range paths {
+		full := rw
+		full.format, full.summary, full.quiet, full.color = outputFormat(path, rw.format), false, false, false
+		if err := full.writeFile(path, result); err != nil {
+			return err
+		}
+	}
+	counts := rw
+	counts.format, counts.quiet = "text", !rw.summary
+	if err := counts.write(w, result); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		rw.lang.Fprintf(w, "Report written to %s\n", path)
+	}
+	return nil
+}
+
+// writeFile writes the report of result to path, replacing the previous
+// file atomically so a reader never sees half a report.
+func (rw reportWriter) writeFile(path string, result *cleanup.CleanupResult) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := rw.write(tmp, result); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// printCounts writes the number of findings of every category and the
+// reclaimable space, one line each, instead of listing every item. Scan
+// errors are still listed one by one. Nothing is written when there is
+// nothing to report, so cron has nothing to mail.
+func (rw reportWriter) printCounts(w io.Writer, result *cleanup.CleanupResult) {
+	for _, c := range cleanup.Categories {
+		if n := len(result.ByCategory(c)); n > 0 {
+			fmt.Fprintf(w, "%s: %d\n", rw.heading(string(c)), n)
+		}
+	}
+	if usage := result.Usage(); usage.Bytes > 0 {
+		fmt.Fprintf(w, "%s: %s\n", rw.heading(sectionReclaimableSpace), cleanup.FormatBytes(usage.Reclaimable))
+	}
+	for _, err := range result.Errors {
+		fmt.Fprintf(w, "%s: %v\n", rw.heading(sectionScanErrors), err)
+	}
+}
+
+// printSummary writes the finding counts of every category, the
+// reclaimable space and the scan duration as a single key=value line, fit
+// for a nightly log.
+func printSummary(w io.Writer, result *cleanup.CleanupResult, elapsed time.Duration) {
+	var fields []string
+	for _, c := range cleanup.Categories {
+		fields = append(fields, fmt.Sprintf("%s=%d", c, len(result.ByCategory(c))))
+	}
+	fields = append(fields,
+		fmt.Sprintf("errors=%d", len(result.Errors)),
+		fmt.Sprintf("reclaimable_bytes=%d", result.Usage().Reclaimable),
+		fmt.Sprintf("duration=%s", elapsed.Round(time.Millisecond)))
+	fmt.Fprintln(w, strings.Join(fields, " "))
+}
+
+// streamFindings wraps the Scan callback add so that every finding it
+// records is also written to w as a JSON line right away, for --format
+// ndjson. It is safe for concurrent use.
+func streamFindings(w io.Writer, add func(cleanup.Finding) error) func(cleanup.Finding) error {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(f cleanup.Finding) error {
+		if err := add(f); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return enc.Encode(f)
+	}
+}
+
+// writeJSON writes result as a single indented JSON document.
+func writeJSON(w io.Writer, result *cleanup.CleanupResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// writeJSONL writes one JSON object per finding, one per line.
+func writeJSONL(w io.Writer, result *cleanup.CleanupResult) error {
+	enc := json.NewEncoder(w)
+	for _, f := range result.Findings {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
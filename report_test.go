@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for report writers
+// ============================================================================
+
+func TestWriteJSONL(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/A/Orphan"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/B"})
+
+	var buf bytes.Buffer
+	if err := writeJSONL(&buf, result); err != nil {
+		t.Fatalf("writeJSONL returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+	var f cleanup.Finding
+	if err := json.Unmarshal([]byte(lines[1]), &f); err != nil {
+		t.Fatalf("Line is not a valid finding: %v", err)
+	}
+	if f.Category != cleanup.CategoryEmptyFolder || f.Path != "/lib/B" {
+		t.Errorf("Unexpected finding on second line: %+v", f)
+	}
+}
+
+func TestWriteJSON_Decodes(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Path: "/lib/old.nfo"})
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, result); err != nil {
+		t.Fatalf("writeJSON returned error: %v", err)
+	}
+
+	var decoded cleanup.CleanupResult
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("writeJSON output does not decode: %v", err)
+	}
+	if len(decoded.OrphanedFiles) != 1 {
+		t.Errorf("Expected 1 orphaned file after decoding, got %d", len(decoded.OrphanedFiles))
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A", Usage: cleanup.Usage{Bytes: 2048, Reclaimable: 1024}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/B"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/x.mkv", Message: "Video file at library level"})
+
+	var buf bytes.Buffer
+	rw := reportWriter{format: "text", summary: true, elapsed: 1234567 * time.Microsecond}
+	if err := rw.write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "structure_warning=1 misfiled_video=0 download_in_progress=0 truncated_video=0 corrupt_video=0 broken_symlink=0 orphaned_folder=2 orphaned_file=0 empty_folder=0 junk_file=0 " +
+		"permission_mismatch=0 duplicate_video=0 metadata_mismatch=0 missing_metadata=0 incompatible_name=0 recycle_bin=0 errors=0 reclaimable_bytes=1024 duration=1.235s\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected summary:\n got: %q\nwant: %q", buf.String(), expected)
+	}
+}
+
+func TestPrintCounts(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A", Usage: cleanup.Usage{Bytes: 2048, Reclaimable: 1024}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/B"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/C"})
+
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "text", quiet: true}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	expected := "🗑️  Orphaned metadata folders (no video file): 2\n📁 Empty folders: 1\n💾 Reclaimable space: 1.0 KiB\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected counts:\n got: %q\nwant: %q", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := (reportWriter{format: "text", quiet: true}).write(&buf, &cleanup.CleanupResult{}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing for a clean library, got %q", buf.String())
+	}
+}
+
+func TestPrintReport_ReclaimableByCategory(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A", Usage: cleanup.Usage{Bytes: 2048, Reclaimable: 2048}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Path: "/lib/S/old.nfo", Usage: cleanup.Usage{Bytes: 1024, Reclaimable: 1024}})
+
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "text"}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"💾 Reclaimable space: 3.0 KiB\n",
+		"   Orphaned metadata folders (no video file): 2.0 KiB\n",
+		"   Orphaned metadata files (no video file at same level): 1.0 KiB\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Expected %q in the report, got:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestPrintReport_LargestOrphans(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Path: "/lib/S/old.nfo", Usage: cleanup.Usage{Bytes: 4096}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Empty", Usage: cleanup.Usage{}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Big", Usage: cleanup.Usage{Bytes: 200 << 30}})
+
+	expected := "🔝 Largest orphans (2):\n    200.0 GiB  /lib/S/Big\n      4.0 KiB  /lib/S/old.nfo\n"
+	for _, rw := range []reportWriter{{format: "text", sort: "size"}, {format: "text", verbose: true}} {
+		var buf bytes.Buffer
+		if err := rw.write(&buf, result); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in the report with %+v, got:\n%s", expected, rw, buf.String())
+		}
+	}
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "text"}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "Largest orphans") {
+		t.Errorf("Expected no largest orphans without --sort size or --verbose, got:\n%s", buf.String())
+	}
+
+	for i := 0; i < 2*topOrphans; i++ {
+		result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Path: "/lib/S/x.nfo", Usage: cleanup.Usage{Bytes: 1}})
+	}
+	if largest := largestOrphans(result); len(largest) != topOrphans || largest[0].Path != "/lib/S/Big" {
+		t.Errorf("Expected the %d largest orphans, biggest first, got %v", topOrphans, largest)
+	}
+}
+
+func TestPrintReport_OldestOrphans(t *testing.T) {
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	recent := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Importing", Modified: &recent})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Path: "/lib/S/x.nfo"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Forgotten", Modified: &old})
+
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "text", verbose: true}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	expected := "🕰️  Oldest orphans (2):\n   2024-01-01 00:00  /lib/S/Forgotten\n   2024-06-01 00:00  /lib/S/Importing\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected %q in the report, got:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := (reportWriter{format: "text", sort: "age"}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Oldest orphans (2)") {
+		t.Errorf("Expected the oldest orphans with --sort age, got:\n%s", buf.String())
+	}
+	expected = "(2):\n   /lib/S/Forgotten\n   /lib/S/Importing\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected the oldest first, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := (reportWriter{format: "text"}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "Oldest orphans") {
+		t.Errorf("Expected no oldest orphans without --sort age or --verbose, got:\n%s", buf.String())
+	}
+}
+
+func TestPrintReport_DuplicateWaste(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryDuplicateVideo, Path: "/lib/A/a.mkv", Message: "Identical to /lib/B/a.mkv"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryDuplicateVideo, Path: "/lib/B/a.mkv", Message: "Identical to /lib/A/a.mkv",
+		Usage: cleanup.Usage{Bytes: 2048, Reclaimable: 2048}})
+
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "text"}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "\n♊ Space wasted by identical copies: 2.0 KiB\n"; !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected %q in the report, got:\n%s", expected, buf.String())
+	}
+	if strings.Contains(buf.String(), "Reclaimable space") {
+		t.Errorf("Expected duplicates not to count as reclaimable, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteOutputs(t *testing.T) {
+	dir := t.TempDir()
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A (2001)"})
+
+	text, jsonl := filepath.Join(dir, "report.txt"), filepath.Join(dir, "report.ndjson")
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "text"}).writeOutputs(&buf, []string{text, jsonl}, result); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "🗑️  Orphaned metadata folders (no video file): 1\nReport written to " + text + "\nReport written to " + jsonl + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected only the counts on the terminal, got:\n%s", buf.String())
+	}
+	data, err := os.ReadFile(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "   /lib/S/A (2001)\n") {
+		t.Errorf("Expected the full text report in %s, got:\n%s", text, data)
+	}
+	data, err = os.ReadFile(jsonl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"category":"orphaned_folder"`) {
+		t.Errorf("Expected JSONL in %s, got:\n%s", jsonl, data)
+	}
+}
+
+func TestStreamFindings(t *testing.T) {
+	var buf bytes.Buffer
+	result := &cleanup.CleanupResult{}
+	add := streamFindings(&buf, result.Add)
+
+	if err := add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A (2001)"}); err != nil {
+		t.Fatal(err)
+	}
+	// Written as soon as it is recorded, not at the end of the scan
+	if expected := `{"category":"orphaned_folder","path":"/lib/S/A (2001)"}` + "\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+	if err := add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/B"}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 || len(result.Findings) != 2 {
+		t.Errorf("Expected 2 lines and 2 findings, got %d and %d", lines, len(result.Findings))
+	}
+
+	// Findings the callback fails to record are not written
+	buf.Reset()
+	refuse := streamFindings(&buf, func(cleanup.Finding) error { return cleanup.ErrFindingLimit })
+	if err := refuse(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/C"}); err == nil || buf.Len() != 0 {
+		t.Errorf("Expected the refused finding not to be written, got %v and %q", err, buf.String())
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := map[string]string{
+		"report.json":   "json",
+		"REPORT.JSON":   "json",
+		"report.jsonl":  "jsonl",
+		"report.ndjson": "jsonl",
+		"report.txt":    "text",
+		"report":        "text",
+	}
+	for path, expected := range tests {
+		if got := outputFormat(path, "text"); got != expected {
+			t.Errorf("outputFormat(%q): expected %s, got %s", path, expected, got)
+		}
+	}
+}
+
+func TestWrite_SortBySize(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A", Usage: cleanup.Usage{Bytes: 10}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/B", Usage: cleanup.Usage{Bytes: 30}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/C", Usage: cleanup.Usage{Bytes: 20}})
+
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "jsonl", sort: "size"}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var f cleanup.Finding
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, f.Path)
+	}
+	if strings.Join(paths, " ") != "/lib/S/B /lib/S/C /lib/S/A" {
+		t.Errorf("Expected the biggest first, got %v", paths)
+	}
+	if result.Findings[0].Path != "/lib/S/A" {
+		t.Errorf("Expected the result left in scan order, got %v", result.Findings)
+	}
+}
@@ -0,0 +1,721 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// runner carries out the runs of a command line whose flags were checked:
+// scan the libraries and report, then delete or fix what was asked for, or
+// apply a plan instead. --every, --schedule, serve and --interactive run it
+// more than once.
+type runner struct {
+	lang   *Language
+	logger *slog.Logger
+	// interrupted is cancelled by Ctrl-C and docker stop
+	interrupted context.Context
+	timeout     time.Duration
+	metrics     *runMetrics
+	board       *dashboard
+
+	libraryPaths []string
+	remotes      map[string]remoteLibrary
+	checkMode    bool
+	planMode     bool
+	planOut      string
+	applyPath    string
+	planKey      string
+	lockDir      string
+
+	workers        int
+	checkOpts      []cleanup.Option            // what the scans check
+	libraryOpts    []cleanup.Option            // the layout of the libraries given on the command line
+	configuredOpts map[string][]cleanup.Option // the layout of each --config library, by path
+	layout         cleanup.Layout
+	statePath      string
+	fullScan       bool
+	checkpointPath string
+	resume         bool
+	cachePath      string
+	maxFindings    int
+	managers       []libraryManager
+
+	rw           reportWriter
+	out          io.Writer // progress and deletions
+	reportOut    io.Writer // the report
+	stdout       io.Writer
+	stderr       io.Writer
+	format       string
+	outputs      stringList
+	summary      bool
+	quiet        bool
+	verbose      bool
+	interactive  bool
+	showProgress bool
+	digestPath   string
+	digestEvery  time.Duration
+	digestHook   string // --digest-webhook
+	diffPath     string
+	failOn       string
+	failLevel    cleanup.Severity
+
+	execute             bool
+	fixPerms            bool
+	fixStructure        bool
+	fixNames            bool
+	policy              cleanup.PermissionPolicy
+	strategy            cleanup.DeleteStrategy
+	deleterOpts         []cleanup.DeleterOption
+	threshold           cleanup.DeletionThreshold
+	protected           cleanup.ProtectedPaths
+	confirmOver         int
+	yes                 bool
+	readOnlyRemote      bool // some library is on ssh://, where nothing is deleted
+	manifestPath        string
+	backupTo            string
+	trashDir            string
+	quarantineDir       string
+	quarantineRetention time.Duration
+
+	// lastResult is the result of the latest complete scan, for the terminal
+	// UI to pick from
+	lastResult *cleanup.CleanupResult
+}
+
+// runOnce scans, reports and deletes once, and returns the exit code of the
+// run. Runs approved in the dashboard or the terminal UI delete the
+// findings at the approved paths, provided the scan still finds them.
+func (r *runner) runOnce(approved map[string]bool) int {
+	runStart := time.Now()
+	r.logger.Info("run started", "libraries", r.libraryPaths, "execute", r.execute, "delete_mode", r.strategy.Name(), "trash", r.trashDir, "quarantine", r.quarantineDir)
+
+	// The summary replaces the report and the chatter around it; deletion
+	// output is kept. --quiet keeps only the totals of deletions and
+	// fixes, and those only when something was done.
+	scanOut, itemOut := r.out, r.out
+	if r.summary || r.interactive || r.quiet {
+		scanOut = io.Discard
+	}
+	if r.quiet {
+		itemOut = io.Discard
+	}
+
+	if !r.execute && !r.planMode && r.applyPath == "" && approved == nil {
+		r.lang.Fprintf(scanOut, "=== DRY RUN MODE (use --execute to actually delete) ===\n\n")
+	}
+
+	ctx := r.interrupted
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	// Library managers are asked before anything is deleted; if one cannot
+	// be reached the run stops rather than fight it
+	for _, m := range r.managers {
+		if err := m.Load(ctx); err != nil {
+			r.lang.Fprintf(os.Stderr, "Cannot reach %s, nothing was scanned: %v\n", m.Name(), err)
+			return exitFailure
+		}
+		r.logger.Info("library manager loaded", "manager", m.Name())
+	}
+
+	// Every run that deletes gets its own id in the manifest and the
+	// backup name
+	runID := cleanup.NewRunID()
+	backupPath := strings.ReplaceAll(r.backupTo, "{run}", runID)
+	var manifest *cleanup.Manifest
+	if r.manifestPath != "" && (r.applyPath != "" || r.execute || approved != nil) {
+		var err error
+		if manifest, err = cleanup.OpenManifest(r.manifestPath, runID); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return exitFailure
+		}
+		defer manifest.Close()
+	}
+	if r.applyPath != "" {
+		return r.applyPlan(ctx, itemOut, manifest, backupPath, runStart)
+	}
+
+	// Another run scanning or deleting from the same libraries would race
+	// on the same folders
+	unlock, err := lockLibraries(r.lockDir, r.libraryPaths)
+	if err != nil {
+		r.logger.Error("run skipped", "error", err)
+		r.lang.Fprintf(os.Stderr, "Nothing was scanned: %v\n", err)
+		return exitFailure
+	}
+	defer r.release(unlock)
+
+	sc, err := r.scan(ctx, scanOut)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitFailure
+	}
+	result := sc.result
+	r.board.scanned(result)
+
+	// The findings the exit code is about: with --fail-on, only those
+	// severe enough, and with --diff as well only the new ones
+	judged := result
+	switch {
+	case r.digestPath != "" && sc.err == nil:
+		err = runDigest(r.reportOut, r.digestPath, r.digestEvery, r.digestHook, r.rw, result, time.Now())
+	case r.digestPath != "":
+		// A partial scan would drop findings from the digest; skip this run
+		r.lang.Fprintf(os.Stderr, "Scan aborted (%v), digest not updated\n", sc.err)
+	case r.diffPath != "" && sc.err == nil:
+		var fresh *cleanup.CleanupResult
+		if fresh, err = runDiff(r.reportOut, r.diffPath, r.rw, result); err == nil && r.failOn != "" {
+			judged = &cleanup.CleanupResult{Findings: fresh.Findings, Errors: result.Errors}
+		}
+	case r.diffPath != "":
+		// A partial scan would report everything it did not reach as resolved
+		r.lang.Fprintf(os.Stderr, "Scan aborted (%v), not compared and %s not updated\n", sc.err, r.diffPath)
+	case r.interactive:
+		// The findings are shown in the terminal UI
+	case r.format == "ndjson":
+		// The findings were written as they were found
+	case len(r.outputs) > 0:
+		err = r.rw.writeOutputs(r.stdout, r.outputs, result)
+	default:
+		err = r.rw.write(r.reportOut, result)
+	}
+	if err != nil {
+		r.lang.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		return exitFailure
+	}
+
+	for _, m := range r.managers {
+		if n := sc.kept[m.Name()]; n > 0 {
+			r.lang.Fprintf(scanOut, "\n🛡️  Kept %d items still wanted by %s\n", n, m.Name())
+		}
+	}
+
+	if r.checkMode {
+		printVerdicts(scanOut, result, r.libraryPaths, r.lang)
+	}
+
+	if sc.err != nil {
+		r.logger.Error("scan stopped early, nothing deleted", "error", sc.err)
+	}
+	if errors.Is(sc.err, cleanup.ErrFindingLimit) {
+		r.lang.Fprintf(r.out, "\n⚠️  Stopped after %d findings (--max-findings), results above are partial and nothing was deleted\n", r.maxFindings)
+		return exitFindings
+	}
+	if sc.err != nil {
+		r.lang.Fprintf(r.out, "\n⚠️  Scan aborted (%v), results above are partial and nothing was deleted\n", sc.err)
+		return exitScanErrors
+	}
+
+	if r.planMode {
+		plan, err := writePlan(r.planOut, r.planKey, result, time.Now())
+		if err != nil {
+			r.lang.Fprintf(os.Stderr, "Failed to write the plan: %v\n", err)
+			return exitFailure
+		}
+		r.logger.Info("plan written", "plan", r.planOut, "actions", len(plan.Actions))
+		r.lang.Fprintf(r.out, "\n📝 Plan with %d items written to %s, review it and run: video-folder-cleanup apply %s\n", len(plan.Actions), r.planOut, r.planOut)
+		r.logger.Info("run finished", "findings", len(result.Findings), "duration", time.Since(runStart))
+		return exitClean
+	}
+
+	r.lastResult = result
+
+	// Execute deletions if requested
+	var acted actions
+	if r.execute || approved != nil {
+		var code int
+		if acted, code = r.act(ctx, itemOut, manifest, backupPath, sc, approved); code != exitClean {
+			return code
+		}
+	} else {
+		r.printHints(scanOut, result)
+	}
+	r.logger.Info("run finished", "findings", len(result.Findings), "duration", time.Since(runStart))
+	if r.failOn != "" {
+		judged = severeFindings(judged, r.failLevel)
+	}
+	return exitCode(judged, acted.deletions, acted.perms, acted.moves, acted.renames)
+}
+
+// scanned is what the scan of a run found.
+type scanned struct {
+	result *cleanup.CleanupResult
+	// Title folders scanned, for --max-delete-percent
+	titles int
+	// Findings kept for each library manager
+	kept map[string]int
+	// Why the scan stopped early, if it did
+	err error
+}
+
+// scan scans the libraries of the run, or the titles checked. An error
+// means the scan could not start.
+func (r *runner) scan(ctx context.Context, scanOut io.Writer) (*scanned, error) {
+	var err error
+	// Libraries are scanned concurrently; the shared budget keeps the number
+	// of folders processed at once to --workers in total
+	scanOpts := append([]cleanup.Option{cleanup.WithWorkerBudget(cleanup.NewWorkerBudget(r.workers))}, r.checkOpts...)
+	// Checked titles are read in full; the state is for library scans
+	var state *cleanup.ScanState
+	if r.statePath != "" && !r.checkMode {
+		if state, err = cleanup.LoadScanState(r.statePath); err != nil {
+			return nil, err
+		}
+		state.Refresh = r.fullScan
+		scanOpts = append(scanOpts, cleanup.WithScanState(state))
+	}
+	// With --checkpoint, library scans record their progress, so that an
+	// interrupted one can be resumed
+	var checkpoint *cleanup.Checkpoint
+	if !r.checkMode && r.checkpointPath != "" {
+		if checkpoint, err = cleanup.LoadCheckpoint(r.checkpointPath); err != nil {
+			return nil, err
+		}
+		checkpoint.Resume = r.resume
+		scanOpts = append(scanOpts, cleanup.WithCheckpoint(checkpoint))
+	}
+	// Remote libraries are listed in one go anyway; the cache is for local
+	// folders
+	var listings *cleanup.ListingCache
+	var localOpts []cleanup.Option
+	if r.cachePath != "" {
+		if listings, err = cleanup.LoadListingCache(r.cachePath); err != nil {
+			return nil, err
+		}
+		localOpts = append(localOpts, cleanup.WithListingCache(listings))
+	}
+	// Title folders scanned, for --max-delete-percent
+	var titles atomic.Int64
+	var progress *scanProgress
+	if r.showProgress && !r.verbose && isTerminal(os.Stderr) {
+		progress = newScanProgress(r.stderr, r.lang, r.layout)
+	}
+	if debug := r.logger.Enabled(ctx, slog.LevelDebug); progress != nil || debug || r.verbose || r.resume || r.threshold.MaxPercent > 0 {
+		scanOpts = append(scanOpts, cleanup.WithProgress(func(ev cleanup.ProgressEvent) {
+			if _, ok := ev.(cleanup.TitleScanned); ok {
+				titles.Add(1)
+			}
+			if progress != nil {
+				progress.event(ev)
+			}
+			if started, ok := ev.(cleanup.LibraryStarted); ok && started.Resumed > 0 && !r.verbose {
+				r.lang.Fprintf(r.stderr, "↪️  Resuming %s: %d of %d top-level folders were finished by the interrupted scan\n", started.Library, started.Resumed, started.Studios)
+			}
+			if r.verbose {
+				traceEvent(os.Stderr, r.lang, ev)
+			}
+			if !debug {
+				return
+			}
+			switch ev := ev.(type) {
+			case cleanup.LibraryStarted:
+				r.logger.Debug("library started", "library", ev.Library, "studios", ev.Studios, "resumed", ev.Resumed)
+			case cleanup.StudioScanned:
+				r.logger.Debug("studio scanned", "library", ev.Library, "path", ev.Path, "done", ev.Done, "total", ev.Total)
+			case cleanup.TitleScanned:
+				r.logger.Debug("title scanned", "library", ev.Library, "path", ev.Path, "decision", ev.Decision)
+			case cleanup.FolderSkipped:
+				r.logger.Debug("folder skipped", "library", ev.Library, "path", ev.Path, "reason", ev.Reason)
+			}
+		}))
+	}
+	scanners := libraryScanners{fallback: cleanup.NewScanner(append(append(append([]cleanup.Option{}, scanOpts...), r.libraryOpts...), localOpts...)...)}
+	if r.configuredOpts != nil {
+		scanners.configured = map[string]*cleanup.Scanner{}
+		for path, opts := range r.configuredOpts {
+			if !isRemote(path) {
+				scanners.configured[path] = cleanup.NewScanner(append(append(append([]cleanup.Option{}, scanOpts...), opts...), localOpts...)...)
+			}
+		}
+	}
+	if len(r.remotes) > 0 {
+		scanners.remote = map[string]*cleanup.Scanner{}
+		for url, remote := range r.remotes {
+			opts, ok := r.configuredOpts[url]
+			if !ok {
+				opts = r.libraryOpts
+			}
+			opts = append(append(append([]cleanup.Option{}, scanOpts...), opts...), cleanup.WithFS(remote.fsys))
+			scanners.remote[url] = cleanup.NewScanner(opts...)
+		}
+	}
+
+	// One result per library, in command-line order. Titles checked in the
+	// same library share its result.
+	var results []*cleanup.CleanupResult
+	resultFor := map[string]*cleanup.CleanupResult{}
+	for _, libraryPath := range r.libraryPaths {
+		library := scanRoot(r.remotes, libraryPath)
+		if r.checkMode {
+			r.lang.Fprintf(scanOut, "Checking title: %s\n", libraryPath)
+			library = scanners.forPath(libraryPath).TitleLibrary(libraryPath)
+		} else {
+			r.lang.Fprintf(scanOut, "Scanning library: %s\n", libraryPath)
+		}
+		if resultFor[library] == nil {
+			resultFor[library] = &cleanup.CleanupResult{Libraries: []string{library}}
+			results = append(results, resultFor[library])
+		}
+	}
+
+	var mu sync.Mutex
+	add := func(f cleanup.Finding) error {
+		mu.Lock()
+		defer mu.Unlock()
+		r.logger.Info("finding", "category", f.Category, "path", f.Path, "library", f.Library, "message", f.Message)
+		if progress != nil {
+			progress.foundOne()
+		}
+		return resultFor[f.Library].Add(f)
+	}
+	// Consumers of --format ndjson start on the findings during the scan
+	if r.format == "ndjson" {
+		add = streamFindings(os.Stdout, add)
+	}
+	if r.maxFindings > 0 {
+		add = cleanup.LimitFindings(r.maxFindings, add)
+	}
+	// Findings kept for a manager do not count towards --max-findings
+	wanted := &wantedFilter{managers: r.managers, logger: r.logger}
+	if len(r.managers) > 0 {
+		add = wanted.wrap(add)
+	}
+	scanCtx, stopScans := context.WithCancel(ctx)
+	defer stopScans()
+	var scanErr error
+	scanStart := time.Now()
+	if progress != nil {
+		progress.start(100 * time.Millisecond)
+	}
+	// The callback never fails; scan errors are recorded per library
+	_ = cleanup.RunPool(scanCtx, len(r.libraryPaths), r.libraryPaths, func(libraryPath string) error {
+		libraryStart := time.Now()
+		library := scanRoot(r.remotes, libraryPath)
+		var err error
+		if r.checkMode {
+			scanner := scanners.forPath(libraryPath)
+			library = scanner.TitleLibrary(libraryPath)
+			err = scanner.ScanTitle(scanCtx, libraryPath, add)
+		} else {
+			err = scanners.forPath(libraryPath).Scan(scanCtx, library, add)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if errors.Is(err, cleanup.ErrFindingLimit) || errors.Is(scanErr, cleanup.ErrFindingLimit) {
+			// The other libraries stop too, their results are partial anyway
+			scanErr = cleanup.ErrFindingLimit
+			stopScans()
+			return nil
+		}
+		// Anything but cancellation only affects this library (or part of it)
+		libraryResult := resultFor[library]
+		var errs []error
+		for _, err := range cleanup.SplitErrors(err) {
+			// Cancellation is reported once for the whole run
+			if ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+				errs = append(errs, err)
+			}
+		}
+		libraryResult.Errors = append(libraryResult.Errors, errs...)
+		for _, err := range errs {
+			r.logger.Warn("scan error", "library", libraryPath, "error", err)
+		}
+		r.logger.Info("library scanned", "library", libraryPath, "findings", len(libraryResult.Findings),
+			"errors", len(errs), "duration", time.Since(libraryStart))
+		r.metrics.scanned(library, time.Since(libraryStart), libraryResult, len(errs))
+		return nil
+	})
+	if progress != nil {
+		progress.finish()
+	}
+	if scanErr == nil && ctx.Err() != nil {
+		scanErr = context.Cause(ctx)
+	}
+	result := cleanup.MergeResults(results...)
+	r.rw.elapsed = time.Since(scanStart)
+	if state != nil {
+		if err := state.Save(r.statePath); err != nil {
+			r.logger.Warn("scan state not saved", "state", r.statePath, "error", err)
+			if !r.quiet {
+				r.lang.Fprintf(os.Stderr, "Scan state not saved, the next scan reads every title folder: %v\n", err)
+			}
+		}
+	}
+	if checkpoint != nil {
+		if err := checkpoint.Save(); err != nil {
+			r.logger.Warn("checkpoint not saved", "checkpoint", checkpoint.Path, "error", err)
+			if !r.quiet {
+				r.lang.Fprintf(os.Stderr, "Checkpoint not saved, an interrupted scan cannot be resumed: %v\n", err)
+			}
+		}
+	}
+	if listings != nil {
+		if err := listings.Save(r.cachePath); err != nil {
+			r.logger.Warn("listing cache not saved", "cache", r.cachePath, "error", err)
+			if !r.quiet {
+				r.lang.Fprintf(os.Stderr, "Listing cache not saved, the next scan lists every folder: %v\n", err)
+			}
+		}
+	}
+	return &scanned{result: result, titles: int(titles.Load()), kept: wanted.kept, err: scanErr}, nil
+}
+
+// afterDeletions asks the library managers to rescan what a run deleted
+// from, and purges the quarantine of items kept longer than wanted.
+func (r *runner) afterDeletions(ctx context.Context, report *cleanup.DeletionReport) {
+	for _, err := range notifyManagers(ctx, r.managers, report, r.logger) {
+		r.lang.Fprintf(r.out, "⚠️  Rescan request failed: %v\n", err)
+	}
+	if r.quarantineDir != "" && r.quarantineRetention > 0 {
+		purgeQuarantine(r.out, r.lang, r.logger, r.quarantineDir, r.quarantineRetention)
+	}
+}
+
+// release releases the library locks of a run. A lock left behind keeps
+// the next runs off the library until it is removed, so it is reported.
+func (r *runner) release(unlock func() error) {
+	if err := unlock(); err != nil {
+		r.logger.Error("lock not released", "error", err)
+		r.lang.Fprintf(os.Stderr, "⚠️  Library lock not released, later runs may find the library locked: %v\n", err)
+	}
+}
+
+// applyPlan deletes what the plan of apply lists, provided nothing it
+// covers changed since it was made.
+func (r *runner) applyPlan(ctx context.Context, itemOut io.Writer, manifest *cleanup.Manifest, backupPath string, runStart time.Time) int {
+	plan, err := readVerifiedPlan(r.applyPath, r.planKey, r.strategy)
+	if err != nil {
+		r.logger.Error("plan refused", "plan", r.applyPath, "error", err)
+		r.lang.Fprintf(os.Stderr, "Plan %s not applied, nothing was deleted: %v\n", r.applyPath, err)
+		return exitFailure
+	}
+	unlock, err := lockLibraries(r.lockDir, plan.Libraries)
+	if err != nil {
+		r.logger.Error("plan not applied", "plan", r.applyPath, "error", err)
+		r.lang.Fprintf(os.Stderr, "Plan %s not applied, nothing was deleted: %v\n", r.applyPath, err)
+		return exitFailure
+	}
+	defer r.release(unlock)
+	if err := checkProtected(r.protected, r.remotes, plan.Result().Findings); err != nil {
+		r.logger.Error("plan not applied", "plan", r.applyPath, "error", err)
+		refuseProtected(r.stderr, r.lang, err)
+		r.lang.Fprintf(os.Stderr, "Plan %s not applied, nothing was deleted: %v\n", r.applyPath, err)
+		return exitFailure
+	}
+	// The plan does not say how many title folders were scanned
+	if err := r.threshold.Check(plan.Result(), -1); err != nil {
+		r.logger.Error("plan not applied", "plan", r.applyPath, "error", err)
+		r.lang.Fprintf(os.Stderr, "Plan %s not applied, nothing was deleted: %v\n", r.applyPath, err)
+		return exitFailure
+	}
+	r.lang.Fprintf(r.out, "Applying plan %s (%d items, made %s)\n", r.applyPath, len(plan.Actions), plan.Created.Local().Format(time.DateTime))
+	report, err := runDeletions(ctx, r.out, itemOut, r.lang, r.logger, r.strategy, manifest, backupPath, plan.Result(), r.deleterOpts...)
+	if err != nil {
+		return exitActionFailures
+	}
+	r.afterDeletions(ctx, report)
+	r.logger.Info("run finished", "plan", r.applyPath, "duration", time.Since(runStart))
+	if len(report.Failures) > 0 {
+		return exitActionFailures
+	}
+	return exitClean
+}
+
+// actions are the reports of what a run did.
+type actions struct {
+	deletions *cleanup.DeletionReport
+	perms     *cleanup.PermissionFixReport
+	moves     *cleanup.StructureFixReport
+	renames   *cleanup.NameFixReport
+}
+
+// act deletes the findings of the scan, or those approved, and fixes what
+// was asked. The code is exitClean unless the run stops there.
+func (r *runner) act(ctx context.Context, itemOut io.Writer, manifest *cleanup.Manifest, backupPath string, sc *scanned, approved map[string]bool) (actions, int) {
+	var acted actions
+	result := sc.result
+	toDelete := result
+	if approved != nil {
+		toDelete = approvedFindings(result, approved)
+	}
+	if err := r.threshold.Check(toDelete, sc.titles); err != nil {
+		r.logger.Error("deletions refused", "error", err)
+		r.lang.Fprintf(r.out, "\n🛑 Deletions refused, nothing was deleted: %v\n", err)
+		return acted, exitFailure
+	}
+	if err := checkProtected(r.protected, r.remotes, actedOn(toDelete, result, r.fixPerms, r.fixStructure, r.fixNames)); err != nil {
+		r.logger.Error("actions refused", "error", err)
+		refuseProtected(r.out, r.lang, err)
+		return acted, exitFailure
+	}
+	// Items picked in the terminal UI or the dashboard were confirmed
+	// there already
+	if deletions := countDeletable(toDelete); approved == nil && !r.yes && r.confirmOver > 0 && deletions > r.confirmOver {
+		if !isTerminal(os.Stdin) {
+			err := fmt.Errorf("%d items to delete and no terminal to confirm them, pass --yes", deletions)
+			r.logger.Error("deletions refused", "error", err)
+			r.lang.Fprintf(r.out, "\n🛑 Deletions refused, nothing was deleted: %v\n", err)
+			return acted, exitFailure
+		}
+		if !confirmDeletions(ctx, os.Stdin, r.stderr, r.lang, toDelete) {
+			r.logger.Info("deletions cancelled")
+			r.lang.Fprintf(r.out, "\nCancelled, nothing was deleted\n")
+			return acted, exitFindings
+		}
+	}
+	report, err := runDeletions(ctx, r.out, itemOut, r.lang, r.logger, r.strategy, manifest, backupPath, toDelete, r.deleterOpts...)
+	r.metrics.deleted(report)
+	r.board.deleted(report)
+	acted.deletions = report
+	if err != nil {
+		return acted, exitActionFailures
+	}
+	r.afterDeletions(ctx, report)
+
+	if r.fixPerms {
+		if acted.perms, err = r.fixPermissions(ctx, itemOut, result); err != nil {
+			return acted, exitActionFailures
+		}
+	}
+	if r.fixStructure {
+		if acted.moves, err = r.moveMisplaced(ctx, itemOut, result); err != nil {
+			return acted, exitActionFailures
+		}
+	}
+	if r.fixNames {
+		if acted.renames, err = r.renameTitles(ctx, itemOut, result); err != nil {
+			return acted, exitActionFailures
+		}
+	}
+	return acted, exitClean
+}
+
+// fixPermissions fixes the permission mismatches found.
+func (r *runner) fixPermissions(ctx context.Context, itemOut io.Writer, result *cleanup.CleanupResult) (*cleanup.PermissionFixReport, error) {
+	r.lang.Fprintf(itemOut, "\nFixing permissions...\n")
+	fixer := cleanup.NewPermissionFixer(r.policy, cleanup.WithFixWorkers(r.workers), cleanup.WithFixProgress(func(ev cleanup.ProgressEvent) {
+		fixed := ev.(cleanup.PermissionFixed)
+		if fixed.Err != nil {
+			r.logger.Warn("permission fix failed", "path", fixed.Finding.Path, "error", fixed.Err)
+			fmt.Fprintf(r.out, "❌ %v\n", fixed.Err)
+		} else {
+			r.logger.Info("permissions fixed", "path", fixed.Finding.Path)
+			r.lang.Fprintf(itemOut, "✓ Fixed: %s\n", fixed.Finding.Path)
+		}
+	}))
+	fixReport, err := fixer.Fix(ctx, result)
+	r.logger.Info("permission fix finished", "fixed", len(fixReport.Fixed), "failures", len(fixReport.Failures))
+	r.lang.Fprintf(totalsOut(r.out, itemOut, len(fixReport.Fixed)+len(fixReport.Failures)), "\nFixed %d items, %d failures\n", len(fixReport.Fixed), len(fixReport.Failures))
+	if err != nil {
+		r.logger.Error("permission fix aborted", "error", err)
+		r.lang.Fprintf(r.out, "⚠️  Permission fix aborted: %v\n", abortCause(ctx, err))
+		return fixReport, err
+	}
+	return fixReport, nil
+}
+
+// moveMisplaced moves the misplaced files found where they belong.
+func (r *runner) moveMisplaced(ctx context.Context, itemOut io.Writer, result *cleanup.CleanupResult) (*cleanup.StructureFixReport, error) {
+	r.lang.Fprintf(itemOut, "\nMoving misplaced files...\n")
+	mover := cleanup.NewStructureFixer(cleanup.WithMoveProgress(func(ev cleanup.ProgressEvent) {
+		moved := ev.(cleanup.FileMoved)
+		if moved.Err != nil {
+			r.logger.Warn("move failed", "path", moved.Finding.Path, "target", moved.Finding.Target, "error", moved.Err)
+			fmt.Fprintf(r.out, "❌ %v\n", moved.Err)
+		} else {
+			r.logger.Info("file moved", "path", moved.Finding.Path, "target", moved.Finding.Target)
+			r.lang.Fprintf(itemOut, "✓ Moved: %s → %s\n", moved.Finding.Path, moved.Finding.Target)
+		}
+	}))
+	moveReport, err := mover.Fix(ctx, result)
+	r.logger.Info("move finished", "moved", len(moveReport.Moved), "failures", len(moveReport.Failures))
+	r.lang.Fprintf(totalsOut(r.out, itemOut, len(moveReport.Moved)+len(moveReport.Failures)), "\nMoved %d files, %d failures\n", len(moveReport.Moved), len(moveReport.Failures))
+	if err != nil {
+		r.logger.Error("move aborted", "error", err)
+		r.lang.Fprintf(r.out, "⚠️  Move aborted: %v\n", abortCause(ctx, err))
+		return moveReport, err
+	}
+	return moveReport, nil
+}
+
+// renameTitles renames the title folders found misnamed.
+func (r *runner) renameTitles(ctx context.Context, itemOut io.Writer, result *cleanup.CleanupResult) (*cleanup.NameFixReport, error) {
+	r.lang.Fprintf(itemOut, "\nRenaming title folders...\n")
+	renamer := cleanup.NewNameFixer(cleanup.WithRenameProgress(func(ev cleanup.ProgressEvent) {
+		renamed := ev.(cleanup.FileMoved)
+		if renamed.Err != nil {
+			r.logger.Warn("rename failed", "path", renamed.Finding.Path, "target", renamed.Finding.Target, "error", renamed.Err)
+			fmt.Fprintf(r.out, "❌ %v\n", renamed.Err)
+		} else {
+			r.logger.Info("folder renamed", "path", renamed.Finding.Path, "target", renamed.Finding.Target)
+			r.lang.Fprintf(itemOut, "✓ Renamed: %s → %s\n", renamed.Finding.Path, filepath.Base(renamed.Finding.Target))
+		}
+	}))
+	renameReport, err := renamer.Fix(ctx, result)
+	r.logger.Info("rename finished", "renamed", len(renameReport.Renamed), "failures", len(renameReport.Failures))
+	r.lang.Fprintf(totalsOut(r.out, itemOut, len(renameReport.Renamed)+len(renameReport.Failures)), "\nRenamed %d folders, %d failures\n", len(renameReport.Renamed), len(renameReport.Failures))
+	if err != nil {
+		r.logger.Error("rename aborted", "error", err)
+		r.lang.Fprintf(r.out, "⚠️  Rename aborted: %v\n", abortCause(ctx, err))
+		return renameReport, err
+	}
+	return renameReport, nil
+}
+
+// printHints tells what a run with --execute would do.
+func (r *runner) printHints(scanOut io.Writer, result *cleanup.CleanupResult) {
+	total := countDeletable(result)
+	mismatches := 0
+	if r.fixPerms {
+		mismatches = len(result.ByCategory(cleanup.CategoryPermissionMismatch))
+	}
+	var movable, renamable []cleanup.Finding
+	if r.fixStructure {
+		movable = cleanup.Movable(result)
+	}
+	if r.fixNames {
+		renamable = cleanup.Renamable(result)
+	}
+	if total > 0 && r.readOnlyRemote {
+		r.lang.Fprintf(scanOut, "\n💡 %d items to delete on ssh:// libraries, run the tool on that machine with --execute to delete them\n", total)
+	} else if total > 0 {
+		if reclaimable := result.Usage().Reclaimable; reclaimable > 0 {
+			r.lang.Fprintf(scanOut, "\n💡 Run with --execute to delete %d items and reclaim ~%s\n", total, cleanup.FormatBytes(reclaimable))
+		} else {
+			r.lang.Fprintf(scanOut, "\n💡 Run with --execute to delete %d items\n", total)
+		}
+	}
+	if mismatches > 0 {
+		r.lang.Fprintf(scanOut, "\n💡 Run with --execute to fix permissions of %d items\n", mismatches)
+	}
+	if len(movable) > 0 {
+		r.lang.Fprintf(scanOut, "\n💡 Run with --execute to move %d misplaced files:\n", len(movable))
+		for _, f := range movable {
+			fmt.Fprintf(scanOut, "   %s → %s\n", f.Path, f.Target)
+		}
+	}
+	if len(renamable) > 0 {
+		r.lang.Fprintf(scanOut, "\n💡 Run with --execute to rename %d title folders:\n", len(renamable))
+		for _, f := range renamable {
+			fmt.Fprintf(scanOut, "   %s → %s\n", f.Path, filepath.Base(f.Target))
+		}
+	}
+	if total == 0 && mismatches == 0 && len(movable) == 0 && len(renamable) == 0 {
+		r.lang.Fprintf(scanOut, "\n✓ Nothing to clean up\n")
+	}
+}
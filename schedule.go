@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule tells daemon runs when to happen.
+type schedule interface {
+	// next returns when the run following one that started at start is
+	// due; a zero start asks for the first run.
+	next(start time.Time) time.Time
+}
+
+// everySchedule is --every: a first run right away, then one each interval
+// counting from the start of the previous one.
+type everySchedule time.Duration
+
+func (s everySchedule) next(start time.Time) time.Time {
+	if start.IsZero() {
+		return time.Now()
+	}
+	return start.Add(time.Duration(s))
+}
+
+// cronSchedule is --schedule: the minutes matching a cron expression, in
+// local time. Each field holds the values it matches.
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	// Restricted day and weekday fields match a day if either does, as in
+	// cron: "0 3 1 * 1" runs on the 1st and on Mondays. Fields starting
+	// with "*" are not restricted.
+	anyDay, anyWeekday bool
+}
+
+// cronMacros are the @ shorthands cron accepts.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// parseCron parses a five-field cron expression (minute, hour, day of month,
+// month, day of week) with lists, ranges, steps and month and weekday names,
+// or one of the @daily style shorthands.
+func parseCron(expr string) (*cronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+	s := &cronSchedule{anyDay: strings.HasPrefix(fields[2], "*"), anyWeekday: strings.HasPrefix(fields[4], "*")}
+	var err error
+	for _, f := range []struct {
+		values   *map[int]bool
+		field    string
+		min, max int
+		names    []string
+		nameBase int
+	}{
+		{&s.minutes, fields[0], 0, 59, nil, 0},
+		{&s.hours, fields[1], 0, 23, nil, 0},
+		{&s.days, fields[2], 1, 31, nil, 0},
+		{&s.months, fields[3], 1, 12, monthNames, 1},
+		{&s.weekdays, fields[4], 0, 7, weekdayNames, 0},
+	} {
+		if *f.values, err = parseCronField(f.field, f.min, f.max, f.names, f.nameBase); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+		}
+	}
+	// Sunday is 0 or 7
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+	if s.next(time.Time{}).IsZero() {
+		return nil, fmt.Errorf("invalid schedule %q: it never matches", expr)
+	}
+	return s, nil
+}
+
+// parseCronField returns the values between min and max a field matches.
+// names, if any, stand for nameBase, nameBase+1 and so on.
+func parseCronField(field string, min, max int, names []string, nameBase int) (map[int]bool, error) {
+	value := func(s string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				return nameBase + i, nil
+			}
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < min || n > max {
+			return 0, fmt.Errorf("%q is not a number from %d to %d", s, min, max)
+		}
+		return n, nil
+	}
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+		low, high := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = value(from); err != nil {
+				return nil, err
+			}
+			high = low
+			if isRange {
+				if high, err = value(to); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				// "5/15" runs from 5 to the end of the range
+				high = max
+			}
+			if high < low {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+		for n := low; n <= high; n += step {
+			values[n] = true
+		}
+	}
+	return values, nil
+}
+
+// matchesDay reports whether the schedule runs on the day of t.
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	day, weekday := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
+
+// next returns the first matching minute after start, or after now for the
+// first run. A run that overran the following match is followed by another
+// right away.
+func (s *cronSchedule) next(start time.Time) time.Time {
+	if start.IsZero() {
+		start = time.Now()
+	}
+	t := start.Truncate(time.Minute).Add(time.Minute)
+	// Any valid expression matches within a few years (February 29th on a
+	// given weekday at worst)
+	for limit := t.AddDate(30, 0, 0); t.Before(limit); {
+		switch {
+		case !s.months[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	// Only expressions such as "0 0 31 2 *" never match
+	return time.Time{}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Tests for the daemon schedules
+// ============================================================================
+
+func TestParseCron_Next(t *testing.T) {
+	// A Wednesday
+	start := time.Date(2024, time.January, 10, 3, 0, 0, 0, time.Local)
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"0 3 * * *", time.Date(2024, time.January, 11, 3, 0, 0, 0, time.Local)},
+		{"@daily", time.Date(2024, time.January, 11, 0, 0, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2024, time.January, 10, 3, 15, 0, 0, time.Local)},
+		{"30 1-4 * * *", time.Date(2024, time.January, 10, 3, 30, 0, 0, time.Local)},
+		{"0 3 * * sat,sun", time.Date(2024, time.January, 13, 3, 0, 0, 0, time.Local)},
+		{"0 3 * * 7", time.Date(2024, time.January, 14, 3, 0, 0, 0, time.Local)},
+		{"0 0 1 * *", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.Local)},
+		{"0 0 1 JUN *", time.Date(2024, time.June, 1, 0, 0, 0, 0, time.Local)},
+		// Day and weekday both restricted: either matches
+		{"0 3 15 * 5", time.Date(2024, time.January, 12, 3, 0, 0, 0, time.Local)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.expr, err)
+			continue
+		}
+		if next := s.next(start); !next.Equal(tt.expected) {
+			t.Errorf("%s: expected %s, got %s", tt.expr, tt.expected, next)
+		}
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "0 3 * *", "60 * * * *", "0 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 * foo *", "0 0 31 2 *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestEverySchedule(t *testing.T) {
+	s := everySchedule(6 * time.Hour)
+	if first := s.next(time.Time{}); time.Since(first) > time.Minute {
+		t.Errorf("Expected the first run right away, got %s", first)
+	}
+	start := time.Date(2024, time.January, 10, 3, 0, 0, 0, time.UTC)
+	if next := s.next(start); !next.Equal(start.Add(6 * time.Hour)) {
+		t.Errorf("Expected %s, got %s", start.Add(6*time.Hour), next)
+	}
+}
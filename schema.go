@@ -0,0 +1,10 @@
+package main
+
+import _ "embed"
+
+// reportSchema is the JSON Schema of the JSON and JSONL outputs, printed by
+// --schema, with the plan files of plan and apply. Keep it in step with
+// Finding, CleanupResult, SchemaVersion, Plan and Plan.Validate.
+//
+//go:embed schema.json
+var reportSchema []byte
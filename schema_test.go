@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for the published JSON Schema
+// ============================================================================
+
+type schemaObject struct {
+	Properties map[string]struct {
+		Const *int     `json:"const"`
+		Enum  []string `json:"enum"`
+	} `json:"properties"`
+	Defs map[string]*schemaObject `json:"$defs"`
+}
+
+func loadReportSchema(t *testing.T) *schemaObject {
+	t.Helper()
+	var schema schemaObject
+	if err := json.Unmarshal(reportSchema, &schema); err != nil {
+		t.Fatalf("schema.json is not valid JSON: %v", err)
+	}
+	return &schema
+}
+
+// jsonKeys returns the keys v serializes to.
+func jsonKeys(t *testing.T, v any) []string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unionKeys returns the keys in a or b, sorted.
+func unionKeys(a, b []string) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, key := range append(a, b...) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func propertyNames(obj *schemaObject) []string {
+	var names []string
+	for name := range obj.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReportSchema_MatchesFinding(t *testing.T) {
+	schema := loadReportSchema(t)
+	// Findings and plan actions share the fields of finding_fields
+	finding := schema.Defs["finding_fields"]
+	if finding == nil {
+		t.Fatal("Expected a finding_fields definition")
+	}
+
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	full := cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/p", Severity: cleanup.SeverityInfo, Library: "/l", Message: "m", Target: "/t", Modified: &modified,
+		Usage: cleanup.Usage{Bytes: 1, Reclaimable: 1, Hardlinked: 1}}
+	// The --diff lines of --format jsonl add the change
+	keys, props := jsonKeys(t, changedFinding{Finding: full, Change: "new"}), propertyNames(finding)
+	if len(keys) != len(props) {
+		t.Fatalf("Expected schema properties %v, got %v", keys, props)
+	}
+	for i := range keys {
+		if keys[i] != props[i] {
+			t.Errorf("Expected schema properties %v, got %v", keys, props)
+			break
+		}
+	}
+
+	enum := map[string]bool{}
+	for _, c := range finding.Properties["category"].Enum {
+		enum[c] = true
+	}
+	if len(enum) != len(cleanup.Categories) {
+		t.Errorf("Expected %d categories in the schema, got %d", len(cleanup.Categories), len(enum))
+	}
+	for _, c := range cleanup.Categories {
+		if !enum[string(c)] {
+			t.Errorf("Category %q missing from the schema", c)
+		}
+	}
+
+	severities := finding.Properties["severity"].Enum
+	if fmt.Sprint(severities) != fmt.Sprint(cleanup.Severities) {
+		t.Errorf("Expected severities %v in the schema, got %v", cleanup.Severities, severities)
+	}
+}
+
+func TestReportSchema_MatchesResult(t *testing.T) {
+	schema := loadReportSchema(t)
+
+	full := &cleanup.CleanupResult{Libraries: []string{"/l"}, Errors: []error{errors.New("e")}}
+	full.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/l/x"})
+	var diff bytes.Buffer
+	if err := writeDiffJSON(&diff, ReportDiff{New: full, Resolved: full}); err != nil {
+		t.Fatal(err)
+	}
+	// --diff adds the resolved findings to the report
+	keys, props := unionKeys(jsonKeys(t, full), jsonKeys(t, json.RawMessage(diff.Bytes()))), propertyNames(schema)
+	if len(keys) != len(props) {
+		t.Fatalf("Expected schema properties %v, got %v", keys, props)
+	}
+	for i := range keys {
+		if keys[i] != props[i] {
+			t.Errorf("Expected schema properties %v, got %v", keys, props)
+			break
+		}
+	}
+
+	version := schema.Properties["schema_version"].Const
+	if version == nil || *version != cleanup.SchemaVersion {
+		t.Errorf("Expected schema_version const %d, got %v", cleanup.SchemaVersion, version)
+	}
+}
+
+func TestReportSchema_MatchesPlan(t *testing.T) {
+	schema := loadReportSchema(t)
+	plan, action := schema.Defs["plan"], schema.Defs["plan_action"]
+	if plan == nil || action == nil {
+		t.Fatal("Expected plan and plan_action definitions")
+	}
+
+	full := cleanup.Plan{Version: cleanup.PlanVersion, Created: time.Now(), Libraries: []string{"/l"}, Actions: []cleanup.PlanAction{}, Signature: "s"}
+	if keys, props := jsonKeys(t, full), propertyNames(plan); fmt.Sprint(keys) != fmt.Sprint(props) {
+		t.Errorf("Expected plan properties %v, got %v", keys, props)
+	}
+	version := plan.Properties["plan_version"].Const
+	if version == nil || *version != cleanup.PlanVersion {
+		t.Errorf("Expected plan_version const %d, got %v", cleanup.PlanVersion, version)
+	}
+
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	finding := cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/p", Severity: cleanup.SeverityInfo, Library: "/l", Message: "m", Target: "/t", Modified: &modified,
+		Usage: cleanup.Usage{Bytes: 1, Reclaimable: 1, Hardlinked: 1}}
+	keys := jsonKeys(t, cleanup.PlanAction{Finding: finding, Fingerprint: "f"})
+	// The change of --diff lines is not part of a finding
+	var props []string
+	for _, name := range unionKeys(propertyNames(schema.Defs["finding_fields"]), propertyNames(action)) {
+		if name != "change" {
+			props = append(props, name)
+		}
+	}
+	if fmt.Sprint(keys) != fmt.Sprint(props) {
+		t.Errorf("Expected plan_action properties %v, got %v", keys, props)
+	}
+}
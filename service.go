@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// serviceName names the systemd units and the Windows scheduled task.
+const serviceName = "video-folder-cleanup"
+
+// ServiceSpec describes the scheduled run registered by "service install":
+// the program and arguments to run, and how often to run them.
+type ServiceSpec struct {
+	Executable string
+	Args       []string
+	Every      time.Duration
+}
+
+// serviceSpec builds the spec of the current invocation: every flag set on
+// the command line except --service-every, followed by the library paths
+// made absolute, so the scheduled run does what this one would have done.
+func serviceSpec(fs *flag.FlagSet, libraryPaths []string, every time.Duration) (ServiceSpec, error) {
+	if every < time.Minute {
+		return ServiceSpec{}, fmt.Errorf("--service-every must be at least 1m, got %s", every)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return ServiceSpec{}, err
+	}
+	spec := ServiceSpec{Executable: exe, Every: every}
+	fs.Visit(func(f *flag.Flag) {
+		switch value := f.Value.(type) {
+		case *stringList:
+			for _, v := range *value {
+				spec.Args = append(spec.Args, "--"+f.Name+"="+v)
+			}
+		default:
+			if f.Name != "service-every" {
+				spec.Args = append(spec.Args, "--"+f.Name+"="+f.Value.String())
+			}
+		}
+	})
+	for _, path := range libraryPaths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return ServiceSpec{}, err
+		}
+		spec.Args = append(spec.Args, abs)
+	}
+	return spec, nil
+}
+
+// systemdUnits returns the service and timer units running spec. The timer
+// is persistent, so a run missed while the machine was off happens at the
+// next boot. Findings left in place do not mark the service as failed.
+func systemdUnits(spec ServiceSpec) (service, timer string) {
+	cmd := []string{systemdQuote(spec.Executable)}
+	for _, arg := range spec.Args {
+		cmd = append(cmd, systemdQuote(arg))
+	}
+	service = fmt.Sprintf(`[Unit]
+Description=Clean up orphaned media metadata
+Wants=network-online.target
+After=network-online.target local-fs.target
+
+[Service]
+Type=oneshot
+ExecStart=%s
+SuccessExitStatus=%d
+`, strings.Join(cmd, " "), exitFindings)
+	timer = fmt.Sprintf(`[Unit]
+Description=Run %[1]s every %[2]s
+
+[Timer]
+OnBootSec=15min
+OnUnitActiveSec=%[3]ds
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, serviceName, spec.Every, int64(spec.Every/time.Second))
+	return service, timer
+}
+
+// systemdQuote quotes arg for an ExecStart line, escaping the specifier and
+// variable characters systemd would otherwise expand.
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// schtasksCreateArgs returns the schtasks.exe arguments registering spec as a
+// scheduled task run by SYSTEM, which needs no logged-in user and survives
+// reboots. The period is rounded down to whole days, hours or minutes,
+// whichever the task scheduler can express.
+func schtasksCreateArgs(spec ServiceSpec) ([]string, error) {
+	var schedule, modifier string
+	switch {
+	case spec.Every%(24*time.Hour) == 0:
+		schedule, modifier = "DAILY", fmt.Sprint(int64(spec.Every/(24*time.Hour)))
+	case spec.Every < 24*time.Hour && spec.Every%time.Hour == 0:
+		schedule, modifier = "HOURLY", fmt.Sprint(int64(spec.Every/time.Hour))
+	case spec.Every < 24*time.Hour:
+		schedule, modifier = "MINUTE", fmt.Sprint(int64(spec.Every/time.Minute))
+	default:
+		return nil, fmt.Errorf("the task scheduler needs a period of whole days above 24h, got %s", spec.Every)
+	}
+
+	cmd := []string{windowsQuote(spec.Executable)}
+	for _, arg := range spec.Args {
+		cmd = append(cmd, windowsQuote(arg))
+	}
+	command := strings.Join(cmd, " ")
+	// schtasks rejects longer /TR values
+	if len(command) > 261 {
+		return nil, errors.New("the command line is too long for a scheduled task (261 characters at most), use fewer options or shorter paths")
+	}
+	return []string{"/Create", "/F", "/TN", serviceName, "/RU", "SYSTEM", "/SC", schedule, "/MO", modifier, "/TR", command}, nil
+}
+
+// windowsQuote quotes arg for a Windows command line.
+func windowsQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"") {
+		return arg
+	}
+	return `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+}
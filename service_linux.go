@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// systemdUnitDir is where "service install" writes its units.
+var systemdUnitDir = "/etc/systemd/system"
+
+// installService writes the systemd service and timer units for spec and
+// enables the timer. It needs root.
+func installService(spec ServiceSpec) error {
+	service, timer := systemdUnits(spec)
+	if err := os.WriteFile(filepath.Join(systemdUnitDir, serviceName+".service"), []byte(service), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(systemdUnitDir, serviceName+".timer"), []byte(timer), 0644); err != nil {
+		return err
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl("enable", "--now", serviceName+".timer")
+}
+
+// uninstallService disables the timer and removes both units.
+func uninstallService() error {
+	timerPath := filepath.Join(systemdUnitDir, serviceName+".timer")
+	if _, err := os.Stat(timerPath); err != nil {
+		return fmt.Errorf("%s is not installed: %w", serviceName, err)
+	}
+	if err := systemctl("disable", "--now", serviceName+".timer"); err != nil {
+		return err
+	}
+	var errs []error
+	for _, path := range []string{timerPath, filepath.Join(systemdUnitDir, serviceName+".service")} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return systemctl("daemon-reload")
+}
+
+func systemctl(args ...string) error {
+	if out, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl %v: %v: %s", args, err, out)
+	}
+	return nil
+}
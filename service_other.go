@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package main
+
+import "errors"
+
+// installService is only implemented for systemd and the Windows task
+// scheduler; elsewhere, run the tool from cron or launchd.
+func installService(spec ServiceSpec) error {
+	return errors.New("service install is only supported with systemd on Linux and on Windows, use cron or launchd instead")
+}
+
+func uninstallService() error {
+	return errors.New("service uninstall is only supported with systemd on Linux and on Windows")
+}
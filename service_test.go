@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ============================================================================
+// Tests for serviceSpec
+// ============================================================================
+
+func TestServiceSpec_KeepsSetFlagsAndAbsolutePaths(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("execute", false, "")
+	fs.Int("workers", 10, "")
+	fs.Duration("service-every", 24*time.Hour, "")
+	if err := fs.Parse([]string{"--execute", "--service-every", "1h", "lib"}); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := serviceSpec(fs, fs.Args(), time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	abs, _ := filepath.Abs("lib")
+	expected := []string{"--execute=true", abs}
+	if strings.Join(spec.Args, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected args %q, got %q", expected, spec.Args)
+	}
+	if spec.Every != time.Hour {
+		t.Errorf("Expected every 1h, got %s", spec.Every)
+	}
+}
+
+func TestServiceSpec_RepeatsListFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var excludes stringList
+	fs.Var(&excludes, "exclude", "")
+	if err := fs.Parse([]string{"--exclude", "**/Staging/**", "--exclude", "Studio, Inc", "lib"}); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := serviceSpec(fs, fs.Args(), time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	abs, _ := filepath.Abs("lib")
+	expected := []string{"--exclude=**/Staging/**", "--exclude=Studio, Inc", abs}
+	if strings.Join(spec.Args, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected args %q, got %q", expected, spec.Args)
+	}
+}
+
+func TestServiceSpec_RejectsShortPeriods(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := serviceSpec(fs, []string{"lib"}, 30*time.Second); err == nil {
+		t.Error("Expected an error for a period under a minute")
+	}
+}
+
+// ============================================================================
+// Tests for systemdUnits
+// ============================================================================
+
+func TestSystemdUnits(t *testing.T) {
+	spec := ServiceSpec{
+		Executable: "/usr/local/bin/video-folder-cleanup",
+		Args:       []string{"--execute=true", "/mnt/media/Movies 4K", "/mnt/100%"},
+		Every:      6 * time.Hour,
+	}
+	service, timer := systemdUnits(spec)
+
+	expectedExec := `ExecStart=/usr/local/bin/video-folder-cleanup --execute=true "/mnt/media/Movies 4K" /mnt/100%%`
+	if !strings.Contains(service, expectedExec+"\n") {
+		t.Errorf("Expected service to contain %q, got:\n%s", expectedExec, service)
+	}
+	if !strings.Contains(service, "Type=oneshot") {
+		t.Errorf("Expected a oneshot service, got:\n%s", service)
+	}
+	if !strings.Contains(service, "SuccessExitStatus=1\n") {
+		t.Errorf("Expected findings not to fail the service, got:\n%s", service)
+	}
+	for _, line := range []string{"OnUnitActiveSec=21600s", "Persistent=true", "WantedBy=timers.target"} {
+		if !strings.Contains(timer, line+"\n") {
+			t.Errorf("Expected timer to contain %q, got:\n%s", line, timer)
+		}
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected string
+	}{
+		{"/plain/path", "/plain/path"},
+		{"", `""`},
+		{"with space", `"with space"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"$HOME", "$$HOME"},
+		{"50%", "50%%"},
+	}
+	for _, tt := range tests {
+		if got := systemdQuote(tt.arg); got != tt.expected {
+			t.Errorf("systemdQuote(%q): expected %s, got %s", tt.arg, tt.expected, got)
+		}
+	}
+}
+
+// ============================================================================
+// Tests for schtasksCreateArgs
+// ============================================================================
+
+func TestSchtasksCreateArgs_Schedule(t *testing.T) {
+	tests := []struct {
+		every    time.Duration
+		schedule string
+		modifier string
+	}{
+		{24 * time.Hour, "DAILY", "1"},
+		{7 * 24 * time.Hour, "DAILY", "7"},
+		{6 * time.Hour, "HOURLY", "6"},
+		{90 * time.Minute, "MINUTE", "90"},
+	}
+	for _, tt := range tests {
+		args, err := schtasksCreateArgs(ServiceSpec{Executable: `C:\tools\cleanup.exe`, Every: tt.every})
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.every, err)
+			continue
+		}
+		joined := strings.Join(args, " ")
+		expected := "/SC " + tt.schedule + " /MO " + tt.modifier
+		if !strings.Contains(joined, expected) {
+			t.Errorf("%s: expected %q in %q", tt.every, expected, joined)
+		}
+	}
+}
+
+func TestSchtasksCreateArgs_Command(t *testing.T) {
+	spec := ServiceSpec{Executable: `C:\Program Files\cleanup.exe`, Args: []string{`D:\Movies`, `E:\TV Shows`}, Every: 24 * time.Hour}
+	args, err := schtasksCreateArgs(spec)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := `"C:\Program Files\cleanup.exe" D:\Movies "E:\TV Shows"`
+	if args[len(args)-1] != expected {
+		t.Errorf("Expected /TR %s, got %s", expected, args[len(args)-1])
+	}
+}
+
+func TestSchtasksCreateArgs_Rejects(t *testing.T) {
+	if _, err := schtasksCreateArgs(ServiceSpec{Executable: "cleanup.exe", Every: 36 * time.Hour}); err == nil {
+		t.Error("Expected an error for a period of 36h")
+	}
+	long := ServiceSpec{Executable: "cleanup.exe", Args: []string{strings.Repeat("x", 300)}, Every: time.Hour}
+	if _, err := schtasksCreateArgs(long); err == nil {
+		t.Error("Expected an error for a command line over 261 characters")
+	}
+}
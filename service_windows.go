@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// installService registers spec as a scheduled task run by SYSTEM. It needs
+// an elevated prompt. The tool runs once and exits, which a scheduled task
+// is made for, while a Windows service would have to stay up between runs
+// and answer the service control manager.
+func installService(spec ServiceSpec) error {
+	args, err := schtasksCreateArgs(spec)
+	if err != nil {
+		return err
+	}
+	return schtasks(args...)
+}
+
+// uninstallService deletes the scheduled task.
+func uninstallService() error {
+	return schtasks("/Delete", "/F", "/TN", serviceName)
+}
+
+func schtasks(args ...string) error {
+	if out, err := exec.Command("schtasks", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("schtasks %s: %v: %s", args[0], err, out)
+	}
+	return nil
+}
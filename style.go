@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// Report sections that are not a finding category.
+const (
+	sectionReclaimableSpace = "reclaimable_space"
+	sectionLibrarySummary   = "library_summary"
+	sectionScanErrors       = "scan_errors"
+	sectionResolved         = "resolved"
+	sectionLargestOrphans   = "largest_orphans"
+	sectionOldestOrphans    = "oldest_orphans"
+	sectionDuplicateWaste   = "duplicate_waste"
+)
+
+// SectionStyle is how a text report section header is presented.
+type SectionStyle struct {
+	Symbol string
+	Label  string // English; translated unless overridden
+}
+
+// ReportStyle controls the symbols, labels and item prefix of the text
+// report. The machine-readable formats are not affected.
+type ReportStyle struct {
+	ItemPrefix string
+	Sections   map[string]SectionStyle // by category or section name
+	// overridden marks labels given by the user, which are not translated
+	overridden map[string]bool
+}
+
+// DefaultReportStyle returns the built-in style.
+func DefaultReportStyle() *ReportStyle {
+	return &ReportStyle{
+		ItemPrefix: "   ",
+		Sections: map[string]SectionStyle{
+			string(cleanup.CategoryStructureWarning):   {"⚠️", "Structure warnings"},
+			string(cleanup.CategoryMisfiledVideo):      {"📦", "Misfiled videos (main feature in an extras folder, not deleted)"},
+			string(cleanup.CategoryDownloadInProgress): {"⏳", "Downloads in progress (not deleted)"},
+			string(cleanup.CategoryTruncatedVideo):     {"💔", "Empty or truncated videos (not deleted)"},
+			string(cleanup.CategoryCorruptVideo):       {"🩹", "Corrupt videos (ffprobe failed, not deleted)"},
+			string(cleanup.CategoryBrokenSymlink):      {"🔗", "Broken video symlinks (target missing)"},
+			string(cleanup.CategoryOrphanedFolder):     {"🗑️", "Orphaned metadata folders (no video file)"},
+			string(cleanup.CategoryOrphanedFile):       {"🗑️", "Orphaned metadata files (no video file at same level)"},
+			string(cleanup.CategoryEmptyFolder):        {"📁", "Empty folders"},
+			string(cleanup.CategoryJunkFile):           {"🧹", "OS junk files"},
+			sectionReclaimableSpace:                    {"💾", "Reclaimable space"},
+			sectionLargestOrphans:                      {"🔝", "Largest orphans"},
+			sectionOldestOrphans:                       {"🕰️", "Oldest orphans"},
+			string(cleanup.CategoryDuplicateVideo):     {"🎞️", "Possible duplicate videos"},
+			sectionDuplicateWaste:                      {"♊", "Space wasted by identical copies"},
+			string(cleanup.CategoryPermissionMismatch): {"🔒", "Permission mismatches"},
+			string(cleanup.CategoryMetadataMismatch):   {"🏷️", "Metadata mismatches (NFO vs folder name)"},
+			string(cleanup.CategoryMissingMetadata):    {"🖼️", "Missing metadata (NFO or poster)"},
+			string(cleanup.CategoryIncompatibleName):   {"🔤", "Names incompatible with Windows/exFAT/SMB"},
+			string(cleanup.CategoryRecycleBin):         {"♻️", "NAS recycle bins (not scanned or deleted)"},
+			sectionLibrarySummary:                      {"📚", "Per-library summary"},
+			sectionScanErrors:                          {"❌", "Scan errors"},
+			sectionResolved:                            {"✅", "Resolved findings"},
+		},
+		overridden: map[string]bool{},
+	}
+}
+
+// reportStyleFile is the JSON form of a --report-style file. Omitted fields
+// keep their default; an empty symbol removes it.
+type reportStyleFile struct {
+	ItemPrefix *string `json:"item_prefix"`
+	Sections   map[string]struct {
+		Symbol *string `json:"symbol"`
+		Label  *string `json:"label"`
+	} `json:"sections"`
+}
+
+// LoadReportStyle reads a JSON style file and applies it over the default
+// style, e.g.
+//
+//	{"item_prefix": "- ", "sections": {"orphaned_folder": {"symbol": "", "label": "ORPHANS"}}}
+func LoadReportStyle(path string) (*ReportStyle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file reportStyleFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("report style %s: %w", path, err)
+	}
+
+	style := DefaultReportStyle()
+	if file.ItemPrefix != nil {
+		style.ItemPrefix = *file.ItemPrefix
+	}
+	for name, override := range file.Sections {
+		section, ok := style.Sections[name]
+		if !ok {
+			return nil, fmt.Errorf("report style %s: unknown section %q", path, name)
+		}
+		if override.Symbol != nil {
+			section.Symbol = *override.Symbol
+		}
+		if override.Label != nil {
+			section.Label = *override.Label
+			style.overridden[name] = true
+		}
+		style.Sections[name] = section
+	}
+	return style, nil
+}
+
+// label returns the label of section, translated unless overridden.
+func (s *ReportStyle) label(section string, lang *Language) string {
+	if s.overridden[section] {
+		return s.Sections[section].Label
+	}
+	return lang.T(s.Sections[section].Label)
+}
+
+// title returns the symbol and label of section, ready to print.
+func (s *ReportStyle) title(section string, lang *Language) string {
+	st := s.Sections[section]
+	label := s.label(section, lang)
+	if st.Symbol == "" {
+		return label
+	}
+	// Emoji with a variation selector render two columns wide but count
+	// as one in most terminals; pad them so labels line up
+	pad := " "
+	if r, _ := utf8.DecodeLastRuneInString(st.Symbol); r == '\uFE0F' {
+		pad = "  "
+	}
+	return strings.TrimRight(st.Symbol, " ") + pad + label
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for ReportStyle
+// ============================================================================
+
+func TestLoadReportStyle(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "style.json")
+	if err := os.WriteFile(path, []byte(`{
+		"item_prefix": "- ",
+		"sections": {
+			"orphaned_folder": {"symbol": "", "label": "ORPHANED_FOLDERS"},
+			"empty_folder": {"symbol": "[E]"}
+		}
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	style, err := LoadReportStyle(path)
+	if err != nil {
+		t.Fatalf("LoadReportStyle returned error: %v", err)
+	}
+
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/Gone"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/Empty"})
+
+	var buf bytes.Buffer
+	reportWriter{lang: Languages["de"], style: style}.printReport(&buf, result)
+	output := buf.String()
+	for _, expected := range []string{
+		"\nORPHANED_FOLDERS (1):\n- /lib/S/Gone\n",  // overridden labels are not translated
+		"\n[E] Leere Ordner (1):\n- /lib/S/Empty\n", // default labels still are
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in report, got %q", expected, output)
+		}
+	}
+}
+
+func TestLoadReportStyle_UnknownSection(t *testing.T) {
+	tempDir := setupTestDir(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "style.json")
+	if err := os.WriteFile(path, []byte(`{"sections": {"orphans": {"label": "x"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadReportStyle(path); err == nil || !strings.Contains(err.Error(), `"orphans"`) {
+		t.Errorf("Expected unknown section error, got %v", err)
+	}
+}
+
+func TestDefaultReportStyle_CoversCategories(t *testing.T) {
+	style := DefaultReportStyle()
+	for _, c := range cleanup.Categories {
+		if _, ok := style.Sections[string(c)]; !ok {
+			t.Errorf("Expected a default section style for %q", c)
+		}
+	}
+}
+
+func TestReportStyle_TitlePadsVariationSelector(t *testing.T) {
+	style := DefaultReportStyle()
+	if got := style.title(string(cleanup.CategoryStructureWarning), English); got != "⚠️  Structure warnings" {
+		t.Errorf("Expected two spaces after a variation selector emoji, got %q", got)
+	}
+	if got := style.title(string(cleanup.CategoryEmptyFolder), English); got != "📁 Empty folders" {
+		t.Errorf("Expected one space after a plain emoji, got %q", got)
+	}
+}
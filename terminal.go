@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// sectionColors are the ANSI colors of the text report headings on a
+// terminal: red for what --execute deletes, yellow for what needs a look,
+// cyan for what is only reported.
+var sectionColors = map[string]string{
+	string(cleanup.CategoryOrphanedFolder):     "31",
+	string(cleanup.CategoryOrphanedFile):       "31",
+	string(cleanup.CategoryEmptyFolder):        "31",
+	string(cleanup.CategoryJunkFile):           "31",
+	sectionLargestOrphans:                      "31",
+	sectionOldestOrphans:                       "31",
+	string(cleanup.CategoryStructureWarning):   "33",
+	string(cleanup.CategoryMisfiledVideo):      "33",
+	string(cleanup.CategoryDownloadInProgress): "33",
+	string(cleanup.CategoryTruncatedVideo):     "33",
+	string(cleanup.CategoryCorruptVideo):       "33",
+	string(cleanup.CategoryBrokenSymlink):      "33",
+	string(cleanup.CategoryPermissionMismatch): "36",
+	string(cleanup.CategoryDuplicateVideo):     "36",
+	string(cleanup.CategoryMetadataMismatch):   "36",
+	string(cleanup.CategoryMissingMetadata):    "36",
+	string(cleanup.CategoryIncompatibleName):   "36",
+	string(cleanup.CategoryRecycleBin):         "36",
+	sectionDuplicateWaste:                      "36",
+	sectionReclaimableSpace:                    "32",
+	sectionResolved:                            "32",
+	sectionLibrarySummary:                      "1",
+	sectionScanErrors:                          "1;31",
+}
+
+// colorize wraps s in the ANSI color of section, if it has one.
+func colorize(section, s string) string {
+	code := sectionColors[section]
+	if code == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// useColor reports whether the text report written to f is colored: only
+// on a terminal, and neither with --no-color nor with NO_COLOR set, as
+// https://no-color.org asks.
+func useColor(f *os.File, disabled bool) bool {
+	if disabled || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f) && enableColor(f)
+}
+
+// asciiSymbols spells out in ASCII the symbols of the output, for logs that
+// mangle anything else, such as Windows Task Scheduler's. Emoji followed by
+// the two spaces that line labels up go first, taking one space instead.
+var asciiSymbols = func() *strings.Replacer {
+	symbols := []string{
+		"⚠️", "[!]", "❌", "[x]", "🛑", "[stop]", "⏹️", "[stop]",
+		"✓", "[ok]", "✅", "[ok]", "✨", "[ok]", "💡", "[hint]",
+		"🗑️", "[delete]", "📁", "[empty]", "🧹", "[junk]", "💾", "[space]",
+		"🔝", "[largest]", "🕰️", "[oldest]", "📦", "[misfiled]", "⏳", "[partial]",
+		"💔", "[truncated]", "🩹", "[corrupt]", "🔗", "[link]", "🎞️", "[duplicate]",
+		"♊", "[identical]", "🔒", "[perms]", "🏷️", "[nfo]", "🖼️", "[metadata]",
+		"🔤", "[name]", "♻️", "[recycle]", "📚", "[libraries]", "🛡️", "[kept]",
+		"🔀", "[changes]", "📬", "[digest]", "📝", "[log]", "🗄️", "[backup]",
+		"↩️", "[restored]", "↪️", "[resume]", "🔎", "[check]",
+		"→", "->", "↑", "^", "↓", "v", "█", "#", "░", "-",
+		"⠋", "|", "⠙", "/", "⠹", "-", "⠸", "\\", "⠼", "|", "⠴", "/", "⠦", "-", "⠧", "\\", "⠇", "|", "⠏", "/",
+	}
+	var pairs []string
+	for i := 0; i < len(symbols); i += 2 {
+		if strings.HasSuffix(symbols[i], "️") {
+			pairs = append(pairs, symbols[i]+"  ", symbols[i+1]+" ")
+		}
+	}
+	return strings.NewReplacer(append(pairs, symbols...)...)
+}()
+
+// asciiWriter writes to w with the symbols spelled out by asciiSymbols,
+// for --no-emoji. Each Write must hold whole symbols, as every Fprintf does.
+type asciiWriter struct {
+	w io.Writer
+}
+
+func (a asciiWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(a.w, asciiSymbols.Replace(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
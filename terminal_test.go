@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for colors and ASCII output
+// ============================================================================
+
+func TestAsciiWriter(t *testing.T) {
+	tests := map[string]string{
+		"🗑️  Orphaned metadata folders (no video file) (2):\n": "[delete] Orphaned metadata folders (no video file) (2):\n",
+		"💾 Reclaimable space: 2 B\n":                           "[space] Reclaimable space: 2 B\n",
+		"✓ Moved: /lib/a.mkv → /lib/A\n":                       "[ok] Moved: /lib/a.mkv -> /lib/A\n",
+		"⚠️  Deletion aborted\n":                               "[!] Deletion aborted\n",
+		"/lib/Amélie (2001)\n":                                 "/lib/Amélie (2001)\n",
+	}
+	for input, expected := range tests {
+		var buf bytes.Buffer
+		n, err := fmt.Fprint(asciiWriter{&buf}, input)
+		if err != nil || n != len(input) {
+			t.Errorf("Expected %d bytes written, got %d, %v", len(input), n, err)
+		}
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	}
+}
+
+func TestAsciiSymbols_CoverDefaultStyle(t *testing.T) {
+	for name, section := range DefaultReportStyle().Sections {
+		if ascii := asciiSymbols.Replace(section.Symbol); strings.ContainsFunc(ascii, func(r rune) bool { return r > 127 }) {
+			t.Errorf("Expected the symbol of %s spelled out in ASCII, got %q", name, ascii)
+		}
+	}
+}
+
+func TestReportWriter_Color(t *testing.T) {
+	result := &cleanup.CleanupResult{}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/S/A (2001)"})
+
+	var buf bytes.Buffer
+	if err := (reportWriter{format: "text", color: true}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "\x1b[31m🗑️  Orphaned metadata folders (no video file)\x1b[0m (1):\n   /lib/S/A (2001)\n"; !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected %q in the report, got:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := (reportWriter{format: "text"}).write(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("Expected no colors by default, got:\n%s", buf.String())
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if useColor(file, false) {
+		t.Error("Expected no colors in a file")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if useColor(os.Stdout, false) {
+		t.Error("Expected no colors with NO_COLOR set")
+	}
+}
+
+func TestSectionColors_KnownSections(t *testing.T) {
+	style := DefaultReportStyle()
+	for section := range sectionColors {
+		if _, ok := style.Sections[section]; !ok {
+			t.Errorf("Expected %s to be a report section", section)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// enableColor prepares the terminal f for ANSI colors; Unix terminals
+// always understand them.
+func enableColor(f *os.File) bool {
+	return true
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableVirtualTerminalProcessing is the console mode flag for ANSI escape
+// sequences, supported since Windows 10.
+const enableVirtualTerminalProcessing = 0x0004
+
+// enableColor turns on ANSI escape sequences in the console f is attached
+// to, and reports whether they are understood.
+func enableColor(f *os.File) bool {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ok, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
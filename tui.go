@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// picker is the state of the --interactive terminal UI: the deletable
+// findings of a scan grouped by studio, the ones selected for deletion and
+// the folder being previewed. Keys are fed to key and the screen is drawn
+// by render, so the UI can be driven without a terminal.
+type picker struct {
+	lang    *Language
+	style   *ReportStyle
+	groups  []*pickerGroup
+	readDir func(cleanup.Finding) ([]fs.DirEntry, error) // for previews
+
+	selected map[string]bool
+	cursor   int      // index in rows()
+	top      int      // first row shown
+	preview  []string // lines of the preview pane, nil when closed
+	confirm  bool     // asking whether to delete the selection
+	done     bool
+	approved bool // the selection is to be deleted
+}
+
+// pickerGroup is the findings of one studio (or show) of a library.
+type pickerGroup struct {
+	name     string
+	findings []cleanup.Finding
+	open     bool
+}
+
+// pickerRow is a line of the tree: a group header, or a finding of it.
+type pickerRow struct {
+	group   *pickerGroup
+	finding *cleanup.Finding // nil for the header
+}
+
+// newPicker groups the deletable findings of result by library and the
+// first folder below it.
+func newPicker(result *cleanup.CleanupResult, lang *Language, style *ReportStyle, readDir func(cleanup.Finding) ([]fs.DirEntry, error)) *picker {
+	if style == nil {
+		style = DefaultReportStyle()
+	}
+	p := &picker{lang: lang, style: style, readDir: readDir, selected: map[string]bool{}}
+	byName := map[string]*pickerGroup{}
+	for _, f := range result.Findings {
+		if !isDeletable(f) {
+			continue
+		}
+		name := f.Library
+		if rel, err := filepath.Rel(f.Library, f.Path); err == nil {
+			name = filepath.Join(f.Library, strings.Split(filepath.ToSlash(rel), "/")[0])
+		}
+		if byName[name] == nil {
+			byName[name] = &pickerGroup{name: name, open: true}
+			p.groups = append(p.groups, byName[name])
+		}
+		byName[name].findings = append(byName[name].findings, f)
+	}
+	sort.Slice(p.groups, func(i, j int) bool { return p.groups[i].name < p.groups[j].name })
+	for _, g := range p.groups {
+		sort.Slice(g.findings, func(i, j int) bool { return g.findings[i].Path < g.findings[j].Path })
+	}
+	return p
+}
+
+func (p *picker) rows() []pickerRow {
+	var rows []pickerRow
+	for _, g := range p.groups {
+		rows = append(rows, pickerRow{group: g})
+		if g.open {
+			for i := range g.findings {
+				rows = append(rows, pickerRow{group: g, finding: &g.findings[i]})
+			}
+		}
+	}
+	return rows
+}
+
+// approvedPaths returns the paths selected for deletion.
+func (p *picker) approvedPaths() map[string]bool {
+	return p.selected
+}
+
+// key handles one key press, named as readKeys names them.
+func (p *picker) key(k string) {
+	if p.confirm {
+		p.confirm = false
+		if k == "y" || k == "Y" {
+			p.done, p.approved = true, true
+		}
+		return
+	}
+	rows := p.rows()
+	if len(rows) == 0 {
+		p.done = k == "q" || k == "ctrl-c" || k == "esc"
+		return
+	}
+	row := rows[p.cursor]
+	switch k {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		p.preview = nil
+	case "down", "j":
+		if p.cursor < len(rows)-1 {
+			p.cursor++
+		}
+		p.preview = nil
+	case " ":
+		if row.finding != nil {
+			p.toggle(row.finding.Path, !p.selected[row.finding.Path])
+			break
+		}
+		// A header selects its whole group, or clears it if all of it is
+		all := true
+		for _, f := range row.group.findings {
+			all = all && p.selected[f.Path]
+		}
+		for _, f := range row.group.findings {
+			p.toggle(f.Path, !all)
+		}
+	case "a":
+		all := len(p.selected) > 0
+		for _, g := range p.groups {
+			for _, f := range g.findings {
+				all = all && p.selected[f.Path]
+			}
+		}
+		for _, g := range p.groups {
+			for _, f := range g.findings {
+				p.toggle(f.Path, !all)
+			}
+		}
+	case "enter", "right", "l":
+		if row.finding == nil {
+			row.group.open = k == "right" || k == "l" || !row.group.open
+		} else if p.preview == nil {
+			p.preview = p.previewLines(*row.finding)
+		} else {
+			p.preview = nil
+		}
+	case "p":
+		if row.finding != nil && p.preview == nil {
+			p.preview = p.previewLines(*row.finding)
+		} else {
+			p.preview = nil
+		}
+	case "left", "h":
+		row.group.open = false
+		for i, r := range p.rows() {
+			if r.group == row.group {
+				p.cursor = i
+				break
+			}
+		}
+		p.preview = nil
+	case "x":
+		p.confirm = len(p.selected) > 0
+	case "q", "ctrl-c", "esc":
+		p.done = true
+	}
+}
+
+func (p *picker) toggle(path string, on bool) {
+	if on {
+		p.selected[path] = true
+	} else {
+		delete(p.selected, path)
+	}
+}
+
+// previewLines lists the contents of the folder of f, or f itself if it is
+// a file.
+func (p *picker) previewLines(f cleanup.Finding) []string {
+	if f.Category == cleanup.CategoryOrphanedFile || f.Category == cleanup.CategoryJunkFile {
+		return []string{filepath.Base(f.Path)}
+	}
+	entries, err := p.readDir(f)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if len(entries) == 0 {
+		return []string{p.lang.T("(empty)")}
+	}
+	var lines []string
+	for _, entry := range entries {
+		line := entry.Name()
+		if entry.IsDir() {
+			line += "/"
+		} else if info, err := entry.Info(); err == nil {
+			line += "  " + cleanup.FormatBytes(info.Size())
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// render draws the screen for a terminal of height lines and width columns.
+func (p *picker) render(height, width int) string {
+	var b strings.Builder
+	line := func(s string, highlight bool) {
+		s = truncate(s, width)
+		if highlight {
+			s = "\x1b[7m" + s + "\x1b[0m"
+		}
+		b.WriteString(s + "\x1b[K\r\n")
+	}
+	line(p.lang.T("↑↓ move, space select, enter open/preview, a all, x delete selected, q quit"), false)
+
+	preview := p.preview
+	if max := height / 3; len(preview) > max {
+		preview = append(preview[:max-1:max-1], "…")
+	}
+	listHeight := height - 3
+	if preview != nil {
+		listHeight -= len(preview) + 1
+	}
+	listHeight = max(listHeight, 1)
+
+	rows := p.rows()
+	if p.cursor < p.top {
+		p.top = p.cursor
+	}
+	if p.cursor >= p.top+listHeight {
+		p.top = p.cursor - listHeight + 1
+	}
+	for i := p.top; i < len(rows) && i < p.top+listHeight; i++ {
+		line(p.rowText(rows[i]), i == p.cursor)
+	}
+	if len(rows) == 0 {
+		line(p.lang.T("Nothing to clean up"), false)
+	}
+	for i := len(rows) - p.top; i < listHeight; i++ {
+		line("", false)
+	}
+	if preview != nil {
+		line(strings.Repeat("─", width), false)
+		for _, l := range preview {
+			line("  "+l, false)
+		}
+	}
+
+	var reclaimable int64
+	for _, g := range p.groups {
+		for _, f := range g.findings {
+			if p.selected[f.Path] {
+				reclaimable += f.Reclaimable
+			}
+		}
+	}
+	status := p.lang.Sprintf("%d selected, ~%s to reclaim", len(p.selected), cleanup.FormatBytes(reclaimable))
+	if p.confirm {
+		status = p.lang.Sprintf("Delete %d selected items? (y/n)", len(p.selected))
+	}
+	line(status, p.confirm)
+	return b.String()
+}
+
+func (p *picker) rowText(row pickerRow) string {
+	if row.finding == nil {
+		arrow, selected := "▸", 0
+		if row.group.open {
+			arrow = "▾"
+		}
+		for _, f := range row.group.findings {
+			if p.selected[f.Path] {
+				selected++
+			}
+		}
+		return fmt.Sprintf("%s %s (%d/%d)", arrow, row.group.name, selected, len(row.group.findings))
+	}
+	f := row.finding
+	box := "[ ]"
+	if p.selected[f.Path] {
+		box = "[x]"
+	}
+	name, err := filepath.Rel(row.group.name, f.Path)
+	if err != nil || name == "." {
+		name = filepath.Base(f.Path)
+	}
+	text := fmt.Sprintf("    %s %s %s", box, p.style.Sections[string(f.Category)].Symbol, name)
+	if f.Bytes > 0 {
+		text += "  " + cleanup.FormatBytes(f.Reclaimable)
+	}
+	return text
+}
+
+// truncate cuts s to width runes.
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:max(width-1, 0)]) + "…"
+}
+
+// readKeys splits what the terminal sent into key names: arrows, "enter",
+// "esc", "ctrl-c" and printable characters as themselves.
+func readKeys(buf []byte) []string {
+	named := map[string]string{"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left", "\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left"}
+	var keys []string
+	for len(buf) > 0 {
+		if len(buf) >= 3 && named[string(buf[:3])] != "" {
+			keys = append(keys, named[string(buf[:3])])
+			buf = buf[3:]
+			continue
+		}
+		switch buf[0] {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 3:
+			keys = append(keys, "ctrl-c")
+		case 0x1b:
+			keys = append(keys, "esc")
+		default:
+			r, size := utf8.DecodeRune(buf)
+			keys = append(keys, string(r))
+			buf = buf[size:]
+			continue
+		}
+		buf = buf[1:]
+	}
+	return keys
+}
+
+// runPicker shows p on the terminal until the user quits or confirms, and
+// returns the paths approved for deletion, nil if none.
+func runPicker(p *picker, in *os.File, out io.Writer) (map[string]bool, error) {
+	restore, err := rawTerminal(in)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+	// The alternate screen leaves the shell as it was once done
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 64)
+	for !p.done {
+		height, width := terminalSize(in)
+		fmt.Fprint(out, "\x1b[H"+p.render(height, width))
+		n, err := in.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range readKeys(buf[:n]) {
+			p.key(k)
+		}
+	}
+	if !p.approved {
+		return nil, nil
+	}
+	return p.approvedPaths(), nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"video-folder-cleanup/cleanup"
+)
+
+// ============================================================================
+// Tests for the --interactive terminal UI
+// ============================================================================
+
+func testPicker(t *testing.T) *picker {
+	t.Helper()
+	result := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: "/lib/B/Orphan (2001)", Library: "/lib", Usage: cleanup.Usage{Bytes: 2048, Reclaimable: 2048}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/A/Empty (2002)", Library: "/lib"})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFile, Path: "/lib/A/stray.nfo", Library: "/lib", Usage: cleanup.Usage{Bytes: 10, Reclaimable: 10}})
+	result.Add(cleanup.Finding{Category: cleanup.CategoryStructureWarning, Path: "/lib/x.mkv", Library: "/lib", Message: "Video file at library level"})
+	return newPicker(result, English, nil, func(f cleanup.Finding) ([]fs.DirEntry, error) {
+		return nil, errors.New("no preview")
+	})
+}
+
+func press(p *picker, keys ...string) {
+	for _, k := range keys {
+		p.key(k)
+	}
+}
+
+func TestPicker_GroupsByStudio(t *testing.T) {
+	p := testPicker(t)
+	var rows []string
+	for _, row := range p.rows() {
+		rows = append(rows, strings.TrimSpace(p.rowText(row)))
+	}
+	// Warnings cannot be deleted and are left out
+	expected := []string{
+		"▾ /lib/A (0/2)",
+		"[ ] 📁 Empty (2002)",
+		"[ ] 🗑️ stray.nfo  10 B",
+		"▾ /lib/B (0/1)",
+		"[ ] 🗑️ Orphan (2001)  2.0 KiB",
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected rows %q, got %q", expected, rows)
+	}
+}
+
+func TestPicker_Select(t *testing.T) {
+	p := testPicker(t)
+
+	// Space on a header selects the whole studio, and clears it again
+	press(p, " ")
+	if len(p.selected) != 2 {
+		t.Errorf("Expected the 2 items of /lib/A selected, got %v", p.selected)
+	}
+	press(p, " ")
+	if len(p.selected) != 0 {
+		t.Errorf("Expected nothing selected, got %v", p.selected)
+	}
+
+	press(p, "down", "down", "down", "down", " ")
+	if !reflect.DeepEqual(p.selected, map[string]bool{"/lib/B/Orphan (2001)": true}) {
+		t.Errorf("Expected the orphaned folder selected, got %v", p.selected)
+	}
+	if status := p.render(10, 80); !strings.Contains(status, "1 selected, ~2.0 KiB to reclaim") {
+		t.Errorf("Expected the selection in the status line, got %q", status)
+	}
+
+	press(p, "a")
+	if len(p.selected) != 3 {
+		t.Errorf("Expected everything selected, got %v", p.selected)
+	}
+	press(p, "a")
+	if len(p.selected) != 0 {
+		t.Errorf("Expected nothing selected, got %v", p.selected)
+	}
+}
+
+func TestPicker_CollapseGroup(t *testing.T) {
+	p := testPicker(t)
+	press(p, "down", "left")
+	if p.cursor != 0 || len(p.rows()) != 3 {
+		t.Errorf("Expected /lib/A collapsed with the cursor on it, got cursor %d and %d rows", p.cursor, len(p.rows()))
+	}
+	press(p, "enter")
+	if len(p.rows()) != 5 {
+		t.Errorf("Expected /lib/A open again, got %d rows", len(p.rows()))
+	}
+}
+
+func TestPicker_Confirm(t *testing.T) {
+	p := testPicker(t)
+
+	// Nothing to confirm without a selection
+	press(p, "x")
+	if p.confirm {
+		t.Error("Expected no confirmation without a selection")
+	}
+
+	press(p, "down", " ", "x")
+	if !strings.Contains(p.render(10, 80), "Delete 1 selected items? (y/n)") {
+		t.Errorf("Expected the confirmation, got %q", p.render(10, 80))
+	}
+	press(p, "n")
+	if p.done {
+		t.Error("Expected the picker to go on after a refusal")
+	}
+	press(p, "x", "y")
+	if !p.done || !p.approved {
+		t.Errorf("Expected the selection approved, got done %v approved %v", p.done, p.approved)
+	}
+	if !reflect.DeepEqual(p.approvedPaths(), map[string]bool{"/lib/A/Empty (2002)": true}) {
+		t.Errorf("Expected the empty folder approved, got %v", p.approvedPaths())
+	}
+}
+
+func TestPicker_Quit(t *testing.T) {
+	p := testPicker(t)
+	press(p, "down", " ", "q")
+	if !p.done || p.approved {
+		t.Errorf("Expected the picker to quit without approving, got done %v approved %v", p.done, p.approved)
+	}
+}
+
+func TestPicker_Preview(t *testing.T) {
+	dir := setupTestDir(t)
+	folder := filepath.Join(dir, "Studio", "Orphan (2001)")
+	createFile(t, filepath.Join(folder, "movie.nfo"))
+	if err := os.Mkdir(filepath.Join(folder, "extras"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	result := &cleanup.CleanupResult{Libraries: []string{dir}}
+	result.Add(cleanup.Finding{Category: cleanup.CategoryOrphanedFolder, Path: folder, Library: dir})
+	p := newPicker(result, English, nil, func(f cleanup.Finding) ([]fs.DirEntry, error) {
+		return os.ReadDir(f.Path)
+	})
+
+	press(p, "down", "p")
+	if len(p.preview) != 2 || p.preview[0] != "extras/" || !strings.HasPrefix(p.preview[1], "movie.nfo") {
+		t.Errorf("Expected extras/ and movie.nfo in the preview, got %q", p.preview)
+	}
+	if screen := p.render(12, 80); !strings.Contains(screen, "  extras/") {
+		t.Errorf("Expected the preview on screen, got %q", screen)
+	}
+	press(p, "up")
+	if p.preview != nil {
+		t.Errorf("Expected the preview closed by moving, got %q", p.preview)
+	}
+}
+
+func TestPicker_Scrolls(t *testing.T) {
+	result := &cleanup.CleanupResult{Libraries: []string{"/lib"}}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		result.Add(cleanup.Finding{Category: cleanup.CategoryEmptyFolder, Path: "/lib/S/" + name, Library: "/lib"})
+	}
+	p := newPicker(result, English, nil, nil)
+	press(p, "down", "down", "down", "down", "down", "down", "down", "down")
+	// The help and status lines leave 4 rows of the 9 on a 7-line terminal
+	screen := p.render(7, 80)
+	if !strings.Contains(screen, "\x1b[7m    [ ] 📁 h") || strings.Contains(screen, "▾ /lib/S") {
+		t.Errorf("Expected the list scrolled to the last row, got %q", screen)
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	keys := readKeys([]byte("\x1b[A\x1b[Bj \r\x03\x1bé"))
+	expected := []string{"up", "down", "j", " ", "enter", "ctrl-c", "esc", "é"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %q, got %q", expected, keys)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// stty runs stty on the terminal in and returns its output.
+func stty(in *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = in
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// rawTerminal makes in deliver keys as they are pressed, without echo, and
+// returns the function putting it back as it was.
+func rawTerminal(in *os.File) (func(), error) {
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("cannot set up the terminal: %w", err)
+	}
+	if _, err := stty(in, "-icanon", "-echo", "-isig", "min", "1"); err != nil {
+		return nil, fmt.Errorf("cannot set up the terminal: %w", err)
+	}
+	return func() { stty(in, saved) }, nil
+}
+
+// terminalSize returns the height and width of the terminal in, 24x80 if it
+// cannot be told.
+func terminalSize(in *os.File) (height, width int) {
+	size, err := stty(in, "size")
+	if fields := strings.Fields(size); err == nil && len(fields) == 2 {
+		height, _ = strconv.Atoi(fields[0])
+		width, _ = strconv.Atoi(fields[1])
+	}
+	if height <= 0 || width <= 0 {
+		return 24, 80
+	}
+	return height, width
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var getConsoleScreenBufferInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleScreenBufferInfo")
+
+// Console input mode flags.
+const (
+	enableProcessedInput       = 0x0001
+	enableLineInput            = 0x0002
+	enableEchoInput            = 0x0004
+	enableVirtualTerminalInput = 0x0200
+)
+
+// rawTerminal makes the console in deliver keys as they are pressed,
+// without echo, arrows as the escape sequences of a Unix terminal and
+// Ctrl-C as a key, and has stdout understand escape sequences. It returns
+// the function putting both back as they were. Windows 10 or later is
+// needed.
+func rawTerminal(in *os.File) (func(), error) {
+	input := syscall.Handle(in.Fd())
+	var inMode uint32
+	if err := syscall.GetConsoleMode(input, &inMode); err != nil {
+		return nil, fmt.Errorf("cannot set up the terminal: %w", err)
+	}
+	raw := inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if err := setMode(input, raw); err != nil {
+		return nil, fmt.Errorf("cannot set up the terminal: %w", err)
+	}
+	output := syscall.Handle(os.Stdout.Fd())
+	var outMode uint32
+	if err := syscall.GetConsoleMode(output, &outMode); err != nil {
+		_ = setMode(input, inMode)
+		return nil, fmt.Errorf("cannot set up the terminal: %w", err)
+	}
+	if err := setMode(output, outMode|enableVirtualTerminalProcessing); err != nil {
+		_ = setMode(input, inMode)
+		return nil, fmt.Errorf("cannot set up the terminal: %w", err)
+	}
+	// Nothing more can be done if the console cannot be put back
+	return func() {
+		_ = setMode(output, outMode)
+		_ = setMode(input, inMode)
+	}, nil
+}
+
+// setMode sets the mode of a console handle.
+func setMode(handle syscall.Handle, mode uint32) error {
+	if ok, _, err := setConsoleMode.Call(uintptr(handle), uintptr(mode)); ok == 0 {
+		return err
+	}
+	return nil
+}
+
+// consoleScreenBufferInfo is CONSOLE_SCREEN_BUFFER_INFO.
+type consoleScreenBufferInfo struct {
+	size, cursorPosition     [2]int16
+	attributes               uint16
+	left, top, right, bottom int16
+	maximumWindowSize        [2]int16
+}
+
+// terminalSize returns the height and width of the console window stdout
+// is shown in, 24x80 if it cannot be told.
+func terminalSize(in *os.File) (height, width int) {
+	var info consoleScreenBufferInfo
+	if ok, _, _ := getConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return 24, 80
+	}
+	height, width = int(info.bottom-info.top)+1, int(info.right-info.left)+1
+	if height <= 0 || width <= 0 {
+		return 24, 80
+	}
+	return height, width
+}
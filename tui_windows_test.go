@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ============================================================================
+// Tests for rawTerminal
+// ============================================================================
+
+func TestRawTerminal_RefusesFiles(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if restore, err := rawTerminal(f); err == nil {
+		restore()
+		t.Errorf("Expected an error for a file that is not a console")
+	}
+}